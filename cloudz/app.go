@@ -1,8 +1,10 @@
 package cloudz
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/ibrt/golang-validation/vz"
@@ -12,12 +14,31 @@ import (
 
 // AppConfig describes the app config.
 type AppConfig struct {
-	DisplayName   string      `validate:"required"`
-	Name          string      `validate:"required,resource-name"`
-	ConfigDirPath string      `validate:"required,dir"`
-	BuildDirPath  string      `validate:"required,parent-dir"`
-	AWSConfig     *aws.Config `validate:"required"`
-	Plugins       []Plugin    `validate:"required"`
+	DisplayName      string                `validate:"required"`
+	Name             string                `validate:"required,resource-name"`
+	ConfigDirPath    string                `validate:"required,dir"`
+	BuildDirPath     string                `validate:"required,parent-dir"`
+	AWSConfig        *aws.Config           `validate:"required"`
+	AWSClientOptions []opz.AWSClientOption // e.g. custom endpoints, HTTP proxy, CA bundle
+	Offline          bool                  // if true, AWSConfig is only used for its region and AWS is never called
+	Context          context.Context       // defaults to context.Background(), bounds AWS calls, stack waits, and commands, see also CloudStage.TryDeploy
+	CommandPolicy    *opz.CommandPolicy    // e.g. dry-run, allow-list, timeouts, audit sink
+	ApprovalPolicy   *ApprovalPolicy       // approval of destructive actions, see ApprovalPolicy
+	Images           *AppConfigImages
+	Versions         *VersionCatalog // defaults to NewDefaultVersionCatalog()
+	Naming           NamingStrategy  // defaults to &DefaultNamingStrategy{}
+	Plugins          []Plugin        `validate:"required"`
+}
+
+// AppConfigImages describes part of the app config.
+//
+// It applies to the public (Docker Hub) images used by plugins, either directly or as base images for the images they
+// build. Digests maps image references as used by the plugins (e.g. "caddy:2.5.1") to the digests they should be pinned
+// to (e.g. "sha256:..."). RegistryPrefix is prepended to all image references (e.g. "mirror.example.com/docker-hub"),
+// for use with a registry mirror or pull-through cache. Official images are qualified with "library/" in this case.
+type AppConfigImages struct {
+	RegistryPrefix string
+	Digests        map[string]string `validate:"dive,keys,required,endkeys,required,startswith=sha256:"`
 }
 
 // GetBuildDirPath returns the build dir path.
@@ -58,9 +79,58 @@ func (c *AppConfig) GetConfigDirPathForPlugin(p Plugin, additionalParts ...strin
 	}, additionalParts...)...)
 }
 
+// GetImage returns the reference for the given public image, after applying the image config (if any).
+func (c *AppConfig) GetImage(image string) string {
+	if c.Images == nil {
+		return image
+	}
+
+	if digest, ok := c.Images.Digests[image]; ok {
+		image = fmt.Sprintf("%v@%v", image, digest)
+	}
+
+	if c.Images.RegistryPrefix != "" {
+		if !strings.Contains(strings.SplitN(strings.SplitN(image, "@", 2)[0], ":", 2)[0], "/") {
+			image = "library/" + image
+		}
+
+		image = fmt.Sprintf("%v/%v", strings.TrimSuffix(c.Images.RegistryPrefix, "/"), image)
+	}
+
+	return image
+}
+
+// GetVersions returns the version catalog.
+func (c *AppConfig) GetVersions() *VersionCatalog {
+	if c.Versions == nil {
+		return NewDefaultVersionCatalog()
+	}
+	return c.Versions
+}
+
+// GetContext returns the context (see opz.NewOperations).
+func (c *AppConfig) GetContext() context.Context {
+	if c.Context == nil {
+		return context.Background()
+	}
+	return c.Context
+}
+
+// GetNaming returns the naming strategy.
+func (c *AppConfig) GetNaming() NamingStrategy {
+	if c.Naming == nil {
+		return &DefaultNamingStrategy{}
+	}
+	return c.Naming
+}
+
 // MustValidate validates the app config.
 func (c *AppConfig) MustValidate() {
 	vz.MustValidateStruct(c)
+
+	if naming, ok := c.Naming.(*DefaultNamingStrategy); ok {
+		vz.MustValidateStruct(naming)
+	}
 }
 
 // App describes an App.
@@ -70,6 +140,12 @@ type App interface {
 	GetSortedPlugins() [][]Plugin
 }
 
+// appContextBinder is implemented by apps whose operations can be temporarily bound to another context, e.g. the one given
+// to CloudStage.TryDeploy. The returned function restores the previous operations.
+type appContextBinder interface {
+	bindContext(ctx context.Context) func()
+}
+
 type appImpl struct {
 	cfg           *AppConfig
 	ops           opz.Operations
@@ -105,9 +181,14 @@ func NewApp(cfg *AppConfig) App {
 		}
 	}
 
+	ops := opz.NewOperations(cfg.GetContext(), cfg.BuildDirPath, cfg.AWSConfig, cfg.GetVersions().Tools, cfg.CommandPolicy, cfg.AWSClientOptions...)
+	if cfg.Offline {
+		ops = opz.NewOfflineOperations(cfg.GetContext(), cfg.BuildDirPath, cfg.AWSConfig.Region, cfg.GetVersions().Tools, cfg.CommandPolicy)
+	}
+
 	return &appImpl{
 		cfg:           cfg,
-		ops:           opz.NewOperations(cfg.BuildDirPath, cfg.AWSConfig),
+		ops:           ops,
 		sortedPlugins: sortedPlugins,
 	}
 }
@@ -122,7 +203,28 @@ func (a *appImpl) GetOperations() opz.Operations {
 	return a.ops
 }
 
+func (a *appImpl) bindContext(ctx context.Context) func() {
+	ops := a.ops
+	a.ops = ops.WithContext(ctx)
+
+	return func() {
+		a.ops = ops
+	}
+}
+
 // GetSortedPlugins implements the App interface.
 func (a *appImpl) GetSortedPlugins() [][]Plugin {
 	return a.sortedPlugins
 }
+
+// tryWithContext calls f with the operations of the given app bound to the given context instead of the app context (see
+// AppConfig.Context), and returns the error it panics with (if any). Stages, plugins, and plugin event hooks all see the
+// bound context via Operations.GetContext. Apps not created by NewApp keep their own context.
+func tryWithContext(a App, ctx context.Context, f func()) error {
+	return opz.Try(func() {
+		if binder, ok := a.(appContextBinder); ok {
+			defer binder.bindContext(ctx)()
+		}
+		f()
+	})
+}
@@ -0,0 +1,98 @@
+package cloudz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ibrt/golang-cloud/opz"
+)
+
+func TestAppConfig_GetImage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		images   *AppConfigImages
+		image    string
+		expected string
+	}{
+		{
+			name:     "no config",
+			images:   nil,
+			image:    "caddy:2.5.1",
+			expected: "caddy:2.5.1",
+		},
+		{
+			name: "digest",
+			images: &AppConfigImages{
+				Digests: map[string]string{"caddy:2.5.1": "sha256:abc"},
+			},
+			image:    "caddy:2.5.1",
+			expected: "caddy:2.5.1@sha256:abc",
+		},
+		{
+			name: "digest for other image",
+			images: &AppConfigImages{
+				Digests: map[string]string{"caddy:2.5.1": "sha256:abc"},
+			},
+			image:    "nginx:1.21.6",
+			expected: "nginx:1.21.6",
+		},
+		{
+			name: "registry prefix for official image",
+			images: &AppConfigImages{
+				RegistryPrefix: "mirror.example.com/docker-hub/",
+			},
+			image:    "caddy:2.5.1",
+			expected: "mirror.example.com/docker-hub/library/caddy:2.5.1",
+		},
+		{
+			name: "registry prefix for namespaced image",
+			images: &AppConfigImages{
+				RegistryPrefix: "mirror.example.com/docker-hub",
+			},
+			image:    "hasura/graphql-engine:v2.5.1",
+			expected: "mirror.example.com/docker-hub/hasura/graphql-engine:v2.5.1",
+		},
+		{
+			name: "registry prefix and digest",
+			images: &AppConfigImages{
+				RegistryPrefix: "mirror.example.com/docker-hub",
+				Digests:        map[string]string{"caddy:2.5.1": "sha256:abc"},
+			},
+			image:    "caddy:2.5.1",
+			expected: "mirror.example.com/docker-hub/library/caddy:2.5.1@sha256:abc",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expected, (&AppConfig{Images: testCase.images}).GetImage(testCase.image))
+		})
+	}
+}
+
+func TestAppConfig_GetVersions(t *testing.T) {
+	require.Equal(t, NewDefaultVersionCatalog(), (&AppConfig{}).GetVersions())
+
+	versions := NewDefaultVersionCatalog()
+	versions.Postgres = "14.3"
+	require.Equal(t, "14.3", (&AppConfig{Versions: versions}).GetVersions().Postgres)
+}
+
+func TestTryWithContext(t *testing.T) {
+	type key struct{}
+	appCtx := context.Background()
+	ctx := context.WithValue(context.Background(), key{}, "v")
+	app := &appImpl{ops: opz.NewOfflineOperations(appCtx, t.TempDir(), "us-east-1", NewDefaultVersionCatalog().Tools, nil)}
+
+	require.NoError(t, tryWithContext(app, ctx, func() {
+		require.Equal(t, ctx, app.GetOperations().GetContext())
+	}))
+	require.Equal(t, appCtx, app.GetOperations().GetContext())
+
+	require.EqualError(t, tryWithContext(app, ctx, func() { panic("test panic") }), "test panic")
+	require.Equal(t, appCtx, app.GetOperations().GetContext())
+}
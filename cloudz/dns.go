@@ -0,0 +1,72 @@
+package cloudz
+
+import (
+	"github.com/ibrt/golang-bites/stringz"
+)
+
+// Known DNS record types.
+const (
+	DNSRecordTypeCNAME = "CNAME"
+)
+
+var (
+	_ DNSProvider = &route53DNSProviderImpl{}
+)
+
+// DNSRecord describes a simple DNS record.
+type DNSRecord struct {
+	Name  string
+	Type  string
+	Value string
+	TTL   int64
+}
+
+// DNSProvider describes a provider hosting the DNS records for a domain.
+type DNSProvider interface {
+	// IsRoute53 returns true if the records are managed in Route53, false if they must be created via UpsertRecord.
+	IsRoute53() bool
+	// GetHostedZoneID returns the Route53 hosted zone ID, or nil if records must be created via UpsertRecord.
+	GetHostedZoneID() *string
+	MustCheckDomain(p Plugin, domainName string)
+	UpsertRecord(p Plugin, record *DNSRecord)
+	// DeleteRecord deletes a record created via UpsertRecord, e.g. when the plugin is destroyed. It does nothing if the
+	// record does not exist.
+	DeleteRecord(p Plugin, record *DNSRecord)
+}
+
+type route53DNSProviderImpl struct {
+	hostedZoneID string
+}
+
+// NewRoute53DNSProvider initializes a new DNSProvider backed by a Route53 hosted zone.
+// Records are managed in the CloudFormation stacks of the plugins that need them.
+func NewRoute53DNSProvider(hostedZoneID string) DNSProvider {
+	return &route53DNSProviderImpl{
+		hostedZoneID: hostedZoneID,
+	}
+}
+
+// IsRoute53 implements the DNSProvider interface.
+func (*route53DNSProviderImpl) IsRoute53() bool {
+	return true
+}
+
+// GetHostedZoneID implements the DNSProvider interface.
+func (d *route53DNSProviderImpl) GetHostedZoneID() *string {
+	return stringz.Ptr(d.hostedZoneID)
+}
+
+// MustCheckDomain implements the DNSProvider interface.
+func (d *route53DNSProviderImpl) MustCheckDomain(p Plugin, domainName string) {
+	CloudMustCheckHostedZone(p, d.hostedZoneID, domainName)
+}
+
+// UpsertRecord implements the DNSProvider interface.
+func (d *route53DNSProviderImpl) UpsertRecord(p Plugin, record *DNSRecord) {
+	p.GetStage().GetConfig().App.GetOperations().UpsertRecordSet(d.hostedZoneID, record.Name, record.Type, record.Value, record.TTL)
+}
+
+// DeleteRecord implements the DNSProvider interface.
+func (d *route53DNSProviderImpl) DeleteRecord(p Plugin, record *DNSRecord) {
+	p.GetStage().GetConfig().App.GetOperations().DeleteRecordSet(d.hostedZoneID, record.Name, record.Type, record.Value, record.TTL)
+}
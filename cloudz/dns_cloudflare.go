@@ -0,0 +1,167 @@
+package cloudz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+const (
+	cloudflareAPIBaseURL = "https://api.cloudflare.com/client/v4"
+)
+
+var (
+	_ DNSProvider = &cloudflareDNSProviderImpl{}
+)
+
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+type cloudflareZone struct {
+	Name        string   `json:"name"`
+	Status      string   `json:"status"`
+	NameServers []string `json:"name_servers"`
+}
+
+type cloudflareDNSRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int64  `json:"ttl"`
+	Proxied bool   `json:"proxied"`
+}
+
+type cloudflareDNSProviderImpl struct {
+	baseURL    string
+	zoneID     string
+	apiToken   string
+	httpClient *http.Client
+}
+
+// NewCloudflareDNSProvider initializes a new DNSProvider backed by a Cloudflare zone.
+// Records are created via the Cloudflare API after deploy, as (unproxied) CNAME records, since Cloudflare flattens
+// CNAME records at the zone apex. The API token requires the "Zone:Read" and "DNS:Edit" permissions.
+func NewCloudflareDNSProvider(zoneID, apiToken string) DNSProvider {
+	return &cloudflareDNSProviderImpl{
+		baseURL:  cloudflareAPIBaseURL,
+		zoneID:   zoneID,
+		apiToken: apiToken,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// IsRoute53 implements the DNSProvider interface.
+func (*cloudflareDNSProviderImpl) IsRoute53() bool {
+	return false
+}
+
+// GetHostedZoneID implements the DNSProvider interface.
+func (d *cloudflareDNSProviderImpl) GetHostedZoneID() *string {
+	return nil
+}
+
+// MustCheckDomain implements the DNSProvider interface.
+func (d *cloudflareDNSProviderImpl) MustCheckDomain(p Plugin, domainName string) {
+	zone := &cloudflareZone{}
+	d.mustCall(p, http.MethodGet, fmt.Sprintf("/zones/%v", d.zoneID), nil, zone)
+
+	errorz.Assertf(domainName == zone.Name || strings.HasSuffix(domainName, "."+zone.Name), "domain %v does not belong to Cloudflare zone %v (%v)",
+		errorz.A(domainName, d.zoneID, zone.Name), errorz.Prefix(p.GetName()))
+	errorz.Assertf(zone.Status == "active", "Cloudflare zone %v is %v: update the name servers at the registrar to %v",
+		errorz.A(zone.Name, zone.Status, zone.NameServers), errorz.Prefix(p.GetName()))
+}
+
+// UpsertRecord implements the DNSProvider interface.
+func (d *cloudflareDNSProviderImpl) UpsertRecord(p Plugin, record *DNSRecord) {
+	name := strings.TrimSuffix(record.Name, ".")
+
+	existingRecords := make([]*cloudflareDNSRecord, 0)
+	d.mustCall(p, http.MethodGet,
+		fmt.Sprintf("/zones/%v/dns_records?type=%v&name=%v", d.zoneID, url.QueryEscape(record.Type), url.QueryEscape(name)),
+		nil, &existingRecords)
+
+	newRecord := &cloudflareDNSRecord{
+		Type:    record.Type,
+		Name:    name,
+		Content: strings.TrimSuffix(record.Value, "."),
+		TTL: func() int64 {
+			if record.TTL > 0 {
+				return record.TTL
+			}
+			return 1 // automatic
+		}(),
+		Proxied: false,
+	}
+
+	if len(existingRecords) > 0 {
+		d.mustCall(p, http.MethodPut, fmt.Sprintf("/zones/%v/dns_records/%v", d.zoneID, existingRecords[0].ID), newRecord, nil)
+		return
+	}
+
+	d.mustCall(p, http.MethodPost, fmt.Sprintf("/zones/%v/dns_records", d.zoneID), newRecord, nil)
+}
+
+// DeleteRecord implements the DNSProvider interface.
+func (d *cloudflareDNSProviderImpl) DeleteRecord(p Plugin, record *DNSRecord) {
+	existingRecords := make([]*cloudflareDNSRecord, 0)
+	d.mustCall(p, http.MethodGet,
+		fmt.Sprintf("/zones/%v/dns_records?type=%v&name=%v", d.zoneID, url.QueryEscape(record.Type), url.QueryEscape(strings.TrimSuffix(record.Name, "."))),
+		nil, &existingRecords)
+
+	for _, existingRecord := range existingRecords {
+		if existingRecord.Content == strings.TrimSuffix(record.Value, ".") {
+			d.mustCall(p, http.MethodDelete, fmt.Sprintf("/zones/%v/dns_records/%v", d.zoneID, existingRecord.ID), nil, nil)
+		}
+	}
+}
+
+func (d *cloudflareDNSProviderImpl) mustCall(p Plugin, method, path string, reqBody interface{}, respResult interface{}) {
+	var body io.Reader
+
+	if reqBody != nil {
+		buf, err := json.Marshal(reqBody)
+		errorz.MaybeMustWrap(err)
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(p.GetStage().GetConfig().App.GetOperations().GetContext(), method, d.baseURL+path, body)
+	errorz.MaybeMustWrap(err)
+	req.Header.Set("Authorization", "Bearer "+d.apiToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	errorz.MaybeMustWrap(err, errorz.M("method", method), errorz.M("path", path))
+	defer errorz.IgnoreClose(resp.Body)
+
+	cfResp := &cloudflareResponse{}
+	errorz.MaybeMustWrap(json.NewDecoder(resp.Body).Decode(cfResp), errorz.M("method", method), errorz.M("path", path))
+
+	if !cfResp.Success {
+		messages := make([]string, 0, len(cfResp.Errors))
+		for _, e := range cfResp.Errors {
+			messages = append(messages, fmt.Sprintf("%v (%v)", e.Message, e.Code))
+		}
+		panic(errorz.Errorf("Cloudflare API error: %v", errorz.A(strings.Join(messages, "; ")),
+			errorz.M("method", method), errorz.M("path", path), errorz.M("status", resp.StatusCode)))
+	}
+
+	if respResult != nil {
+		errorz.MaybeMustWrap(json.Unmarshal(cfResp.Result, respResult), errorz.M("method", method), errorz.M("path", path))
+	}
+}
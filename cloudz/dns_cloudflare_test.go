@@ -0,0 +1,321 @@
+package cloudz
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ibrt/golang-cloud/opz"
+)
+
+type testCloudflareRequest struct {
+	Method        string
+	Path          string
+	Query         string
+	Authorization string
+	Body          map[string]interface{}
+}
+
+type testPlugin struct {
+	Plugin
+	name  string
+	stage Stage
+}
+
+func (p *testPlugin) GetName() string {
+	return p.name
+}
+
+func (p *testPlugin) GetStage() Stage {
+	return p.stage
+}
+
+func newTestPlugin(t *testing.T) *testPlugin {
+	return &testPlugin{
+		name: "test",
+		stage: &testStage{cfg: &StageConfig{
+			App: &testApp{
+				ops: opz.NewOfflineOperations(context.Background(), t.TempDir(), "us-east-1", NewDefaultVersionCatalog().Tools, nil),
+			},
+		}},
+	}
+}
+
+func newTestCloudflareDNSProvider(t *testing.T, handler func(req *testCloudflareRequest) interface{}) (*cloudflareDNSProviderImpl, *[]*testCloudflareRequest) {
+	requests := make([]*testCloudflareRequest, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &testCloudflareRequest{
+			Method:        r.Method,
+			Path:          r.URL.Path,
+			Query:         r.URL.RawQuery,
+			Authorization: r.Header.Get("Authorization"),
+		}
+
+		buf, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		if len(buf) > 0 {
+			assert.NoError(t, json.Unmarshal(buf, &req.Body))
+		}
+
+		requests = append(requests, req)
+		assert.NoError(t, json.NewEncoder(w).Encode(handler(req)))
+	}))
+	t.Cleanup(server.Close)
+
+	return &cloudflareDNSProviderImpl{
+		baseURL:  server.URL,
+		zoneID:   "zone-id",
+		apiToken: "token",
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}, &requests
+}
+
+func newTestCloudflareSuccess(result interface{}) interface{} {
+	return map[string]interface{}{
+		"success": true,
+		"errors":  []interface{}{},
+		"result":  result,
+	}
+}
+
+func TestCloudflareDNSProvider_GetHostedZoneID(t *testing.T) {
+	require.Nil(t, NewCloudflareDNSProvider("zone-id", "token").GetHostedZoneID())
+	require.False(t, NewCloudflareDNSProvider("zone-id", "token").IsRoute53())
+}
+
+func TestCloudflareDNSProvider_UpsertRecord(t *testing.T) {
+	testCases := []struct {
+		name             string
+		record           *DNSRecord
+		existingRecords  []interface{}
+		expectedMethod   string
+		expectedPath     string
+		expectedName     string
+		expectedContent  string
+		expectedTTL      float64
+		expectedQueryArg string
+	}{
+		{
+			name: "create",
+			record: &DNSRecord{
+				Name:  "api.example.com.",
+				Type:  DNSRecordTypeCNAME,
+				Value: "target.example.net.",
+				TTL:   300,
+			},
+			existingRecords:  []interface{}{},
+			expectedMethod:   http.MethodPost,
+			expectedPath:     "/zones/zone-id/dns_records",
+			expectedName:     "api.example.com",
+			expectedContent:  "target.example.net",
+			expectedTTL:      300,
+			expectedQueryArg: "name=api.example.com&type=CNAME",
+		},
+		{
+			name: "update",
+			record: &DNSRecord{
+				Name:  "api.example.com",
+				Type:  DNSRecordTypeCNAME,
+				Value: "target.example.net",
+			},
+			existingRecords: []interface{}{
+				map[string]interface{}{
+					"id":      "record-id",
+					"type":    DNSRecordTypeCNAME,
+					"name":    "api.example.com",
+					"content": "old.example.net",
+				},
+			},
+			expectedMethod:   http.MethodPut,
+			expectedPath:     "/zones/zone-id/dns_records/record-id",
+			expectedName:     "api.example.com",
+			expectedContent:  "target.example.net",
+			expectedTTL:      1,
+			expectedQueryArg: "name=api.example.com&type=CNAME",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			d, requests := newTestCloudflareDNSProvider(t, func(req *testCloudflareRequest) interface{} {
+				if req.Method == http.MethodGet {
+					return newTestCloudflareSuccess(testCase.existingRecords)
+				}
+				return newTestCloudflareSuccess(map[string]interface{}{})
+			})
+
+			d.UpsertRecord(newTestPlugin(t), testCase.record)
+			require.Len(t, *requests, 2)
+
+			listReq := (*requests)[0]
+			require.Equal(t, http.MethodGet, listReq.Method)
+			require.Equal(t, "/zones/zone-id/dns_records", listReq.Path)
+			require.Equal(t, testCase.expectedQueryArg, sortQuery(t, listReq.Query))
+			require.Equal(t, "Bearer token", listReq.Authorization)
+
+			upsertReq := (*requests)[1]
+			require.Equal(t, testCase.expectedMethod, upsertReq.Method)
+			require.Equal(t, testCase.expectedPath, upsertReq.Path)
+			require.Equal(t, map[string]interface{}{
+				"type":    DNSRecordTypeCNAME,
+				"name":    testCase.expectedName,
+				"content": testCase.expectedContent,
+				"ttl":     testCase.expectedTTL,
+				"proxied": false,
+			}, upsertReq.Body)
+		})
+	}
+}
+
+func TestCloudflareDNSProvider_DeleteRecord(t *testing.T) {
+	d, requests := newTestCloudflareDNSProvider(t, func(req *testCloudflareRequest) interface{} {
+		if req.Method == http.MethodGet {
+			return newTestCloudflareSuccess([]interface{}{
+				map[string]interface{}{
+					"id":      "record-id",
+					"type":    DNSRecordTypeCNAME,
+					"name":    "api.example.com",
+					"content": "target.example.net",
+				},
+				map[string]interface{}{
+					"id":      "other-record-id",
+					"type":    DNSRecordTypeCNAME,
+					"name":    "api.example.com",
+					"content": "other.example.net",
+				},
+			})
+		}
+		return newTestCloudflareSuccess(map[string]interface{}{})
+	})
+
+	d.DeleteRecord(newTestPlugin(t), &DNSRecord{
+		Name:  "api.example.com.",
+		Type:  DNSRecordTypeCNAME,
+		Value: "target.example.net.",
+	})
+	require.Len(t, *requests, 2)
+
+	listReq := (*requests)[0]
+	require.Equal(t, http.MethodGet, listReq.Method)
+	require.Equal(t, "name=api.example.com&type=CNAME", sortQuery(t, listReq.Query))
+
+	deleteReq := (*requests)[1]
+	require.Equal(t, http.MethodDelete, deleteReq.Method)
+	require.Equal(t, "/zones/zone-id/dns_records/record-id", deleteReq.Path)
+}
+
+func TestCloudflareDNSProvider_MustCheckDomain(t *testing.T) {
+	testCases := []struct {
+		name       string
+		domainName string
+		status     string
+		isValid    bool
+	}{
+		{
+			name:       "apex",
+			domainName: "example.com",
+			status:     "active",
+			isValid:    true,
+		},
+		{
+			name:       "subdomain",
+			domainName: "api.example.com",
+			status:     "active",
+			isValid:    true,
+		},
+		{
+			name:       "other zone",
+			domainName: "api.example.org",
+			status:     "active",
+			isValid:    false,
+		},
+		{
+			name:       "suffix without dot",
+			domainName: "notexample.com",
+			status:     "active",
+			isValid:    false,
+		},
+		{
+			name:       "pending zone",
+			domainName: "api.example.com",
+			status:     "pending",
+			isValid:    false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			d, _ := newTestCloudflareDNSProvider(t, func(req *testCloudflareRequest) interface{} {
+				assert.Equal(t, "/zones/zone-id", req.Path)
+
+				return newTestCloudflareSuccess(map[string]interface{}{
+					"name":         "example.com",
+					"status":       testCase.status,
+					"name_servers": []string{"a.ns.cloudflare.com", "b.ns.cloudflare.com"},
+				})
+			})
+
+			p := newTestPlugin(t)
+
+			if testCase.isValid {
+				require.NotPanics(t, func() { d.MustCheckDomain(p, testCase.domainName) })
+			} else {
+				require.Panics(t, func() { d.MustCheckDomain(p, testCase.domainName) })
+			}
+		})
+	}
+}
+
+func TestCloudflareDNSProvider_APIError(t *testing.T) {
+	d, _ := newTestCloudflareDNSProvider(t, func(_ *testCloudflareRequest) interface{} {
+		return map[string]interface{}{
+			"success": false,
+			"errors": []interface{}{
+				map[string]interface{}{
+					"code":    10000,
+					"message": "Authentication error",
+				},
+			},
+			"result": nil,
+		}
+	})
+
+	err := func() (err error) {
+		defer func() {
+			err = errorz.MaybeWrapRecover(recover())
+		}()
+
+		d.UpsertRecord(newTestPlugin(t), &DNSRecord{
+			Name:  "api.example.com",
+			Type:  DNSRecordTypeCNAME,
+			Value: "target.example.net",
+		})
+		return nil
+	}()
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Authentication error (10000)")
+}
+
+func sortQuery(t *testing.T, rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+	require.NoError(t, err)
+	return values.Encode()
+}
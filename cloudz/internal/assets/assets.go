@@ -11,6 +11,33 @@ const (
 
 // Embedded assets.
 var (
+	//go:embed cdc/application.properties.gotpl
+	CDCApplicationPropertiesTemplateAsset string
+
+	//go:embed cdc/Dockerfile.gotpl
+	CDCDockerfileTemplateAsset string
+
+	//go:embed data-export/Dockerfile.gotpl
+	DataExportDockerfileTemplateAsset string
+
+	//go:embed data-export/export.sh.asset
+	DataExportSHAsset []byte
+
+	//go:embed data-export/export.sql.gotpl
+	DataExportSQLTemplateAsset string
+
+	//go:embed error-tracking/bootstrap.py.gotpl
+	ErrorTrackingBootstrapPYTemplateAsset string
+
+	//go:embed event-bus/Dockerfile.gotpl
+	EventBusDockerfileTemplateAsset string
+
+	//go:embed event-bus/elasticmq.conf.gotpl
+	EventBusElasticMQConfTemplateAsset string
+
+	//go:embed event-bus/main.go.asset
+	EventBusMainGoAsset []byte
+
 	//go:embed go-function/air.toml.gotpl
 	GoFunctionAirTOMLTemplateAsset string
 
@@ -29,12 +56,39 @@ var (
 	//go:embed hasura-console/Dockerfile.gotpl
 	HasuraConsoleDockerfileTemplateAsset string
 
+	//go:embed http-api/contract-cases.go.gotpl
+	HTTPAPIContractCasesTemplateAsset string
+
+	//go:embed http-api/contract-gen.go.gotpl
+	HTTPAPIContractGenTemplateAsset string
+
 	//go:embed http-api/Dockerfile.gotpl
 	HTTPAPIDockerfileTemplateAsset string
 
+	//go:embed iac/app.ts.gotpl
+	IaCCDKAppTemplateAsset string
+
+	//go:embed keycloak/Dockerfile.gotpl
+	KeycloakDockerfileTemplateAsset string
+
 	//go:embed load-balancer/not-found.html.asset
 	LoadBalancerNotFoundHTMLAsset string
 
+	//go:embed metadata/metadata.go.gotpl
+	MetadataGoBindingTemplateAsset string
+
+	//go:embed metadata/metadata.ts.gotpl
+	MetadataTypeScriptBindingTemplateAsset string
+
+	//go:embed observability/grafana-dashboards.yml.asset
+	ObservabilityGrafanaDashboardsYMLAsset []byte
+
+	//go:embed observability/grafana-datasources.yml.gotpl
+	ObservabilityGrafanaDatasourcesYMLTemplateAsset string
+
+	//go:embed observability/prometheus.yml.gotpl
+	ObservabilityPrometheusYMLTemplateAsset string
+
 	//go:embed postgres/Dockerfile.gotpl
 	PostgresDockerfileTemplateAsset string
 
@@ -46,8 +100,101 @@ var (
 
 	//go:embed postgres/servers.json.gotpl
 	PostgresServersJSONTemplateAsset string
+
+	//go:embed proxy/Caddyfile.gotpl
+	ProxyCaddyfileTemplateAsset string
+
+	//go:embed static-site/nginx.conf.gotpl
+	StaticSiteNginxConfTemplateAsset string
+
+	//go:embed websocket-api/Dockerfile.gotpl
+	WebSocketAPIDockerfileTemplateAsset string
+
+	//go:embed websocket-api/main.go.asset
+	WebSocketAPIMainGoAsset []byte
 )
 
+// CDCApplicationPropertiesTemplateData describes the template data for CDCApplicationPropertiesTemplateAsset.
+type CDCApplicationPropertiesTemplateData struct {
+	BootstrapBrokers string
+	IAMAuth          bool
+	DatabaseHost     string
+	DatabasePort     string
+	DatabaseUser     string
+	DatabaseName     string
+	DatabaseSSLMode  string
+	TopicPrefix      string
+	PublicationName  string
+	SlotName         string
+	SnapshotMode     string
+	TableIncludeList string
+	Outbox           bool
+}
+
+// CDCDockerfileTemplateData describes the template data for CDCDockerfileTemplateAsset.
+// If MSKIAMAuthVersion is set, the MSK IAM authentication library is added to the image.
+type CDCDockerfileTemplateData struct {
+	BaseImage         string
+	DownloaderImage   string
+	MSKIAMAuthVersion string
+}
+
+// DataExportDockerfileTemplateData describes the template data for DataExportDockerfileTemplateAsset.
+type DataExportDockerfileTemplateData struct {
+	BaseImage     string
+	DuckDBVersion string
+}
+
+// DataExportSQLTemplateData describes the template data for DataExportSQLTemplateAsset.
+// If Endpoint is set, the bucket is accessed with the given static credentials (i.e. locally), otherwise with the
+// credentials of the task role.
+type DataExportSQLTemplateData struct {
+	Region    string
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Tables    []*DataExportSQLTemplateDataTable
+}
+
+// DataExportSQLTemplateDataTable describes part of DataExportSQLTemplateData.
+type DataExportSQLTemplateDataTable struct {
+	Source   string // i.e. an SQL table expression
+	Location string // i.e. an SQL string literal
+}
+
+// ErrorTrackingBootstrapPYTemplateData describes the template data for ErrorTrackingBootstrapPYTemplateAsset.
+type ErrorTrackingBootstrapPYTemplateData struct {
+	AdminEmail       string
+	AdminPassword    string
+	OrganizationSlug string
+	ProjectSlug      string
+	ProjectID        int
+	PublicKey        string
+}
+
+// EventBusDockerfileTemplateData describes the template data for EventBusDockerfileTemplateAsset.
+type EventBusDockerfileTemplateData struct {
+	BaseImage  string
+	ListenAddr string
+}
+
+// EventBusElasticMQConfTemplateData describes the template data for EventBusElasticMQConfTemplateAsset.
+type EventBusElasticMQConfTemplateData struct {
+	Host   string
+	Port   uint16
+	Queues []*EventBusElasticMQConfTemplateDataQueue
+}
+
+// EventBusElasticMQConfTemplateDataQueue describes part of EventBusElasticMQConfTemplateData.
+type EventBusElasticMQConfTemplateDataQueue struct {
+	Name                      string
+	VisibilityTimeoutSeconds  int
+	DeadLetterQueueName       string
+	MaxReceiveCount           int
+	FIFO                      bool
+	ContentBasedDeduplication bool
+}
+
 // GoFunctionAirTOMLTemplateData describes the template data for GoFunctionAirTOMLTemplateAsset.
 type GoFunctionAirTOMLTemplateData struct {
 	PackageName             string
@@ -58,14 +205,14 @@ type GoFunctionAirTOMLTemplateData struct {
 
 // GoFunctionDockerfileTemplateData describes the template data for HasuraDockerfileTemplateAsset.
 type GoFunctionDockerfileTemplateData struct {
-	GoVersion      string
+	BaseImage      string
 	FunctionName   string
 	TimeoutSeconds uint16
 }
 
 // HasuraDockerfileTemplateData describes the template data for HasuraDockerfileTemplateAsset.
 type HasuraDockerfileTemplateData struct {
-	Version string
+	BaseImage string
 }
 
 // HasuraConsoleDockerEntrypointSHTemplateData describes the template data for HasuraConsoleDockerEntrypointSHTemplateAsset.
@@ -78,20 +225,101 @@ type HasuraConsoleDockerEntrypointSHTemplateData struct {
 
 // HasuraConsoleDockerfileTemplateData describes the template data for HasuraConsoleDockerfileTemplateAsset.
 type HasuraConsoleDockerfileTemplateData struct {
+	BaseImage   string
 	Version     string
 	Port        uint16
 	AdminSecret string
 }
 
+// HTTPAPIContractTemplateData describes the template data for HTTPAPIContractCasesTemplateAsset and HTTPAPIContractGenTemplateAsset.
+type HTTPAPIContractTemplateData struct {
+	PackageName string
+	APIName     string
+	DefaultURL  string
+	Routes      []*HTTPAPIContractTemplateDataRoute
+}
+
+// HTTPAPIContractTemplateDataRoute describes part of HTTPAPIContractTemplateData.
+type HTTPAPIContractTemplateDataRoute struct {
+	RouteKey string
+	Path     string
+	Body     string
+}
+
 // HTTPAPIDockerfileTemplateData describes the template data for HTTPAPIDockerfileTemplateAsset.
 type HTTPAPIDockerfileTemplateData struct {
-	GoVersion  string
+	BaseImage  string
 	ListenAddr string
 }
 
+// IaCCDKAppTemplateData describes the template data for IaCCDKAppTemplateAsset.
+type IaCCDKAppTemplateData struct {
+	Region string
+	Stacks []*IaCCDKAppTemplateDataStack
+}
+
+// IaCCDKAppTemplateDataStack describes part of IaCCDKAppTemplateData.
+type IaCCDKAppTemplateDataStack struct {
+	ID               string
+	VarName          string
+	DisplayName      string
+	StackName        string
+	TemplateFilePath string
+	Tags             string // JSON object
+	Dependencies     []string
+}
+
+// KeycloakDockerfileTemplateData describes the template data for KeycloakDockerfileTemplateAsset.
+type KeycloakDockerfileTemplateData struct {
+	BaseImage string
+}
+
+// MetadataBindingTemplateData describes the template data for MetadataGoBindingTemplateAsset and MetadataTypeScriptBindingTemplateAsset.
+type MetadataBindingTemplateData struct {
+	PackageName string
+	StageName   string
+	StageTarget string
+	StageMode   string
+	Plugins     []*MetadataBindingTemplateDataPlugin
+}
+
+// MetadataBindingTemplateDataPlugin describes part of MetadataBindingTemplateData.
+type MetadataBindingTemplateDataPlugin struct {
+	TypeName    string
+	VarName     string
+	DisplayName string
+	Values      []*MetadataBindingTemplateDataValue
+}
+
+// MetadataBindingTemplateDataValue describes part of MetadataBindingTemplateData.
+type MetadataBindingTemplateDataValue struct {
+	Name         string
+	PropertyName string
+	Value        string
+}
+
+// ObservabilityGrafanaDatasourcesYMLTemplateData describes the template data for ObservabilityGrafanaDatasourcesYMLTemplateAsset.
+type ObservabilityGrafanaDatasourcesYMLTemplateData struct {
+	PrometheusURL string
+}
+
+// ObservabilityPrometheusYMLTemplateData describes the template data for ObservabilityPrometheusYMLTemplateAsset.
+type ObservabilityPrometheusYMLTemplateData struct {
+	Port          uint16
+	ScrapeTargets []*ObservabilityPrometheusYMLTemplateDataScrapeTarget
+}
+
+// ObservabilityPrometheusYMLTemplateDataScrapeTarget describes part of ObservabilityPrometheusYMLTemplateData.
+type ObservabilityPrometheusYMLTemplateDataScrapeTarget struct {
+	JobName     string
+	MetricsPath string
+	Target      string
+}
+
 // PostgresDockerfileTemplateData describes the template data for PostgresDockerfileTemplateAsset.
 type PostgresDockerfileTemplateData struct {
-	Version string
+	BaseImage          string
+	LogicalReplication bool
 }
 
 // PostgresPGPassTemplateData describes the template data for PostgresPGPassTemplateAsset.
@@ -111,3 +339,31 @@ type PostgresServersJSONTemplateData struct {
 	Username string
 	Database string
 }
+
+// ProxyCaddyfileTemplateData describes the template data for ProxyCaddyfileTemplateAsset.
+// If TLSCertificateFilePath and TLSKeyFilePath are empty, certificates are issued by the Caddy internal CA.
+type ProxyCaddyfileTemplateData struct {
+	HTTPPort               uint16
+	HTTPSPort              uint16
+	TLSCertificateFilePath string
+	TLSKeyFilePath         string
+	Routes                 []*ProxyCaddyfileTemplateDataRoute
+}
+
+// ProxyCaddyfileTemplateDataRoute describes part of the template data for ProxyCaddyfileTemplateAsset.
+type ProxyCaddyfileTemplateDataRoute struct {
+	Hostname string
+	Upstream string
+}
+
+// StaticSiteNginxConfTemplateData describes the template data for StaticSiteNginxConfTemplateAsset.
+type StaticSiteNginxConfTemplateData struct {
+	Port            uint16
+	IsSinglePageApp bool
+}
+
+// WebSocketAPIDockerfileTemplateData describes the template data for WebSocketAPIDockerfileTemplateAsset.
+type WebSocketAPIDockerfileTemplateData struct {
+	BaseImage  string
+	ListenAddr string
+}
@@ -0,0 +1,360 @@
+// Command gen-metadata-accessors generates typed accessors for the exports of the cloud metadata of the plugins in the
+// given package directory. It is meant to be run via "go generate".
+//
+// For each plugin implementation (i.e. type with an UpdateCloudMetadata method), the exports are collected from the
+// CloudAddExpRef and CloudAddExpGetAtt calls with constant arguments, directly in its methods or in the package
+// functions they call. Each reference export "<Plugin>Ref<Ref>" gets a "<Ref>Ref()" accessor, each attribute export
+// "<Plugin>Att<Att>" gets a "<Ref><Att>()" accessor (with any overlap between the two collapsed, e.g.
+// "TargetGroupFullName()"), on the cloud metadata type assigned in UpdateCloudMetadata.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const header = "// Code generated by gen-metadata-accessors. DO NOT EDIT."
+
+var (
+	refRegexp = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*?Ref([A-Z][A-Za-z0-9]*)$`)
+	attRegexp = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*?Att([A-Z][A-Za-z0-9]*)$`)
+)
+
+// arg is a resolved call argument: either a package-level constant or a parameter of the enclosing function.
+type arg struct {
+	constName  string
+	paramIndex int
+}
+
+type export struct {
+	ref arg
+	att *arg
+}
+
+type funcInfo struct {
+	decl    *ast.FuncDecl
+	params  map[string]int
+	exports []*export
+	done    bool
+	visited bool
+}
+
+type generator struct {
+	consts  map[string]struct{}
+	funcs   map[string]*funcInfo            // package functions
+	methods map[string]map[string]*funcInfo // receiver type -> method name -> method
+}
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: gen-metadata-accessors <package dir> <output file>")
+		os.Exit(2)
+	}
+
+	src, err := generate(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(os.Args[2], src, 0666); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(dirPath string) ([]byte, error) {
+	fset := token.NewFileSet()
+	g := &generator{
+		consts:  map[string]struct{}{},
+		funcs:   map[string]*funcInfo{},
+		methods: map[string]map[string]*funcInfo{},
+	}
+
+	fileNames, err := filepath.Glob(filepath.Join(dirPath, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	pkgName := ""
+	existing := map[string]map[string]struct{}{} // existing methods, by receiver type
+
+	for _, fileName := range fileNames {
+		if strings.HasSuffix(fileName, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, fileName, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		pkgName = f.Name.Name
+
+		if len(f.Comments) > 0 && f.Comments[0].List[0].Text == header {
+			continue // previous output
+		}
+
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				if decl.Tok != token.CONST {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					for _, name := range spec.(*ast.ValueSpec).Names {
+						g.consts[name.Name] = struct{}{}
+					}
+				}
+			case *ast.FuncDecl:
+				info := &funcInfo{decl: decl, params: map[string]int{}}
+				i := 0
+				for _, field := range decl.Type.Params.List {
+					for _, name := range field.Names {
+						info.params[name.Name] = i
+						i++
+					}
+					if len(field.Names) == 0 {
+						i++
+					}
+				}
+
+				if decl.Recv == nil {
+					g.funcs[decl.Name.Name] = info
+					continue
+				}
+
+				recvType := getRecvTypeName(decl)
+				if g.methods[recvType] == nil {
+					g.methods[recvType] = map[string]*funcInfo{}
+				}
+				g.methods[recvType][decl.Name.Name] = info
+
+				if existing[recvType] == nil {
+					existing[recvType] = map[string]struct{}{}
+				}
+				existing[recvType][decl.Name.Name] = struct{}{}
+			}
+		}
+	}
+
+	accessors := map[string]map[string]string{} // metadata type -> accessor name -> body
+
+	for recvType, methods := range g.methods {
+		updateCloudMetadata, ok := methods["UpdateCloudMetadata"]
+		if !ok {
+			continue
+		}
+
+		metadataType := getCloudMetadataType(updateCloudMetadata.decl)
+		if metadataType == "" {
+			continue
+		}
+
+		for _, method := range methods {
+			for _, e := range g.getExports(recvType, method) {
+				if e.ref.constName == "" || (e.att != nil && e.att.constName == "") {
+					continue
+				}
+
+				refMatch := refRegexp.FindStringSubmatch(e.ref.constName)
+				if refMatch == nil {
+					continue
+				}
+
+				name := refMatch[1] + "Ref"
+				doc := fmt.Sprintf("returns the value of the %v reference export.", e.ref.constName)
+				body := fmt.Sprintf("return m.Exports.GetRef(%v)", e.ref.constName)
+
+				if e.att != nil {
+					attMatch := attRegexp.FindStringSubmatch(e.att.constName)
+					if attMatch == nil {
+						continue
+					}
+					name = joinOverlapping(refMatch[1], attMatch[1])
+					doc = fmt.Sprintf("returns the value of the %v attribute export of %v.", e.att.constName, e.ref.constName)
+					body = fmt.Sprintf("return m.Exports.GetAtt(%v, %v)", e.ref.constName, e.att.constName)
+				}
+
+				if _, ok := existing[metadataType][name]; ok {
+					return nil, fmt.Errorf("%v: accessor %v conflicts with an existing method", metadataType, name)
+				}
+
+				if accessors[metadataType] == nil {
+					accessors[metadataType] = map[string]string{}
+				}
+
+				fn := fmt.Sprintf("// %v %v\nfunc (m *%v) %v() string {\n%v\n}\n", name, doc, metadataType, name, body)
+				if prev, ok := accessors[metadataType][name]; ok && prev != fn {
+					return nil, fmt.Errorf("%v: accessor %v generated for different exports", metadataType, name)
+				}
+				accessors[metadataType][name] = fn
+			}
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%v\n\npackage %v\n", header, pkgName)
+
+	for _, metadataType := range getSortedKeys(accessors) {
+		for _, name := range getSortedKeys(accessors[metadataType]) {
+			fmt.Fprintf(buf, "\n%v", accessors[metadataType][name])
+		}
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+// getExports returns the exports added by the given function, including the ones added by the functions it calls.
+func (g *generator) getExports(recvType string, info *funcInfo) []*export {
+	if info.done || info.visited {
+		return info.exports
+	}
+	info.visited = true
+
+	resolve := func(expr ast.Expr) arg {
+		if ident, ok := expr.(*ast.Ident); ok {
+			if i, ok := info.params[ident.Name]; ok {
+				return arg{paramIndex: i}
+			}
+			if _, ok := g.consts[ident.Name]; ok {
+				return arg{constName: ident.Name}
+			}
+		}
+		return arg{paramIndex: -1}
+	}
+
+	ast.Inspect(info.decl, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		var callee *funcInfo
+
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			switch {
+			case fun.Name == "CloudAddExpRef" && len(call.Args) == 3:
+				info.exports = append(info.exports, &export{ref: resolve(call.Args[2])})
+				return true
+			case fun.Name == "CloudAddExpGetAtt" && len(call.Args) == 4:
+				att := resolve(call.Args[3])
+				info.exports = append(info.exports, &export{ref: resolve(call.Args[2]), att: &att})
+				return true
+			}
+			callee = g.funcs[fun.Name]
+		case *ast.SelectorExpr:
+			if x, ok := fun.X.(*ast.Ident); ok && info.decl.Recv != nil && len(info.decl.Recv.List[0].Names) > 0 &&
+				x.Name == info.decl.Recv.List[0].Names[0].Name {
+				callee = g.methods[recvType][fun.Sel.Name]
+			}
+		}
+
+		if callee == nil {
+			return true
+		}
+
+		args := make([]arg, len(call.Args))
+		for i, callArg := range call.Args {
+			args[i] = resolve(callArg)
+		}
+
+		bind := func(a arg) arg {
+			if a.constName == "" && a.paramIndex >= 0 && a.paramIndex < len(args) {
+				return args[a.paramIndex]
+			}
+			return a
+		}
+
+		calleeRecvType := recvType
+		if callee.decl.Recv != nil {
+			calleeRecvType = getRecvTypeName(callee.decl)
+		}
+
+		for _, e := range g.getExports(calleeRecvType, callee) {
+			bound := &export{ref: bind(e.ref)}
+			if e.att != nil {
+				att := bind(*e.att)
+				bound.att = &att
+			}
+			info.exports = append(info.exports, bound)
+		}
+
+		return true
+	})
+
+	info.done = true
+	return info.exports
+}
+
+// getCloudMetadataType returns the name of the type of the composite literal assigned in UpdateCloudMetadata.
+func getCloudMetadataType(decl *ast.FuncDecl) string {
+	metadataType := ""
+
+	ast.Inspect(decl, func(node ast.Node) bool {
+		if lit, ok := node.(*ast.CompositeLit); ok && metadataType == "" {
+			if ident, ok := lit.Type.(*ast.Ident); ok && strings.HasSuffix(ident.Name, "CloudMetadata") {
+				metadataType = ident.Name
+			}
+		}
+		return metadataType == ""
+	})
+
+	return metadataType
+}
+
+func getRecvTypeName(decl *ast.FuncDecl) string {
+	expr := decl.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// joinOverlapping joins the two names, collapsing the longest suffix of the first that is a prefix of the second.
+func joinOverlapping(first, second string) string {
+	for i := 0; i < len(first); i++ {
+		if i > 0 && !isUpper(first[i]) {
+			continue
+		}
+		if suffix := first[i:]; strings.HasPrefix(second, suffix) &&
+			(len(second) == len(suffix) || isUpper(second[len(suffix)])) {
+			return first[:i] + second
+		}
+	}
+	return first + second
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+func getSortedKeys(m interface{}) []string {
+	keys := make([]string, 0)
+
+	switch m := m.(type) {
+	case map[string]map[string]string:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]string:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
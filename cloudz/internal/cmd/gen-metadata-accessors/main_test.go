@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinOverlapping(t *testing.T) {
+	testCases := []struct {
+		first    string
+		second   string
+		expected string
+	}{
+		{first: "TargetGroup", second: "TargetGroupFullName", expected: "TargetGroupFullName"},
+		{first: "ListenerRule", second: "RuleARN", expected: "ListenerRuleARN"},
+		{first: "Service", second: "Name", expected: "ServiceName"},
+		{first: "RoleExecution", second: "ARN", expected: "RoleExecutionARN"},
+		{first: "Cluster", second: "Clusters", expected: "ClusterClusters"},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.expected, func(t *testing.T) {
+			require.Equal(t, testCase.expected, joinOverlapping(testCase.first, testCase.second))
+		})
+	}
+}
+
+func TestGenerate_UpToDate(t *testing.T) {
+	expected, err := os.ReadFile("../../../metadata_accessors.go")
+	require.NoError(t, err)
+
+	actual, err := generate("../../..")
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(actual), `run "go generate" in the cloudz directory`)
+}
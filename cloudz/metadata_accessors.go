@@ -0,0 +1,1648 @@
+// Code generated by gen-metadata-accessors. DO NOT EDIT.
+
+package cloudz
+
+// APIEndpoint returns the value of the APIAttAPIEndpoint attribute export of APIRefAPI.
+func (m *APICloudMetadata) APIEndpoint() string {
+	return m.Exports.GetAtt(APIRefAPI, APIAttAPIEndpoint)
+}
+
+// APIMappingRef returns the value of the APIRefAPIMapping reference export.
+func (m *APICloudMetadata) APIMappingRef() string {
+	return m.Exports.GetRef(APIRefAPIMapping)
+}
+
+// APIRef returns the value of the APIRefAPI reference export.
+func (m *APICloudMetadata) APIRef() string {
+	return m.Exports.GetRef(APIRefAPI)
+}
+
+// DomainNameRef returns the value of the APIRefDomainName reference export.
+func (m *APICloudMetadata) DomainNameRef() string {
+	return m.Exports.GetRef(APIRefDomainName)
+}
+
+// DomainNameRegionalDomainName returns the value of the APIAttRegionalDomainName attribute export of APIRefDomainName.
+func (m *APICloudMetadata) DomainNameRegionalDomainName() string {
+	return m.Exports.GetAtt(APIRefDomainName, APIAttRegionalDomainName)
+}
+
+// DomainNameRegionalHostedZoneID returns the value of the APIAttRegionalHostedZoneID attribute export of APIRefDomainName.
+func (m *APICloudMetadata) DomainNameRegionalHostedZoneID() string {
+	return m.Exports.GetAtt(APIRefDomainName, APIAttRegionalHostedZoneID)
+}
+
+// HealthCheckRef returns the value of the APIRefHealthCheck reference export.
+func (m *APICloudMetadata) HealthCheckRef() string {
+	return m.Exports.GetRef(APIRefHealthCheck)
+}
+
+// IntegrationRef returns the value of the APIRefIntegration reference export.
+func (m *APICloudMetadata) IntegrationRef() string {
+	return m.Exports.GetRef(APIRefIntegration)
+}
+
+// RecordSetRef returns the value of the APIRefRecordSet reference export.
+func (m *APICloudMetadata) RecordSetRef() string {
+	return m.Exports.GetRef(APIRefRecordSet)
+}
+
+// StageRef returns the value of the APIRefStage reference export.
+func (m *APICloudMetadata) StageRef() string {
+	return m.Exports.GetRef(APIRefStage)
+}
+
+// PermissionSetOperatorPermissionSetARN returns the value of the AccessRolesAttPermissionSetARN attribute export of AccessRolesRefPermissionSetOperator.
+func (m *AccessRolesCloudMetadata) PermissionSetOperatorPermissionSetARN() string {
+	return m.Exports.GetAtt(AccessRolesRefPermissionSetOperator, AccessRolesAttPermissionSetARN)
+}
+
+// PermissionSetOperatorRef returns the value of the AccessRolesRefPermissionSetOperator reference export.
+func (m *AccessRolesCloudMetadata) PermissionSetOperatorRef() string {
+	return m.Exports.GetRef(AccessRolesRefPermissionSetOperator)
+}
+
+// PermissionSetReadOnlyPermissionSetARN returns the value of the AccessRolesAttPermissionSetARN attribute export of AccessRolesRefPermissionSetReadOnly.
+func (m *AccessRolesCloudMetadata) PermissionSetReadOnlyPermissionSetARN() string {
+	return m.Exports.GetAtt(AccessRolesRefPermissionSetReadOnly, AccessRolesAttPermissionSetARN)
+}
+
+// PermissionSetReadOnlyRef returns the value of the AccessRolesRefPermissionSetReadOnly reference export.
+func (m *AccessRolesCloudMetadata) PermissionSetReadOnlyRef() string {
+	return m.Exports.GetRef(AccessRolesRefPermissionSetReadOnly)
+}
+
+// RoleOperatorARN returns the value of the AccessRolesAttARN attribute export of AccessRolesRefRoleOperator.
+func (m *AccessRolesCloudMetadata) RoleOperatorARN() string {
+	return m.Exports.GetAtt(AccessRolesRefRoleOperator, AccessRolesAttARN)
+}
+
+// RoleOperatorRef returns the value of the AccessRolesRefRoleOperator reference export.
+func (m *AccessRolesCloudMetadata) RoleOperatorRef() string {
+	return m.Exports.GetRef(AccessRolesRefRoleOperator)
+}
+
+// RoleReadOnlyARN returns the value of the AccessRolesAttARN attribute export of AccessRolesRefRoleReadOnly.
+func (m *AccessRolesCloudMetadata) RoleReadOnlyARN() string {
+	return m.Exports.GetAtt(AccessRolesRefRoleReadOnly, AccessRolesAttARN)
+}
+
+// RoleReadOnlyRef returns the value of the AccessRolesRefRoleReadOnly reference export.
+func (m *AccessRolesCloudMetadata) RoleReadOnlyRef() string {
+	return m.Exports.GetRef(AccessRolesRefRoleReadOnly)
+}
+
+// BrokerRef returns the value of the ActiveMQRefBroker reference export.
+func (m *ActiveMQCloudMetadata) BrokerRef() string {
+	return m.Exports.GetRef(ActiveMQRefBroker)
+}
+
+// SecretRef returns the value of the ActiveMQRefSecret reference export.
+func (m *ActiveMQCloudMetadata) SecretRef() string {
+	return m.Exports.GetRef(ActiveMQRefSecret)
+}
+
+// RoleAccessARN returns the value of the AppRunnerServiceAttARN attribute export of AppRunnerServiceRefRoleAccess.
+func (m *AppRunnerServiceCloudMetadata) RoleAccessARN() string {
+	return m.Exports.GetAtt(AppRunnerServiceRefRoleAccess, AppRunnerServiceAttARN)
+}
+
+// RoleAccessRef returns the value of the AppRunnerServiceRefRoleAccess reference export.
+func (m *AppRunnerServiceCloudMetadata) RoleAccessRef() string {
+	return m.Exports.GetRef(AppRunnerServiceRefRoleAccess)
+}
+
+// RoleInstanceARN returns the value of the AppRunnerServiceAttARN attribute export of AppRunnerServiceRefRoleInstance.
+func (m *AppRunnerServiceCloudMetadata) RoleInstanceARN() string {
+	return m.Exports.GetAtt(AppRunnerServiceRefRoleInstance, AppRunnerServiceAttARN)
+}
+
+// RoleInstanceRef returns the value of the AppRunnerServiceRefRoleInstance reference export.
+func (m *AppRunnerServiceCloudMetadata) RoleInstanceRef() string {
+	return m.Exports.GetRef(AppRunnerServiceRefRoleInstance)
+}
+
+// ServiceID returns the value of the AppRunnerServiceAttServiceID attribute export of AppRunnerServiceRefService.
+func (m *AppRunnerServiceCloudMetadata) ServiceID() string {
+	return m.Exports.GetAtt(AppRunnerServiceRefService, AppRunnerServiceAttServiceID)
+}
+
+// ServiceRef returns the value of the AppRunnerServiceRefService reference export.
+func (m *AppRunnerServiceCloudMetadata) ServiceRef() string {
+	return m.Exports.GetRef(AppRunnerServiceRefService)
+}
+
+// ServiceURL returns the value of the AppRunnerServiceAttServiceURL attribute export of AppRunnerServiceRefService.
+func (m *AppRunnerServiceCloudMetadata) ServiceURL() string {
+	return m.Exports.GetAtt(AppRunnerServiceRefService, AppRunnerServiceAttServiceURL)
+}
+
+// PlanBackupPlanARN returns the value of the BackupAttBackupPlanARN attribute export of BackupRefPlan.
+func (m *BackupCloudMetadata) PlanBackupPlanARN() string {
+	return m.Exports.GetAtt(BackupRefPlan, BackupAttBackupPlanARN)
+}
+
+// PlanRef returns the value of the BackupRefPlan reference export.
+func (m *BackupCloudMetadata) PlanRef() string {
+	return m.Exports.GetRef(BackupRefPlan)
+}
+
+// RoleARN returns the value of the BackupAttARN attribute export of BackupRefRole.
+func (m *BackupCloudMetadata) RoleARN() string {
+	return m.Exports.GetAtt(BackupRefRole, BackupAttARN)
+}
+
+// RoleRef returns the value of the BackupRefRole reference export.
+func (m *BackupCloudMetadata) RoleRef() string {
+	return m.Exports.GetRef(BackupRefRole)
+}
+
+// VaultBackupVaultARN returns the value of the BackupAttBackupVaultARN attribute export of BackupRefVault.
+func (m *BackupCloudMetadata) VaultBackupVaultARN() string {
+	return m.Exports.GetAtt(BackupRefVault, BackupAttBackupVaultARN)
+}
+
+// VaultRef returns the value of the BackupRefVault reference export.
+func (m *BackupCloudMetadata) VaultRef() string {
+	return m.Exports.GetRef(BackupRefVault)
+}
+
+// BucketARN returns the value of the BucketAttARN attribute export of BucketRefBucket.
+func (m *BucketCloudMetadata) BucketARN() string {
+	return m.Exports.GetAtt(BucketRefBucket, BucketAttARN)
+}
+
+// BucketDomainName returns the value of the BucketAttDomainName attribute export of BucketRefBucket.
+func (m *BucketCloudMetadata) BucketDomainName() string {
+	return m.Exports.GetAtt(BucketRefBucket, BucketAttDomainName)
+}
+
+// BucketDualStackDomainName returns the value of the BucketAttDualStackDomainName attribute export of BucketRefBucket.
+func (m *BucketCloudMetadata) BucketDualStackDomainName() string {
+	return m.Exports.GetAtt(BucketRefBucket, BucketAttDualStackDomainName)
+}
+
+// BucketRef returns the value of the BucketRefBucket reference export.
+func (m *BucketCloudMetadata) BucketRef() string {
+	return m.Exports.GetRef(BucketRefBucket)
+}
+
+// BucketRegionalDomainName returns the value of the BucketAttRegionalDomainName attribute export of BucketRefBucket.
+func (m *BucketCloudMetadata) BucketRegionalDomainName() string {
+	return m.Exports.GetAtt(BucketRefBucket, BucketAttRegionalDomainName)
+}
+
+// BudgetRef returns the value of the BudgetRefBudget reference export.
+func (m *BudgetCloudMetadata) BudgetRef() string {
+	return m.Exports.GetRef(BudgetRefBudget)
+}
+
+// ClusterARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefCluster.
+func (m *CDCCloudMetadata) ClusterARN() string {
+	return m.Exports.GetAtt(ECSServiceRefCluster, ECSServiceAttARN)
+}
+
+// ClusterRef returns the value of the ECSServiceRefCluster reference export.
+func (m *CDCCloudMetadata) ClusterRef() string {
+	return m.Exports.GetRef(ECSServiceRefCluster)
+}
+
+// HealthCheckRef returns the value of the ECSServiceRefHealthCheck reference export.
+func (m *CDCCloudMetadata) HealthCheckRef() string {
+	return m.Exports.GetRef(ECSServiceRefHealthCheck)
+}
+
+// ListenerRuleARN returns the value of the ECSServiceAttRuleARN attribute export of ECSServiceRefListenerRule.
+func (m *CDCCloudMetadata) ListenerRuleARN() string {
+	return m.Exports.GetAtt(ECSServiceRefListenerRule, ECSServiceAttRuleARN)
+}
+
+// ListenerRuleRef returns the value of the ECSServiceRefListenerRule reference export.
+func (m *CDCCloudMetadata) ListenerRuleRef() string {
+	return m.Exports.GetRef(ECSServiceRefListenerRule)
+}
+
+// LogGroupARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefLogGroup.
+func (m *CDCCloudMetadata) LogGroupARN() string {
+	return m.Exports.GetAtt(ECSServiceRefLogGroup, ECSServiceAttARN)
+}
+
+// LogGroupRef returns the value of the ECSServiceRefLogGroup reference export.
+func (m *CDCCloudMetadata) LogGroupRef() string {
+	return m.Exports.GetRef(ECSServiceRefLogGroup)
+}
+
+// RecordSetRef returns the value of the ECSServiceRefRecordSet reference export.
+func (m *CDCCloudMetadata) RecordSetRef() string {
+	return m.Exports.GetRef(ECSServiceRefRecordSet)
+}
+
+// RoleExecutionARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefRoleExecution.
+func (m *CDCCloudMetadata) RoleExecutionARN() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleExecution, ECSServiceAttARN)
+}
+
+// RoleExecutionRef returns the value of the ECSServiceRefRoleExecution reference export.
+func (m *CDCCloudMetadata) RoleExecutionRef() string {
+	return m.Exports.GetRef(ECSServiceRefRoleExecution)
+}
+
+// RoleExecutionRoleID returns the value of the ECSServiceAttRoleID attribute export of ECSServiceRefRoleExecution.
+func (m *CDCCloudMetadata) RoleExecutionRoleID() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleExecution, ECSServiceAttRoleID)
+}
+
+// RoleTaskARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefRoleTask.
+func (m *CDCCloudMetadata) RoleTaskARN() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleTask, ECSServiceAttARN)
+}
+
+// RoleTaskRef returns the value of the ECSServiceRefRoleTask reference export.
+func (m *CDCCloudMetadata) RoleTaskRef() string {
+	return m.Exports.GetRef(ECSServiceRefRoleTask)
+}
+
+// RoleTaskRoleID returns the value of the ECSServiceAttRoleID attribute export of ECSServiceRefRoleTask.
+func (m *CDCCloudMetadata) RoleTaskRoleID() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleTask, ECSServiceAttRoleID)
+}
+
+// ServiceName returns the value of the ECSServiceAttName attribute export of ECSServiceRefService.
+func (m *CDCCloudMetadata) ServiceName() string {
+	return m.Exports.GetAtt(ECSServiceRefService, ECSServiceAttName)
+}
+
+// ServiceRef returns the value of the ECSServiceRefService reference export.
+func (m *CDCCloudMetadata) ServiceRef() string {
+	return m.Exports.GetRef(ECSServiceRefService)
+}
+
+// TargetGroupFullName returns the value of the ECSServiceAttTargetGroupFullName attribute export of ECSServiceRefTargetGroup.
+func (m *CDCCloudMetadata) TargetGroupFullName() string {
+	return m.Exports.GetAtt(ECSServiceRefTargetGroup, ECSServiceAttTargetGroupFullName)
+}
+
+// TargetGroupName returns the value of the ECSServiceAttTargetGroupName attribute export of ECSServiceRefTargetGroup.
+func (m *CDCCloudMetadata) TargetGroupName() string {
+	return m.Exports.GetAtt(ECSServiceRefTargetGroup, ECSServiceAttTargetGroupName)
+}
+
+// TargetGroupRef returns the value of the ECSServiceRefTargetGroup reference export.
+func (m *CDCCloudMetadata) TargetGroupRef() string {
+	return m.Exports.GetRef(ECSServiceRefTargetGroup)
+}
+
+// TaskDefinitionRef returns the value of the ECSServiceRefTaskDefinition reference export.
+func (m *CDCCloudMetadata) TaskDefinitionRef() string {
+	return m.Exports.GetRef(ECSServiceRefTaskDefinition)
+}
+
+// DistributionDomainName returns the value of the CDNAttDomainName attribute export of CDNRefDistribution.
+func (m *CDNCloudMetadata) DistributionDomainName() string {
+	return m.Exports.GetAtt(CDNRefDistribution, CDNAttDomainName)
+}
+
+// DistributionRef returns the value of the CDNRefDistribution reference export.
+func (m *CDNCloudMetadata) DistributionRef() string {
+	return m.Exports.GetRef(CDNRefDistribution)
+}
+
+// HealthCheckRef returns the value of the CDNRefHealthCheck reference export.
+func (m *CDNCloudMetadata) HealthCheckRef() string {
+	return m.Exports.GetRef(CDNRefHealthCheck)
+}
+
+// OriginAccessIdentityID returns the value of the CDNAttID attribute export of CDNRefOriginAccessIdentity.
+func (m *CDNCloudMetadata) OriginAccessIdentityID() string {
+	return m.Exports.GetAtt(CDNRefOriginAccessIdentity, CDNAttID)
+}
+
+// OriginAccessIdentityRef returns the value of the CDNRefOriginAccessIdentity reference export.
+func (m *CDNCloudMetadata) OriginAccessIdentityRef() string {
+	return m.Exports.GetRef(CDNRefOriginAccessIdentity)
+}
+
+// OriginAccessIdentityS3CanonicalUserID returns the value of the CDNAttS3CanonicalUserID attribute export of CDNRefOriginAccessIdentity.
+func (m *CDNCloudMetadata) OriginAccessIdentityS3CanonicalUserID() string {
+	return m.Exports.GetAtt(CDNRefOriginAccessIdentity, CDNAttS3CanonicalUserID)
+}
+
+// RecordSetRef returns the value of the CDNRefRecordSet reference export.
+func (m *CDNCloudMetadata) RecordSetRef() string {
+	return m.Exports.GetRef(CDNRefRecordSet)
+}
+
+// CertificateRef returns the value of the CertificateRefCertificate reference export.
+func (m *CertificateCloudMetadata) CertificateRef() string {
+	return m.Exports.GetRef(CertificateRefCertificate)
+}
+
+// EndpointRef returns the value of the ClientVPNRefEndpoint reference export.
+func (m *ClientVPNCloudMetadata) EndpointRef() string {
+	return m.Exports.GetRef(ClientVPNRefEndpoint)
+}
+
+// LogGroupRef returns the value of the ClientVPNRefLogGroup reference export.
+func (m *ClientVPNCloudMetadata) LogGroupRef() string {
+	return m.Exports.GetRef(ClientVPNRefLogGroup)
+}
+
+// ClusterARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefCluster.
+func (m *ContainerServiceCloudMetadata) ClusterARN() string {
+	return m.Exports.GetAtt(ECSServiceRefCluster, ECSServiceAttARN)
+}
+
+// ClusterRef returns the value of the ECSServiceRefCluster reference export.
+func (m *ContainerServiceCloudMetadata) ClusterRef() string {
+	return m.Exports.GetRef(ECSServiceRefCluster)
+}
+
+// HealthCheckRef returns the value of the ECSServiceRefHealthCheck reference export.
+func (m *ContainerServiceCloudMetadata) HealthCheckRef() string {
+	return m.Exports.GetRef(ECSServiceRefHealthCheck)
+}
+
+// ListenerRuleARN returns the value of the ECSServiceAttRuleARN attribute export of ECSServiceRefListenerRule.
+func (m *ContainerServiceCloudMetadata) ListenerRuleARN() string {
+	return m.Exports.GetAtt(ECSServiceRefListenerRule, ECSServiceAttRuleARN)
+}
+
+// ListenerRuleRef returns the value of the ECSServiceRefListenerRule reference export.
+func (m *ContainerServiceCloudMetadata) ListenerRuleRef() string {
+	return m.Exports.GetRef(ECSServiceRefListenerRule)
+}
+
+// LogGroupARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefLogGroup.
+func (m *ContainerServiceCloudMetadata) LogGroupARN() string {
+	return m.Exports.GetAtt(ECSServiceRefLogGroup, ECSServiceAttARN)
+}
+
+// LogGroupRef returns the value of the ECSServiceRefLogGroup reference export.
+func (m *ContainerServiceCloudMetadata) LogGroupRef() string {
+	return m.Exports.GetRef(ECSServiceRefLogGroup)
+}
+
+// RecordSetRef returns the value of the ECSServiceRefRecordSet reference export.
+func (m *ContainerServiceCloudMetadata) RecordSetRef() string {
+	return m.Exports.GetRef(ECSServiceRefRecordSet)
+}
+
+// RoleExecutionARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefRoleExecution.
+func (m *ContainerServiceCloudMetadata) RoleExecutionARN() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleExecution, ECSServiceAttARN)
+}
+
+// RoleExecutionRef returns the value of the ECSServiceRefRoleExecution reference export.
+func (m *ContainerServiceCloudMetadata) RoleExecutionRef() string {
+	return m.Exports.GetRef(ECSServiceRefRoleExecution)
+}
+
+// RoleExecutionRoleID returns the value of the ECSServiceAttRoleID attribute export of ECSServiceRefRoleExecution.
+func (m *ContainerServiceCloudMetadata) RoleExecutionRoleID() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleExecution, ECSServiceAttRoleID)
+}
+
+// RoleTaskARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefRoleTask.
+func (m *ContainerServiceCloudMetadata) RoleTaskARN() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleTask, ECSServiceAttARN)
+}
+
+// RoleTaskRef returns the value of the ECSServiceRefRoleTask reference export.
+func (m *ContainerServiceCloudMetadata) RoleTaskRef() string {
+	return m.Exports.GetRef(ECSServiceRefRoleTask)
+}
+
+// RoleTaskRoleID returns the value of the ECSServiceAttRoleID attribute export of ECSServiceRefRoleTask.
+func (m *ContainerServiceCloudMetadata) RoleTaskRoleID() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleTask, ECSServiceAttRoleID)
+}
+
+// ServiceName returns the value of the ECSServiceAttName attribute export of ECSServiceRefService.
+func (m *ContainerServiceCloudMetadata) ServiceName() string {
+	return m.Exports.GetAtt(ECSServiceRefService, ECSServiceAttName)
+}
+
+// ServiceRef returns the value of the ECSServiceRefService reference export.
+func (m *ContainerServiceCloudMetadata) ServiceRef() string {
+	return m.Exports.GetRef(ECSServiceRefService)
+}
+
+// TargetGroupFullName returns the value of the ECSServiceAttTargetGroupFullName attribute export of ECSServiceRefTargetGroup.
+func (m *ContainerServiceCloudMetadata) TargetGroupFullName() string {
+	return m.Exports.GetAtt(ECSServiceRefTargetGroup, ECSServiceAttTargetGroupFullName)
+}
+
+// TargetGroupName returns the value of the ECSServiceAttTargetGroupName attribute export of ECSServiceRefTargetGroup.
+func (m *ContainerServiceCloudMetadata) TargetGroupName() string {
+	return m.Exports.GetAtt(ECSServiceRefTargetGroup, ECSServiceAttTargetGroupName)
+}
+
+// TargetGroupRef returns the value of the ECSServiceRefTargetGroup reference export.
+func (m *ContainerServiceCloudMetadata) TargetGroupRef() string {
+	return m.Exports.GetRef(ECSServiceRefTargetGroup)
+}
+
+// TaskDefinitionRef returns the value of the ECSServiceRefTaskDefinition reference export.
+func (m *ContainerServiceCloudMetadata) TaskDefinitionRef() string {
+	return m.Exports.GetRef(ECSServiceRefTaskDefinition)
+}
+
+// ClusterARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefCluster.
+func (m *CronJobCloudMetadata) ClusterARN() string {
+	return m.Exports.GetAtt(ECSServiceRefCluster, ECSServiceAttARN)
+}
+
+// ClusterRef returns the value of the ECSServiceRefCluster reference export.
+func (m *CronJobCloudMetadata) ClusterRef() string {
+	return m.Exports.GetRef(ECSServiceRefCluster)
+}
+
+// LogGroupARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefLogGroup.
+func (m *CronJobCloudMetadata) LogGroupARN() string {
+	return m.Exports.GetAtt(ECSServiceRefLogGroup, ECSServiceAttARN)
+}
+
+// LogGroupRef returns the value of the ECSServiceRefLogGroup reference export.
+func (m *CronJobCloudMetadata) LogGroupRef() string {
+	return m.Exports.GetRef(ECSServiceRefLogGroup)
+}
+
+// RoleEventsARN returns the value of the CronJobAttARN attribute export of CronJobRefRoleEvents.
+func (m *CronJobCloudMetadata) RoleEventsARN() string {
+	return m.Exports.GetAtt(CronJobRefRoleEvents, CronJobAttARN)
+}
+
+// RoleEventsRef returns the value of the CronJobRefRoleEvents reference export.
+func (m *CronJobCloudMetadata) RoleEventsRef() string {
+	return m.Exports.GetRef(CronJobRefRoleEvents)
+}
+
+// RoleExecutionARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefRoleExecution.
+func (m *CronJobCloudMetadata) RoleExecutionARN() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleExecution, ECSServiceAttARN)
+}
+
+// RoleExecutionRef returns the value of the ECSServiceRefRoleExecution reference export.
+func (m *CronJobCloudMetadata) RoleExecutionRef() string {
+	return m.Exports.GetRef(ECSServiceRefRoleExecution)
+}
+
+// RoleExecutionRoleID returns the value of the ECSServiceAttRoleID attribute export of ECSServiceRefRoleExecution.
+func (m *CronJobCloudMetadata) RoleExecutionRoleID() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleExecution, ECSServiceAttRoleID)
+}
+
+// RoleTaskARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefRoleTask.
+func (m *CronJobCloudMetadata) RoleTaskARN() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleTask, ECSServiceAttARN)
+}
+
+// RoleTaskRef returns the value of the ECSServiceRefRoleTask reference export.
+func (m *CronJobCloudMetadata) RoleTaskRef() string {
+	return m.Exports.GetRef(ECSServiceRefRoleTask)
+}
+
+// RoleTaskRoleID returns the value of the ECSServiceAttRoleID attribute export of ECSServiceRefRoleTask.
+func (m *CronJobCloudMetadata) RoleTaskRoleID() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleTask, ECSServiceAttRoleID)
+}
+
+// RuleARN returns the value of the CronJobAttARN attribute export of CronJobRefRule.
+func (m *CronJobCloudMetadata) RuleARN() string {
+	return m.Exports.GetAtt(CronJobRefRule, CronJobAttARN)
+}
+
+// RuleRef returns the value of the CronJobRefRule reference export.
+func (m *CronJobCloudMetadata) RuleRef() string {
+	return m.Exports.GetRef(CronJobRefRule)
+}
+
+// TaskDefinitionRef returns the value of the ECSServiceRefTaskDefinition reference export.
+func (m *CronJobCloudMetadata) TaskDefinitionRef() string {
+	return m.Exports.GetRef(ECSServiceRefTaskDefinition)
+}
+
+// ClusterARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefCluster.
+func (m *DataExportCloudMetadata) ClusterARN() string {
+	return m.Exports.GetAtt(ECSServiceRefCluster, ECSServiceAttARN)
+}
+
+// ClusterRef returns the value of the ECSServiceRefCluster reference export.
+func (m *DataExportCloudMetadata) ClusterRef() string {
+	return m.Exports.GetRef(ECSServiceRefCluster)
+}
+
+// LogGroupARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefLogGroup.
+func (m *DataExportCloudMetadata) LogGroupARN() string {
+	return m.Exports.GetAtt(ECSServiceRefLogGroup, ECSServiceAttARN)
+}
+
+// LogGroupRef returns the value of the ECSServiceRefLogGroup reference export.
+func (m *DataExportCloudMetadata) LogGroupRef() string {
+	return m.Exports.GetRef(ECSServiceRefLogGroup)
+}
+
+// RoleEventsARN returns the value of the DataExportAttARN attribute export of DataExportRefRoleEvents.
+func (m *DataExportCloudMetadata) RoleEventsARN() string {
+	return m.Exports.GetAtt(DataExportRefRoleEvents, DataExportAttARN)
+}
+
+// RoleEventsRef returns the value of the DataExportRefRoleEvents reference export.
+func (m *DataExportCloudMetadata) RoleEventsRef() string {
+	return m.Exports.GetRef(DataExportRefRoleEvents)
+}
+
+// RoleExecutionARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefRoleExecution.
+func (m *DataExportCloudMetadata) RoleExecutionARN() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleExecution, ECSServiceAttARN)
+}
+
+// RoleExecutionRef returns the value of the ECSServiceRefRoleExecution reference export.
+func (m *DataExportCloudMetadata) RoleExecutionRef() string {
+	return m.Exports.GetRef(ECSServiceRefRoleExecution)
+}
+
+// RoleExecutionRoleID returns the value of the ECSServiceAttRoleID attribute export of ECSServiceRefRoleExecution.
+func (m *DataExportCloudMetadata) RoleExecutionRoleID() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleExecution, ECSServiceAttRoleID)
+}
+
+// RoleTaskARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefRoleTask.
+func (m *DataExportCloudMetadata) RoleTaskARN() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleTask, ECSServiceAttARN)
+}
+
+// RoleTaskRef returns the value of the ECSServiceRefRoleTask reference export.
+func (m *DataExportCloudMetadata) RoleTaskRef() string {
+	return m.Exports.GetRef(ECSServiceRefRoleTask)
+}
+
+// RoleTaskRoleID returns the value of the ECSServiceAttRoleID attribute export of ECSServiceRefRoleTask.
+func (m *DataExportCloudMetadata) RoleTaskRoleID() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleTask, ECSServiceAttRoleID)
+}
+
+// RuleARN returns the value of the DataExportAttARN attribute export of DataExportRefRule.
+func (m *DataExportCloudMetadata) RuleARN() string {
+	return m.Exports.GetAtt(DataExportRefRule, DataExportAttARN)
+}
+
+// RuleRef returns the value of the DataExportRefRule reference export.
+func (m *DataExportCloudMetadata) RuleRef() string {
+	return m.Exports.GetRef(DataExportRefRule)
+}
+
+// TaskDefinitionRef returns the value of the ECSServiceRefTaskDefinition reference export.
+func (m *DataExportCloudMetadata) TaskDefinitionRef() string {
+	return m.Exports.GetRef(ECSServiceRefTaskDefinition)
+}
+
+// FileSystemARN returns the value of the EFSAttARN attribute export of EFSRefFileSystem.
+func (m *EFSCloudMetadata) FileSystemARN() string {
+	return m.Exports.GetAtt(EFSRefFileSystem, EFSAttARN)
+}
+
+// FileSystemRef returns the value of the EFSRefFileSystem reference export.
+func (m *EFSCloudMetadata) FileSystemRef() string {
+	return m.Exports.GetRef(EFSRefFileSystem)
+}
+
+// FunctionARN returns the value of the EdgeFunctionAttFunctionARN attribute export of EdgeFunctionRefFunction.
+func (m *EdgeFunctionCloudMetadata) FunctionARN() string {
+	return m.Exports.GetAtt(EdgeFunctionRefFunction, EdgeFunctionAttFunctionARN)
+}
+
+// FunctionRef returns the value of the EdgeFunctionRefFunction reference export.
+func (m *EdgeFunctionCloudMetadata) FunctionRef() string {
+	return m.Exports.GetRef(EdgeFunctionRefFunction)
+}
+
+// FunctionARN returns the value of the FunctionAttARN attribute export of FunctionRefFunction.
+func (m *FunctionCloudMetadata) FunctionARN() string {
+	return m.Exports.GetAtt(FunctionRefFunction, FunctionAttARN)
+}
+
+// FunctionRef returns the value of the FunctionRefFunction reference export.
+func (m *FunctionCloudMetadata) FunctionRef() string {
+	return m.Exports.GetRef(FunctionRefFunction)
+}
+
+// LogGroupARN returns the value of the FunctionAttARN attribute export of FunctionRefLogGroup.
+func (m *FunctionCloudMetadata) LogGroupARN() string {
+	return m.Exports.GetAtt(FunctionRefLogGroup, FunctionAttARN)
+}
+
+// LogGroupRef returns the value of the FunctionRefLogGroup reference export.
+func (m *FunctionCloudMetadata) LogGroupRef() string {
+	return m.Exports.GetRef(FunctionRefLogGroup)
+}
+
+// RoleARN returns the value of the FunctionAttARN attribute export of FunctionRefRole.
+func (m *FunctionCloudMetadata) RoleARN() string {
+	return m.Exports.GetAtt(FunctionRefRole, FunctionAttARN)
+}
+
+// RoleID returns the value of the FunctionAttRoleID attribute export of FunctionRefRole.
+func (m *FunctionCloudMetadata) RoleID() string {
+	return m.Exports.GetAtt(FunctionRefRole, FunctionAttRoleID)
+}
+
+// RoleRef returns the value of the FunctionRefRole reference export.
+func (m *FunctionCloudMetadata) RoleRef() string {
+	return m.Exports.GetRef(FunctionRefRole)
+}
+
+// WarmUpRuleARN returns the value of the FunctionAttARN attribute export of FunctionRefWarmUpRule.
+func (m *FunctionCloudMetadata) WarmUpRuleARN() string {
+	return m.Exports.GetAtt(FunctionRefWarmUpRule, FunctionAttARN)
+}
+
+// WarmUpRuleRef returns the value of the FunctionRefWarmUpRule reference export.
+func (m *FunctionCloudMetadata) WarmUpRuleRef() string {
+	return m.Exports.GetRef(FunctionRefWarmUpRule)
+}
+
+// ClusterARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefCluster.
+func (m *HasuraCloudMetadata) ClusterARN() string {
+	return m.Exports.GetAtt(ECSServiceRefCluster, ECSServiceAttARN)
+}
+
+// ClusterRef returns the value of the ECSServiceRefCluster reference export.
+func (m *HasuraCloudMetadata) ClusterRef() string {
+	return m.Exports.GetRef(ECSServiceRefCluster)
+}
+
+// HealthCheckRef returns the value of the ECSServiceRefHealthCheck reference export.
+func (m *HasuraCloudMetadata) HealthCheckRef() string {
+	return m.Exports.GetRef(ECSServiceRefHealthCheck)
+}
+
+// ListenerRuleARN returns the value of the ECSServiceAttRuleARN attribute export of ECSServiceRefListenerRule.
+func (m *HasuraCloudMetadata) ListenerRuleARN() string {
+	return m.Exports.GetAtt(ECSServiceRefListenerRule, ECSServiceAttRuleARN)
+}
+
+// ListenerRuleRef returns the value of the ECSServiceRefListenerRule reference export.
+func (m *HasuraCloudMetadata) ListenerRuleRef() string {
+	return m.Exports.GetRef(ECSServiceRefListenerRule)
+}
+
+// LogGroupARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefLogGroup.
+func (m *HasuraCloudMetadata) LogGroupARN() string {
+	return m.Exports.GetAtt(ECSServiceRefLogGroup, ECSServiceAttARN)
+}
+
+// LogGroupRef returns the value of the ECSServiceRefLogGroup reference export.
+func (m *HasuraCloudMetadata) LogGroupRef() string {
+	return m.Exports.GetRef(ECSServiceRefLogGroup)
+}
+
+// RecordSetRef returns the value of the ECSServiceRefRecordSet reference export.
+func (m *HasuraCloudMetadata) RecordSetRef() string {
+	return m.Exports.GetRef(ECSServiceRefRecordSet)
+}
+
+// RoleExecutionARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefRoleExecution.
+func (m *HasuraCloudMetadata) RoleExecutionARN() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleExecution, ECSServiceAttARN)
+}
+
+// RoleExecutionRef returns the value of the ECSServiceRefRoleExecution reference export.
+func (m *HasuraCloudMetadata) RoleExecutionRef() string {
+	return m.Exports.GetRef(ECSServiceRefRoleExecution)
+}
+
+// RoleExecutionRoleID returns the value of the ECSServiceAttRoleID attribute export of ECSServiceRefRoleExecution.
+func (m *HasuraCloudMetadata) RoleExecutionRoleID() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleExecution, ECSServiceAttRoleID)
+}
+
+// RoleTaskARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefRoleTask.
+func (m *HasuraCloudMetadata) RoleTaskARN() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleTask, ECSServiceAttARN)
+}
+
+// RoleTaskRef returns the value of the ECSServiceRefRoleTask reference export.
+func (m *HasuraCloudMetadata) RoleTaskRef() string {
+	return m.Exports.GetRef(ECSServiceRefRoleTask)
+}
+
+// RoleTaskRoleID returns the value of the ECSServiceAttRoleID attribute export of ECSServiceRefRoleTask.
+func (m *HasuraCloudMetadata) RoleTaskRoleID() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleTask, ECSServiceAttRoleID)
+}
+
+// ServiceName returns the value of the ECSServiceAttName attribute export of ECSServiceRefService.
+func (m *HasuraCloudMetadata) ServiceName() string {
+	return m.Exports.GetAtt(ECSServiceRefService, ECSServiceAttName)
+}
+
+// ServiceRef returns the value of the ECSServiceRefService reference export.
+func (m *HasuraCloudMetadata) ServiceRef() string {
+	return m.Exports.GetRef(ECSServiceRefService)
+}
+
+// TargetGroupFullName returns the value of the ECSServiceAttTargetGroupFullName attribute export of ECSServiceRefTargetGroup.
+func (m *HasuraCloudMetadata) TargetGroupFullName() string {
+	return m.Exports.GetAtt(ECSServiceRefTargetGroup, ECSServiceAttTargetGroupFullName)
+}
+
+// TargetGroupName returns the value of the ECSServiceAttTargetGroupName attribute export of ECSServiceRefTargetGroup.
+func (m *HasuraCloudMetadata) TargetGroupName() string {
+	return m.Exports.GetAtt(ECSServiceRefTargetGroup, ECSServiceAttTargetGroupName)
+}
+
+// TargetGroupRef returns the value of the ECSServiceRefTargetGroup reference export.
+func (m *HasuraCloudMetadata) TargetGroupRef() string {
+	return m.Exports.GetRef(ECSServiceRefTargetGroup)
+}
+
+// TaskDefinitionRef returns the value of the ECSServiceRefTaskDefinition reference export.
+func (m *HasuraCloudMetadata) TaskDefinitionRef() string {
+	return m.Exports.GetRef(ECSServiceRefTaskDefinition)
+}
+
+// HostedZoneRef returns the value of the HostedZoneRefHostedZone reference export.
+func (m *HostedZoneCloudMetadata) HostedZoneRef() string {
+	return m.Exports.GetRef(HostedZoneRefHostedZone)
+}
+
+// RepositoryARN returns the value of the ImageRepositoryAttARN attribute export of ImageRepositoryRefRepository.
+func (m *ImageRepositoryCloudMetadata) RepositoryARN() string {
+	return m.Exports.GetAtt(ImageRepositoryRefRepository, ImageRepositoryAttARN)
+}
+
+// RepositoryRef returns the value of the ImageRepositoryRefRepository reference export.
+func (m *ImageRepositoryCloudMetadata) RepositoryRef() string {
+	return m.Exports.GetRef(ImageRepositoryRefRepository)
+}
+
+// RepositoryURI returns the value of the ImageRepositoryAttRepositoryURI attribute export of ImageRepositoryRefRepository.
+func (m *ImageRepositoryCloudMetadata) RepositoryURI() string {
+	return m.Exports.GetAtt(ImageRepositoryRefRepository, ImageRepositoryAttRepositoryURI)
+}
+
+// ClusterRef returns the value of the KafkaRefCluster reference export.
+func (m *KafkaCloudMetadata) ClusterRef() string {
+	return m.Exports.GetRef(KafkaRefCluster)
+}
+
+// ClusterARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefCluster.
+func (m *KeycloakCloudMetadata) ClusterARN() string {
+	return m.Exports.GetAtt(ECSServiceRefCluster, ECSServiceAttARN)
+}
+
+// ClusterRef returns the value of the ECSServiceRefCluster reference export.
+func (m *KeycloakCloudMetadata) ClusterRef() string {
+	return m.Exports.GetRef(ECSServiceRefCluster)
+}
+
+// HealthCheckRef returns the value of the ECSServiceRefHealthCheck reference export.
+func (m *KeycloakCloudMetadata) HealthCheckRef() string {
+	return m.Exports.GetRef(ECSServiceRefHealthCheck)
+}
+
+// ListenerRuleARN returns the value of the ECSServiceAttRuleARN attribute export of ECSServiceRefListenerRule.
+func (m *KeycloakCloudMetadata) ListenerRuleARN() string {
+	return m.Exports.GetAtt(ECSServiceRefListenerRule, ECSServiceAttRuleARN)
+}
+
+// ListenerRuleRef returns the value of the ECSServiceRefListenerRule reference export.
+func (m *KeycloakCloudMetadata) ListenerRuleRef() string {
+	return m.Exports.GetRef(ECSServiceRefListenerRule)
+}
+
+// LogGroupARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefLogGroup.
+func (m *KeycloakCloudMetadata) LogGroupARN() string {
+	return m.Exports.GetAtt(ECSServiceRefLogGroup, ECSServiceAttARN)
+}
+
+// LogGroupRef returns the value of the ECSServiceRefLogGroup reference export.
+func (m *KeycloakCloudMetadata) LogGroupRef() string {
+	return m.Exports.GetRef(ECSServiceRefLogGroup)
+}
+
+// RecordSetRef returns the value of the ECSServiceRefRecordSet reference export.
+func (m *KeycloakCloudMetadata) RecordSetRef() string {
+	return m.Exports.GetRef(ECSServiceRefRecordSet)
+}
+
+// RoleExecutionARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefRoleExecution.
+func (m *KeycloakCloudMetadata) RoleExecutionARN() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleExecution, ECSServiceAttARN)
+}
+
+// RoleExecutionRef returns the value of the ECSServiceRefRoleExecution reference export.
+func (m *KeycloakCloudMetadata) RoleExecutionRef() string {
+	return m.Exports.GetRef(ECSServiceRefRoleExecution)
+}
+
+// RoleExecutionRoleID returns the value of the ECSServiceAttRoleID attribute export of ECSServiceRefRoleExecution.
+func (m *KeycloakCloudMetadata) RoleExecutionRoleID() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleExecution, ECSServiceAttRoleID)
+}
+
+// RoleTaskARN returns the value of the ECSServiceAttARN attribute export of ECSServiceRefRoleTask.
+func (m *KeycloakCloudMetadata) RoleTaskARN() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleTask, ECSServiceAttARN)
+}
+
+// RoleTaskRef returns the value of the ECSServiceRefRoleTask reference export.
+func (m *KeycloakCloudMetadata) RoleTaskRef() string {
+	return m.Exports.GetRef(ECSServiceRefRoleTask)
+}
+
+// RoleTaskRoleID returns the value of the ECSServiceAttRoleID attribute export of ECSServiceRefRoleTask.
+func (m *KeycloakCloudMetadata) RoleTaskRoleID() string {
+	return m.Exports.GetAtt(ECSServiceRefRoleTask, ECSServiceAttRoleID)
+}
+
+// ServiceName returns the value of the ECSServiceAttName attribute export of ECSServiceRefService.
+func (m *KeycloakCloudMetadata) ServiceName() string {
+	return m.Exports.GetAtt(ECSServiceRefService, ECSServiceAttName)
+}
+
+// ServiceRef returns the value of the ECSServiceRefService reference export.
+func (m *KeycloakCloudMetadata) ServiceRef() string {
+	return m.Exports.GetRef(ECSServiceRefService)
+}
+
+// TargetGroupFullName returns the value of the ECSServiceAttTargetGroupFullName attribute export of ECSServiceRefTargetGroup.
+func (m *KeycloakCloudMetadata) TargetGroupFullName() string {
+	return m.Exports.GetAtt(ECSServiceRefTargetGroup, ECSServiceAttTargetGroupFullName)
+}
+
+// TargetGroupName returns the value of the ECSServiceAttTargetGroupName attribute export of ECSServiceRefTargetGroup.
+func (m *KeycloakCloudMetadata) TargetGroupName() string {
+	return m.Exports.GetAtt(ECSServiceRefTargetGroup, ECSServiceAttTargetGroupName)
+}
+
+// TargetGroupRef returns the value of the ECSServiceRefTargetGroup reference export.
+func (m *KeycloakCloudMetadata) TargetGroupRef() string {
+	return m.Exports.GetRef(ECSServiceRefTargetGroup)
+}
+
+// TaskDefinitionRef returns the value of the ECSServiceRefTaskDefinition reference export.
+func (m *KeycloakCloudMetadata) TaskDefinitionRef() string {
+	return m.Exports.GetRef(ECSServiceRefTaskDefinition)
+}
+
+// ListenerHTTPListenerArn returns the value of the LoadBalancerAttListenerArn attribute export of LoadBalancerRefListenerHTTP.
+func (m *LoadBalancerCloudMetadata) ListenerHTTPListenerArn() string {
+	return m.Exports.GetAtt(LoadBalancerRefListenerHTTP, LoadBalancerAttListenerArn)
+}
+
+// ListenerHTTPRef returns the value of the LoadBalancerRefListenerHTTP reference export.
+func (m *LoadBalancerCloudMetadata) ListenerHTTPRef() string {
+	return m.Exports.GetRef(LoadBalancerRefListenerHTTP)
+}
+
+// ListenerHTTPSListenerArn returns the value of the LoadBalancerAttListenerArn attribute export of LoadBalancerRefListenerHTTPS.
+func (m *LoadBalancerCloudMetadata) ListenerHTTPSListenerArn() string {
+	return m.Exports.GetAtt(LoadBalancerRefListenerHTTPS, LoadBalancerAttListenerArn)
+}
+
+// ListenerHTTPSRef returns the value of the LoadBalancerRefListenerHTTPS reference export.
+func (m *LoadBalancerCloudMetadata) ListenerHTTPSRef() string {
+	return m.Exports.GetRef(LoadBalancerRefListenerHTTPS)
+}
+
+// LoadBalancerCanonicalHostedZoneID returns the value of the LoadBalancerAttCanonicalHostedZoneID attribute export of LoadBalancerRefLoadBalancer.
+func (m *LoadBalancerCloudMetadata) LoadBalancerCanonicalHostedZoneID() string {
+	return m.Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttCanonicalHostedZoneID)
+}
+
+// LoadBalancerDNSName returns the value of the LoadBalancerAttDNSName attribute export of LoadBalancerRefLoadBalancer.
+func (m *LoadBalancerCloudMetadata) LoadBalancerDNSName() string {
+	return m.Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttDNSName)
+}
+
+// LoadBalancerFullName returns the value of the LoadBalancerAttLoadBalancerFullName attribute export of LoadBalancerRefLoadBalancer.
+func (m *LoadBalancerCloudMetadata) LoadBalancerFullName() string {
+	return m.Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttLoadBalancerFullName)
+}
+
+// LoadBalancerName returns the value of the LoadBalancerAttLoadBalancerName attribute export of LoadBalancerRefLoadBalancer.
+func (m *LoadBalancerCloudMetadata) LoadBalancerName() string {
+	return m.Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttLoadBalancerName)
+}
+
+// LoadBalancerRef returns the value of the LoadBalancerRefLoadBalancer reference export.
+func (m *LoadBalancerCloudMetadata) LoadBalancerRef() string {
+	return m.Exports.GetRef(LoadBalancerRefLoadBalancer)
+}
+
+// ConfigurationSetRef returns the value of the MailRefConfigurationSet reference export.
+func (m *MailCloudMetadata) ConfigurationSetRef() string {
+	return m.Exports.GetRef(MailRefConfigurationSet)
+}
+
+// EmailIdentityRef returns the value of the MailRefEmailIdentity reference export.
+func (m *MailCloudMetadata) EmailIdentityRef() string {
+	return m.Exports.GetRef(MailRefEmailIdentity)
+}
+
+// EIPAAllocationID returns the value of the NetworkAttAllocationID attribute export of NetworkRefEIPA.
+func (m *NetworkCloudMetadata) EIPAAllocationID() string {
+	return m.Exports.GetAtt(NetworkRefEIPA, NetworkAttAllocationID)
+}
+
+// EIPARef returns the value of the NetworkRefEIPA reference export.
+func (m *NetworkCloudMetadata) EIPARef() string {
+	return m.Exports.GetRef(NetworkRefEIPA)
+}
+
+// EIPBAllocationID returns the value of the NetworkAttAllocationID attribute export of NetworkRefEIPB.
+func (m *NetworkCloudMetadata) EIPBAllocationID() string {
+	return m.Exports.GetAtt(NetworkRefEIPB, NetworkAttAllocationID)
+}
+
+// EIPBRef returns the value of the NetworkRefEIPB reference export.
+func (m *NetworkCloudMetadata) EIPBRef() string {
+	return m.Exports.GetRef(NetworkRefEIPB)
+}
+
+// EndpointSecurityGroupID returns the value of the NetworkAttGroupID attribute export of NetworkRefEndpointSecurityGroup.
+func (m *NetworkCloudMetadata) EndpointSecurityGroupID() string {
+	return m.Exports.GetAtt(NetworkRefEndpointSecurityGroup, NetworkAttGroupID)
+}
+
+// EndpointSecurityGroupRef returns the value of the NetworkRefEndpointSecurityGroup reference export.
+func (m *NetworkCloudMetadata) EndpointSecurityGroupRef() string {
+	return m.Exports.GetRef(NetworkRefEndpointSecurityGroup)
+}
+
+// InternetGatewayID returns the value of the NetworkAttInternetGatewayID attribute export of NetworkRefInternetGateway.
+func (m *NetworkCloudMetadata) InternetGatewayID() string {
+	return m.Exports.GetAtt(NetworkRefInternetGateway, NetworkAttInternetGatewayID)
+}
+
+// InternetGatewayRef returns the value of the NetworkRefInternetGateway reference export.
+func (m *NetworkCloudMetadata) InternetGatewayRef() string {
+	return m.Exports.GetRef(NetworkRefInternetGateway)
+}
+
+// NATGatewayARef returns the value of the NetworkRefNATGatewayA reference export.
+func (m *NetworkCloudMetadata) NATGatewayARef() string {
+	return m.Exports.GetRef(NetworkRefNATGatewayA)
+}
+
+// NATGatewayBRef returns the value of the NetworkRefNATGatewayB reference export.
+func (m *NetworkCloudMetadata) NATGatewayBRef() string {
+	return m.Exports.GetRef(NetworkRefNATGatewayB)
+}
+
+// NATInstanceAutoScalingGroupRef returns the value of the NetworkRefNATInstanceAutoScalingGroup reference export.
+func (m *NetworkCloudMetadata) NATInstanceAutoScalingGroupRef() string {
+	return m.Exports.GetRef(NetworkRefNATInstanceAutoScalingGroup)
+}
+
+// NATInstanceLaunchTemplateLatestVersionNumber returns the value of the NetworkAttLatestVersionNumber attribute export of NetworkRefNATInstanceLaunchTemplate.
+func (m *NetworkCloudMetadata) NATInstanceLaunchTemplateLatestVersionNumber() string {
+	return m.Exports.GetAtt(NetworkRefNATInstanceLaunchTemplate, NetworkAttLatestVersionNumber)
+}
+
+// NATInstanceLaunchTemplateRef returns the value of the NetworkRefNATInstanceLaunchTemplate reference export.
+func (m *NetworkCloudMetadata) NATInstanceLaunchTemplateRef() string {
+	return m.Exports.GetRef(NetworkRefNATInstanceLaunchTemplate)
+}
+
+// NATInstanceNetworkInterfaceRef returns the value of the NetworkRefNATInstanceNetworkInterface reference export.
+func (m *NetworkCloudMetadata) NATInstanceNetworkInterfaceRef() string {
+	return m.Exports.GetRef(NetworkRefNATInstanceNetworkInterface)
+}
+
+// NATInstanceProfileARN returns the value of the NetworkAttARN attribute export of NetworkRefNATInstanceProfile.
+func (m *NetworkCloudMetadata) NATInstanceProfileARN() string {
+	return m.Exports.GetAtt(NetworkRefNATInstanceProfile, NetworkAttARN)
+}
+
+// NATInstanceProfileRef returns the value of the NetworkRefNATInstanceProfile reference export.
+func (m *NetworkCloudMetadata) NATInstanceProfileRef() string {
+	return m.Exports.GetRef(NetworkRefNATInstanceProfile)
+}
+
+// NATInstanceRoleARN returns the value of the NetworkAttARN attribute export of NetworkRefNATInstanceRole.
+func (m *NetworkCloudMetadata) NATInstanceRoleARN() string {
+	return m.Exports.GetAtt(NetworkRefNATInstanceRole, NetworkAttARN)
+}
+
+// NATInstanceRoleRef returns the value of the NetworkRefNATInstanceRole reference export.
+func (m *NetworkCloudMetadata) NATInstanceRoleRef() string {
+	return m.Exports.GetRef(NetworkRefNATInstanceRole)
+}
+
+// NATInstanceSecurityGroupID returns the value of the NetworkAttGroupID attribute export of NetworkRefNATInstanceSecurityGroup.
+func (m *NetworkCloudMetadata) NATInstanceSecurityGroupID() string {
+	return m.Exports.GetAtt(NetworkRefNATInstanceSecurityGroup, NetworkAttGroupID)
+}
+
+// NATInstanceSecurityGroupRef returns the value of the NetworkRefNATInstanceSecurityGroup reference export.
+func (m *NetworkCloudMetadata) NATInstanceSecurityGroupRef() string {
+	return m.Exports.GetRef(NetworkRefNATInstanceSecurityGroup)
+}
+
+// RoutePrivateARef returns the value of the NetworkRefRoutePrivateA reference export.
+func (m *NetworkCloudMetadata) RoutePrivateARef() string {
+	return m.Exports.GetRef(NetworkRefRoutePrivateA)
+}
+
+// RoutePrivateBRef returns the value of the NetworkRefRoutePrivateB reference export.
+func (m *NetworkCloudMetadata) RoutePrivateBRef() string {
+	return m.Exports.GetRef(NetworkRefRoutePrivateB)
+}
+
+// RoutePublicRef returns the value of the NetworkRefRoutePublic reference export.
+func (m *NetworkCloudMetadata) RoutePublicRef() string {
+	return m.Exports.GetRef(NetworkRefRoutePublic)
+}
+
+// RouteTablePrivateARef returns the value of the NetworkRefRouteTablePrivateA reference export.
+func (m *NetworkCloudMetadata) RouteTablePrivateARef() string {
+	return m.Exports.GetRef(NetworkRefRouteTablePrivateA)
+}
+
+// RouteTablePrivateARouteTableID returns the value of the NetworkAttRouteTableID attribute export of NetworkRefRouteTablePrivateA.
+func (m *NetworkCloudMetadata) RouteTablePrivateARouteTableID() string {
+	return m.Exports.GetAtt(NetworkRefRouteTablePrivateA, NetworkAttRouteTableID)
+}
+
+// RouteTablePrivateBRef returns the value of the NetworkRefRouteTablePrivateB reference export.
+func (m *NetworkCloudMetadata) RouteTablePrivateBRef() string {
+	return m.Exports.GetRef(NetworkRefRouteTablePrivateB)
+}
+
+// RouteTablePrivateBRouteTableID returns the value of the NetworkAttRouteTableID attribute export of NetworkRefRouteTablePrivateB.
+func (m *NetworkCloudMetadata) RouteTablePrivateBRouteTableID() string {
+	return m.Exports.GetAtt(NetworkRefRouteTablePrivateB, NetworkAttRouteTableID)
+}
+
+// RouteTablePublicRef returns the value of the NetworkRefRouteTablePublic reference export.
+func (m *NetworkCloudMetadata) RouteTablePublicRef() string {
+	return m.Exports.GetRef(NetworkRefRouteTablePublic)
+}
+
+// RouteTablePublicRouteTableID returns the value of the NetworkAttRouteTableID attribute export of NetworkRefRouteTablePublic.
+func (m *NetworkCloudMetadata) RouteTablePublicRouteTableID() string {
+	return m.Exports.GetAtt(NetworkRefRouteTablePublic, NetworkAttRouteTableID)
+}
+
+// SecurityGroupID returns the value of the NetworkAttGroupID attribute export of NetworkRefSecurityGroup.
+func (m *NetworkCloudMetadata) SecurityGroupID() string {
+	return m.Exports.GetAtt(NetworkRefSecurityGroup, NetworkAttGroupID)
+}
+
+// SecurityGroupRef returns the value of the NetworkRefSecurityGroup reference export.
+func (m *NetworkCloudMetadata) SecurityGroupRef() string {
+	return m.Exports.GetRef(NetworkRefSecurityGroup)
+}
+
+// SecurityGroupVPCID returns the value of the NetworkAttVPCID attribute export of NetworkRefSecurityGroup.
+func (m *NetworkCloudMetadata) SecurityGroupVPCID() string {
+	return m.Exports.GetAtt(NetworkRefSecurityGroup, NetworkAttVPCID)
+}
+
+// ServiceConnectNamespaceARN returns the value of the NetworkAttARN attribute export of NetworkRefServiceConnectNamespace.
+func (m *NetworkCloudMetadata) ServiceConnectNamespaceARN() string {
+	return m.Exports.GetAtt(NetworkRefServiceConnectNamespace, NetworkAttARN)
+}
+
+// ServiceConnectNamespaceRef returns the value of the NetworkRefServiceConnectNamespace reference export.
+func (m *NetworkCloudMetadata) ServiceConnectNamespaceRef() string {
+	return m.Exports.GetRef(NetworkRefServiceConnectNamespace)
+}
+
+// SubnetPrivateANetworkACLAssociationID returns the value of the NetworkAttNetworkACLAssociationID attribute export of NetworkRefSubnetPrivateA.
+func (m *NetworkCloudMetadata) SubnetPrivateANetworkACLAssociationID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetPrivateA, NetworkAttNetworkACLAssociationID)
+}
+
+// SubnetPrivateARef returns the value of the NetworkRefSubnetPrivateA reference export.
+func (m *NetworkCloudMetadata) SubnetPrivateARef() string {
+	return m.Exports.GetRef(NetworkRefSubnetPrivateA)
+}
+
+// SubnetPrivateASubnetID returns the value of the NetworkAttSubnetID attribute export of NetworkRefSubnetPrivateA.
+func (m *NetworkCloudMetadata) SubnetPrivateASubnetID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetPrivateA, NetworkAttSubnetID)
+}
+
+// SubnetPrivateAVPCID returns the value of the NetworkAttVPCID attribute export of NetworkRefSubnetPrivateA.
+func (m *NetworkCloudMetadata) SubnetPrivateAVPCID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetPrivateA, NetworkAttVPCID)
+}
+
+// SubnetPrivateBNetworkACLAssociationID returns the value of the NetworkAttNetworkACLAssociationID attribute export of NetworkRefSubnetPrivateB.
+func (m *NetworkCloudMetadata) SubnetPrivateBNetworkACLAssociationID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetPrivateB, NetworkAttNetworkACLAssociationID)
+}
+
+// SubnetPrivateBRef returns the value of the NetworkRefSubnetPrivateB reference export.
+func (m *NetworkCloudMetadata) SubnetPrivateBRef() string {
+	return m.Exports.GetRef(NetworkRefSubnetPrivateB)
+}
+
+// SubnetPrivateBSubnetID returns the value of the NetworkAttSubnetID attribute export of NetworkRefSubnetPrivateB.
+func (m *NetworkCloudMetadata) SubnetPrivateBSubnetID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetPrivateB, NetworkAttSubnetID)
+}
+
+// SubnetPrivateBVPCID returns the value of the NetworkAttVPCID attribute export of NetworkRefSubnetPrivateB.
+func (m *NetworkCloudMetadata) SubnetPrivateBVPCID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetPrivateB, NetworkAttVPCID)
+}
+
+// SubnetPublicANetworkACLAssociationID returns the value of the NetworkAttNetworkACLAssociationID attribute export of NetworkRefSubnetPublicA.
+func (m *NetworkCloudMetadata) SubnetPublicANetworkACLAssociationID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetPublicA, NetworkAttNetworkACLAssociationID)
+}
+
+// SubnetPublicARef returns the value of the NetworkRefSubnetPublicA reference export.
+func (m *NetworkCloudMetadata) SubnetPublicARef() string {
+	return m.Exports.GetRef(NetworkRefSubnetPublicA)
+}
+
+// SubnetPublicASubnetID returns the value of the NetworkAttSubnetID attribute export of NetworkRefSubnetPublicA.
+func (m *NetworkCloudMetadata) SubnetPublicASubnetID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetPublicA, NetworkAttSubnetID)
+}
+
+// SubnetPublicAVPCID returns the value of the NetworkAttVPCID attribute export of NetworkRefSubnetPublicA.
+func (m *NetworkCloudMetadata) SubnetPublicAVPCID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetPublicA, NetworkAttVPCID)
+}
+
+// SubnetPublicBNetworkACLAssociationID returns the value of the NetworkAttNetworkACLAssociationID attribute export of NetworkRefSubnetPublicB.
+func (m *NetworkCloudMetadata) SubnetPublicBNetworkACLAssociationID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetPublicB, NetworkAttNetworkACLAssociationID)
+}
+
+// SubnetPublicBRef returns the value of the NetworkRefSubnetPublicB reference export.
+func (m *NetworkCloudMetadata) SubnetPublicBRef() string {
+	return m.Exports.GetRef(NetworkRefSubnetPublicB)
+}
+
+// SubnetPublicBSubnetID returns the value of the NetworkAttSubnetID attribute export of NetworkRefSubnetPublicB.
+func (m *NetworkCloudMetadata) SubnetPublicBSubnetID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetPublicB, NetworkAttSubnetID)
+}
+
+// SubnetPublicBVPCID returns the value of the NetworkAttVPCID attribute export of NetworkRefSubnetPublicB.
+func (m *NetworkCloudMetadata) SubnetPublicBVPCID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetPublicB, NetworkAttVPCID)
+}
+
+// SubnetRouteTableAssociationPrivateAID returns the value of the NetworkAttID attribute export of NetworkRefSubnetRouteTableAssociationPrivateA.
+func (m *NetworkCloudMetadata) SubnetRouteTableAssociationPrivateAID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetRouteTableAssociationPrivateA, NetworkAttID)
+}
+
+// SubnetRouteTableAssociationPrivateARef returns the value of the NetworkRefSubnetRouteTableAssociationPrivateA reference export.
+func (m *NetworkCloudMetadata) SubnetRouteTableAssociationPrivateARef() string {
+	return m.Exports.GetRef(NetworkRefSubnetRouteTableAssociationPrivateA)
+}
+
+// SubnetRouteTableAssociationPrivateBID returns the value of the NetworkAttID attribute export of NetworkRefSubnetRouteTableAssociationPrivateB.
+func (m *NetworkCloudMetadata) SubnetRouteTableAssociationPrivateBID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetRouteTableAssociationPrivateB, NetworkAttID)
+}
+
+// SubnetRouteTableAssociationPrivateBRef returns the value of the NetworkRefSubnetRouteTableAssociationPrivateB reference export.
+func (m *NetworkCloudMetadata) SubnetRouteTableAssociationPrivateBRef() string {
+	return m.Exports.GetRef(NetworkRefSubnetRouteTableAssociationPrivateB)
+}
+
+// SubnetRouteTableAssociationPublicAID returns the value of the NetworkAttID attribute export of NetworkRefSubnetRouteTableAssociationPublicA.
+func (m *NetworkCloudMetadata) SubnetRouteTableAssociationPublicAID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetRouteTableAssociationPublicA, NetworkAttID)
+}
+
+// SubnetRouteTableAssociationPublicARef returns the value of the NetworkRefSubnetRouteTableAssociationPublicA reference export.
+func (m *NetworkCloudMetadata) SubnetRouteTableAssociationPublicARef() string {
+	return m.Exports.GetRef(NetworkRefSubnetRouteTableAssociationPublicA)
+}
+
+// SubnetRouteTableAssociationPublicBID returns the value of the NetworkAttID attribute export of NetworkRefSubnetRouteTableAssociationPublicB.
+func (m *NetworkCloudMetadata) SubnetRouteTableAssociationPublicBID() string {
+	return m.Exports.GetAtt(NetworkRefSubnetRouteTableAssociationPublicB, NetworkAttID)
+}
+
+// SubnetRouteTableAssociationPublicBRef returns the value of the NetworkRefSubnetRouteTableAssociationPublicB reference export.
+func (m *NetworkCloudMetadata) SubnetRouteTableAssociationPublicBRef() string {
+	return m.Exports.GetRef(NetworkRefSubnetRouteTableAssociationPublicB)
+}
+
+// VPCDefaultNetworkACL returns the value of the NetworkAttDefaultNetworkACL attribute export of NetworkRefVPC.
+func (m *NetworkCloudMetadata) VPCDefaultNetworkACL() string {
+	return m.Exports.GetAtt(NetworkRefVPC, NetworkAttDefaultNetworkACL)
+}
+
+// VPCDefaultSecurityGroup returns the value of the NetworkAttDefaultSecurityGroup attribute export of NetworkRefVPC.
+func (m *NetworkCloudMetadata) VPCDefaultSecurityGroup() string {
+	return m.Exports.GetAtt(NetworkRefVPC, NetworkAttDefaultSecurityGroup)
+}
+
+// VPCGatewayAttachmentRef returns the value of the NetworkRefVPCGatewayAttachment reference export.
+func (m *NetworkCloudMetadata) VPCGatewayAttachmentRef() string {
+	return m.Exports.GetRef(NetworkRefVPCGatewayAttachment)
+}
+
+// VPCIDRBlock returns the value of the NetworkAttCIDRBlock attribute export of NetworkRefVPC.
+func (m *NetworkCloudMetadata) VPCIDRBlock() string {
+	return m.Exports.GetAtt(NetworkRefVPC, NetworkAttCIDRBlock)
+}
+
+// VPCRef returns the value of the NetworkRefVPC reference export.
+func (m *NetworkCloudMetadata) VPCRef() string {
+	return m.Exports.GetRef(NetworkRefVPC)
+}
+
+// GrafanaRoleARN returns the value of the ObservabilityAttARN attribute export of ObservabilityRefGrafanaRole.
+func (m *ObservabilityCloudMetadata) GrafanaRoleARN() string {
+	return m.Exports.GetAtt(ObservabilityRefGrafanaRole, ObservabilityAttARN)
+}
+
+// GrafanaRoleRef returns the value of the ObservabilityRefGrafanaRole reference export.
+func (m *ObservabilityCloudMetadata) GrafanaRoleRef() string {
+	return m.Exports.GetRef(ObservabilityRefGrafanaRole)
+}
+
+// GrafanaWorkspaceEndpoint returns the value of the ObservabilityAttEndpoint attribute export of ObservabilityRefGrafanaWorkspace.
+func (m *ObservabilityCloudMetadata) GrafanaWorkspaceEndpoint() string {
+	return m.Exports.GetAtt(ObservabilityRefGrafanaWorkspace, ObservabilityAttEndpoint)
+}
+
+// GrafanaWorkspaceRef returns the value of the ObservabilityRefGrafanaWorkspace reference export.
+func (m *ObservabilityCloudMetadata) GrafanaWorkspaceRef() string {
+	return m.Exports.GetRef(ObservabilityRefGrafanaWorkspace)
+}
+
+// PrometheusWorkspaceID returns the value of the ObservabilityAttWorkspaceID attribute export of ObservabilityRefPrometheusWorkspace.
+func (m *ObservabilityCloudMetadata) PrometheusWorkspaceID() string {
+	return m.Exports.GetAtt(ObservabilityRefPrometheusWorkspace, ObservabilityAttWorkspaceID)
+}
+
+// PrometheusWorkspacePrometheusEndpoint returns the value of the ObservabilityAttPrometheusEndpoint attribute export of ObservabilityRefPrometheusWorkspace.
+func (m *ObservabilityCloudMetadata) PrometheusWorkspacePrometheusEndpoint() string {
+	return m.Exports.GetAtt(ObservabilityRefPrometheusWorkspace, ObservabilityAttPrometheusEndpoint)
+}
+
+// PrometheusWorkspaceRef returns the value of the ObservabilityRefPrometheusWorkspace reference export.
+func (m *ObservabilityCloudMetadata) PrometheusWorkspaceRef() string {
+	return m.Exports.GetRef(ObservabilityRefPrometheusWorkspace)
+}
+
+// DomainARN returns the value of the OpenSearchAttARN attribute export of OpenSearchRefDomain.
+func (m *OpenSearchCloudMetadata) DomainARN() string {
+	return m.Exports.GetAtt(OpenSearchRefDomain, OpenSearchAttARN)
+}
+
+// DomainEndpoint returns the value of the OpenSearchAttDomainEndpoint attribute export of OpenSearchRefDomain.
+func (m *OpenSearchCloudMetadata) DomainEndpoint() string {
+	return m.Exports.GetAtt(OpenSearchRefDomain, OpenSearchAttDomainEndpoint)
+}
+
+// DomainRef returns the value of the OpenSearchRefDomain reference export.
+func (m *OpenSearchCloudMetadata) DomainRef() string {
+	return m.Exports.GetRef(OpenSearchRefDomain)
+}
+
+// DBClusterEndpointAddress returns the value of the PostgresAttEndpointAddress attribute export of PostgresRefDBCluster.
+func (m *PostgresCloudMetadata) DBClusterEndpointAddress() string {
+	return m.Exports.GetAtt(PostgresRefDBCluster, PostgresAttEndpointAddress)
+}
+
+// DBClusterEndpointPort returns the value of the PostgresAttEndpointPort attribute export of PostgresRefDBCluster.
+func (m *PostgresCloudMetadata) DBClusterEndpointPort() string {
+	return m.Exports.GetAtt(PostgresRefDBCluster, PostgresAttEndpointPort)
+}
+
+// DBClusterParameterGroupRef returns the value of the PostgresRefDBClusterParameterGroup reference export.
+func (m *PostgresCloudMetadata) DBClusterParameterGroupRef() string {
+	return m.Exports.GetRef(PostgresRefDBClusterParameterGroup)
+}
+
+// DBClusterReadEndpointAddress returns the value of the PostgresAttReadEndpointAddress attribute export of PostgresRefDBCluster.
+func (m *PostgresCloudMetadata) DBClusterReadEndpointAddress() string {
+	return m.Exports.GetAtt(PostgresRefDBCluster, PostgresAttReadEndpointAddress)
+}
+
+// DBClusterRef returns the value of the PostgresRefDBCluster reference export.
+func (m *PostgresCloudMetadata) DBClusterRef() string {
+	return m.Exports.GetRef(PostgresRefDBCluster)
+}
+
+// DBInstanceEndpointAddress returns the value of the PostgresAttEndpointAddress attribute export of PostgresRefDBInstance.
+func (m *PostgresCloudMetadata) DBInstanceEndpointAddress() string {
+	return m.Exports.GetAtt(PostgresRefDBInstance, PostgresAttEndpointAddress)
+}
+
+// DBInstanceEndpointPort returns the value of the PostgresAttEndpointPort attribute export of PostgresRefDBInstance.
+func (m *PostgresCloudMetadata) DBInstanceEndpointPort() string {
+	return m.Exports.GetAtt(PostgresRefDBInstance, PostgresAttEndpointPort)
+}
+
+// DBInstanceRef returns the value of the PostgresRefDBInstance reference export.
+func (m *PostgresCloudMetadata) DBInstanceRef() string {
+	return m.Exports.GetRef(PostgresRefDBInstance)
+}
+
+// DBParameterGroupRef returns the value of the PostgresRefDBParameterGroup reference export.
+func (m *PostgresCloudMetadata) DBParameterGroupRef() string {
+	return m.Exports.GetRef(PostgresRefDBParameterGroup)
+}
+
+// DBSubnetGroupRef returns the value of the PostgresRefDBSubnetGroup reference export.
+func (m *PostgresCloudMetadata) DBSubnetGroupRef() string {
+	return m.Exports.GetRef(PostgresRefDBSubnetGroup)
+}
+
+// LogGroupARN returns the value of the PostgresAttARN attribute export of PostgresRefLogGroup.
+func (m *PostgresCloudMetadata) LogGroupARN() string {
+	return m.Exports.GetAtt(PostgresRefLogGroup, PostgresAttARN)
+}
+
+// LogGroupRef returns the value of the PostgresRefLogGroup reference export.
+func (m *PostgresCloudMetadata) LogGroupRef() string {
+	return m.Exports.GetRef(PostgresRefLogGroup)
+}
+
+// MaintenanceClusterRef returns the value of the PostgresRefMaintenanceCluster reference export.
+func (m *PostgresCloudMetadata) MaintenanceClusterRef() string {
+	return m.Exports.GetRef(PostgresRefMaintenanceCluster)
+}
+
+// MaintenanceLogGroupRef returns the value of the PostgresRefMaintenanceLogGroup reference export.
+func (m *PostgresCloudMetadata) MaintenanceLogGroupRef() string {
+	return m.Exports.GetRef(PostgresRefMaintenanceLogGroup)
+}
+
+// MaintenanceRoleEventsRef returns the value of the PostgresRefMaintenanceRoleEvents reference export.
+func (m *PostgresCloudMetadata) MaintenanceRoleEventsRef() string {
+	return m.Exports.GetRef(PostgresRefMaintenanceRoleEvents)
+}
+
+// MaintenanceRoleExecutionRef returns the value of the PostgresRefMaintenanceRoleExecution reference export.
+func (m *PostgresCloudMetadata) MaintenanceRoleExecutionRef() string {
+	return m.Exports.GetRef(PostgresRefMaintenanceRoleExecution)
+}
+
+// MaintenanceSecretRef returns the value of the PostgresRefMaintenanceSecret reference export.
+func (m *PostgresCloudMetadata) MaintenanceSecretRef() string {
+	return m.Exports.GetRef(PostgresRefMaintenanceSecret)
+}
+
+// RoleMonitoringARN returns the value of the PostgresAttARN attribute export of PostgresRefRoleMonitoring.
+func (m *PostgresCloudMetadata) RoleMonitoringARN() string {
+	return m.Exports.GetAtt(PostgresRefRoleMonitoring, PostgresAttARN)
+}
+
+// RoleMonitoringRef returns the value of the PostgresRefRoleMonitoring reference export.
+func (m *PostgresCloudMetadata) RoleMonitoringRef() string {
+	return m.Exports.GetRef(PostgresRefRoleMonitoring)
+}
+
+// RoleMonitoringRoleID returns the value of the PostgresAttRoleID attribute export of PostgresRefRoleMonitoring.
+func (m *PostgresCloudMetadata) RoleMonitoringRoleID() string {
+	return m.Exports.GetAtt(PostgresRefRoleMonitoring, PostgresAttRoleID)
+}
+
+// DBProxyARN returns the value of the PostgresProxyAttDBProxyARN attribute export of PostgresProxyRefDBProxy.
+func (m *PostgresProxyCloudMetadata) DBProxyARN() string {
+	return m.Exports.GetAtt(PostgresProxyRefDBProxy, PostgresProxyAttDBProxyARN)
+}
+
+// DBProxyEndpoint returns the value of the PostgresProxyAttEndpoint attribute export of PostgresProxyRefDBProxy.
+func (m *PostgresProxyCloudMetadata) DBProxyEndpoint() string {
+	return m.Exports.GetAtt(PostgresProxyRefDBProxy, PostgresProxyAttEndpoint)
+}
+
+// DBProxyRef returns the value of the PostgresProxyRefDBProxy reference export.
+func (m *PostgresProxyCloudMetadata) DBProxyRef() string {
+	return m.Exports.GetRef(PostgresProxyRefDBProxy)
+}
+
+// DBProxyTargetGroupRef returns the value of the PostgresProxyRefDBProxyTargetGroup reference export.
+func (m *PostgresProxyCloudMetadata) DBProxyTargetGroupRef() string {
+	return m.Exports.GetRef(PostgresProxyRefDBProxyTargetGroup)
+}
+
+// LogGroupARN returns the value of the PostgresProxyAttARN attribute export of PostgresProxyRefLogGroup.
+func (m *PostgresProxyCloudMetadata) LogGroupARN() string {
+	return m.Exports.GetAtt(PostgresProxyRefLogGroup, PostgresProxyAttARN)
+}
+
+// LogGroupRef returns the value of the PostgresProxyRefLogGroup reference export.
+func (m *PostgresProxyCloudMetadata) LogGroupRef() string {
+	return m.Exports.GetRef(PostgresProxyRefLogGroup)
+}
+
+// RoleARN returns the value of the PostgresProxyAttARN attribute export of PostgresProxyRefRole.
+func (m *PostgresProxyCloudMetadata) RoleARN() string {
+	return m.Exports.GetAtt(PostgresProxyRefRole, PostgresProxyAttARN)
+}
+
+// RoleID returns the value of the PostgresProxyAttRoleID attribute export of PostgresProxyRefRole.
+func (m *PostgresProxyCloudMetadata) RoleID() string {
+	return m.Exports.GetAtt(PostgresProxyRefRole, PostgresProxyAttRoleID)
+}
+
+// RoleRef returns the value of the PostgresProxyRefRole reference export.
+func (m *PostgresProxyCloudMetadata) RoleRef() string {
+	return m.Exports.GetRef(PostgresProxyRefRole)
+}
+
+// SecretRef returns the value of the PostgresProxyRefSecret reference export.
+func (m *PostgresProxyCloudMetadata) SecretRef() string {
+	return m.Exports.GetRef(PostgresProxyRefSecret)
+}
+
+// SecretRef returns the value of the RuntimeSecretsRefSecret reference export.
+func (m *RuntimeSecretsCloudMetadata) SecretRef() string {
+	return m.Exports.GetRef(RuntimeSecretsRefSecret)
+}
+
+// LoggingRoleARN returns the value of the SFTPAttARN attribute export of SFTPRefLoggingRole.
+func (m *SFTPCloudMetadata) LoggingRoleARN() string {
+	return m.Exports.GetAtt(SFTPRefLoggingRole, SFTPAttARN)
+}
+
+// LoggingRoleRef returns the value of the SFTPRefLoggingRole reference export.
+func (m *SFTPCloudMetadata) LoggingRoleRef() string {
+	return m.Exports.GetRef(SFTPRefLoggingRole)
+}
+
+// RoleARN returns the value of the SFTPAttARN attribute export of SFTPRefRole.
+func (m *SFTPCloudMetadata) RoleARN() string {
+	return m.Exports.GetAtt(SFTPRefRole, SFTPAttARN)
+}
+
+// RoleRef returns the value of the SFTPRefRole reference export.
+func (m *SFTPCloudMetadata) RoleRef() string {
+	return m.Exports.GetRef(SFTPRefRole)
+}
+
+// ServerARN returns the value of the SFTPAttARN attribute export of SFTPRefServer.
+func (m *SFTPCloudMetadata) ServerARN() string {
+	return m.Exports.GetAtt(SFTPRefServer, SFTPAttARN)
+}
+
+// ServerID returns the value of the SFTPAttServerID attribute export of SFTPRefServer.
+func (m *SFTPCloudMetadata) ServerID() string {
+	return m.Exports.GetAtt(SFTPRefServer, SFTPAttServerID)
+}
+
+// ServerRef returns the value of the SFTPRefServer reference export.
+func (m *SFTPCloudMetadata) ServerRef() string {
+	return m.Exports.GetRef(SFTPRefServer)
+}
+
+// RuleARN returns the value of the ScheduleAttARN attribute export of ScheduleRefRule.
+func (m *ScheduleCloudMetadata) RuleARN() string {
+	return m.Exports.GetAtt(ScheduleRefRule, ScheduleAttARN)
+}
+
+// RuleRef returns the value of the ScheduleRefRule reference export.
+func (m *ScheduleCloudMetadata) RuleRef() string {
+	return m.Exports.GetRef(ScheduleRefRule)
+}
+
+// BucketARN returns the value of the StaticSiteAttARN attribute export of StaticSiteRefBucket.
+func (m *StaticSiteCloudMetadata) BucketARN() string {
+	return m.Exports.GetAtt(StaticSiteRefBucket, StaticSiteAttARN)
+}
+
+// BucketRef returns the value of the StaticSiteRefBucket reference export.
+func (m *StaticSiteCloudMetadata) BucketRef() string {
+	return m.Exports.GetRef(StaticSiteRefBucket)
+}
+
+// BucketRegionalDomainName returns the value of the StaticSiteAttRegionalDomainName attribute export of StaticSiteRefBucket.
+func (m *StaticSiteCloudMetadata) BucketRegionalDomainName() string {
+	return m.Exports.GetAtt(StaticSiteRefBucket, StaticSiteAttRegionalDomainName)
+}
+
+// DistributionDomainName returns the value of the StaticSiteAttDomainName attribute export of StaticSiteRefDistribution.
+func (m *StaticSiteCloudMetadata) DistributionDomainName() string {
+	return m.Exports.GetAtt(StaticSiteRefDistribution, StaticSiteAttDomainName)
+}
+
+// DistributionRef returns the value of the StaticSiteRefDistribution reference export.
+func (m *StaticSiteCloudMetadata) DistributionRef() string {
+	return m.Exports.GetRef(StaticSiteRefDistribution)
+}
+
+// HealthCheckRef returns the value of the StaticSiteRefHealthCheck reference export.
+func (m *StaticSiteCloudMetadata) HealthCheckRef() string {
+	return m.Exports.GetRef(StaticSiteRefHealthCheck)
+}
+
+// OriginAccessIdentityRef returns the value of the StaticSiteRefOriginAccessIdentity reference export.
+func (m *StaticSiteCloudMetadata) OriginAccessIdentityRef() string {
+	return m.Exports.GetRef(StaticSiteRefOriginAccessIdentity)
+}
+
+// RecordSetRef returns the value of the StaticSiteRefRecordSet reference export.
+func (m *StaticSiteCloudMetadata) RecordSetRef() string {
+	return m.Exports.GetRef(StaticSiteRefRecordSet)
+}
+
+// DetectorRef returns the value of the ThreatDetectionRefDetector reference export.
+func (m *ThreatDetectionCloudMetadata) DetectorRef() string {
+	return m.Exports.GetRef(ThreatDetectionRefDetector)
+}
+
+// GuardDutyFindingsRuleARN returns the value of the ThreatDetectionAttARN attribute export of ThreatDetectionRefGuardDutyFindingsRule.
+func (m *ThreatDetectionCloudMetadata) GuardDutyFindingsRuleARN() string {
+	return m.Exports.GetAtt(ThreatDetectionRefGuardDutyFindingsRule, ThreatDetectionAttARN)
+}
+
+// GuardDutyFindingsRuleRef returns the value of the ThreatDetectionRefGuardDutyFindingsRule reference export.
+func (m *ThreatDetectionCloudMetadata) GuardDutyFindingsRuleRef() string {
+	return m.Exports.GetRef(ThreatDetectionRefGuardDutyFindingsRule)
+}
+
+// HubRef returns the value of the ThreatDetectionRefHub reference export.
+func (m *ThreatDetectionCloudMetadata) HubRef() string {
+	return m.Exports.GetRef(ThreatDetectionRefHub)
+}
+
+// SecurityHubFindingsRuleARN returns the value of the ThreatDetectionAttARN attribute export of ThreatDetectionRefSecurityHubFindingsRule.
+func (m *ThreatDetectionCloudMetadata) SecurityHubFindingsRuleARN() string {
+	return m.Exports.GetAtt(ThreatDetectionRefSecurityHubFindingsRule, ThreatDetectionAttARN)
+}
+
+// SecurityHubFindingsRuleRef returns the value of the ThreatDetectionRefSecurityHubFindingsRule reference export.
+func (m *ThreatDetectionCloudMetadata) SecurityHubFindingsRuleRef() string {
+	return m.Exports.GetRef(ThreatDetectionRefSecurityHubFindingsRule)
+}
+
+// BucketsRoleARN returns the value of the WarehouseAttARN attribute export of WarehouseRefBucketsRole.
+func (m *WarehouseCloudMetadata) BucketsRoleARN() string {
+	return m.Exports.GetAtt(WarehouseRefBucketsRole, WarehouseAttARN)
+}
+
+// NamespaceARN returns the value of the WarehouseAttNamespaceARN attribute export of WarehouseRefNamespace.
+func (m *WarehouseCloudMetadata) NamespaceARN() string {
+	return m.Exports.GetAtt(WarehouseRefNamespace, WarehouseAttNamespaceARN)
+}
+
+// NamespaceRef returns the value of the WarehouseRefNamespace reference export.
+func (m *WarehouseCloudMetadata) NamespaceRef() string {
+	return m.Exports.GetRef(WarehouseRefNamespace)
+}
+
+// SecretRef returns the value of the WarehouseRefSecret reference export.
+func (m *WarehouseCloudMetadata) SecretRef() string {
+	return m.Exports.GetRef(WarehouseRefSecret)
+}
+
+// WorkgroupARN returns the value of the WarehouseAttWorkgroupARN attribute export of WarehouseRefWorkgroup.
+func (m *WarehouseCloudMetadata) WorkgroupARN() string {
+	return m.Exports.GetAtt(WarehouseRefWorkgroup, WarehouseAttWorkgroupARN)
+}
+
+// WorkgroupEndpointAddress returns the value of the WarehouseAttEndpointAddress attribute export of WarehouseRefWorkgroup.
+func (m *WarehouseCloudMetadata) WorkgroupEndpointAddress() string {
+	return m.Exports.GetAtt(WarehouseRefWorkgroup, WarehouseAttEndpointAddress)
+}
+
+// WorkgroupEndpointPort returns the value of the WarehouseAttEndpointPort attribute export of WarehouseRefWorkgroup.
+func (m *WarehouseCloudMetadata) WorkgroupEndpointPort() string {
+	return m.Exports.GetAtt(WarehouseRefWorkgroup, WarehouseAttEndpointPort)
+}
+
+// WorkgroupRef returns the value of the WarehouseRefWorkgroup reference export.
+func (m *WarehouseCloudMetadata) WorkgroupRef() string {
+	return m.Exports.GetRef(WarehouseRefWorkgroup)
+}
+
+// APIEndpoint returns the value of the WebSocketAPIAttAPIEndpoint attribute export of WebSocketAPIRefAPI.
+func (m *WebSocketAPICloudMetadata) APIEndpoint() string {
+	return m.Exports.GetAtt(WebSocketAPIRefAPI, WebSocketAPIAttAPIEndpoint)
+}
+
+// APIRef returns the value of the WebSocketAPIRefAPI reference export.
+func (m *WebSocketAPICloudMetadata) APIRef() string {
+	return m.Exports.GetRef(WebSocketAPIRefAPI)
+}
+
+// ConnectionsTableARN returns the value of the WebSocketAPIAttARN attribute export of WebSocketAPIRefConnectionsTable.
+func (m *WebSocketAPICloudMetadata) ConnectionsTableARN() string {
+	return m.Exports.GetAtt(WebSocketAPIRefConnectionsTable, WebSocketAPIAttARN)
+}
+
+// ConnectionsTableRef returns the value of the WebSocketAPIRefConnectionsTable reference export.
+func (m *WebSocketAPICloudMetadata) ConnectionsTableRef() string {
+	return m.Exports.GetRef(WebSocketAPIRefConnectionsTable)
+}
+
+// IntegrationRef returns the value of the WebSocketAPIRefIntegration reference export.
+func (m *WebSocketAPICloudMetadata) IntegrationRef() string {
+	return m.Exports.GetRef(WebSocketAPIRefIntegration)
+}
+
+// StageRef returns the value of the WebSocketAPIRefStage reference export.
+func (m *WebSocketAPICloudMetadata) StageRef() string {
+	return m.Exports.GetRef(WebSocketAPIRefStage)
+}
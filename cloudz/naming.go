@@ -0,0 +1,85 @@
+package cloudz
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	_ NamingStrategy = &DefaultNamingStrategy{}
+)
+
+// NamingStrategy describes how the names of stacks, cloud resources, exports, and local containers are generated, e.g.
+// to conform to existing organizational naming standards (see AppConfig.Naming).
+//
+// Names must be valid for all the resources they are used for: for example, stack and export names can only contain
+// alphanumeric characters and hyphens, and S3 bucket and ECR repository names must be lowercase. Export names must end
+// with the given name, and be stable across deploys: changing the strategy of a deployed app replaces its stacks.
+//
+// A few names are not generated by the strategy, since they are not per-plugin or have stricter constraints: the local
+// MinIO container and bucket names (shared by all the Bucket plugins), local image names (see ImageRepository), the
+// names of the AccessRoles permission sets (limited to 32 characters), and the SSM parameter names (see
+// CloudStageSSMExportConfig, whose Prefix can be customized instead).
+type NamingStrategy interface {
+	GetStackName(p Plugin) string
+	GetResourceName(p Plugin, ref CloudRef) string
+	GetExportName(p Plugin, name string) string
+	GetContainerName(p Plugin, additionalParts ...string) string
+}
+
+// DefaultNamingStrategy is the default NamingStrategy.
+//
+// Stack names are made of the app, stage, plugin, and instance (if any) names, e.g. "app-prod-postgres-main". Resource
+// and export names are made of the stack name and the given ref or name, e.g. "app-prod-postgres-main-db". Export names
+// exceeding the CloudFormation limit are shortened with a stable hash (see getCloudExportName). Container names are made
+// of the app, plugin, and instance (if any) names, and the given additional parts. Names are joined by hyphens, and
+// prepended with Prefix if set.
+type DefaultNamingStrategy struct {
+	Prefix string `validate:"omitempty,resource-name"` // e.g. "acme"
+}
+
+// GetStackName implements the NamingStrategy interface.
+func (s *DefaultNamingStrategy) GetStackName(p Plugin) string {
+	parts := []string{
+		p.GetStage().GetConfig().App.GetConfig().Name,
+		p.GetStage().GetName(),
+		p.GetName(),
+	}
+
+	if instanceName := p.GetInstanceName(); instanceName != nil {
+		parts = append(parts, *instanceName)
+	}
+
+	return s.join(parts...)
+}
+
+// GetResourceName implements the NamingStrategy interface.
+func (s *DefaultNamingStrategy) GetResourceName(p Plugin, ref CloudRef) string {
+	return fmt.Sprintf("%v-%v", s.GetStackName(p), ref)
+}
+
+// GetExportName implements the NamingStrategy interface.
+func (s *DefaultNamingStrategy) GetExportName(p Plugin, name string) string {
+	return getCloudExportName(s.GetStackName(p), "-"+name)
+}
+
+// GetContainerName implements the NamingStrategy interface.
+func (s *DefaultNamingStrategy) GetContainerName(p Plugin, additionalParts ...string) string {
+	parts := []string{
+		p.GetStage().GetConfig().App.GetConfig().Name,
+		p.GetName(),
+	}
+
+	if instanceName := p.GetInstanceName(); instanceName != nil {
+		parts = append(parts, *instanceName)
+	}
+
+	return s.join(append(parts, additionalParts...)...)
+}
+
+func (s *DefaultNamingStrategy) join(parts ...string) string {
+	if s.Prefix != "" {
+		parts = append([]string{s.Prefix}, parts...)
+	}
+	return strings.Join(parts, "-")
+}
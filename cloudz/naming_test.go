@@ -0,0 +1,28 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultNamingStrategy(t *testing.T) {
+	stage, plugins := newTestMetadataStage(nil, newTestMetadata(), newTestMetadata())
+	app := stage.cfg.App.(*testApp)
+	app.cfg = &AppConfig{Name: "app"}
+
+	require.Equal(t, "app-test-test", CloudGetStackName(plugins[0]))
+	require.Equal(t, "app-test-test-a", CloudGetStackName(plugins[1]))
+	require.Equal(t, "app-test-test-a-r", CloudRef("r").Name(plugins[1]))
+	require.Equal(t, "app-test-test-a-r-exp-ref", CloudRef("r").ExpRefName(plugins[1]))
+	require.Equal(t, "app-test-test-a-r-exp-domain-name", CloudRef("r").ExpAttName(plugins[1], "DomainName"))
+	require.Equal(t, "app-test", LocalGetContainerName(plugins[0]))
+	require.Equal(t, "app-test-a-ui", LocalGetContainerName(plugins[1], "ui"))
+
+	app.cfg.Naming = &DefaultNamingStrategy{Prefix: "acme"}
+
+	require.Equal(t, "acme-app-test-test-a", CloudGetStackName(plugins[1]))
+	require.Equal(t, "acme-app-test-test-a-r", CloudRef("r").Name(plugins[1]))
+	require.Equal(t, "acme-app-test-test-a-r-exp-ref", CloudRef("r").ExpRefName(plugins[1]))
+	require.Equal(t, "acme-app-test-a-ui", LocalGetContainerName(plugins[1], "ui"))
+}
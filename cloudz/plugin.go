@@ -11,10 +11,13 @@ type Event string
 
 // Known events.
 const (
-	LocalBeforeCreateEvent Event = "localBeforeCreate"
-	LocalAfterCreateEvent  Event = "localAfterCreate"
-	CloudBeforeDeployEvent Event = "cloudBeforeDeploy"
-	CloudAfterDeployEvent  Event = "cloudAfterDeploy"
+	LocalBeforeCreateEvent  Event = "localBeforeCreate"
+	LocalAfterCreateEvent   Event = "localAfterCreate"
+	CloudPreflightEvent     Event = "cloudPreflight"
+	CloudBeforeDeployEvent  Event = "cloudBeforeDeploy"
+	CloudAfterDeployEvent   Event = "cloudAfterDeploy"
+	CloudBeforeDestroyEvent Event = "cloudBeforeDestroy"
+	CloudAfterDestroyEvent  Event = "cloudAfterDestroy"
 )
 
 // Plugin describes a plugin, i.e. a set of behaviors, tools, and components.
@@ -0,0 +1,437 @@
+package cloudz
+
+import (
+	"fmt"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	gosso "github.com/awslabs/goformation/v6/cloudformation/sso"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// AccessRoles constants.
+const (
+	AccessRolesPluginDisplayName        = "AccessRoles"
+	AccessRolesPluginName               = "access-roles"
+	AccessRolesRefRoleReadOnly          = CloudRef("r-ro")
+	AccessRolesRefRoleOperator          = CloudRef("r-op")
+	AccessRolesRefPermissionSetReadOnly = CloudRef("ps-ro")
+	AccessRolesRefPermissionSetOperator = CloudRef("ps-op")
+	AccessRolesAttARN                   = CloudAtt("Arn")
+	AccessRolesAttSwitchRoleURL         = CloudAtt("SwitchRoleURL")
+	AccessRolesAttPermissionSetARN      = CloudAtt("PermissionSetArn")
+
+	accessRolesDefaultSessionDurationHours = 1
+	accessRolesMaxPermissionSetNameLength  = 32
+	accessRolesViewOnlyAccessPolicyARN     = "arn:${AWS::Partition}:iam::aws:policy/job-function/ViewOnlyAccess"
+	accessRolesSwitchRoleURL               = "https://signin.aws.amazon.com/switchrole?roleName=%v&account=${AWS::AccountId}&displayName=%v"
+)
+
+var (
+	_ AccessRoles = &accessRolesImpl{}
+	_ Plugin      = &accessRolesImpl{}
+)
+
+var (
+	// accessRolesReadOnlyTaggedActions are the read actions allowed on the resources of the stage (i.e. with its tags).
+	accessRolesReadOnlyTaggedActions = []string{
+		"cloudformation:DescribeStackEvents",
+		"cloudformation:DescribeStackResources",
+		"cloudformation:GetTemplate",
+		"logs:FilterLogEvents",
+		"logs:GetLogEvents",
+		"logs:StartQuery",
+		"sqs:GetQueueAttributes",
+	}
+
+	// accessRolesReadOnlyUntaggedActions are the read actions that do not support tag conditions, allowed account-wide.
+	// They only expose telemetry (i.e. metrics, query results, traces), never data.
+	accessRolesReadOnlyUntaggedActions = []string{
+		"cloudwatch:GetDashboard",
+		"cloudwatch:GetMetricData",
+		"cloudwatch:GetMetricStatistics",
+		"logs:GetQueryResults",
+		"logs:StopQuery",
+		"xray:BatchGetTraces",
+		"xray:GetServiceGraph",
+		"xray:GetTraceSummaries",
+	}
+
+	// accessRolesOperatorTaggedActions are the operational actions allowed on the resources of the stage (i.e. with its
+	// tags), e.g. to restart services, run jobs, redrive queues, or take snapshots.
+	accessRolesOperatorTaggedActions = []string{
+		"backup:StartBackupJob",
+		"cloudfront:CreateInvalidation",
+		"ecs:ExecuteCommand",
+		"ecs:RunTask",
+		"ecs:StopTask",
+		"ecs:UpdateService",
+		"events:DisableRule",
+		"events:EnableRule",
+		"lambda:InvokeFunction",
+		"rds:CreateDBClusterSnapshot",
+		"rds:CreateDBSnapshot",
+		"rds:RebootDBInstance",
+		"sqs:ChangeMessageVisibility",
+		"sqs:DeleteMessage",
+		"sqs:ReceiveMessage",
+		"sqs:SendMessage",
+		"sqs:StartMessageMoveTask",
+	}
+)
+
+// AccessRolesConfigFunc returns the access roles config for a given Stage.
+type AccessRolesConfigFunc func(Stage, *AccessRolesDependencies) *AccessRolesConfig
+
+// AccessRolesEventHookFunc describes an access roles event hook.
+type AccessRolesEventHookFunc func(AccessRoles, Event, string)
+
+// AccessRolesConfig describes the access roles config.
+type AccessRolesConfig struct {
+	Stage     Stage  `validate:"required"`
+	Name      string `validate:"required,resource-name"`
+	Cloud     *AccessRolesConfigCloud
+	EventHook AccessRolesEventHookFunc
+}
+
+// MustValidate validates the access roles config.
+func (c *AccessRolesConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing AccessRolesConfig.Cloud", errorz.Prefix(AccessRolesPluginName))
+}
+
+// AccessRolesConfigCloud describes part of the access roles config.
+//
+// The roles can be assumed (e.g. with "switch role" in the console, see AccessRolesCloudMetadata) by the
+// TrustedPrincipalARNs, which default to the current account, i.e. to the IAM users and roles allowed to do so by their
+// own policies. Unless MFAOptional is set, assuming the roles requires MFA. The SessionDurationHours default to 1. The
+// OperatorRolePolicies are added to the operator role and permission set, e.g. to allow app-specific actions. Their
+// documents must be built with NewPolicyDocument.
+type AccessRolesConfigCloud struct {
+	TrustedPrincipalARNs []string `validate:"dive,required"`
+	MFAOptional          bool
+	SessionDurationHours int `validate:"omitempty,min=1,max=12"`
+	OperatorRolePolicies []goiam.Role_Policy
+	IdentityCenter       *AccessRolesConfigCloudIdentityCenter
+}
+
+// AccessRolesConfigCloudIdentityCenter describes part of the access roles config.
+//
+// If set, equivalent permission sets are created in the IAM Identity Center instance, and assigned to the given groups
+// for the current account. Permission sets can only be created in the management account (or a delegated administrator
+// account) of the organization, so in practice this requires the stage to be deployed there.
+type AccessRolesConfigCloudIdentityCenter struct {
+	InstanceARN      string   `validate:"required"`
+	ReadOnlyGroupIDs []string `validate:"dive,required"`
+	OperatorGroupIDs []string `validate:"dive,required"`
+}
+
+// AccessRolesDependencies describes the access roles dependencies.
+type AccessRolesDependencies struct {
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the access roles dependencies.
+func (d *AccessRolesDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// AccessRolesCloudMetadata describes the access roles cloud metadata.
+type AccessRolesCloudMetadata struct {
+	Exports CloudExports
+}
+
+// GetReadOnlyRoleARN returns the read-only role ARN.
+func (m *AccessRolesCloudMetadata) GetReadOnlyRoleARN() string {
+	return m.Exports.GetAtt(AccessRolesRefRoleReadOnly, AccessRolesAttARN)
+}
+
+// GetReadOnlySwitchRoleURL returns the console URL to switch to the read-only role.
+func (m *AccessRolesCloudMetadata) GetReadOnlySwitchRoleURL() string {
+	return m.Exports.GetAtt(AccessRolesRefRoleReadOnly, AccessRolesAttSwitchRoleURL)
+}
+
+// GetOperatorRoleARN returns the operator role ARN.
+func (m *AccessRolesCloudMetadata) GetOperatorRoleARN() string {
+	return m.Exports.GetAtt(AccessRolesRefRoleOperator, AccessRolesAttARN)
+}
+
+// GetOperatorSwitchRoleURL returns the console URL to switch to the operator role.
+func (m *AccessRolesCloudMetadata) GetOperatorSwitchRoleURL() string {
+	return m.Exports.GetAtt(AccessRolesRefRoleOperator, AccessRolesAttSwitchRoleURL)
+}
+
+// AccessRoles describes a pair of IAM roles (and optionally IAM Identity Center permission sets) for humans to access the
+// stage: a read-only one (e.g. for support engineers to inspect production safely) and an operator one, which can also
+// perform operational actions. Beyond account-wide metadata (i.e. the "ViewOnlyAccess" managed policy) and telemetry,
+// permissions are scoped to the resources of the stage by their "App" and "Stage" tags (see CloudGetStackTags). The
+// read-only role cannot read data (e.g. objects, secrets, messages), while the operator role can through operational
+// actions (e.g. receiving messages, executing commands in containers). It has no local equivalent.
+type AccessRoles interface {
+	Plugin
+	GetConfig() *AccessRolesConfig
+	GetCloudMetadata(require bool) *AccessRolesCloudMetadata
+}
+
+type accessRolesImpl struct {
+	cfgFunc       AccessRolesConfigFunc
+	deps          *AccessRolesDependencies
+	cfg           *AccessRolesConfig
+	cloudMetadata *AccessRolesCloudMetadata
+}
+
+// NewAccessRoles initializes a new AccessRoles.
+func NewAccessRoles(cfgFunc AccessRolesConfigFunc, deps *AccessRolesDependencies) AccessRoles {
+	deps.MustValidate()
+
+	return &accessRolesImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*accessRolesImpl) GetDisplayName() string {
+	return AccessRolesPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *accessRolesImpl) GetName() string {
+	return AccessRolesPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *accessRolesImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *accessRolesImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{}
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *accessRolesImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+
+	if p.cfg.Cloud != nil && p.cfg.Cloud.IdentityCenter != nil {
+		for _, ref := range []CloudRef{AccessRolesRefPermissionSetReadOnly, AccessRolesRefPermissionSetOperator} {
+			name := p.getPermissionSetName(ref)
+			errorz.Assertf(len(name) <= accessRolesMaxPermissionSetNameLength,
+				"permission set name too long: %v", errorz.A(name), errorz.Prefix(AccessRolesPluginName))
+		}
+	}
+}
+
+// GetStage implements the Plugin interface.
+func (p *accessRolesImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(AccessRolesPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the AccessRoles interface.
+func (p *accessRolesImpl) GetConfig() *AccessRolesConfig {
+	return p.cfg
+}
+
+// GetCloudMetadata implements the AccessRoles interface.
+func (p *accessRolesImpl) GetCloudMetadata(require bool) *AccessRolesCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(AccessRolesPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *accessRolesImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (*accessRolesImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
+	// intentionally empty
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *accessRolesImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	readOnlyStatements := p.getReadOnlyPolicyStatements()
+	operatorStatements := append(p.getReadOnlyPolicyStatements(), p.getOperatorPolicyStatements()...)
+
+	p.addRole(tpl, AccessRolesRefRoleReadOnly, readOnlyStatements, nil)
+	p.addRole(tpl, AccessRolesRefRoleOperator, operatorStatements, p.cfg.Cloud.OperatorRolePolicies)
+
+	if identityCenter := p.cfg.Cloud.IdentityCenter; identityCenter != nil {
+		for _, policy := range p.cfg.Cloud.OperatorRolePolicies {
+			operatorStatements = append(operatorStatements, getAccessRolesPolicyDocumentStatements(policy.PolicyDocument)...)
+		}
+
+		p.addPermissionSet(tpl, AccessRolesRefPermissionSetReadOnly, readOnlyStatements, identityCenter.ReadOnlyGroupIDs)
+		p.addPermissionSet(tpl, AccessRolesRefPermissionSetOperator, operatorStatements, identityCenter.OperatorGroupIDs)
+	}
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *accessRolesImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &AccessRolesCloudMetadata{
+		Exports: NewCloudExports(stack),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *accessRolesImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *accessRolesImpl) addRole(tpl *gocf.Template, ref CloudRef, statements []interface{}, additionalPolicies []goiam.Role_Policy) {
+	policies := append([]goiam.Role_Policy{
+		{
+			PolicyName:     ref.Name(p),
+			PolicyDocument: newAccessRolesPolicyDocument(statements),
+		},
+	}, additionalPolicies...)
+
+	tpl.Resources[ref.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: p.getAssumeRolePolicyDocument(),
+		Description:              stringz.Ptr(fmt.Sprintf("Human access to the %v stage.", p.cfg.Stage.GetName())),
+		ManagedPolicyArns:        &[]string{gocf.Sub(accessRolesViewOnlyAccessPolicyARN)},
+		MaxSessionDuration:       intz.Ptr(p.getSessionDurationHours() * 3600),
+		Policies:                 &policies,
+		RoleName:                 stringz.Ptr(ref.Name(p)),
+		Tags:                     CloudGetDefaultTags(ref.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, ref)
+	CloudAddExpGetAtt(tpl, p, ref, AccessRolesAttARN)
+
+	tpl.Outputs[ref.ExpAttRef(AccessRolesAttSwitchRoleURL)] = gocf.Output{
+		Value: gocf.Sub(fmt.Sprintf(accessRolesSwitchRoleURL, ref.Name(p), ref.Name(p))),
+		Export: &gocf.Export{
+			Name: ref.ExpAttName(p, AccessRolesAttSwitchRoleURL),
+		},
+	}
+}
+
+func (p *accessRolesImpl) addPermissionSet(tpl *gocf.Template, ref CloudRef, statements []interface{}, groupIDs []string) {
+	instanceARN := p.cfg.Cloud.IdentityCenter.InstanceARN
+
+	tpl.Resources[ref.Ref()] = &gosso.PermissionSet{
+		Description:     stringz.Ptr(fmt.Sprintf("Human access to the %v stage.", p.cfg.Stage.GetName())),
+		InlinePolicy:    newAccessRolesInlinePolicy(statements),
+		InstanceArn:     instanceARN,
+		ManagedPolicies: &[]string{gocf.Sub(accessRolesViewOnlyAccessPolicyARN)},
+		Name:            p.getPermissionSetName(ref),
+		SessionDuration: stringz.Ptr(fmt.Sprintf("PT%vH", p.getSessionDurationHours())),
+		Tags:            CloudGetDefaultTags(ref.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, ref)
+	CloudAddExpGetAtt(tpl, p, ref, AccessRolesAttPermissionSetARN)
+
+	for i, groupID := range groupIDs {
+		tpl.Resources[CloudRef(fmt.Sprintf("%v-a-%v", ref, i)).Ref()] = &gosso.Assignment{
+			InstanceArn:      instanceARN,
+			PermissionSetArn: gocf.GetAtt(ref.Ref(), AccessRolesAttPermissionSetARN.Ref()),
+			PrincipalId:      groupID,
+			PrincipalType:    "GROUP",
+			TargetId:         gocf.Ref("AWS::AccountId"),
+			TargetType:       "AWS_ACCOUNT",
+		}
+	}
+}
+
+func (p *accessRolesImpl) getAssumeRolePolicyDocument() interface{} {
+	principals := make([]string, 0, len(p.cfg.Cloud.TrustedPrincipalARNs))
+	principals = append(principals, p.cfg.Cloud.TrustedPrincipalARNs...)
+	if len(principals) == 0 {
+		principals = append(principals, gocf.Sub("arn:${AWS::Partition}:iam::${AWS::AccountId}:root"))
+	}
+
+	statement := map[string]interface{}{
+		"Effect": "Allow",
+		"Principal": map[string]interface{}{
+			"AWS": principals,
+		},
+		"Action": "sts:AssumeRole",
+	}
+
+	if !p.cfg.Cloud.MFAOptional {
+		statement["Condition"] = map[string]interface{}{
+			"Bool": map[string]interface{}{
+				"aws:MultiFactorAuthPresent": "true",
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"Version":   "2012-10-17",
+		"Statement": []interface{}{statement},
+	}
+}
+
+func (p *accessRolesImpl) getReadOnlyPolicyStatements() []interface{} {
+	return []interface{}{
+		p.addTagConditions(NewPolicyStatement().AddActions(accessRolesReadOnlyTaggedActions...).AddResources("*")).Build(),
+		NewPolicyStatement().AddActions(accessRolesReadOnlyUntaggedActions...).AddResources("*").Build(),
+	}
+}
+
+func (p *accessRolesImpl) getOperatorPolicyStatements() []interface{} {
+	return []interface{}{
+		p.addTagConditions(NewPolicyStatement().AddActions(accessRolesOperatorTaggedActions...).AddResources("*")).Build(),
+		p.addTagConditions(NewPolicyStatement().AddActions("iam:PassRole").AddResources("*")).
+			AddCondition("StringEquals", "iam:PassedToService", "ecs-tasks.amazonaws.com").
+			Build(),
+	}
+}
+
+func (p *accessRolesImpl) addTagConditions(statement *PolicyStatement) *PolicyStatement {
+	return statement.
+		AddCondition("StringEquals", "aws:ResourceTag/"+CloudStackTagApp, p.cfg.Stage.GetConfig().App.GetConfig().Name).
+		AddCondition("StringEquals", "aws:ResourceTag/"+CloudStackTagStage, p.cfg.Stage.GetName())
+}
+
+// getPermissionSetName returns the name of a permission set. It doesn't go through the NamingStrategy, since permission
+// set names are limited to 32 characters (see accessRolesMaxPermissionSetNameLength).
+func (p *accessRolesImpl) getPermissionSetName(ref CloudRef) string {
+	return fmt.Sprintf("%v-%v-%v", p.cfg.Stage.GetConfig().App.GetConfig().Name, p.cfg.Stage.GetName(), ref)
+}
+
+func (p *accessRolesImpl) getSessionDurationHours() int {
+	if p.cfg.Cloud.SessionDurationHours > 0 {
+		return p.cfg.Cloud.SessionDurationHours
+	}
+	return accessRolesDefaultSessionDurationHours
+}
+
+func newAccessRolesPolicyDocument(statements []interface{}) interface{} {
+	return map[string]interface{}{
+		"Version":   "2012-10-17",
+		"Statement": statements,
+	}
+}
+
+func newAccessRolesInlinePolicy(statements []interface{}) *interface{} {
+	policy := newAccessRolesPolicyDocument(statements)
+	return &policy
+}
+
+// getAccessRolesPolicyDocumentStatements returns the statements of a policy document built by NewPolicyDocument.
+func getAccessRolesPolicyDocumentStatements(policyDocument interface{}) []interface{} {
+	document, ok := policyDocument.(map[string]interface{})
+	errorz.Assertf(ok, "unexpected policy document type", errorz.Prefix(AccessRolesPluginName))
+	statements, ok := document["Statement"].([]interface{})
+	errorz.Assertf(ok, "unexpected policy document statements type", errorz.Prefix(AccessRolesPluginName))
+	return statements
+}
@@ -0,0 +1,94 @@
+package cloudz
+
+import (
+	"testing"
+
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessRolesConfig_MustValidate(t *testing.T) {
+	newConfig := func(cloud *AccessRolesConfigCloud) *AccessRolesConfig {
+		return &AccessRolesConfig{
+			Stage: &cloudStageImpl{cfg: &CloudStageConfig{Name: "prod"}},
+			Name:  "support",
+			Cloud: cloud,
+		}
+	}
+
+	require.NotPanics(t, func() { newConfig(nil).MustValidate(Local) })
+	require.NotPanics(t, func() { newConfig(&AccessRolesConfigCloud{}).MustValidate(Cloud) })
+	require.Panics(t, func() { newConfig(nil).MustValidate(Cloud) })
+	require.Panics(t, func() { newConfig(&AccessRolesConfigCloud{SessionDurationHours: 13}).MustValidate(Cloud) })
+	require.Panics(t, func() { newConfig(&AccessRolesConfigCloud{TrustedPrincipalARNs: []string{""}}).MustValidate(Cloud) })
+	require.Panics(t, func() {
+		newConfig(&AccessRolesConfigCloud{IdentityCenter: &AccessRolesConfigCloudIdentityCenter{}}).MustValidate(Cloud)
+	})
+}
+
+func TestAccessRoles_GetAssumeRolePolicyDocument(t *testing.T) {
+	mfaCondition := map[string]interface{}{
+		"Bool": map[string]interface{}{
+			"aws:MultiFactorAuthPresent": "true",
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		cloud              *AccessRolesConfigCloud
+		expectedPrincipals []string
+		expectedCondition  interface{}
+	}{
+		{
+			name:               "default",
+			cloud:              &AccessRolesConfigCloud{},
+			expectedPrincipals: []string{gocf.Sub("arn:${AWS::Partition}:iam::${AWS::AccountId}:root")},
+			expectedCondition:  mfaCondition,
+		},
+		{
+			name: "trusted principals without mfa",
+			cloud: &AccessRolesConfigCloud{
+				TrustedPrincipalARNs: []string{"arn:aws:iam::123456789012:role/support"},
+				MFAOptional:          true,
+			},
+			expectedPrincipals: []string{"arn:aws:iam::123456789012:role/support"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			p := &accessRolesImpl{
+				cfg: &AccessRolesConfig{
+					Name:  "support",
+					Cloud: testCase.cloud,
+				},
+			}
+
+			statement := map[string]interface{}{
+				"Effect": "Allow",
+				"Principal": map[string]interface{}{
+					"AWS": testCase.expectedPrincipals,
+				},
+				"Action": "sts:AssumeRole",
+			}
+			if testCase.expectedCondition != nil {
+				statement["Condition"] = testCase.expectedCondition
+			}
+
+			require.Equal(t, map[string]interface{}{
+				"Version":   "2012-10-17",
+				"Statement": []interface{}{statement},
+			}, p.getAssumeRolePolicyDocument())
+		})
+	}
+}
+
+func TestGetAccessRolesPolicyDocumentStatements(t *testing.T) {
+	statement := NewPolicyStatement().AddActions("ssm:GetParameter").AddResources("*")
+	require.Equal(t,
+		[]interface{}{statement.Build()},
+		getAccessRolesPolicyDocumentStatements(NewPolicyDocument(statement)))
+	require.Panics(t, func() { getAccessRolesPolicyDocumentStatements("{}") })
+}
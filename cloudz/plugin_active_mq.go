@@ -0,0 +1,412 @@
+package cloudz
+
+import (
+	"fmt"
+	"strings"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goamazonmq "github.com/awslabs/goformation/v6/cloudformation/amazonmq"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	gosm "github.com/awslabs/goformation/v6/cloudformation/secretsmanager"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/jsonz"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// ActiveMQ constants.
+const (
+	ActiveMQPluginDisplayName = "ActiveMQ"
+	ActiveMQPluginName        = "active-mq"
+	ActiveMQRefSecret         = CloudRef("s")
+	ActiveMQRefBroker         = CloudRef("b")
+	ActiveMQUsername          = "app"
+
+	activeMQLocalOpenWirePort    = 61616
+	activeMQLocalSTOMPPort       = 61613
+	activeMQLocalConsolePort     = 8161
+	activeMQCloudOpenWirePort    = 61617
+	activeMQCloudSTOMPPort       = 61614
+	activeMQCloudConsolePort     = 8162
+	activeMQSecretPasswordKey    = "password"
+	activeMQDefaultInstanceType  = "mq.t3.micro"
+	activeMQDeploymentSingle     = "SINGLE_INSTANCE"
+	activeMQDeploymentMultiAZ    = "ACTIVE_STANDBY_MULTI_AZ"
+	activeMQEngineType           = "ACTIVEMQ"
+	activeMQMultiAZInstanceCount = 2
+)
+
+var (
+	_ ActiveMQ = &activeMQImpl{}
+	_ Plugin   = &activeMQImpl{}
+)
+
+// ActiveMQConfigFunc returns the ActiveMQ config for a given Stage.
+type ActiveMQConfigFunc func(Stage, *ActiveMQDependencies) *ActiveMQConfig
+
+// ActiveMQEventHookFunc describes an ActiveMQ event hook.
+type ActiveMQEventHookFunc func(ActiveMQ, Event, string)
+
+// ActiveMQConfig describes the ActiveMQ config.
+//
+// It is meant for services that speak legacy messaging protocols (OpenWire, STOMP): new services should prefer EventBus
+// or Kafka. Local stages run a single ActiveMQ Classic broker, with plaintext transports. Cloud stages provision an
+// Amazon MQ broker in the private subnets of the Network, with TLS transports only. Its password is generated and stored
+// in Secrets Manager (see ActiveMQCloudMetadata.SecretARN and ActiveMQ.GetClientRolePolicy): it never appears in the
+// template or in the metadata.
+type ActiveMQConfig struct {
+	Stage     Stage `validate:"required"`
+	Local     *ActiveMQConfigLocal
+	Cloud     *ActiveMQConfigCloud
+	EventHook ActiveMQEventHookFunc
+}
+
+// MustValidate validates the ActiveMQ config.
+func (c *ActiveMQConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing ActiveMQConfig.Local")
+	errorz.Assertf(!c.Cloud.GetIsMultiAZ() || c.Cloud.GetInstanceType() != activeMQDefaultInstanceType,
+		"ActiveMQConfig.Cloud.IsMultiAZ is not supported by %v", errorz.A(activeMQDefaultInstanceType))
+}
+
+// ActiveMQConfigLocal describes part of the ActiveMQ config.
+type ActiveMQConfigLocal struct {
+	OpenWireExternalPort uint16 `validate:"required"`
+	STOMPExternalPort    uint16 `validate:"required"`
+	ConsoleExternalPort  uint16 `validate:"required"`
+}
+
+// ActiveMQConfigCloud describes part of the ActiveMQ config.
+// The InstanceType defaults to "mq.t3.micro", which does not support IsMultiAZ.
+type ActiveMQConfigCloud struct {
+	InstanceType string `validate:"omitempty,startswith=mq."`
+	IsMultiAZ    bool
+}
+
+// GetInstanceType returns the instance type.
+func (c *ActiveMQConfigCloud) GetInstanceType() string {
+	if c == nil || c.InstanceType == "" {
+		return activeMQDefaultInstanceType
+	}
+	return c.InstanceType
+}
+
+// GetIsMultiAZ returns true if the broker is deployed in active/standby mode across two availability zones.
+func (c *ActiveMQConfigCloud) GetIsMultiAZ() bool {
+	return c != nil && c.IsMultiAZ
+}
+
+// ActiveMQDependencies describes the ActiveMQ dependencies.
+type ActiveMQDependencies struct {
+	Network           Network `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the ActiveMQ dependencies.
+func (d *ActiveMQDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// ActiveMQLocalMetadata describes the ActiveMQ local metadata.
+type ActiveMQLocalMetadata struct {
+	ContainerName       string
+	Username            string
+	Password            string `metadata:"secret"`
+	ExternalOpenWireURL string
+	InternalOpenWireURL string
+	ExternalSTOMPURL    string
+	InternalSTOMPURL    string
+	ExternalConsoleURL  string
+}
+
+// ActiveMQCloudMetadata describes the ActiveMQ cloud metadata.
+// The URLs do not include the password, which must be read from the secret at runtime (key "password"). In multi-AZ
+// deployments, the OpenWireURL uses the failover transport, and clients using STOMP must try each of the STOMPURLs.
+type ActiveMQCloudMetadata struct {
+	Exports     CloudExports
+	SecretARN   string
+	Username    string
+	OpenWireURL string
+	STOMPURLs   []string
+	ConsoleURL  string
+}
+
+// ActiveMQ describes an ActiveMQ broker.
+type ActiveMQ interface {
+	Plugin
+	GetConfig() *ActiveMQConfig
+	GetDependencies() *ActiveMQDependencies
+	GetLocalMetadata() *ActiveMQLocalMetadata
+	GetCloudMetadata(require bool) *ActiveMQCloudMetadata
+	GetClientRolePolicy() goiam.Role_Policy
+}
+
+type activeMQImpl struct {
+	cfgFunc       ActiveMQConfigFunc
+	deps          *ActiveMQDependencies
+	cfg           *ActiveMQConfig
+	localMetadata *ActiveMQLocalMetadata
+	cloudMetadata *ActiveMQCloudMetadata
+}
+
+// NewActiveMQ initializes a new ActiveMQ.
+func NewActiveMQ(cfgFunc ActiveMQConfigFunc, deps *ActiveMQDependencies) ActiveMQ {
+	deps.MustValidate()
+
+	return &activeMQImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*activeMQImpl) GetDisplayName() string {
+	return ActiveMQPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *activeMQImpl) GetName() string {
+	return ActiveMQPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *activeMQImpl) GetInstanceName() *string {
+	return nil
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *activeMQImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Network: {},
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *activeMQImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *activeMQImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(ActiveMQPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the ActiveMQ interface.
+func (p *activeMQImpl) GetConfig() *ActiveMQConfig {
+	return p.cfg
+}
+
+// GetDependencies implements the ActiveMQ interface.
+func (p *activeMQImpl) GetDependencies() *ActiveMQDependencies {
+	return p.deps
+}
+
+// GetLocalMetadata implements the ActiveMQ interface.
+func (p *activeMQImpl) GetLocalMetadata() *ActiveMQLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(ActiveMQPluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the ActiveMQ interface.
+func (p *activeMQImpl) GetCloudMetadata(require bool) *ActiveMQCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(ActiveMQPluginName))
+	return p.cloudMetadata
+}
+
+// GetClientRolePolicy implements the ActiveMQ interface.
+// It returns a role policy granting read access to the secret holding the broker credentials.
+func (p *activeMQImpl) GetClientRolePolicy() goiam.Role_Policy {
+	return goiam.Role_Policy{
+		PolicyName: ActiveMQPluginName + "-client",
+		PolicyDocument: NewPolicyDocument(
+			NewPolicyStatement().
+				AddActions("secretsmanager:GetSecretValue").
+				AddResources(p.GetCloudMetadata(true).SecretARN),
+			NewPolicyStatement().
+				AddActions("kms:Decrypt").
+				AddResources(gocf.Sub("arn:aws:kms:${AWS::Region}:${AWS::AccountId}:key/aws/secretsmanager"))),
+	}
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *activeMQImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *activeMQImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
+	containerName := LocalGetContainerName(p)
+
+	p.localMetadata = &ActiveMQLocalMetadata{
+		ContainerName:       containerName,
+		Username:            ActiveMQUsername,
+		Password:            LocalPassword,
+		ExternalOpenWireURL: fmt.Sprintf("tcp://localhost:%v", p.cfg.Local.OpenWireExternalPort),
+		InternalOpenWireURL: fmt.Sprintf("tcp://%v:%v", containerName, activeMQLocalOpenWirePort),
+		ExternalSTOMPURL:    fmt.Sprintf("stomp://localhost:%v", p.cfg.Local.STOMPExternalPort),
+		InternalSTOMPURL:    fmt.Sprintf("stomp://%v:%v", containerName, activeMQLocalSTOMPPort),
+		ExternalConsoleURL:  fmt.Sprintf("http://localhost:%v/admin/", p.cfg.Local.ConsoleExternalPort),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          containerName,
+		ContainerName: containerName,
+		Environment: map[string]*string{
+			"ACTIVEMQ_CONNECTION_USER":     stringz.Ptr(ActiveMQUsername),
+			"ACTIVEMQ_CONNECTION_PASSWORD": stringz.Ptr(LocalPassword),
+			"ACTIVEMQ_WEB_USER":            stringz.Ptr(ActiveMQUsername),
+			"ACTIVEMQ_WEB_PASSWORD":        stringz.Ptr(LocalPassword),
+		},
+		Image:    LocalGetImage(p, "apache/activemq-classic:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().ActiveMQ),
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    activeMQLocalOpenWirePort,
+				Published: uint32(p.cfg.Local.OpenWireExternalPort),
+			},
+			{
+				Target:    activeMQLocalSTOMPPort,
+				Published: uint32(p.cfg.Local.STOMPExternalPort),
+			},
+			{
+				Target:    activeMQLocalConsolePort,
+				Published: uint32(p.cfg.Local.ConsoleExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *activeMQImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+	network := p.deps.Network.GetCloudMetadata(true)
+
+	tpl.Resources[ActiveMQRefSecret.Ref()] = &gosm.Secret{
+		GenerateSecretString: &gosm.Secret_GenerateSecretString{
+			ExcludePunctuation:      boolz.Ptr(true),
+			GenerateStringKey:       stringz.Ptr(activeMQSecretPasswordKey),
+			PasswordLength:          intz.Ptr(32),
+			RequireEachIncludedType: boolz.Ptr(true),
+			SecretStringTemplate: stringz.Ptr(jsonz.MustMarshalString(map[string]string{
+				"username": ActiveMQUsername,
+			})),
+		},
+		Name: stringz.Ptr(ActiveMQRefSecret.Name(p)),
+		Tags: CloudGetDefaultTags(ActiveMQRefSecret.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, ActiveMQRefSecret)
+
+	deploymentMode := activeMQDeploymentSingle
+	subnetIDs := []string{
+		network.Exports.GetRef(NetworkRefSubnetPrivateA),
+	}
+
+	if p.cfg.Cloud.GetIsMultiAZ() {
+		deploymentMode = activeMQDeploymentMultiAZ
+		subnetIDs = append(subnetIDs, network.Exports.GetRef(NetworkRefSubnetPrivateB))
+	}
+
+	tpl.Resources[ActiveMQRefBroker.Ref()] = &goamazonmq.Broker{
+		AutoMinorVersionUpgrade: true,
+		BrokerName:              ActiveMQRefBroker.Name(p),
+		DeploymentMode:          deploymentMode,
+		EngineType:              activeMQEngineType,
+		EngineVersion:           p.getEngineVersion(),
+		HostInstanceType:        p.cfg.Cloud.GetInstanceType(),
+		Logs: &goamazonmq.Broker_LogList{
+			General: boolz.Ptr(true),
+		},
+		PubliclyAccessible: false,
+		SecurityGroups: &[]string{
+			network.Exports.GetRef(NetworkRefSecurityGroup),
+		},
+		SubnetIds: &subnetIDs,
+		Tags: &[]goamazonmq.Broker_TagsEntry{
+			{
+				Key:   "Name",
+				Value: ActiveMQRefBroker.Name(p),
+			},
+		},
+		Users: []goamazonmq.Broker_User{
+			{
+				ConsoleAccess: boolz.Ptr(true),
+				Password: gocf.Sub(fmt.Sprintf("{{resolve:secretsmanager:${%v}:SecretString:%v}}",
+					ActiveMQRefSecret.Ref(), activeMQSecretPasswordKey)),
+				Username: ActiveMQUsername,
+			},
+		},
+	}
+	CloudAddExpRef(tpl, p, ActiveMQRefBroker)
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *activeMQImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+	hosts := p.getCloudHosts(exports.GetRef(ActiveMQRefBroker))
+
+	p.cloudMetadata = &ActiveMQCloudMetadata{
+		Exports:    exports,
+		SecretARN:  exports.GetRef(ActiveMQRefSecret),
+		Username:   ActiveMQUsername,
+		STOMPURLs:  make([]string, 0, len(hosts)),
+		ConsoleURL: fmt.Sprintf("https://%v:%v", hosts[0], activeMQCloudConsolePort),
+	}
+
+	openWireURLs := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		openWireURLs = append(openWireURLs, fmt.Sprintf("ssl://%v:%v", host, activeMQCloudOpenWirePort))
+		p.cloudMetadata.STOMPURLs = append(p.cloudMetadata.STOMPURLs, fmt.Sprintf("stomp+ssl://%v:%v", host, activeMQCloudSTOMPPort))
+	}
+
+	if len(openWireURLs) == 1 {
+		p.cloudMetadata.OpenWireURL = openWireURLs[0]
+	} else {
+		p.cloudMetadata.OpenWireURL = fmt.Sprintf("failover:(%v)", strings.Join(openWireURLs, ","))
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *activeMQImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+// getEngineVersion returns the Amazon MQ engine version, i.e. the "major.minor" part of the ActiveMQ version: Amazon MQ
+// manages patch versions itself (see AutoMinorVersionUpgrade).
+func (p *activeMQImpl) getEngineVersion() string {
+	parts := strings.SplitN(p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().ActiveMQ, ".", 3)
+	errorz.Assertf(len(parts) >= 2, "invalid ActiveMQ version", errorz.Prefix(ActiveMQPluginName))
+	return parts[0] + "." + parts[1]
+}
+
+// getCloudHosts returns the hostnames of the broker instances, given the broker ID.
+func (p *activeMQImpl) getCloudHosts(brokerID string) []string {
+	count := 1
+	if p.cfg.Cloud.GetIsMultiAZ() {
+		count = activeMQMultiAZInstanceCount
+	}
+
+	hosts := make([]string, 0, count)
+	for i := 1; i <= count; i++ {
+		hosts = append(hosts, fmt.Sprintf("%v-%v.mq.%v.amazonaws.com",
+			brokerID, i, p.cfg.Stage.GetConfig().App.GetConfig().AWSConfig.Region))
+	}
+
+	return hosts
+}
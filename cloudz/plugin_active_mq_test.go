@@ -0,0 +1,34 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestActiveMQConfig_MustValidate(t *testing.T) {
+	newConfig := func(cloud *ActiveMQConfigCloud) *ActiveMQConfig {
+		return &ActiveMQConfig{
+			Stage: &cloudStageImpl{cfg: &CloudStageConfig{Name: "staging"}},
+			Cloud: cloud,
+		}
+	}
+
+	require.NotPanics(t, func() { newConfig(nil).MustValidate(Cloud) })
+	require.NotPanics(t, func() {
+		newConfig(&ActiveMQConfigCloud{InstanceType: "mq.m5.large", IsMultiAZ: true}).MustValidate(Cloud)
+	})
+	require.Panics(t, func() { newConfig(&ActiveMQConfigCloud{IsMultiAZ: true}).MustValidate(Cloud) })
+	require.Panics(t, func() { newConfig(&ActiveMQConfigCloud{InstanceType: "m5.large"}).MustValidate(Cloud) })
+	require.Panics(t, func() { newConfig(nil).MustValidate(Local) })
+}
+
+func TestActiveMQConfigCloud_Getters(t *testing.T) {
+	var c *ActiveMQConfigCloud
+	require.Equal(t, "mq.t3.micro", c.GetInstanceType())
+	require.False(t, c.GetIsMultiAZ())
+
+	c = &ActiveMQConfigCloud{InstanceType: "mq.m5.large", IsMultiAZ: true}
+	require.Equal(t, "mq.m5.large", c.GetInstanceType())
+	require.True(t, c.GetIsMultiAZ())
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/sha1"
 	"fmt"
 	"net/url"
+	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -17,6 +18,7 @@ import (
 	"github.com/ibrt/golang-bites/boolz"
 	"github.com/ibrt/golang-bites/filez"
 	"github.com/ibrt/golang-bites/jsonz"
+	"github.com/ibrt/golang-bites/numeric/float64z"
 	"github.com/ibrt/golang-bites/numeric/intz"
 	"github.com/ibrt/golang-bites/stringz"
 	"github.com/ibrt/golang-bites/templatez"
@@ -39,6 +41,7 @@ const (
 	APIRefStage                = CloudRef("stg")
 	APIRefAPIMapping           = CloudRef("dnmap")
 	APIRefIntegration          = CloudRef("intg")
+	APIRefHealthCheck          = CloudRef("hc")
 	APIAttAPIEndpoint          = CloudAtt("ApiEndpoint")
 	APIAttRegionalDomainName   = CloudAtt("RegionalDomainName")
 	APIAttRegionalHostedZoneID = CloudAtt("RegionalHostedZoneId")
@@ -56,13 +59,19 @@ type APIConfigFunc func(Stage, *APIDependencies) *APIConfig
 type APIEventHookFunc func(API, Event, string)
 
 // APIConfig describes the api config.
+//
+// If OpenAPIFilePath is set, a route key is derived for each operation of the given OpenAPI 3 document (YAML or JSON),
+// in addition to the RouteKeys (e.g. "$default"). In local stages, requests and responses are also validated against
+// the document by a Prism proxy in front of the simulator: violations are returned as errors, so that contract drift
+// is caught during development.
 type APIConfig struct {
-	Stage     Stage    `validate:"required"`
-	Name      string   `validate:"required,resource-name"`
-	RouteKeys []string `validate:"required"`
-	Local     *APIConfigLocal
-	Cloud     *APIConfigCloud
-	EventHook APIEventHookFunc
+	Stage           Stage    `validate:"required"`
+	Name            string   `validate:"required,resource-name"`
+	RouteKeys       []string `validate:"required"`
+	OpenAPIFilePath string
+	Local           *APIConfigLocal
+	Cloud           *APIConfigCloud
+	EventHook       APIEventHookFunc
 }
 
 // MustValidate validates the api config.
@@ -70,6 +79,18 @@ func (c *APIConfig) MustValidate(stageTarget StageTarget) {
 	vz.MustValidateStruct(c)
 	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing APIConfig.Local")
 	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing APIConfig.Cloud")
+
+	if stageTarget == Cloud && c.Cloud.Routing != nil {
+		c.Cloud.Routing.MustValidate()
+	}
+
+	if stageTarget == Cloud && c.Cloud.Throttling != nil {
+		routeKeys := stringz.SliceToMap(c.RouteKeys)
+		for routeKey := range c.Cloud.Throttling.Routes {
+			_, ok := routeKeys[routeKey]
+			errorz.Assertf(ok, "unknown route key in APIConfig.Cloud.Throttling.Routes: %v", errorz.A(routeKey))
+		}
+	}
 }
 
 // APIConfigLocal describes part of the api config.
@@ -81,6 +102,23 @@ type APIConfigLocal struct {
 type APIConfigCloud struct {
 	DomainName string `validate:"required,fqdn"`
 	CORSDomain string `validate:"required,fqdn"`
+	Routing    *RecordSetRoutingConfig
+	Throttling *APIConfigCloudThrottling
+}
+
+// APIConfigCloudThrottling describes part of the api config.
+// The Default limits apply to every route, unless overridden in Routes (keyed by route key, e.g. "POST /orders").
+// Requests over the limits are rejected by API Gateway with status 429, before reaching the function.
+type APIConfigCloudThrottling struct {
+	Default *APIThrottlingLimits            `validate:"required"`
+	Routes  map[string]*APIThrottlingLimits `validate:"dive,required"`
+}
+
+// APIThrottlingLimits describes the throttling limits of an api or route, as a token bucket: RateLimit is the
+// steady-state number of requests per second, BurstLimit the maximum number of concurrent requests.
+type APIThrottlingLimits struct {
+	RateLimit  float64 `validate:"required,gt=0"`
+	BurstLimit int     `validate:"required,gt=0"`
 }
 
 // APIDependencies describes the api dependencies.
@@ -113,6 +151,7 @@ type API interface {
 	GetConfig() *APIConfig
 	GetLocalMetadata() *APILocalMetadata
 	GetCloudMetadata(require bool) *APICloudMetadata
+	GenerateContractTests(outDirPath string)
 }
 
 type apiImpl struct {
@@ -165,6 +204,11 @@ func (p *apiImpl) GetDependenciesMap() map[Plugin]struct{} {
 // Configure implements the Plugin interface.
 func (p *apiImpl) Configure(stage Stage) {
 	p.cfg = p.cfgFunc(stage, p.deps)
+
+	if p.cfg.OpenAPIFilePath != "" {
+		p.cfg.RouteKeys = mergeAPIRouteKeys(p.cfg.RouteKeys, mustGetAPIOpenAPIRouteKeys(p.cfg.OpenAPIFilePath))
+	}
+
 	p.cfg.MustValidate(stage.GetTarget())
 }
 
@@ -199,12 +243,25 @@ func (p *apiImpl) IsDeployed() bool {
 // UpdateLocalTemplate implements the Plugin interface.
 func (p *apiImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath string) {
 	containerName := LocalGetContainerName(p)
+	validatorContainerName := LocalGetContainerName(p, "validator")
 
 	p.localMetadata = &APILocalMetadata{
 		ExternalURL: urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.ExternalPort)),
 		InternalURL: urlz.MustParse(fmt.Sprintf("http://%v:%v", containerName, p.cfg.Local.ExternalPort)),
 	}
 
+	ports := []dctypes.ServicePortConfig{
+		{
+			Target:    uint32(p.cfg.Local.ExternalPort),
+			Published: uint32(p.cfg.Local.ExternalPort),
+		},
+	}
+
+	if p.cfg.OpenAPIFilePath != "" {
+		p.localMetadata.InternalURL = urlz.MustParse(fmt.Sprintf("http://%v:%v", validatorContainerName, apiOpenAPIValidatorPort))
+		ports = nil
+	}
+
 	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
 		Name: containerName,
 		Build: dctypes.BuildConfig{
@@ -213,13 +270,45 @@ func (p *apiImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath string)
 		ContainerName: containerName,
 		Image:         containerName,
 		Networks:      p.GetConfig().Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports:         ports,
+		Restart:       "unless-stopped",
+	})
+
+	if p.cfg.OpenAPIFilePath == "" {
+		return
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          validatorContainerName,
+		ContainerName: validatorContainerName,
+		Command: dctypes.ShellCommand{
+			"proxy",
+			"--host", "0.0.0.0",
+			"--port", fmt.Sprintf("%v", apiOpenAPIValidatorPort),
+			"--errors",
+			"/tmp/" + p.getLocalOpenAPIFileName(),
+			fmt.Sprintf("http://%v:%v", containerName, p.cfg.Local.ExternalPort),
+		},
+		DependsOn: []string{
+			containerName,
+		},
+		Image:    LocalGetImage(p, "stoplight/prism:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Prism),
+		Networks: p.GetConfig().Stage.AsLocalStage().GetServiceNetworkConfig(),
 		Ports: []dctypes.ServicePortConfig{
 			{
-				Target:    uint32(p.cfg.Local.ExternalPort),
+				Target:    apiOpenAPIValidatorPort,
 				Published: uint32(p.cfg.Local.ExternalPort),
 			},
 		},
 		Restart: "unless-stopped",
+		Volumes: []dctypes.ServiceVolumeConfig{
+			{
+				Type:     "bind",
+				Source:   filez.MustAbs(filepath.Join(buildDirPath, p.getLocalOpenAPIFileName())),
+				Target:   "/tmp/" + p.getLocalOpenAPIFileName(),
+				ReadOnly: true,
+			},
+		},
 	})
 }
 
@@ -270,21 +359,27 @@ func (p *apiImpl) GetCloudTemplate(_ string) *gocf.Template {
 	CloudAddExpGetAtt(tpl, p, APIRefDomainName, APIAttRegionalDomainName)
 	CloudAddExpGetAtt(tpl, p, APIRefDomainName, APIAttRegionalHostedZoneID)
 
-	tpl.Resources[APIRefRecordSet.Ref()] = &goroute53.RecordSet{
-		AliasTarget: &goroute53.RecordSet_AliasTarget{
-			DNSName:      gocf.GetAtt(APIRefDomainName.Ref(), APIAttRegionalDomainName.Ref()),
-			HostedZoneId: gocf.GetAtt(APIRefDomainName.Ref(), APIAttRegionalHostedZoneID.Ref()),
-		},
-		HostedZoneId: stringz.Ptr(p.deps.Certificate.GetConfig().Cloud.HostedZoneID),
-		Name:         p.cfg.Cloud.DomainName,
-		Type:         "A",
+	if hostedZoneID := p.deps.Certificate.GetConfig().Cloud.GetDNSProvider().GetHostedZoneID(); hostedZoneID != nil {
+		recordSet := &goroute53.RecordSet{
+			AliasTarget: &goroute53.RecordSet_AliasTarget{
+				DNSName:      gocf.GetAtt(APIRefDomainName.Ref(), APIAttRegionalDomainName.Ref()),
+				HostedZoneId: gocf.GetAtt(APIRefDomainName.Ref(), APIAttRegionalHostedZoneID.Ref()),
+			},
+			HostedZoneId: hostedZoneID,
+			Name:         p.cfg.Cloud.DomainName,
+			Type:         "A",
+		}
+		CloudApplyRecordSetRouting(tpl, p, APIRefHealthCheck, recordSet, p.cfg.Cloud.Routing)
+		tpl.Resources[APIRefRecordSet.Ref()] = recordSet
+		CloudAddExpRef(tpl, p, APIRefRecordSet)
 	}
-	CloudAddExpRef(tpl, p, APIRefRecordSet)
 
 	tpl.Resources[APIRefStage.Ref()] = &goapigwv2.Stage{
-		ApiId:      gocf.Ref(APIRefAPI.Ref()),
-		AutoDeploy: boolz.Ptr(true),
-		StageName:  "$default",
+		ApiId:                gocf.Ref(APIRefAPI.Ref()),
+		AutoDeploy:           boolz.Ptr(true),
+		DefaultRouteSettings: p.getDefaultRouteSettings(),
+		RouteSettings:        p.getRouteSettings(),
+		StageName:            "$default",
 	}
 	CloudAddExpRef(tpl, p, APIRefStage)
 
@@ -322,6 +417,35 @@ func (p *apiImpl) GetCloudTemplate(_ string) *gocf.Template {
 	return tpl
 }
 
+func (p *apiImpl) getDefaultRouteSettings() *goapigwv2.Stage_RouteSettings {
+	if p.cfg.Cloud.Throttling == nil {
+		return nil
+	}
+
+	return newAPIRouteSettings(p.cfg.Cloud.Throttling.Default)
+}
+
+func (p *apiImpl) getRouteSettings() *interface{} {
+	if p.cfg.Cloud.Throttling == nil || len(p.cfg.Cloud.Throttling.Routes) == 0 {
+		return nil
+	}
+
+	routeSettings := make(map[string]*goapigwv2.Stage_RouteSettings, len(p.cfg.Cloud.Throttling.Routes))
+	for routeKey, limits := range p.cfg.Cloud.Throttling.Routes {
+		routeSettings[routeKey] = newAPIRouteSettings(limits)
+	}
+
+	var v interface{} = routeSettings
+	return &v
+}
+
+func newAPIRouteSettings(limits *APIThrottlingLimits) *goapigwv2.Stage_RouteSettings {
+	return &goapigwv2.Stage_RouteSettings{
+		ThrottlingBurstLimit: intz.Ptr(limits.BurstLimit),
+		ThrottlingRateLimit:  float64z.Ptr(limits.RateLimit),
+	}
+}
+
 // UpdateCloudMetadata implements the Plugin interface.
 func (p *apiImpl) UpdateCloudMetadata(stack *awscft.Stack) {
 	p.cloudMetadata = &APICloudMetadata{
@@ -335,6 +459,12 @@ func (p *apiImpl) EventHook(event Event, buildDirPath string) {
 	switch event {
 	case LocalBeforeCreateEvent:
 		p.localBeforeCreateEventHook(buildDirPath)
+	case CloudPreflightEvent:
+		p.cloudPreflightEventHook()
+	case CloudAfterDeployEvent:
+		p.cloudAfterDeployEventHook()
+	case CloudBeforeDestroyEvent:
+		p.cloudBeforeDestroyEventHook()
 	}
 
 	if p.cfg.EventHook != nil {
@@ -342,6 +472,20 @@ func (p *apiImpl) EventHook(event Event, buildDirPath string) {
 	}
 }
 
+func (p *apiImpl) cloudPreflightEventHook() {
+	CloudMustCheckDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName, p.cfg.Cloud.Routing)
+}
+
+func (p *apiImpl) cloudAfterDeployEventHook() {
+	CloudMaybeUpsertDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName,
+		p.cloudMetadata.Exports.GetAtt(APIRefDomainName, APIAttRegionalDomainName))
+}
+
+func (p *apiImpl) cloudBeforeDestroyEventHook() {
+	CloudMaybeDeleteDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName,
+		p.cloudMetadata.Exports.GetAtt(APIRefDomainName, APIAttRegionalDomainName))
+}
+
 func (p *apiImpl) localBeforeCreateEventHook(buildDirPath string) {
 	filez.MustPrepareDir(buildDirPath, 0777)
 
@@ -350,7 +494,7 @@ func (p *apiImpl) localBeforeCreateEventHook(buildDirPath string) {
 		templatez.MustParseAndExecuteText(
 			assets.HTTPAPIDockerfileTemplateAsset,
 			assets.HTTPAPIDockerfileTemplateData{
-				GoVersion:  strings.TrimPrefix(runtime.Version(), "go"),
+				BaseImage:  LocalGetImage(p, fmt.Sprintf("golang:%v-alpine", strings.TrimPrefix(runtime.Version(), "go"))),
 				ListenAddr: fmt.Sprintf(":%v", p.cfg.Local.ExternalPort),
 			}))
 
@@ -374,4 +518,17 @@ func (p *apiImpl) localBeforeCreateEventHook(buildDirPath string) {
 	filez.MustWriteFile(
 		filepath.Join(buildDirPath, "config.json"), 0777, 0666,
 		jsonz.MustMarshalIndentDefault(cfg))
+
+	if p.cfg.OpenAPIFilePath != "" {
+		buf, err := os.ReadFile(p.cfg.OpenAPIFilePath)
+		errorz.MaybeMustWrap(err, errorz.M("filePath", p.cfg.OpenAPIFilePath))
+
+		filez.MustWriteFile(
+			filepath.Join(buildDirPath, p.getLocalOpenAPIFileName()), 0777, 0666,
+			buf)
+	}
+}
+
+func (p *apiImpl) getLocalOpenAPIFileName() string {
+	return apiOpenAPIValidatorFileName + filepath.Ext(p.cfg.OpenAPIFilePath)
 }
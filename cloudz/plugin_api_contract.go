@@ -0,0 +1,74 @@
+package cloudz
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/templatez"
+
+	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+)
+
+var (
+	apiRouteKeyPathParamRegexp = regexp.MustCompile(`{[^}]+}`)
+)
+
+// GenerateContractTests implements the API interface.
+//
+// Two files are generated in the given directory (the package name is taken from its last element). The first one,
+// "api_contract_gen_test.go", is always regenerated: it contains the route keys and the test runner, including a test
+// that fails if the route keys and the test cases are out of sync. The second one, "api_contract_cases_test.go", is only
+// generated if missing: it contains a skeleton test case for each route key, meant to be edited by hand.
+//
+// The tests run against the URL of the stage the API is configured with, or "API_CONTRACT_TEST_URL" if set.
+func (p *apiImpl) GenerateContractTests(outDirPath string) {
+	data := &assets.HTTPAPIContractTemplateData{
+		PackageName: filepath.Base(outDirPath),
+		APIName:     p.cfg.Name,
+		Routes:      make([]*assets.HTTPAPIContractTemplateDataRoute, 0, len(p.cfg.RouteKeys)),
+	}
+
+	switch p.cfg.Stage.GetTarget() {
+	case Local:
+		data.DefaultURL = p.GetLocalMetadata().ExternalURL.String()
+	case Cloud:
+		data.DefaultURL = p.GetCloudMetadata(true).URL.String()
+	}
+
+	for _, routeKey := range p.cfg.RouteKeys {
+		data.Routes = append(data.Routes, getAPIContractTemplateDataRoute(routeKey))
+	}
+
+	filez.MustWriteFile(
+		filepath.Join(outDirPath, "api_contract_gen_test.go"), 0777, 0666,
+		templatez.MustParseAndExecuteGo(assets.HTTPAPIContractGenTemplateAsset, data))
+
+	if casesFilePath := filepath.Join(outDirPath, "api_contract_cases_test.go"); !filez.MustCheckExists(casesFilePath) {
+		filez.MustWriteFile(
+			casesFilePath, 0777, 0666,
+			templatez.MustParseAndExecuteGo(assets.HTTPAPIContractCasesTemplateAsset, data))
+	}
+}
+
+func getAPIContractTemplateDataRoute(routeKey string) *assets.HTTPAPIContractTemplateDataRoute {
+	route := &assets.HTTPAPIContractTemplateDataRoute{
+		RouteKey: routeKey,
+		Path:     "/",
+	}
+
+	parts := strings.SplitN(routeKey, " ", 2)
+	if len(parts) != 2 {
+		return route // e.g. "$default"
+	}
+
+	route.Path = apiRouteKeyPathParamRegexp.ReplaceAllString(parts[1], "test")
+
+	switch parts[0] {
+	case "POST", "PUT", "PATCH":
+		route.Body = "{}"
+	}
+
+	return route
+}
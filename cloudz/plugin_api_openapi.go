@@ -0,0 +1,78 @@
+package cloudz
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ibrt/golang-errors/errorz"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	apiOpenAPIValidatorPort     = 4010
+	apiOpenAPIValidatorFileName = "openapi"
+)
+
+var (
+	apiOpenAPIMethods = map[string]struct{}{
+		"get":     {},
+		"put":     {},
+		"post":    {},
+		"delete":  {},
+		"options": {},
+		"head":    {},
+		"patch":   {},
+		"trace":   {},
+	}
+)
+
+// apiOpenAPIDocument describes the subset of an OpenAPI document needed to derive route keys.
+type apiOpenAPIDocument struct {
+	OpenAPI string                          `yaml:"openapi"`
+	Paths   map[string]map[string]yaml.Node `yaml:"paths"`
+}
+
+// mustGetAPIOpenAPIRouteKeys parses the OpenAPI 3 document (YAML or JSON) at the given path, and returns a sorted route
+// key (e.g. "GET /orders/{id}") for each operation. OpenAPI path templates use the same syntax as HTTP API route keys.
+func mustGetAPIOpenAPIRouteKeys(filePath string) []string {
+	buf, err := os.ReadFile(filePath)
+	errorz.MaybeMustWrap(err, errorz.M("filePath", filePath))
+
+	doc := &apiOpenAPIDocument{}
+	errorz.MaybeMustWrap(yaml.Unmarshal(buf, doc), errorz.M("filePath", filePath))
+	errorz.Assertf(strings.HasPrefix(doc.OpenAPI, "3."), "unsupported OpenAPI version: %v", errorz.A(doc.OpenAPI), errorz.M("filePath", filePath))
+
+	routeKeys := make([]string, 0)
+
+	for path, pathItem := range doc.Paths {
+		errorz.Assertf(strings.HasPrefix(path, "/"), "invalid OpenAPI path: %v", errorz.A(path), errorz.M("filePath", filePath))
+
+		for method := range pathItem {
+			if _, ok := apiOpenAPIMethods[method]; ok {
+				routeKeys = append(routeKeys, fmt.Sprintf("%v %v", strings.ToUpper(method), path))
+			}
+		}
+	}
+
+	sort.Strings(routeKeys)
+	return routeKeys
+}
+
+// mergeAPIRouteKeys returns the union of the given route keys, preserving the order of the first occurrence.
+func mergeAPIRouteKeys(routeKeys ...[]string) []string {
+	merged := make([]string, 0)
+	seen := map[string]struct{}{}
+
+	for _, keys := range routeKeys {
+		for _, key := range keys {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				merged = append(merged, key)
+			}
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,56 @@
+package cloudz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMustGetAPIOpenAPIRouteKeys(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "openapi.yaml")
+
+	require.NoError(t, os.WriteFile(filePath, []byte(`openapi: 3.0.3
+info:
+  title: Test
+  version: 1.0.0
+paths:
+  /orders:
+    summary: Orders
+    get:
+      responses:
+        "200":
+          description: OK
+    post:
+      responses:
+        "201":
+          description: Created
+  /orders/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      responses:
+        "200":
+          description: OK
+`), 0666))
+
+	require.Equal(t, []string{
+		"GET /orders",
+		"GET /orders/{id}",
+		"POST /orders",
+	}, mustGetAPIOpenAPIRouteKeys(filePath))
+
+	require.NoError(t, os.WriteFile(filePath, []byte(`{"swagger": "2.0", "paths": {}}`), 0666))
+	require.Panics(t, func() { mustGetAPIOpenAPIRouteKeys(filePath) })
+}
+
+func TestMergeAPIRouteKeys(t *testing.T) {
+	require.Equal(t,
+		[]string{"$default", "GET /orders", "POST /orders"},
+		mergeAPIRouteKeys([]string{"$default", "GET /orders"}, []string{"GET /orders", "POST /orders"}))
+}
@@ -0,0 +1,344 @@
+package cloudz
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goapprunner "github.com/awslabs/goformation/v6/cloudformation/apprunner"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// AppRunnerService constants.
+const (
+	AppRunnerServicePluginDisplayName = "AppRunnerService"
+	AppRunnerServicePluginName        = "app-runner-service"
+	AppRunnerServiceRefRoleAccess     = CloudRef("r-acc")
+	AppRunnerServiceRefRoleInstance   = CloudRef("r-ins")
+	AppRunnerServiceRefService        = CloudRef("s")
+	AppRunnerServiceAttARN            = CloudAtt("Arn")
+	AppRunnerServiceAttServiceID      = CloudAtt("ServiceId")
+	AppRunnerServiceAttServiceURL     = CloudAtt("ServiceUrl")
+)
+
+var (
+	_ AppRunnerService = &appRunnerServiceImpl{}
+	_ Plugin           = &appRunnerServiceImpl{}
+)
+
+// AppRunnerServiceConfigFunc returns the App Runner service config for a given Stage.
+type AppRunnerServiceConfigFunc func(Stage, *AppRunnerServiceDependencies) *AppRunnerServiceConfig
+
+// AppRunnerServiceEventHookFunc describes an App Runner service event hook.
+type AppRunnerServiceEventHookFunc func(AppRunnerService, Event, string)
+
+// AppRunnerServiceConfig describes the App Runner service config.
+//
+// The image is built from the Dockerfile in DirPath, and is expected to serve HTTP on Port. In the cloud, it runs on
+// AWS App Runner, which provides auto scaling and a managed TLS endpoint (see AppRunnerServiceCloudMetadata.URL), as a
+// simpler alternative to a ContainerService for low-traffic services. The Environment is set in plain text: secrets
+// should be read at runtime (e.g. from Secrets Manager, allowed by the RolePolicies).
+type AppRunnerServiceConfig struct {
+	Stage       Stage  `validate:"required"`
+	Name        string `validate:"required,resource-name"`
+	DirPath     string `validate:"required"`
+	Port        uint16 `validate:"required"`
+	Environment map[string]string
+	Local       *AppRunnerServiceConfigLocal
+	Cloud       *AppRunnerServiceConfigCloud
+	EventHook   AppRunnerServiceEventHookFunc
+}
+
+// MustValidate validates the App Runner service config.
+func (c *AppRunnerServiceConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing AppRunnerServiceConfig.Local")
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing AppRunnerServiceConfig.Cloud")
+}
+
+// AppRunnerServiceConfigLocal describes part of the App Runner service config.
+type AppRunnerServiceConfigLocal struct {
+	ExternalPort uint16 `validate:"required"`
+}
+
+// AppRunnerServiceConfigCloud describes part of the App Runner service config.
+// The CPU (in vCPU units) and Memory (in MiB) must be a combination supported by App Runner.
+type AppRunnerServiceConfigCloud struct {
+	HealthCheckPath string `validate:"required,startswith=/"`
+	CPU             int    `validate:"required,oneof=256 512 1024 2048 4096"`
+	Memory          int    `validate:"required,oneof=512 1024 2048 3072 4096 6144 8192 10240 12288"`
+	RolePolicies    []goiam.Role_Policy
+}
+
+// AppRunnerServiceDependencies describes the App Runner service dependencies.
+type AppRunnerServiceDependencies struct {
+	ImageRepository   ImageRepository `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the App Runner service dependencies.
+func (d *AppRunnerServiceDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// AppRunnerServiceLocalMetadata describes the App Runner service local metadata.
+type AppRunnerServiceLocalMetadata struct {
+	ContainerName string
+	ExternalURL   *url.URL
+	InternalURL   *url.URL
+}
+
+// AppRunnerServiceCloudMetadata describes the App Runner service cloud metadata.
+type AppRunnerServiceCloudMetadata struct {
+	Exports CloudExports
+	URL     *url.URL
+}
+
+// AppRunnerService describes an App Runner service.
+type AppRunnerService interface {
+	Plugin
+	GetConfig() *AppRunnerServiceConfig
+	GetLocalMetadata() *AppRunnerServiceLocalMetadata
+	GetCloudMetadata(require bool) *AppRunnerServiceCloudMetadata
+}
+
+type appRunnerServiceImpl struct {
+	cfgFunc       AppRunnerServiceConfigFunc
+	deps          *AppRunnerServiceDependencies
+	cfg           *AppRunnerServiceConfig
+	localMetadata *AppRunnerServiceLocalMetadata
+	cloudMetadata *AppRunnerServiceCloudMetadata
+}
+
+// NewAppRunnerService initializes a new AppRunnerService.
+func NewAppRunnerService(cfgFunc AppRunnerServiceConfigFunc, deps *AppRunnerServiceDependencies) AppRunnerService {
+	deps.MustValidate()
+
+	return &appRunnerServiceImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*appRunnerServiceImpl) GetDisplayName() string {
+	return AppRunnerServicePluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *appRunnerServiceImpl) GetName() string {
+	return AppRunnerServicePluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *appRunnerServiceImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *appRunnerServiceImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.ImageRepository: {},
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *appRunnerServiceImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *appRunnerServiceImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(AppRunnerServicePluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the AppRunnerService interface.
+func (p *appRunnerServiceImpl) GetConfig() *AppRunnerServiceConfig {
+	return p.cfg
+}
+
+// GetLocalMetadata implements the AppRunnerService interface.
+func (p *appRunnerServiceImpl) GetLocalMetadata() *AppRunnerServiceLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(AppRunnerServicePluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the AppRunnerService interface.
+func (p *appRunnerServiceImpl) GetCloudMetadata(require bool) *AppRunnerServiceCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(AppRunnerServicePluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *appRunnerServiceImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *appRunnerServiceImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
+	containerName := LocalGetContainerName(p)
+
+	p.localMetadata = &AppRunnerServiceLocalMetadata{
+		ContainerName: containerName,
+		ExternalURL:   urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.ExternalPort)),
+		InternalURL:   urlz.MustParse(fmt.Sprintf("http://%v:%v", containerName, p.cfg.Port)),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name: containerName,
+		Build: dctypes.BuildConfig{
+			Context: p.cfg.DirPath,
+		},
+		ContainerName: containerName,
+		Environment: func() map[string]*string {
+			e := make(map[string]*string)
+			for k, v := range p.cfg.Environment {
+				e[k] = stringz.Ptr(v)
+			}
+			return e
+		}(),
+		Image:    containerName,
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    uint32(p.cfg.Port),
+				Published: uint32(p.cfg.Local.ExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *appRunnerServiceImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	tpl.Resources[AppRunnerServiceRefRoleAccess.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("build.apprunner.amazonaws.com"),
+		ManagedPolicyArns: &[]string{
+			"arn:aws:iam::aws:policy/service-role/AWSAppRunnerServicePolicyForECRAccess",
+		},
+		RoleName: stringz.Ptr(AppRunnerServiceRefRoleAccess.Name(p)),
+		Tags:     CloudGetDefaultTags(AppRunnerServiceRefRoleAccess.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, AppRunnerServiceRefRoleAccess)
+	CloudAddExpGetAtt(tpl, p, AppRunnerServiceRefRoleAccess, AppRunnerServiceAttARN)
+
+	tpl.Resources[AppRunnerServiceRefRoleInstance.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("tasks.apprunner.amazonaws.com"),
+		Policies: func() *[]goiam.Role_Policy {
+			if len(p.cfg.Cloud.RolePolicies) == 0 {
+				return nil
+			}
+			return &p.cfg.Cloud.RolePolicies
+		}(),
+		RoleName: stringz.Ptr(AppRunnerServiceRefRoleInstance.Name(p)),
+		Tags:     CloudGetDefaultTags(AppRunnerServiceRefRoleInstance.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, AppRunnerServiceRefRoleInstance)
+	CloudAddExpGetAtt(tpl, p, AppRunnerServiceRefRoleInstance, AppRunnerServiceAttARN)
+
+	tpl.Resources[AppRunnerServiceRefService.Ref()] = &goapprunner.Service{
+		HealthCheckConfiguration: &goapprunner.Service_HealthCheckConfiguration{
+			Path:     stringz.Ptr(p.cfg.Cloud.HealthCheckPath),
+			Protocol: stringz.Ptr("HTTP"),
+		},
+		InstanceConfiguration: &goapprunner.Service_InstanceConfiguration{
+			Cpu:             stringz.Ptr(fmt.Sprintf("%v", p.cfg.Cloud.CPU)),
+			InstanceRoleArn: stringz.Ptr(gocf.GetAtt(AppRunnerServiceRefRoleInstance.Ref(), AppRunnerServiceAttARN.Ref())),
+			Memory:          stringz.Ptr(fmt.Sprintf("%v", p.cfg.Cloud.Memory)),
+		},
+		SourceConfiguration: &goapprunner.Service_SourceConfiguration{
+			AuthenticationConfiguration: &goapprunner.Service_AuthenticationConfiguration{
+				AccessRoleArn: stringz.Ptr(gocf.GetAtt(AppRunnerServiceRefRoleAccess.Ref(), AppRunnerServiceAttARN.Ref())),
+			},
+			AutoDeploymentsEnabled: boolz.Ptr(false),
+			ImageRepository: &goapprunner.Service_ImageRepository{
+				ImageConfiguration: &goapprunner.Service_ImageConfiguration{
+					Port:                        stringz.Ptr(fmt.Sprintf("%v", p.cfg.Port)),
+					RuntimeEnvironmentVariables: p.getRuntimeEnvironmentVariables(),
+				},
+				ImageIdentifier:     p.getImageWithTag(),
+				ImageRepositoryType: "ECR",
+			},
+		},
+		Tags: CloudGetDefaultTags(AppRunnerServiceRefService.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, AppRunnerServiceRefService)
+	CloudAddExpGetAtt(tpl, p, AppRunnerServiceRefService, AppRunnerServiceAttServiceID)
+	CloudAddExpGetAtt(tpl, p, AppRunnerServiceRefService, AppRunnerServiceAttServiceURL)
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *appRunnerServiceImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+
+	p.cloudMetadata = &AppRunnerServiceCloudMetadata{
+		Exports: exports,
+		URL:     urlz.MustParse(fmt.Sprintf("https://%v", exports.GetAtt(AppRunnerServiceRefService, AppRunnerServiceAttServiceURL))),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *appRunnerServiceImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case CloudBeforeDeployEvent:
+		p.cloudBeforeDeployEventHook()
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *appRunnerServiceImpl) cloudBeforeDeployEventHook() {
+	imageWithTag := p.getImageWithTag()
+
+	CloudBuildImage(p, p.cfg.DirPath, imageWithTag)
+	p.cfg.Stage.GetConfig().App.GetOperations().DockerLoginToECR()
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker", "push", imageWithTag).MustRun()
+}
+
+func (p *appRunnerServiceImpl) getImageWithTag() string {
+	return p.deps.ImageRepository.GetCloudMetadata(true).ImageName + ":" + p.cfg.Stage.AsCloudStage().GetCloudConfig().Version
+}
+
+func (p *appRunnerServiceImpl) getRuntimeEnvironmentVariables() *[]goapprunner.Service_KeyValuePair {
+	if len(p.cfg.Environment) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(p.cfg.Environment))
+	for k := range p.cfg.Environment {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]goapprunner.Service_KeyValuePair, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, goapprunner.Service_KeyValuePair{
+			Name:  stringz.Ptr(k),
+			Value: stringz.Ptr(p.cfg.Environment[k]),
+		})
+	}
+
+	return &kvs
+}
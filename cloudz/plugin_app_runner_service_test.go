@@ -0,0 +1,25 @@
+package cloudz
+
+import (
+	"testing"
+
+	goapprunner "github.com/awslabs/goformation/v6/cloudformation/apprunner"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppRunnerService_GetRuntimeEnvironmentVariables(t *testing.T) {
+	p := &appRunnerServiceImpl{
+		cfg: &AppRunnerServiceConfig{},
+	}
+	require.Nil(t, p.getRuntimeEnvironmentVariables())
+
+	p.cfg.Environment = map[string]string{
+		"B": "2",
+		"A": "1",
+	}
+	require.Equal(t, &[]goapprunner.Service_KeyValuePair{
+		{Name: stringz.Ptr("A"), Value: stringz.Ptr("1")},
+		{Name: stringz.Ptr("B"), Value: stringz.Ptr("2")},
+	}, p.getRuntimeEnvironmentVariables())
+}
@@ -0,0 +1,313 @@
+package cloudz
+
+import (
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	gobackup "github.com/awslabs/goformation/v6/cloudformation/backup"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	"github.com/awslabs/goformation/v6/cloudformation/policies"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/numeric/float64z"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// Backup constants.
+const (
+	BackupPluginDisplayName = "Backup"
+	BackupPluginName        = "backup"
+	BackupRefVault          = CloudRef("v")
+	BackupRefPlan           = CloudRef("p")
+	BackupRefRole           = CloudRef("r")
+	BackupAttARN            = CloudAtt("Arn")
+	BackupAttBackupVaultARN = CloudAtt("BackupVaultArn")
+	BackupAttBackupPlanARN  = CloudAtt("BackupPlanArn")
+
+	// BackupDefaultScheduleExpression runs backups daily at 05:00 UTC.
+	BackupDefaultScheduleExpression = "cron(0 5 ? * * *)"
+
+	backupRuleName                   = "default"
+	backupContinuousMaxRetentionDays = 35
+)
+
+var (
+	_ Backup = &backupImpl{}
+	_ Plugin = &backupImpl{}
+)
+
+// BackupConfigFunc returns the backup config for a given Stage.
+type BackupConfigFunc func(Stage, *BackupDependencies) *BackupConfig
+
+// BackupEventHookFunc describes a backup event hook.
+type BackupEventHookFunc func(Backup, Event, string)
+
+// BackupConfig describes the backup config.
+//
+// The backup is an AWS Backup vault and plan, backing up the Postgres and EFS dependencies on the given schedule (which
+// defaults to BackupDefaultScheduleExpression), and keeping recovery points for RetentionDays. Resources are selected by
+// the tags propagated from the stacks of the dependencies (see CloudGetStackTags). The vault is retained when the stack
+// is deleted, as it cannot be deleted while it holds recovery points. Backups are not run in local stages.
+type BackupConfig struct {
+	Stage     Stage  `validate:"required"`
+	Name      string `validate:"required,resource-name"`
+	Cloud     *BackupConfigCloud
+	EventHook BackupEventHookFunc
+}
+
+// MustValidate validates the backup config.
+func (c *BackupConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing BackupConfig.Cloud", errorz.Prefix(BackupPluginName))
+
+	if c.Cloud != nil && c.Cloud.IsContinuousBackupEnabled {
+		errorz.Assertf(c.Cloud.RetentionDays <= backupContinuousMaxRetentionDays,
+			"BackupConfigCloud.RetentionDays must be <= %v with continuous backup", errorz.A(backupContinuousMaxRetentionDays), errorz.Prefix(BackupPluginName))
+	}
+}
+
+// BackupConfigCloud describes part of the backup config.
+// If IsContinuousBackupEnabled, Postgres instances also support point-in-time recovery (RetentionDays must be <= 35).
+type BackupConfigCloud struct {
+	ScheduleExpression        string `validate:"omitempty,startswith=cron(|startswith=rate("`
+	RetentionDays             int    `validate:"required,min=1"`
+	IsContinuousBackupEnabled bool
+}
+
+// BackupDependencies describes the backup dependencies.
+type BackupDependencies struct {
+	Postgres          []Postgres
+	EFS               []EFS
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the backup dependencies.
+func (d *BackupDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+	errorz.Assertf(len(d.Postgres)+len(d.EFS) > 0, "missing BackupDependencies.Postgres or BackupDependencies.EFS", errorz.Prefix(BackupPluginName))
+}
+
+// BackupCloudMetadata describes the backup cloud metadata.
+type BackupCloudMetadata struct {
+	Exports CloudExports
+}
+
+// Backup describes a backup.
+type Backup interface {
+	Plugin
+	GetConfig() *BackupConfig
+	GetCloudMetadata(require bool) *BackupCloudMetadata
+}
+
+type backupImpl struct {
+	cfgFunc       BackupConfigFunc
+	deps          *BackupDependencies
+	cfg           *BackupConfig
+	cloudMetadata *BackupCloudMetadata
+}
+
+// NewBackup initializes a new Backup.
+func NewBackup(cfgFunc BackupConfigFunc, deps *BackupDependencies) Backup {
+	deps.MustValidate()
+
+	return &backupImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*backupImpl) GetDisplayName() string {
+	return BackupPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *backupImpl) GetName() string {
+	return BackupPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *backupImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *backupImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{}
+
+	for _, postgres := range p.deps.Postgres {
+		dependenciesMap[postgres] = struct{}{}
+	}
+
+	for _, efs := range p.deps.EFS {
+		dependenciesMap[efs] = struct{}{}
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *backupImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *backupImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(BackupPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the Backup interface.
+func (p *backupImpl) GetConfig() *BackupConfig {
+	return p.cfg
+}
+
+// GetCloudMetadata implements the Backup interface.
+func (p *backupImpl) GetCloudMetadata(require bool) *BackupCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(BackupPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *backupImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *backupImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
+	// nothing to do here
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *backupImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	tpl.Resources[BackupRefVault.Ref()] = &gobackup.BackupVault{
+		AWSCloudFormationDeletionPolicy:      policies.DeletionPolicy("Retain"),
+		AWSCloudFormationUpdateReplacePolicy: policies.UpdateReplacePolicy("Retain"),
+		BackupVaultName:                      BackupRefVault.Name(p),
+		BackupVaultTags: &map[string]string{
+			"Name": BackupRefVault.Name(p),
+		},
+	}
+	CloudAddExpRef(tpl, p, BackupRefVault)
+	CloudAddExpGetAtt(tpl, p, BackupRefVault, BackupAttBackupVaultARN)
+
+	tpl.Resources[BackupRefPlan.Ref()] = &gobackup.BackupPlan{
+		BackupPlan: &gobackup.BackupPlan_BackupPlanResourceType{
+			BackupPlanName: BackupRefPlan.Name(p),
+			BackupPlanRule: []gobackup.BackupPlan_BackupRuleResourceType{
+				{
+					EnableContinuousBackup: func() *bool {
+						if p.cfg.Cloud.IsContinuousBackupEnabled {
+							return boolz.Ptr(true)
+						}
+						return nil
+					}(),
+					Lifecycle: &gobackup.BackupPlan_LifecycleResourceType{
+						DeleteAfterDays: float64z.Ptr(float64(p.cfg.Cloud.RetentionDays)),
+					},
+					RuleName:           backupRuleName,
+					ScheduleExpression: stringz.Ptr(p.getScheduleExpression()),
+					TargetBackupVault:  gocf.Ref(BackupRefVault.Ref()),
+				},
+			},
+		},
+		BackupPlanTags: &map[string]string{
+			"Name": BackupRefPlan.Name(p),
+		},
+	}
+	CloudAddExpRef(tpl, p, BackupRefPlan)
+	CloudAddExpGetAtt(tpl, p, BackupRefPlan, BackupAttBackupPlanARN)
+
+	tpl.Resources[BackupRefRole.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("backup.amazonaws.com"),
+		ManagedPolicyArns: &[]string{
+			"arn:aws:iam::aws:policy/service-role/AWSBackupServiceRolePolicyForBackup",
+			"arn:aws:iam::aws:policy/service-role/AWSBackupServiceRolePolicyForRestores",
+		},
+		RoleName: stringz.Ptr(BackupRefRole.Name(p)),
+		Tags:     CloudGetDefaultTags(BackupRefRole.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, BackupRefRole)
+	CloudAddExpGetAtt(tpl, p, BackupRefRole, BackupAttARN)
+
+	for _, postgres := range p.deps.Postgres {
+		resourceARN := "arn:${AWS::Partition}:rds:${AWS::Region}:${AWS::AccountId}:db:*"
+		if postgres.GetConfig().Cloud.Aurora != nil {
+			resourceARN = "arn:${AWS::Partition}:rds:${AWS::Region}:${AWS::AccountId}:cluster:*"
+		}
+
+		p.addSelection(tpl, postgres, resourceARN)
+	}
+
+	for _, efs := range p.deps.EFS {
+		p.addSelection(tpl, efs, "arn:${AWS::Partition}:elasticfilesystem:${AWS::Region}:${AWS::AccountId}:file-system/*")
+	}
+
+	return tpl
+}
+
+// addSelection selects the resources of the given dependency matching the given ARN pattern (a Fn::Sub template), by
+// the tags propagated from its stack.
+func (p *backupImpl) addSelection(tpl *gocf.Template, dependency Plugin, resourceARN string) {
+	selectionRef := CloudRef("s-" + GetMetadataKey(dependency))
+	stackTags := CloudGetStackTags(dependency)
+
+	tpl.Resources[selectionRef.Ref()] = &gobackup.BackupSelection{
+		BackupPlanId: gocf.Ref(BackupRefPlan.Ref()),
+		BackupSelection: &gobackup.BackupSelection_BackupSelectionResourceType{
+			Conditions: func() *interface{} {
+				var conditions interface{} = map[string]interface{}{
+					"StringEquals": []map[string]string{
+						{
+							"ConditionKey":   "aws:ResourceTag/" + CloudStackTagApp,
+							"ConditionValue": stackTags[CloudStackTagApp],
+						},
+						{
+							"ConditionKey":   "aws:ResourceTag/" + CloudStackTagStage,
+							"ConditionValue": stackTags[CloudStackTagStage],
+						},
+						{
+							"ConditionKey":   "aws:ResourceTag/" + CloudStackTagPlugin,
+							"ConditionValue": stackTags[CloudStackTagPlugin],
+						},
+					},
+				}
+				return &conditions
+			}(),
+			IamRoleArn: gocf.GetAtt(BackupRefRole.Ref(), BackupAttARN.Ref()),
+			Resources: &[]string{
+				gocf.Sub(resourceARN),
+			},
+			SelectionName: GetMetadataKey(dependency), // unique within the plan
+		},
+	}
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *backupImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &BackupCloudMetadata{
+		Exports: NewCloudExports(stack),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *backupImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *backupImpl) getScheduleExpression() string {
+	if p.cfg.Cloud.ScheduleExpression != "" {
+		return p.cfg.Cloud.ScheduleExpression
+	}
+	return BackupDefaultScheduleExpression
+}
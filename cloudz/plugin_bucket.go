@@ -3,6 +3,7 @@ package cloudz
 import (
 	"fmt"
 	"net/url"
+	"path/filepath"
 
 	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	gocf "github.com/awslabs/goformation/v6/cloudformation"
@@ -27,14 +28,18 @@ const (
 	BucketAttDualStackDomainName = CloudAtt("DualStackDomainName")
 	BucketAttRegionalDomainName  = CloudAtt("RegionalDomainName")
 
-	minioVersion     = "2022.4.16"
-	minioPort        = 9000
-	minioConsolePort = 9001
+	minioPort               = 9000
+	minioConsolePort        = 9001
+	bucketSnapshotDirName   = "objects"
+	bucketSnapshotMCAlias   = "local"
+	bucketSnapshotTmpPrefix = "/tmp/snapshot-"
 )
 
 var (
-	_ Bucket = &bucketImpl{}
-	_ Plugin = &bucketImpl{}
+	_ Bucket           = &bucketImpl{}
+	_ Plugin           = &bucketImpl{}
+	_ cloudBucketOwner = &bucketImpl{}
+	_ localSnapshotter = &bucketImpl{}
 )
 
 // BucketConfigFunc returns the bucket config for a given Stage.
@@ -86,8 +91,8 @@ func (d *BucketDependencies) MustValidate() {
 // BucketLocalMetadata describes the bucket local metadata.
 type BucketLocalMetadata struct {
 	ContainerName      string
-	AccessKey          string
-	SecretKey          string
+	AccessKey          string `metadata:"secret"`
+	SecretKey          string `metadata:"secret"`
 	BucketName         string
 	ExternalURL        *url.URL
 	InternalURL        *url.URL
@@ -192,6 +197,7 @@ func (p *bucketImpl) IsDeployed() bool {
 
 // UpdateLocalTemplate implements the Plugin interface.
 func (p *bucketImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
+	// Note: the container is shared by all the Bucket plugins, so its name and the bucket names bypass the NamingStrategy.
 	containerName := fmt.Sprintf("%v-%v", p.cfg.Stage.GetConfig().App.GetConfig().Name, BucketPluginName)
 	bucketName := fmt.Sprintf("%v-%v", p.cfg.Stage.GetConfig().App.GetConfig().Name, p.cfg.Name)
 
@@ -237,7 +243,7 @@ func (p *bucketImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
 			"BITNAMI_DEBUG":         stringz.Ptr("true"),
 			"MINIO_DEFAULT_BUCKETS": stringz.Ptr(bucketName + bucketSuffix),
 		},
-		Image:    "bitnami/minio:" + minioVersion,
+		Image:    LocalGetImage(p, "bitnami/minio:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().MinIO),
 		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
 		Ports: []dctypes.ServicePortConfig{
 			{
@@ -337,6 +343,50 @@ func (p *bucketImpl) UpdateCloudMetadata(stack *awscft.Stack) {
 	}
 }
 
+// getCloudBucketNames implements the cloudBucketOwner interface.
+func (p *bucketImpl) getCloudBucketNames() []string {
+	return []string{p.cloudMetadata.BucketName}
+}
+
+// snapshotLocal implements the localSnapshotter interface.
+// Note: objects are mirrored as plain files, so their metadata (e.g. content type) is not preserved.
+func (p *bucketImpl) snapshotLocal(dirPath string) {
+	tmpDirPath := bucketSnapshotTmpPrefix + p.localMetadata.BucketName
+
+	p.runLocalMinIOClientScript(fmt.Sprintf("rm -rf %[1]v && mkdir -p %[1]v && mc mirror --overwrite %[2]v/%[3]v %[1]v",
+		tmpDirPath, bucketSnapshotMCAlias, p.localMetadata.BucketName))
+
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker").
+		AddParams("cp", fmt.Sprintf("%v:%v/.", p.localMetadata.ContainerName, tmpDirPath), filepath.Join(dirPath, bucketSnapshotDirName)).
+		MustRun()
+
+	p.runLocalMinIOClientScript(fmt.Sprintf("rm -rf %v", tmpDirPath))
+}
+
+// restoreLocal implements the localSnapshotter interface.
+// Objects not in the snapshot are removed from the bucket.
+func (p *bucketImpl) restoreLocal(dirPath string) {
+	tmpDirPath := bucketSnapshotTmpPrefix + p.localMetadata.BucketName
+	p.runLocalMinIOClientScript(fmt.Sprintf("rm -rf %v", tmpDirPath))
+
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker").
+		AddParams("cp", filepath.Join(dirPath, bucketSnapshotDirName)+"/.", fmt.Sprintf("%v:%v", p.localMetadata.ContainerName, tmpDirPath)).
+		MustRun()
+
+	p.runLocalMinIOClientScript(fmt.Sprintf("mc mirror --overwrite --remove %[1]v %[2]v/%[3]v && rm -rf %[1]v",
+		tmpDirPath, bucketSnapshotMCAlias, p.localMetadata.BucketName))
+}
+
+// runLocalMinIOClientScript runs the given script in the local MinIO container, with the MinIO client configured.
+func (p *bucketImpl) runLocalMinIOClientScript(script string) {
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker").
+		AddParams("exec", "-u", "root", "-e").
+		AddSecretParams(fmt.Sprintf("MC_HOST_%v=http://%v:%v@localhost:%v",
+			bucketSnapshotMCAlias, p.localMetadata.AccessKey, p.localMetadata.SecretKey, minioPort)).
+		AddParams(p.localMetadata.ContainerName, "sh", "-c", script).
+		MustRun()
+}
+
 // EventHook implements the Plugin interface.
 func (p *bucketImpl) EventHook(event Event, buildDirPath string) {
 	if p.cfg.EventHook != nil {
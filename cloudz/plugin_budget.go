@@ -0,0 +1,264 @@
+package cloudz
+
+import (
+	"fmt"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	gobudgets "github.com/awslabs/goformation/v6/cloudformation/budgets"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// Budget constants.
+const (
+	BudgetPluginDisplayName = "Budget"
+	BudgetPluginName        = "budget"
+	BudgetRefBudget         = CloudRef("b")
+)
+
+var (
+	_ Budget = &budgetImpl{}
+	_ Plugin = &budgetImpl{}
+)
+
+// BudgetConfigFunc returns the budget config for a given Stage.
+type BudgetConfigFunc func(Stage, *BudgetDependencies) *BudgetConfig
+
+// BudgetEventHookFunc describes a budget event hook.
+type BudgetEventHookFunc func(Budget, Event, string)
+
+// BudgetConfig describes the budget config.
+type BudgetConfig struct {
+	Stage     Stage  `validate:"required"`
+	Name      string `validate:"required,resource-name"`
+	Cloud     *BudgetConfigCloud
+	EventHook BudgetEventHookFunc
+}
+
+// MustValidate validates the budget config.
+func (c *BudgetConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing BudgetConfig.Cloud")
+}
+
+// BudgetConfigCloud describes part of the budget config.
+//
+// Unless AccountWide is set, the budget only tracks the costs of resources with the "Stage" tag of the stage (see
+// CloudGetStackTags), which must be activated as a cost allocation tag in the billing console. SNS topics must allow
+// "budgets.amazonaws.com" to publish to them. The Thresholds default to 80% and 100% of the actual spend, and 100% of
+// the forecasted spend.
+type BudgetConfigCloud struct {
+	MonthlyLimitUSD float64                       `validate:"required,gt=0"`
+	Thresholds      []*BudgetConfigCloudThreshold `validate:"dive,required"`
+	EmailAddresses  []string                      `validate:"max=10,dive,email"`
+	SNSTopicARN     string
+	AccountWide     bool
+}
+
+// BudgetConfigCloudThreshold describes a notification threshold, as a percentage of the monthly limit.
+type BudgetConfigCloudThreshold struct {
+	Percentage float64 `validate:"required,gt=0"`
+	Forecasted bool    // if true, notifies when the forecasted spend exceeds the threshold
+}
+
+// BudgetDependencies describes the budget dependencies.
+type BudgetDependencies struct {
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the budget dependencies.
+func (d *BudgetDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// BudgetCloudMetadata describes the budget cloud metadata.
+type BudgetCloudMetadata struct {
+	Exports CloudExports
+}
+
+// GetName returns the budget name.
+func (m *BudgetCloudMetadata) GetName() string {
+	return m.Exports.GetRef(BudgetRefBudget)
+}
+
+// Budget describes an AWS Budgets cost budget, which sends notifications when the monthly spend of the stage exceeds
+// the configured thresholds. It has no local equivalent.
+type Budget interface {
+	Plugin
+	GetConfig() *BudgetConfig
+	GetCloudMetadata(require bool) *BudgetCloudMetadata
+}
+
+type budgetImpl struct {
+	cfgFunc       BudgetConfigFunc
+	deps          *BudgetDependencies
+	cfg           *BudgetConfig
+	cloudMetadata *BudgetCloudMetadata
+}
+
+// NewBudget initializes a new Budget.
+func NewBudget(cfgFunc BudgetConfigFunc, deps *BudgetDependencies) Budget {
+	deps.MustValidate()
+
+	return &budgetImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*budgetImpl) GetDisplayName() string {
+	return BudgetPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *budgetImpl) GetName() string {
+	return BudgetPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *budgetImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *budgetImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{}
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *budgetImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+
+	errorz.Assertf(p.cfg.Cloud == nil || len(p.cfg.Cloud.EmailAddresses) > 0 || p.cfg.Cloud.SNSTopicARN != "",
+		"at least one of BudgetConfigCloud.EmailAddresses, SNSTopicARN must be set", errorz.Prefix(BudgetPluginName))
+}
+
+// GetStage implements the Plugin interface.
+func (p *budgetImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(BudgetPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the Budget interface.
+func (p *budgetImpl) GetConfig() *BudgetConfig {
+	return p.cfg
+}
+
+// GetCloudMetadata implements the Budget interface.
+func (p *budgetImpl) GetCloudMetadata(require bool) *BudgetCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(BudgetPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *budgetImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (*budgetImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
+	// intentionally empty
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *budgetImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	budgetData := &gobudgets.Budget_BudgetData{
+		BudgetLimit: &gobudgets.Budget_Spend{
+			Amount: p.cfg.Cloud.MonthlyLimitUSD,
+			Unit:   "USD",
+		},
+		BudgetName: stringz.Ptr(BudgetRefBudget.Name(p)),
+		BudgetType: "COST",
+		TimeUnit:   "MONTHLY",
+	}
+
+	if !p.cfg.Cloud.AccountWide {
+		var costFilters interface{} = map[string]interface{}{
+			"TagKeyValue": []string{
+				fmt.Sprintf("user:%v$%v", CloudStackTagStage, p.cfg.Stage.GetName()),
+			},
+		}
+		budgetData.CostFilters = &costFilters
+	}
+
+	tpl.Resources[BudgetRefBudget.Ref()] = &gobudgets.Budget{
+		Budget:                       budgetData,
+		NotificationsWithSubscribers: p.getNotifications(),
+	}
+	CloudAddExpRef(tpl, p, BudgetRefBudget)
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *budgetImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &BudgetCloudMetadata{
+		Exports: NewCloudExports(stack),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *budgetImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *budgetImpl) getNotifications() *[]gobudgets.Budget_NotificationWithSubscribers {
+	thresholds := p.cfg.Cloud.Thresholds
+	if len(thresholds) == 0 {
+		thresholds = []*BudgetConfigCloudThreshold{
+			{Percentage: 80},
+			{Percentage: 100},
+			{Percentage: 100, Forecasted: true},
+		}
+	}
+
+	subscribers := make([]gobudgets.Budget_Subscriber, 0, len(p.cfg.Cloud.EmailAddresses)+1)
+
+	for _, emailAddress := range p.cfg.Cloud.EmailAddresses {
+		subscribers = append(subscribers, gobudgets.Budget_Subscriber{
+			Address:          emailAddress,
+			SubscriptionType: "EMAIL",
+		})
+	}
+
+	if p.cfg.Cloud.SNSTopicARN != "" {
+		subscribers = append(subscribers, gobudgets.Budget_Subscriber{
+			Address:          p.cfg.Cloud.SNSTopicARN,
+			SubscriptionType: "SNS",
+		})
+	}
+
+	notifications := make([]gobudgets.Budget_NotificationWithSubscribers, 0, len(thresholds))
+
+	for _, threshold := range thresholds {
+		notificationType := "ACTUAL"
+		if threshold.Forecasted {
+			notificationType = "FORECASTED"
+		}
+
+		notifications = append(notifications, gobudgets.Budget_NotificationWithSubscribers{
+			Notification: &gobudgets.Budget_Notification{
+				ComparisonOperator: "GREATER_THAN",
+				NotificationType:   notificationType,
+				Threshold:          threshold.Percentage,
+				ThresholdType:      stringz.Ptr("PERCENTAGE"),
+			},
+			Subscribers: subscribers,
+		})
+	}
+
+	return &notifications
+}
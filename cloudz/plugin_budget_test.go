@@ -0,0 +1,76 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudget_GetNotifications(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		cfg                   *BudgetConfigCloud
+		expectedThresholds    []float64
+		expectedTypes         []string
+		expectedSubscriptions []string
+	}{
+		{
+			name: "defaults",
+			cfg: &BudgetConfigCloud{
+				MonthlyLimitUSD: 100,
+				EmailAddresses:  []string{"ops@example.com"},
+			},
+			expectedThresholds:    []float64{80, 100, 100},
+			expectedTypes:         []string{"ACTUAL", "ACTUAL", "FORECASTED"},
+			expectedSubscriptions: []string{"EMAIL:ops@example.com"},
+		},
+		{
+			name: "custom",
+			cfg: &BudgetConfigCloud{
+				MonthlyLimitUSD: 100,
+				Thresholds: []*BudgetConfigCloudThreshold{
+					{Percentage: 50, Forecasted: true},
+				},
+				EmailAddresses: []string{"a@example.com", "b@example.com"},
+				SNSTopicARN:    "arn:aws:sns:us-east-1:123456789012:alerts",
+			},
+			expectedThresholds: []float64{50},
+			expectedTypes:      []string{"FORECASTED"},
+			expectedSubscriptions: []string{
+				"EMAIL:a@example.com",
+				"EMAIL:b@example.com",
+				"SNS:arn:aws:sns:us-east-1:123456789012:alerts",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			p := &budgetImpl{
+				cfg: &BudgetConfig{
+					Name:  "test",
+					Cloud: testCase.cfg,
+				},
+			}
+
+			thresholds := make([]float64, 0)
+			types := make([]string, 0)
+
+			for _, notification := range *p.getNotifications() {
+				thresholds = append(thresholds, notification.Notification.Threshold)
+				types = append(types, notification.Notification.NotificationType)
+
+				subscriptions := make([]string, 0)
+				for _, subscriber := range notification.Subscribers {
+					subscriptions = append(subscriptions, subscriber.SubscriptionType+":"+subscriber.Address)
+				}
+				require.Equal(t, testCase.expectedSubscriptions, subscriptions)
+			}
+
+			require.Equal(t, testCase.expectedThresholds, thresholds)
+			require.Equal(t, testCase.expectedTypes, types)
+		})
+	}
+}
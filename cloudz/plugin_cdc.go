@@ -0,0 +1,373 @@
+package cloudz
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+)
+
+// CDC constants.
+const (
+	CDCPluginDisplayName = "CDC"
+	CDCPluginName        = "cdc"
+
+	cdcDatabasePasswordEnvVar = "CDC_DATABASE_PASSWORD"
+	cdcDefaultSnapshotMode    = "initial"
+	cdcOutboxTopicPrefix      = "outbox.event." // default of the Debezium outbox event router
+)
+
+var (
+	_ CDC    = &cdcImpl{}
+	_ Plugin = &cdcImpl{}
+)
+
+// CDCConfigFunc returns the CDC config for a given Stage.
+type CDCConfigFunc func(Stage, *CDCDependencies) *CDCConfig
+
+// CDCEventHookFunc describes a CDC event hook.
+type CDCEventHookFunc func(CDC, Event, string)
+
+// CDCConfig describes the CDC config.
+//
+// The CDC runs Debezium Server, which streams the changes to the tables of a Postgres publication to Kafka topics named
+// "<Name>.<schema>.<table>" (see CDC.GetTopicName), in JSON format. The publication must be declared in the
+// PostgresConfig.LogicalReplication of the Postgres, with a SlotName. The connector only captures the tables declared in
+// the publication (or all tables, if none are declared). In cloud stages it runs as a single Fargate task in the private
+// subnets of the Network, and the topics must be created in advance.
+//
+// If Outbox is set, the CDC implements the outbox pattern using the Debezium outbox event router: the publication must
+// contain a single outbox table (see opz.Operations.GeneratePostgresOutboxMigration), and each inserted row is published
+// to the topic for its "aggregatetype" (see CDC.GetOutboxTopicName), keyed by its "aggregateid".
+//
+// Note that connector offsets are not persisted across container restarts: the connector resumes from the position of
+// the replication slot, after performing a new snapshot unless SnapshotMode is "never", so consumers must be idempotent.
+type CDCConfig struct {
+	Stage           Stage  `validate:"required"`
+	Name            string `validate:"required,resource-name"`
+	PublicationName string `validate:"required"`
+	SnapshotMode    string `validate:"omitempty,oneof=initial never initial_only always"` // defaults to "initial"
+	Outbox          bool
+	Cloud           *CDCConfigCloud
+	EventHook       CDCEventHookFunc
+}
+
+// MustValidate validates the CDC config.
+func (c *CDCConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing CDCConfig.Cloud")
+	errorz.Assertf(stageTarget == Local || !c.Cloud.ServiceConnect.IsServer(), "CDCConfigCloud.ServiceConnect cannot set DiscoveryName")
+}
+
+// CDCConfigCloud describes part of the CDC config.
+// The connector has no ingress, so if ServiceConnect is set it can only be a client.
+type CDCConfigCloud struct {
+	CPU            int `validate:"required"`
+	Memory         int `validate:"required"`
+	ServiceConnect *ECSServiceTemplateConfigServiceConnect
+}
+
+// CDCDependencies describes the CDC dependencies.
+// RuntimeSecrets is required in cloud stages, and must depend on Postgres: the database password is injected from it.
+type CDCDependencies struct {
+	ImageRepository   ImageRepository `validate:"required"`
+	Kafka             Kafka           `validate:"required"`
+	Network           Network         `validate:"required"`
+	Postgres          Postgres        `validate:"required"`
+	RuntimeSecrets    RuntimeSecrets
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the CDC dependencies.
+func (d *CDCDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// CDCLocalMetadata describes the CDC local metadata.
+type CDCLocalMetadata struct {
+	ContainerName string
+	TopicPrefix   string
+}
+
+// CDCCloudMetadata describes the CDC cloud metadata.
+type CDCCloudMetadata struct {
+	Exports     CloudExports
+	TopicPrefix string
+}
+
+// CDC describes a CDC.
+type CDC interface {
+	Plugin
+	GetConfig() *CDCConfig
+	GetDependencies() *CDCDependencies
+	GetLocalMetadata() *CDCLocalMetadata
+	GetCloudMetadata(require bool) *CDCCloudMetadata
+	GetTopicName(table string) string
+	GetOutboxTopicName(aggregateType string) string
+}
+
+type cdcImpl struct {
+	cfgFunc       CDCConfigFunc
+	deps          *CDCDependencies
+	cfg           *CDCConfig
+	localMetadata *CDCLocalMetadata
+	cloudMetadata *CDCCloudMetadata
+}
+
+// NewCDC initializes a new CDC.
+func NewCDC(cfgFunc CDCConfigFunc, deps *CDCDependencies) CDC {
+	deps.MustValidate()
+
+	return &cdcImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*cdcImpl) GetDisplayName() string {
+	return CDCPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *cdcImpl) GetName() string {
+	return CDCPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *cdcImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *cdcImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.ImageRepository: {},
+		p.deps.Kafka:           {},
+		p.deps.Network:         {},
+		p.deps.Postgres:        {},
+	}
+
+	if p.deps.RuntimeSecrets != nil {
+		dependenciesMap[p.deps.RuntimeSecrets] = struct{}{}
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *cdcImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+	errorz.Assertf(stage.GetTarget() == Local || p.deps.RuntimeSecrets != nil, "missing CDCDependencies.RuntimeSecrets", errorz.Prefix(CDCPluginName))
+	p.getPublication()
+}
+
+// GetStage implements the Plugin interface.
+func (p *cdcImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(CDCPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the CDC interface.
+func (p *cdcImpl) GetConfig() *CDCConfig {
+	return p.cfg
+}
+
+// GetDependencies implements the CDC interface.
+func (p *cdcImpl) GetDependencies() *CDCDependencies {
+	return p.deps
+}
+
+// GetLocalMetadata implements the CDC interface.
+func (p *cdcImpl) GetLocalMetadata() *CDCLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(CDCPluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the CDC interface.
+func (p *cdcImpl) GetCloudMetadata(require bool) *CDCCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(CDCPluginName))
+	return p.cloudMetadata
+}
+
+// GetTopicName implements the CDC interface.
+// The table must be schema-qualified, e.g. "public.orders".
+func (p *cdcImpl) GetTopicName(table string) string {
+	return p.cfg.Name + "." + table
+}
+
+// GetOutboxTopicName implements the CDC interface.
+func (p *cdcImpl) GetOutboxTopicName(aggregateType string) string {
+	errorz.Assertf(p.cfg.Outbox, "outbox not enabled", errorz.Prefix(CDCPluginName))
+	return cdcOutboxTopicPrefix + aggregateType
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *cdcImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *cdcImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath string) {
+	containerName := LocalGetContainerName(p)
+
+	p.localMetadata = &CDCLocalMetadata{
+		ContainerName: containerName,
+		TopicPrefix:   p.cfg.Name,
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name: containerName,
+		Build: dctypes.BuildConfig{
+			Context: buildDirPath,
+		},
+		ContainerName: containerName,
+		DependsOn: []string{
+			p.deps.Kafka.GetLocalMetadata().ContainerName,
+			p.deps.Postgres.GetLocalMetadata().ContainerName,
+		},
+		Environment: map[string]*string{
+			cdcDatabasePasswordEnvVar: stringz.Ptr(LocalPassword),
+		},
+		Image:    containerName,
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Restart:  "unless-stopped",
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *cdcImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	CloudAddECSServiceResources(tpl, p, &ECSServiceTemplateConfig{
+		Image: p.getImageWithTag(),
+		Secrets: []*ECSServiceTemplateConfigSecret{
+			p.deps.RuntimeSecrets.GetECSSecret(cdcDatabasePasswordEnvVar, RuntimeSecretsKeyPostgresPassword),
+		},
+		Replicas: 1, // the connector cannot be scaled out
+		CPU:      p.cfg.Cloud.CPU,
+		Memory:   p.cfg.Cloud.Memory,
+		TaskRolePolicies: []goiam.Role_Policy{
+			p.deps.Kafka.GetClientRolePolicy(),
+		},
+		ServiceConnect: p.cfg.Cloud.ServiceConnect,
+		Network:        p.deps.Network,
+	})
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *cdcImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &CDCCloudMetadata{
+		Exports:     NewCloudExports(stack),
+		TopicPrefix: p.cfg.Name,
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *cdcImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case LocalBeforeCreateEvent:
+		p.localBeforeCreateEventHook(buildDirPath)
+	case CloudBeforeDeployEvent:
+		p.cloudBeforeDeployEventHook(buildDirPath)
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *cdcImpl) localBeforeCreateEventHook(buildDirPath string) {
+	p.writeBuildDir(buildDirPath, p.deps.Kafka.GetLocalMetadata().InternalBootstrapBrokers, p.deps.Postgres.GetLocalMetadata().InternalURL, false)
+}
+
+func (p *cdcImpl) cloudBeforeDeployEventHook(buildDirPath string) {
+	p.writeBuildDir(buildDirPath, p.deps.Kafka.GetCloudMetadata(true).BootstrapBrokers, p.deps.Postgres.GetCloudMetadata(true).URL, true)
+
+	imageWithTag := p.getImageWithTag()
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker", "build", "-t", imageWithTag, ".").SetDir(buildDirPath).MustRun()
+	p.cfg.Stage.GetConfig().App.GetOperations().DockerLoginToECR()
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker", "push", imageWithTag).MustRun()
+}
+
+func (p *cdcImpl) writeBuildDir(buildDirPath, bootstrapBrokers string, pgURL *url.URL, isCloud bool) {
+	filez.MustPrepareDir(buildDirPath, 0777)
+	versions := p.cfg.Stage.GetConfig().App.GetConfig().GetVersions()
+	publication := p.getPublication()
+
+	dockerfileTemplateData := assets.CDCDockerfileTemplateData{
+		BaseImage: LocalGetImage(p, "debezium/server:"+versions.Debezium),
+	}
+
+	propertiesTemplateData := assets.CDCApplicationPropertiesTemplateData{
+		BootstrapBrokers: bootstrapBrokers,
+		DatabaseHost:     pgURL.Hostname(),
+		DatabasePort:     pgURL.Port(),
+		DatabaseUser:     pgURL.User.Username(),
+		DatabaseName:     strings.TrimPrefix(pgURL.Path, "/"),
+		DatabaseSSLMode:  "disable",
+		TopicPrefix:      p.cfg.Name,
+		PublicationName:  publication.Name,
+		SlotName:         publication.SlotName,
+		SnapshotMode:     p.cfg.SnapshotMode,
+		TableIncludeList: strings.Join(publication.Tables, ","),
+		Outbox:           p.cfg.Outbox,
+	}
+
+	if propertiesTemplateData.SnapshotMode == "" {
+		propertiesTemplateData.SnapshotMode = cdcDefaultSnapshotMode
+	}
+
+	if isCloud {
+		dockerfileTemplateData.DownloaderImage = LocalGetImage(p, "alpine:"+versions.Alpine)
+		dockerfileTemplateData.MSKIAMAuthVersion = versions.MSKIAMAuth
+		propertiesTemplateData.IAMAuth = true
+		propertiesTemplateData.DatabaseSSLMode = "require"
+	}
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "Dockerfile"), 0777, 0666,
+		templatez.MustParseAndExecuteText(assets.CDCDockerfileTemplateAsset, dockerfileTemplateData))
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "application.properties"), 0777, 0666,
+		templatez.MustParseAndExecuteText(assets.CDCApplicationPropertiesTemplateAsset, propertiesTemplateData))
+}
+
+func (p *cdcImpl) getPublication() *PostgresConfigPublication {
+	if logicalReplication := p.deps.Postgres.GetConfig().LogicalReplication; logicalReplication != nil {
+		for _, publication := range logicalReplication.Publications {
+			if publication.Name == p.cfg.PublicationName {
+				errorz.Assertf(publication.SlotName != "", "publication has no slot: %v",
+					errorz.A(p.cfg.PublicationName), errorz.Prefix(CDCPluginName))
+				errorz.Assertf(!p.cfg.Outbox || len(publication.Tables) == 1, "outbox publication must have a single table: %v",
+					errorz.A(p.cfg.PublicationName), errorz.Prefix(CDCPluginName))
+				return publication
+			}
+		}
+	}
+
+	panic(errorz.Errorf("unknown publication: %v", errorz.A(p.cfg.PublicationName), errorz.Prefix(CDCPluginName)))
+}
+
+func (p *cdcImpl) getImageWithTag() string {
+	return p.deps.ImageRepository.GetCloudMetadata(true).ImageName + ":" + p.cfg.Stage.AsCloudStage().GetCloudConfig().Version
+}
@@ -0,0 +1,544 @@
+package cloudz
+
+import (
+	"fmt"
+	"net/url"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	gocloudfront "github.com/awslabs/goformation/v6/cloudformation/cloudfront"
+	goroute53 "github.com/awslabs/goformation/v6/cloudformation/route53"
+	gos3 "github.com/awslabs/goformation/v6/cloudformation/s3"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// CDN constants.
+const (
+	CDNPluginDisplayName         = "CDN"
+	CDNPluginName                = "cdn"
+	CDNRefDistribution           = CloudRef("d")
+	CDNRefOriginAccessIdentity   = CloudRef("oai")
+	CDNRefBucketPolicy           = CloudRef("bp")
+	CDNRefRecordSet              = CloudRef("rs")
+	CDNRefHealthCheck            = CloudRef("hc")
+	CDNAttDomainName             = CloudAtt("DomainName")
+	CDNAttID                     = CloudAtt("Id")
+	CDNAttS3CanonicalUserID      = CloudAtt("S3CanonicalUserId")
+	cdnCloudFrontHostedZoneID    = "Z2FDTNDATAQYW2" // fixed for all CloudFront distributions
+	cdnCertificateRequiredRegion = "us-east-1"
+)
+
+// Known CDN origins.
+const (
+	CDNOriginBucket       CDNOrigin = "bucket"
+	CDNOriginLoadBalancer CDNOrigin = "load-balancer"
+	CDNOriginAPI          CDNOrigin = "api"
+)
+
+// Known CDN cache policies.
+const (
+	CDNCachePolicyOptimized CDNCachePolicy = "optimized"
+	CDNCachePolicyDisabled  CDNCachePolicy = "disabled"
+)
+
+// Known CDN edge event types.
+const (
+	CDNEdgeEventTypeViewerRequest  CDNEdgeEventType = "viewer-request"
+	CDNEdgeEventTypeViewerResponse CDNEdgeEventType = "viewer-response"
+)
+
+var (
+	_ CDN    = &cdnImpl{}
+	_ Plugin = &cdnImpl{}
+
+	// AWS managed cache and origin request policies.
+	cdnCachePolicyIDs = map[CDNCachePolicy]string{
+		CDNCachePolicyOptimized: "658327ea-f89d-4fab-a63d-7e88639e58f6", // Managed-CachingOptimized
+		CDNCachePolicyDisabled:  "4135ea2d-6df8-44a3-9df3-4b5a84be39ad", // Managed-CachingDisabled
+	}
+	cdnOriginRequestPolicyIDs = map[CDNOrigin]string{
+		CDNOriginLoadBalancer: "216adef6-5c7f-47e4-b989-5492eafa07d3", // Managed-AllViewer
+		CDNOriginAPI:          "b689b0a8-53d0-40ab-baf2-68738e2966ac", // Managed-AllViewerExceptHostHeader
+	}
+)
+
+// CDNOrigin describes a CDN origin.
+type CDNOrigin string
+
+// CDNEdgeEventType describes a CloudFront event an edge function can be associated with.
+type CDNEdgeEventType string
+
+// CDNCachePolicy describes a CDN cache policy.
+type CDNCachePolicy string
+
+// CDNConfigFunc returns the CDN config for a given Stage.
+type CDNConfigFunc func(Stage, *CDNDependencies) *CDNConfig
+
+// CDNEventHookFunc describes a CDN event hook.
+type CDNEventHookFunc func(CDN, Event, string)
+
+// CDNConfig describes the CDN config.
+type CDNConfig struct {
+	Stage     Stage  `validate:"required"`
+	Name      string `validate:"required,resource-name"`
+	Cloud     *CDNConfigCloud
+	EventHook CDNEventHookFunc
+}
+
+// MustValidate validates the CDN config.
+func (c *CDNConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing CDNConfig.Cloud")
+
+	if stageTarget == Cloud {
+		c.Cloud.DefaultBehavior.MustValidate()
+		errorz.Assertf(c.Cloud.DefaultBehavior.PathPattern == "", "CDNConfigCloud.DefaultBehavior must not have a PathPattern")
+
+		for _, behavior := range c.Cloud.Behaviors {
+			behavior.MustValidate()
+			errorz.Assertf(behavior.PathPattern != "", "missing CDNConfigBehavior.PathPattern")
+		}
+
+		if c.Cloud.Routing != nil {
+			c.Cloud.Routing.MustValidate()
+		}
+	}
+}
+
+// CDNConfigCloud describes part of the CDN config.
+// The LoadBalancerDomainName is required if any behavior uses the load balancer origin: it must resolve to the load
+// balancer, and be covered by its certificate (e.g. the Hasura domain name).
+type CDNConfigCloud struct {
+	DomainName             string             `validate:"required,fqdn"`
+	DefaultBehavior        *CDNConfigBehavior `validate:"required"`
+	Behaviors              []*CDNConfigBehavior
+	LoadBalancerDomainName string `validate:"omitempty,fqdn"`
+	DefaultRootObject      string
+	IsSinglePageApp        bool
+	PriceClass             string `validate:"omitempty,oneof=PriceClass_100 PriceClass_200 PriceClass_All"`
+	Routing                *RecordSetRoutingConfig
+}
+
+// CDNConfigBehavior describes part of the CDN config.
+// The EdgeFunctions must be among CDNDependencies.EdgeFunctions.
+type CDNConfigBehavior struct {
+	PathPattern   string
+	Origin        CDNOrigin                         `validate:"required,oneof=bucket load-balancer api"`
+	CachePolicy   CDNCachePolicy                    `validate:"required,oneof=optimized disabled"`
+	EdgeFunctions map[CDNEdgeEventType]EdgeFunction `validate:"dive,keys,oneof=viewer-request viewer-response,endkeys,required"`
+}
+
+// MustValidate validates the CDN config behavior.
+func (c *CDNConfigBehavior) MustValidate() {
+	vz.MustValidateStruct(c)
+}
+
+// CDNDependencies describes the CDN dependencies.
+// The Certificate must be in the us-east-1 region, as required by CloudFront. Bucket, LoadBalancer, and API are
+// optional, but must be set if used as origins. EdgeFunctions must include all the edge functions used by behaviors.
+type CDNDependencies struct {
+	Certificate       Certificate `validate:"required"`
+	Bucket            Bucket
+	LoadBalancer      LoadBalancer
+	API               API
+	EdgeFunctions     []EdgeFunction `validate:"dive,required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the CDN dependencies.
+func (d *CDNDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// CDNCloudMetadata describes the CDN cloud metadata.
+type CDNCloudMetadata struct {
+	Exports CloudExports
+	URL     *url.URL
+}
+
+// GetDistributionID returns the CloudFront distribution ID.
+func (m *CDNCloudMetadata) GetDistributionID() string {
+	return m.Exports.GetRef(CDNRefDistribution)
+}
+
+// GetDistributionDomainName returns the CloudFront distribution domain name.
+func (m *CDNCloudMetadata) GetDistributionDomainName() string {
+	return m.Exports.GetAtt(CDNRefDistribution, CDNAttDomainName)
+}
+
+// CDN describes a CDN.
+type CDN interface {
+	Plugin
+	GetConfig() *CDNConfig
+	GetDependencies() *CDNDependencies
+	GetCloudMetadata(require bool) *CDNCloudMetadata
+}
+
+type cdnImpl struct {
+	cfgFunc       CDNConfigFunc
+	deps          *CDNDependencies
+	cfg           *CDNConfig
+	cloudMetadata *CDNCloudMetadata
+}
+
+// NewCDN initializes a new CDN.
+func NewCDN(cfgFunc CDNConfigFunc, deps *CDNDependencies) CDN {
+	deps.MustValidate()
+
+	return &cdnImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*cdnImpl) GetDisplayName() string {
+	return CDNPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *cdnImpl) GetName() string {
+	return CDNPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *cdnImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *cdnImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Certificate: {},
+	}
+
+	if p.deps.Bucket != nil {
+		dependenciesMap[p.deps.Bucket] = struct{}{}
+	}
+
+	if p.deps.LoadBalancer != nil {
+		dependenciesMap[p.deps.LoadBalancer] = struct{}{}
+	}
+
+	if p.deps.API != nil {
+		dependenciesMap[p.deps.API] = struct{}{}
+	}
+
+	for _, edgeFunction := range p.deps.EdgeFunctions {
+		dependenciesMap[edgeFunction] = struct{}{}
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *cdnImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *cdnImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(CDNPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the CDN interface.
+func (p *cdnImpl) GetConfig() *CDNConfig {
+	return p.cfg
+}
+
+// GetDependencies implements the CDN interface.
+func (p *cdnImpl) GetDependencies() *CDNDependencies {
+	return p.deps
+}
+
+// GetCloudMetadata implements the CDN interface.
+func (p *cdnImpl) GetCloudMetadata(require bool) *CDNCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(CDNPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *cdnImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *cdnImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
+	// nothing to do here
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *cdnImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	if p.usesOrigin(CDNOriginBucket) && !p.deps.Bucket.GetConfig().IsPublicAccessEnabled {
+		tpl.Resources[CDNRefOriginAccessIdentity.Ref()] = &gocloudfront.CloudFrontOriginAccessIdentity{
+			CloudFrontOriginAccessIdentityConfig: &gocloudfront.CloudFrontOriginAccessIdentity_CloudFrontOriginAccessIdentityConfig{
+				Comment: CDNRefOriginAccessIdentity.Name(p),
+			},
+		}
+		CloudAddExpRef(tpl, p, CDNRefOriginAccessIdentity)
+		CloudAddExpGetAtt(tpl, p, CDNRefOriginAccessIdentity, CDNAttID)
+		CloudAddExpGetAtt(tpl, p, CDNRefOriginAccessIdentity, CDNAttS3CanonicalUserID)
+
+		tpl.Resources[CDNRefBucketPolicy.Ref()] = &gos3.BucketPolicy{
+			Bucket: p.deps.Bucket.GetCloudMetadata(true).GetName(),
+			PolicyDocument: NewPolicyDocument(
+				NewPolicyStatement().
+					SetCanonicalUserPrincipal(gocf.GetAtt(CDNRefOriginAccessIdentity.Ref(), CDNAttS3CanonicalUserID.Ref())).
+					AddActions("s3:GetObject").
+					AddResources(p.deps.Bucket.GetCloudMetadata(true).Exports.GetAtt(BucketRefBucket, BucketAttARN) + "/*")),
+		}
+	}
+
+	tpl.Resources[CDNRefDistribution.Ref()] = &gocloudfront.Distribution{
+		DistributionConfig: &gocloudfront.Distribution_DistributionConfig{
+			Aliases: &[]string{
+				p.cfg.Cloud.DomainName,
+			},
+			CacheBehaviors: func() *[]gocloudfront.Distribution_CacheBehavior {
+				behaviors := make([]gocloudfront.Distribution_CacheBehavior, 0, len(p.cfg.Cloud.Behaviors))
+				for _, behavior := range p.cfg.Cloud.Behaviors {
+					behaviors = append(behaviors, gocloudfront.Distribution_CacheBehavior{
+						AllowedMethods:        p.getAllowedMethods(behavior),
+						CachePolicyId:         stringz.Ptr(cdnCachePolicyIDs[behavior.CachePolicy]),
+						Compress:              boolz.Ptr(true),
+						FunctionAssociations:  getCDNFunctionAssociations(behavior),
+						OriginRequestPolicyId: p.getOriginRequestPolicyID(behavior),
+						PathPattern:           behavior.PathPattern,
+						TargetOriginId:        string(behavior.Origin),
+						ViewerProtocolPolicy:  "redirect-to-https",
+					})
+				}
+				return &behaviors
+			}(),
+			Comment: stringz.Ptr(CDNRefDistribution.Name(p)),
+			CustomErrorResponses: func() *[]gocloudfront.Distribution_CustomErrorResponse {
+				if !p.cfg.Cloud.IsSinglePageApp {
+					return nil
+				}
+
+				return &[]gocloudfront.Distribution_CustomErrorResponse{
+					{
+						ErrorCode:        403,
+						ResponseCode:     intz.Ptr(200),
+						ResponsePagePath: stringz.Ptr("/index.html"),
+					},
+					{
+						ErrorCode:        404,
+						ResponseCode:     intz.Ptr(200),
+						ResponsePagePath: stringz.Ptr("/index.html"),
+					},
+				}
+			}(),
+			DefaultCacheBehavior: &gocloudfront.Distribution_DefaultCacheBehavior{
+				AllowedMethods:        p.getAllowedMethods(p.cfg.Cloud.DefaultBehavior),
+				CachePolicyId:         stringz.Ptr(cdnCachePolicyIDs[p.cfg.Cloud.DefaultBehavior.CachePolicy]),
+				Compress:              boolz.Ptr(true),
+				FunctionAssociations:  getCDNFunctionAssociations(p.cfg.Cloud.DefaultBehavior),
+				OriginRequestPolicyId: p.getOriginRequestPolicyID(p.cfg.Cloud.DefaultBehavior),
+				TargetOriginId:        string(p.cfg.Cloud.DefaultBehavior.Origin),
+				ViewerProtocolPolicy:  "redirect-to-https",
+			},
+			DefaultRootObject: stringz.PtrZeroToNil(p.cfg.Cloud.DefaultRootObject),
+			Enabled:           true,
+			HttpVersion:       stringz.Ptr("http2"),
+			IPV6Enabled:       boolz.Ptr(true),
+			Origins:           p.getOrigins(),
+			PriceClass:        stringz.PtrZeroToNil(p.cfg.Cloud.PriceClass),
+			ViewerCertificate: &gocloudfront.Distribution_ViewerCertificate{
+				AcmCertificateArn:      stringz.Ptr(p.deps.Certificate.GetCloudMetadata(true).ARN),
+				MinimumProtocolVersion: stringz.Ptr("TLSv1.2_2021"),
+				SslSupportMethod:       stringz.Ptr("sni-only"),
+			},
+		},
+		Tags: CloudGetDefaultTags(CDNRefDistribution.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, CDNRefDistribution)
+	CloudAddExpGetAtt(tpl, p, CDNRefDistribution, CDNAttDomainName)
+
+	if hostedZoneID := p.deps.Certificate.GetConfig().Cloud.GetDNSProvider().GetHostedZoneID(); hostedZoneID != nil {
+		recordSet := &goroute53.RecordSet{
+			AliasTarget: &goroute53.RecordSet_AliasTarget{
+				DNSName:      gocf.GetAtt(CDNRefDistribution.Ref(), CDNAttDomainName.Ref()),
+				HostedZoneId: cdnCloudFrontHostedZoneID,
+			},
+			HostedZoneId: hostedZoneID,
+			Name:         p.cfg.Cloud.DomainName,
+			Type:         "A",
+		}
+		CloudApplyRecordSetRouting(tpl, p, CDNRefHealthCheck, recordSet, p.cfg.Cloud.Routing)
+		tpl.Resources[CDNRefRecordSet.Ref()] = recordSet
+		CloudAddExpRef(tpl, p, CDNRefRecordSet)
+	}
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *cdnImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &CDNCloudMetadata{
+		Exports: NewCloudExports(stack),
+		URL:     urlz.MustParse(fmt.Sprintf("https://%v", p.cfg.Cloud.DomainName)),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *cdnImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case CloudPreflightEvent:
+		p.cloudPreflightEventHook()
+	case CloudAfterDeployEvent:
+		p.cloudAfterDeployEventHook()
+	case CloudBeforeDestroyEvent:
+		p.cloudBeforeDestroyEventHook()
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *cdnImpl) cloudPreflightEventHook() {
+	region := p.deps.Certificate.GetStage().GetConfig().App.GetConfig().AWSConfig.Region
+	errorz.Assertf(region == cdnCertificateRequiredRegion, "CloudFront requires a certificate in %v, got %v",
+		errorz.A(cdnCertificateRequiredRegion, region), errorz.Prefix(CDNPluginName))
+
+	errorz.Assertf(!p.usesOrigin(CDNOriginBucket) || p.deps.Bucket != nil,
+		"bucket origin requires CDNDependencies.Bucket", errorz.Prefix(CDNPluginName))
+	errorz.Assertf(!p.usesOrigin(CDNOriginLoadBalancer) || p.deps.LoadBalancer != nil,
+		"load balancer origin requires CDNDependencies.LoadBalancer", errorz.Prefix(CDNPluginName))
+	errorz.Assertf(!p.usesOrigin(CDNOriginLoadBalancer) || p.cfg.Cloud.LoadBalancerDomainName != "",
+		"load balancer origin requires CDNConfigCloud.LoadBalancerDomainName", errorz.Prefix(CDNPluginName))
+	errorz.Assertf(!p.usesOrigin(CDNOriginAPI) || p.deps.API != nil,
+		"api origin requires CDNDependencies.API", errorz.Prefix(CDNPluginName))
+
+	edgeFunctions := map[Plugin]struct{}{}
+	for _, edgeFunction := range p.deps.EdgeFunctions {
+		edgeFunctions[edgeFunction] = struct{}{}
+	}
+
+	for _, behavior := range append([]*CDNConfigBehavior{p.cfg.Cloud.DefaultBehavior}, p.cfg.Cloud.Behaviors...) {
+		for _, edgeFunction := range behavior.EdgeFunctions {
+			_, ok := edgeFunctions[edgeFunction]
+			errorz.Assertf(ok, "edge function %v requires CDNDependencies.EdgeFunctions",
+				errorz.A(edgeFunction.GetConfig().Name), errorz.Prefix(CDNPluginName))
+		}
+	}
+
+	CloudMustCheckDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName, p.cfg.Cloud.Routing)
+}
+
+func (p *cdnImpl) cloudAfterDeployEventHook() {
+	CloudMaybeUpsertDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName,
+		p.cloudMetadata.GetDistributionDomainName())
+}
+
+func (p *cdnImpl) cloudBeforeDestroyEventHook() {
+	CloudMaybeDeleteDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName,
+		p.cloudMetadata.GetDistributionDomainName())
+}
+
+func (p *cdnImpl) usesOrigin(origin CDNOrigin) bool {
+	if p.cfg.Cloud.DefaultBehavior.Origin == origin {
+		return true
+	}
+
+	for _, behavior := range p.cfg.Cloud.Behaviors {
+		if behavior.Origin == origin {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *cdnImpl) getOrigins() *[]gocloudfront.Distribution_Origin {
+	origins := make([]gocloudfront.Distribution_Origin, 0)
+
+	if p.usesOrigin(CDNOriginBucket) {
+		origins = append(origins, gocloudfront.Distribution_Origin{
+			DomainName: p.deps.Bucket.GetCloudMetadata(true).Exports.GetAtt(BucketRefBucket, BucketAttRegionalDomainName),
+			Id:         string(CDNOriginBucket),
+			S3OriginConfig: &gocloudfront.Distribution_S3OriginConfig{
+				OriginAccessIdentity: func() *string {
+					if p.deps.Bucket.GetConfig().IsPublicAccessEnabled {
+						return stringz.Ptr("")
+					}
+					return stringz.Ptr(gocf.Join("", []string{
+						"origin-access-identity/cloudfront/",
+						gocf.Ref(CDNRefOriginAccessIdentity.Ref()),
+					}))
+				}(),
+			},
+		})
+	}
+
+	if p.usesOrigin(CDNOriginLoadBalancer) {
+		origins = append(origins, gocloudfront.Distribution_Origin{
+			CustomOriginConfig: &gocloudfront.Distribution_CustomOriginConfig{
+				OriginProtocolPolicy: "https-only",
+				OriginSSLProtocols:   &[]string{"TLSv1.2"},
+			},
+			DomainName: p.cfg.Cloud.LoadBalancerDomainName,
+			Id:         string(CDNOriginLoadBalancer),
+		})
+	}
+
+	if p.usesOrigin(CDNOriginAPI) {
+		origins = append(origins, gocloudfront.Distribution_Origin{
+			CustomOriginConfig: &gocloudfront.Distribution_CustomOriginConfig{
+				OriginProtocolPolicy: "https-only",
+				OriginSSLProtocols:   &[]string{"TLSv1.2"},
+			},
+			DomainName: p.deps.API.GetConfig().Cloud.DomainName,
+			Id:         string(CDNOriginAPI),
+		})
+	}
+
+	return &origins
+}
+
+func getCDNFunctionAssociations(behavior *CDNConfigBehavior) *[]gocloudfront.Distribution_FunctionAssociation {
+	if len(behavior.EdgeFunctions) == 0 {
+		return nil
+	}
+
+	functionAssociations := make([]gocloudfront.Distribution_FunctionAssociation, 0, len(behavior.EdgeFunctions))
+	for _, eventType := range []CDNEdgeEventType{CDNEdgeEventTypeViewerRequest, CDNEdgeEventTypeViewerResponse} {
+		if edgeFunction, ok := behavior.EdgeFunctions[eventType]; ok {
+			functionAssociations = append(functionAssociations, gocloudfront.Distribution_FunctionAssociation{
+				EventType:   stringz.Ptr(string(eventType)),
+				FunctionARN: stringz.Ptr(edgeFunction.GetCloudMetadata(true).ARN),
+			})
+		}
+	}
+
+	return &functionAssociations
+}
+
+func (p *cdnImpl) getAllowedMethods(behavior *CDNConfigBehavior) *[]string {
+	if behavior.Origin == CDNOriginBucket {
+		return &[]string{"GET", "HEAD", "OPTIONS"}
+	}
+	return &[]string{"GET", "HEAD", "OPTIONS", "PUT", "PATCH", "POST", "DELETE"}
+}
+
+func (p *cdnImpl) getOriginRequestPolicyID(behavior *CDNConfigBehavior) *string {
+	if id, ok := cdnOriginRequestPolicyIDs[behavior.Origin]; ok {
+		return stringz.Ptr(id)
+	}
+	return nil
+}
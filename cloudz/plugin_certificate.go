@@ -1,6 +1,12 @@
 package cloudz
 
 import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsacmt "github.com/aws/aws-sdk-go-v2/service/acm/types"
 	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	gocf "github.com/awslabs/goformation/v6/cloudformation"
 	gocm "github.com/awslabs/goformation/v6/cloudformation/certificatemanager"
@@ -15,11 +21,15 @@ const (
 	CertificatePluginDisplayName = "Certificate"
 	CertificatePluginName        = "certificate"
 	CertificateRefCertificate    = CloudRef("c")
+
+	certificateDefaultValidationTimeout = 10 * time.Minute
+	certificateValidationPollInterval   = 15 * time.Second
 )
 
 var (
-	_ Certificate = &certificateImpl{}
-	_ Plugin      = &certificateImpl{}
+	_ Certificate       = &certificateImpl{}
+	_ Plugin            = &certificateImpl{}
+	_ cloudStackWatcher = &certificateImpl{}
 )
 
 // CertificateConfigFunc returns the certificate for a given Stage.
@@ -28,6 +38,18 @@ type CertificateConfigFunc func(Stage, *CertificateDependencies) *CertificateCon
 // CertificateEventHookFunc describes a certificate event hook.
 type CertificateEventHookFunc func(Certificate, Event, string)
 
+// CertificateValidationHookFunc receives the DNS validation status of a certificate while its stack is being deployed.
+// It is called from a background goroutine, each time the status changes.
+type CertificateValidationHookFunc func(Certificate, *CertificateValidationStatus)
+
+// CertificateValidationStatus describes the DNS validation status of a certificate while its stack is being deployed.
+type CertificateValidationStatus struct {
+	Status         string       // ACM certificate status (e.g. "PENDING_VALIDATION"), empty if not created yet
+	PendingRecords []*DNSRecord // validation records for the domains not validated yet
+	IsTimedOut     bool         // true if validation has not completed within CertificateConfigCloud.ValidationTimeout
+	Err            error        // error polling the status or creating the validation records, if any
+}
+
 // CertificateConfig describes the certificate config.
 type CertificateConfig struct {
 	Stage     Stage  `validate:"required"`
@@ -40,16 +62,38 @@ type CertificateConfig struct {
 func (c *CertificateConfig) MustValidate(stageTarget StageTarget) {
 	vz.MustValidateStruct(c)
 	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing CertificateConfig.Cloud")
+	errorz.Assertf(stageTarget == Local || (c.Cloud.HostedZoneID != "") != (c.Cloud.DNSProvider != nil),
+		"exactly one of CertificateConfigCloud.HostedZoneID, DNSProvider must be set")
 }
 
 // CertificateConfigCloud describes part of the certificate config.
+// The DNSProvider is also used by the plugins that depend on the certificate to create their DNS records. Setting the
+// HostedZoneID is a shorthand for a Route53 DNS provider (see NewRoute53DNSProvider). If neither is set, the DNSProvider
+// of the HostedZone dependency is used. While the certificate is being deployed, its DNS validation status is reported
+// to the ValidationHook (if set), e.g. to surface the pending validation records if validation does not complete within
+// the ValidationTimeout. If ImportStackName is set, the exports of the given certificate stack (e.g. a shared certificate
+// deployed by another app) are used instead of creating a new certificate (see ExternalStackExports), the DomainName
+// and DNSProvider must still match it.
 type CertificateConfigCloud struct {
-	DomainName   string `validate:"required"`
-	HostedZoneID string `validate:"required"`
+	DomainName        string `validate:"required"`
+	ImportStackName   string
+	HostedZoneID      string
+	DNSProvider       DNSProvider
+	ValidationTimeout time.Duration `validate:"omitempty,min=0"`
+	ValidationHook    CertificateValidationHookFunc
+}
+
+// GetDNSProvider returns the DNS provider.
+func (c *CertificateConfigCloud) GetDNSProvider() DNSProvider {
+	if c.HostedZoneID != "" {
+		return NewRoute53DNSProvider(c.HostedZoneID)
+	}
+	return c.DNSProvider
 }
 
 // CertificateDependencies describes the certificate dependencies.
 type CertificateDependencies struct {
+	HostedZone        HostedZone
 	OtherDependencies OtherDependencies
 }
 
@@ -106,16 +150,36 @@ func (p *certificateImpl) GetInstanceName() *string {
 // GetDependenciesMap implements the Plugin interface.
 func (p *certificateImpl) GetDependenciesMap() map[Plugin]struct{} {
 	dependenciesMap := map[Plugin]struct{}{}
+
+	if p.deps.HostedZone != nil {
+		dependenciesMap[p.deps.HostedZone] = struct{}{}
+	}
+
 	for _, otherDependency := range p.deps.OtherDependencies {
 		dependenciesMap[otherDependency] = struct{}{}
 	}
+
 	return dependenciesMap
 }
 
 // Configure implements the Plugin interface.
 func (p *certificateImpl) Configure(stage Stage) {
 	p.cfg = p.cfgFunc(stage, p.deps)
+
+	if p.cfg.Cloud != nil && p.cfg.Cloud.HostedZoneID == "" && p.cfg.Cloud.DNSProvider == nil && p.deps.HostedZone != nil {
+		p.cfg.Cloud.DNSProvider = p.deps.HostedZone.GetDNSProvider()
+	}
+
 	p.cfg.MustValidate(stage.GetTarget())
+
+	if stage.GetTarget() == Cloud && p.isImported() && p.cloudMetadata == nil {
+		exports := ExternalStackExports(stage, p.cfg.Cloud.ImportStackName)
+
+		p.cloudMetadata = &CertificateCloudMetadata{
+			Exports: exports,
+			ARN:     exports.GetRef(CertificateRefCertificate),
+		}
+	}
 }
 
 // GetStage implements the Plugin interface.
@@ -147,6 +211,10 @@ func (p *certificateImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
 
 // GetCloudTemplate implements the Plugin interface.
 func (p *certificateImpl) GetCloudTemplate(_ string) *gocf.Template {
+	if p.isImported() {
+		return nil
+	}
+
 	tpl := gocf.NewTemplate()
 
 	tpl.Resources[CertificateRefCertificate.Ref()] = &gocm.Certificate{
@@ -154,7 +222,7 @@ func (p *certificateImpl) GetCloudTemplate(_ string) *gocf.Template {
 		DomainValidationOptions: &[]gocm.Certificate_DomainValidationOption{
 			{
 				DomainName:   p.cfg.Cloud.DomainName,
-				HostedZoneId: stringz.Ptr(p.cfg.Cloud.HostedZoneID),
+				HostedZoneId: p.cfg.Cloud.GetDNSProvider().GetHostedZoneID(),
 			},
 		},
 		ValidationMethod: stringz.Ptr("DNS"),
@@ -177,7 +245,157 @@ func (p *certificateImpl) UpdateCloudMetadata(stack *awscft.Stack) {
 
 // EventHook implements the Plugin interface.
 func (p *certificateImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case CloudPreflightEvent:
+		p.cloudPreflightEventHook()
+	case CloudBeforeDestroyEvent:
+		p.cloudBeforeDestroyEventHook()
+	}
+
 	if p.cfg.EventHook != nil {
 		p.cfg.EventHook(p, event, buildDirPath)
 	}
 }
+
+func (p *certificateImpl) cloudPreflightEventHook() {
+	p.cfg.Cloud.GetDNSProvider().MustCheckDomain(p, p.cfg.Cloud.DomainName)
+}
+
+// cloudBeforeDestroyEventHook deletes the validation records created via the DNS provider (see watchValidation). Note
+// that ACM uses the same validation record for all the certificates of a domain name in an account.
+func (p *certificateImpl) cloudBeforeDestroyEventHook() {
+	if p.isImported() || p.cfg.Cloud.GetDNSProvider().IsRoute53() {
+		return
+	}
+
+	certificate := p.GetStage().GetConfig().App.GetOperations().DescribeCertificate(p.GetCloudMetadata(true).ARN)
+	if certificate == nil {
+		return
+	}
+
+	for _, option := range certificate.DomainValidationOptions {
+		if option.ResourceRecord != nil {
+			p.cfg.Cloud.GetDNSProvider().DeleteRecord(p, newCertificateValidationRecord(option.ResourceRecord))
+		}
+	}
+}
+
+// watchCloudStack implements the cloudStackWatcher interface.
+func (p *certificateImpl) watchCloudStack() func() {
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+
+	go func() {
+		defer close(doneCh)
+		p.watchValidation(stopCh, time.Now())
+	}()
+
+	return func() {
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+// watchValidation polls the certificate while the stack is being deployed, reporting its DNS validation status to the
+// validation hook (and creating the validation records if not managed by Route53).
+func (p *certificateImpl) watchValidation(stopCh <-chan struct{}, startTime time.Time) {
+	ticker := time.NewTicker(certificateValidationPollInterval)
+	defer ticker.Stop()
+
+	timeout := p.cfg.Cloud.ValidationTimeout
+	if timeout == 0 {
+		timeout = certificateDefaultValidationTimeout
+	}
+
+	createdRecords := map[DNSRecord]struct{}{}
+	status := &CertificateValidationStatus{
+		PendingRecords: make([]*DNSRecord, 0),
+	}
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		newStatus := p.pollValidationStatus(startTime, createdRecords)
+		newStatus.IsTimedOut = len(newStatus.PendingRecords) > 0 && time.Since(startTime) > timeout
+
+		if !reflect.DeepEqual(newStatus, status) {
+			status = newStatus
+
+			if p.cfg.Cloud.ValidationHook != nil {
+				p.cfg.Cloud.ValidationHook(p, status)
+			}
+		}
+	}
+}
+
+func (p *certificateImpl) pollValidationStatus(startTime time.Time, createdRecords map[DNSRecord]struct{}) (status *CertificateValidationStatus) {
+	status = &CertificateValidationStatus{
+		PendingRecords: make([]*DNSRecord, 0),
+	}
+
+	defer func() {
+		if err := errorz.MaybeWrapRecover(recover()); err != nil {
+			status.Err = err
+		}
+	}()
+
+	ops := p.GetStage().GetConfig().App.GetOperations()
+
+	arn := getCertificateARN(ops.DescribeStackEvents(CloudGetStackName(p)), startTime)
+	if arn == "" {
+		return status // not created yet
+	}
+
+	certificate := ops.DescribeCertificate(arn)
+	if certificate == nil {
+		return status
+	}
+
+	status.Status = string(certificate.Status)
+
+	for _, option := range certificate.DomainValidationOptions {
+		if option.ResourceRecord == nil || option.ValidationStatus == awsacmt.DomainStatusSuccess {
+			continue
+		}
+
+		record := newCertificateValidationRecord(option.ResourceRecord)
+		status.PendingRecords = append(status.PendingRecords, record)
+
+		if _, ok := createdRecords[*record]; !ok && !p.cfg.Cloud.GetDNSProvider().IsRoute53() {
+			p.cfg.Cloud.GetDNSProvider().UpsertRecord(p, record)
+			createdRecords[*record] = struct{}{}
+		}
+	}
+
+	return status
+}
+
+func newCertificateValidationRecord(resourceRecord *awsacmt.ResourceRecord) *DNSRecord {
+	return &DNSRecord{
+		Name:  aws.ToString(resourceRecord.Name),
+		Type:  string(resourceRecord.Type),
+		Value: aws.ToString(resourceRecord.Value),
+		TTL:   300,
+	}
+}
+
+// getCertificateARN returns the ARN of the certificate created or replaced after the given time, if any.
+func getCertificateARN(events []awscft.StackEvent, startTime time.Time) string {
+	for _, event := range events {
+		if event.Timestamp != nil && !event.Timestamp.Before(startTime) &&
+			aws.ToString(event.LogicalResourceId) == CertificateRefCertificate.Ref() &&
+			strings.HasPrefix(aws.ToString(event.PhysicalResourceId), "arn:") {
+			return *event.PhysicalResourceId
+		}
+	}
+
+	return ""
+}
+
+func (p *certificateImpl) isImported() bool {
+	return p.cfg.Cloud != nil && p.cfg.Cloud.ImportStackName != ""
+}
@@ -0,0 +1,297 @@
+package cloudz
+
+import (
+	"net"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goec2 "github.com/awslabs/goformation/v6/cloudformation/ec2"
+	gologs "github.com/awslabs/goformation/v6/cloudformation/logs"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// ClientVPN constants.
+const (
+	ClientVPNPluginDisplayName          = "ClientVPN"
+	ClientVPNPluginName                 = "client-vpn"
+	ClientVPNRefLogGroup                = CloudRef("lg")
+	ClientVPNRefEndpoint                = CloudRef("e")
+	ClientVPNRefTargetNetworkA          = CloudRef("tn-a")
+	ClientVPNRefTargetNetworkB          = CloudRef("tn-b")
+	ClientVPNRefAuthorizationRule       = CloudRef("ar")
+	ClientVPNDefaultSessionTimeoutHours = 12
+
+	clientVPNDNSServer = "10.0.0.2" // the Amazon-provided DNS server of the VPC (i.e. base of CIDRVPC plus two)
+)
+
+var (
+	_ ClientVPN = &clientVPNImpl{}
+	_ Plugin    = &clientVPNImpl{}
+)
+
+// ClientVPNConfigFunc returns the client VPN config for a given Stage.
+type ClientVPNConfigFunc func(Stage, *ClientVPNDependencies) *ClientVPNConfig
+
+// ClientVPNEventHookFunc describes a client VPN event hook.
+type ClientVPNEventHookFunc func(ClientVPN, Event, string)
+
+// ClientVPNConfig describes the client VPN config.
+//
+// The endpoint is associated with the private subnets of the Network and shares its security group, so connected
+// clients can reach the private resources of the stage (e.g. Postgres, container services). Only the traffic to the VPC
+// is routed through the VPN, and the Amazon-provided DNS server of the VPC is pushed to the clients. The Certificate is
+// used as server certificate. It has no local equivalent.
+type ClientVPNConfig struct {
+	Stage     Stage  `validate:"required"`
+	Name      string `validate:"required,resource-name"`
+	Cloud     *ClientVPNConfigCloud
+	EventHook ClientVPNEventHookFunc
+}
+
+// MustValidate validates the client VPN config.
+func (c *ClientVPNConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing ClientVPNConfig.Cloud")
+
+	if c.Cloud != nil {
+		errorz.Assertf((c.Cloud.ClientRootCertificateChainARN != "") != (c.Cloud.SAMLProviderARN != ""),
+			"exactly one of ClientVPNConfigCloud.ClientRootCertificateChainARN, SAMLProviderARN must be set")
+		clientVPNMustValidateClientCIDRBlock(c.Cloud.ClientCIDRBlock)
+	}
+}
+
+// ClientVPNConfigCloud describes part of the client VPN config.
+//
+// Clients are assigned addresses from the ClientCIDRBlock, which must be between /12 and /22 and must not overlap with
+// CIDRVPC. They authenticate either with a client certificate signed by the ClientRootCertificateChainARN (an ACM
+// certificate), or with a SAML identity provider (SAMLProviderARN).
+type ClientVPNConfigCloud struct {
+	ClientCIDRBlock               string `validate:"required,cidrv4"`
+	ClientRootCertificateChainARN string
+	SAMLProviderARN               string
+	SessionTimeoutHours           int `validate:"omitempty,oneof=8 10 12 24"`
+}
+
+// ClientVPNDependencies describes the client VPN dependencies.
+type ClientVPNDependencies struct {
+	Network           Network     `validate:"required"`
+	Certificate       Certificate `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the client VPN dependencies.
+func (d *ClientVPNDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// ClientVPNCloudMetadata describes the client VPN cloud metadata.
+type ClientVPNCloudMetadata struct {
+	Exports CloudExports
+}
+
+// GetEndpointID returns the client VPN endpoint ID, used to download the client configuration.
+func (m *ClientVPNCloudMetadata) GetEndpointID() string {
+	return m.Exports.GetRef(ClientVPNRefEndpoint)
+}
+
+// ClientVPN describes an AWS Client VPN endpoint, which gives developers access to the private resources of the stage.
+type ClientVPN interface {
+	Plugin
+	GetConfig() *ClientVPNConfig
+	GetCloudMetadata(require bool) *ClientVPNCloudMetadata
+}
+
+type clientVPNImpl struct {
+	cfgFunc       ClientVPNConfigFunc
+	deps          *ClientVPNDependencies
+	cfg           *ClientVPNConfig
+	cloudMetadata *ClientVPNCloudMetadata
+}
+
+// NewClientVPN initializes a new ClientVPN.
+func NewClientVPN(cfgFunc ClientVPNConfigFunc, deps *ClientVPNDependencies) ClientVPN {
+	deps.MustValidate()
+
+	return &clientVPNImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*clientVPNImpl) GetDisplayName() string {
+	return ClientVPNPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *clientVPNImpl) GetName() string {
+	return ClientVPNPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *clientVPNImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *clientVPNImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Network:     {},
+		p.deps.Certificate: {},
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *clientVPNImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *clientVPNImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(ClientVPNPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the ClientVPN interface.
+func (p *clientVPNImpl) GetConfig() *ClientVPNConfig {
+	return p.cfg
+}
+
+// GetCloudMetadata implements the ClientVPN interface.
+func (p *clientVPNImpl) GetCloudMetadata(require bool) *ClientVPNCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(ClientVPNPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *clientVPNImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (*clientVPNImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
+	// intentionally empty
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *clientVPNImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+	network := p.deps.Network.GetCloudMetadata(true)
+
+	tpl.Resources[ClientVPNRefLogGroup.Ref()] = &gologs.LogGroup{
+		LogGroupName:    stringz.Ptr(ClientVPNRefLogGroup.Name(p)),
+		RetentionInDays: intz.Ptr(90),
+	}
+	CloudAddExpRef(tpl, p, ClientVPNRefLogGroup)
+
+	authenticationOption := goec2.ClientVpnEndpoint_ClientAuthenticationRequest{
+		Type: "certificate-authentication",
+		MutualAuthentication: &goec2.ClientVpnEndpoint_CertificateAuthenticationRequest{
+			ClientRootCertificateChainArn: p.cfg.Cloud.ClientRootCertificateChainARN,
+		},
+	}
+
+	if p.cfg.Cloud.SAMLProviderARN != "" {
+		authenticationOption = goec2.ClientVpnEndpoint_ClientAuthenticationRequest{
+			Type: "federated-authentication",
+			FederatedAuthentication: &goec2.ClientVpnEndpoint_FederatedAuthenticationRequest{
+				SAMLProviderArn: p.cfg.Cloud.SAMLProviderARN,
+			},
+		}
+	}
+
+	sessionTimeoutHours := p.cfg.Cloud.SessionTimeoutHours
+	if sessionTimeoutHours == 0 {
+		sessionTimeoutHours = ClientVPNDefaultSessionTimeoutHours
+	}
+
+	tpl.Resources[ClientVPNRefEndpoint.Ref()] = &goec2.ClientVpnEndpoint{
+		AuthenticationOptions: []goec2.ClientVpnEndpoint_ClientAuthenticationRequest{
+			authenticationOption,
+		},
+		ClientCidrBlock: p.cfg.Cloud.ClientCIDRBlock,
+		ConnectionLogOptions: &goec2.ClientVpnEndpoint_ConnectionLogOptions{
+			CloudwatchLogGroup: stringz.Ptr(gocf.Ref(ClientVPNRefLogGroup.Ref())),
+			Enabled:            true,
+		},
+		Description: stringz.Ptr(ClientVPNRefEndpoint.Name(p)),
+		DnsServers: &[]string{
+			clientVPNDNSServer,
+		},
+		SecurityGroupIds: &[]string{
+			network.Exports.GetRef(NetworkRefSecurityGroup),
+		},
+		ServerCertificateArn: p.deps.Certificate.GetCloudMetadata(true).ARN,
+		SessionTimeoutHours:  intz.Ptr(sessionTimeoutHours),
+		SplitTunnel:          boolz.Ptr(true),
+		TagSpecifications: &[]goec2.ClientVpnEndpoint_TagSpecification{
+			{
+				ResourceType: "client-vpn-endpoint",
+				Tags:         *CloudGetDefaultTags(ClientVPNRefEndpoint.Name(p)),
+			},
+		},
+		VpcId: stringz.Ptr(network.Exports.GetRef(NetworkRefVPC)),
+	}
+	CloudAddExpRef(tpl, p, ClientVPNRefEndpoint)
+
+	for ref, subnetRef := range map[CloudRef]CloudRef{
+		ClientVPNRefTargetNetworkA: NetworkRefSubnetPrivateA,
+		ClientVPNRefTargetNetworkB: NetworkRefSubnetPrivateB,
+	} {
+		tpl.Resources[ref.Ref()] = &goec2.ClientVpnTargetNetworkAssociation{
+			ClientVpnEndpointId: gocf.Ref(ClientVPNRefEndpoint.Ref()),
+			SubnetId:            network.Exports.GetRef(subnetRef),
+		}
+		CloudAddExpRef(tpl, p, ref)
+	}
+
+	tpl.Resources[ClientVPNRefAuthorizationRule.Ref()] = &goec2.ClientVpnAuthorizationRule{
+		AuthorizeAllGroups:  boolz.Ptr(true),
+		ClientVpnEndpointId: gocf.Ref(ClientVPNRefEndpoint.Ref()),
+		Description:         stringz.Ptr(ClientVPNRefAuthorizationRule.Name(p)),
+		TargetNetworkCidr:   CIDRVPC,
+	}
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *clientVPNImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &ClientVPNCloudMetadata{
+		Exports: NewCloudExports(stack),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *clientVPNImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+// clientVPNMustValidateClientCIDRBlock checks that the client CIDR block has a valid size and does not overlap CIDRVPC.
+func clientVPNMustValidateClientCIDRBlock(clientCIDRBlock string) {
+	_, clientNet, err := net.ParseCIDR(clientCIDRBlock)
+	errorz.MaybeMustWrap(err)
+
+	ones, _ := clientNet.Mask.Size()
+	errorz.Assertf(ones >= 12 && ones <= 22, "ClientVPNConfigCloud.ClientCIDRBlock must be between /12 and /22")
+
+	_, vpcNet, err := net.ParseCIDR(CIDRVPC)
+	errorz.MaybeMustWrap(err)
+
+	errorz.Assertf(!clientNet.Contains(vpcNet.IP) && !vpcNet.Contains(clientNet.IP),
+		"ClientVPNConfigCloud.ClientCIDRBlock must not overlap with %v", errorz.A(CIDRVPC))
+}
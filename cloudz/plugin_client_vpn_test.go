@@ -0,0 +1,63 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientVPNMustValidateClientCIDRBlock(t *testing.T) {
+	testCases := []struct {
+		name            string
+		clientCIDRBlock string
+		isValid         bool
+	}{
+		{
+			name:            "valid",
+			clientCIDRBlock: "10.100.0.0/22",
+			isValid:         true,
+		},
+		{
+			name:            "largest",
+			clientCIDRBlock: "172.16.0.0/12",
+			isValid:         true,
+		},
+		{
+			name:            "too small",
+			clientCIDRBlock: "10.100.0.0/24",
+			isValid:         false,
+		},
+		{
+			name:            "too large",
+			clientCIDRBlock: "10.0.0.0/8",
+			isValid:         false,
+		},
+		{
+			name:            "inside vpc",
+			clientCIDRBlock: "10.0.128.0/22",
+			isValid:         false,
+		},
+		{
+			name:            "containing vpc",
+			clientCIDRBlock: "10.0.0.0/12",
+			isValid:         false,
+		},
+		{
+			name:            "invalid",
+			clientCIDRBlock: "10.100.0.0",
+			isValid:         false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			if testCase.isValid {
+				require.NotPanics(t, func() { clientVPNMustValidateClientCIDRBlock(testCase.clientCIDRBlock) })
+			} else {
+				require.Panics(t, func() { clientVPNMustValidateClientCIDRBlock(testCase.clientCIDRBlock) })
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package cloudz
+
+import (
+	"fmt"
+	"net/url"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// ContainerService constants.
+const (
+	ContainerServicePluginDisplayName = "ContainerService"
+	ContainerServicePluginName        = "container-service"
+)
+
+var (
+	_ ContainerService = &containerServiceImpl{}
+	_ Plugin           = &containerServiceImpl{}
+)
+
+// ContainerServiceConfigFunc returns the container service config for a given Stage.
+type ContainerServiceConfigFunc func(Stage, *ContainerServiceDependencies) *ContainerServiceConfig
+
+// ContainerServiceEventHookFunc describes a container service event hook.
+type ContainerServiceEventHookFunc func(ContainerService, Event, string)
+
+// ContainerServiceConfig describes the container service config.
+//
+// The image is built from the Dockerfile in DirPath, and is expected to serve HTTP on Port. In the cloud, it runs as a
+// Fargate service behind the HTTPS listener of the LoadBalancer. The EFSMounts refer to access points of the EFS
+// dependency.
+type ContainerServiceConfig struct {
+	Stage       Stage  `validate:"required"`
+	Name        string `validate:"required,resource-name"`
+	DirPath     string `validate:"required"`
+	Port        uint16 `validate:"required"`
+	Environment map[string]string
+	EFSMounts   []*EFSMount
+	Local       *ContainerServiceConfigLocal
+	Cloud       *ContainerServiceConfigCloud
+	EventHook   ContainerServiceEventHookFunc
+}
+
+// MustValidate validates the container service config.
+func (c *ContainerServiceConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing ContainerServiceConfig.Local")
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing ContainerServiceConfig.Cloud")
+
+	if stageTarget == Cloud && c.Cloud.Routing != nil {
+		c.Cloud.Routing.MustValidate()
+	}
+}
+
+// ContainerServiceConfigLocal describes part of the container service config.
+type ContainerServiceConfigLocal struct {
+	ExternalPort uint16 `validate:"required"`
+}
+
+// ContainerServiceConfigCloud describes part of the container service config.
+// The ListenerRulePriority must be unique among the plugins sharing the LoadBalancer (Hasura uses 100).
+type ContainerServiceConfigCloud struct {
+	DomainName           string `validate:"required"`
+	HealthCheckPath      string `validate:"required,startswith=/"`
+	Replicas             int    `validate:"required"`
+	CPU                  int    `validate:"required"`
+	Memory               int    `validate:"required"`
+	ListenerRulePriority int    `validate:"required,min=1,max=50000"`
+	RolePolicies         []goiam.Role_Policy
+	Routing              *RecordSetRoutingConfig
+	ServiceConnect       *ECSServiceTemplateConfigServiceConnect
+}
+
+// ContainerServiceDependencies describes the container service dependencies.
+type ContainerServiceDependencies struct {
+	Certificate       Certificate `validate:"required"`
+	EFS               EFS
+	ImageRepository   ImageRepository `validate:"required"`
+	LoadBalancer      LoadBalancer    `validate:"required"`
+	Network           Network         `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the container service dependencies.
+func (d *ContainerServiceDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// ContainerServiceLocalMetadata describes the container service local metadata.
+type ContainerServiceLocalMetadata struct {
+	ContainerName string
+	ExternalURL   *url.URL
+	InternalURL   *url.URL
+}
+
+// ContainerServiceCloudMetadata describes the container service cloud metadata.
+type ContainerServiceCloudMetadata struct {
+	Exports CloudExports
+	URL     *url.URL
+}
+
+// ContainerService describes a container service.
+type ContainerService interface {
+	Plugin
+	GetConfig() *ContainerServiceConfig
+	GetDependencies() *ContainerServiceDependencies
+	GetLocalMetadata() *ContainerServiceLocalMetadata
+	GetCloudMetadata(require bool) *ContainerServiceCloudMetadata
+}
+
+type containerServiceImpl struct {
+	cfgFunc       ContainerServiceConfigFunc
+	deps          *ContainerServiceDependencies
+	cfg           *ContainerServiceConfig
+	localMetadata *ContainerServiceLocalMetadata
+	cloudMetadata *ContainerServiceCloudMetadata
+}
+
+// NewContainerService initializes a new ContainerService.
+func NewContainerService(cfgFunc ContainerServiceConfigFunc, deps *ContainerServiceDependencies) ContainerService {
+	deps.MustValidate()
+
+	return &containerServiceImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*containerServiceImpl) GetDisplayName() string {
+	return ContainerServicePluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *containerServiceImpl) GetName() string {
+	return ContainerServicePluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *containerServiceImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *containerServiceImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Certificate:     {},
+		p.deps.ImageRepository: {},
+		p.deps.LoadBalancer:    {},
+		p.deps.Network:         {},
+	}
+
+	if p.deps.EFS != nil {
+		dependenciesMap[p.deps.EFS] = struct{}{}
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *containerServiceImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+	efsMustValidateMounts(p, p.deps.EFS, p.cfg.EFSMounts)
+}
+
+// GetStage implements the Plugin interface.
+func (p *containerServiceImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(ContainerServicePluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the ContainerService interface.
+func (p *containerServiceImpl) GetConfig() *ContainerServiceConfig {
+	return p.cfg
+}
+
+// GetDependencies implements the ContainerService interface.
+func (p *containerServiceImpl) GetDependencies() *ContainerServiceDependencies {
+	return p.deps
+}
+
+// GetLocalMetadata implements the ContainerService interface.
+func (p *containerServiceImpl) GetLocalMetadata() *ContainerServiceLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(ContainerServicePluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the ContainerService interface.
+func (p *containerServiceImpl) GetCloudMetadata(require bool) *ContainerServiceCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(ContainerServicePluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *containerServiceImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *containerServiceImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
+	containerName := LocalGetContainerName(p)
+
+	p.localMetadata = &ContainerServiceLocalMetadata{
+		ContainerName: containerName,
+		ExternalURL:   urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.ExternalPort)),
+		InternalURL:   urlz.MustParse(fmt.Sprintf("http://%v:%v", containerName, p.cfg.Port)),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name: containerName,
+		Build: dctypes.BuildConfig{
+			Context: p.cfg.DirPath,
+		},
+		ContainerName: containerName,
+		Environment: func() map[string]*string {
+			e := make(map[string]*string)
+			for k, v := range p.cfg.Environment {
+				e[k] = stringz.Ptr(v)
+			}
+			return e
+		}(),
+		Image:    containerName,
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    uint32(p.cfg.Port),
+				Published: uint32(p.cfg.Local.ExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+		Volumes: func() []dctypes.ServiceVolumeConfig {
+			if len(p.cfg.EFSMounts) == 0 {
+				return nil
+			}
+			return LocalGetEFSServiceVolumeConfigs(p.deps.EFS, p.cfg.EFSMounts)
+		}(),
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *containerServiceImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	CloudAddECSServiceResources(tpl, p, &ECSServiceTemplateConfig{
+		Image:                p.getImageWithTag(),
+		Port:                 int(p.cfg.Port),
+		Environment:          p.cfg.Environment,
+		HealthCheckPath:      p.cfg.Cloud.HealthCheckPath,
+		Replicas:             p.cfg.Cloud.Replicas,
+		CPU:                  p.cfg.Cloud.CPU,
+		Memory:               p.cfg.Cloud.Memory,
+		TaskRolePolicies:     p.cfg.Cloud.RolePolicies,
+		EFS:                  p.deps.EFS,
+		EFSMounts:            p.cfg.EFSMounts,
+		DomainName:           p.cfg.Cloud.DomainName,
+		ListenerRulePriority: p.cfg.Cloud.ListenerRulePriority,
+		ServiceConnect:       p.cfg.Cloud.ServiceConnect,
+		Routing:              p.cfg.Cloud.Routing,
+		Certificate:          p.deps.Certificate,
+		LoadBalancer:         p.deps.LoadBalancer,
+		Network:              p.deps.Network,
+	})
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *containerServiceImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &ContainerServiceCloudMetadata{
+		Exports: NewCloudExports(stack),
+		URL:     urlz.MustParse(fmt.Sprintf("https://%v", p.cfg.Cloud.DomainName)),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *containerServiceImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case CloudPreflightEvent:
+		p.cloudPreflightEventHook()
+	case CloudBeforeDeployEvent:
+		p.cloudBeforeDeployEventHook()
+	case CloudAfterDeployEvent:
+		p.cloudAfterDeployEventHook()
+	case CloudBeforeDestroyEvent:
+		p.cloudBeforeDestroyEventHook()
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *containerServiceImpl) cloudPreflightEventHook() {
+	CloudMustCheckDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName, p.cfg.Cloud.Routing)
+}
+
+func (p *containerServiceImpl) cloudBeforeDeployEventHook() {
+	imageWithTag := p.getImageWithTag()
+
+	CloudBuildImage(p, p.cfg.DirPath, imageWithTag)
+	p.cfg.Stage.GetConfig().App.GetOperations().DockerLoginToECR()
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker", "push", imageWithTag).MustRun()
+}
+
+func (p *containerServiceImpl) cloudAfterDeployEventHook() {
+	CloudMaybeUpsertDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName,
+		p.deps.LoadBalancer.GetCloudMetadata(true).Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttDNSName))
+}
+
+func (p *containerServiceImpl) cloudBeforeDestroyEventHook() {
+	CloudMaybeDeleteDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName,
+		p.deps.LoadBalancer.GetCloudMetadata(true).Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttDNSName))
+}
+
+func (p *containerServiceImpl) getImageWithTag() string {
+	return p.deps.ImageRepository.GetCloudMetadata(true).ImageName + ":" + p.cfg.Stage.AsCloudStage().GetCloudConfig().Version
+}
@@ -0,0 +1,369 @@
+package cloudz
+
+import (
+	"fmt"
+	"path/filepath"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goevents "github.com/awslabs/goformation/v6/cloudformation/events"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// CronJob constants.
+const (
+	CronJobPluginDisplayName = "CronJob"
+	CronJobPluginName        = "cron-job"
+	CronJobRefRule           = CloudRef("r")
+	CronJobRefRoleEvents     = CloudRef("r-ev")
+	CronJobAttARN            = CloudAtt("Arn")
+
+	cronJobTargetID = "task"
+)
+
+var (
+	_ CronJob = &cronJobImpl{}
+	_ Plugin  = &cronJobImpl{}
+)
+
+// CronJobConfigFunc returns the cron job config for a given Stage.
+type CronJobConfigFunc func(Stage, *CronJobDependencies) *CronJobConfig
+
+// CronJobEventHookFunc describes a cron job event hook.
+type CronJobEventHookFunc func(CronJob, Event, string)
+
+// CronJobConfig describes the cron job config.
+//
+// The image is built from the Dockerfile in DirPath, and is expected to run the job and exit. The Expression uses the
+// EventBridge syntax (see ScheduleConfig). In the cloud, each run is a Fargate task started by an EventBridge rule in
+// the private subnets of the Network, so the job is not subject to the Lambda time limit.
+type CronJobConfig struct {
+	Stage       Stage  `validate:"required"`
+	Name        string `validate:"required,resource-name"`
+	DirPath     string `validate:"required"`
+	Expression  string `validate:"required"`
+	Environment map[string]string
+	Disabled    bool
+	Cloud       *CronJobConfigCloud
+	EventHook   CronJobEventHookFunc
+}
+
+// MustValidate validates the cron job config.
+func (c *CronJobConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing CronJobConfig.Cloud")
+	errorz.Assertf(
+		scheduleRateExpressionRegexp.MatchString(c.Expression) || scheduleCronExpressionRegexp.MatchString(c.Expression),
+		"invalid CronJobConfig.Expression: %v", errorz.A(c.Expression))
+}
+
+// CronJobConfigCloud describes part of the cron job config.
+type CronJobConfigCloud struct {
+	CPU          int `validate:"required"`
+	Memory       int `validate:"required"`
+	RolePolicies []goiam.Role_Policy
+}
+
+// CronJobDependencies describes the cron job dependencies.
+type CronJobDependencies struct {
+	ImageRepository   ImageRepository `validate:"required"`
+	Network           Network         `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the cron job dependencies.
+func (d *CronJobDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// CronJobLocalMetadata describes the cron job local metadata.
+type CronJobLocalMetadata struct {
+	ContainerName     string
+	CronContainerName string
+	CronExpression    string
+}
+
+// CronJobCloudMetadata describes the cron job cloud metadata.
+type CronJobCloudMetadata struct {
+	Exports CloudExports
+}
+
+// CronJob describes a container image periodically run as an ECS task by an EventBridge rule.
+// Locally, the job container is created alongside a cron container which starts it on schedule using the Docker socket.
+// Note that the job container also runs once when the local stage is started.
+type CronJob interface {
+	Plugin
+	GetConfig() *CronJobConfig
+	GetDependencies() *CronJobDependencies
+	GetLocalMetadata() *CronJobLocalMetadata
+	GetCloudMetadata(require bool) *CronJobCloudMetadata
+}
+
+type cronJobImpl struct {
+	cfgFunc       CronJobConfigFunc
+	deps          *CronJobDependencies
+	cfg           *CronJobConfig
+	localMetadata *CronJobLocalMetadata
+	cloudMetadata *CronJobCloudMetadata
+}
+
+// NewCronJob initializes a new CronJob.
+func NewCronJob(cfgFunc CronJobConfigFunc, deps *CronJobDependencies) CronJob {
+	deps.MustValidate()
+
+	return &cronJobImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*cronJobImpl) GetDisplayName() string {
+	return CronJobPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *cronJobImpl) GetName() string {
+	return CronJobPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *cronJobImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *cronJobImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.ImageRepository: {},
+		p.deps.Network:         {},
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *cronJobImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *cronJobImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(CronJobPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the CronJob interface.
+func (p *cronJobImpl) GetConfig() *CronJobConfig {
+	return p.cfg
+}
+
+// GetDependencies implements the CronJob interface.
+func (p *cronJobImpl) GetDependencies() *CronJobDependencies {
+	return p.deps
+}
+
+// GetLocalMetadata implements the CronJob interface.
+func (p *cronJobImpl) GetLocalMetadata() *CronJobLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(CronJobPluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the CronJob interface.
+func (p *cronJobImpl) GetCloudMetadata(require bool) *CronJobCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(CronJobPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *cronJobImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *cronJobImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath string) {
+	containerName := LocalGetContainerName(p)
+	cronContainerName := containerName + "-cron"
+
+	p.localMetadata = &CronJobLocalMetadata{
+		ContainerName:     containerName,
+		CronContainerName: cronContainerName,
+		CronExpression:    getScheduleCronExpression(p.cfg.Expression),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name: containerName,
+		Build: dctypes.BuildConfig{
+			Context: p.cfg.DirPath,
+		},
+		ContainerName: containerName,
+		Environment: func() map[string]*string {
+			e := make(map[string]*string)
+			for k, v := range p.cfg.Environment {
+				e[k] = stringz.Ptr(v)
+			}
+			return e
+		}(),
+		Image:    containerName,
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Restart:  "no",
+	})
+
+	if p.cfg.Disabled {
+		return
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          cronContainerName,
+		ContainerName: cronContainerName,
+		Image:         LocalGetImage(p, "docker:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Docker+"-cli"),
+		Command:       dctypes.ShellCommand{"crond", "-f", "-l", "8"},
+		DependsOn:     []string{containerName},
+		Restart:       "unless-stopped",
+		Volumes: []dctypes.ServiceVolumeConfig{
+			{
+				Type:     "bind",
+				Source:   filez.MustAbs(filepath.Join(buildDirPath, "crontab")),
+				Target:   "/etc/crontabs/root",
+				ReadOnly: true,
+			},
+			{
+				Type:   "bind",
+				Source: "/var/run/docker.sock",
+				Target: "/var/run/docker.sock",
+			},
+		},
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *cronJobImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+	network := p.deps.Network.GetCloudMetadata(true)
+
+	CloudAddECSTaskResources(tpl, p, &ECSServiceTemplateConfig{
+		Image:            p.getImageWithTag(),
+		Environment:      p.cfg.Environment,
+		CPU:              p.cfg.Cloud.CPU,
+		Memory:           p.cfg.Cloud.Memory,
+		TaskRolePolicies: p.cfg.Cloud.RolePolicies,
+		Network:          p.deps.Network,
+	})
+
+	tpl.Resources[CronJobRefRoleEvents.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("events.amazonaws.com"),
+		Policies: &[]goiam.Role_Policy{
+			{
+				PolicyName: CronJobRefRoleEvents.Name(p),
+				PolicyDocument: NewPolicyDocument(
+					NewPolicyStatement().
+						AddActions("ecs:RunTask").
+						AddResources(gocf.Ref(ECSServiceRefTaskDefinition.Ref())),
+					NewPolicyStatement().
+						AddActions("iam:PassRole").
+						AddResources(
+							gocf.GetAtt(ECSServiceRefRoleExecution.Ref(), ECSServiceAttARN.Ref()),
+							gocf.GetAtt(ECSServiceRefRoleTask.Ref(), ECSServiceAttARN.Ref()))),
+			},
+		},
+		RoleName: stringz.Ptr(CronJobRefRoleEvents.Name(p)),
+		Tags:     CloudGetDefaultTags(CronJobRefRoleEvents.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, CronJobRefRoleEvents)
+	CloudAddExpGetAtt(tpl, p, CronJobRefRoleEvents, CronJobAttARN)
+
+	tpl.Resources[CronJobRefRule.Ref()] = &goevents.Rule{
+		Description:        stringz.Ptr(CronJobRefRule.Name(p)),
+		Name:               stringz.Ptr(CronJobRefRule.Name(p)),
+		ScheduleExpression: stringz.Ptr(p.cfg.Expression),
+		State: stringz.Ptr(func() string {
+			if p.cfg.Disabled {
+				return "DISABLED"
+			}
+			return "ENABLED"
+		}()),
+		Targets: &[]goevents.Rule_Target{
+			{
+				Arn: gocf.GetAtt(ECSServiceRefCluster.Ref(), ECSServiceAttARN.Ref()),
+				EcsParameters: &goevents.Rule_EcsParameters{
+					EnableECSManagedTags: boolz.Ptr(true),
+					LaunchType:           stringz.Ptr("FARGATE"),
+					NetworkConfiguration: &goevents.Rule_NetworkConfiguration{
+						AwsVpcConfiguration: &goevents.Rule_AwsVpcConfiguration{
+							AssignPublicIp: stringz.Ptr("DISABLED"),
+							SecurityGroups: &[]string{
+								network.Exports.GetRef(NetworkRefSecurityGroup),
+							},
+							Subnets: []string{
+								network.Exports.GetRef(NetworkRefSubnetPrivateA),
+								network.Exports.GetRef(NetworkRefSubnetPrivateB),
+							},
+						},
+					},
+					PropagateTags:     stringz.Ptr("TASK_DEFINITION"),
+					TaskCount:         intz.Ptr(1),
+					TaskDefinitionArn: gocf.Ref(ECSServiceRefTaskDefinition.Ref()),
+				},
+				Id:      cronJobTargetID,
+				RoleArn: stringz.Ptr(gocf.GetAtt(CronJobRefRoleEvents.Ref(), CronJobAttARN.Ref())),
+			},
+		},
+	}
+	CloudAddExpRef(tpl, p, CronJobRefRule)
+	CloudAddExpGetAtt(tpl, p, CronJobRefRule, CronJobAttARN)
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *cronJobImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &CronJobCloudMetadata{
+		Exports: NewCloudExports(stack),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *cronJobImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case LocalBeforeCreateEvent:
+		p.localBeforeCreateEventHook(buildDirPath)
+	case CloudBeforeDeployEvent:
+		p.cloudBeforeDeployEventHook()
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *cronJobImpl) localBeforeCreateEventHook(buildDirPath string) {
+	filez.MustPrepareDir(buildDirPath, 0777)
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "crontab"), 0777, 0666,
+		[]byte(fmt.Sprintf("%v docker start '%v'\n", p.localMetadata.CronExpression, p.localMetadata.ContainerName)))
+}
+
+func (p *cronJobImpl) cloudBeforeDeployEventHook() {
+	imageWithTag := p.getImageWithTag()
+
+	CloudBuildImage(p, p.cfg.DirPath, imageWithTag)
+	p.cfg.Stage.GetConfig().App.GetOperations().DockerLoginToECR()
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker", "push", imageWithTag).MustRun()
+}
+
+func (p *cronJobImpl) getImageWithTag() string {
+	return p.deps.ImageRepository.GetCloudMetadata(true).ImageName + ":" + p.cfg.Stage.AsCloudStage().GetCloudConfig().Version
+}
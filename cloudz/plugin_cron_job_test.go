@@ -0,0 +1,31 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronJobConfig_MustValidate(t *testing.T) {
+	newConfig := func(expression string) *CronJobConfig {
+		return &CronJobConfig{
+			Stage:      &cloudStageImpl{cfg: &CloudStageConfig{Name: "staging"}},
+			Name:       "test",
+			DirPath:    "job",
+			Expression: expression,
+			Cloud: &CronJobConfigCloud{
+				CPU:    256,
+				Memory: 512,
+			},
+		}
+	}
+
+	require.NotPanics(t, func() { newConfig("rate(1 hour)").MustValidate(Cloud) })
+	require.NotPanics(t, func() { newConfig("cron(0 3 * * ? *)").MustValidate(Cloud) })
+	require.Panics(t, func() { newConfig("0 3 * * *").MustValidate(Cloud) })
+
+	cfg := newConfig("rate(1 hour)")
+	cfg.Cloud = nil
+	require.NotPanics(t, func() { cfg.MustValidate(Local) })
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+}
@@ -0,0 +1,498 @@
+package cloudz
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goevents "github.com/awslabs/goformation/v6/cloudformation/events"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+)
+
+// DataExport constants.
+const (
+	DataExportPluginDisplayName = "DataExport"
+	DataExportPluginName        = "data-export"
+	DataExportRefRule           = CloudRef("r")
+	DataExportRefRoleEvents     = CloudRef("r-ev")
+	DataExportAttARN            = CloudAtt("Arn")
+
+	dataExportTargetID = "task"
+)
+
+var (
+	_ DataExport = &dataExportImpl{}
+	_ Plugin     = &dataExportImpl{}
+)
+
+// DataExportConfigFunc returns the data export config for a given Stage.
+type DataExportConfigFunc func(Stage, *DataExportDependencies) *DataExportConfig
+
+// DataExportEventHookFunc describes a data export event hook.
+type DataExportEventHookFunc func(DataExport, Event, string)
+
+// DataExportConfig describes the data export config.
+//
+// On each run, the selected Tables of the Postgres are extracted by DuckDB to parquet files in the Bucket, with keys of
+// the form "<KeyPrefix>/<table>/dt=<YYYY-MM-DD>/<run-id>.parquet" (see DataExport.GetTableKeyPrefix), so that downstream
+// analytics (e.g. Athena, Redshift Spectrum, or the Warehouse) can query them without direct database access. KeyPrefix
+// defaults to Name. The Expression uses the EventBridge syntax (see ScheduleConfig). Scheduling works like in CronJob.
+type DataExportConfig struct {
+	Stage      Stage                    `validate:"required"`
+	Name       string                   `validate:"required,resource-name"`
+	Expression string                   `validate:"required"`
+	Tables     []*DataExportConfigTable `validate:"required,min=1,dive,required"`
+	KeyPrefix  string
+	Disabled   bool
+	Cloud      *DataExportConfigCloud
+	EventHook  DataExportEventHookFunc
+}
+
+// MustValidate validates the data export config.
+func (c *DataExportConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing DataExportConfig.Cloud", errorz.Prefix(DataExportPluginName))
+	errorz.Assertf(
+		scheduleRateExpressionRegexp.MatchString(c.Expression) || scheduleCronExpressionRegexp.MatchString(c.Expression),
+		"invalid DataExportConfig.Expression: %v", errorz.A(c.Expression), errorz.Prefix(DataExportPluginName))
+	errorz.Assertf(!strings.HasPrefix(c.KeyPrefix, "/") && !strings.HasSuffix(c.KeyPrefix, "/"),
+		"invalid DataExportConfig.KeyPrefix: %v", errorz.A(c.KeyPrefix), errorz.Prefix(DataExportPluginName))
+
+	tables := map[string]struct{}{}
+	for _, table := range c.Tables {
+		_, ok := tables[table.Table]
+		errorz.Assertf(!ok, "duplicate DataExportConfigTable.Table: %v", errorz.A(table.Table), errorz.Prefix(DataExportPluginName))
+		tables[table.Table] = struct{}{}
+	}
+}
+
+// DataExportConfigTable describes part of the data export config.
+// The Table must be schema-qualified, e.g. "public.orders". If Query is set, it is run as-is in Postgres to select the
+// exported rows and columns (e.g. to omit personal data), otherwise the whole table is exported.
+type DataExportConfigTable struct {
+	Table string `validate:"required"`
+	Query string
+}
+
+// DataExportConfigCloud describes part of the data export config.
+type DataExportConfigCloud struct {
+	CPU    int `validate:"required"`
+	Memory int `validate:"required"`
+}
+
+// DataExportDependencies describes the data export dependencies.
+// RuntimeSecrets is required in cloud stages, and must depend on Postgres: the database password is injected from it.
+type DataExportDependencies struct {
+	Bucket            Bucket          `validate:"required"`
+	ImageRepository   ImageRepository `validate:"required"`
+	Network           Network         `validate:"required"`
+	Postgres          Postgres        `validate:"required"`
+	RuntimeSecrets    RuntimeSecrets
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the data export dependencies.
+func (d *DataExportDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// DataExportLocalMetadata describes the data export local metadata.
+type DataExportLocalMetadata struct {
+	ContainerName     string
+	CronContainerName string
+	CronExpression    string
+	KeyPrefix         string
+}
+
+// DataExportCloudMetadata describes the data export cloud metadata.
+type DataExportCloudMetadata struct {
+	Exports   CloudExports
+	KeyPrefix string
+}
+
+// DataExport describes a scheduled export of Postgres tables to parquet files in a Bucket, run as an ECS task by an
+// EventBridge rule. Locally, it runs against the local Postgres and Bucket (MinIO), also once when the stage is started.
+type DataExport interface {
+	Plugin
+	GetConfig() *DataExportConfig
+	GetDependencies() *DataExportDependencies
+	GetLocalMetadata() *DataExportLocalMetadata
+	GetCloudMetadata(require bool) *DataExportCloudMetadata
+	GetTableKeyPrefix(table string) string
+}
+
+type dataExportImpl struct {
+	cfgFunc       DataExportConfigFunc
+	deps          *DataExportDependencies
+	cfg           *DataExportConfig
+	localMetadata *DataExportLocalMetadata
+	cloudMetadata *DataExportCloudMetadata
+}
+
+// NewDataExport initializes a new DataExport.
+func NewDataExport(cfgFunc DataExportConfigFunc, deps *DataExportDependencies) DataExport {
+	deps.MustValidate()
+
+	return &dataExportImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*dataExportImpl) GetDisplayName() string {
+	return DataExportPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *dataExportImpl) GetName() string {
+	return DataExportPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *dataExportImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *dataExportImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Bucket:          {},
+		p.deps.ImageRepository: {},
+		p.deps.Network:         {},
+		p.deps.Postgres:        {},
+	}
+
+	if p.deps.RuntimeSecrets != nil {
+		dependenciesMap[p.deps.RuntimeSecrets] = struct{}{}
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *dataExportImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+	errorz.Assertf(stage.GetTarget() == Local || p.deps.RuntimeSecrets != nil, "missing DataExportDependencies.RuntimeSecrets", errorz.Prefix(DataExportPluginName))
+}
+
+// GetStage implements the Plugin interface.
+func (p *dataExportImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(DataExportPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the DataExport interface.
+func (p *dataExportImpl) GetConfig() *DataExportConfig {
+	return p.cfg
+}
+
+// GetDependencies implements the DataExport interface.
+func (p *dataExportImpl) GetDependencies() *DataExportDependencies {
+	return p.deps
+}
+
+// GetLocalMetadata implements the DataExport interface.
+func (p *dataExportImpl) GetLocalMetadata() *DataExportLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(DataExportPluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the DataExport interface.
+func (p *dataExportImpl) GetCloudMetadata(require bool) *DataExportCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(DataExportPluginName))
+	return p.cloudMetadata
+}
+
+// GetTableKeyPrefix implements the DataExport interface.
+// It returns the key prefix under which the date partitions of the given table are stored, e.g. "exports/public.orders".
+func (p *dataExportImpl) GetTableKeyPrefix(table string) string {
+	return p.getKeyPrefix() + "/" + table
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *dataExportImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *dataExportImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath string) {
+	containerName := LocalGetContainerName(p)
+	cronContainerName := containerName + "-cron"
+
+	p.localMetadata = &DataExportLocalMetadata{
+		ContainerName:     containerName,
+		CronContainerName: cronContainerName,
+		CronExpression:    getScheduleCronExpression(p.cfg.Expression),
+		KeyPrefix:         p.getKeyPrefix(),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name: containerName,
+		Build: dctypes.BuildConfig{
+			Context: buildDirPath,
+		},
+		ContainerName: containerName,
+		DependsOn: []string{
+			p.deps.Bucket.GetLocalMetadata().ContainerName,
+			p.deps.Postgres.GetLocalMetadata().ContainerName,
+		},
+		Environment: func() map[string]*string {
+			e := make(map[string]*string)
+			for k, v := range getDataExportPostgresEnvironment(p.deps.Postgres.GetLocalMetadata().InternalURL, "disable") {
+				e[k] = stringz.Ptr(v)
+			}
+			e["PGPASSWORD"] = stringz.Ptr(LocalPassword)
+			return e
+		}(),
+		Image:    containerName,
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Restart:  "no",
+	})
+
+	if p.cfg.Disabled {
+		return
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          cronContainerName,
+		ContainerName: cronContainerName,
+		Image:         LocalGetImage(p, "docker:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Docker+"-cli"),
+		Command:       dctypes.ShellCommand{"crond", "-f", "-l", "8"},
+		DependsOn:     []string{containerName},
+		Restart:       "unless-stopped",
+		Volumes: []dctypes.ServiceVolumeConfig{
+			{
+				Type:     "bind",
+				Source:   filez.MustAbs(filepath.Join(buildDirPath, "crontab")),
+				Target:   "/etc/crontabs/root",
+				ReadOnly: true,
+			},
+			{
+				Type:   "bind",
+				Source: "/var/run/docker.sock",
+				Target: "/var/run/docker.sock",
+			},
+		},
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *dataExportImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+	network := p.deps.Network.GetCloudMetadata(true)
+	bucketName := p.deps.Bucket.GetCloudMetadata(true).BucketName
+
+	CloudAddECSTaskResources(tpl, p, &ECSServiceTemplateConfig{
+		Image:       p.getImageWithTag(),
+		Environment: getDataExportPostgresEnvironment(p.deps.Postgres.GetCloudMetadata(true).URL, "require"),
+		Secrets: []*ECSServiceTemplateConfigSecret{
+			p.deps.RuntimeSecrets.GetECSSecret("PGPASSWORD", RuntimeSecretsKeyPostgresPassword),
+		},
+		CPU:    p.cfg.Cloud.CPU,
+		Memory: p.cfg.Cloud.Memory,
+		TaskRolePolicies: []goiam.Role_Policy{
+			{
+				PolicyName: DataExportPluginName + "-bucket",
+				PolicyDocument: NewPolicyDocument(
+					NewPolicyStatement().
+						AddActions("s3:PutObject", "s3:AbortMultipartUpload").
+						AddResources(fmt.Sprintf("arn:aws:s3:::%v/%v/*", bucketName, p.getKeyPrefix()))),
+			},
+		},
+		Network: p.deps.Network,
+	})
+
+	tpl.Resources[DataExportRefRoleEvents.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("events.amazonaws.com"),
+		Policies: &[]goiam.Role_Policy{
+			{
+				PolicyName: DataExportRefRoleEvents.Name(p),
+				PolicyDocument: NewPolicyDocument(
+					NewPolicyStatement().
+						AddActions("ecs:RunTask").
+						AddResources(gocf.Ref(ECSServiceRefTaskDefinition.Ref())),
+					NewPolicyStatement().
+						AddActions("iam:PassRole").
+						AddResources(
+							gocf.GetAtt(ECSServiceRefRoleExecution.Ref(), ECSServiceAttARN.Ref()),
+							gocf.GetAtt(ECSServiceRefRoleTask.Ref(), ECSServiceAttARN.Ref()))),
+			},
+		},
+		RoleName: stringz.Ptr(DataExportRefRoleEvents.Name(p)),
+		Tags:     CloudGetDefaultTags(DataExportRefRoleEvents.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, DataExportRefRoleEvents)
+	CloudAddExpGetAtt(tpl, p, DataExportRefRoleEvents, DataExportAttARN)
+
+	tpl.Resources[DataExportRefRule.Ref()] = &goevents.Rule{
+		Description:        stringz.Ptr(DataExportRefRule.Name(p)),
+		Name:               stringz.Ptr(DataExportRefRule.Name(p)),
+		ScheduleExpression: stringz.Ptr(p.cfg.Expression),
+		State: stringz.Ptr(func() string {
+			if p.cfg.Disabled {
+				return "DISABLED"
+			}
+			return "ENABLED"
+		}()),
+		Targets: &[]goevents.Rule_Target{
+			{
+				Arn: gocf.GetAtt(ECSServiceRefCluster.Ref(), ECSServiceAttARN.Ref()),
+				EcsParameters: &goevents.Rule_EcsParameters{
+					EnableECSManagedTags: boolz.Ptr(true),
+					LaunchType:           stringz.Ptr("FARGATE"),
+					NetworkConfiguration: &goevents.Rule_NetworkConfiguration{
+						AwsVpcConfiguration: &goevents.Rule_AwsVpcConfiguration{
+							AssignPublicIp: stringz.Ptr("DISABLED"),
+							SecurityGroups: &[]string{
+								network.Exports.GetRef(NetworkRefSecurityGroup),
+							},
+							Subnets: []string{
+								network.Exports.GetRef(NetworkRefSubnetPrivateA),
+								network.Exports.GetRef(NetworkRefSubnetPrivateB),
+							},
+						},
+					},
+					PropagateTags:     stringz.Ptr("TASK_DEFINITION"),
+					TaskCount:         intz.Ptr(1),
+					TaskDefinitionArn: gocf.Ref(ECSServiceRefTaskDefinition.Ref()),
+				},
+				Id:      dataExportTargetID,
+				RoleArn: stringz.Ptr(gocf.GetAtt(DataExportRefRoleEvents.Ref(), DataExportAttARN.Ref())),
+			},
+		},
+	}
+	CloudAddExpRef(tpl, p, DataExportRefRule)
+	CloudAddExpGetAtt(tpl, p, DataExportRefRule, DataExportAttARN)
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *dataExportImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &DataExportCloudMetadata{
+		Exports:   NewCloudExports(stack),
+		KeyPrefix: p.getKeyPrefix(),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *dataExportImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case LocalBeforeCreateEvent:
+		p.localBeforeCreateEventHook(buildDirPath)
+	case CloudBeforeDeployEvent:
+		p.cloudBeforeDeployEventHook(buildDirPath)
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *dataExportImpl) localBeforeCreateEventHook(buildDirPath string) {
+	bucket := p.deps.Bucket.GetLocalMetadata()
+
+	p.writeBuildDir(buildDirPath, bucket.BucketName, &assets.DataExportSQLTemplateData{
+		Region:    p.cfg.Stage.GetConfig().App.GetConfig().AWSConfig.Region,
+		Endpoint:  bucket.InternalURL.Host,
+		AccessKey: bucket.AccessKey,
+		SecretKey: bucket.SecretKey,
+	})
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "crontab"), 0777, 0666,
+		[]byte(fmt.Sprintf("%v docker start '%v'\n", p.localMetadata.CronExpression, p.localMetadata.ContainerName)))
+}
+
+func (p *dataExportImpl) cloudBeforeDeployEventHook(buildDirPath string) {
+	p.writeBuildDir(buildDirPath, p.deps.Bucket.GetCloudMetadata(true).BucketName, &assets.DataExportSQLTemplateData{
+		Region: p.cfg.Stage.GetConfig().App.GetConfig().AWSConfig.Region,
+	})
+
+	imageWithTag := p.getImageWithTag()
+	CloudBuildImage(p, buildDirPath, imageWithTag)
+	p.cfg.Stage.GetConfig().App.GetOperations().DockerLoginToECR()
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker", "push", imageWithTag).MustRun()
+}
+
+func (p *dataExportImpl) writeBuildDir(buildDirPath, bucketName string, sqlTemplateData *assets.DataExportSQLTemplateData) {
+	filez.MustPrepareDir(buildDirPath, 0777)
+	versions := p.cfg.Stage.GetConfig().App.GetConfig().GetVersions()
+	sqlTemplateData.Tables = p.getSQLTemplateDataTables(bucketName)
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "Dockerfile"), 0777, 0666,
+		templatez.MustParseAndExecuteText(assets.DataExportDockerfileTemplateAsset, assets.DataExportDockerfileTemplateData{
+			BaseImage:     LocalGetImage(p, "debian:"+versions.Debian),
+			DuckDBVersion: versions.DuckDB,
+		}))
+
+	filez.MustWriteFile(filepath.Join(buildDirPath, "export.sh"), 0777, 0666, assets.DataExportSHAsset)
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "export.sql"), 0777, 0666,
+		templatez.MustParseAndExecuteText(assets.DataExportSQLTemplateAsset, sqlTemplateData))
+}
+
+func (p *dataExportImpl) getSQLTemplateDataTables(bucketName string) []*assets.DataExportSQLTemplateDataTable {
+	tables := make([]*assets.DataExportSQLTemplateDataTable, 0, len(p.cfg.Tables))
+
+	for _, table := range p.cfg.Tables {
+		source := "db." + quotePostgresMaintenanceIdentifier(table.Table)
+		if table.Query != "" {
+			source = fmt.Sprintf("postgres_query('db', %v)", quoteDataExportSQLString(table.Query))
+		}
+
+		tables = append(tables, &assets.DataExportSQLTemplateDataTable{
+			Source: source,
+			Location: quoteDataExportSQLString(fmt.Sprintf("s3://%v/%v/dt=__EXPORT_DATE__/__EXPORT_RUN_ID__.parquet",
+				bucketName, p.GetTableKeyPrefix(table.Table))),
+		})
+	}
+
+	return tables
+}
+
+func (p *dataExportImpl) getKeyPrefix() string {
+	if p.cfg.KeyPrefix != "" {
+		return p.cfg.KeyPrefix
+	}
+	return p.cfg.Name
+}
+
+func (p *dataExportImpl) getImageWithTag() string {
+	return p.deps.ImageRepository.GetCloudMetadata(true).ImageName + ":" + p.cfg.Stage.AsCloudStage().GetCloudConfig().Version
+}
+
+func getDataExportPostgresEnvironment(pgURL *url.URL, sslMode string) map[string]string {
+	return map[string]string{
+		"PGHOST":     pgURL.Hostname(),
+		"PGPORT":     pgURL.Port(),
+		"PGUSER":     pgURL.User.Username(),
+		"PGDATABASE": strings.TrimPrefix(pgURL.Path, "/"),
+		"PGSSLMODE":  sslMode,
+	}
+}
+
+func quoteDataExportSQLString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,67 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataExportConfig_MustValidate(t *testing.T) {
+	newConfig := func(tables ...string) *DataExportConfig {
+		cfg := &DataExportConfig{
+			Stage:      &cloudStageImpl{cfg: &CloudStageConfig{Name: "staging"}},
+			Name:       "test",
+			Expression: "cron(0 3 * * ? *)",
+			Cloud: &DataExportConfigCloud{
+				CPU:    256,
+				Memory: 512,
+			},
+		}
+
+		for _, table := range tables {
+			cfg.Tables = append(cfg.Tables, &DataExportConfigTable{Table: table})
+		}
+
+		return cfg
+	}
+
+	require.NotPanics(t, func() { newConfig("public.orders", "public.customers").MustValidate(Cloud) })
+	require.Panics(t, func() { newConfig().MustValidate(Cloud) })
+	require.Panics(t, func() { newConfig("public.orders", "public.orders").MustValidate(Cloud) })
+
+	cfg := newConfig("public.orders")
+	cfg.Expression = "0 3 * * *"
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+
+	cfg = newConfig("public.orders")
+	cfg.KeyPrefix = "exports/"
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+
+	cfg = newConfig("public.orders")
+	cfg.Cloud = nil
+	require.NotPanics(t, func() { cfg.MustValidate(Local) })
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+}
+
+func TestDataExport_GetSQLTemplateDataTables(t *testing.T) {
+	p := &dataExportImpl{
+		cfg: &DataExportConfig{
+			Name:      "test",
+			KeyPrefix: "exports/daily",
+			Tables: []*DataExportConfigTable{
+				{Table: "public.orders"},
+				{Table: "public.customers", Query: "SELECT id, created_at FROM public.customers WHERE region <> 'eu'"},
+			},
+		},
+	}
+
+	tables := p.getSQLTemplateDataTables("bucket")
+	require.Len(t, tables, 2)
+	require.Equal(t, `db."public"."orders"`, tables[0].Source)
+	require.Equal(t, "'s3://bucket/exports/daily/public.orders/dt=__EXPORT_DATE__/__EXPORT_RUN_ID__.parquet'", tables[0].Location)
+	require.Equal(t, "postgres_query('db', 'SELECT id, created_at FROM public.customers WHERE region <> ''eu''')", tables[1].Source)
+	require.Equal(t, "exports/daily/public.customers", p.GetTableKeyPrefix("public.customers"))
+
+	p.cfg.KeyPrefix = ""
+	require.Equal(t, "test/public.orders", p.GetTableKeyPrefix("public.orders"))
+}
@@ -0,0 +1,207 @@
+package cloudz
+
+import (
+	"os"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	gocloudfront "github.com/awslabs/goformation/v6/cloudformation/cloudfront"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// EdgeFunction constants.
+const (
+	EdgeFunctionPluginDisplayName = "EdgeFunction"
+	EdgeFunctionPluginName        = "edge-function"
+	EdgeFunctionRefFunction       = CloudRef("f")
+	EdgeFunctionAttFunctionARN    = CloudAtt("FunctionARN")
+
+	edgeFunctionRuntime     = "cloudfront-js-1.0"
+	edgeFunctionMaxCodeSize = 10 * 1024
+)
+
+var (
+	_ EdgeFunction = &edgeFunctionImpl{}
+	_ Plugin       = &edgeFunctionImpl{}
+)
+
+// EdgeFunctionConfigFunc returns the edge function config for a given Stage.
+type EdgeFunctionConfigFunc func(Stage, *EdgeFunctionDependencies) *EdgeFunctionConfig
+
+// EdgeFunctionEventHookFunc describes an edge function event hook.
+type EdgeFunctionEventHookFunc func(EdgeFunction, Event, string)
+
+// EdgeFunctionConfig describes the edge function config.
+//
+// The edge function is a CloudFront Function: a JavaScript handler (see the "cloudfront-js-1.0" runtime) read from
+// SourceFilePath, published automatically on deploy, and associated with the viewer-request or viewer-response event
+// of a CDN behavior (see CDNConfigBehavior.EdgeFunctions). CloudFront Functions are global, so unlike Lambda@Edge they
+// are deployed with the rest of the stage regardless of its region. Edge functions are not run in local stages.
+type EdgeFunctionConfig struct {
+	Stage          Stage  `validate:"required"`
+	Name           string `validate:"required,resource-name"`
+	SourceFilePath string `validate:"required"`
+	EventHook      EdgeFunctionEventHookFunc
+}
+
+// MustValidate validates the edge function config.
+func (c *EdgeFunctionConfig) MustValidate(_ StageTarget) {
+	vz.MustValidateStruct(c)
+}
+
+// EdgeFunctionDependencies describes the edge function dependencies.
+type EdgeFunctionDependencies struct {
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the edge function dependencies.
+func (d *EdgeFunctionDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// EdgeFunctionCloudMetadata describes the edge function cloud metadata.
+type EdgeFunctionCloudMetadata struct {
+	Exports CloudExports
+	ARN     string
+}
+
+// EdgeFunction describes an edge function.
+type EdgeFunction interface {
+	Plugin
+	GetConfig() *EdgeFunctionConfig
+	GetCloudMetadata(require bool) *EdgeFunctionCloudMetadata
+}
+
+type edgeFunctionImpl struct {
+	cfgFunc       EdgeFunctionConfigFunc
+	deps          *EdgeFunctionDependencies
+	cfg           *EdgeFunctionConfig
+	cloudMetadata *EdgeFunctionCloudMetadata
+}
+
+// NewEdgeFunction initializes a new EdgeFunction.
+func NewEdgeFunction(cfgFunc EdgeFunctionConfigFunc, deps *EdgeFunctionDependencies) EdgeFunction {
+	deps.MustValidate()
+
+	return &edgeFunctionImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*edgeFunctionImpl) GetDisplayName() string {
+	return EdgeFunctionPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *edgeFunctionImpl) GetName() string {
+	return EdgeFunctionPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *edgeFunctionImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *edgeFunctionImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *edgeFunctionImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *edgeFunctionImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(EdgeFunctionPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the EdgeFunction interface.
+func (p *edgeFunctionImpl) GetConfig() *EdgeFunctionConfig {
+	return p.cfg
+}
+
+// GetCloudMetadata implements the EdgeFunction interface.
+func (p *edgeFunctionImpl) GetCloudMetadata(require bool) *EdgeFunctionCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(EdgeFunctionPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *edgeFunctionImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *edgeFunctionImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
+	// nothing to do here
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *edgeFunctionImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	tpl.Resources[EdgeFunctionRefFunction.Ref()] = &gocloudfront.Function{
+		AutoPublish:  boolz.Ptr(true),
+		FunctionCode: stringz.Ptr(string(p.mustReadSource())),
+		FunctionConfig: &gocloudfront.Function_FunctionConfig{
+			Comment: EdgeFunctionRefFunction.Name(p),
+			Runtime: edgeFunctionRuntime,
+		},
+		Name: EdgeFunctionRefFunction.Name(p),
+	}
+	CloudAddExpRef(tpl, p, EdgeFunctionRefFunction)
+	CloudAddExpGetAtt(tpl, p, EdgeFunctionRefFunction, EdgeFunctionAttFunctionARN)
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *edgeFunctionImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+
+	p.cloudMetadata = &EdgeFunctionCloudMetadata{
+		Exports: exports,
+		ARN:     exports.GetAtt(EdgeFunctionRefFunction, EdgeFunctionAttFunctionARN),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *edgeFunctionImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case CloudPreflightEvent:
+		p.cloudPreflightEventHook()
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *edgeFunctionImpl) cloudPreflightEventHook() {
+	source := p.mustReadSource()
+	errorz.Assertf(len(source) > 0, "empty source: %v", errorz.A(p.cfg.SourceFilePath), errorz.Prefix(EdgeFunctionPluginName))
+	errorz.Assertf(len(source) <= edgeFunctionMaxCodeSize, "source exceeds %v bytes: %v",
+		errorz.A(edgeFunctionMaxCodeSize, p.cfg.SourceFilePath), errorz.Prefix(EdgeFunctionPluginName))
+}
+
+func (p *edgeFunctionImpl) mustReadSource() []byte {
+	buf, err := os.ReadFile(p.cfg.SourceFilePath)
+	errorz.MaybeMustWrap(err, errorz.M("filePath", p.cfg.SourceFilePath))
+	return buf
+}
@@ -0,0 +1,407 @@
+package cloudz
+
+import (
+	"fmt"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goefs "github.com/awslabs/goformation/v6/cloudformation/efs"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// EFS constants.
+const (
+	EFSPluginDisplayName = "EFS"
+	EFSPluginName        = "efs"
+	EFSRefFileSystem     = CloudRef("fs")
+	EFSRefMountTargetA   = CloudRef("mt-a")
+	EFSRefMountTargetB   = CloudRef("mt-b")
+	EFSAttARN            = CloudAtt("Arn")
+	EFSAttAccessPointID  = CloudAtt("AccessPointId")
+	EFSAttIPAddress      = CloudAtt("IpAddress")
+)
+
+var (
+	_ EFS    = &efsImpl{}
+	_ Plugin = &efsImpl{}
+)
+
+// EFSConfigFunc returns the EFS config for a given Stage.
+type EFSConfigFunc func(Stage, *EFSDependencies) *EFSConfig
+
+// EFSEventHookFunc describes an EFS event hook.
+type EFSEventHookFunc func(EFS, Event, string)
+
+// EFSConfig describes the EFS config.
+//
+// In the cloud, the file system has a mount target in each private subnet of the Network, and each access point exposes
+// a directory of it (created on first use with the given owner and permissions) to the functions and container services
+// mounting it (see EFSMount). Locally, each access point is a named Docker volume, persisted across runs.
+type EFSConfig struct {
+	Stage        Stage                   `validate:"required"`
+	Name         string                  `validate:"required,resource-name"`
+	AccessPoints []*EFSConfigAccessPoint `validate:"required,min=1,dive,required"`
+	Cloud        *EFSConfigCloud
+	EventHook    EFSEventHookFunc
+}
+
+// MustValidate validates the EFS config.
+func (c *EFSConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing EFSConfig.Cloud")
+
+	names := map[string]struct{}{}
+	for _, accessPoint := range c.AccessPoints {
+		_, ok := names[accessPoint.Name]
+		errorz.Assertf(!ok, "duplicate EFSConfigAccessPoint.Name: %v", errorz.A(accessPoint.Name))
+		names[accessPoint.Name] = struct{}{}
+	}
+}
+
+// GetAccessPoint returns the access point with the given name, or nil if not found.
+func (c *EFSConfig) GetAccessPoint(name string) *EFSConfigAccessPoint {
+	for _, accessPoint := range c.AccessPoints {
+		if accessPoint.Name == name {
+			return accessPoint
+		}
+	}
+	return nil
+}
+
+// EFSConfigAccessPoint describes part of the EFS config.
+// Clients connecting through the access point act as UID:GID, and are confined to Path.
+type EFSConfigAccessPoint struct {
+	Name        string `validate:"required,resource-name"`
+	Path        string `validate:"required,startswith=/"`
+	UID         uint32
+	GID         uint32
+	Permissions string `validate:"required,numeric,min=3,max=4"`
+}
+
+// GetCloudRef returns the cloud ref for the access point.
+func (a *EFSConfigAccessPoint) GetCloudRef() CloudRef {
+	return CloudRef("ap-" + a.Name)
+}
+
+// EFSConfigCloud describes part of the EFS config.
+// If TransitionToIAAfterDays is set, files not accessed for the given number of days are moved to Infrequent Access
+// storage. Automatic backups are always enabled in production stages.
+type EFSConfigCloud struct {
+	ThroughputMode          string  `validate:"omitempty,oneof=bursting elastic"`
+	TransitionToIAAfterDays *uint16 `validate:"omitempty,oneof=1 7 14 30 60 90"`
+}
+
+// EFSDependencies describes the EFS dependencies.
+type EFSDependencies struct {
+	Network           Network `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the EFS dependencies.
+func (d *EFSDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// EFSLocalMetadata describes the EFS local metadata.
+type EFSLocalMetadata struct {
+	VolumeNames map[string]string
+}
+
+// EFSCloudMetadata describes the EFS cloud metadata.
+type EFSCloudMetadata struct {
+	Exports CloudExports
+}
+
+// GetFileSystemID returns the file system ID.
+func (m *EFSCloudMetadata) GetFileSystemID() string {
+	return m.Exports.GetRef(EFSRefFileSystem)
+}
+
+// GetFileSystemARN returns the file system ARN.
+func (m *EFSCloudMetadata) GetFileSystemARN() string {
+	return m.Exports.GetAtt(EFSRefFileSystem, EFSAttARN)
+}
+
+// GetAccessPointID returns the ID of the access point with the given name.
+func (m *EFSCloudMetadata) GetAccessPointID(name string) string {
+	return m.Exports.GetAtt((&EFSConfigAccessPoint{Name: name}).GetCloudRef(), EFSAttAccessPointID)
+}
+
+// GetAccessPointARN returns the ARN of the access point with the given name.
+func (m *EFSCloudMetadata) GetAccessPointARN(name string) string {
+	return m.Exports.GetAtt((&EFSConfigAccessPoint{Name: name}).GetCloudRef(), EFSAttARN)
+}
+
+// EFSMount describes a mount of an EFS access point, see FunctionConfig and ContainerServiceConfig.
+type EFSMount struct {
+	AccessPoint string `validate:"required"`
+	Path        string `validate:"required,startswith=/"`
+	IsReadOnly  bool
+}
+
+// EFS describes an EFS file system.
+type EFS interface {
+	Plugin
+	GetConfig() *EFSConfig
+	GetDependencies() *EFSDependencies
+	GetLocalMetadata() *EFSLocalMetadata
+	GetCloudMetadata(require bool) *EFSCloudMetadata
+}
+
+type efsImpl struct {
+	cfgFunc       EFSConfigFunc
+	deps          *EFSDependencies
+	cfg           *EFSConfig
+	localMetadata *EFSLocalMetadata
+	cloudMetadata *EFSCloudMetadata
+}
+
+// NewEFS initializes a new EFS.
+func NewEFS(cfgFunc EFSConfigFunc, deps *EFSDependencies) EFS {
+	deps.MustValidate()
+
+	return &efsImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*efsImpl) GetDisplayName() string {
+	return EFSPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *efsImpl) GetName() string {
+	return EFSPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *efsImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *efsImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Network: {},
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *efsImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *efsImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(EFSPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the EFS interface.
+func (p *efsImpl) GetConfig() *EFSConfig {
+	return p.cfg
+}
+
+// GetDependencies implements the EFS interface.
+func (p *efsImpl) GetDependencies() *EFSDependencies {
+	return p.deps
+}
+
+// GetLocalMetadata implements the EFS interface.
+func (p *efsImpl) GetLocalMetadata() *EFSLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(EFSPluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the EFS interface.
+func (p *efsImpl) GetCloudMetadata(require bool) *EFSCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(EFSPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *efsImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *efsImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
+	p.localMetadata = &EFSLocalMetadata{
+		VolumeNames: map[string]string{},
+	}
+
+	for _, accessPoint := range p.cfg.AccessPoints {
+		volumeName := fmt.Sprintf("%v-%v", LocalGetContainerName(p), accessPoint.Name)
+		p.localMetadata.VolumeNames[accessPoint.Name] = volumeName
+		tpl.Volumes[volumeName] = dctypes.VolumeConfig{
+			Name: volumeName,
+		}
+	}
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *efsImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+	network := p.deps.Network.GetCloudMetadata(true)
+
+	fileSystem := &goefs.FileSystem{
+		Encrypted: boolz.Ptr(true),
+		FileSystemTags: &[]goefs.FileSystem_ElasticFileSystemTag{
+			{
+				Key:   "Name",
+				Value: EFSRefFileSystem.Name(p),
+			},
+		},
+		PerformanceMode: stringz.Ptr("generalPurpose"),
+	}
+
+	if p.cfg.Cloud.ThroughputMode != "" {
+		fileSystem.ThroughputMode = stringz.Ptr(p.cfg.Cloud.ThroughputMode)
+	}
+
+	if p.cfg.Cloud.TransitionToIAAfterDays != nil {
+		fileSystem.LifecyclePolicies = &[]goefs.FileSystem_LifecyclePolicy{
+			{
+				TransitionToIA: stringz.Ptr(fmt.Sprintf("AFTER_%v_DAYS", *p.cfg.Cloud.TransitionToIAAfterDays)),
+			},
+			{
+				TransitionToPrimaryStorageClass: stringz.Ptr("AFTER_1_ACCESS"),
+			},
+		}
+	}
+
+	if p.cfg.Stage.GetMode().IsProduction() {
+		fileSystem.BackupPolicy = &goefs.FileSystem_BackupPolicy{
+			Status: "ENABLED",
+		}
+	}
+
+	tpl.Resources[EFSRefFileSystem.Ref()] = fileSystem
+	CloudAddExpRef(tpl, p, EFSRefFileSystem)
+	CloudAddExpGetAtt(tpl, p, EFSRefFileSystem, EFSAttARN)
+
+	for ref, subnetRef := range map[CloudRef]CloudRef{
+		EFSRefMountTargetA: NetworkRefSubnetPrivateA,
+		EFSRefMountTargetB: NetworkRefSubnetPrivateB,
+	} {
+		tpl.Resources[ref.Ref()] = &goefs.MountTarget{
+			FileSystemId: gocf.Ref(EFSRefFileSystem.Ref()),
+			SecurityGroups: []string{
+				network.Exports.GetRef(NetworkRefSecurityGroup),
+			},
+			SubnetId: network.Exports.GetRef(subnetRef),
+		}
+		CloudAddExpRef(tpl, p, ref)
+		CloudAddExpGetAtt(tpl, p, ref, EFSAttIPAddress)
+	}
+
+	for _, accessPoint := range p.cfg.AccessPoints {
+		ref := accessPoint.GetCloudRef()
+
+		tpl.Resources[ref.Ref()] = &goefs.AccessPoint{
+			AccessPointTags: &[]goefs.AccessPoint_AccessPointTag{
+				{
+					Key:   stringz.Ptr("Name"),
+					Value: stringz.Ptr(ref.Name(p)),
+				},
+			},
+			FileSystemId: gocf.Ref(EFSRefFileSystem.Ref()),
+			PosixUser: &goefs.AccessPoint_PosixUser{
+				Gid: fmt.Sprintf("%v", accessPoint.GID),
+				Uid: fmt.Sprintf("%v", accessPoint.UID),
+			},
+			RootDirectory: &goefs.AccessPoint_RootDirectory{
+				CreationInfo: &goefs.AccessPoint_CreationInfo{
+					OwnerGid:    fmt.Sprintf("%v", accessPoint.GID),
+					OwnerUid:    fmt.Sprintf("%v", accessPoint.UID),
+					Permissions: accessPoint.Permissions,
+				},
+				Path: stringz.Ptr(accessPoint.Path),
+			},
+		}
+		CloudAddExpRef(tpl, p, ref)
+		CloudAddExpGetAtt(tpl, p, ref, EFSAttAccessPointID)
+		CloudAddExpGetAtt(tpl, p, ref, EFSAttARN)
+	}
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *efsImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &EFSCloudMetadata{
+		Exports: NewCloudExports(stack),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *efsImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+// efsMustValidateMounts checks that the mounts refer to access points of the given EFS.
+func efsMustValidateMounts(p Plugin, efs EFS, mounts []*EFSMount) {
+	if len(mounts) == 0 {
+		return
+	}
+
+	errorz.Assertf(efs != nil, "mounts require an EFS dependency", errorz.Prefix(p.GetName()))
+
+	for _, mount := range mounts {
+		vz.MustValidateStruct(mount)
+		errorz.Assertf(efs.GetConfig().GetAccessPoint(mount.AccessPoint) != nil,
+			"unknown EFS access point: %v", errorz.A(mount.AccessPoint), errorz.Prefix(p.GetName()))
+	}
+}
+
+// LocalGetEFSServiceVolumeConfigs returns the service volume configs for mounting the given EFS access points locally.
+func LocalGetEFSServiceVolumeConfigs(efs EFS, mounts []*EFSMount) []dctypes.ServiceVolumeConfig {
+	volumes := make([]dctypes.ServiceVolumeConfig, 0, len(mounts))
+
+	for _, mount := range mounts {
+		volumes = append(volumes, dctypes.ServiceVolumeConfig{
+			Type:     "volume",
+			Source:   efs.GetLocalMetadata().VolumeNames[mount.AccessPoint],
+			Target:   mount.Path,
+			ReadOnly: mount.IsReadOnly,
+		})
+	}
+
+	return volumes
+}
+
+// CloudGetEFSClientRolePolicy returns a role policy allowing to mount the given EFS access points.
+func CloudGetEFSClientRolePolicy(p Plugin, efs EFS, mounts []*EFSMount) goiam.Role_Policy {
+	actions := []string{"elasticfilesystem:ClientMount"}
+
+	for _, mount := range mounts {
+		if !mount.IsReadOnly {
+			actions = append(actions, "elasticfilesystem:ClientWrite")
+			break
+		}
+	}
+
+	return goiam.Role_Policy{
+		PolicyName: p.GetName() + "-efs",
+		PolicyDocument: NewPolicyDocument(
+			NewPolicyStatement().
+				AddActions(actions...).
+				AddResources(efs.GetCloudMetadata(true).GetFileSystemARN())),
+	}
+}
@@ -0,0 +1,369 @@
+package cloudz
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"time"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+	"github.com/ibrt/golang-cloud/opz"
+)
+
+// ErrorTracking constants.
+const (
+	ErrorTrackingPluginDisplayName = "ErrorTracking"
+	ErrorTrackingPluginName        = "error-tracking"
+
+	// ErrorTrackingEnvDSN is the environment variable holding the DSN, as read by the Sentry SDKs.
+	ErrorTrackingEnvDSN = "SENTRY_DSN"
+
+	// ErrorTrackingEnvEnvironment is the environment variable holding the stage name, as read by the Sentry SDKs.
+	ErrorTrackingEnvEnvironment = "SENTRY_ENVIRONMENT"
+
+	// ErrorTrackingAdminEmail is the email address of the local GlitchTip admin user.
+	ErrorTrackingAdminEmail = "admin@example.com"
+
+	errorTrackingPort                  = 8000
+	errorTrackingPostgresPort          = 5432
+	errorTrackingRedisPort             = 6379
+	errorTrackingLocalOrganization     = "local"
+	errorTrackingLocalProjectID        = 1
+	errorTrackingLocalPublicKey        = "00000000000040008000000000000001"
+	errorTrackingLocalBootstrapTimeout = 2 * time.Minute
+)
+
+var (
+	_ ErrorTracking = &errorTrackingImpl{}
+	_ Plugin        = &errorTrackingImpl{}
+)
+
+// ErrorTrackingConfigFunc returns the error tracking config for a given Stage.
+type ErrorTrackingConfigFunc func(Stage, *ErrorTrackingDependencies) *ErrorTrackingConfig
+
+// ErrorTrackingEventHookFunc describes an error tracking event hook.
+type ErrorTrackingEventHookFunc func(ErrorTracking, Event, string)
+
+// ErrorTrackingConfig describes the error tracking config.
+//
+// Locally, it runs GlitchTip (a Sentry-compatible error tracker), bootstrapped with an admin user and a project with a
+// fixed DSN. In the cloud, it has no resources: the DSN of an externally managed Sentry-compatible project is
+// provided instead. Either way, the DSN is injected in the environment of the Function and Hasura plugins that depend on
+// it (see ErrorTracking.GetEnvironment). Note that DSNs only allow submitting events, and are meant to be public.
+type ErrorTrackingConfig struct {
+	Stage     Stage `validate:"required"`
+	Local     *ErrorTrackingConfigLocal
+	Cloud     *ErrorTrackingConfigCloud
+	EventHook ErrorTrackingEventHookFunc
+}
+
+// MustValidate validates the error tracking config.
+func (c *ErrorTrackingConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing ErrorTrackingConfig.Local")
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing ErrorTrackingConfig.Cloud")
+}
+
+// ErrorTrackingConfigLocal describes part of the error tracking config.
+type ErrorTrackingConfigLocal struct {
+	ExternalPort uint16 `validate:"required"`
+}
+
+// ErrorTrackingConfigCloud describes part of the error tracking config.
+type ErrorTrackingConfigCloud struct {
+	DSN string `validate:"required,url"`
+}
+
+// ErrorTrackingDependencies describes the error tracking dependencies.
+type ErrorTrackingDependencies struct {
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the error tracking dependencies.
+func (d *ErrorTrackingDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// ErrorTrackingLocalMetadata describes the error tracking local metadata.
+type ErrorTrackingLocalMetadata struct {
+	ContainerName string
+	AdminEmail    string
+	AdminPassword string `metadata:"secret"`
+	ExternalURL   *url.URL
+	InternalURL   *url.URL
+	ExternalDSN   string
+	InternalDSN   string
+}
+
+// ErrorTrackingCloudMetadata describes the error tracking cloud metadata.
+type ErrorTrackingCloudMetadata struct {
+	DSN string
+}
+
+// ErrorTracking describes a Sentry-compatible error tracker.
+type ErrorTracking interface {
+	Plugin
+	GetConfig() *ErrorTrackingConfig
+	GetLocalMetadata() *ErrorTrackingLocalMetadata
+	GetCloudMetadata(require bool) *ErrorTrackingCloudMetadata
+	GetEnvironment() map[string]string
+}
+
+type errorTrackingImpl struct {
+	cfgFunc       ErrorTrackingConfigFunc
+	deps          *ErrorTrackingDependencies
+	cfg           *ErrorTrackingConfig
+	localMetadata *ErrorTrackingLocalMetadata
+	cloudMetadata *ErrorTrackingCloudMetadata
+}
+
+// NewErrorTracking initializes a new ErrorTracking.
+func NewErrorTracking(cfgFunc ErrorTrackingConfigFunc, deps *ErrorTrackingDependencies) ErrorTracking {
+	deps.MustValidate()
+
+	return &errorTrackingImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*errorTrackingImpl) GetDisplayName() string {
+	return ErrorTrackingPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *errorTrackingImpl) GetName() string {
+	return ErrorTrackingPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *errorTrackingImpl) GetInstanceName() *string {
+	return nil
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *errorTrackingImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{}
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *errorTrackingImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+
+	if stage.GetTarget() == Cloud {
+		p.cloudMetadata = &ErrorTrackingCloudMetadata{
+			DSN: p.cfg.Cloud.DSN,
+		}
+	}
+}
+
+// GetStage implements the Plugin interface.
+func (p *errorTrackingImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(ErrorTrackingPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the ErrorTracking interface.
+func (p *errorTrackingImpl) GetConfig() *ErrorTrackingConfig {
+	return p.cfg
+}
+
+// GetLocalMetadata implements the ErrorTracking interface.
+func (p *errorTrackingImpl) GetLocalMetadata() *ErrorTrackingLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(ErrorTrackingPluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the ErrorTracking interface.
+func (p *errorTrackingImpl) GetCloudMetadata(require bool) *ErrorTrackingCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(ErrorTrackingPluginName))
+	return p.cloudMetadata
+}
+
+// GetEnvironment implements the ErrorTracking interface.
+// It returns the environment variables configuring the Sentry SDKs for the target of the stage.
+func (p *errorTrackingImpl) GetEnvironment() map[string]string {
+	dsn := ""
+
+	switch p.cfg.Stage.GetTarget() {
+	case Local:
+		dsn = p.GetLocalMetadata().InternalDSN
+	case Cloud:
+		dsn = p.GetCloudMetadata(true).DSN
+	}
+
+	return map[string]string{
+		ErrorTrackingEnvDSN:         dsn,
+		ErrorTrackingEnvEnvironment: p.cfg.Stage.GetName(),
+	}
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *errorTrackingImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *errorTrackingImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
+	containerName := LocalGetContainerName(p)
+	workerContainerName := LocalGetContainerName(p, "worker")
+	postgresContainerName := LocalGetContainerName(p, "postgres")
+	redisContainerName := LocalGetContainerName(p, "redis")
+	versions := p.cfg.Stage.GetConfig().App.GetConfig().GetVersions()
+
+	p.localMetadata = &ErrorTrackingLocalMetadata{
+		ContainerName: containerName,
+		AdminEmail:    ErrorTrackingAdminEmail,
+		AdminPassword: LocalPassword,
+		ExternalURL:   urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.ExternalPort)),
+		InternalURL:   urlz.MustParse(fmt.Sprintf("http://%v:%v", containerName, errorTrackingPort)),
+		ExternalDSN:   getErrorTrackingLocalDSN(fmt.Sprintf("localhost:%v", p.cfg.Local.ExternalPort)),
+		InternalDSN:   getErrorTrackingLocalDSN(fmt.Sprintf("%v:%v", containerName, errorTrackingPort)),
+	}
+
+	environment := map[string]*string{
+		"DATABASE_URL":       stringz.Ptr(fmt.Sprintf("postgres://postgres:%v@%v:%v/postgres", LocalPassword, postgresContainerName, errorTrackingPostgresPort)),
+		"DEFAULT_FROM_EMAIL": stringz.Ptr("glitchtip@example.com"),
+		"EMAIL_URL":          stringz.Ptr("consolemail://"),
+		"GLITCHTIP_DOMAIN":   stringz.Ptr(p.localMetadata.ExternalURL.String()),
+		"PORT":               stringz.Ptr(fmt.Sprintf("%v", errorTrackingPort)),
+		"REDIS_URL":          stringz.Ptr(fmt.Sprintf("redis://%v:%v/0", redisContainerName, errorTrackingRedisPort)),
+		"SECRET_KEY":         stringz.Ptr(LocalSecret),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          postgresContainerName,
+		ContainerName: postgresContainerName,
+		Environment: map[string]*string{
+			"POSTGRES_PASSWORD": stringz.Ptr(LocalPassword),
+		},
+		Image:    LocalGetImage(p, "postgres:"+versions.Postgres),
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Restart:  "unless-stopped",
+	})
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          redisContainerName,
+		ContainerName: redisContainerName,
+		Image:         LocalGetImage(p, "redis:"+versions.Redis),
+		Networks:      p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Restart:       "unless-stopped",
+	})
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          containerName,
+		ContainerName: containerName,
+		DependsOn: []string{
+			postgresContainerName,
+			redisContainerName,
+		},
+		Environment: environment,
+		Image:       LocalGetImage(p, "glitchtip/glitchtip:v"+versions.GlitchTip),
+		Networks:    p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    errorTrackingPort,
+				Published: uint32(p.cfg.Local.ExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+	})
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          workerContainerName,
+		ContainerName: workerContainerName,
+		Command:       dctypes.ShellCommand{"./bin/run-celery-with-beat.sh"},
+		DependsOn: []string{
+			postgresContainerName,
+			redisContainerName,
+		},
+		Environment: environment,
+		Image:       LocalGetImage(p, "glitchtip/glitchtip:v"+versions.GlitchTip),
+		Networks:    p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Restart:     "unless-stopped",
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (*errorTrackingImpl) GetCloudTemplate(_ string) *gocf.Template {
+	return nil
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (*errorTrackingImpl) UpdateCloudMetadata(_ *awscft.Stack) {
+	// intentionally empty: the cloud metadata is set in Configure
+}
+
+// EventHook implements the Plugin interface.
+func (p *errorTrackingImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case LocalAfterCreateEvent:
+		p.localAfterCreateEventHook()
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *errorTrackingImpl) localAfterCreateEventHook() {
+	deadline := time.Now().Add(errorTrackingLocalBootstrapTimeout)
+
+	// The database might not be ready yet: retry the (idempotent) migrations until they succeed.
+	for {
+		err := p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker").
+			AddParams("exec", p.localMetadata.ContainerName, "./manage.py", "migrate", "--noinput").
+			Run()
+		if err == nil {
+			break
+		}
+
+		errorz.Assertf(time.Now().Before(deadline), "timed out waiting for migrations", errorz.Prefix(ErrorTrackingPluginName))
+		opz.Sleep(p.cfg.Stage.GetConfig().App.GetOperations().GetContext(), 5*time.Second)
+	}
+
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker").
+		AddParams("exec", "-i", p.localMetadata.ContainerName, "./manage.py", "shell").
+		SetStdin(bytes.NewReader(p.getLocalBootstrapScript())).
+		MustRun()
+}
+
+func (p *errorTrackingImpl) getLocalBootstrapScript() []byte {
+	return templatez.MustParseAndExecuteText(
+		assets.ErrorTrackingBootstrapPYTemplateAsset,
+		assets.ErrorTrackingBootstrapPYTemplateData{
+			AdminEmail:       ErrorTrackingAdminEmail,
+			AdminPassword:    LocalPassword,
+			OrganizationSlug: errorTrackingLocalOrganization,
+			ProjectSlug:      p.cfg.Stage.GetConfig().App.GetConfig().Name,
+			ProjectID:        errorTrackingLocalProjectID,
+			PublicKey:        errorTrackingLocalPublicKey,
+		})
+}
+
+func getErrorTrackingLocalDSN(host string) string {
+	return fmt.Sprintf("http://%v@%v/%v", errorTrackingLocalPublicKey, host, errorTrackingLocalProjectID)
+}
+
+// getErrorTrackingEnvironment returns the environment variables of the given ErrorTracking, or an empty map if nil.
+func getErrorTrackingEnvironment(errorTracking ErrorTracking) map[string]string {
+	if errorTracking == nil {
+		return map[string]string{}
+	}
+	return errorTracking.GetEnvironment()
+}
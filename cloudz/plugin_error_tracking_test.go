@@ -0,0 +1,30 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetErrorTrackingLocalDSN(t *testing.T) {
+	require.Equal(t,
+		"http://00000000000040008000000000000001@localhost:8000/1",
+		getErrorTrackingLocalDSN("localhost:8000"))
+}
+
+func TestGetErrorTrackingEnvironment(t *testing.T) {
+	require.Equal(t, map[string]string{}, getErrorTrackingEnvironment(nil))
+
+	p := &errorTrackingImpl{
+		cfg: &ErrorTrackingConfig{
+			Stage: &cloudStageImpl{cfg: &CloudStageConfig{Name: "staging"}},
+			Cloud: &ErrorTrackingConfigCloud{DSN: "https://key@sentry.example.com/42"},
+		},
+		cloudMetadata: &ErrorTrackingCloudMetadata{DSN: "https://key@sentry.example.com/42"},
+	}
+
+	require.Equal(t, map[string]string{
+		ErrorTrackingEnvDSN:         "https://key@sentry.example.com/42",
+		ErrorTrackingEnvEnvironment: "staging",
+	}, getErrorTrackingEnvironment(p))
+}
@@ -0,0 +1,820 @@
+package cloudz
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	golambda "github.com/awslabs/goformation/v6/cloudformation/lambda"
+	gosns "github.com/awslabs/goformation/v6/cloudformation/sns"
+	gosqs "github.com/awslabs/goformation/v6/cloudformation/sqs"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/jsonz"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+)
+
+// EventBus constants.
+const (
+	EventBusPluginDisplayName = "EventBus"
+	EventBusPluginName        = "event-bus"
+	EventBusAttARN            = CloudAtt("Arn")
+
+	eventBusSimulatorPort                  = 8080
+	eventBusElasticMQPort                  = 9324
+	eventBusLocalAccountID                 = "000000000000"
+	eventBusDefaultVisibilityTimeout       = 30
+	eventBusDefaultBatchSize               = 10
+	eventBusDeadLetterQueueRetentionPeriod = 14 * 24 * 60 * 60
+	eventBusFIFOSuffix                     = ".fifo"
+)
+
+var (
+	_ EventBus = &eventBusImpl{}
+	_ Plugin   = &eventBusImpl{}
+)
+
+// EventBusConfigFunc returns the event bus config for a given Stage.
+type EventBusConfigFunc func(Stage, *EventBusDependencies) *EventBusConfig
+
+// EventBusEventHookFunc describes an event bus event hook.
+type EventBusEventHookFunc func(EventBus, Event, string)
+
+// EventBusConfig describes the event bus config.
+//
+// Each topic fans out the published messages to its routes, i.e. to queues (declared in Queues) or directly to functions
+// (declared in EventBusDependencies.Functions, referenced by instance name). Queues can have a Consumer function, which
+// is invoked with batches of messages through an event source mapping. The Publishers functions are allowed to publish
+// to all topics.
+//
+// Since the functions are deployed before the event bus, they cannot reference its metadata in their config: they
+// should read the topic ARNs and queue URLs at runtime (e.g. from SSM parameters, see CloudStageSSMExportConfig).
+type EventBusConfig struct {
+	Stage      Stage                  `validate:"required"`
+	Name       string                 `validate:"required,resource-name"`
+	Topics     []*EventBusConfigTopic `validate:"required,min=1,dive,required"`
+	Queues     []*EventBusConfigQueue `validate:"dive,required"`
+	Publishers []string               `validate:"unique,dive,required"`
+	Local      *EventBusConfigLocal
+	EventHook  EventBusEventHookFunc
+}
+
+// MustValidate validates the event bus config.
+func (c *EventBusConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing EventBusConfig.Local", errorz.Prefix(EventBusPluginName))
+
+	queues := map[string]*EventBusConfigQueue{}
+	for _, queue := range c.Queues {
+		_, ok := queues[queue.Name]
+		errorz.Assertf(!ok, "duplicate queue: %v", errorz.A(queue.Name), errorz.Prefix(EventBusPluginName))
+		errorz.Assertf(queue.FIFO || !queue.ContentBasedDeduplication,
+			"EventBusConfigQueue.ContentBasedDeduplication requires FIFO", errorz.Prefix(EventBusPluginName))
+		queues[queue.Name] = queue
+	}
+
+	topics := map[string]struct{}{}
+	for _, topic := range c.Topics {
+		_, ok := topics[topic.Name]
+		errorz.Assertf(!ok, "duplicate topic: %v", errorz.A(topic.Name), errorz.Prefix(EventBusPluginName))
+		errorz.Assertf(topic.FIFO || !topic.ContentBasedDeduplication,
+			"EventBusConfigTopic.ContentBasedDeduplication requires FIFO", errorz.Prefix(EventBusPluginName))
+		topics[topic.Name] = struct{}{}
+
+		targets := map[string]struct{}{}
+
+		for _, route := range topic.Routes {
+			errorz.Assertf((route.Queue == "") != (route.Function == ""),
+				"exactly one of EventBusConfigRoute.Queue, Function must be set", errorz.Prefix(EventBusPluginName))
+			errorz.Assertf(route.Function == "" || !route.RawMessageDelivery,
+				"EventBusConfigRoute.RawMessageDelivery is only supported for queues", errorz.Prefix(EventBusPluginName))
+			errorz.Assertf(!topic.FIFO || route.Function == "",
+				"FIFO topics can only route to queues", errorz.Prefix(EventBusPluginName))
+
+			if route.Queue != "" {
+				queue, ok := queues[route.Queue]
+				errorz.Assertf(ok, "unknown queue: %v", errorz.A(route.Queue), errorz.Prefix(EventBusPluginName))
+				errorz.Assertf(queue.FIFO == topic.FIFO, "FIFO topics can only route to FIFO queues, and vice versa: %v -> %v",
+					errorz.A(topic.Name, queue.Name), errorz.Prefix(EventBusPluginName))
+			}
+
+			_, ok := targets[route.getTarget()]
+			errorz.Assertf(!ok, "duplicate route: %v -> %v", errorz.A(topic.Name, route.getTarget()), errorz.Prefix(EventBusPluginName))
+			targets[route.getTarget()] = struct{}{}
+		}
+	}
+}
+
+// EventBusConfigTopic describes part of the event bus config. FIFO topics preserve the order of the messages within a
+// message group and deduplicate them (by MessageDeduplicationId, or by content if ContentBasedDeduplication is set): they
+// can only route to FIFO queues, and publishers must set a MessageGroupId.
+type EventBusConfigTopic struct {
+	Name                      string                 `validate:"required,resource-name"`
+	Routes                    []*EventBusConfigRoute `validate:"required,min=1,dive,required"`
+	FIFO                      bool
+	ContentBasedDeduplication bool
+}
+
+func (t *EventBusConfigTopic) getRef() CloudRef {
+	return CloudRef("t-" + t.Name)
+}
+
+func (t *EventBusConfigTopic) getName(p Plugin) string {
+	return getEventBusResourceName(p, t.getRef(), t.FIFO)
+}
+
+// EventBusConfigRoute describes part of the event bus config. Exactly one of Queue and Function must be set. The
+// FilterPolicy, if set, uses the SNS filter policy syntax, and is evaluated against the message attributes (locally,
+// only exact values, "exists", "prefix", and "anything-but" are supported). RawMessageDelivery delivers the message
+// as-is to the queue, instead of wrapped in the SNS notification JSON.
+type EventBusConfigRoute struct {
+	Queue              string
+	Function           string
+	FilterPolicy       map[string]interface{}
+	RawMessageDelivery bool
+}
+
+func (r *EventBusConfigRoute) getTarget() string {
+	if r.Queue != "" {
+		return "q-" + r.Queue
+	}
+	return "f-" + r.Function
+}
+
+func (r *EventBusConfigRoute) getSubscriptionRef(topic *EventBusConfigTopic) CloudRef {
+	return CloudRef(fmt.Sprintf("s-%v-%v", topic.Name, r.getTarget()))
+}
+
+func (r *EventBusConfigRoute) getPermissionRef(topic *EventBusConfigTopic) CloudRef {
+	return CloudRef(fmt.Sprintf("p-%v-%v", topic.Name, r.getTarget()))
+}
+
+// EventBusConfigQueue describes part of the event bus config. If MaxReceiveCount is set, messages that fail to be
+// processed that many times are moved to a dead-letter queue. The VisibilityTimeoutSeconds defaults to 30 and must be
+// at least the timeout of the Consumer function, if any. BatchSize defaults to 10. FIFO queues (and their dead-letter
+// queues) preserve the order of the messages within a message group, and the Consumer function receives them in order.
+type EventBusConfigQueue struct {
+	Name                      string `validate:"required,resource-name"`
+	VisibilityTimeoutSeconds  int    `validate:"omitempty,min=1,max=43200"`
+	MaxReceiveCount           int    `validate:"omitempty,min=1,max=1000"`
+	Consumer                  string
+	BatchSize                 int `validate:"omitempty,min=1,max=10"`
+	FIFO                      bool
+	ContentBasedDeduplication bool
+}
+
+func (q *EventBusConfigQueue) getRef() CloudRef {
+	return CloudRef("q-" + q.Name)
+}
+
+func (q *EventBusConfigQueue) getName(p Plugin) string {
+	return getEventBusResourceName(p, q.getRef(), q.FIFO)
+}
+
+func (q *EventBusConfigQueue) getDeadLetterQueueRef() CloudRef {
+	return CloudRef("dlq-" + q.Name)
+}
+
+func (q *EventBusConfigQueue) getDeadLetterQueueName(p Plugin) string {
+	return getEventBusResourceName(p, q.getDeadLetterQueueRef(), q.FIFO)
+}
+
+func (q *EventBusConfigQueue) getPolicyRef() CloudRef {
+	return CloudRef("qp-" + q.Name)
+}
+
+func (q *EventBusConfigQueue) getEventSourceMappingRef() CloudRef {
+	return CloudRef("esm-" + q.Name)
+}
+
+func (q *EventBusConfigQueue) getVisibilityTimeoutSeconds() int {
+	if q.VisibilityTimeoutSeconds > 0 {
+		return q.VisibilityTimeoutSeconds
+	}
+	return eventBusDefaultVisibilityTimeout
+}
+
+func (q *EventBusConfigQueue) getBatchSize() int {
+	if q.BatchSize > 0 {
+		return q.BatchSize
+	}
+	return eventBusDefaultBatchSize
+}
+
+// EventBusConfigLocal describes part of the event bus config.
+// Locally, the topics are served by a simulator (which also invokes the consumers), and the queues by ElasticMQ.
+type EventBusConfigLocal struct {
+	SNSExternalPort uint16 `validate:"required"`
+	SQSExternalPort uint16 `validate:"required"`
+}
+
+// EventBusDependencies describes the event bus dependencies.
+type EventBusDependencies struct {
+	Functions         []Function `validate:"dive,required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the event bus dependencies.
+func (d *EventBusDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// EventBusLocalMetadata describes the event bus local metadata. Topics and queues are keyed by name.
+type EventBusLocalMetadata struct {
+	ContainerName               string
+	SNSExternalEndpointURL      *url.URL
+	SNSInternalEndpointURL      *url.URL
+	SQSExternalEndpointURL      *url.URL
+	SQSInternalEndpointURL      *url.URL
+	TopicARNs                   map[string]string
+	QueueExternalURLs           map[string]string
+	QueueInternalURLs           map[string]string
+	DeadLetterQueueExternalURLs map[string]string
+	DeadLetterQueueInternalURLs map[string]string
+}
+
+// EventBusCloudMetadata describes the event bus cloud metadata. Topics and queues are keyed by name.
+type EventBusCloudMetadata struct {
+	Exports             CloudExports
+	TopicARNs           map[string]string
+	QueueURLs           map[string]string
+	DeadLetterQueueURLs map[string]string
+}
+
+// EventBus describes a set of SNS topics fanning out to SQS queues and Lambda functions.
+type EventBus interface {
+	Plugin
+	GetConfig() *EventBusConfig
+	GetLocalMetadata() *EventBusLocalMetadata
+	GetCloudMetadata(require bool) *EventBusCloudMetadata
+}
+
+type eventBusImpl struct {
+	cfgFunc       EventBusConfigFunc
+	deps          *EventBusDependencies
+	cfg           *EventBusConfig
+	localMetadata *EventBusLocalMetadata
+	cloudMetadata *EventBusCloudMetadata
+}
+
+// NewEventBus initializes a new EventBus.
+func NewEventBus(cfgFunc EventBusConfigFunc, deps *EventBusDependencies) EventBus {
+	deps.MustValidate()
+
+	return &eventBusImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*eventBusImpl) GetDisplayName() string {
+	return EventBusPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *eventBusImpl) GetName() string {
+	return EventBusPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *eventBusImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *eventBusImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{}
+
+	for _, function := range p.deps.Functions {
+		dependenciesMap[function] = struct{}{}
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *eventBusImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+
+	for _, name := range p.getFunctionNames() {
+		p.mustGetFunction(name)
+	}
+}
+
+// GetStage implements the Plugin interface.
+func (p *eventBusImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(EventBusPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the EventBus interface.
+func (p *eventBusImpl) GetConfig() *EventBusConfig {
+	return p.cfg
+}
+
+// GetLocalMetadata implements the EventBus interface.
+func (p *eventBusImpl) GetLocalMetadata() *EventBusLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(EventBusPluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the EventBus interface.
+func (p *eventBusImpl) GetCloudMetadata(require bool) *EventBusCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(EventBusPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *eventBusImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *eventBusImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath string) {
+	containerName := LocalGetContainerName(p)
+	elasticMQContainerName := LocalGetContainerName(p, "elasticmq")
+
+	p.localMetadata = &EventBusLocalMetadata{
+		ContainerName:               containerName,
+		SNSExternalEndpointURL:      urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.SNSExternalPort)),
+		SNSInternalEndpointURL:      urlz.MustParse(fmt.Sprintf("http://%v:%v", containerName, eventBusSimulatorPort)),
+		SQSExternalEndpointURL:      urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.SQSExternalPort)),
+		SQSInternalEndpointURL:      urlz.MustParse(fmt.Sprintf("http://%v:%v", elasticMQContainerName, eventBusElasticMQPort)),
+		TopicARNs:                   map[string]string{},
+		QueueExternalURLs:           map[string]string{},
+		QueueInternalURLs:           map[string]string{},
+		DeadLetterQueueExternalURLs: map[string]string{},
+		DeadLetterQueueInternalURLs: map[string]string{},
+	}
+
+	for _, topic := range p.cfg.Topics {
+		p.localMetadata.TopicARNs[topic.Name] = p.getLocalARN("sns", topic.getName(p))
+	}
+
+	for _, queue := range p.cfg.Queues {
+		p.localMetadata.QueueExternalURLs[queue.Name] = p.getLocalQueueURL(p.localMetadata.SQSExternalEndpointURL, queue.getName(p))
+		p.localMetadata.QueueInternalURLs[queue.Name] = p.getLocalQueueURL(p.localMetadata.SQSInternalEndpointURL, queue.getName(p))
+
+		if queue.MaxReceiveCount > 0 {
+			p.localMetadata.DeadLetterQueueExternalURLs[queue.Name] = p.getLocalQueueURL(p.localMetadata.SQSExternalEndpointURL, queue.getDeadLetterQueueName(p))
+			p.localMetadata.DeadLetterQueueInternalURLs[queue.Name] = p.getLocalQueueURL(p.localMetadata.SQSInternalEndpointURL, queue.getDeadLetterQueueName(p))
+		}
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          elasticMQContainerName,
+		ContainerName: elasticMQContainerName,
+		Image:         LocalGetImage(p, "softwaremill/elasticmq-native:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().ElasticMQ),
+		Networks:      p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    eventBusElasticMQPort,
+				Published: uint32(p.cfg.Local.SQSExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+		Volumes: []dctypes.ServiceVolumeConfig{
+			{
+				Type:     "bind",
+				Source:   filez.MustAbs(filepath.Join(buildDirPath, "elasticmq.conf")),
+				Target:   "/opt/elasticmq.conf",
+				ReadOnly: true,
+			},
+		},
+	})
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name: containerName,
+		Build: dctypes.BuildConfig{
+			Context: buildDirPath,
+		},
+		ContainerName: containerName,
+		DependsOn: []string{
+			elasticMQContainerName,
+		},
+		Image:    containerName,
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    eventBusSimulatorPort,
+				Published: uint32(p.cfg.Local.SNSExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *eventBusImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	for _, topic := range p.cfg.Topics {
+		tpl.Resources[topic.getRef().Ref()] = &gosns.Topic{
+			ContentBasedDeduplication: boolz.Ptr(topic.ContentBasedDeduplication),
+			FifoTopic:                 boolz.Ptr(topic.FIFO),
+			TopicName:                 stringz.Ptr(topic.getName(p)),
+			Tags:                      CloudGetDefaultTags(topic.getName(p)),
+		}
+		CloudAddExpRef(tpl, p, topic.getRef())
+	}
+
+	for _, queue := range p.cfg.Queues {
+		if queue.MaxReceiveCount > 0 {
+			tpl.Resources[queue.getDeadLetterQueueRef().Ref()] = &gosqs.Queue{
+				FifoQueue:              boolz.Ptr(queue.FIFO),
+				MessageRetentionPeriod: intz.Ptr(eventBusDeadLetterQueueRetentionPeriod),
+				QueueName:              stringz.Ptr(queue.getDeadLetterQueueName(p)),
+				Tags:                   CloudGetDefaultTags(queue.getDeadLetterQueueName(p)),
+			}
+			CloudAddExpRef(tpl, p, queue.getDeadLetterQueueRef())
+			CloudAddExpGetAtt(tpl, p, queue.getDeadLetterQueueRef(), EventBusAttARN)
+		}
+
+		tpl.Resources[queue.getRef().Ref()] = &gosqs.Queue{
+			ContentBasedDeduplication: boolz.Ptr(queue.ContentBasedDeduplication),
+			FifoQueue:                 boolz.Ptr(queue.FIFO),
+			QueueName:                 stringz.Ptr(queue.getName(p)),
+			RedrivePolicy: func() *interface{} {
+				if queue.MaxReceiveCount == 0 {
+					return nil
+				}
+				var redrivePolicy interface{} = map[string]interface{}{
+					"deadLetterTargetArn": gocf.GetAtt(queue.getDeadLetterQueueRef().Ref(), EventBusAttARN.Ref()),
+					"maxReceiveCount":     queue.MaxReceiveCount,
+				}
+				return &redrivePolicy
+			}(),
+			VisibilityTimeout: intz.Ptr(queue.getVisibilityTimeoutSeconds()),
+			Tags:              CloudGetDefaultTags(queue.getName(p)),
+		}
+		CloudAddExpRef(tpl, p, queue.getRef())
+		CloudAddExpGetAtt(tpl, p, queue.getRef(), EventBusAttARN)
+
+		if topicARNs := p.getQueueTopicARNs(queue.Name); len(topicARNs) > 0 {
+			tpl.Resources[queue.getPolicyRef().Ref()] = &gosqs.QueuePolicy{
+				PolicyDocument: NewPolicyDocument(
+					NewPolicyStatement().
+						SetServicePrincipal("sns.amazonaws.com").
+						AddActions("sqs:SendMessage").
+						AddResources(gocf.GetAtt(queue.getRef().Ref(), EventBusAttARN.Ref())).
+						AddCondition("ArnEquals", "aws:SourceArn", topicARNs...)),
+				Queues: []string{
+					gocf.Ref(queue.getRef().Ref()),
+				},
+			}
+		}
+	}
+
+	for _, topic := range p.cfg.Topics {
+		for _, route := range topic.Routes {
+			subscription := &gosns.Subscription{
+				FilterPolicy: func() *interface{} {
+					if route.FilterPolicy == nil {
+						return nil
+					}
+					var filterPolicy interface{} = route.FilterPolicy
+					return &filterPolicy
+				}(),
+				TopicArn: gocf.Ref(topic.getRef().Ref()),
+			}
+
+			if route.Queue != "" {
+				queue := p.mustGetQueue(route.Queue)
+				subscription.AWSCloudFormationDependsOn = []string{queue.getPolicyRef().Ref()}
+				subscription.Endpoint = stringz.Ptr(gocf.GetAtt(queue.getRef().Ref(), EventBusAttARN.Ref()))
+				subscription.Protocol = "sqs"
+				subscription.RawMessageDelivery = boolz.Ptr(route.RawMessageDelivery)
+			} else {
+				functionARN := p.mustGetFunction(route.Function).GetCloudMetadata(true).GetARN()
+
+				tpl.Resources[route.getPermissionRef(topic).Ref()] = &golambda.Permission{
+					Action:       "lambda:InvokeFunction",
+					FunctionName: functionARN,
+					Principal:    "sns.amazonaws.com",
+					SourceArn:    stringz.Ptr(gocf.Ref(topic.getRef().Ref())),
+				}
+
+				subscription.Endpoint = stringz.Ptr(functionARN)
+				subscription.Protocol = "lambda"
+			}
+
+			tpl.Resources[route.getSubscriptionRef(topic).Ref()] = subscription
+		}
+	}
+
+	for _, name := range p.getFunctionNames() {
+		function := p.mustGetFunction(name)
+		statements := make([]*PolicyStatement, 0)
+
+		for _, publisher := range p.cfg.Publishers {
+			if publisher == name {
+				statement := NewPolicyStatement().AddActions("sns:Publish")
+				for _, topic := range p.cfg.Topics {
+					statement.AddResources(gocf.Ref(topic.getRef().Ref()))
+				}
+				statements = append(statements, statement)
+			}
+		}
+
+		for _, queue := range p.cfg.Queues {
+			if queue.Consumer == name {
+				errorz.Assertf(int(function.GetConfig().TimeoutSeconds) <= queue.getVisibilityTimeoutSeconds(),
+					"the timeout of function %v exceeds the visibility timeout of queue %v",
+					errorz.A(name, queue.Name), errorz.Prefix(EventBusPluginName))
+
+				statements = append(statements, NewPolicyStatement().
+					AddActions(
+						"sqs:ChangeMessageVisibility",
+						"sqs:DeleteMessage",
+						"sqs:GetQueueAttributes",
+						"sqs:ReceiveMessage").
+					AddResources(gocf.GetAtt(queue.getRef().Ref(), EventBusAttARN.Ref())))
+			}
+		}
+
+		if len(statements) == 0 {
+			continue
+		}
+
+		tpl.Resources[p.getFunctionPolicyRef(name).Ref()] = &goiam.Policy{
+			PolicyName:     p.getFunctionPolicyRef(name).Name(p),
+			PolicyDocument: NewPolicyDocument(statements...),
+			Roles: &[]string{
+				function.GetCloudMetadata(true).RoleRef(),
+			},
+		}
+	}
+
+	for _, queue := range p.cfg.Queues {
+		if queue.Consumer == "" {
+			continue
+		}
+
+		tpl.Resources[queue.getEventSourceMappingRef().Ref()] = &golambda.EventSourceMapping{
+			AWSCloudFormationDependsOn: []string{
+				p.getFunctionPolicyRef(queue.Consumer).Ref(),
+			},
+			BatchSize:      intz.Ptr(queue.getBatchSize()),
+			EventSourceArn: stringz.Ptr(gocf.GetAtt(queue.getRef().Ref(), EventBusAttARN.Ref())),
+			FunctionName:   p.mustGetFunction(queue.Consumer).GetCloudMetadata(true).GetARN(),
+		}
+	}
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *eventBusImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+
+	p.cloudMetadata = &EventBusCloudMetadata{
+		Exports:             exports,
+		TopicARNs:           map[string]string{},
+		QueueURLs:           map[string]string{},
+		DeadLetterQueueURLs: map[string]string{},
+	}
+
+	for _, topic := range p.cfg.Topics {
+		p.cloudMetadata.TopicARNs[topic.Name] = exports.GetRef(topic.getRef())
+	}
+
+	for _, queue := range p.cfg.Queues {
+		p.cloudMetadata.QueueURLs[queue.Name] = exports.GetRef(queue.getRef())
+
+		if queue.MaxReceiveCount > 0 {
+			p.cloudMetadata.DeadLetterQueueURLs[queue.Name] = exports.GetRef(queue.getDeadLetterQueueRef())
+		}
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *eventBusImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case LocalBeforeCreateEvent:
+		p.localBeforeCreateEventHook(buildDirPath)
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *eventBusImpl) localBeforeCreateEventHook(buildDirPath string) {
+	filez.MustPrepareDir(buildDirPath, 0777)
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "Dockerfile"), 0777, 0666,
+		templatez.MustParseAndExecuteText(
+			assets.EventBusDockerfileTemplateAsset,
+			assets.EventBusDockerfileTemplateData{
+				BaseImage:  LocalGetImage(p, fmt.Sprintf("golang:%v-alpine", strings.TrimPrefix(runtime.Version(), "go"))),
+				ListenAddr: fmt.Sprintf(":%v", eventBusSimulatorPort),
+			}))
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "main.go"), 0777, 0666,
+		assets.EventBusMainGoAsset)
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "elasticmq.conf"), 0777, 0666,
+		templatez.MustParseAndExecuteText(
+			assets.EventBusElasticMQConfTemplateAsset,
+			assets.EventBusElasticMQConfTemplateData{
+				Host:   p.localMetadata.SQSInternalEndpointURL.Hostname(),
+				Port:   eventBusElasticMQPort,
+				Queues: p.getElasticMQQueues(),
+			}))
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "config.json"), 0777, 0666,
+		jsonz.MustMarshalIndentDefault(p.getSimulatorConfig()))
+}
+
+func (p *eventBusImpl) getElasticMQQueues() []*assets.EventBusElasticMQConfTemplateDataQueue {
+	queues := make([]*assets.EventBusElasticMQConfTemplateDataQueue, 0)
+
+	for _, queue := range p.cfg.Queues {
+		if queue.MaxReceiveCount > 0 {
+			queues = append(queues, &assets.EventBusElasticMQConfTemplateDataQueue{
+				Name:                     queue.getDeadLetterQueueName(p),
+				VisibilityTimeoutSeconds: eventBusDefaultVisibilityTimeout,
+				FIFO:                     queue.FIFO,
+			})
+		}
+
+		queues = append(queues, &assets.EventBusElasticMQConfTemplateDataQueue{
+			Name:                      queue.getName(p),
+			VisibilityTimeoutSeconds:  queue.getVisibilityTimeoutSeconds(),
+			FIFO:                      queue.FIFO,
+			ContentBasedDeduplication: queue.ContentBasedDeduplication,
+			DeadLetterQueueName: func() string {
+				if queue.MaxReceiveCount == 0 {
+					return ""
+				}
+				return queue.getDeadLetterQueueName(p)
+			}(),
+			MaxReceiveCount: queue.MaxReceiveCount,
+		})
+	}
+
+	return queues
+}
+
+func (p *eventBusImpl) getSimulatorConfig() map[string]interface{} {
+	topics := make([]interface{}, 0, len(p.cfg.Topics))
+
+	for _, topic := range p.cfg.Topics {
+		routes := make([]interface{}, 0, len(topic.Routes))
+
+		for _, route := range topic.Routes {
+			r := map[string]interface{}{
+				"subscriptionARN":    p.getLocalARN("sns", route.getSubscriptionRef(topic).Name(p)),
+				"filterPolicy":       route.FilterPolicy,
+				"rawMessageDelivery": route.RawMessageDelivery,
+			}
+
+			if route.Queue != "" {
+				r["queueURL"] = p.localMetadata.QueueInternalURLs[route.Queue]
+			} else {
+				r["functionURL"] = p.mustGetFunction(route.Function).GetLocalMetadata().InternalURL.String()
+			}
+
+			routes = append(routes, r)
+		}
+
+		topics = append(topics, map[string]interface{}{
+			"name":                      topic.Name,
+			"arn":                       p.localMetadata.TopicARNs[topic.Name],
+			"fifo":                      topic.FIFO,
+			"contentBasedDeduplication": topic.ContentBasedDeduplication,
+			"routes":                    routes,
+		})
+	}
+
+	consumers := make([]interface{}, 0)
+
+	for _, queue := range p.cfg.Queues {
+		if queue.Consumer == "" {
+			continue
+		}
+
+		consumers = append(consumers, map[string]interface{}{
+			"queueURL":    p.localMetadata.QueueInternalURLs[queue.Name],
+			"queueARN":    p.getLocalARN("sqs", queue.getName(p)),
+			"functionURL": p.mustGetFunction(queue.Consumer).GetLocalMetadata().InternalURL.String(),
+			"batchSize":   queue.getBatchSize(),
+		})
+	}
+
+	return map[string]interface{}{
+		"region":    p.cfg.Stage.GetConfig().App.GetConfig().AWSConfig.Region,
+		"topics":    topics,
+		"consumers": consumers,
+	}
+}
+
+func (p *eventBusImpl) getLocalARN(service string, name string) string {
+	return fmt.Sprintf("arn:aws:%v:%v:%v:%v", service, p.cfg.Stage.GetConfig().App.GetConfig().AWSConfig.Region, eventBusLocalAccountID, name)
+}
+
+func (p *eventBusImpl) getLocalQueueURL(endpointURL *url.URL, name string) string {
+	return fmt.Sprintf("%v/%v/%v", endpointURL.String(), eventBusLocalAccountID, name)
+}
+
+// getQueueTopicARNs returns the (cloud) ARNs of the topics routing to the given queue.
+func (p *eventBusImpl) getQueueTopicARNs(queueName string) []string {
+	topicARNs := make([]string, 0)
+
+	for _, topic := range p.cfg.Topics {
+		for _, route := range topic.Routes {
+			if route.Queue == queueName {
+				topicARNs = append(topicARNs, gocf.Ref(topic.getRef().Ref()))
+			}
+		}
+	}
+
+	return topicARNs
+}
+
+// getFunctionNames returns the sorted names of the functions referenced by the config.
+func (p *eventBusImpl) getFunctionNames() []string {
+	namesSet := map[string]struct{}{}
+
+	for _, topic := range p.cfg.Topics {
+		for _, route := range topic.Routes {
+			if route.Function != "" {
+				namesSet[route.Function] = struct{}{}
+			}
+		}
+	}
+
+	for _, queue := range p.cfg.Queues {
+		if queue.Consumer != "" {
+			namesSet[queue.Consumer] = struct{}{}
+		}
+	}
+
+	for _, publisher := range p.cfg.Publishers {
+		namesSet[publisher] = struct{}{}
+	}
+
+	names := make([]string, 0, len(namesSet))
+	for name := range namesSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (p *eventBusImpl) getFunctionPolicyRef(name string) CloudRef {
+	return CloudRef("fp-" + name)
+}
+
+func (p *eventBusImpl) mustGetFunction(name string) Function {
+	for _, function := range p.deps.Functions {
+		if instanceName := function.GetInstanceName(); instanceName != nil && *instanceName == name {
+			return function
+		}
+	}
+
+	panic(errorz.Errorf("unknown function: %v", errorz.A(name), errorz.Prefix(EventBusPluginName)))
+}
+
+func (p *eventBusImpl) mustGetQueue(name string) *EventBusConfigQueue {
+	for _, queue := range p.cfg.Queues {
+		if queue.Name == name {
+			return queue
+		}
+	}
+
+	panic(errorz.Errorf("unknown queue: %v", errorz.A(name), errorz.Prefix(EventBusPluginName)))
+}
+
+// getEventBusResourceName returns the name of a topic or queue, which must have the ".fifo" suffix if FIFO.
+func getEventBusResourceName(p Plugin, ref CloudRef, isFIFO bool) string {
+	if isFIFO {
+		return ref.Name(p) + eventBusFIFOSuffix
+	}
+	return ref.Name(p)
+}
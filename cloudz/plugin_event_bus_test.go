@@ -0,0 +1,129 @@
+package cloudz
+
+import (
+	"testing"
+
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBusConfig_MustValidate(t *testing.T) {
+	newConfig := func() *EventBusConfig {
+		return &EventBusConfig{
+			Stage: &cloudStageImpl{cfg: &CloudStageConfig{Name: "staging"}},
+			Name:  "test",
+			Topics: []*EventBusConfigTopic{
+				{
+					Name: "orders",
+					Routes: []*EventBusConfigRoute{
+						{Queue: "billing", RawMessageDelivery: true},
+						{Function: "notifier", FilterPolicy: map[string]interface{}{"type": []interface{}{"created"}}},
+					},
+				},
+			},
+			Queues: []*EventBusConfigQueue{
+				{Name: "billing", MaxReceiveCount: 5, Consumer: "biller"},
+			},
+		}
+	}
+
+	require.NotPanics(t, func() { newConfig().MustValidate(Cloud) })
+	require.Panics(t, func() { newConfig().MustValidate(Local) })
+
+	cfg := newConfig()
+	cfg.Topics[0].Routes[0].Queue = "unknown"
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+
+	cfg = newConfig()
+	cfg.Topics[0].Routes[1].Queue = "billing"
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+
+	cfg = newConfig()
+	cfg.Topics[0].Routes[1].RawMessageDelivery = true
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+
+	cfg = newConfig()
+	cfg.Topics[0].Routes = append(cfg.Topics[0].Routes, &EventBusConfigRoute{Queue: "billing"})
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+
+	cfg = newConfig()
+	cfg.Queues = append(cfg.Queues, &EventBusConfigQueue{Name: "billing"})
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+}
+
+func TestEventBusConfig_MustValidate_FIFO(t *testing.T) {
+	newConfig := func() *EventBusConfig {
+		return &EventBusConfig{
+			Stage: &cloudStageImpl{cfg: &CloudStageConfig{Name: "staging"}},
+			Name:  "test",
+			Topics: []*EventBusConfigTopic{
+				{
+					Name:                      "orders",
+					Routes:                    []*EventBusConfigRoute{{Queue: "billing"}},
+					FIFO:                      true,
+					ContentBasedDeduplication: true,
+				},
+			},
+			Queues: []*EventBusConfigQueue{
+				{Name: "billing", MaxReceiveCount: 5, FIFO: true},
+			},
+		}
+	}
+
+	require.NotPanics(t, func() { newConfig().MustValidate(Cloud) })
+
+	cfg := newConfig()
+	cfg.Queues[0].FIFO = false
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+
+	cfg = newConfig()
+	cfg.Topics[0].Routes = append(cfg.Topics[0].Routes, &EventBusConfigRoute{Function: "notifier"})
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+
+	cfg = newConfig()
+	cfg.Topics[0].FIFO = false
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+
+	cfg = newConfig()
+	cfg.Queues[0].ContentBasedDeduplication = true
+	cfg.Queues[0].FIFO = false
+	cfg.Topics[0].FIFO = false
+	cfg.Topics[0].ContentBasedDeduplication = false
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+}
+
+func TestEventBus_GetFunctionNames(t *testing.T) {
+	p := &eventBusImpl{
+		cfg: &EventBusConfig{
+			Topics: []*EventBusConfigTopic{
+				{
+					Name: "orders",
+					Routes: []*EventBusConfigRoute{
+						{Queue: "billing"},
+						{Function: "notifier"},
+					},
+				},
+			},
+			Queues: []*EventBusConfigQueue{
+				{Name: "billing", Consumer: "biller"},
+			},
+			Publishers: []string{"api", "notifier"},
+		},
+	}
+
+	require.Equal(t, []string{"api", "biller", "notifier"}, p.getFunctionNames())
+	require.Equal(t, []string{gocf.Ref("TOrders")}, p.getQueueTopicARNs("billing"))
+	require.Empty(t, p.getQueueTopicARNs("unknown"))
+}
+
+func TestPolicyStatement_AddCondition(t *testing.T) {
+	s := NewPolicyStatement().
+		AddActions("sqs:SendMessage").
+		AddResources("*").
+		AddCondition("ArnEquals", "aws:SourceArn", "a").
+		AddCondition("ArnEquals", "aws:SourceArn", "b")
+
+	require.Equal(t, map[string]map[string][]string{
+		"ArnEquals": {"aws:SourceArn": {"a", "b"}},
+	}, s.Build().(map[string]interface{})["Condition"])
+}
@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"strings"
 
 	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goevents "github.com/awslabs/goformation/v6/cloudformation/events"
 	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
 	golambda "github.com/awslabs/goformation/v6/cloudformation/lambda"
 	gologs "github.com/awslabs/goformation/v6/cloudformation/logs"
+	gotags "github.com/awslabs/goformation/v6/cloudformation/tags"
 	dctypes "github.com/docker/cli/cli/compose/types"
 	"github.com/ibrt/golang-bites/filez"
 	"github.com/ibrt/golang-bites/numeric/intz"
@@ -21,13 +24,15 @@ import (
 
 // Function constants.
 const (
-	FunctionPluginDisplayName = "Function"
-	FunctionPluginName        = "function"
-	FunctionRefRole           = CloudRef("r")
-	FunctionRefLogGroup       = CloudRef("lg")
-	FunctionRefFunction       = CloudRef("f")
-	FunctionAttARN            = CloudAtt("Arn")
-	FunctionAttRoleID         = CloudAtt("RoleId")
+	FunctionPluginDisplayName   = "Function"
+	FunctionPluginName          = "function"
+	FunctionRefRole             = CloudRef("r")
+	FunctionRefLogGroup         = CloudRef("lg")
+	FunctionRefFunction         = CloudRef("f")
+	FunctionRefWarmUpRule       = CloudRef("wu-r")
+	FunctionRefWarmUpPermission = CloudRef("wu-p")
+	FunctionAttARN              = CloudAtt("Arn")
+	FunctionAttRoleID           = CloudAtt("RoleId")
 
 	FunctionHandlerFileName = "handler"
 	FunctionPackageFileName = "function.zip"
@@ -47,12 +52,16 @@ type FunctionConfigFunc func(Stage, *FunctionDependencies) *FunctionConfig
 type FunctionEventHookFunc func(Function, Event, string)
 
 // FunctionConfig describes the function config.
+//
+// If EFSMount is set, the given access point of the EFS dependency is mounted at EFSMount.Path, which must be under
+// "/mnt/" (as required by Lambda).
 type FunctionConfig struct {
 	Stage          Stage           `validate:"required"`
 	Name           string          `validate:"required"`
 	Builder        FunctionBuilder `validate:"required"`
 	TimeoutSeconds uint16          `validate:"required"`
 	Environment    map[string]string
+	EFSMount       *EFSMount
 	Local          *FunctionConfigLocal
 	Cloud          *FunctionConfigCloud
 	EventHook      FunctionEventHookFunc
@@ -63,6 +72,7 @@ func (c *FunctionConfig) MustValidate(stageTarget StageTarget) {
 	vz.MustValidateStruct(c)
 	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing FunctionConfig.Cloud")
 	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing FunctionConfig.Local")
+	errorz.Assertf(c.EFSMount == nil || strings.HasPrefix(c.EFSMount.Path, "/mnt/"), "invalid FunctionConfig.EFSMount.Path")
 }
 
 // FunctionConfigLocal describes part of the function config.
@@ -72,14 +82,36 @@ type FunctionConfigLocal struct {
 }
 
 // FunctionConfigCloud describes part of the function config.
+// If WarmUp is set, the function is invoked periodically with a warm-up event (see IsFunctionWarmUpEvent).
 type FunctionConfigCloud struct {
-	Memory       int `validate:"required"`
-	RolePolicies []goiam.Role_Policy
+	Memory            int `validate:"required"`
+	RolePolicies      []goiam.Role_Policy
+	WarmUp            *FunctionConfigCloudWarmUp
+	PackageSizeBudget *FunctionConfigCloudPackageSizeBudget
+}
+
+// FunctionConfigCloudWarmUp describes part of the function config.
+// Keeping an instance warm mitigates cold starts for latency-sensitive functions at a fraction of the cost of
+// provisioned concurrency, but it only guarantees one warm instance: concurrent requests may still hit cold starts.
+type FunctionConfigCloudWarmUp struct {
+	IntervalMinutes int `validate:"required,min=1,max=60"`
+}
+
+// FunctionConfigCloudPackageSizeBudget describes part of the function config.
+// Sizes are in MB, and refer to the zipped package. Exceeding WarningSizeMB adds a warning to the package report (see
+// FunctionCloudMetadata.PackageReport), while exceeding MaxSizeMB fails the deploy before uploading the package.
+type FunctionConfigCloudPackageSizeBudget struct {
+	WarningSizeMB float64 `validate:"omitempty,gt=0,ltefield=MaxSizeMB"`
+	MaxSizeMB     float64 `validate:"required,gt=0"`
 }
 
 // FunctionDependencies describes the function dependencies.
+// The EFS dependency requires the Network dependency, since file systems can only be mounted from within the VPC. If
+// the ErrorTracking dependency is set, its environment variables are added to the function environment.
 type FunctionDependencies struct {
 	ArtifactsBucket   Bucket `validate:"required"`
+	EFS               EFS
+	ErrorTracking     ErrorTracking
 	Network           Network
 	OtherDependencies OtherDependencies
 }
@@ -87,6 +119,7 @@ type FunctionDependencies struct {
 // MustValidate validates the function dependencies.
 func (d *FunctionDependencies) MustValidate() {
 	vz.MustValidateStruct(d)
+	errorz.Assertf(d.EFS == nil || d.Network != nil, "FunctionDependencies.EFS requires FunctionDependencies.Network")
 }
 
 // FunctionLocalMetadata describes the function local metadata.
@@ -97,8 +130,9 @@ type FunctionLocalMetadata struct {
 
 // FunctionCloudMetadata describes the function cloud metadata.
 type FunctionCloudMetadata struct {
-	Exports      CloudExports
-	FunctionName string
+	Exports       CloudExports
+	FunctionName  string
+	PackageReport *FunctionPackageReport // set if the package was built by the current deploy
 }
 
 // GetARN returns the function ARN.
@@ -126,6 +160,7 @@ type functionImpl struct {
 	cfg           *FunctionConfig
 	localMetadata *FunctionLocalMetadata
 	cloudMetadata *FunctionCloudMetadata
+	packageReport *FunctionPackageReport
 }
 
 // NewFunction initializes a new Function.
@@ -159,6 +194,14 @@ func (p *functionImpl) GetDependenciesMap() map[Plugin]struct{} {
 		p.deps.ArtifactsBucket: {},
 	}
 
+	if p.deps.EFS != nil {
+		dependenciesMap[p.deps.EFS] = struct{}{}
+	}
+
+	if p.deps.ErrorTracking != nil {
+		dependenciesMap[p.deps.ErrorTracking] = struct{}{}
+	}
+
 	if p.deps.Network != nil {
 		dependenciesMap[p.deps.Network] = struct{}{}
 	}
@@ -174,6 +217,7 @@ func (p *functionImpl) GetDependenciesMap() map[Plugin]struct{} {
 func (p *functionImpl) Configure(stage Stage) {
 	p.cfg = p.cfgFunc(stage, p.deps)
 	p.cfg.MustValidate(stage.GetTarget())
+	efsMustValidateMounts(p, p.deps.EFS, p.getEFSMounts())
 }
 
 // GetStage implements the Plugin interface.
@@ -227,7 +271,7 @@ func (p *functionImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath str
 		Image:         containerName,
 		Environment: func() map[string]*string {
 			e := make(map[string]*string)
-			for k, v := range p.GetConfig().Environment {
+			for k, v := range p.getEnvironment() {
 				e[k] = stringz.Ptr(v)
 			}
 			return e
@@ -240,7 +284,9 @@ func (p *functionImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath str
 			},
 		},
 		Restart: "unless-stopped",
-		Volumes: p.cfg.Builder.GetLocalServiceConfigVolumes(p, buildDirPath),
+		Volumes: append(
+			p.cfg.Builder.GetLocalServiceConfigVolumes(p, buildDirPath),
+			LocalGetEFSServiceVolumeConfigs(p.deps.EFS, p.getEFSMounts())...),
 	})
 }
 
@@ -253,7 +299,13 @@ func (p *functionImpl) GetCloudTemplate(_ string) *gocf.Template {
 		ManagedPolicyArns: &[]string{
 			"arn:aws:iam::aws:policy/service-role/AWSLambdaVPCAccessExecutionRole",
 		},
-		Policies: &p.cfg.Cloud.RolePolicies,
+		Policies: func() *[]goiam.Role_Policy {
+			policies := append([]goiam.Role_Policy{}, p.cfg.Cloud.RolePolicies...)
+			if p.cfg.EFSMount != nil {
+				policies = append(policies, CloudGetEFSClientRolePolicy(p, p.deps.EFS, p.getEFSMounts()))
+			}
+			return &policies
+		}(),
 		RoleName: stringz.Ptr(FunctionRefRole.Name(p)),
 		Tags:     CloudGetDefaultTags(FunctionRefRole.Name(p)),
 	}
@@ -279,18 +331,27 @@ func (p *functionImpl) GetCloudTemplate(_ string) *gocf.Template {
 		},
 		Environment: &golambda.Function_Environment{
 			Variables: func() *map[string]string {
-				e := map[string]string{}
-				for k, v := range p.cfg.Environment {
-					e[k] = v
-				}
+				e := p.getEnvironment()
 				return &e
 			}(),
 		},
+		FileSystemConfigs: func() *[]golambda.Function_FileSystemConfig {
+			if p.cfg.EFSMount == nil {
+				return nil
+			}
+			return &[]golambda.Function_FileSystemConfig{
+				{
+					Arn:            p.deps.EFS.GetCloudMetadata(true).GetAccessPointARN(p.cfg.EFSMount.AccessPoint),
+					LocalMountPath: p.cfg.EFSMount.Path,
+				},
+			}
+		}(),
 		FunctionName: stringz.Ptr(FunctionRefFunction.Name(p)),
 		Handler:      stringz.Ptr(FunctionHandlerFileName),
 		MemorySize:   intz.Ptr(p.cfg.Cloud.Memory),
 		Role:         gocf.GetAtt(FunctionRefRole.Ref(), FunctionAttARN.Ref()),
 		Runtime:      stringz.Ptr(p.cfg.Builder.GetCloudRuntime(p)),
+		Tags:         p.getCloudTags(),
 		Timeout:      intz.Ptr(int(p.cfg.TimeoutSeconds)),
 		VpcConfig: func() *golambda.Function_VpcConfig {
 			if network := p.deps.Network; network != nil {
@@ -310,14 +371,44 @@ func (p *functionImpl) GetCloudTemplate(_ string) *gocf.Template {
 	CloudAddExpRef(tpl, p, FunctionRefFunction)
 	CloudAddExpGetAtt(tpl, p, FunctionRefFunction, FunctionAttARN)
 
+	if p.cfg.Cloud.WarmUp != nil {
+		p.addCloudWarmUpResources(tpl)
+	}
+
 	return tpl
 }
 
+func (p *functionImpl) addCloudWarmUpResources(tpl *gocf.Template) {
+	tpl.Resources[FunctionRefWarmUpRule.Ref()] = &goevents.Rule{
+		Description:        stringz.Ptr(FunctionRefWarmUpRule.Name(p)),
+		Name:               stringz.Ptr(FunctionRefWarmUpRule.Name(p)),
+		ScheduleExpression: stringz.Ptr(getFunctionWarmUpScheduleExpression(p.cfg.Cloud.WarmUp.IntervalMinutes)),
+		State:              stringz.Ptr("ENABLED"),
+		Targets: &[]goevents.Rule_Target{
+			{
+				Arn:   gocf.GetAtt(FunctionRefFunction.Ref(), FunctionAttARN.Ref()),
+				Id:    functionWarmUpTargetID,
+				Input: stringz.Ptr(string(FunctionWarmUpEvent)),
+			},
+		},
+	}
+	CloudAddExpRef(tpl, p, FunctionRefWarmUpRule)
+	CloudAddExpGetAtt(tpl, p, FunctionRefWarmUpRule, FunctionAttARN)
+
+	tpl.Resources[FunctionRefWarmUpPermission.Ref()] = &golambda.Permission{
+		Action:       "lambda:InvokeFunction",
+		FunctionName: gocf.GetAtt(FunctionRefFunction.Ref(), FunctionAttARN.Ref()),
+		Principal:    "events.amazonaws.com",
+		SourceArn:    stringz.Ptr(gocf.GetAtt(FunctionRefWarmUpRule.Ref(), FunctionAttARN.Ref())),
+	}
+}
+
 // UpdateCloudMetadata implements the Plugin interface.
 func (p *functionImpl) UpdateCloudMetadata(stack *awscft.Stack) {
 	p.cloudMetadata = &FunctionCloudMetadata{
-		Exports:      NewCloudExports(stack),
-		FunctionName: FunctionRefFunction.Name(p),
+		Exports:       NewCloudExports(stack),
+		FunctionName:  FunctionRefFunction.Name(p),
+		PackageReport: p.packageReport,
 	}
 }
 
@@ -335,6 +426,21 @@ func (p *functionImpl) EventHook(event Event, buildDirPath string) {
 	}
 }
 
+func (p *functionImpl) getEnvironment() map[string]string {
+	e := getErrorTrackingEnvironment(p.deps.ErrorTracking)
+	for k, v := range p.cfg.Environment {
+		e[k] = v
+	}
+	return e
+}
+
+func (p *functionImpl) getEFSMounts() []*EFSMount {
+	if p.cfg.EFSMount == nil {
+		return nil
+	}
+	return []*EFSMount{p.cfg.EFSMount}
+}
+
 func (p *functionImpl) localBeforeCreateEventHook(buildDirPath string) {
 	filez.MustPrepareDir(buildDirPath, 0777)
 	p.cfg.Builder.LocalBeforeCreateEventHook(p, buildDirPath)
@@ -345,10 +451,34 @@ func (p *functionImpl) cloudBeforeDeployEventHook(buildDirPath string) {
 
 	p.cfg.Builder.BuildCloudPackage(p, buildDirPath)
 	packageContents := filez.MustReadFile(filepath.Join(buildDirPath, FunctionPackageFileName))
+	p.mustCheckPackageSize(packageContents)
 
 	p.cfg.Stage.GetConfig().App.GetOperations().UploadFile(
 		p.deps.ArtifactsBucket.GetCloudMetadata(true).GetName(),
 		p.cfg.Stage.AsCloudStage().GetArtifactsKeyPrefix(p, FunctionPackageFileName),
 		"application/zip",
 		packageContents)
+
+	p.cfg.Stage.AsCloudStage().AddReleaseArtifact(&CloudReleaseArtifact{
+		MetadataKey: GetMetadataKey(p),
+		Kind:        CloudReleaseArtifactKindFunctionPackage,
+		Name:        p.cfg.Stage.AsCloudStage().GetArtifactsKeyPrefix(p, FunctionPackageFileName),
+		SHA256:      getCloudReleaseArtifactHash(packageContents),
+	})
+}
+
+func (p *functionImpl) getCloudTags() *[]gotags.Tag {
+	tags := *CloudGetDefaultTags(FunctionRefFunction.Name(p))
+	provenanceLabels := CloudGetProvenanceLabels(p.cfg.Stage.AsCloudStage())
+
+	for _, k := range []string{
+		CloudProvenanceLabelRevision,
+		CloudProvenanceLabelVersion,
+		CloudProvenanceLabelSourceHash,
+		CloudProvenanceLabelGoVersion,
+	} {
+		tags = append(tags, gotags.Tag{Key: k, Value: provenanceLabels[k]})
+	}
+
+	return &tags
 }
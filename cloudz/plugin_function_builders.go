@@ -1,6 +1,7 @@
 package cloudz
 
 import (
+	"fmt"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/ibrt/golang-bites/templatez"
 
 	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+	"github.com/ibrt/golang-cloud/opz"
 )
 
 var (
@@ -28,14 +30,17 @@ type goFunctionBuilder struct {
 	workDirPath  string
 	packageName  string
 	injectValues map[string]string
+	buildOptions []opz.GoBuildOption
 }
 
 // NewGoFunctionBuilder initializes a new Go function builder.
-func NewGoFunctionBuilder(workDirPath, packageName string, injectValues map[string]string) FunctionBuilder {
+// The build options only apply to cloud packages, e.g. opz.GoBuildOptionUPX to reduce their size.
+func NewGoFunctionBuilder(workDirPath, packageName string, injectValues map[string]string, buildOptions ...opz.GoBuildOption) FunctionBuilder {
 	return &goFunctionBuilder{
 		workDirPath:  workDirPath,
 		packageName:  packageName,
 		injectValues: injectValues,
+		buildOptions: buildOptions,
 	}
 }
 
@@ -57,7 +62,7 @@ func (b *goFunctionBuilder) LocalBeforeCreateEventHook(p Function, buildDirPath
 		templatez.MustParseAndExecuteText(
 			assets.GoFunctionDockerfileTemplateAsset,
 			assets.GoFunctionDockerfileTemplateData{
-				GoVersion:      strings.TrimPrefix(runtime.Version(), "go"),
+				BaseImage:      LocalGetImage(p, fmt.Sprintf("golang:%v-alpine", strings.TrimPrefix(runtime.Version(), "go"))),
 				FunctionName:   FunctionRefFunction.Name(p),
 				TimeoutSeconds: p.GetConfig().TimeoutSeconds,
 			}))
@@ -86,6 +91,6 @@ func (b *goFunctionBuilder) BuildCloudPackage(p Function, buildDirPath string) {
 	handlerFilePath := filepath.Join(buildDirPath, FunctionHandlerFileName)
 	packageFilePath := filepath.Join(buildDirPath, FunctionPackageFileName)
 
-	ops.GoCrossBuildForLinuxAMD64(b.workDirPath, b.packageName, handlerFilePath, b.injectValues)
+	ops.GoCrossBuildForLinuxAMD64(b.workDirPath, b.packageName, handlerFilePath, b.injectValues, b.buildOptions...)
 	ops.PackageLambdaFunctionHandler(handlerFilePath, FunctionHandlerFileName, packageFilePath)
 }
@@ -0,0 +1,80 @@
+package cloudz
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+const (
+	functionPackageMaxUnzippedSizeMB = 250 // AWS Lambda limit
+	functionPackageBytesPerMB        = 1024 * 1024
+)
+
+// FunctionPackageSize describes the size of a function package.
+type FunctionPackageSize struct {
+	ZippedSizeMB   float64
+	UnzippedSizeMB float64
+}
+
+// String implements the fmt.Stringer interface.
+func (s *FunctionPackageSize) String() string {
+	return fmt.Sprintf("%.1f MB zipped, %.1f MB unzipped", s.ZippedSizeMB, s.UnzippedSizeMB)
+}
+
+// FunctionPackageReport describes the package built for a function.
+type FunctionPackageReport struct {
+	Size    *FunctionPackageSize
+	Summary string // e.g. "42.0 MB zipped, 120.0 MB unzipped (budget is 45 MB)"
+}
+
+// mustGetFunctionPackageSize returns the size of the given function package.
+func mustGetFunctionPackageSize(packageContents []byte) *FunctionPackageSize {
+	r, err := zip.NewReader(bytes.NewReader(packageContents), int64(len(packageContents)))
+	errorz.MaybeMustWrap(err)
+
+	unzippedSize := uint64(0)
+	for _, f := range r.File {
+		unzippedSize += f.UncompressedSize64
+	}
+
+	return &FunctionPackageSize{
+		ZippedSizeMB:   float64(len(packageContents)) / functionPackageBytesPerMB,
+		UnzippedSizeMB: float64(unzippedSize) / functionPackageBytesPerMB,
+	}
+}
+
+// mustCheckFunctionPackageSize checks the given size against the budget (optional) and the AWS Lambda limits, and
+// returns a report line.
+func mustCheckFunctionPackageSize(size *FunctionPackageSize, budget *FunctionConfigCloudPackageSizeBudget) string {
+	errorz.Assertf(size.UnzippedSizeMB <= functionPackageMaxUnzippedSizeMB,
+		"package exceeds the unzipped size limit: %v (limit is %v MB)",
+		errorz.A(size, functionPackageMaxUnzippedSizeMB), errorz.Prefix(FunctionPluginName))
+
+	if budget == nil {
+		return size.String()
+	}
+
+	errorz.Assertf(size.ZippedSizeMB <= budget.MaxSizeMB,
+		"package exceeds its size budget: %v (budget is %v MB)",
+		errorz.A(size, budget.MaxSizeMB), errorz.Prefix(FunctionPluginName))
+
+	if budget.WarningSizeMB > 0 && size.ZippedSizeMB > budget.WarningSizeMB {
+		return fmt.Sprintf("%v (WARNING: over %v MB, budget is %v MB)", size, budget.WarningSizeMB, budget.MaxSizeMB)
+	}
+
+	return fmt.Sprintf("%v (budget is %v MB)", size, budget.MaxSizeMB)
+}
+
+// mustCheckPackageSize checks the size of the given function package (see mustCheckFunctionPackageSize), and stores the
+// resulting report, which is exposed in the cloud metadata after deploy.
+func (p *functionImpl) mustCheckPackageSize(packageContents []byte) {
+	size := mustGetFunctionPackageSize(packageContents)
+
+	p.packageReport = &FunctionPackageReport{
+		Size:    size,
+		Summary: mustCheckFunctionPackageSize(size, p.cfg.Cloud.PackageSizeBudget),
+	}
+}
@@ -0,0 +1,40 @@
+package cloudz
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMustGetFunctionPackageSize(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	fw, err := w.Create(FunctionHandlerFileName)
+	require.NoError(t, err)
+	_, err = fw.Write(make([]byte, 2*functionPackageBytesPerMB))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	size := mustGetFunctionPackageSize(buf.Bytes())
+	require.Equal(t, 2.0, size.UnzippedSizeMB)
+	require.Less(t, size.ZippedSizeMB, 0.1)
+
+	require.Panics(t, func() { mustGetFunctionPackageSize([]byte("not a zip")) })
+}
+
+func TestMustCheckFunctionPackageSize(t *testing.T) {
+	size := &FunctionPackageSize{ZippedSizeMB: 42, UnzippedSizeMB: 120}
+	budget := &FunctionConfigCloudPackageSizeBudget{WarningSizeMB: 40, MaxSizeMB: 45}
+
+	require.Equal(t, "42.0 MB zipped, 120.0 MB unzipped", mustCheckFunctionPackageSize(size, nil))
+	require.Equal(t, "42.0 MB zipped, 120.0 MB unzipped (WARNING: over 40 MB, budget is 45 MB)", mustCheckFunctionPackageSize(size, budget))
+
+	budget.WarningSizeMB = 0
+	require.Equal(t, "42.0 MB zipped, 120.0 MB unzipped (budget is 45 MB)", mustCheckFunctionPackageSize(size, budget))
+
+	budget.MaxSizeMB = 40
+	require.Panics(t, func() { mustCheckFunctionPackageSize(size, budget) })
+	require.Panics(t, func() { mustCheckFunctionPackageSize(&FunctionPackageSize{ZippedSizeMB: 50, UnzippedSizeMB: 251}, nil) })
+}
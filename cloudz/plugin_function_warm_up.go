@@ -0,0 +1,42 @@
+package cloudz
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	functionWarmUpTargetID = "warm-up"
+	functionWarmUpEventKey = "golangCloudWarmUp"
+)
+
+var (
+	// FunctionWarmUpEvent is the payload functions with FunctionConfigCloud.WarmUp are periodically invoked with.
+	FunctionWarmUpEvent = json.RawMessage(fmt.Sprintf(`{"%v":true}`, functionWarmUpEventKey))
+)
+
+// IsFunctionWarmUpEvent returns true if the given event payload is a warm-up event (see FunctionConfigCloud.WarmUp).
+// Handlers should check it first and return immediately (e.g. with a nil response), without running any business logic:
+//
+//	func handler(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+//		if cloudz.IsFunctionWarmUpEvent(payload) {
+//			return nil, nil
+//		}
+//		// ...
+//	}
+func IsFunctionWarmUpEvent(payload json.RawMessage) bool {
+	event := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return false
+	}
+
+	isWarmUp, ok := event[functionWarmUpEventKey].(bool)
+	return ok && isWarmUp && len(event) == 1
+}
+
+func getFunctionWarmUpScheduleExpression(intervalMinutes int) string {
+	if intervalMinutes == 1 {
+		return "rate(1 minute)"
+	}
+	return fmt.Sprintf("rate(%v minutes)", intervalMinutes)
+}
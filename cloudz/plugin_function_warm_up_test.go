@@ -0,0 +1,23 @@
+package cloudz
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsFunctionWarmUpEvent(t *testing.T) {
+	require.True(t, IsFunctionWarmUpEvent(FunctionWarmUpEvent))
+	require.True(t, IsFunctionWarmUpEvent(json.RawMessage(`{ "golangCloudWarmUp": true }`)))
+	require.False(t, IsFunctionWarmUpEvent(json.RawMessage(`{"golangCloudWarmUp": false}`)))
+	require.False(t, IsFunctionWarmUpEvent(json.RawMessage(`{"golangCloudWarmUp": true, "body": "{}"}`)))
+	require.False(t, IsFunctionWarmUpEvent(json.RawMessage(`{"version": "2.0", "routeKey": "GET /"}`)))
+	require.False(t, IsFunctionWarmUpEvent(json.RawMessage(`[]`)))
+	require.False(t, IsFunctionWarmUpEvent(nil))
+}
+
+func TestGetFunctionWarmUpScheduleExpression(t *testing.T) {
+	require.Equal(t, "rate(1 minute)", getFunctionWarmUpScheduleExpression(1))
+	require.Equal(t, "rate(5 minutes)", getFunctionWarmUpScheduleExpression(5))
+}
@@ -4,18 +4,13 @@ import (
 	"crypto/rsa"
 	"fmt"
 	"net/url"
+	"os"
 	"path/filepath"
 	"time"
 
 	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	gocf "github.com/awslabs/goformation/v6/cloudformation"
-	goecs "github.com/awslabs/goformation/v6/cloudformation/ecs"
-	elbv2 "github.com/awslabs/goformation/v6/cloudformation/elasticloadbalancingv2"
-	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
-	gologs "github.com/awslabs/goformation/v6/cloudformation/logs"
-	goroute53 "github.com/awslabs/goformation/v6/cloudformation/route53"
 	dctypes "github.com/docker/cli/cli/compose/types"
-	"github.com/ibrt/golang-bites/boolz"
 	"github.com/ibrt/golang-bites/filez"
 	"github.com/ibrt/golang-bites/jsonz"
 	"github.com/ibrt/golang-bites/numeric/intz"
@@ -24,39 +19,44 @@ import (
 	"github.com/ibrt/golang-bites/templatez"
 	"github.com/ibrt/golang-bites/urlz"
 	"github.com/ibrt/golang-errors/errorz"
-	"github.com/ibrt/golang-shell/shellz"
 	"github.com/ibrt/golang-validation/vz"
 
 	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+	"github.com/ibrt/golang-cloud/opz"
 )
 
 // Hasura constants.
 const (
 	HasuraPluginDisplayName      = "Hasura"
 	HasuraPluginName             = "hasura"
-	HasuraRefLogGroup            = CloudRef("lg")
-	HasuraRefRoleExecution       = CloudRef("r-ex")
-	HasuraRefRoleTask            = CloudRef("r-tk")
-	HasuraRefTaskDefinition      = CloudRef("td")
-	HasuraRefTargetGroup         = CloudRef("tg")
-	HasuraRefListenerRule        = CloudRef("lr")
-	HasuraRefCluster             = CloudRef("cl")
-	HasuraRefService             = CloudRef("svc")
-	HasuraRefRecordSet           = CloudRef("rs")
-	HasuraAttARN                 = CloudAtt("Arn")
-	HasuraAttName                = CloudAtt("Name")
-	HasuraAttRoleID              = CloudAtt("RoleId")
-	HasuraAttRuleARN             = CloudAtt("RuleArn")
-	HasuraAttTargetGroupFullName = CloudAtt("TargetGroupFullName")
-	HasuraAttTargetGroupName     = CloudAtt("TargetGroupName")
-
-	hasuraVersion   = "2.5.1"
-	hasuraCloudPort = 7329 // Note: it doesn't really matter as long as it's unique-ish.
+	HasuraRefLogGroup            = ECSServiceRefLogGroup
+	HasuraRefRoleExecution       = ECSServiceRefRoleExecution
+	HasuraRefRoleTask            = ECSServiceRefRoleTask
+	HasuraRefTaskDefinition      = ECSServiceRefTaskDefinition
+	HasuraRefTargetGroup         = ECSServiceRefTargetGroup
+	HasuraRefListenerRule        = ECSServiceRefListenerRule
+	HasuraRefCluster             = ECSServiceRefCluster
+	HasuraRefService             = ECSServiceRefService
+	HasuraRefRecordSet           = ECSServiceRefRecordSet
+	HasuraRefHealthCheck         = ECSServiceRefHealthCheck
+	HasuraAttARN                 = ECSServiceAttARN
+	HasuraAttName                = ECSServiceAttName
+	HasuraAttRoleID              = ECSServiceAttRoleID
+	HasuraAttRuleARN             = ECSServiceAttRuleARN
+	HasuraAttTargetGroupFullName = ECSServiceAttTargetGroupFullName
+	HasuraAttTargetGroupName     = ECSServiceAttTargetGroupName
+
+	hasuraCloudPort              = 7329 // Note: it doesn't really matter as long as it's unique-ish.
+	hasuraListenerRulePriority   = 100
+	hasuraWebSocketKeepAlive     = 5
+	hasuraOutputMigrationVersion = "MigrationVersion"
+	hasuraSnapshotFileName       = "metadata.json"
 )
 
 var (
-	_ Hasura = &hasuraImpl{}
-	_ Plugin = &hasuraImpl{}
+	_ Hasura           = &hasuraImpl{}
+	_ Plugin           = &hasuraImpl{}
+	_ localSnapshotter = &hasuraImpl{}
 
 	hasuraConfigDirParts = []string{
 		"config",
@@ -71,7 +71,8 @@ type HasuraEventHookFunc func(Hasura, Event, string)
 
 // HasuraConfig describes the hasura config.
 type HasuraConfig struct {
-	Stage            Stage `validate:"required"`
+	Stage            Stage  `validate:"required"`
+	InstanceName     string `validate:"omitempty,resource-name"` // if empty, the plugin is a singleton
 	EnableAllowList  bool
 	UnauthorizedRole *string
 	JWT              *HasuraConfigJWT `validate:"required"`
@@ -86,13 +87,49 @@ func (c *HasuraConfig) MustValidate(stageTarget StageTarget) {
 	vz.MustValidateStruct(c)
 	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing HasuraConfig.Local")
 	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing HasuraConfig.Cloud")
+
+	if stageTarget == Cloud && c.Cloud.Routing != nil {
+		c.Cloud.Routing.MustValidate()
+	}
+
+	errorz.Assertf((c.JWT.PublicKey != nil) != (c.JWT.JWKURL != ""), "exactly one of HasuraConfigJWT.PublicKey, JWKURL must be set")
+	errorz.Assertf(c.JWT.ClaimsNamespace == nil || c.JWT.ClaimsMap == nil, "at most one of HasuraConfigJWT.ClaimsNamespace, ClaimsMap can be set")
 }
 
 // HasuraConfigJWT describes part of the hasura config.
+// Exactly one of PublicKey and JWKURL must be set. ClaimsNamespace and ClaimsMap are optional and mutually exclusive.
 type HasuraConfigJWT struct {
-	PublicKey *rsa.PublicKey `validate:"required"`
-	Issuer    string         `validate:"required"`
-	Audience  string         `validate:"required"`
+	PublicKey       *rsa.PublicKey
+	JWKURL          string `validate:"omitempty,url"`
+	Issuer          string `validate:"required"`
+	Audience        string `validate:"required"`
+	ClaimsNamespace *string
+	ClaimsMap       map[string]interface{}
+}
+
+// GetSecret returns the JSON value of the HASURA_GRAPHQL_JWT_SECRET environment variable.
+func (c *HasuraConfigJWT) GetSecret() string {
+	secret := map[string]interface{}{
+		"issuer":   c.Issuer,
+		"audience": c.Audience,
+	}
+
+	if c.PublicKey != nil {
+		secret["type"] = "RS256"
+		secret["key"] = string(rsaz.RSAPublicKeyToPEM(c.PublicKey))
+	} else {
+		secret["jwk_url"] = c.JWKURL
+	}
+
+	if c.ClaimsNamespace != nil {
+		secret["claims_namespace"] = *c.ClaimsNamespace
+	}
+
+	if c.ClaimsMap != nil {
+		secret["claims_map"] = c.ClaimsMap
+	}
+
+	return jsonz.MustMarshalString(secret)
 }
 
 // HasuraConfigLocal describes part of the hasura config.
@@ -110,6 +147,24 @@ type HasuraConfigCloud struct {
 	Memory      int    `validate:"required"`
 	AdminSecret string `validate:"required,min=16"`
 	CORSDomain  *string
+	Routing     *RecordSetRoutingConfig
+
+	// ListenerRulePriority must be unique among the services sharing the LoadBalancer, defaults to 100.
+	ListenerRulePriority int
+
+	// TargetGroup optionally configures stickiness and slow start on the load balancer target group.
+	TargetGroup *ECSServiceTemplateConfigTargetGroup
+
+	// ServiceConnect optionally makes Hasura reachable by the other services of the Network via ECS Service Connect.
+	ServiceConnect *ECSServiceTemplateConfigServiceConnect
+
+	// WebSocketKeepAliveSeconds is the interval between keepalive messages on subscription connections, defaults to 5.
+	// It must be lower than the LoadBalancer idle timeout, or idle subscriptions are dropped by the load balancer.
+	WebSocketKeepAliveSeconds int `validate:"omitempty,min=1"`
+
+	// AllowDestructiveMigrations allows deploying migrations with potentially destructive statements to production
+	// without approval (see ApprovalPolicy).
+	AllowDestructiveMigrations bool
 }
 
 // HasuraDependencies describes the hasura dependencies.
@@ -119,6 +174,8 @@ type HasuraDependencies struct {
 	LoadBalancer      LoadBalancer    `validate:"required"`
 	Network           Network         `validate:"required"`
 	Postgres          Postgres        `validate:"required"`
+	ErrorTracking     ErrorTracking
+	RuntimeSecrets    RuntimeSecrets
 	OtherDependencies OtherDependencies
 }
 
@@ -131,16 +188,22 @@ func (d *HasuraDependencies) MustValidate() {
 type HasuraLocalMetadata struct {
 	ContainerName        string
 	ConsoleContainerName string
-	AdminSecret          string
+	AdminSecret          string `metadata:"secret"`
 	ExternalURL          *url.URL
 	InternalURL          *url.URL
 	ConsoleExternalURL   *url.URL
+	JWTIssuer            string
+	JWTAudience          string
+	JWKURL               string
 }
 
 // HasuraCloudMetadata describes the hasura cloud metadata.
 type HasuraCloudMetadata struct {
-	Exports CloudExports
-	URL     *url.URL
+	Exports     CloudExports
+	URL         *url.URL
+	JWTIssuer   string
+	JWTAudience string
+	JWKURL      string
 }
 
 // Hasura describes a hasura.
@@ -159,6 +222,8 @@ type hasuraImpl struct {
 	cfg           *HasuraConfig
 	localMetadata *HasuraLocalMetadata
 	cloudMetadata *HasuraCloudMetadata
+
+	deployedMigrationVersion int64
 }
 
 // NewHasura initializes a new Hasura.
@@ -183,7 +248,10 @@ func (p *hasuraImpl) GetName() string {
 
 // GetInstanceName implements the Plugin interface.
 func (p *hasuraImpl) GetInstanceName() *string {
-	return nil
+	if p.cfg.InstanceName == "" {
+		return nil
+	}
+	return stringz.Ptr(p.cfg.InstanceName)
 }
 
 // GetDependenciesMap implements the Plugin interface.
@@ -196,6 +264,14 @@ func (p *hasuraImpl) GetDependenciesMap() map[Plugin]struct{} {
 		p.deps.Postgres:        {},
 	}
 
+	if p.deps.ErrorTracking != nil {
+		dependenciesMap[p.deps.ErrorTracking] = struct{}{}
+	}
+
+	if p.deps.RuntimeSecrets != nil {
+		dependenciesMap[p.deps.RuntimeSecrets] = struct{}{}
+	}
+
 	for _, otherDependency := range p.deps.OtherDependencies {
 		dependenciesMap[otherDependency] = struct{}{}
 	}
@@ -260,6 +336,9 @@ func (p *hasuraImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath strin
 		ExternalURL:          urlz.MustParse(fmt.Sprintf("http://localhost:%v/v1/graphql", p.cfg.Local.ExternalPort)),
 		InternalURL:          urlz.MustParse(fmt.Sprintf("http://%v:%v/v1/graphql", containerName, p.cfg.Local.ExternalPort)),
 		ConsoleExternalURL:   urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.ConsoleExternalPort)),
+		JWTIssuer:            p.cfg.JWT.Issuer,
+		JWTAudience:          p.cfg.JWT.Audience,
+		JWKURL:               p.cfg.JWT.JWKURL,
 	}
 
 	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
@@ -279,25 +358,24 @@ func (p *hasuraImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath strin
 				"HASURA_GRAPHQL_ENABLE_TELEMETRY":  stringz.Ptr("false"),
 				"HASURA_GRAPHQL_LOG_LEVEL":         stringz.Ptr("debug"),
 				"HASURA_GRAPHQL_SERVER_PORT":       stringz.Ptr(fmt.Sprintf("%v", p.cfg.Local.ExternalPort)),
-				"HASURA_GRAPHQL_JWT_SECRET": stringz.Ptr(jsonz.MustMarshalString(map[string]interface{}{
-					"type":     "RS256",
-					"key":      string(rsaz.RSAPublicKeyToPEM(p.cfg.JWT.PublicKey)),
-					"issuer":   p.cfg.JWT.Issuer,
-					"audience": p.cfg.JWT.Audience,
-				})),
+				"HASURA_GRAPHQL_JWT_SECRET":        stringz.Ptr(p.cfg.JWT.GetSecret()),
 			}
 
 			if p.cfg.UnauthorizedRole != nil && *p.cfg.UnauthorizedRole != "" {
 				e["HASURA_GRAPHQL_UNAUTHORIZED_ROLE"] = p.cfg.UnauthorizedRole
 			}
 
+			for k, v := range getErrorTrackingEnvironment(p.deps.ErrorTracking) {
+				e[k] = stringz.Ptr(v)
+			}
+
 			for k, v := range p.cfg.Environment {
 				e[k] = stringz.Ptr(v)
 			}
 
 			return e
 		}(),
-		Image:    "hasura/graphql-engine:v" + hasuraVersion,
+		Image:    LocalGetImage(p, "hasura/graphql-engine:v"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Hasura),
 		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
 		Ports: []dctypes.ServicePortConfig{
 			{
@@ -333,7 +411,7 @@ func (p *hasuraImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath strin
 		Volumes: []dctypes.ServiceVolumeConfig{
 			{
 				Type:   "bind",
-				Source: filez.MustAbs(p.cfg.Stage.GetConfig().App.GetConfig().GetConfigDirPathForPlugin(p, hasuraConfigDirParts...)),
+				Source: filez.MustAbs(p.getConfigDirPath()),
 				Target: "/hasura",
 			},
 		},
@@ -342,266 +420,108 @@ func (p *hasuraImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath strin
 
 // GetCloudTemplate implements the Plugin interface.
 func (p *hasuraImpl) GetCloudTemplate(_ string) *gocf.Template {
+	errorz.Assertf(
+		p.getWebSocketKeepAliveSeconds() < p.deps.LoadBalancer.GetConfig().Cloud.GetIdleTimeoutSeconds(),
+		"HasuraConfigCloud.WebSocketKeepAliveSeconds must be lower than the load balancer idle timeout",
+		errorz.Prefix(HasuraPluginName))
+
 	tpl := gocf.NewTemplate()
 
-	tpl.Resources[HasuraRefLogGroup.Ref()] = &gologs.LogGroup{
-		LogGroupName:    stringz.Ptr(HasuraRefLogGroup.Name(p)),
-		RetentionInDays: intz.Ptr(90),
-	}
-	CloudAddExpRef(tpl, p, HasuraRefLogGroup)
-	CloudAddExpGetAtt(tpl, p, HasuraRefLogGroup, HasuraAttARN)
+	CloudAddECSServiceResources(tpl, p, &ECSServiceTemplateConfig{
+		Image: p.getImageWithTag(),
+		Port:  hasuraCloudPort,
+		Environment: func() map[string]string {
+			e := map[string]string{
+				"HASURA_GRAPHQL_ADMIN_SECRET":              p.cfg.Cloud.AdminSecret,
+				"HASURA_GRAPHQL_DATABASE_URL":              p.deps.Postgres.GetCloudMetadata(true).URL.String(),
+				"HASURA_GRAPHQL_DEV_MODE":                  "false",
+				"HASURA_GRAPHQL_ENABLED_APIS":              "graphql",
+				"HASURA_GRAPHQL_ENABLED_LOG_TYPES":         "startup,http-log,webhook-log,websocket-log,query-log",
+				"HASURA_GRAPHQL_ENABLE_ALLOWLIST":          fmt.Sprintf("%v", p.cfg.EnableAllowList),
+				"HASURA_GRAPHQL_ENABLE_CONSOLE":            "false",
+				"HASURA_GRAPHQL_ENABLE_MAINTENANCE_MODE":   "false",
+				"HASURA_GRAPHQL_ENABLE_TELEMETRY":          "false",
+				"HASURA_GRAPHQL_GRACEFUL_SHUTDOWN_TIMEOUT": "29",
+				"HASURA_GRAPHQL_SERVER_PORT":               fmt.Sprintf("%v", hasuraCloudPort),
+				"HASURA_GRAPHQL_JWT_SECRET":                p.cfg.JWT.GetSecret(),
+				"HASURA_GRAPHQL_WEBSOCKET_KEEPALIVE":       fmt.Sprintf("%v", p.getWebSocketKeepAliveSeconds()),
+				"HASURA_GRAPHQL_LOG_LEVEL": func() string {
+					if p.cfg.Stage.GetMode().IsProduction() {
+						return "warn"
+					}
+					return "debug"
+				}(),
+			}
 
-	tpl.Resources[HasuraRefRoleExecution.Ref()] = &goiam.Role{
-		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("ecs-tasks.amazonaws.com"),
-		ManagedPolicyArns: &[]string{
-			"arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy",
-		},
-		RoleName: stringz.Ptr(HasuraRefRoleExecution.Name(p)),
-		Tags:     CloudGetDefaultTags(HasuraRefRoleExecution.Name(p)),
-	}
-	CloudAddExpRef(tpl, p, HasuraRefRoleExecution)
-	CloudAddExpGetAtt(tpl, p, HasuraRefRoleExecution, HasuraAttARN)
-	CloudAddExpGetAtt(tpl, p, HasuraRefRoleExecution, HasuraAttRoleID)
-
-	tpl.Resources[HasuraRefRoleTask.Ref()] = &goiam.Role{
-		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("ecs-tasks.amazonaws.com"),
-		RoleName:                 stringz.Ptr(HasuraRefRoleTask.Name(p)),
-		Tags:                     CloudGetDefaultTags(HasuraRefRoleTask.Name(p)),
-	}
-	CloudAddExpRef(tpl, p, HasuraRefRoleTask)
-	CloudAddExpGetAtt(tpl, p, HasuraRefRoleTask, HasuraAttARN)
-	CloudAddExpGetAtt(tpl, p, HasuraRefRoleTask, HasuraAttRoleID)
+			if p.cfg.UnauthorizedRole != nil && *p.cfg.UnauthorizedRole != "" {
+				e["HASURA_GRAPHQL_UNAUTHORIZED_ROLE"] = *p.cfg.UnauthorizedRole
+			}
 
-	tpl.Resources[HasuraRefTaskDefinition.Ref()] = &goecs.TaskDefinition{
-		ContainerDefinitions: &[]goecs.TaskDefinition_ContainerDefinition{
-			{
-				Environment: CloudGetTaskDefinitionKeyValuePairs(
-					func() map[string]string {
-						e := map[string]string{
-							"HASURA_GRAPHQL_ADMIN_SECRET":              p.cfg.Cloud.AdminSecret,
-							"HASURA_GRAPHQL_DATABASE_URL":              p.deps.Postgres.GetCloudMetadata(true).URL.String(),
-							"HASURA_GRAPHQL_DEV_MODE":                  "false",
-							"HASURA_GRAPHQL_ENABLED_APIS":              "graphql",
-							"HASURA_GRAPHQL_ENABLED_LOG_TYPES":         "startup,http-log,webhook-log,websocket-log,query-log",
-							"HASURA_GRAPHQL_ENABLE_ALLOWLIST":          fmt.Sprintf("%v", p.cfg.EnableAllowList),
-							"HASURA_GRAPHQL_ENABLE_CONSOLE":            "false",
-							"HASURA_GRAPHQL_ENABLE_MAINTENANCE_MODE":   "false",
-							"HASURA_GRAPHQL_ENABLE_TELEMETRY":          "false",
-							"HASURA_GRAPHQL_GRACEFUL_SHUTDOWN_TIMEOUT": "29",
-							"HASURA_GRAPHQL_SERVER_PORT":               fmt.Sprintf("%v", hasuraCloudPort),
-							"HASURA_GRAPHQL_JWT_SECRET": jsonz.MustMarshalString(map[string]interface{}{
-								"type":     "RS256",
-								"key":      string(rsaz.RSAPublicKeyToPEM(p.cfg.JWT.PublicKey)),
-								"issuer":   p.cfg.JWT.Issuer,
-								"audience": p.cfg.JWT.Audience,
-							}),
-							"HASURA_GRAPHQL_LOG_LEVEL": func() string {
-								if p.cfg.Stage.GetMode().IsProduction() {
-									return "warn"
-								}
-								return "debug"
-							}(),
-						}
-
-						if p.cfg.UnauthorizedRole != nil && *p.cfg.UnauthorizedRole != "" {
-							e["HASURA_GRAPHQL_UNAUTHORIZED_ROLE"] = *p.cfg.UnauthorizedRole
-						}
-
-						if p.cfg.Cloud.CORSDomain != nil && *p.cfg.Cloud.CORSDomain != "" {
-							e["HASURA_GRAPHQL_CORS_DOMAIN"] = fmt.Sprintf("https://%v", *p.cfg.Cloud.CORSDomain)
-						}
-
-						for k, v := range p.cfg.Environment {
-							e[k] = v
-						}
-
-						return e
-					}()),
-				Image: stringz.Ptr(fmt.Sprintf("%v:%v",
-					p.deps.ImageRepository.GetCloudMetadata(true).ImageName,
-					p.cfg.Stage.AsCloudStage().GetCloudConfig().Version)),
-				LogConfiguration: &goecs.TaskDefinition_LogConfiguration{
-					LogDriver: "awslogs",
-					Options: &map[string]string{
-						"awslogs-region":        gocf.Ref("AWS::Region"),
-						"awslogs-group":         gocf.Ref(HasuraRefLogGroup.Ref()),
-						"awslogs-stream-prefix": HasuraRefTaskDefinition.Name(p),
-					},
-				},
-				MountPoints: &[]goecs.TaskDefinition_MountPoint{
-					{
-						ContainerPath: stringz.Ptr("/tmp"),
-						SourceVolume:  stringz.Ptr("tmp"),
-					},
-					{
-						ContainerPath: stringz.Ptr("/root/.hasura"),
-						SourceVolume:  stringz.Ptr("hasura"),
-					},
-				},
-				Name: stringz.Ptr(HasuraRefTaskDefinition.Name(p)),
-				PortMappings: &[]goecs.TaskDefinition_PortMapping{
-					{
-						ContainerPort: intz.Ptr(hasuraCloudPort),
-						HostPort:      intz.Ptr(hasuraCloudPort),
-						Protocol:      stringz.Ptr("tcp"),
-					},
-				},
-				ReadonlyRootFilesystem: boolz.Ptr(true),
-				StopTimeout:            intz.Ptr(30),
-			},
-		},
-		Cpu:              stringz.Ptr(fmt.Sprintf("%v", p.cfg.Cloud.CPU)),
-		ExecutionRoleArn: stringz.Ptr(gocf.Ref(HasuraRefRoleExecution.Ref())),
-		Family:           stringz.Ptr(HasuraRefTaskDefinition.Name(p)),
-		Memory:           stringz.Ptr(fmt.Sprintf("%v", p.cfg.Cloud.Memory)),
-		NetworkMode:      stringz.Ptr("awsvpc"),
-		RequiresCompatibilities: &[]string{
-			"FARGATE",
-		},
-		TaskRoleArn: stringz.Ptr(gocf.Ref(HasuraRefRoleTask.Ref())),
-		Volumes: &[]goecs.TaskDefinition_Volume{
-			{
-				Name: stringz.Ptr("tmp"),
-			},
-			{
-				Name: stringz.Ptr("hasura"),
-			},
-		},
-		Tags: CloudGetDefaultTags(HasuraRefTaskDefinition.Name(p)),
-	}
-	CloudAddExpRef(tpl, p, HasuraRefTaskDefinition)
-
-	tpl.Resources[HasuraRefTargetGroup.Ref()] = &elbv2.TargetGroup{
-		HealthCheckPath:            stringz.Ptr("/healthz"),
-		HealthCheckIntervalSeconds: intz.Ptr(15),
-		HealthyThresholdCount:      intz.Ptr(2),
-		UnhealthyThresholdCount:    intz.Ptr(8),
-		Port:                       intz.Ptr(hasuraCloudPort),
-		Protocol:                   stringz.Ptr("HTTP"),
-		ProtocolVersion:            stringz.Ptr("HTTP1"), // TODO(ibrt): Try HTTP2?
-		TargetGroupAttributes: &[]elbv2.TargetGroup_TargetGroupAttribute{
-			{
-				Key:   stringz.Ptr("deregistration_delay.timeout_seconds"),
-				Value: stringz.Ptr("30"),
-			},
-		},
-		TargetType: stringz.Ptr("ip"),
-		VpcId:      stringz.Ptr(p.deps.Network.GetCloudMetadata(true).Exports.GetRef(NetworkRefVPC)),
-		Tags:       CloudGetDefaultTags(HasuraRefTargetGroup.Name(p)),
-	}
-	CloudAddExpRef(tpl, p, HasuraRefTargetGroup)
-	CloudAddExpGetAtt(tpl, p, HasuraRefTargetGroup, HasuraAttTargetGroupFullName)
-	CloudAddExpGetAtt(tpl, p, HasuraRefTargetGroup, HasuraAttTargetGroupName)
+			if p.cfg.Cloud.CORSDomain != nil && *p.cfg.Cloud.CORSDomain != "" {
+				e["HASURA_GRAPHQL_CORS_DOMAIN"] = fmt.Sprintf("https://%v", *p.cfg.Cloud.CORSDomain)
+			}
 
-	tpl.Resources[HasuraRefListenerRule.Ref()] = &elbv2.ListenerRule{
-		Actions: []elbv2.ListenerRule_Action{
-			{
-				TargetGroupArn: stringz.Ptr(gocf.Ref(HasuraRefTargetGroup.Ref())),
-				Type:           "forward",
-			},
-		},
-		Conditions: []elbv2.ListenerRule_RuleCondition{
-			{
-				Field: stringz.Ptr("host-header"),
-				HostHeaderConfig: &elbv2.ListenerRule_HostHeaderConfig{
-					Values: &[]string{
-						p.cfg.Cloud.DomainName,
-					},
-				},
-			},
-		},
-		ListenerArn: p.deps.LoadBalancer.GetCloudMetadata(true).Exports.GetAtt(LoadBalancerRefListenerHTTPS, LoadBalancerAttListenerArn),
-		Priority:    100,
-	}
-	CloudAddExpRef(tpl, p, HasuraRefListenerRule)
-	CloudAddExpGetAtt(tpl, p, HasuraRefListenerRule, HasuraAttRuleARN)
+			for k, v := range getErrorTrackingEnvironment(p.deps.ErrorTracking) {
+				e[k] = v
+			}
 
-	tpl.Resources[HasuraRefCluster.Ref()] = &goecs.Cluster{
-		ClusterName: stringz.Ptr(HasuraRefCluster.Name(p)),
-		ClusterSettings: &[]goecs.Cluster_ClusterSettings{
-			{
-				Name: stringz.Ptr("containerInsights"),
-				Value: func() *string {
-					if p.cfg.Stage.GetMode().IsProduction() {
-						return stringz.Ptr("enabled")
-					}
-					return stringz.Ptr("disabled")
-				}(),
-			},
-		},
-		Tags: CloudGetDefaultTags(HasuraRefCluster.Name(p)),
-	}
-	CloudAddExpRef(tpl, p, HasuraRefCluster)
-	CloudAddExpGetAtt(tpl, p, HasuraRefCluster, HasuraAttARN)
+			for k, v := range p.cfg.Environment {
+				e[k] = v
+			}
 
-	tpl.Resources[HasuraRefService.Ref()] = &goecs.Service{
-		AWSCloudFormationDependsOn: []string{
-			HasuraRefTargetGroup.Ref(),
-		},
-		Cluster: stringz.Ptr(gocf.Ref(HasuraRefCluster.Ref())),
-		DeploymentController: &goecs.Service_DeploymentController{
-			Type: stringz.Ptr("ECS"),
-		},
-		DeploymentConfiguration: &goecs.Service_DeploymentConfiguration{
-			DeploymentCircuitBreaker: &goecs.Service_DeploymentCircuitBreaker{
-				Enable:   true,
-				Rollback: true,
-			},
-		},
-		DesiredCount: func() *int {
-			if p.cfg.Stage.GetMode().IsProduction() {
-				return intz.Ptr(p.cfg.Cloud.Replicas)
+			for _, secret := range p.getCloudSecrets() {
+				delete(e, secret.Name)
 			}
-			return intz.Ptr(1)
+
+			return e
 		}(),
-		EnableECSManagedTags: boolz.Ptr(true),
-		LaunchType:           stringz.Ptr("FARGATE"),
-		LoadBalancers: &[]goecs.Service_LoadBalancer{
+		Secrets:         p.getCloudSecrets(),
+		HealthCheckPath: "/healthz",
+		Replicas:        p.cfg.Cloud.Replicas,
+		CPU:             p.cfg.Cloud.CPU,
+		Memory:          p.cfg.Cloud.Memory,
+		ScratchVolumes: []*ECSServiceTemplateConfigScratchVolume{
 			{
-				ContainerName:  stringz.Ptr(HasuraRefTaskDefinition.Name(p)),
-				ContainerPort:  intz.Ptr(hasuraCloudPort),
-				TargetGroupArn: stringz.Ptr(gocf.Ref(HasuraRefTargetGroup.Ref())),
+				Name:          "tmp",
+				ContainerPath: "/tmp",
 			},
-		},
-		NetworkConfiguration: &goecs.Service_NetworkConfiguration{
-			AwsvpcConfiguration: &goecs.Service_AwsVpcConfiguration{
-				AssignPublicIp: stringz.Ptr("DISABLED"),
-				SecurityGroups: &[]string{
-					p.deps.Network.GetCloudMetadata(true).Exports.GetRef(NetworkRefSecurityGroup),
-				},
-				Subnets: &[]string{
-					p.deps.Network.GetCloudMetadata(true).Exports.GetRef(NetworkRefSubnetPrivateA),
-					p.deps.Network.GetCloudMetadata(true).Exports.GetRef(NetworkRefSubnetPrivateB),
-				},
+			{
+				Name:          "hasura",
+				ContainerPath: "/root/.hasura",
 			},
 		},
-		PropagateTags:      stringz.Ptr("TASK_DEFINITION"),
-		SchedulingStrategy: stringz.Ptr("REPLICA"),
-		TaskDefinition:     stringz.Ptr(gocf.Ref(HasuraRefTaskDefinition.Ref())),
-		Tags:               CloudGetDefaultTags(HasuraRefService.Name(p)),
-	}
-	CloudAddExpRef(tpl, p, HasuraRefService)
-	CloudAddExpGetAtt(tpl, p, HasuraRefService, HasuraAttName)
+		ReadonlyRootFilesystem: true,
+		DomainName:             p.cfg.Cloud.DomainName,
+		ListenerRulePriority:   p.getListenerRulePriority(),
+		TargetGroup:            p.cfg.Cloud.TargetGroup,
+		ServiceConnect:         p.cfg.Cloud.ServiceConnect,
+		Routing:                p.cfg.Cloud.Routing,
+		Certificate:            p.deps.Certificate,
+		LoadBalancer:           p.deps.LoadBalancer,
+		Network:                p.deps.Network,
+	})
 
-	tpl.Resources[HasuraRefRecordSet.Ref()] = &goroute53.RecordSet{
-		AliasTarget: &goroute53.RecordSet_AliasTarget{
-			DNSName:      p.deps.LoadBalancer.GetCloudMetadata(true).Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttDNSName),
-			HostedZoneId: p.deps.LoadBalancer.GetCloudMetadata(true).Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttCanonicalHostedZoneID),
-		},
-		HostedZoneId: stringz.Ptr(p.deps.Certificate.GetConfig().Cloud.HostedZoneID),
-		Name:         p.cfg.Cloud.DomainName,
-		Type:         "A",
+	tpl.Outputs[hasuraOutputMigrationVersion] = gocf.Output{
+		Value: fmt.Sprintf("%v", p.lintMigrations().LatestVersion),
 	}
-	CloudAddExpRef(tpl, p, HasuraRefRecordSet)
 
 	return tpl
 }
 
 // UpdateCloudMetadata implements the Plugin interface.
 func (p *hasuraImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	for _, output := range stack.Outputs {
+		if *output.OutputKey == hasuraOutputMigrationVersion {
+			p.deployedMigrationVersion = int64(intz.MustParse(*output.OutputValue))
+		}
+	}
+
 	p.cloudMetadata = &HasuraCloudMetadata{
-		Exports: NewCloudExports(stack),
-		URL:     urlz.MustParse(fmt.Sprintf("https://%v/v1/graphql", p.cfg.Cloud.DomainName)),
+		Exports:     NewCloudExports(stack),
+		URL:         urlz.MustParse(fmt.Sprintf("https://%v/v1/graphql", p.cfg.Cloud.DomainName)),
+		JWTIssuer:   p.cfg.JWT.Issuer,
+		JWTAudience: p.cfg.JWT.Audience,
+		JWKURL:      p.cfg.JWT.JWKURL,
 	}
 }
 
@@ -612,8 +532,14 @@ func (p *hasuraImpl) EventHook(event Event, buildDirPath string) {
 		p.localBeforeCreateEventHook(buildDirPath)
 	case LocalAfterCreateEvent:
 		p.localAfterCreateEventHook()
+	case CloudPreflightEvent:
+		p.cloudPreflightEventHook()
 	case CloudBeforeDeployEvent:
 		p.cloudBeforeDeployEventHook(buildDirPath)
+	case CloudAfterDeployEvent:
+		p.cloudAfterDeployEventHook()
+	case CloudBeforeDestroyEvent:
+		p.cloudBeforeDestroyEventHook()
 	}
 
 	if p.cfg.EventHook != nil {
@@ -623,7 +549,7 @@ func (p *hasuraImpl) EventHook(event Event, buildDirPath string) {
 
 func (p *hasuraImpl) localBeforeCreateEventHook(buildDirPath string) {
 	filez.MustPrepareDir(buildDirPath, 0777)
-	cfgDirPath := p.cfg.Stage.GetConfig().App.GetConfig().GetConfigDirPathForPlugin(p, hasuraConfigDirParts...)
+	cfgDirPath := p.getConfigDirPath()
 
 	if !filez.MustCheckExists(cfgDirPath) {
 		filez.MustCopyEmbedFSSimple(
@@ -648,7 +574,8 @@ func (p *hasuraImpl) localBeforeCreateEventHook(buildDirPath string) {
 		templatez.MustParseAndExecuteText(
 			assets.HasuraConsoleDockerfileTemplateAsset,
 			assets.HasuraConsoleDockerfileTemplateData{
-				Version:     hasuraVersion,
+				BaseImage:   LocalGetImage(p, "debian:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Debian),
+				Version:     p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Hasura,
 				Port:        p.cfg.Local.ExternalPort,
 				AdminSecret: LocalSecret,
 			}))
@@ -656,34 +583,87 @@ func (p *hasuraImpl) localBeforeCreateEventHook(buildDirPath string) {
 
 func (p *hasuraImpl) localAfterCreateEventHook() {
 	// TODO(ibrt): Use a waiter instead.
-	time.Sleep(30 * time.Second)
+	opz.Sleep(p.cfg.Stage.GetConfig().App.GetOperations().GetContext(), 30*time.Second)
 	p.ApplyLocalMetadata()
 }
 
+// snapshotLocal implements the localSnapshotter interface.
+func (p *hasuraImpl) snapshotLocal(dirPath string) {
+	metadata := p.cfg.Stage.GetConfig().App.GetOperations().ExportHasuraMetadata(
+		p.localMetadata.ExternalURL.String(), p.localMetadata.AdminSecret)
+	filez.MustWriteFile(filepath.Join(dirPath, hasuraSnapshotFileName), 0777, 0666, metadata)
+}
+
+// restoreLocal implements the localSnapshotter interface.
+func (p *hasuraImpl) restoreLocal(dirPath string) {
+	p.cfg.Stage.GetConfig().App.GetOperations().ReplaceHasuraMetadata(
+		p.localMetadata.ExternalURL.String(), p.localMetadata.AdminSecret,
+		filez.MustReadFile(filepath.Join(dirPath, hasuraSnapshotFileName)))
+}
+
+func (p *hasuraImpl) cloudPreflightEventHook() {
+	CloudMustCheckDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName, p.cfg.Cloud.Routing)
+
+	p.cloudLintMigrations()
+}
+
+func (p *hasuraImpl) cloudLintMigrations() {
+	report := p.lintMigrations()
+
+	if len(report.Issues) > 0 && p.cfg.Stage.GetMode().IsProduction() && !p.cfg.Cloud.AllowDestructiveMigrations {
+		details := make([]string, 0, len(report.Issues))
+		for _, issue := range report.Issues {
+			details = append(details, issue.String())
+		}
+
+		MustApproveDestructiveAction(p.cfg.Stage, &DestructiveAction{
+			Plugin:      p,
+			Description: "potentially destructive migrations found (set HasuraConfigCloud.AllowDestructiveMigrations to deploy them anyway)",
+			Details:     details,
+		})
+	}
+}
+
+func (p *hasuraImpl) lintMigrations() *opz.MigrationLintReport {
+	return p.cfg.Stage.GetConfig().App.GetOperations().LintPostgresHasuraMigrations(
+		os.DirFS(p.getConfigDirPath()), "migrations", p.deployedMigrationVersion)
+}
+
+func (p *hasuraImpl) cloudAfterDeployEventHook() {
+	CloudMaybeUpsertDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName,
+		p.deps.LoadBalancer.GetCloudMetadata(true).Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttDNSName))
+}
+
+func (p *hasuraImpl) cloudBeforeDestroyEventHook() {
+	CloudMaybeDeleteDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName,
+		p.deps.LoadBalancer.GetCloudMetadata(true).Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttDNSName))
+}
+
 func (p *hasuraImpl) cloudBeforeDeployEventHook(buildDirPath string) {
 	filez.MustPrepareDir(buildDirPath, 0777)
 
-	imageWithTag := p.deps.ImageRepository.GetCloudMetadata(true).ImageName + ":" + p.cfg.Stage.AsCloudStage().GetCloudConfig().Version
-	cfgDirPath := p.cfg.Stage.GetConfig().App.GetConfig().GetConfigDirPathForPlugin(p, hasuraConfigDirParts...)
+	imageWithTag := p.getImageWithTag()
+	cfgDirPath := p.getConfigDirPath()
 
 	filez.MustWriteFile(
 		filepath.Join(buildDirPath, "Dockerfile"), 0777, 0666,
 		templatez.MustParseAndExecuteText(
 			assets.HasuraDockerfileTemplateAsset,
 			assets.HasuraDockerfileTemplateData{
-				Version: hasuraVersion,
+				BaseImage: LocalGetImage(p,
+					fmt.Sprintf("hasura/graphql-engine:v%v.cli-migrations-v3", p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Hasura)),
 			}))
 
-	shellz.NewCommand("cp", "-R", filepath.Join(cfgDirPath, "metadata"), filepath.Join(buildDirPath, "hasura-metadata")).MustRun()
-	shellz.NewCommand("cp", "-R", filepath.Join(cfgDirPath, "migrations"), filepath.Join(buildDirPath, "hasura-migrations")).MustRun()
-	shellz.NewCommand("docker", "build", "--no-cache", "-t", imageWithTag, ".").SetDir(buildDirPath).MustRun()
+	opz.MustCopyDir(filepath.Join(cfgDirPath, "metadata"), filepath.Join(buildDirPath, "hasura-metadata"))
+	opz.MustCopyDir(filepath.Join(cfgDirPath, "migrations"), filepath.Join(buildDirPath, "hasura-migrations"))
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker", "build", "--no-cache", "-t", imageWithTag, ".").SetDir(buildDirPath).MustRun()
 
 	p.cfg.Stage.GetConfig().App.GetOperations().DockerLoginToECR()
-	shellz.NewCommand("docker", "push", imageWithTag).MustRun()
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker", "push", imageWithTag).MustRun()
 }
 
 func (p *hasuraImpl) runCmd(params ...interface{}) {
-	shellz.NewCommand("docker").
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker").
 		AddParams("exec").
 		AddParams("-t").
 		AddParams(p.GetLocalMetadata().ConsoleContainerName).
@@ -691,3 +671,51 @@ func (p *hasuraImpl) runCmd(params ...interface{}) {
 		AddParams(params...).
 		MustRun()
 }
+
+func (p *hasuraImpl) getCloudSecrets() []*ECSServiceTemplateConfigSecret {
+	if p.deps.RuntimeSecrets == nil {
+		return nil
+	}
+
+	secrets := make([]*ECSServiceTemplateConfigSecret, 0)
+
+	if p.deps.RuntimeSecrets.HasValue(RuntimeSecretsKeyHasuraAdminSecret) {
+		errorz.Assertf(p.deps.RuntimeSecrets.GetValue(RuntimeSecretsKeyHasuraAdminSecret) == p.cfg.Cloud.AdminSecret,
+			"runtime secret %v does not match HasuraConfigCloud.AdminSecret", errorz.A(RuntimeSecretsKeyHasuraAdminSecret),
+			errorz.Prefix(HasuraPluginName))
+		secrets = append(secrets, p.deps.RuntimeSecrets.GetECSSecret("HASURA_GRAPHQL_ADMIN_SECRET", RuntimeSecretsKeyHasuraAdminSecret))
+	}
+
+	if p.deps.RuntimeSecrets.HasValue(RuntimeSecretsKeyPostgresURL) {
+		secrets = append(secrets, p.deps.RuntimeSecrets.GetECSSecret("HASURA_GRAPHQL_DATABASE_URL", RuntimeSecretsKeyPostgresURL))
+	}
+
+	return secrets
+}
+
+// getConfigDirPath returns the config dir path, which is shared by all instances so that they have the same schema.
+func (p *hasuraImpl) getConfigDirPath() string {
+	return p.cfg.Stage.GetConfig().App.GetConfig().GetConfigDirPath(append([]string{HasuraPluginName}, hasuraConfigDirParts...)...)
+}
+
+func (p *hasuraImpl) getImageWithTag() string {
+	tag := p.cfg.Stage.AsCloudStage().GetCloudConfig().Version
+	if p.cfg.InstanceName != "" {
+		tag += "-" + p.cfg.InstanceName
+	}
+	return p.deps.ImageRepository.GetCloudMetadata(true).ImageName + ":" + tag
+}
+
+func (p *hasuraImpl) getListenerRulePriority() int {
+	if p.cfg.Cloud.ListenerRulePriority != 0 {
+		return p.cfg.Cloud.ListenerRulePriority
+	}
+	return hasuraListenerRulePriority
+}
+
+func (p *hasuraImpl) getWebSocketKeepAliveSeconds() int {
+	if p.cfg.Cloud.WebSocketKeepAliveSeconds != 0 {
+		return p.cfg.Cloud.WebSocketKeepAliveSeconds
+	}
+	return hasuraWebSocketKeepAlive
+}
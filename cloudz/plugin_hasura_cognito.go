@@ -0,0 +1,50 @@
+package cloudz
+
+import (
+	"fmt"
+
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// HasuraCognitoConfig describes a Cognito user pool used to authenticate Hasura requests.
+type HasuraCognitoConfig struct {
+	Region      string `validate:"required"`
+	UserPoolID  string `validate:"required"`
+	ClientID    string `validate:"required"`
+	DefaultRole string `validate:"required"`
+}
+
+// MustValidate validates the hasura cognito config.
+func (c *HasuraCognitoConfig) MustValidate() {
+	vz.MustValidateStruct(c)
+}
+
+// NewHasuraConfigJWTForCognito returns a HasuraConfigJWT that accepts ID tokens issued by the given Cognito user pool.
+//
+// Cognito groups are mapped to Hasura roles by name: the allowed roles are taken from the "cognito:groups" claim, and the
+// default role is the group with the highest precedence (i.e. the first one in the claim). Users that don't belong to
+// any group are given the default role. The user ID is taken from the "sub" claim.
+func NewHasuraConfigJWTForCognito(cfg *HasuraCognitoConfig) *HasuraConfigJWT {
+	cfg.MustValidate()
+
+	issuer := fmt.Sprintf("https://cognito-idp.%v.amazonaws.com/%v", cfg.Region, cfg.UserPoolID)
+
+	return &HasuraConfigJWT{
+		JWKURL:   issuer + "/.well-known/jwks.json",
+		Issuer:   issuer,
+		Audience: cfg.ClientID,
+		ClaimsMap: map[string]interface{}{
+			"x-hasura-user-id": map[string]interface{}{
+				"path": "$.sub",
+			},
+			"x-hasura-allowed-roles": map[string]interface{}{
+				"path":    "$.cognito:groups",
+				"default": []string{cfg.DefaultRole},
+			},
+			"x-hasura-default-role": map[string]interface{}{
+				"path":    "$.cognito:groups[0]",
+				"default": cfg.DefaultRole,
+			},
+		},
+	}
+}
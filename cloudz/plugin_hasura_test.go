@@ -0,0 +1,90 @@
+package cloudz
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/ibrt/golang-bites/rsaz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasuraConfigJWT_GetSecret(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		cfg      *HasuraConfigJWT
+		expected map[string]interface{}
+	}{
+		{
+			name: "public key",
+			cfg: &HasuraConfigJWT{
+				PublicKey: &privateKey.PublicKey,
+				Issuer:    "issuer",
+				Audience:  "audience",
+			},
+			expected: map[string]interface{}{
+				"type":     "RS256",
+				"key":      string(rsaz.RSAPublicKeyToPEM(&privateKey.PublicKey)),
+				"issuer":   "issuer",
+				"audience": "audience",
+			},
+		},
+		{
+			name: "jwk url with claims namespace",
+			cfg: &HasuraConfigJWT{
+				JWKURL:          "https://example.com/jwks.json",
+				Issuer:          "issuer",
+				Audience:        "audience",
+				ClaimsNamespace: stringz.Ptr("https://example.com/claims"),
+			},
+			expected: map[string]interface{}{
+				"jwk_url":          "https://example.com/jwks.json",
+				"issuer":           "issuer",
+				"audience":         "audience",
+				"claims_namespace": "https://example.com/claims",
+			},
+		},
+		{
+			name: "cognito",
+			cfg: NewHasuraConfigJWTForCognito(&HasuraCognitoConfig{
+				Region:      "us-east-1",
+				UserPoolID:  "us-east-1_abc",
+				ClientID:    "client-id",
+				DefaultRole: "user",
+			}),
+			expected: map[string]interface{}{
+				"jwk_url":  "https://cognito-idp.us-east-1.amazonaws.com/us-east-1_abc/.well-known/jwks.json",
+				"issuer":   "https://cognito-idp.us-east-1.amazonaws.com/us-east-1_abc",
+				"audience": "client-id",
+				"claims_map": map[string]interface{}{
+					"x-hasura-user-id": map[string]interface{}{
+						"path": "$.sub",
+					},
+					"x-hasura-allowed-roles": map[string]interface{}{
+						"path":    "$.cognito:groups",
+						"default": []interface{}{"user"},
+					},
+					"x-hasura-default-role": map[string]interface{}{
+						"path":    "$.cognito:groups[0]",
+						"default": "user",
+					},
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			secret := map[string]interface{}{}
+			require.NoError(t, json.Unmarshal([]byte(testCase.cfg.GetSecret()), &secret))
+			require.Equal(t, testCase.expected, secret)
+		})
+	}
+}
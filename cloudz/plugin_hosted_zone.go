@@ -0,0 +1,288 @@
+package cloudz
+
+import (
+	"strings"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goroute53 "github.com/awslabs/goformation/v6/cloudformation/route53"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// HostedZone constants.
+const (
+	HostedZonePluginDisplayName = "HostedZone"
+	HostedZonePluginName        = "hosted-zone"
+	HostedZoneRefHostedZone     = CloudRef("hz")
+	HostedZoneAttNameServers    = CloudAtt("NameServers")
+)
+
+var (
+	_ HostedZone  = &hostedZoneImpl{}
+	_ Plugin      = &hostedZoneImpl{}
+	_ DNSProvider = &hostedZoneDNSProviderImpl{}
+)
+
+// HostedZoneConfigFunc returns the hosted zone config for a given Stage.
+type HostedZoneConfigFunc func(Stage, *HostedZoneDependencies) *HostedZoneConfig
+
+// HostedZoneEventHookFunc describes a hosted zone event hook.
+type HostedZoneEventHookFunc func(HostedZone, Event, string)
+
+// HostedZoneConfig describes the hosted zone config.
+//
+// The hosted zone can be used as DNSProvider by the Certificate plugin (see HostedZone.GetDNSProvider), which is then
+// used by the plugins depending on the certificate to create their record sets. When it is first created, the name
+// servers of the hosted zone must be configured at the registrar or parent zone (see HostedZoneCloudMetadata).
+type HostedZoneConfig struct {
+	Stage      Stage  `validate:"required"`
+	Name       string `validate:"required,resource-name"`
+	DomainName string `validate:"required,fqdn"`
+	Cloud      *HostedZoneConfigCloud
+	EventHook  HostedZoneEventHookFunc
+}
+
+// MustValidate validates the hosted zone config.
+func (c *HostedZoneConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing HostedZoneConfig.Cloud")
+}
+
+// HostedZoneConfigCloud describes part of the hosted zone config.
+// If ImportHostedZoneID is set, the existing hosted zone with the given ID is used instead of creating a new one.
+type HostedZoneConfigCloud struct {
+	ImportHostedZoneID string
+}
+
+// HostedZoneDependencies describes the hosted zone dependencies.
+type HostedZoneDependencies struct {
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the hosted zone dependencies.
+func (d *HostedZoneDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// HostedZoneCloudMetadata describes the hosted zone cloud metadata.
+// Exports is nil if the hosted zone is imported, in which case NameServers are only populated during preflight.
+type HostedZoneCloudMetadata struct {
+	Exports      CloudExports
+	HostedZoneID string
+	NameServers  []string
+}
+
+// GetNameServers returns the comma-separated name servers of the hosted zone.
+func (m *HostedZoneCloudMetadata) GetNameServers() string {
+	return strings.Join(m.NameServers, ",")
+}
+
+// HostedZone describes a hosted zone.
+type HostedZone interface {
+	Plugin
+	GetConfig() *HostedZoneConfig
+	GetCloudMetadata(require bool) *HostedZoneCloudMetadata
+	GetDNSProvider() DNSProvider
+}
+
+type hostedZoneImpl struct {
+	cfgFunc       HostedZoneConfigFunc
+	deps          *HostedZoneDependencies
+	cfg           *HostedZoneConfig
+	cloudMetadata *HostedZoneCloudMetadata
+}
+
+// NewHostedZone initializes a new HostedZone.
+func NewHostedZone(cfgFunc HostedZoneConfigFunc, deps *HostedZoneDependencies) HostedZone {
+	deps.MustValidate()
+
+	return &hostedZoneImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*hostedZoneImpl) GetDisplayName() string {
+	return HostedZonePluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *hostedZoneImpl) GetName() string {
+	return HostedZonePluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *hostedZoneImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *hostedZoneImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{}
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *hostedZoneImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+
+	if stage.GetTarget() == Cloud && p.isImported() && p.cloudMetadata == nil {
+		p.cloudMetadata = &HostedZoneCloudMetadata{
+			HostedZoneID: p.cfg.Cloud.ImportHostedZoneID,
+		}
+	}
+}
+
+// GetStage implements the Plugin interface.
+func (p *hostedZoneImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(HostedZonePluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the HostedZone interface.
+func (p *hostedZoneImpl) GetConfig() *HostedZoneConfig {
+	return p.cfg
+}
+
+// GetCloudMetadata implements the HostedZone interface.
+func (p *hostedZoneImpl) GetCloudMetadata(require bool) *HostedZoneCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(HostedZonePluginName))
+	return p.cloudMetadata
+}
+
+// GetDNSProvider implements the HostedZone interface.
+func (p *hostedZoneImpl) GetDNSProvider() DNSProvider {
+	return &hostedZoneDNSProviderImpl{
+		hostedZone: p,
+	}
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *hostedZoneImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (*hostedZoneImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
+	// intentionally empty
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *hostedZoneImpl) GetCloudTemplate(_ string) *gocf.Template {
+	if p.isImported() {
+		return nil
+	}
+
+	tpl := gocf.NewTemplate()
+
+	tpl.Resources[HostedZoneRefHostedZone.Ref()] = &goroute53.HostedZone{
+		HostedZoneConfig: &goroute53.HostedZone_HostedZoneConfig{
+			Comment: stringz.Ptr(HostedZoneRefHostedZone.Name(p)),
+		},
+		HostedZoneTags: &[]goroute53.HostedZone_HostedZoneTag{
+			{
+				Key:   "Name",
+				Value: HostedZoneRefHostedZone.Name(p),
+			},
+		},
+		Name: stringz.Ptr(p.cfg.DomainName),
+	}
+	CloudAddExpRef(tpl, p, HostedZoneRefHostedZone)
+
+	// Note: the name servers attribute is a list, exported as a comma-separated string.
+	tpl.Outputs[HostedZoneRefHostedZone.ExpAttRef(HostedZoneAttNameServers)] = gocf.Output{
+		Value: gocf.Join(",", gocf.GetAtt(HostedZoneRefHostedZone.Ref(), HostedZoneAttNameServers.Ref())),
+		Export: &gocf.Export{
+			Name: HostedZoneRefHostedZone.ExpAttName(p, HostedZoneAttNameServers),
+		},
+	}
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *hostedZoneImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+
+	p.cloudMetadata = &HostedZoneCloudMetadata{
+		Exports:      exports,
+		HostedZoneID: exports.GetRef(HostedZoneRefHostedZone),
+		NameServers:  strings.Split(exports.GetAtt(HostedZoneRefHostedZone, HostedZoneAttNameServers), ","),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *hostedZoneImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case CloudPreflightEvent:
+		p.cloudPreflightEventHook()
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *hostedZoneImpl) cloudPreflightEventHook() {
+	ops := p.cfg.Stage.GetConfig().App.GetOperations()
+	if !p.isImported() || ops.IsOffline() {
+		return
+	}
+
+	hostedZone := ops.GetHostedZone(p.cfg.Cloud.ImportHostedZoneID)
+	errorz.Assertf(hostedZone != nil, "hosted zone %v does not exist",
+		errorz.A(p.cfg.Cloud.ImportHostedZoneID), errorz.Prefix(HostedZonePluginName))
+
+	if hostedZone.DelegationSet != nil {
+		p.cloudMetadata.NameServers = hostedZone.DelegationSet.NameServers
+	}
+}
+
+func (p *hostedZoneImpl) isImported() bool {
+	return p.cfg.Cloud != nil && p.cfg.Cloud.ImportHostedZoneID != ""
+}
+
+type hostedZoneDNSProviderImpl struct {
+	hostedZone HostedZone
+}
+
+// IsRoute53 implements the DNSProvider interface.
+func (*hostedZoneDNSProviderImpl) IsRoute53() bool {
+	return true
+}
+
+// GetHostedZoneID implements the DNSProvider interface.
+// Note: the hosted zone must be deployed, which is the case when rendering the templates of the plugins depending on it.
+func (d *hostedZoneDNSProviderImpl) GetHostedZoneID() *string {
+	return stringz.Ptr(d.hostedZone.GetCloudMetadata(true).HostedZoneID)
+}
+
+// MustCheckDomain implements the DNSProvider interface.
+func (d *hostedZoneDNSProviderImpl) MustCheckDomain(p Plugin, domainName string) {
+	if cloudMetadata := d.hostedZone.GetCloudMetadata(false); cloudMetadata != nil {
+		CloudMustCheckHostedZone(p, cloudMetadata.HostedZoneID, domainName)
+		return
+	}
+
+	zoneName := d.hostedZone.GetConfig().DomainName
+	errorz.Assertf(domainName == zoneName || strings.HasSuffix(domainName, "."+zoneName), "domain %v does not belong to hosted zone %v",
+		errorz.A(domainName, zoneName), errorz.Prefix(p.GetName()))
+}
+
+// UpsertRecord implements the DNSProvider interface.
+func (d *hostedZoneDNSProviderImpl) UpsertRecord(p Plugin, record *DNSRecord) {
+	p.GetStage().GetConfig().App.GetOperations().UpsertRecordSet(*d.GetHostedZoneID(), record.Name, record.Type, record.Value, record.TTL)
+}
+
+// DeleteRecord implements the DNSProvider interface.
+func (d *hostedZoneDNSProviderImpl) DeleteRecord(p Plugin, record *DNSRecord) {
+	p.GetStage().GetConfig().App.GetOperations().DeleteRecordSet(*d.GetHostedZoneID(), record.Name, record.Type, record.Value, record.TTL)
+}
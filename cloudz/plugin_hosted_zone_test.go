@@ -0,0 +1,75 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostedZoneDNSProvider_GetHostedZoneID(t *testing.T) {
+	p := &hostedZoneImpl{
+		cfg: &HostedZoneConfig{
+			Name:       "main",
+			DomainName: "example.com",
+		},
+	}
+
+	d := p.GetDNSProvider()
+	require.True(t, d.IsRoute53())
+	require.Panics(t, func() { d.GetHostedZoneID() })
+
+	p.cloudMetadata = &HostedZoneCloudMetadata{
+		HostedZoneID: "Z123",
+	}
+	require.Equal(t, "Z123", *d.GetHostedZoneID())
+}
+
+func TestHostedZoneDNSProvider_MustCheckDomain(t *testing.T) {
+	testCases := []struct {
+		name       string
+		domainName string
+		isValid    bool
+	}{
+		{
+			name:       "apex",
+			domainName: "example.com",
+			isValid:    true,
+		},
+		{
+			name:       "subdomain",
+			domainName: "api.example.com",
+			isValid:    true,
+		},
+		{
+			name:       "other zone",
+			domainName: "api.example.org",
+			isValid:    false,
+		},
+		{
+			name:       "suffix without dot",
+			domainName: "notexample.com",
+			isValid:    false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			d := (&hostedZoneImpl{
+				cfg: &HostedZoneConfig{
+					Name:       "main",
+					DomainName: "example.com",
+				},
+			}).GetDNSProvider()
+
+			p := &testPlugin{name: "test"}
+
+			if testCase.isValid {
+				require.NotPanics(t, func() { d.MustCheckDomain(p, testCase.domainName) })
+			} else {
+				require.Panics(t, func() { d.MustCheckDomain(p, testCase.domainName) })
+			}
+		})
+	}
+}
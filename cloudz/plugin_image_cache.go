@@ -0,0 +1,392 @@
+package cloudz
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// ImageCache constants.
+const (
+	ImageCachePluginDisplayName = "ImageCache"
+	ImageCachePluginName        = "image-cache"
+	ImageCacheAttRegistryPrefix = CloudAtt("RegistryPrefix")
+)
+
+// Known image cache upstreams.
+const (
+	ImageCacheUpstreamDockerHub ImageCacheUpstream = "docker-hub"
+	ImageCacheUpstreamECRPublic ImageCacheUpstream = "ecr-public"
+	ImageCacheUpstreamGHCR      ImageCacheUpstream = "ghcr"
+)
+
+var (
+	_ ImageCache = &imageCacheImpl{}
+	_ Plugin     = &imageCacheImpl{}
+)
+
+var (
+	imageCacheRepositoryPrefixRegexp = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*$`)
+
+	imageCacheUpstreamRegistries = map[ImageCacheUpstream]string{
+		ImageCacheUpstreamDockerHub: "docker-hub",
+		ImageCacheUpstreamECRPublic: "ecr-public",
+		ImageCacheUpstreamGHCR:      "github-container-registry",
+	}
+
+	imageCacheUpstreamRegistryURLs = map[ImageCacheUpstream]string{
+		ImageCacheUpstreamDockerHub: "registry-1.docker.io",
+		ImageCacheUpstreamECRPublic: "public.ecr.aws",
+		ImageCacheUpstreamGHCR:      "ghcr.io",
+	}
+
+	imageCacheUpstreamLocalRegistryPrefixes = map[ImageCacheUpstream]string{
+		ImageCacheUpstreamDockerHub: "docker.io",
+		ImageCacheUpstreamECRPublic: "public.ecr.aws",
+		ImageCacheUpstreamGHCR:      "ghcr.io",
+	}
+)
+
+// ImageCacheUpstream describes an upstream registry supported by ECR pull-through cache rules.
+type ImageCacheUpstream string
+
+// isCredentialRequired returns true if the upstream requires credentials.
+func (u ImageCacheUpstream) isCredentialRequired() bool {
+	return u != ImageCacheUpstreamECRPublic
+}
+
+// getRef returns the pull-through cache rule ref.
+func (u ImageCacheUpstream) getRef() CloudRef {
+	return CloudRef("r-" + string(u))
+}
+
+// ImageCacheRegistryPrefixes maps upstream registries to the registry prefixes to pull their images from.
+type ImageCacheRegistryPrefixes map[ImageCacheUpstream]string
+
+// GetImageName returns the name to pull the given upstream image from, e.g. "postgres:14" or "hasura/graphql-engine".
+// Docker Hub official images are qualified with "library/", which the cache requires.
+func (r ImageCacheRegistryPrefixes) GetImageName(upstream ImageCacheUpstream, image string) string {
+	registryPrefix, ok := r[upstream]
+	errorz.Assertf(ok, "upstream not configured: %v", errorz.A(upstream), errorz.Prefix(ImageCachePluginName))
+
+	if upstream == ImageCacheUpstreamDockerHub && !strings.Contains(image, "/") {
+		image = "library/" + image
+	}
+
+	return registryPrefix + "/" + image
+}
+
+// ImageCacheConfigFunc returns the image cache config for a given Stage.
+type ImageCacheConfigFunc func(Stage, *ImageCacheDependencies) *ImageCacheConfig
+
+// ImageCacheEventHookFunc describes an image cache event hook.
+type ImageCacheEventHookFunc func(ImageCache, Event, string)
+
+// ImageCacheConfig describes the image cache config.
+type ImageCacheConfig struct {
+	Stage     Stage                `validate:"required"`
+	Name      string               `validate:"required,resource-name"`
+	Upstreams []ImageCacheUpstream `validate:"required,min=1,dive,oneof=docker-hub ecr-public ghcr"`
+	Cloud     *ImageCacheConfigCloud
+	EventHook ImageCacheEventHookFunc
+}
+
+// MustValidate validates the image cache config.
+func (c *ImageCacheConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing ImageCacheConfig.Cloud", errorz.Prefix(ImageCachePluginName))
+
+	upstreams := map[ImageCacheUpstream]struct{}{}
+	for _, upstream := range c.Upstreams {
+		_, ok := upstreams[upstream]
+		errorz.Assertf(!ok, "duplicate upstream: %v", errorz.A(upstream), errorz.Prefix(ImageCachePluginName))
+		upstreams[upstream] = struct{}{}
+	}
+
+	if stageTarget == Cloud {
+		for _, upstream := range c.Upstreams {
+			_, ok := c.Cloud.CredentialSecretARNs[upstream]
+			errorz.Assertf(ok || !upstream.isCredentialRequired(), "missing credential secret for upstream: %v", errorz.A(upstream), errorz.Prefix(ImageCachePluginName))
+			errorz.Assertf(!ok || upstream.isCredentialRequired(), "unexpected credential secret for upstream: %v", errorz.A(upstream), errorz.Prefix(ImageCachePluginName))
+		}
+
+		for upstream := range c.Cloud.CredentialSecretARNs {
+			_, ok := upstreams[upstream]
+			errorz.Assertf(ok, "credential secret for unknown upstream: %v", errorz.A(upstream), errorz.Prefix(ImageCachePluginName))
+		}
+
+		errorz.Assertf(c.Cloud.RepositoryPrefix == "" || imageCacheRepositoryPrefixRegexp.MatchString(c.Cloud.RepositoryPrefix),
+			"invalid repository prefix: %v", errorz.A(c.Cloud.RepositoryPrefix), errorz.Prefix(ImageCachePluginName))
+	}
+}
+
+// ImageCacheConfigCloud describes part of the image cache config.
+//
+// Pull-through cache rules apply to the whole registry, i.e. to the account and region, so each repository prefix can
+// only be used by one stage per account and region. Each rule uses the prefix "<RepositoryPrefix>-<upstream>", where
+// RepositoryPrefix defaults to the app name, and must be at most 30 characters long in total. Docker Hub and GHCR
+// require credentials, as the ARN of a Secrets Manager secret whose name starts with "ecr-pullthroughcache/" and whose
+// value is a JSON object with "username" and "accessToken" keys. The secrets are not managed by this plugin, so
+// tokens never end up in templates.
+type ImageCacheConfigCloud struct {
+	RepositoryPrefix     string
+	CredentialSecretARNs map[ImageCacheUpstream]string
+}
+
+// ImageCacheDependencies describes the image cache dependencies.
+type ImageCacheDependencies struct {
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the image cache dependencies.
+func (d *ImageCacheDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// ImageCacheLocalMetadata describes the image cache local metadata.
+// Images are pulled directly from the upstream registries.
+type ImageCacheLocalMetadata struct {
+	RegistryPrefixes ImageCacheRegistryPrefixes
+}
+
+// GetDockerHubRegistryPrefix returns the Docker Hub registry prefix, if configured.
+func (m *ImageCacheLocalMetadata) GetDockerHubRegistryPrefix() string {
+	return m.RegistryPrefixes[ImageCacheUpstreamDockerHub]
+}
+
+// GetECRPublicRegistryPrefix returns the ECR Public registry prefix, if configured.
+func (m *ImageCacheLocalMetadata) GetECRPublicRegistryPrefix() string {
+	return m.RegistryPrefixes[ImageCacheUpstreamECRPublic]
+}
+
+// GetGHCRRegistryPrefix returns the GHCR registry prefix, if configured.
+func (m *ImageCacheLocalMetadata) GetGHCRRegistryPrefix() string {
+	return m.RegistryPrefixes[ImageCacheUpstreamGHCR]
+}
+
+// ImageCacheCloudMetadata describes the image cache cloud metadata.
+// Images are pulled through the ECR cache, e.g. "<account>.dkr.ecr.<region>.amazonaws.com/<app>-docker-hub".
+type ImageCacheCloudMetadata struct {
+	Exports          CloudExports
+	RegistryPrefixes ImageCacheRegistryPrefixes
+}
+
+// GetDockerHubRegistryPrefix returns the Docker Hub registry prefix, if configured.
+func (m *ImageCacheCloudMetadata) GetDockerHubRegistryPrefix() string {
+	return m.RegistryPrefixes[ImageCacheUpstreamDockerHub]
+}
+
+// GetECRPublicRegistryPrefix returns the ECR Public registry prefix, if configured.
+func (m *ImageCacheCloudMetadata) GetECRPublicRegistryPrefix() string {
+	return m.RegistryPrefixes[ImageCacheUpstreamECRPublic]
+}
+
+// GetGHCRRegistryPrefix returns the GHCR registry prefix, if configured.
+func (m *ImageCacheCloudMetadata) GetGHCRRegistryPrefix() string {
+	return m.RegistryPrefixes[ImageCacheUpstreamGHCR]
+}
+
+// ImageCache describes a set of ECR pull-through cache rules, which mirror images from upstream registries (e.g. to
+// avoid Docker Hub rate limits). Tasks pulling through the cache need the GetPullRolePolicy policy on their execution
+// role (see ECSServiceTemplateConfig.ExecutionRolePolicies), since the first pull of an image creates its repository.
+type ImageCache interface {
+	Plugin
+	GetConfig() *ImageCacheConfig
+	GetLocalMetadata() *ImageCacheLocalMetadata
+	GetCloudMetadata(require bool) *ImageCacheCloudMetadata
+	GetImageName(upstream ImageCacheUpstream, image string) string
+	GetPullRolePolicy() goiam.Role_Policy
+}
+
+type imageCacheImpl struct {
+	cfgFunc       ImageCacheConfigFunc
+	deps          *ImageCacheDependencies
+	cfg           *ImageCacheConfig
+	localMetadata *ImageCacheLocalMetadata
+	cloudMetadata *ImageCacheCloudMetadata
+}
+
+// NewImageCache initializes a new ImageCache.
+func NewImageCache(cfgFunc ImageCacheConfigFunc, deps *ImageCacheDependencies) ImageCache {
+	deps.MustValidate()
+
+	return &imageCacheImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*imageCacheImpl) GetDisplayName() string {
+	return ImageCachePluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *imageCacheImpl) GetName() string {
+	return ImageCachePluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *imageCacheImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *imageCacheImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{}
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *imageCacheImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+
+	if stage.GetTarget() == Cloud {
+		for _, upstream := range p.cfg.Upstreams {
+			repositoryPrefix := p.getRepositoryPrefix(upstream)
+			errorz.Assertf(len(repositoryPrefix) <= 30, "repository prefix too long: %v", errorz.A(repositoryPrefix), errorz.Prefix(ImageCachePluginName))
+		}
+	}
+}
+
+// GetStage implements the Plugin interface.
+func (p *imageCacheImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(ImageCachePluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the ImageCache interface.
+func (p *imageCacheImpl) GetConfig() *ImageCacheConfig {
+	return p.cfg
+}
+
+// GetLocalMetadata implements the ImageCache interface.
+func (p *imageCacheImpl) GetLocalMetadata() *ImageCacheLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(ImageCachePluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the ImageCache interface.
+func (p *imageCacheImpl) GetCloudMetadata(require bool) *ImageCacheCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(ImageCachePluginName))
+	return p.cloudMetadata
+}
+
+// GetImageName implements the ImageCache interface.
+func (p *imageCacheImpl) GetImageName(upstream ImageCacheUpstream, image string) string {
+	if p.cfg.Stage.GetTarget() == Local {
+		return p.GetLocalMetadata().RegistryPrefixes.GetImageName(upstream, image)
+	}
+	return p.GetCloudMetadata(true).RegistryPrefixes.GetImageName(upstream, image)
+}
+
+// GetPullRolePolicy implements the ImageCache interface.
+func (p *imageCacheImpl) GetPullRolePolicy() goiam.Role_Policy {
+	repositoryARNs := make([]string, 0, len(p.cfg.Upstreams))
+	for _, upstream := range p.cfg.Upstreams {
+		repositoryARNs = append(repositoryARNs,
+			gocf.Sub(fmt.Sprintf("arn:${AWS::Partition}:ecr:${AWS::Region}:${AWS::AccountId}:repository/%v/*", p.getRepositoryPrefix(upstream))))
+	}
+
+	return goiam.Role_Policy{
+		PolicyName: ImageCachePluginName + "-pull",
+		PolicyDocument: NewPolicyDocument(
+			NewPolicyStatement().
+				AddActions("ecr:BatchImportUpstreamImage", "ecr:CreateRepository").
+				AddResources(repositoryARNs...)),
+	}
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *imageCacheImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *imageCacheImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
+	registryPrefixes := ImageCacheRegistryPrefixes{}
+	for _, upstream := range p.cfg.Upstreams {
+		registryPrefixes[upstream] = imageCacheUpstreamLocalRegistryPrefixes[upstream]
+	}
+
+	p.localMetadata = &ImageCacheLocalMetadata{
+		RegistryPrefixes: registryPrefixes,
+	}
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *imageCacheImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	for _, upstream := range p.cfg.Upstreams {
+		repositoryPrefix := p.getRepositoryPrefix(upstream)
+
+		properties := map[string]interface{}{
+			"EcrRepositoryPrefix": repositoryPrefix,
+			"UpstreamRegistry":    imageCacheUpstreamRegistries[upstream],
+			"UpstreamRegistryUrl": imageCacheUpstreamRegistryURLs[upstream],
+		}
+
+		if credentialSecretARN, ok := p.cfg.Cloud.CredentialSecretARNs[upstream]; ok {
+			properties["CredentialArn"] = credentialSecretARN
+		}
+
+		// Note: goformation does not support UpstreamRegistry and CredentialArn yet.
+		tpl.Resources[upstream.getRef().Ref()] = &gocf.CustomResource{
+			Type:       "AWS::ECR::PullThroughCacheRule",
+			Properties: properties,
+		}
+		CloudAddExpRef(tpl, p, upstream.getRef())
+
+		tpl.Outputs[upstream.getRef().ExpAttRef(ImageCacheAttRegistryPrefix)] = gocf.Output{
+			Value: gocf.Sub(fmt.Sprintf("${AWS::AccountId}.dkr.ecr.${AWS::Region}.${AWS::URLSuffix}/%v", repositoryPrefix)),
+			Export: &gocf.Export{
+				Name: upstream.getRef().ExpAttName(p, ImageCacheAttRegistryPrefix),
+			},
+		}
+	}
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *imageCacheImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+
+	registryPrefixes := ImageCacheRegistryPrefixes{}
+	for _, upstream := range p.cfg.Upstreams {
+		registryPrefixes[upstream] = exports.GetAtt(upstream.getRef(), ImageCacheAttRegistryPrefix)
+	}
+
+	p.cloudMetadata = &ImageCacheCloudMetadata{
+		Exports:          exports,
+		RegistryPrefixes: registryPrefixes,
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *imageCacheImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *imageCacheImpl) getRepositoryPrefix(upstream ImageCacheUpstream) string {
+	repositoryPrefix := p.cfg.Cloud.RepositoryPrefix
+	if repositoryPrefix == "" {
+		repositoryPrefix = p.cfg.Stage.GetConfig().App.GetConfig().Name
+	}
+	return repositoryPrefix + "-" + string(upstream)
+}
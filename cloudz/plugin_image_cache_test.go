@@ -0,0 +1,88 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageCacheConfig_MustValidate(t *testing.T) {
+	newConfig := func(upstreams []ImageCacheUpstream, cloud *ImageCacheConfigCloud) *ImageCacheConfig {
+		return &ImageCacheConfig{
+			Stage:     &cloudStageImpl{cfg: &CloudStageConfig{Name: "staging"}},
+			Name:      "cache",
+			Upstreams: upstreams,
+			Cloud:     cloud,
+		}
+	}
+
+	dockerHubCredentials := map[ImageCacheUpstream]string{
+		ImageCacheUpstreamDockerHub: "arn:aws:secretsmanager:us-east-1:123456789012:secret:ecr-pullthroughcache/docker-hub",
+	}
+
+	require.NotPanics(t, func() {
+		newConfig([]ImageCacheUpstream{ImageCacheUpstreamDockerHub, ImageCacheUpstreamECRPublic}, nil).MustValidate(Local)
+	})
+	require.NotPanics(t, func() {
+		newConfig([]ImageCacheUpstream{ImageCacheUpstreamDockerHub, ImageCacheUpstreamECRPublic}, &ImageCacheConfigCloud{
+			CredentialSecretARNs: dockerHubCredentials,
+		}).MustValidate(Cloud)
+	})
+	require.Panics(t, func() { newConfig(nil, nil).MustValidate(Local) })
+	require.Panics(t, func() { newConfig([]ImageCacheUpstream{"quay"}, nil).MustValidate(Local) })
+	require.Panics(t, func() {
+		newConfig([]ImageCacheUpstream{ImageCacheUpstreamGHCR, ImageCacheUpstreamGHCR}, nil).MustValidate(Local)
+	})
+	require.Panics(t, func() {
+		newConfig([]ImageCacheUpstream{ImageCacheUpstreamECRPublic}, nil).MustValidate(Cloud)
+	})
+	require.Panics(t, func() {
+		newConfig([]ImageCacheUpstream{ImageCacheUpstreamGHCR}, &ImageCacheConfigCloud{}).MustValidate(Cloud)
+	})
+	require.Panics(t, func() {
+		newConfig([]ImageCacheUpstream{ImageCacheUpstreamECRPublic}, &ImageCacheConfigCloud{
+			CredentialSecretARNs: map[ImageCacheUpstream]string{ImageCacheUpstreamECRPublic: "arn"},
+		}).MustValidate(Cloud)
+	})
+	require.Panics(t, func() {
+		newConfig([]ImageCacheUpstream{ImageCacheUpstreamECRPublic}, &ImageCacheConfigCloud{
+			CredentialSecretARNs: dockerHubCredentials,
+		}).MustValidate(Cloud)
+	})
+	require.Panics(t, func() {
+		newConfig([]ImageCacheUpstream{ImageCacheUpstreamECRPublic}, &ImageCacheConfigCloud{
+			RepositoryPrefix: "My_App",
+		}).MustValidate(Cloud)
+	})
+}
+
+func TestImageCacheRegistryPrefixes_GetImageName(t *testing.T) {
+	r := ImageCacheRegistryPrefixes{
+		ImageCacheUpstreamDockerHub: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app-docker-hub",
+		ImageCacheUpstreamGHCR:      "ghcr.io",
+	}
+
+	require.Equal(t, "123456789012.dkr.ecr.us-east-1.amazonaws.com/app-docker-hub/library/postgres:14",
+		r.GetImageName(ImageCacheUpstreamDockerHub, "postgres:14"))
+	require.Equal(t, "123456789012.dkr.ecr.us-east-1.amazonaws.com/app-docker-hub/hasura/graphql-engine:v2.8.0",
+		r.GetImageName(ImageCacheUpstreamDockerHub, "hasura/graphql-engine:v2.8.0"))
+	require.Equal(t, "ghcr.io/dbt-labs/dbt-postgres:1.7.4", r.GetImageName(ImageCacheUpstreamGHCR, "dbt-labs/dbt-postgres:1.7.4"))
+	require.Panics(t, func() { r.GetImageName(ImageCacheUpstreamECRPublic, "docker/library/caddy") })
+}
+
+func TestImageCacheUpstream(t *testing.T) {
+	require.Equal(t, CloudRef("r-docker-hub"), ImageCacheUpstreamDockerHub.getRef())
+	require.True(t, ImageCacheUpstreamDockerHub.isCredentialRequired())
+	require.True(t, ImageCacheUpstreamGHCR.isCredentialRequired())
+	require.False(t, ImageCacheUpstreamECRPublic.isCredentialRequired())
+
+	for _, upstream := range []ImageCacheUpstream{
+		ImageCacheUpstreamDockerHub,
+		ImageCacheUpstreamECRPublic,
+		ImageCacheUpstreamGHCR,
+	} {
+		require.NotEmpty(t, imageCacheUpstreamRegistries[upstream])
+		require.NotEmpty(t, imageCacheUpstreamRegistryURLs[upstream])
+		require.NotEmpty(t, imageCacheUpstreamLocalRegistryPrefixes[upstream])
+	}
+}
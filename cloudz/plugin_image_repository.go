@@ -78,6 +78,7 @@ func (d *ImageRepositoryDependencies) MustValidate() {
 }
 
 // ImageRepositoryLocalMetadata describes the image repository local metadata.
+// The ImageName is "<app name>-<repository name>", and doesn't go through the NamingStrategy, since it's not a container.
 type ImageRepositoryLocalMetadata struct {
 	ImageName string
 }
@@ -0,0 +1,333 @@
+package cloudz
+
+import (
+	"encoding/json"
+	"fmt"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// Kafka constants.
+const (
+	KafkaPluginDisplayName = "Kafka"
+	KafkaPluginName        = "kafka"
+	KafkaRefCluster        = CloudRef("c")
+
+	kafkaInternalPort         = 9092
+	kafkaExternalListenerPort = 19092
+)
+
+var (
+	_ Kafka  = &kafkaImpl{}
+	_ Plugin = &kafkaImpl{}
+)
+
+// KafkaConfigFunc returns the kafka config for a given Stage.
+type KafkaConfigFunc func(Stage, *KafkaDependencies) *KafkaConfig
+
+// KafkaEventHookFunc describes a kafka event hook.
+type KafkaEventHookFunc func(Kafka, Event, string)
+
+// KafkaConfig describes the kafka config.
+//
+// Local stages run a single-node Redpanda broker, which is Kafka API compatible. Cloud stages provision an MSK
+// Serverless cluster in the private subnets of the Network, which only supports IAM authentication: clients must use
+// SASL/IAM and be granted the relevant "kafka-cluster:*" actions on the cluster ARN (see Kafka.GetClientRolePolicy).
+// Topics are created automatically on first use in local stages, but not in cloud stages.
+type KafkaConfig struct {
+	Stage     Stage `validate:"required"`
+	Local     *KafkaConfigLocal
+	EventHook KafkaEventHookFunc
+}
+
+// MustValidate validates the kafka config.
+func (c *KafkaConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing KafkaConfig.Local")
+}
+
+// KafkaConfigLocal describes part of the kafka config.
+type KafkaConfigLocal struct {
+	ExternalPort uint16 `validate:"required"`
+}
+
+// KafkaDependencies describes the kafka dependencies.
+type KafkaDependencies struct {
+	Network           Network `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the kafka dependencies.
+func (d *KafkaDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// KafkaLocalMetadata describes the kafka local metadata.
+type KafkaLocalMetadata struct {
+	ContainerName            string
+	ExternalBootstrapBrokers string
+	InternalBootstrapBrokers string
+}
+
+// KafkaCloudMetadata describes the kafka cloud metadata.
+type KafkaCloudMetadata struct {
+	Exports          CloudExports
+	BootstrapBrokers string
+}
+
+// GetARN returns the cluster ARN.
+func (m *KafkaCloudMetadata) GetARN() string {
+	return m.Exports.GetRef(KafkaRefCluster)
+}
+
+// Kafka describes a kafka.
+type Kafka interface {
+	Plugin
+	GetConfig() *KafkaConfig
+	GetDependencies() *KafkaDependencies
+	GetLocalMetadata() *KafkaLocalMetadata
+	GetCloudMetadata(require bool) *KafkaCloudMetadata
+	GetClientRolePolicy() goiam.Role_Policy
+}
+
+type kafkaImpl struct {
+	cfgFunc       KafkaConfigFunc
+	deps          *KafkaDependencies
+	cfg           *KafkaConfig
+	localMetadata *KafkaLocalMetadata
+	cloudMetadata *KafkaCloudMetadata
+}
+
+// NewKafka initializes a new Kafka.
+func NewKafka(cfgFunc KafkaConfigFunc, deps *KafkaDependencies) Kafka {
+	deps.MustValidate()
+
+	return &kafkaImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*kafkaImpl) GetDisplayName() string {
+	return KafkaPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *kafkaImpl) GetName() string {
+	return KafkaPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *kafkaImpl) GetInstanceName() *string {
+	return nil
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *kafkaImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Network: {},
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *kafkaImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *kafkaImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(KafkaPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the Kafka interface.
+func (p *kafkaImpl) GetConfig() *KafkaConfig {
+	return p.cfg
+}
+
+// GetDependencies implements the Kafka interface.
+func (p *kafkaImpl) GetDependencies() *KafkaDependencies {
+	return p.deps
+}
+
+// GetLocalMetadata implements the Kafka interface.
+func (p *kafkaImpl) GetLocalMetadata() *KafkaLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(KafkaPluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the Kafka interface.
+func (p *kafkaImpl) GetCloudMetadata(require bool) *KafkaCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(KafkaPluginName))
+	return p.cloudMetadata
+}
+
+// GetClientRolePolicy implements the Kafka interface.
+// It returns a role policy granting full access to the cluster, its topics, and its consumer groups.
+func (p *kafkaImpl) GetClientRolePolicy() goiam.Role_Policy {
+	clusterName := KafkaRefCluster.Name(p)
+
+	return goiam.Role_Policy{
+		PolicyName: KafkaPluginName + "-client",
+		PolicyDocument: NewPolicyDocument(
+			NewPolicyStatement().
+				AddActions("kafka-cluster:*").
+				AddResources(
+					gocf.Sub("arn:aws:kafka:${AWS::Region}:${AWS::AccountId}:cluster/"+clusterName+"/*"),
+					gocf.Sub("arn:aws:kafka:${AWS::Region}:${AWS::AccountId}:topic/"+clusterName+"/*"),
+					gocf.Sub("arn:aws:kafka:${AWS::Region}:${AWS::AccountId}:group/"+clusterName+"/*"))),
+	}
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *kafkaImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *kafkaImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
+	containerName := LocalGetContainerName(p)
+
+	p.localMetadata = &KafkaLocalMetadata{
+		ContainerName:            containerName,
+		ExternalBootstrapBrokers: fmt.Sprintf("localhost:%v", p.cfg.Local.ExternalPort),
+		InternalBootstrapBrokers: fmt.Sprintf("%v:%v", containerName, kafkaInternalPort),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          containerName,
+		ContainerName: containerName,
+		Command: dctypes.ShellCommand{
+			"redpanda", "start",
+			"--overprovisioned",
+			"--smp", "1",
+			"--memory", "1G",
+			"--reserve-memory", "0M",
+			"--node-id", "0",
+			"--check=false",
+			"--set", "redpanda.auto_create_topics_enabled=true",
+			"--kafka-addr", fmt.Sprintf("INTERNAL://0.0.0.0:%v,EXTERNAL://0.0.0.0:%v", kafkaInternalPort, kafkaExternalListenerPort),
+			"--advertise-kafka-addr", fmt.Sprintf("INTERNAL://%v,EXTERNAL://%v", p.localMetadata.InternalBootstrapBrokers, p.localMetadata.ExternalBootstrapBrokers),
+		},
+		Image:    LocalGetImage(p, "vectorized/redpanda:v"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Redpanda),
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    kafkaExternalListenerPort,
+				Published: uint32(p.cfg.Local.ExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *kafkaImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+	network := p.deps.Network.GetCloudMetadata(true)
+
+	tpl.Resources[KafkaRefCluster.Ref()] = &kafkaServerlessCluster{
+		ClusterName: KafkaRefCluster.Name(p),
+		ClientAuthentication: &kafkaServerlessClusterClientAuthentication{
+			Sasl: &kafkaServerlessClusterSasl{
+				Iam: &kafkaServerlessClusterIam{
+					Enabled: true,
+				},
+			},
+		},
+		VpcConfigs: []*kafkaServerlessClusterVpcConfig{
+			{
+				SecurityGroups: []string{
+					network.Exports.GetRef(NetworkRefSecurityGroup),
+				},
+				SubnetIds: []string{
+					network.Exports.GetRef(NetworkRefSubnetPrivateA),
+					network.Exports.GetRef(NetworkRefSubnetPrivateB),
+				},
+			},
+		},
+		Tags: map[string]string{
+			"Name": KafkaRefCluster.Name(p),
+		},
+	}
+
+	CloudAddExpRef(tpl, p, KafkaRefCluster)
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *kafkaImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+	ops := p.cfg.Stage.GetConfig().App.GetOperations()
+
+	p.cloudMetadata = &KafkaCloudMetadata{
+		Exports: exports,
+	}
+
+	if !ops.IsOffline() {
+		p.cloudMetadata.BootstrapBrokers = ops.GetKafkaBootstrapBrokers(exports.GetRef(KafkaRefCluster))
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *kafkaImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+// kafkaServerlessCluster describes an "AWS::MSK::ServerlessCluster" resource, which is not supported by goformation.
+type kafkaServerlessCluster struct {
+	ClusterName          string
+	ClientAuthentication *kafkaServerlessClusterClientAuthentication
+	VpcConfigs           []*kafkaServerlessClusterVpcConfig
+	Tags                 map[string]string `json:",omitempty"`
+}
+
+type kafkaServerlessClusterClientAuthentication struct {
+	Sasl *kafkaServerlessClusterSasl
+}
+
+type kafkaServerlessClusterSasl struct {
+	Iam *kafkaServerlessClusterIam
+}
+
+type kafkaServerlessClusterIam struct {
+	Enabled bool
+}
+
+type kafkaServerlessClusterVpcConfig struct {
+	SecurityGroups []string `json:",omitempty"`
+	SubnetIds      []string
+}
+
+// AWSCloudFormationType implements the gocf.Resource interface.
+func (*kafkaServerlessCluster) AWSCloudFormationType() string {
+	return "AWS::MSK::ServerlessCluster"
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (r kafkaServerlessCluster) MarshalJSON() ([]byte, error) {
+	type Properties kafkaServerlessCluster
+	return json.Marshal(&struct {
+		Type       string
+		Properties Properties
+	}{
+		Type:       r.AWSCloudFormationType(),
+		Properties: (Properties)(r),
+	})
+}
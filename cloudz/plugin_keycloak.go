@@ -0,0 +1,430 @@
+package cloudz
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+	"github.com/ibrt/golang-cloud/opz"
+)
+
+// Keycloak constants.
+const (
+	KeycloakPluginDisplayName      = "Keycloak"
+	KeycloakPluginName             = "keycloak"
+	KeycloakRefLogGroup            = ECSServiceRefLogGroup
+	KeycloakRefRoleExecution       = ECSServiceRefRoleExecution
+	KeycloakRefRoleTask            = ECSServiceRefRoleTask
+	KeycloakRefTaskDefinition      = ECSServiceRefTaskDefinition
+	KeycloakRefTargetGroup         = ECSServiceRefTargetGroup
+	KeycloakRefListenerRule        = ECSServiceRefListenerRule
+	KeycloakRefCluster             = ECSServiceRefCluster
+	KeycloakRefService             = ECSServiceRefService
+	KeycloakRefRecordSet           = ECSServiceRefRecordSet
+	KeycloakRefHealthCheck         = ECSServiceRefHealthCheck
+	KeycloakAttARN                 = ECSServiceAttARN
+	KeycloakAttName                = ECSServiceAttName
+	KeycloakAttRoleID              = ECSServiceAttRoleID
+	KeycloakAttRuleARN             = ECSServiceAttRuleARN
+	KeycloakAttTargetGroupFullName = ECSServiceAttTargetGroupFullName
+	KeycloakAttTargetGroupName     = ECSServiceAttTargetGroupName
+
+	// KeycloakAdminUsername is the username of the admin user of the "master" realm.
+	KeycloakAdminUsername = "admin"
+
+	// KeycloakDatabaseSchema is the Postgres schema holding the Keycloak tables.
+	KeycloakDatabaseSchema = "keycloak"
+
+	keycloakCloudPort            = 8080
+	keycloakListenerRulePriority = 110
+)
+
+var (
+	_ Keycloak = &keycloakImpl{}
+	_ Plugin   = &keycloakImpl{}
+
+	keycloakRealmsDirParts = []string{
+		"realms",
+	}
+)
+
+// KeycloakConfigFunc returns the keycloak config for a given Stage.
+type KeycloakConfigFunc func(Stage, *KeycloakDependencies) *KeycloakConfig
+
+// KeycloakEventHookFunc describes a keycloak event hook.
+type KeycloakEventHookFunc func(Keycloak, Event, string)
+
+// KeycloakConfig describes the keycloak config.
+type KeycloakConfig struct {
+	Stage     Stage `validate:"required"`
+	Local     *KeycloakConfigLocal
+	Cloud     *KeycloakConfigCloud
+	EventHook KeycloakEventHookFunc
+}
+
+// MustValidate validates the keycloak config.
+func (c *KeycloakConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing KeycloakConfig.Local")
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing KeycloakConfig.Cloud")
+
+	if stageTarget == Cloud && c.Cloud.Routing != nil {
+		c.Cloud.Routing.MustValidate()
+	}
+}
+
+// KeycloakConfigLocal describes part of the keycloak config.
+type KeycloakConfigLocal struct {
+	ExternalPort uint16 `validate:"required"`
+}
+
+// KeycloakConfigCloud describes part of the keycloak config.
+type KeycloakConfigCloud struct {
+	DomainName string `validate:"required"`
+	CPU        int    `validate:"required"`
+	Memory     int    `validate:"required"`
+	Routing    *RecordSetRoutingConfig
+
+	// ListenerRulePriority must be unique among the services sharing the LoadBalancer, defaults to 110.
+	ListenerRulePriority int
+
+	// ServiceConnect optionally makes Keycloak reachable by the other services of the Network via ECS Service Connect.
+	ServiceConnect *ECSServiceTemplateConfigServiceConnect
+}
+
+// KeycloakDependencies describes the keycloak dependencies. RuntimeSecrets is required in cloud stages, must depend on
+// Postgres, and must set RuntimeSecretsKeyKeycloakAdminPassword: the passwords are injected from it.
+type KeycloakDependencies struct {
+	Certificate       Certificate     `validate:"required"`
+	ImageRepository   ImageRepository `validate:"required"`
+	LoadBalancer      LoadBalancer    `validate:"required"`
+	Network           Network         `validate:"required"`
+	Postgres          Postgres        `validate:"required"`
+	RuntimeSecrets    RuntimeSecrets
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the keycloak dependencies.
+func (d *KeycloakDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// KeycloakLocalMetadata describes the keycloak local metadata.
+type KeycloakLocalMetadata struct {
+	ContainerName string
+	AdminUsername string
+	AdminPassword string `metadata:"secret"`
+	ExternalURL   *url.URL
+	InternalURL   *url.URL
+}
+
+// KeycloakCloudMetadata describes the keycloak cloud metadata.
+type KeycloakCloudMetadata struct {
+	Exports       CloudExports
+	AdminUsername string
+	URL           *url.URL
+}
+
+// Keycloak describes a self-hosted identity provider, for apps that can't use Cognito. Realms are imported at startup
+// from the JSON files in the "realms" config dir (e.g. as exported from the admin console), unless they already exist.
+// Data is stored in the KeycloakDatabaseSchema schema of the Postgres database. In the cloud, a single replica is run,
+// as the distributed caches require a discovery mechanism not available on Fargate.
+type Keycloak interface {
+	Plugin
+	GetConfig() *KeycloakConfig
+	GetLocalMetadata() *KeycloakLocalMetadata
+	GetCloudMetadata(require bool) *KeycloakCloudMetadata
+}
+
+type keycloakImpl struct {
+	cfgFunc       KeycloakConfigFunc
+	deps          *KeycloakDependencies
+	cfg           *KeycloakConfig
+	localMetadata *KeycloakLocalMetadata
+	cloudMetadata *KeycloakCloudMetadata
+}
+
+// NewKeycloak initializes a new Keycloak.
+func NewKeycloak(cfgFunc KeycloakConfigFunc, deps *KeycloakDependencies) Keycloak {
+	deps.MustValidate()
+
+	return &keycloakImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*keycloakImpl) GetDisplayName() string {
+	return KeycloakPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (*keycloakImpl) GetName() string {
+	return KeycloakPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (*keycloakImpl) GetInstanceName() *string {
+	return nil
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *keycloakImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Certificate:     {},
+		p.deps.ImageRepository: {},
+		p.deps.LoadBalancer:    {},
+		p.deps.Network:         {},
+		p.deps.Postgres:        {},
+	}
+
+	if p.deps.RuntimeSecrets != nil {
+		dependenciesMap[p.deps.RuntimeSecrets] = struct{}{}
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *keycloakImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+	errorz.Assertf(stage.GetTarget() == Local || p.deps.RuntimeSecrets != nil, "missing KeycloakDependencies.RuntimeSecrets", errorz.Prefix(KeycloakPluginName))
+}
+
+// GetStage implements the Plugin interface.
+func (p *keycloakImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(KeycloakPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the Keycloak interface.
+func (p *keycloakImpl) GetConfig() *KeycloakConfig {
+	return p.cfg
+}
+
+// GetLocalMetadata implements the Keycloak interface.
+func (p *keycloakImpl) GetLocalMetadata() *KeycloakLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(KeycloakPluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the Keycloak interface.
+func (p *keycloakImpl) GetCloudMetadata(require bool) *KeycloakCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(KeycloakPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *keycloakImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *keycloakImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
+	containerName := LocalGetContainerName(p)
+	pgURL := p.deps.Postgres.GetLocalMetadata().InternalURL
+
+	p.localMetadata = &KeycloakLocalMetadata{
+		ContainerName: containerName,
+		AdminUsername: KeycloakAdminUsername,
+		AdminPassword: LocalPassword,
+		ExternalURL:   urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.ExternalPort)),
+		InternalURL:   urlz.MustParse(fmt.Sprintf("http://%v:%v", containerName, p.cfg.Local.ExternalPort)),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          containerName,
+		Command:       dctypes.ShellCommand{"start-dev", "--import-realm"},
+		ContainerName: containerName,
+		DependsOn: []string{
+			p.deps.Postgres.GetLocalMetadata().ContainerName,
+		},
+		Environment: map[string]*string{
+			"KC_DB":                   stringz.Ptr("postgres"),
+			"KC_DB_URL":               stringz.Ptr(fmt.Sprintf("jdbc:postgresql://%v%v", pgURL.Host, pgURL.Path)),
+			"KC_DB_USERNAME":          stringz.Ptr(pgURL.User.Username()),
+			"KC_DB_PASSWORD":          stringz.Ptr(LocalPassword),
+			"KC_DB_SCHEMA":            stringz.Ptr(KeycloakDatabaseSchema),
+			"KC_HEALTH_ENABLED":       stringz.Ptr("true"),
+			"KC_HTTP_PORT":            stringz.Ptr(fmt.Sprintf("%v", p.cfg.Local.ExternalPort)),
+			"KEYCLOAK_ADMIN":          stringz.Ptr(KeycloakAdminUsername),
+			"KEYCLOAK_ADMIN_PASSWORD": stringz.Ptr(LocalPassword),
+		},
+		Image:    fmt.Sprintf("quay.io/keycloak/keycloak:%v", p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Keycloak),
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    uint32(p.cfg.Local.ExternalPort),
+				Published: uint32(p.cfg.Local.ExternalPort),
+			},
+		},
+		Restart: "unless-stopped", // Note: restarts until the schema is created (see localAfterCreateEventHook).
+		Volumes: []dctypes.ServiceVolumeConfig{
+			{
+				Type:     "bind",
+				Source:   filez.MustAbs(p.getRealmsDirPath()),
+				Target:   "/opt/keycloak/data/import",
+				ReadOnly: true,
+			},
+		},
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *keycloakImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+	pgURL := p.deps.Postgres.GetCloudMetadata(true).URL
+
+	environment := map[string]string{
+		"KC_DB_URL":      fmt.Sprintf("jdbc:postgresql://%v%v?sslmode=require", pgURL.Host, pgURL.Path),
+		"KC_DB_USERNAME": pgURL.User.Username(),
+		"KC_DB_SCHEMA":   KeycloakDatabaseSchema,
+		"KC_HOSTNAME":    p.cfg.Cloud.DomainName,
+		"KC_HTTP_PORT":   fmt.Sprintf("%v", keycloakCloudPort),
+		"KC_PROXY":       "edge",
+		"KEYCLOAK_ADMIN": KeycloakAdminUsername,
+	}
+
+	secrets := []*ECSServiceTemplateConfigSecret{
+		p.deps.RuntimeSecrets.GetECSSecret("KC_DB_PASSWORD", RuntimeSecretsKeyPostgresPassword),
+		p.deps.RuntimeSecrets.GetECSSecret("KEYCLOAK_ADMIN_PASSWORD", RuntimeSecretsKeyKeycloakAdminPassword),
+	}
+
+	CloudAddECSServiceResources(tpl, p, &ECSServiceTemplateConfig{
+		Image:                p.getImageWithTag(),
+		Port:                 keycloakCloudPort,
+		Environment:          environment,
+		Secrets:              secrets,
+		HealthCheckPath:      "/health/ready",
+		Replicas:             1, // see Keycloak
+		CPU:                  p.cfg.Cloud.CPU,
+		Memory:               p.cfg.Cloud.Memory,
+		DomainName:           p.cfg.Cloud.DomainName,
+		ListenerRulePriority: p.getListenerRulePriority(),
+		ServiceConnect:       p.cfg.Cloud.ServiceConnect,
+		Routing:              p.cfg.Cloud.Routing,
+		Certificate:          p.deps.Certificate,
+		LoadBalancer:         p.deps.LoadBalancer,
+		Network:              p.deps.Network,
+	})
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *keycloakImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &KeycloakCloudMetadata{
+		Exports:       NewCloudExports(stack),
+		AdminUsername: KeycloakAdminUsername,
+		URL:           urlz.MustParse(fmt.Sprintf("https://%v", p.cfg.Cloud.DomainName)),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *keycloakImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case LocalBeforeCreateEvent:
+		p.localBeforeCreateEventHook()
+	case LocalAfterCreateEvent:
+		p.localAfterCreateEventHook()
+	case CloudPreflightEvent:
+		p.cloudPreflightEventHook()
+	case CloudBeforeDeployEvent:
+		p.cloudBeforeDeployEventHook(buildDirPath)
+	case CloudAfterDeployEvent:
+		p.cloudAfterDeployEventHook()
+	case CloudBeforeDestroyEvent:
+		p.cloudBeforeDestroyEventHook()
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *keycloakImpl) localBeforeCreateEventHook() {
+	errorz.MaybeMustWrap(os.MkdirAll(p.getRealmsDirPath(), 0777))
+}
+
+func (p *keycloakImpl) localAfterCreateEventHook() {
+	p.cfg.Stage.GetConfig().App.GetOperations().EnsurePostgresSchema(
+		p.deps.Postgres.GetLocalMetadata().ExternalURL.String(),
+		KeycloakDatabaseSchema)
+}
+
+func (p *keycloakImpl) cloudPreflightEventHook() {
+	errorz.Assertf(len(p.deps.RuntimeSecrets.GetConfig().Values[RuntimeSecretsKeyKeycloakAdminPassword]) >= 16,
+		"runtime secret %v must be set and at least 16 characters long", errorz.A(RuntimeSecretsKeyKeycloakAdminPassword),
+		errorz.Prefix(KeycloakPluginName))
+
+	CloudMustCheckDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName, p.cfg.Cloud.Routing)
+}
+
+func (p *keycloakImpl) cloudBeforeDeployEventHook(buildDirPath string) {
+	filez.MustPrepareDir(buildDirPath, 0777)
+
+	imageWithTag := p.getImageWithTag()
+	realmsDirPath := p.getRealmsDirPath()
+	errorz.MaybeMustWrap(os.MkdirAll(realmsDirPath, 0777))
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "Dockerfile"), 0777, 0666,
+		templatez.MustParseAndExecuteText(
+			assets.KeycloakDockerfileTemplateAsset,
+			assets.KeycloakDockerfileTemplateData{
+				BaseImage: fmt.Sprintf("quay.io/keycloak/keycloak:%v", p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Keycloak),
+			}))
+
+	opz.MustCopyDir(realmsDirPath, filepath.Join(buildDirPath, "keycloak-realms"))
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker", "build", "--no-cache", "-t", imageWithTag, ".").SetDir(buildDirPath).MustRun()
+
+	p.cfg.Stage.GetConfig().App.GetOperations().DockerLoginToECR()
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker", "push", imageWithTag).MustRun()
+
+	p.cfg.Stage.GetConfig().App.GetOperations().EnsurePostgresSchema(
+		p.deps.Postgres.GetCloudMetadata(true).URL.String(),
+		KeycloakDatabaseSchema)
+}
+
+func (p *keycloakImpl) cloudAfterDeployEventHook() {
+	CloudMaybeUpsertDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName,
+		p.deps.LoadBalancer.GetCloudMetadata(true).Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttDNSName))
+}
+
+func (p *keycloakImpl) cloudBeforeDestroyEventHook() {
+	CloudMaybeDeleteDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName,
+		p.deps.LoadBalancer.GetCloudMetadata(true).Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttDNSName))
+}
+
+func (p *keycloakImpl) getRealmsDirPath() string {
+	return p.cfg.Stage.GetConfig().App.GetConfig().GetConfigDirPathForPlugin(p, keycloakRealmsDirParts...)
+}
+
+func (p *keycloakImpl) getImageWithTag() string {
+	return p.deps.ImageRepository.GetCloudMetadata(true).ImageName + ":" + p.cfg.Stage.AsCloudStage().GetCloudConfig().Version
+}
+
+func (p *keycloakImpl) getListenerRulePriority() int {
+	if p.cfg.Cloud.ListenerRulePriority != 0 {
+		return p.cfg.Cloud.ListenerRulePriority
+	}
+	return keycloakListenerRulePriority
+}
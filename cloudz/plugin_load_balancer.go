@@ -1,6 +1,8 @@
 package cloudz
 
 import (
+	"fmt"
+
 	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	gocf "github.com/awslabs/goformation/v6/cloudformation"
 	goec2 "github.com/awslabs/goformation/v6/cloudformation/ec2"
@@ -28,6 +30,7 @@ const (
 	LoadBalancerAttLoadBalancerFullName      = CloudAtt("LoadBalancerFullName")
 	LoadBalancerAttLoadBalancerName          = CloudAtt("LoadBalancerName")
 	LoadBalancerAttListenerArn               = CloudAtt("ListenerArn")
+	LoadBalancerDefaultIdleTimeoutSeconds    = 60
 )
 
 var (
@@ -44,6 +47,7 @@ type LoadBalancerEventHookFunc func(LoadBalancer, Event, string)
 // LoadBalancerConfig describes the load balancer config.
 type LoadBalancerConfig struct {
 	Stage     Stage `validate:"required"`
+	Cloud     *LoadBalancerConfigCloud
 	EventHook LoadBalancerEventHookFunc
 }
 
@@ -52,6 +56,21 @@ func (c *LoadBalancerConfig) MustValidate(_ StageTarget) {
 	vz.MustValidateStruct(c)
 }
 
+// LoadBalancerConfigCloud describes part of the load balancer config.
+type LoadBalancerConfigCloud struct {
+	// IdleTimeoutSeconds is the time a connection is allowed to be idle before it is closed, defaults to 60. Raise it
+	// for services holding long-lived WebSocket connections which don't exchange keepalive messages often enough.
+	IdleTimeoutSeconds int `validate:"omitempty,min=1,max=4000"`
+}
+
+// GetIdleTimeoutSeconds returns the idle timeout, applying the default.
+func (c *LoadBalancerConfigCloud) GetIdleTimeoutSeconds() int {
+	if c == nil || c.IdleTimeoutSeconds == 0 {
+		return LoadBalancerDefaultIdleTimeoutSeconds
+	}
+	return c.IdleTimeoutSeconds
+}
+
 // LoadBalancerDependencies describes the load balancer dependencies.
 type LoadBalancerDependencies struct {
 	Certificate       Certificate `validate:"required"`
@@ -177,6 +196,10 @@ func (p *loadBalancerImpl) GetCloudTemplate(_ string) *gocf.Template {
 					return stringz.Ptr("false")
 				}(),
 			},
+			{
+				Key:   stringz.Ptr("idle_timeout.timeout_seconds"),
+				Value: stringz.Ptr(fmt.Sprintf("%v", p.cfg.Cloud.GetIdleTimeoutSeconds())),
+			},
 		},
 		Name:   stringz.Ptr(LoadBalancerRefLoadBalancer.Name(p)),
 		Scheme: stringz.Ptr("internet-facing"),
@@ -0,0 +1,13 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBalancerConfigCloud_GetIdleTimeoutSeconds(t *testing.T) {
+	require.Equal(t, LoadBalancerDefaultIdleTimeoutSeconds, (*LoadBalancerConfigCloud)(nil).GetIdleTimeoutSeconds())
+	require.Equal(t, LoadBalancerDefaultIdleTimeoutSeconds, (&LoadBalancerConfigCloud{}).GetIdleTimeoutSeconds())
+	require.Equal(t, 3600, (&LoadBalancerConfigCloud{IdleTimeoutSeconds: 3600}).GetIdleTimeoutSeconds())
+}
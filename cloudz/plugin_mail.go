@@ -1,31 +1,72 @@
 package cloudz
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/url"
+	"strconv"
 
 	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	goroute53 "github.com/awslabs/goformation/v6/cloudformation/route53"
+	goses "github.com/awslabs/goformation/v6/cloudformation/ses"
 	dctypes "github.com/docker/cli/cli/compose/types"
 	"github.com/ibrt/golang-bites/stringz"
 	"github.com/ibrt/golang-bites/urlz"
 	"github.com/ibrt/golang-errors/errorz"
 	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/opz"
 )
 
 // Mail constants.
 const (
-	MailPluginDisplayName = "Mail"
-	MailPluginName        = "mail"
-
-	mailHogVersion = "1.0.1"
+	MailPluginDisplayName     = "Mail"
+	MailPluginName            = "mail"
+	MailRefConfigurationSet   = CloudRef("cs")
+	MailRefEmailIdentity      = CloudRef("ei")
+	MailRefRecordSetDKIM1     = CloudRef("rs-dkim-1")
+	MailRefRecordSetDKIM2     = CloudRef("rs-dkim-2")
+	MailRefRecordSetDKIM3     = CloudRef("rs-dkim-3")
+	MailAttDKIMDNSTokenName1  = CloudAtt("DkimDNSTokenName1")
+	MailAttDKIMDNSTokenName2  = CloudAtt("DkimDNSTokenName2")
+	MailAttDKIMDNSTokenName3  = CloudAtt("DkimDNSTokenName3")
+	MailAttDKIMDNSTokenValue1 = CloudAtt("DkimDNSTokenValue1")
+	MailAttDKIMDNSTokenValue2 = CloudAtt("DkimDNSTokenValue2")
+	MailAttDKIMDNSTokenValue3 = CloudAtt("DkimDNSTokenValue3")
 )
 
 var (
 	_ Mail   = &mailImpl{}
 	_ Plugin = &mailImpl{}
+
+	mailDKIMRecords = []*mailDKIMRecord{
+		{
+			RecordSetRef: MailRefRecordSetDKIM1,
+			NameAtt:      MailAttDKIMDNSTokenName1,
+			ValueAtt:     MailAttDKIMDNSTokenValue1,
+		},
+		{
+			RecordSetRef: MailRefRecordSetDKIM2,
+			NameAtt:      MailAttDKIMDNSTokenName2,
+			ValueAtt:     MailAttDKIMDNSTokenValue2,
+		},
+		{
+			RecordSetRef: MailRefRecordSetDKIM3,
+			NameAtt:      MailAttDKIMDNSTokenName3,
+			ValueAtt:     MailAttDKIMDNSTokenValue3,
+		},
+	}
 )
 
+type mailDKIMRecord struct {
+	RecordSetRef CloudRef
+	NameAtt      CloudAtt
+	ValueAtt     CloudAtt
+}
+
 // MailConfigFunc returns the mail config for a given Stage.
 type MailConfigFunc func(Stage, *MailDependencies) *MailConfig
 
@@ -33,9 +74,15 @@ type MailConfigFunc func(Stage, *MailDependencies) *MailConfig
 type MailEventHookFunc func(Mail, Event, string)
 
 // MailConfig describes the mail config.
+//
+// Locally, mail is delivered to a MailHog container. In the cloud, mail is either delivered to an external SMTP relay
+// (MailConfigCloud.SMTP), whose credentials are typically loaded from Secrets, or sent using SES (MailConfigCloud.SES).
+// In the latter case, the sending domain is verified using DKIM, and plugins sending mail (e.g. Functions) must attach
+// the policy returned by Mail.GetSendRolePolicy to their role, and list the Mail in their OtherDependencies.
 type MailConfig struct {
 	Stage     Stage `validate:"required"`
 	Local     *MailConfigLocal
+	Cloud     *MailConfigCloud
 	EventHook MailEventHookFunc
 }
 
@@ -51,6 +98,26 @@ type MailConfigLocal struct {
 	SMTPExternalPort uint16 `validate:"required"`
 }
 
+// MailConfigCloud describes part of the mail config. Exactly one of SMTP and SES must be set.
+type MailConfigCloud struct {
+	SMTP *MailConfigCloudSMTP `validate:"required_without=SES,excluded_with=SES"`
+	SES  *MailConfigCloudSES  `validate:"required_without=SMTP"`
+}
+
+// MailConfigCloudSMTP describes part of the mail config.
+type MailConfigCloudSMTP struct {
+	Host     string `validate:"required,hostname"`
+	Port     uint16 `validate:"required"`
+	Username string
+	Password string
+}
+
+// MailConfigCloudSES describes part of the mail config.
+type MailConfigCloudSES struct {
+	DomainName  string      `validate:"required,fqdn"`
+	DNSProvider DNSProvider `validate:"required"`
+}
+
 // MailDependencies describes the mail dependencies.
 type MailDependencies struct {
 	OtherDependencies OtherDependencies
@@ -69,12 +136,37 @@ type MailLocalMetadata struct {
 	ConsoleExternalURL *url.URL
 }
 
+// GetMailer returns a Mailer that delivers to the local mail, for use outside of containers.
+func (m *MailLocalMetadata) GetMailer() Mailer {
+	return NewSMTPMailer(m.ExternalURL)
+}
+
+// MailCloudMetadata describes the mail cloud metadata.
+// If using an SMTP relay, URL is set. If using SES, Exports, DomainName, and ConfigurationSetName are set.
+type MailCloudMetadata struct {
+	Exports              CloudExports
+	URL                  *url.URL
+	DomainName           string
+	ConfigurationSetName string
+	ops                  opz.Operations
+}
+
+// GetMailer returns a Mailer that delivers to the SMTP relay or via SES.
+func (m *MailCloudMetadata) GetMailer() Mailer {
+	if m.URL != nil {
+		return NewSMTPMailer(m.URL)
+	}
+	return NewSESMailer(m.ops, m.ConfigurationSetName)
+}
+
 // Mail describes a mail.
 type Mail interface {
 	Plugin
 	GetConfig() *MailConfig
 	GetDependencies() *MailDependencies
 	GetLocalMetadata() *MailLocalMetadata
+	GetCloudMetadata(require bool) *MailCloudMetadata
+	GetSendRolePolicy() goiam.Role_Policy
 }
 
 type mailImpl struct {
@@ -82,6 +174,7 @@ type mailImpl struct {
 	deps          *MailDependencies
 	cfg           *MailConfig
 	localMetadata *MailLocalMetadata
+	cloudMetadata *MailCloudMetadata
 }
 
 // NewMail initializes a new Mail.
@@ -122,6 +215,16 @@ func (p *mailImpl) GetDependenciesMap() map[Plugin]struct{} {
 func (p *mailImpl) Configure(stage Stage) {
 	p.cfg = p.cfgFunc(stage, p.deps)
 	p.cfg.MustValidate(stage.GetTarget())
+
+	if stage.GetTarget() == Cloud && p.cfg.Cloud != nil && p.cfg.Cloud.SMTP != nil {
+		p.cloudMetadata = &MailCloudMetadata{
+			URL: &url.URL{
+				Scheme: "smtp",
+				User:   url.UserPassword(p.cfg.Cloud.SMTP.Username, p.cfg.Cloud.SMTP.Password),
+				Host:   net.JoinHostPort(p.cfg.Cloud.SMTP.Host, strconv.Itoa(int(p.cfg.Cloud.SMTP.Port))),
+			},
+		}
+	}
 }
 
 // GetStage implements the Plugin interface.
@@ -146,9 +249,30 @@ func (p *mailImpl) GetLocalMetadata() *MailLocalMetadata {
 	return p.localMetadata
 }
 
+// GetCloudMetadata implements the Mail interface.
+func (p *mailImpl) GetCloudMetadata(require bool) *MailCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not configured", errorz.Prefix(MailPluginName))
+	return p.cloudMetadata
+}
+
+// GetSendRolePolicy implements the Mail interface.
+func (p *mailImpl) GetSendRolePolicy() goiam.Role_Policy {
+	errorz.Assertf(p.cfg != nil && p.cfg.Cloud != nil && p.cfg.Cloud.SES != nil, "SES not configured", errorz.Prefix(MailPluginName))
+
+	return goiam.Role_Policy{
+		PolicyName: MailPluginName + "-send",
+		PolicyDocument: NewPolicyDocument(
+			NewPolicyStatement().
+				AddActions("ses:SendEmail", "ses:SendRawEmail").
+				AddResources(
+					gocf.Sub("arn:aws:ses:${AWS::Region}:${AWS::AccountId}:identity/"+p.cfg.Cloud.SES.DomainName),
+					gocf.Sub("arn:aws:ses:${AWS::Region}:${AWS::AccountId}:configuration-set/"+MailRefConfigurationSet.Name(p)))),
+	}
+}
+
 // IsDeployed implements the Plugin interface.
 func (p *mailImpl) IsDeployed() bool {
-	return false
+	return p.cloudMetadata != nil
 }
 
 // UpdateLocalTemplate implements the Plugin interface.
@@ -172,7 +296,7 @@ func (p *mailImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
 			"MH_SMTP_BIND_ADDR": stringz.Ptr(fmt.Sprintf("0.0.0.0:%v", p.cfg.Local.SMTPExternalPort)),
 			"MH_STORAGE":        stringz.Ptr("memory"),
 		},
-		Image:    "mailhog/mailhog:v" + mailHogVersion,
+		Image:    LocalGetImage(p, "mailhog/mailhog:v"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().MailHog),
 		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
 		Ports: []dctypes.ServicePortConfig{
 			{
@@ -190,18 +314,171 @@ func (p *mailImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
 
 // GetCloudTemplate implements the Plugin interface.
 func (p *mailImpl) GetCloudTemplate(_ string) *gocf.Template {
-	// nothing to do here
-	return nil
+	if !p.hasCloudSES() {
+		// nothing to do here
+		return nil
+	}
+
+	tpl := gocf.NewTemplate()
+
+	tpl.Resources[MailRefConfigurationSet.Ref()] = &goses.ConfigurationSet{
+		Name: stringz.Ptr(MailRefConfigurationSet.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, MailRefConfigurationSet)
+
+	tpl.Resources[MailRefEmailIdentity.Ref()] = &mailEmailIdentity{
+		EmailIdentity: p.cfg.Cloud.SES.DomainName,
+		ConfigurationSetAttributes: &mailEmailIdentityConfigurationSetAttributes{
+			ConfigurationSetName: gocf.Ref(MailRefConfigurationSet.Ref()),
+		},
+		DkimAttributes: &mailEmailIdentityDkimAttributes{
+			SigningEnabled: true,
+		},
+		DkimSigningAttributes: &mailEmailIdentityDkimSigningAttributes{
+			NextSigningKeyLength: "RSA_2048_BIT",
+		},
+	}
+	CloudAddExpRef(tpl, p, MailRefEmailIdentity)
+
+	hostedZoneID := p.cfg.Cloud.SES.DNSProvider.GetHostedZoneID()
+
+	for _, dkimRecord := range mailDKIMRecords {
+		CloudAddExpGetAtt(tpl, p, MailRefEmailIdentity, dkimRecord.NameAtt)
+		CloudAddExpGetAtt(tpl, p, MailRefEmailIdentity, dkimRecord.ValueAtt)
+
+		if hostedZoneID != nil {
+			tpl.Resources[dkimRecord.RecordSetRef.Ref()] = &goroute53.RecordSet{
+				HostedZoneId: hostedZoneID,
+				Name:         gocf.GetAtt(MailRefEmailIdentity.Ref(), dkimRecord.NameAtt.Ref()),
+				ResourceRecords: &[]string{
+					gocf.GetAtt(MailRefEmailIdentity.Ref(), dkimRecord.ValueAtt.Ref()),
+				},
+				TTL:  stringz.Ptr("300"),
+				Type: DNSRecordTypeCNAME,
+			}
+			CloudAddExpRef(tpl, p, dkimRecord.RecordSetRef)
+		}
+	}
+
+	return tpl
 }
 
 // UpdateCloudMetadata implements the Plugin interface.
-func (p *mailImpl) UpdateCloudMetadata(_ *awscft.Stack) {
-	// nothing to do here
+func (p *mailImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+
+	p.cloudMetadata = &MailCloudMetadata{
+		Exports:              exports,
+		DomainName:           p.cfg.Cloud.SES.DomainName,
+		ConfigurationSetName: exports.GetRef(MailRefConfigurationSet),
+		ops:                  p.cfg.Stage.GetConfig().App.GetOperations(),
+	}
 }
 
 // EventHook implements the Plugin interface.
 func (p *mailImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case CloudPreflightEvent:
+		p.cloudPreflightEventHook()
+	case CloudAfterDeployEvent:
+		p.cloudAfterDeployEventHook()
+	case CloudBeforeDestroyEvent:
+		p.cloudBeforeDestroyEventHook()
+	}
+
 	if p.cfg.EventHook != nil {
 		p.cfg.EventHook(p, event, buildDirPath)
 	}
 }
+
+func (p *mailImpl) cloudPreflightEventHook() {
+	if !p.hasCloudSES() {
+		return
+	}
+
+	p.cfg.Cloud.SES.DNSProvider.MustCheckDomain(p, p.cfg.Cloud.SES.DomainName)
+}
+
+func (p *mailImpl) cloudAfterDeployEventHook() {
+	if !p.hasCloudSES() {
+		return
+	}
+
+	dnsProvider := p.cfg.Cloud.SES.DNSProvider
+
+	if !dnsProvider.IsRoute53() {
+		for _, record := range p.getCloudDKIMRecords() {
+			dnsProvider.UpsertRecord(p, record)
+		}
+	}
+}
+
+func (p *mailImpl) cloudBeforeDestroyEventHook() {
+	if !p.hasCloudSES() {
+		return
+	}
+
+	dnsProvider := p.cfg.Cloud.SES.DNSProvider
+
+	if !dnsProvider.IsRoute53() {
+		for _, record := range p.getCloudDKIMRecords() {
+			dnsProvider.DeleteRecord(p, record)
+		}
+	}
+}
+
+func (p *mailImpl) getCloudDKIMRecords() []*DNSRecord {
+	records := make([]*DNSRecord, 0, len(mailDKIMRecords))
+
+	for _, dkimRecord := range mailDKIMRecords {
+		records = append(records, &DNSRecord{
+			Name:  p.cloudMetadata.Exports.GetAtt(MailRefEmailIdentity, dkimRecord.NameAtt),
+			Type:  DNSRecordTypeCNAME,
+			Value: p.cloudMetadata.Exports.GetAtt(MailRefEmailIdentity, dkimRecord.ValueAtt),
+			TTL:   300,
+		})
+	}
+
+	return records
+}
+
+func (p *mailImpl) hasCloudSES() bool {
+	return p.cfg.Cloud != nil && p.cfg.Cloud.SES != nil
+}
+
+// mailEmailIdentity describes an "AWS::SES::EmailIdentity" resource, which is not supported by goformation.
+type mailEmailIdentity struct {
+	EmailIdentity              string
+	ConfigurationSetAttributes *mailEmailIdentityConfigurationSetAttributes `json:",omitempty"`
+	DkimAttributes             *mailEmailIdentityDkimAttributes             `json:",omitempty"`
+	DkimSigningAttributes      *mailEmailIdentityDkimSigningAttributes      `json:",omitempty"`
+}
+
+type mailEmailIdentityConfigurationSetAttributes struct {
+	ConfigurationSetName string
+}
+
+type mailEmailIdentityDkimAttributes struct {
+	SigningEnabled bool
+}
+
+type mailEmailIdentityDkimSigningAttributes struct {
+	NextSigningKeyLength string
+}
+
+// AWSCloudFormationType implements the gocf.Resource interface.
+func (*mailEmailIdentity) AWSCloudFormationType() string {
+	return "AWS::SES::EmailIdentity"
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (r mailEmailIdentity) MarshalJSON() ([]byte, error) {
+	type Properties mailEmailIdentity
+	return json.Marshal(&struct {
+		Type       string
+		Properties Properties
+	}{
+		Type:       r.AWSCloudFormationType(),
+		Properties: (Properties)(r),
+	})
+}
@@ -0,0 +1,113 @@
+package cloudz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// MailHogMessage describes a message received by MailHog.
+type MailHogMessage struct {
+	ID      string
+	From    string
+	To      []string
+	Subject string
+	Headers map[string][]string
+	Body    string
+}
+
+// MailHogClient is a client for the MailHog API, useful to verify sent emails in integration tests.
+type MailHogClient struct {
+	ctx     context.Context
+	baseURL string
+}
+
+// GetClient returns a MailHogClient for the local mail. The given context bounds its requests.
+func (m *MailLocalMetadata) GetClient(ctx context.Context) *MailHogClient {
+	return &MailHogClient{
+		ctx:     ctx,
+		baseURL: strings.TrimSuffix(m.ConsoleExternalURL.String(), "/api/v2"),
+	}
+}
+
+// ListMessages returns all the received messages, most recent first.
+func (c *MailHogClient) ListMessages() []*MailHogMessage {
+	return c.getMessages("/api/v2/messages?limit=1000")
+}
+
+// FindByRecipient returns the received messages for the given recipient address, most recent first.
+func (c *MailHogClient) FindByRecipient(address string) []*MailHogMessage {
+	return c.getMessages("/api/v2/search?kind=to&limit=1000&query=" + url.QueryEscape(address))
+}
+
+// ClearAll deletes all the received messages.
+func (c *MailHogClient) ClearAll() {
+	c.mustDo(http.MethodDelete, "/api/v1/messages")
+}
+
+type mailHogMessagesResponse struct {
+	Items []struct {
+		ID   string `json:"ID"`
+		From struct {
+			Mailbox string `json:"Mailbox"`
+			Domain  string `json:"Domain"`
+		} `json:"From"`
+		To []struct {
+			Mailbox string `json:"Mailbox"`
+			Domain  string `json:"Domain"`
+		} `json:"To"`
+		Content struct {
+			Headers map[string][]string `json:"Headers"`
+			Body    string              `json:"Body"`
+		} `json:"Content"`
+	} `json:"items"`
+}
+
+func (c *MailHogClient) getMessages(path string) []*MailHogMessage {
+	resp := &mailHogMessagesResponse{}
+	errorz.MaybeMustWrap(json.Unmarshal(c.mustDo(http.MethodGet, path), resp))
+
+	messages := make([]*MailHogMessage, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		message := &MailHogMessage{
+			ID:      item.ID,
+			From:    fmt.Sprintf("%v@%v", item.From.Mailbox, item.From.Domain),
+			To:      make([]string, 0, len(item.To)),
+			Headers: item.Content.Headers,
+			Body:    item.Content.Body,
+		}
+
+		for _, to := range item.To {
+			message.To = append(message.To, fmt.Sprintf("%v@%v", to.Mailbox, to.Domain))
+		}
+
+		if subject := item.Content.Headers["Subject"]; len(subject) > 0 {
+			message.Subject = subject[0]
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages
+}
+
+func (c *MailHogClient) mustDo(method, path string) []byte {
+	req, err := http.NewRequestWithContext(c.ctx, method, c.baseURL+path, nil)
+	errorz.MaybeMustWrap(err)
+
+	resp, err := http.DefaultClient.Do(req)
+	errorz.MaybeMustWrap(err)
+	defer errorz.IgnoreClose(resp.Body)
+
+	buf, err := io.ReadAll(resp.Body)
+	errorz.MaybeMustWrap(err)
+	errorz.Assertf(resp.StatusCode == http.StatusOK, "unexpected status code %v: %v", errorz.A(resp.StatusCode, string(buf)))
+
+	return buf
+}
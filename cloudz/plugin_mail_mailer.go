@@ -0,0 +1,65 @@
+package cloudz
+
+import (
+	"net/smtp"
+	"net/url"
+
+	"github.com/ibrt/golang-errors/errorz"
+
+	"github.com/ibrt/golang-cloud/opz"
+)
+
+// Mailer describes a way to send emails, consistent across stage targets.
+type Mailer interface {
+	Send(from string, to []string, msg []byte) error
+}
+
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer initializes a new Mailer that delivers to the SMTP server at the given "smtp://" URL. Credentials are
+// taken from the URL user info, and are only used if a username is set. STARTTLS is used if supported by the server.
+func NewSMTPMailer(smtpURL *url.URL) Mailer {
+	errorz.Assertf(smtpURL.Scheme == "smtp", "unsupported mailer URL scheme: %v", errorz.A(smtpURL.Scheme), errorz.Prefix(MailPluginName))
+
+	m := &smtpMailer{
+		addr: smtpURL.Host,
+	}
+
+	if username := smtpURL.User.Username(); username != "" {
+		password, _ := smtpURL.User.Password()
+		m.auth = smtp.PlainAuth("", username, password, smtpURL.Hostname())
+	}
+
+	return m
+}
+
+// Send implements the Mailer interface.
+func (m *smtpMailer) Send(from string, to []string, msg []byte) error {
+	return errorz.MaybeWrap(smtp.SendMail(m.addr, m.auth, from, to, msg))
+}
+
+type sesMailer struct {
+	ops                  opz.Operations
+	configurationSetName string
+}
+
+// NewSESMailer initializes a new Mailer that delivers using the SES API. The configuration set name is optional.
+func NewSESMailer(ops opz.Operations, configurationSetName string) Mailer {
+	return &sesMailer{
+		ops:                  ops,
+		configurationSetName: configurationSetName,
+	}
+}
+
+// Send implements the Mailer interface.
+func (m *sesMailer) Send(from string, to []string, msg []byte) (err error) {
+	defer func() {
+		err = errorz.MaybeWrapRecover(recover())
+	}()
+
+	m.ops.SendRawEmail(m.configurationSetName, from, to, msg)
+	return nil
+}
@@ -1,11 +1,18 @@
 package cloudz
 
 import (
+	"fmt"
+	"strings"
+
 	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goautoscaling "github.com/awslabs/goformation/v6/cloudformation/autoscaling"
 	goec2 "github.com/awslabs/goformation/v6/cloudformation/ec2"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	goservicediscovery "github.com/awslabs/goformation/v6/cloudformation/servicediscovery"
 	dctypes "github.com/docker/cli/cli/compose/types"
 	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/numeric/intz"
 	"github.com/ibrt/golang-bites/stringz"
 	"github.com/ibrt/golang-errors/errorz"
 	"github.com/ibrt/golang-validation/vz"
@@ -38,18 +45,41 @@ const (
 	NetworkRefSubnetRouteTableAssociationPrivateB = CloudRef("srt-pri-b")
 	NetworkRefSecurityGroup                       = CloudRef("sg")
 	NetworkRefSecurityGroupIngress                = CloudRef("sgi")
+	NetworkRefNATInstanceSecurityGroup            = CloudRef("nat-sg")
+	NetworkRefNATInstanceNetworkInterface         = CloudRef("nat-eni")
+	NetworkRefNATInstanceRole                     = CloudRef("nat-r")
+	NetworkRefNATInstanceProfile                  = CloudRef("nat-ip")
+	NetworkRefNATInstanceLaunchTemplate           = CloudRef("nat-lt")
+	NetworkRefNATInstanceAutoScalingGroup         = CloudRef("nat-asg")
+	NetworkRefEndpointSecurityGroup               = CloudRef("vpce-sg")
+	NetworkRefEndpointS3                          = CloudRef("vpce-s3")
+	NetworkRefEndpointECRAPI                      = CloudRef("vpce-ecr-api")
+	NetworkRefEndpointECRDKR                      = CloudRef("vpce-ecr-dkr")
+	NetworkRefEndpointSecretsManager              = CloudRef("vpce-sm")
+	NetworkRefEndpointLogs                        = CloudRef("vpce-logs")
+	NetworkRefEndpointKMS                         = CloudRef("vpce-kms")
+	NetworkRefServiceConnectNamespace             = CloudRef("sc-ns")
 	NetworkAttAllocationID                        = CloudAtt("AllocationId")
+	NetworkAttARN                                 = CloudAtt("Arn")
 	NetworkAttCIDRBlock                           = CloudAtt("CidrBlock")
 	NetworkAttDefaultNetworkACL                   = CloudAtt("DefaultNetworkAcl")
 	NetworkAttDefaultSecurityGroup                = CloudAtt("DefaultSecurityGroup")
 	NetworkAttGroupID                             = CloudAtt("GroupId")
 	NetworkAttID                                  = CloudAtt("Id")
 	NetworkAttInternetGatewayID                   = CloudAtt("InternetGatewayId")
+	NetworkAttLatestVersionNumber                 = CloudAtt("LatestVersionNumber")
 	NetworkAttNetworkACLAssociationID             = CloudAtt("NetworkAclAssociationId")
 	NetworkAttRouteTableID                        = CloudAtt("RouteTableId")
 	NetworkAttSubnetID                            = CloudAtt("SubnetId")
 	NetworkAttVPCID                               = CloudAtt("VpcId")
 
+	NetworkEndpointS3             NetworkEndpoint = "s3"
+	NetworkEndpointECRAPI         NetworkEndpoint = "ecr.api"
+	NetworkEndpointECRDKR         NetworkEndpoint = "ecr.dkr"
+	NetworkEndpointSecretsManager NetworkEndpoint = "secretsmanager"
+	NetworkEndpointLogs           NetworkEndpoint = "logs"
+	NetworkEndpointKMS            NetworkEndpoint = "kms"
+
 	CIDRAllDestinations = "0.0.0.0/0"
 	CIDRVPC             = "10.0.0.0/16"
 	CIDRSubnetPublicA   = "10.0.0.0/19"
@@ -61,8 +91,20 @@ const (
 var (
 	_ Network = &networkImpl{}
 	_ Plugin  = &networkImpl{}
+
+	networkEndpointRefs = map[NetworkEndpoint]CloudRef{
+		NetworkEndpointS3:             NetworkRefEndpointS3,
+		NetworkEndpointECRAPI:         NetworkRefEndpointECRAPI,
+		NetworkEndpointECRDKR:         NetworkRefEndpointECRDKR,
+		NetworkEndpointSecretsManager: NetworkRefEndpointSecretsManager,
+		NetworkEndpointLogs:           NetworkRefEndpointLogs,
+		NetworkEndpointKMS:            NetworkRefEndpointKMS,
+	}
 )
 
+// NetworkEndpoint describes a VPC endpoint, by the suffix of its AWS service name (e.g. "com.amazonaws.<region>.s3").
+type NetworkEndpoint string
+
 // NetworkConfigFunc returns the network config for a given Stage.
 type NetworkConfigFunc func(Stage, *NetworkDependencies) *NetworkConfig
 
@@ -72,12 +114,47 @@ type NetworkEventHookFunc func(Network, Event, string)
 // NetworkConfig describes the network config.
 type NetworkConfig struct {
 	Stage     Stage `validate:"required"`
+	Cloud     *NetworkConfigCloud
 	EventHook NetworkEventHookFunc
 }
 
 // MustValidate validates the network config.
-func (c *NetworkConfig) MustValidate(_ StageTarget) {
+func (c *NetworkConfig) MustValidate(stageTarget StageTarget) {
 	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud == nil || c.Cloud.NATInstance == nil || !c.Stage.GetMode().IsProduction(),
+		"NetworkConfigCloud.NATInstance is not allowed in production stages")
+}
+
+// NetworkConfigCloud describes part of the network config.
+// If ImportStackName is set, the exports of the given network stack (e.g. a shared network deployed by another app) are
+// used instead of creating a new network (see ExternalStackExports). If NATInstance is set, the private subnets route
+// through a NAT instance instead of NAT gateways (not allowed in production stages).
+//
+// Endpoints lists the VPC endpoints to create, so that resources in the private subnets can call the corresponding AWS
+// services without traversing NAT. S3 uses a (free) gateway endpoint attached to the private route tables; the others
+// use interface endpoints in the private subnets, with private DNS enabled so the default service hostnames resolve to
+// them.
+//
+// If IsServiceConnectEnabled is set, a Cloud Map namespace is created for ECS Service Connect, so that the ECS based
+// plugins sharing this network can opt in to it (see ECSServiceTemplateConfigServiceConnect).
+type NetworkConfigCloud struct {
+	ImportStackName         string
+	NATInstance             *NetworkConfigCloudNATInstance
+	Endpoints               []NetworkEndpoint `validate:"unique,dive,oneof=s3 ecr.api ecr.dkr secretsmanager logs kms"`
+	IsServiceConnectEnabled bool
+}
+
+// NetworkConfigCloudNATInstance describes part of the network config.
+//
+// The ImageID must be a fck-nat AMI (see https://fck-nat.dev) matching the region and the architecture of the
+// InstanceType (e.g. "t4g.nano"). The instance runs in an auto scaling group of one: on boot, it attaches a static
+// network interface (the target of the private routes) and the elastic IP, so replacements only cause a short outage.
+// Since it is a single instance in a single availability zone, both private subnets lose outbound connectivity while it
+// is replaced or if its zone fails: it is meant as a cheaper alternative to NAT gateways for non-production stages, and
+// it is rejected in production stages.
+type NetworkConfigCloudNATInstance struct {
+	ImageID      string `validate:"required"`
+	InstanceType string `validate:"required"`
 }
 
 // NetworkDependencies describes the network dependencies.
@@ -147,6 +224,12 @@ func (p *networkImpl) GetDependenciesMap() map[Plugin]struct{} {
 func (p *networkImpl) Configure(stage Stage) {
 	p.cfg = p.cfgFunc(stage, p.deps)
 	p.cfg.MustValidate(stage.GetTarget())
+
+	if stage.GetTarget() == Cloud && p.isImported() && p.cloudMetadata == nil {
+		p.cloudMetadata = &NetworkCloudMetadata{
+			Exports: ExternalStackExports(stage, p.cfg.Cloud.ImportStackName),
+		}
+	}
 }
 
 // GetStage implements the Plugin interface.
@@ -178,6 +261,10 @@ func (p *networkImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
 
 // GetCloudTemplate implements the Plugin interface.
 func (p *networkImpl) GetCloudTemplate(_ string) *gocf.Template {
+	if p.isImported() {
+		return nil
+	}
+
 	tpl := gocf.NewTemplate()
 
 	tpl.Resources[NetworkRefVPC.Ref()] = &goec2.VPC{
@@ -265,27 +352,31 @@ func (p *networkImpl) GetCloudTemplate(_ string) *gocf.Template {
 	CloudAddExpRef(tpl, p, NetworkRefEIPA)
 	CloudAddExpGetAtt(tpl, p, NetworkRefEIPA, NetworkAttAllocationID)
 
-	tpl.Resources[NetworkRefNATGatewayA.Ref()] = &goec2.NatGateway{
-		AllocationId: stringz.Ptr(gocf.GetAtt(NetworkRefEIPA.Ref(), NetworkAttAllocationID.Ref())),
-		SubnetId:     gocf.Ref(NetworkRefSubnetPublicA.Ref()),
-		Tags:         CloudGetDefaultTags(NetworkRefNATGatewayA.Name(p)),
-	}
-	CloudAddExpRef(tpl, p, NetworkRefNATGatewayA)
-
-	if p.cfg.Stage.GetMode().IsProduction() {
-		tpl.Resources[NetworkRefEIPB.Ref()] = &goec2.EIP{
-			Domain: stringz.Ptr("vpc"),
-			Tags:   CloudGetDefaultTags(NetworkRefEIPB.Name(p)),
+	if p.usesNATInstance() {
+		p.addNATInstanceResources(tpl)
+	} else {
+		tpl.Resources[NetworkRefNATGatewayA.Ref()] = &goec2.NatGateway{
+			AllocationId: stringz.Ptr(gocf.GetAtt(NetworkRefEIPA.Ref(), NetworkAttAllocationID.Ref())),
+			SubnetId:     gocf.Ref(NetworkRefSubnetPublicA.Ref()),
+			Tags:         CloudGetDefaultTags(NetworkRefNATGatewayA.Name(p)),
 		}
-		CloudAddExpRef(tpl, p, NetworkRefEIPB)
-		CloudAddExpGetAtt(tpl, p, NetworkRefEIPB, NetworkAttAllocationID)
+		CloudAddExpRef(tpl, p, NetworkRefNATGatewayA)
+
+		if p.cfg.Stage.GetMode().IsProduction() {
+			tpl.Resources[NetworkRefEIPB.Ref()] = &goec2.EIP{
+				Domain: stringz.Ptr("vpc"),
+				Tags:   CloudGetDefaultTags(NetworkRefEIPB.Name(p)),
+			}
+			CloudAddExpRef(tpl, p, NetworkRefEIPB)
+			CloudAddExpGetAtt(tpl, p, NetworkRefEIPB, NetworkAttAllocationID)
 
-		tpl.Resources[NetworkRefNATGatewayB.Ref()] = &goec2.NatGateway{
-			AllocationId: stringz.Ptr(gocf.GetAtt(NetworkRefEIPB.Ref(), NetworkAttAllocationID.Ref())),
-			SubnetId:     gocf.Ref(NetworkRefSubnetPublicB.Ref()),
-			Tags:         CloudGetDefaultTags(NetworkRefNATGatewayB.Name(p)),
+			tpl.Resources[NetworkRefNATGatewayB.Ref()] = &goec2.NatGateway{
+				AllocationId: stringz.Ptr(gocf.GetAtt(NetworkRefEIPB.Ref(), NetworkAttAllocationID.Ref())),
+				SubnetId:     gocf.Ref(NetworkRefSubnetPublicB.Ref()),
+				Tags:         CloudGetDefaultTags(NetworkRefNATGatewayB.Name(p)),
+			}
+			CloudAddExpRef(tpl, p, NetworkRefNATGatewayB)
 		}
-		CloudAddExpRef(tpl, p, NetworkRefNATGatewayB)
 	}
 
 	tpl.Resources[NetworkRefRouteTablePrivateA.Ref()] = &goec2.RouteTable{
@@ -295,11 +386,7 @@ func (p *networkImpl) GetCloudTemplate(_ string) *gocf.Template {
 	CloudAddExpRef(tpl, p, NetworkRefRouteTablePrivateA)
 	CloudAddExpGetAtt(tpl, p, NetworkRefRouteTablePrivateA, NetworkAttRouteTableID)
 
-	tpl.Resources[NetworkRefRoutePrivateA.Ref()] = &goec2.Route{
-		DestinationCidrBlock: stringz.Ptr(CIDRAllDestinations),
-		NatGatewayId:         stringz.Ptr(gocf.Ref(NetworkRefNATGatewayA.Ref())),
-		RouteTableId:         gocf.Ref(NetworkRefRouteTablePrivateA.Ref()),
-	}
+	tpl.Resources[NetworkRefRoutePrivateA.Ref()] = p.getPrivateRoute(NetworkRefRouteTablePrivateA, NetworkRefNATGatewayA)
 	CloudAddExpRef(tpl, p, NetworkRefRoutePrivateA)
 
 	tpl.Resources[NetworkRefSubnetPrivateA.Ref()] = &goec2.Subnet{
@@ -327,16 +414,12 @@ func (p *networkImpl) GetCloudTemplate(_ string) *gocf.Template {
 	CloudAddExpRef(tpl, p, NetworkRefRouteTablePrivateB)
 	CloudAddExpGetAtt(tpl, p, NetworkRefRouteTablePrivateB, NetworkAttRouteTableID)
 
-	tpl.Resources[NetworkRefRoutePrivateB.Ref()] = &goec2.Route{
-		DestinationCidrBlock: stringz.Ptr(CIDRAllDestinations),
-		NatGatewayId: func() *string {
-			if p.cfg.Stage.GetMode().IsProduction() {
-				return stringz.Ptr(gocf.Ref(NetworkRefNATGatewayB.Ref()))
-			}
-			return stringz.Ptr(gocf.Ref(NetworkRefNATGatewayA.Ref()))
-		}(),
-		RouteTableId: gocf.Ref(NetworkRefRouteTablePrivateB.Ref()),
-	}
+	tpl.Resources[NetworkRefRoutePrivateB.Ref()] = p.getPrivateRoute(NetworkRefRouteTablePrivateB, func() CloudRef {
+		if p.cfg.Stage.GetMode().IsProduction() {
+			return NetworkRefNATGatewayB
+		}
+		return NetworkRefNATGatewayA
+	}())
 	CloudAddExpRef(tpl, p, NetworkRefRoutePrivateB)
 
 	tpl.Resources[NetworkRefSubnetPrivateB.Ref()] = &goec2.Subnet{
@@ -379,6 +462,20 @@ func (p *networkImpl) GetCloudTemplate(_ string) *gocf.Template {
 		SourceSecurityGroupId: stringz.Ptr(gocf.Ref(NetworkRefSecurityGroup.Ref())),
 	}
 
+	if p.cfg.Cloud != nil && len(p.cfg.Cloud.Endpoints) > 0 {
+		p.addEndpointResources(tpl)
+	}
+
+	if p.cfg.Cloud != nil && p.cfg.Cloud.IsServiceConnectEnabled {
+		tpl.Resources[NetworkRefServiceConnectNamespace.Ref()] = &goservicediscovery.HttpNamespace{
+			Description: stringz.Ptr(NetworkRefServiceConnectNamespace.Name(p)),
+			Name:        NetworkRefServiceConnectNamespace.Name(p),
+			Tags:        CloudGetDefaultTags(NetworkRefServiceConnectNamespace.Name(p)),
+		}
+		CloudAddExpRef(tpl, p, NetworkRefServiceConnectNamespace)
+		CloudAddExpGetAtt(tpl, p, NetworkRefServiceConnectNamespace, NetworkAttARN)
+	}
+
 	return tpl
 }
 
@@ -395,3 +492,195 @@ func (p *networkImpl) EventHook(event Event, buildDirPath string) {
 		p.cfg.EventHook(p, event, buildDirPath)
 	}
 }
+
+func (p *networkImpl) isImported() bool {
+	return p.cfg.Cloud != nil && p.cfg.Cloud.ImportStackName != ""
+}
+
+func (p *networkImpl) usesNATInstance() bool {
+	return p.cfg.Cloud != nil && p.cfg.Cloud.NATInstance != nil
+}
+
+func (p *networkImpl) getPrivateRoute(routeTableRef, natGatewayRef CloudRef) *goec2.Route {
+	route := &goec2.Route{
+		DestinationCidrBlock: stringz.Ptr(CIDRAllDestinations),
+		RouteTableId:         gocf.Ref(routeTableRef.Ref()),
+	}
+
+	if p.usesNATInstance() {
+		route.NetworkInterfaceId = stringz.Ptr(gocf.Ref(NetworkRefNATInstanceNetworkInterface.Ref()))
+	} else {
+		route.NatGatewayId = stringz.Ptr(gocf.Ref(natGatewayRef.Ref()))
+	}
+
+	return route
+}
+
+func (p *networkImpl) addNATInstanceResources(tpl *gocf.Template) {
+	tpl.Resources[NetworkRefNATInstanceSecurityGroup.Ref()] = &goec2.SecurityGroup{
+		GroupDescription: NetworkRefNATInstanceSecurityGroup.Name(p),
+		GroupName:        stringz.Ptr(NetworkRefNATInstanceSecurityGroup.Name(p)),
+		SecurityGroupEgress: &[]goec2.SecurityGroup_Egress{
+			{
+				IpProtocol: "-1",
+				CidrIp:     stringz.Ptr(CIDRAllDestinations),
+			},
+		},
+		SecurityGroupIngress: &[]goec2.SecurityGroup_Ingress{
+			{
+				IpProtocol: "-1",
+				CidrIp:     stringz.Ptr(CIDRVPC),
+			},
+		},
+		VpcId: stringz.Ptr(gocf.Ref(NetworkRefVPC.Ref())),
+		Tags:  CloudGetDefaultTags(NetworkRefNATInstanceSecurityGroup.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, NetworkRefNATInstanceSecurityGroup)
+	CloudAddExpGetAtt(tpl, p, NetworkRefNATInstanceSecurityGroup, NetworkAttGroupID)
+
+	tpl.Resources[NetworkRefNATInstanceNetworkInterface.Ref()] = &goec2.NetworkInterface{
+		Description:     stringz.Ptr(NetworkRefNATInstanceNetworkInterface.Name(p)),
+		GroupSet:        &[]string{gocf.Ref(NetworkRefNATInstanceSecurityGroup.Ref())},
+		SourceDestCheck: boolz.Ptr(false),
+		SubnetId:        gocf.Ref(NetworkRefSubnetPublicA.Ref()),
+		Tags:            CloudGetDefaultTags(NetworkRefNATInstanceNetworkInterface.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, NetworkRefNATInstanceNetworkInterface)
+
+	tpl.Resources[NetworkRefNATInstanceRole.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("ec2.amazonaws.com"),
+		ManagedPolicyArns: &[]string{
+			"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore",
+		},
+		Policies: &[]goiam.Role_Policy{
+			{
+				PolicyName: NetworkPluginName + "-nat-instance",
+				PolicyDocument: NewPolicyDocument(
+					NewPolicyStatement().
+						AddActions(
+							"ec2:AssociateAddress",
+							"ec2:AttachNetworkInterface",
+							"ec2:DisassociateAddress",
+							"ec2:ModifyNetworkInterfaceAttribute").
+						AddResources("*")),
+			},
+		},
+		RoleName: stringz.Ptr(NetworkRefNATInstanceRole.Name(p)),
+		Tags:     CloudGetDefaultTags(NetworkRefNATInstanceRole.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, NetworkRefNATInstanceRole)
+	CloudAddExpGetAtt(tpl, p, NetworkRefNATInstanceRole, NetworkAttARN)
+
+	tpl.Resources[NetworkRefNATInstanceProfile.Ref()] = &goiam.InstanceProfile{
+		InstanceProfileName: stringz.Ptr(NetworkRefNATInstanceProfile.Name(p)),
+		Roles:               []string{gocf.Ref(NetworkRefNATInstanceRole.Ref())},
+	}
+	CloudAddExpRef(tpl, p, NetworkRefNATInstanceProfile)
+	CloudAddExpGetAtt(tpl, p, NetworkRefNATInstanceProfile, NetworkAttARN)
+
+	tpl.Resources[NetworkRefNATInstanceLaunchTemplate.Ref()] = &goec2.LaunchTemplate{
+		LaunchTemplateData: &goec2.LaunchTemplate_LaunchTemplateData{
+			IamInstanceProfile: &goec2.LaunchTemplate_IamInstanceProfile{
+				Arn: stringz.Ptr(gocf.GetAtt(NetworkRefNATInstanceProfile.Ref(), NetworkAttARN.Ref())),
+			},
+			ImageId:      stringz.Ptr(p.cfg.Cloud.NATInstance.ImageID),
+			InstanceType: stringz.Ptr(p.cfg.Cloud.NATInstance.InstanceType),
+			MetadataOptions: &goec2.LaunchTemplate_MetadataOptions{
+				HttpTokens: stringz.Ptr("required"),
+			},
+			NetworkInterfaces: &[]goec2.LaunchTemplate_NetworkInterface{
+				{
+					AssociatePublicIpAddress: boolz.Ptr(true),
+					DeviceIndex:              intz.Ptr(0),
+					Groups:                   &[]string{gocf.Ref(NetworkRefNATInstanceSecurityGroup.Ref())},
+				},
+			},
+			TagSpecifications: &[]goec2.LaunchTemplate_TagSpecification{
+				{
+					ResourceType: stringz.Ptr("instance"),
+					Tags:         CloudGetDefaultTags(NetworkRefNATInstanceAutoScalingGroup.Name(p)),
+				},
+			},
+			UserData: stringz.Ptr(gocf.Base64(gocf.Sub(strings.Join([]string{
+				"#!/bin/bash",
+				fmt.Sprintf(`echo "eni_id=${%v}" >> /etc/fck-nat.conf`, NetworkRefNATInstanceNetworkInterface.Ref()),
+				fmt.Sprintf(`echo "eip_id=${%v.%v}" >> /etc/fck-nat.conf`, NetworkRefEIPA.Ref(), NetworkAttAllocationID.Ref()),
+				"service fck-nat restart",
+			}, "\n")))),
+		},
+		LaunchTemplateName: stringz.Ptr(NetworkRefNATInstanceLaunchTemplate.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, NetworkRefNATInstanceLaunchTemplate)
+	CloudAddExpGetAtt(tpl, p, NetworkRefNATInstanceLaunchTemplate, NetworkAttLatestVersionNumber)
+
+	tpl.Resources[NetworkRefNATInstanceAutoScalingGroup.Ref()] = &goautoscaling.AutoScalingGroup{
+		AutoScalingGroupName: stringz.Ptr(NetworkRefNATInstanceAutoScalingGroup.Name(p)),
+		LaunchTemplate: &goautoscaling.AutoScalingGroup_LaunchTemplateSpecification{
+			LaunchTemplateId: stringz.Ptr(gocf.Ref(NetworkRefNATInstanceLaunchTemplate.Ref())),
+			Version:          gocf.GetAtt(NetworkRefNATInstanceLaunchTemplate.Ref(), NetworkAttLatestVersionNumber.Ref()),
+		},
+		MaxSize: "1",
+		MinSize: "1",
+		Tags: &[]goautoscaling.AutoScalingGroup_TagProperty{
+			{
+				Key:               "Name",
+				Value:             NetworkRefNATInstanceAutoScalingGroup.Name(p),
+				PropagateAtLaunch: true,
+			},
+		},
+		VPCZoneIdentifier: &[]string{gocf.Ref(NetworkRefSubnetPublicA.Ref())},
+	}
+	CloudAddExpRef(tpl, p, NetworkRefNATInstanceAutoScalingGroup)
+}
+
+func (p *networkImpl) addEndpointResources(tpl *gocf.Template) {
+	hasInterfaceEndpoints := false
+
+	for _, endpoint := range p.cfg.Cloud.Endpoints {
+		ref := networkEndpointRefs[endpoint]
+
+		vpcEndpoint := &goec2.VPCEndpoint{
+			ServiceName: gocf.Sub(fmt.Sprintf("com.amazonaws.${AWS::Region}.%v", endpoint)),
+			VpcId:       gocf.Ref(NetworkRefVPC.Ref()),
+		}
+
+		if endpoint == NetworkEndpointS3 {
+			vpcEndpoint.VpcEndpointType = stringz.Ptr("Gateway")
+			vpcEndpoint.RouteTableIds = &[]string{
+				gocf.Ref(NetworkRefRouteTablePrivateA.Ref()),
+				gocf.Ref(NetworkRefRouteTablePrivateB.Ref()),
+			}
+		} else {
+			hasInterfaceEndpoints = true
+			vpcEndpoint.VpcEndpointType = stringz.Ptr("Interface")
+			vpcEndpoint.PrivateDnsEnabled = boolz.Ptr(true)
+			vpcEndpoint.SecurityGroupIds = &[]string{gocf.Ref(NetworkRefEndpointSecurityGroup.Ref())}
+			vpcEndpoint.SubnetIds = &[]string{
+				gocf.Ref(NetworkRefSubnetPrivateA.Ref()),
+				gocf.Ref(NetworkRefSubnetPrivateB.Ref()),
+			}
+		}
+
+		tpl.Resources[ref.Ref()] = vpcEndpoint
+		CloudAddExpRef(tpl, p, ref)
+	}
+
+	if hasInterfaceEndpoints {
+		tpl.Resources[NetworkRefEndpointSecurityGroup.Ref()] = &goec2.SecurityGroup{
+			GroupDescription: NetworkRefEndpointSecurityGroup.Name(p),
+			GroupName:        stringz.Ptr(NetworkRefEndpointSecurityGroup.Name(p)),
+			SecurityGroupIngress: &[]goec2.SecurityGroup_Ingress{
+				{
+					IpProtocol: "tcp",
+					CidrIp:     stringz.Ptr(CIDRVPC),
+					FromPort:   intz.Ptr(443),
+					ToPort:     intz.Ptr(443),
+				},
+			},
+			VpcId: stringz.Ptr(gocf.Ref(NetworkRefVPC.Ref())),
+			Tags:  CloudGetDefaultTags(NetworkRefEndpointSecurityGroup.Name(p)),
+		}
+		CloudAddExpRef(tpl, p, NetworkRefEndpointSecurityGroup)
+		CloudAddExpGetAtt(tpl, p, NetworkRefEndpointSecurityGroup, NetworkAttGroupID)
+	}
+}
@@ -0,0 +1,430 @@
+package cloudz
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goaps "github.com/awslabs/goformation/v6/cloudformation/aps"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+)
+
+// Observability constants.
+const (
+	ObservabilityPluginDisplayName         = "Observability"
+	ObservabilityPluginName                = "observability"
+	ObservabilityRefPrometheusWorkspace    = CloudRef("pw")
+	ObservabilityRefGrafanaRole            = CloudRef("gr")
+	ObservabilityRefGrafanaWorkspace       = CloudRef("gw")
+	ObservabilityAttARN                    = CloudAtt("Arn")
+	ObservabilityAttEndpoint               = CloudAtt("Endpoint")
+	ObservabilityAttPrometheusEndpoint     = CloudAtt("PrometheusEndpoint")
+	ObservabilityAttWorkspaceID            = CloudAtt("WorkspaceId")
+	ObservabilityDefaultScrapeMetricsPath  = "/metrics"
+	ObservabilityDefaultAuthenticationType = "AWS_SSO"
+
+	observabilityPrometheusPort = 9090
+	observabilityGrafanaPort    = 3000
+)
+
+var (
+	_ Observability = &observabilityImpl{}
+	_ Plugin        = &observabilityImpl{}
+)
+
+// ObservabilityConfigFunc returns the observability config for a given Stage.
+type ObservabilityConfigFunc func(Stage, *ObservabilityDependencies) *ObservabilityConfig
+
+// ObservabilityEventHookFunc describes an observability event hook.
+type ObservabilityEventHookFunc func(Observability, Event, string)
+
+// ObservabilityConfig describes the observability config.
+//
+// Locally, it runs Prometheus (scraping the ScrapeTargets, and accepting remote writes) and Grafana (with Prometheus as
+// data source, and the dashboards in DashboardsDirPath provisioned). In the cloud, it provisions an Amazon Managed
+// Service for Prometheus workspace (see Observability.GetRemoteWriteRolePolicy) and an Amazon Managed Grafana workspace
+// with the Prometheus and CloudWatch data sources, so the metrics of the ECS, RDS and Lambda resources of the stage are
+// available out of the box. Dashboards must be imported in the Grafana workspace.
+type ObservabilityConfig struct {
+	Stage             Stage  `validate:"required"`
+	Name              string `validate:"required,resource-name"`
+	DashboardsDirPath string
+	Local             *ObservabilityConfigLocal
+	Cloud             *ObservabilityConfigCloud
+	EventHook         ObservabilityEventHookFunc
+}
+
+// MustValidate validates the observability config.
+func (c *ObservabilityConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing ObservabilityConfig.Local")
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing ObservabilityConfig.Cloud")
+}
+
+// ObservabilityConfigLocal describes part of the observability config.
+type ObservabilityConfigLocal struct {
+	GrafanaExternalPort    uint16                                  `validate:"required"`
+	PrometheusExternalPort uint16                                  `validate:"required"`
+	ScrapeTargets          []*ObservabilityConfigLocalScrapeTarget `validate:"dive,required"`
+}
+
+// ObservabilityConfigLocalScrapeTarget describes a local Prometheus scrape target.
+// The Target is a "host:port" reachable from the Prometheus container, e.g. the internal host of a ContainerService.
+type ObservabilityConfigLocalScrapeTarget struct {
+	JobName     string `validate:"required,resource-name"`
+	Target      string `validate:"required,hostname_port"`
+	MetricsPath string `validate:"omitempty,startswith=/"`
+}
+
+// ObservabilityConfigCloud describes part of the observability config.
+// The AuthenticationTypes of the Grafana workspace default to AWS IAM Identity Center ("AWS_SSO").
+type ObservabilityConfigCloud struct {
+	AuthenticationTypes []string `validate:"dive,oneof=AWS_SSO SAML"`
+}
+
+// ObservabilityDependencies describes the observability dependencies.
+type ObservabilityDependencies struct {
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the observability dependencies.
+func (d *ObservabilityDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// ObservabilityLocalMetadata describes the observability local metadata.
+type ObservabilityLocalMetadata struct {
+	GrafanaContainerName     string
+	GrafanaExternalURL       *url.URL
+	PrometheusContainerName  string
+	PrometheusExternalURL    *url.URL
+	PrometheusInternalURL    *url.URL
+	PrometheusRemoteWriteURL *url.URL // internal
+}
+
+// ObservabilityCloudMetadata describes the observability cloud metadata.
+type ObservabilityCloudMetadata struct {
+	Exports                  CloudExports
+	GrafanaURL               *url.URL
+	PrometheusRemoteWriteURL *url.URL
+}
+
+// Observability describes a Prometheus and Grafana observability stack.
+type Observability interface {
+	Plugin
+	GetConfig() *ObservabilityConfig
+	GetLocalMetadata() *ObservabilityLocalMetadata
+	GetCloudMetadata(require bool) *ObservabilityCloudMetadata
+	GetRemoteWriteRolePolicy() goiam.Role_Policy
+}
+
+type observabilityImpl struct {
+	cfgFunc       ObservabilityConfigFunc
+	deps          *ObservabilityDependencies
+	cfg           *ObservabilityConfig
+	localMetadata *ObservabilityLocalMetadata
+	cloudMetadata *ObservabilityCloudMetadata
+}
+
+// NewObservability initializes a new Observability.
+func NewObservability(cfgFunc ObservabilityConfigFunc, deps *ObservabilityDependencies) Observability {
+	deps.MustValidate()
+
+	return &observabilityImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*observabilityImpl) GetDisplayName() string {
+	return ObservabilityPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *observabilityImpl) GetName() string {
+	return ObservabilityPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *observabilityImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *observabilityImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{}
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *observabilityImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *observabilityImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(ObservabilityPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the Observability interface.
+func (p *observabilityImpl) GetConfig() *ObservabilityConfig {
+	return p.cfg
+}
+
+// GetLocalMetadata implements the Observability interface.
+func (p *observabilityImpl) GetLocalMetadata() *ObservabilityLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(ObservabilityPluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the Observability interface.
+func (p *observabilityImpl) GetCloudMetadata(require bool) *ObservabilityCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(ObservabilityPluginName))
+	return p.cloudMetadata
+}
+
+// GetRemoteWriteRolePolicy implements the Observability interface.
+func (p *observabilityImpl) GetRemoteWriteRolePolicy() goiam.Role_Policy {
+	return goiam.Role_Policy{
+		PolicyName: ObservabilityPluginName + "-remote-write",
+		PolicyDocument: NewPolicyDocument(
+			NewPolicyStatement().
+				AddActions("aps:RemoteWrite").
+				AddResources(p.GetCloudMetadata(true).Exports.GetRef(ObservabilityRefPrometheusWorkspace))),
+	}
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *observabilityImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *observabilityImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath string) {
+	prometheusContainerName := LocalGetContainerName(p, "prometheus")
+	grafanaContainerName := LocalGetContainerName(p, "grafana")
+	versions := p.cfg.Stage.GetConfig().App.GetConfig().GetVersions()
+
+	p.localMetadata = &ObservabilityLocalMetadata{
+		GrafanaContainerName:     grafanaContainerName,
+		GrafanaExternalURL:       urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.GrafanaExternalPort)),
+		PrometheusContainerName:  prometheusContainerName,
+		PrometheusExternalURL:    urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.PrometheusExternalPort)),
+		PrometheusInternalURL:    urlz.MustParse(fmt.Sprintf("http://%v:%v", prometheusContainerName, observabilityPrometheusPort)),
+		PrometheusRemoteWriteURL: urlz.MustParse(fmt.Sprintf("http://%v:%v/api/v1/write", prometheusContainerName, observabilityPrometheusPort)),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          prometheusContainerName,
+		ContainerName: prometheusContainerName,
+		Command: dctypes.ShellCommand{
+			"--config.file=/etc/prometheus/prometheus.yml",
+			"--web.enable-remote-write-receiver",
+		},
+		Image:    LocalGetImage(p, "prom/prometheus:v"+versions.Prometheus),
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    observabilityPrometheusPort,
+				Published: uint32(p.cfg.Local.PrometheusExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+		Volumes: []dctypes.ServiceVolumeConfig{
+			{
+				Type:     "bind",
+				Source:   filez.MustAbs(filepath.Join(buildDirPath, "prometheus.yml")),
+				Target:   "/etc/prometheus/prometheus.yml",
+				ReadOnly: true,
+			},
+		},
+	})
+
+	grafanaVolumes := []dctypes.ServiceVolumeConfig{
+		{
+			Type:     "bind",
+			Source:   filez.MustAbs(filepath.Join(buildDirPath, "grafana-provisioning")),
+			Target:   "/etc/grafana/provisioning",
+			ReadOnly: true,
+		},
+	}
+
+	if p.cfg.DashboardsDirPath != "" {
+		grafanaVolumes = append(grafanaVolumes, dctypes.ServiceVolumeConfig{
+			Type:     "bind",
+			Source:   filez.MustAbs(p.cfg.DashboardsDirPath),
+			Target:   "/var/lib/grafana/dashboards",
+			ReadOnly: true,
+		})
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          grafanaContainerName,
+		ContainerName: grafanaContainerName,
+		DependsOn: []string{
+			prometheusContainerName,
+		},
+		Environment: map[string]*string{
+			"GF_AUTH_ANONYMOUS_ENABLED":      stringz.Ptr("true"),
+			"GF_AUTH_ANONYMOUS_ORG_ROLE":     stringz.Ptr("Admin"),
+			"GF_AUTH_DISABLE_LOGIN_FORM":     stringz.Ptr("true"),
+			"GF_ANALYTICS_REPORTING_ENABLED": stringz.Ptr("false"),
+		},
+		Image:    LocalGetImage(p, "grafana/grafana:"+versions.Grafana),
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    observabilityGrafanaPort,
+				Published: uint32(p.cfg.Local.GrafanaExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+		Volumes: grafanaVolumes,
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *observabilityImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	tpl.Resources[ObservabilityRefPrometheusWorkspace.Ref()] = &goaps.Workspace{
+		Alias: stringz.Ptr(ObservabilityRefPrometheusWorkspace.Name(p)),
+		Tags:  CloudGetDefaultTags(ObservabilityRefPrometheusWorkspace.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, ObservabilityRefPrometheusWorkspace)
+	CloudAddExpGetAtt(tpl, p, ObservabilityRefPrometheusWorkspace, ObservabilityAttPrometheusEndpoint)
+	CloudAddExpGetAtt(tpl, p, ObservabilityRefPrometheusWorkspace, ObservabilityAttWorkspaceID)
+
+	tpl.Resources[ObservabilityRefGrafanaRole.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("grafana.amazonaws.com"),
+		ManagedPolicyArns: &[]string{
+			"arn:aws:iam::aws:policy/service-role/AmazonGrafanaCloudWatchAccess",
+		},
+		Policies: &[]goiam.Role_Policy{
+			{
+				PolicyName: ObservabilityPluginName + "-prometheus-query",
+				PolicyDocument: NewPolicyDocument(
+					NewPolicyStatement().
+						AddActions(
+							"aps:DescribeWorkspace",
+							"aps:GetLabels",
+							"aps:GetMetricMetadata",
+							"aps:GetSeries",
+							"aps:QueryMetrics").
+						AddResources(gocf.Ref(ObservabilityRefPrometheusWorkspace.Ref())),
+					NewPolicyStatement().
+						AddActions("aps:ListWorkspaces").
+						AddResources("*")),
+			},
+		},
+		RoleName: stringz.Ptr(ObservabilityRefGrafanaRole.Name(p)),
+		Tags:     CloudGetDefaultTags(ObservabilityRefGrafanaRole.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, ObservabilityRefGrafanaRole)
+	CloudAddExpGetAtt(tpl, p, ObservabilityRefGrafanaRole, ObservabilityAttARN)
+
+	// Note: the Grafana workspace is not supported by goformation yet.
+	tpl.Resources[ObservabilityRefGrafanaWorkspace.Ref()] = &gocf.CustomResource{
+		Type: "AWS::Grafana::Workspace",
+		Properties: map[string]interface{}{
+			"AccountAccessType":       "CURRENT_ACCOUNT",
+			"AuthenticationProviders": p.getAuthenticationTypes(),
+			"DataSources":             []string{"CLOUDWATCH", "PROMETHEUS"},
+			"Description":             ObservabilityRefGrafanaWorkspace.Name(p),
+			"Name":                    ObservabilityRefGrafanaWorkspace.Name(p),
+			"PermissionType":          "CUSTOMER_MANAGED",
+			"RoleArn":                 gocf.GetAtt(ObservabilityRefGrafanaRole.Ref(), ObservabilityAttARN.Ref()),
+		},
+	}
+	CloudAddExpRef(tpl, p, ObservabilityRefGrafanaWorkspace)
+	CloudAddExpGetAtt(tpl, p, ObservabilityRefGrafanaWorkspace, ObservabilityAttEndpoint)
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *observabilityImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+	prometheusEndpoint := exports.GetAtt(ObservabilityRefPrometheusWorkspace, ObservabilityAttPrometheusEndpoint)
+
+	p.cloudMetadata = &ObservabilityCloudMetadata{
+		Exports:                  exports,
+		GrafanaURL:               urlz.MustParse(fmt.Sprintf("https://%v", exports.GetAtt(ObservabilityRefGrafanaWorkspace, ObservabilityAttEndpoint))),
+		PrometheusRemoteWriteURL: urlz.MustParse(strings.TrimSuffix(prometheusEndpoint, "/") + "/api/v1/remote_write"),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *observabilityImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case LocalBeforeCreateEvent:
+		p.localBeforeCreateEventHook(buildDirPath)
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *observabilityImpl) localBeforeCreateEventHook(buildDirPath string) {
+	filez.MustPrepareDir(buildDirPath, 0777)
+
+	prometheusTemplateData := assets.ObservabilityPrometheusYMLTemplateData{
+		Port:          observabilityPrometheusPort,
+		ScrapeTargets: make([]*assets.ObservabilityPrometheusYMLTemplateDataScrapeTarget, 0, len(p.cfg.Local.ScrapeTargets)),
+	}
+
+	for _, scrapeTarget := range p.cfg.Local.ScrapeTargets {
+		metricsPath := scrapeTarget.MetricsPath
+		if metricsPath == "" {
+			metricsPath = ObservabilityDefaultScrapeMetricsPath
+		}
+
+		prometheusTemplateData.ScrapeTargets = append(prometheusTemplateData.ScrapeTargets,
+			&assets.ObservabilityPrometheusYMLTemplateDataScrapeTarget{
+				JobName:     scrapeTarget.JobName,
+				MetricsPath: metricsPath,
+				Target:      scrapeTarget.Target,
+			})
+	}
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "prometheus.yml"), 0777, 0666,
+		templatez.MustParseAndExecuteText(assets.ObservabilityPrometheusYMLTemplateAsset, prometheusTemplateData))
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "grafana-provisioning", "datasources", "datasources.yml"), 0777, 0666,
+		templatez.MustParseAndExecuteText(assets.ObservabilityGrafanaDatasourcesYMLTemplateAsset,
+			assets.ObservabilityGrafanaDatasourcesYMLTemplateData{
+				PrometheusURL: p.localMetadata.PrometheusInternalURL.String(),
+			}))
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "grafana-provisioning", "dashboards", "dashboards.yml"), 0777, 0666,
+		assets.ObservabilityGrafanaDashboardsYMLAsset)
+}
+
+func (p *observabilityImpl) getAuthenticationTypes() []string {
+	if len(p.cfg.Cloud.AuthenticationTypes) == 0 {
+		return []string{ObservabilityDefaultAuthenticationType}
+	}
+	return p.cfg.Cloud.AuthenticationTypes
+}
@@ -0,0 +1,62 @@
+package cloudz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObservability_LocalBeforeCreateEventHook(t *testing.T) {
+	buildDirPath := t.TempDir()
+
+	p := &observabilityImpl{
+		cfg: &ObservabilityConfig{
+			Local: &ObservabilityConfigLocal{
+				ScrapeTargets: []*ObservabilityConfigLocalScrapeTarget{
+					{
+						JobName: "api",
+						Target:  "app-local-container-service-api:8080",
+					},
+					{
+						JobName:     "worker",
+						Target:      "app-local-container-service-worker:9100",
+						MetricsPath: "/internal/metrics",
+					},
+				},
+			},
+		},
+		localMetadata: &ObservabilityLocalMetadata{
+			PrometheusInternalURL: urlz.MustParse("http://app-local-observability-main-prometheus:9090"),
+		},
+	}
+
+	p.localBeforeCreateEventHook(buildDirPath)
+
+	buf, err := os.ReadFile(filepath.Join(buildDirPath, "prometheus.yml"))
+	require.NoError(t, err)
+	require.Equal(t, `global:
+  scrape_interval: 15s
+
+scrape_configs:
+  - job_name: "prometheus"
+    static_configs:
+      - targets: ["localhost:9090"]
+  - job_name: "api"
+    metrics_path: "/metrics"
+    static_configs:
+      - targets: ["app-local-container-service-api:8080"]
+  - job_name: "worker"
+    metrics_path: "/internal/metrics"
+    static_configs:
+      - targets: ["app-local-container-service-worker:9100"]
+`, string(buf))
+
+	buf, err = os.ReadFile(filepath.Join(buildDirPath, "grafana-provisioning", "datasources", "datasources.yml"))
+	require.NoError(t, err)
+	require.Contains(t, string(buf), `url: "http://app-local-observability-main-prometheus:9090"`)
+
+	require.FileExists(t, filepath.Join(buildDirPath, "grafana-provisioning", "dashboards", "dashboards.yml"))
+}
@@ -0,0 +1,357 @@
+package cloudz
+
+import (
+	"fmt"
+	"net/url"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goopensearch "github.com/awslabs/goformation/v6/cloudformation/opensearchservice"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// OpenSearch constants.
+const (
+	OpenSearchPluginDisplayName = "OpenSearch"
+	OpenSearchPluginName        = "opensearch"
+	OpenSearchRefDomain         = CloudRef("d")
+	OpenSearchAttARN            = CloudAtt("Arn")
+	OpenSearchAttDomainEndpoint = CloudAtt("DomainEndpoint")
+
+	openSearchCloudVersion   = "OpenSearch_1.3"
+	openSearchPort           = 9200
+	openSearchDashboardsPort = 5601
+	openSearchMasterUsername = "admin"
+)
+
+var (
+	_ OpenSearch = &openSearchImpl{}
+	_ Plugin     = &openSearchImpl{}
+)
+
+// OpenSearchConfigFunc returns the opensearch config for a given Stage.
+type OpenSearchConfigFunc func(Stage, *OpenSearchDependencies) *OpenSearchConfig
+
+// OpenSearchEventHookFunc describes an opensearch event hook.
+type OpenSearchEventHookFunc func(OpenSearch, Event, string)
+
+// OpenSearchConfig describes the opensearch config.
+type OpenSearchConfig struct {
+	Stage     Stage `validate:"required"`
+	Local     *OpenSearchConfigLocal
+	Cloud     *OpenSearchConfigCloud
+	EventHook OpenSearchEventHookFunc
+}
+
+// MustValidate validates the opensearch config.
+func (c *OpenSearchConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing OpenSearchConfig.Cloud")
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing OpenSearchConfig.Local")
+	errorz.Assertf(stageTarget == Local || !c.Stage.GetMode().IsProduction() || c.Cloud.InstanceCount%2 == 0,
+		"OpenSearchConfigCloud.InstanceCount must be even in production stages")
+}
+
+// OpenSearchConfigLocal describes part of the opensearch config.
+type OpenSearchConfigLocal struct {
+	ExternalPort           uint16 `validate:"required"`
+	DashboardsExternalPort uint16 `validate:"required"`
+}
+
+// OpenSearchConfigCloud describes part of the opensearch config.
+// In production stages, InstanceCount must be even, as the domain is spread across two availability zones.
+type OpenSearchConfigCloud struct {
+	MasterPassword string `validate:"required,min=16"`
+	InstanceType   string `validate:"required"`
+	InstanceCount  int    `validate:"required,min=1"`
+	VolumeSizeGBs  int    `validate:"required,min=10"`
+}
+
+// OpenSearchDependencies describes the opensearch dependencies.
+type OpenSearchDependencies struct {
+	Network           Network `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the opensearch dependencies.
+func (d *OpenSearchDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// OpenSearchLocalMetadata describes the opensearch local metadata.
+type OpenSearchLocalMetadata struct {
+	ContainerName           string
+	DashboardsContainerName string
+	ExternalURL             *url.URL
+	InternalURL             *url.URL
+	DashboardsExternalURL   *url.URL
+}
+
+// OpenSearchCloudMetadata describes the opensearch cloud metadata.
+type OpenSearchCloudMetadata struct {
+	Exports       CloudExports
+	URL           *url.URL
+	DashboardsURL *url.URL
+}
+
+// GetARN returns the domain ARN.
+func (m *OpenSearchCloudMetadata) GetARN() string {
+	return m.Exports.GetAtt(OpenSearchRefDomain, OpenSearchAttARN)
+}
+
+// GetEndpoint returns the domain endpoint.
+func (m *OpenSearchCloudMetadata) GetEndpoint() string {
+	return m.Exports.GetAtt(OpenSearchRefDomain, OpenSearchAttDomainEndpoint)
+}
+
+// OpenSearch describes an opensearch.
+type OpenSearch interface {
+	Plugin
+	GetConfig() *OpenSearchConfig
+	GetDependencies() *OpenSearchDependencies
+	GetLocalMetadata() *OpenSearchLocalMetadata
+	GetCloudMetadata(require bool) *OpenSearchCloudMetadata
+}
+
+type openSearchImpl struct {
+	cfgFunc       OpenSearchConfigFunc
+	deps          *OpenSearchDependencies
+	cfg           *OpenSearchConfig
+	localMetadata *OpenSearchLocalMetadata
+	cloudMetadata *OpenSearchCloudMetadata
+}
+
+// NewOpenSearch initializes a new OpenSearch.
+func NewOpenSearch(cfgFunc OpenSearchConfigFunc, deps *OpenSearchDependencies) OpenSearch {
+	deps.MustValidate()
+
+	return &openSearchImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*openSearchImpl) GetDisplayName() string {
+	return OpenSearchPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *openSearchImpl) GetName() string {
+	return OpenSearchPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *openSearchImpl) GetInstanceName() *string {
+	return nil
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *openSearchImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Network: {},
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *openSearchImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *openSearchImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(OpenSearchPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the OpenSearch interface.
+func (p *openSearchImpl) GetConfig() *OpenSearchConfig {
+	return p.cfg
+}
+
+// GetDependencies implements the OpenSearch interface.
+func (p *openSearchImpl) GetDependencies() *OpenSearchDependencies {
+	return p.deps
+}
+
+// GetLocalMetadata implements the OpenSearch interface.
+func (p *openSearchImpl) GetLocalMetadata() *OpenSearchLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(OpenSearchPluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the OpenSearch interface.
+func (p *openSearchImpl) GetCloudMetadata(require bool) *OpenSearchCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(OpenSearchPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *openSearchImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *openSearchImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
+	containerName := LocalGetContainerName(p)
+	dashboardsContainerName := LocalGetContainerName(p, "dashboards")
+
+	p.localMetadata = &OpenSearchLocalMetadata{
+		ContainerName:           containerName,
+		DashboardsContainerName: dashboardsContainerName,
+		ExternalURL:             urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.ExternalPort)),
+		InternalURL:             urlz.MustParse(fmt.Sprintf("http://%v:%v", containerName, openSearchPort)),
+		DashboardsExternalURL:   urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.DashboardsExternalPort)),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          containerName,
+		ContainerName: containerName,
+		Environment: map[string]*string{
+			"discovery.type":              stringz.Ptr("single-node"),
+			"DISABLE_INSTALL_DEMO_CONFIG": stringz.Ptr("true"),
+			"DISABLE_SECURITY_PLUGIN":     stringz.Ptr("true"),
+			"OPENSEARCH_JAVA_OPTS":        stringz.Ptr("-Xms512m -Xmx512m"),
+		},
+		Image:    LocalGetImage(p, "opensearchproject/opensearch:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().OpenSearch),
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    openSearchPort,
+				Published: uint32(p.cfg.Local.ExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+	})
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          dashboardsContainerName,
+		ContainerName: dashboardsContainerName,
+		DependsOn: []string{
+			containerName,
+		},
+		Environment: map[string]*string{
+			"OPENSEARCH_HOSTS":                   stringz.Ptr(fmt.Sprintf(`["%v"]`, p.localMetadata.InternalURL.String())),
+			"DISABLE_SECURITY_DASHBOARDS_PLUGIN": stringz.Ptr("true"),
+		},
+		Image:    LocalGetImage(p, "opensearchproject/opensearch-dashboards:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().OpenSearch),
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    openSearchDashboardsPort,
+				Published: uint32(p.cfg.Local.DashboardsExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *openSearchImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+	network := p.deps.Network.GetCloudMetadata(true)
+
+	domain := &goopensearch.Domain{
+		AccessPolicies: func() *interface{} {
+			// Note: access is restricted by VPC placement and fine-grained access control.
+			var accessPolicies interface{} = NewPolicyDocument(
+				NewPolicyStatement().
+					SetAnyRootAccountPrincipal().
+					AddActions("es:*").
+					AddResources(gocf.Sub("arn:aws:es:${AWS::Region}:${AWS::AccountId}:domain/*")))
+			return &accessPolicies
+		}(),
+		AdvancedSecurityOptions: &goopensearch.Domain_AdvancedSecurityOptionsInput{
+			Enabled:                     boolz.Ptr(true),
+			InternalUserDatabaseEnabled: boolz.Ptr(true),
+			MasterUserOptions: &goopensearch.Domain_MasterUserOptions{
+				MasterUserName:     stringz.Ptr(openSearchMasterUsername),
+				MasterUserPassword: stringz.Ptr(p.cfg.Cloud.MasterPassword),
+			},
+		},
+		ClusterConfig: &goopensearch.Domain_ClusterConfig{
+			InstanceCount: intz.Ptr(p.cfg.Cloud.InstanceCount),
+			InstanceType:  stringz.Ptr(p.cfg.Cloud.InstanceType),
+		},
+		DomainEndpointOptions: &goopensearch.Domain_DomainEndpointOptions{
+			EnforceHTTPS:      boolz.Ptr(true),
+			TLSSecurityPolicy: stringz.Ptr("Policy-Min-TLS-1-2-2019-07"),
+		},
+		EBSOptions: &goopensearch.Domain_EBSOptions{
+			EBSEnabled: boolz.Ptr(true),
+			VolumeSize: intz.Ptr(p.cfg.Cloud.VolumeSizeGBs),
+			VolumeType: stringz.Ptr("gp2"),
+		},
+		EncryptionAtRestOptions: &goopensearch.Domain_EncryptionAtRestOptions{
+			Enabled: boolz.Ptr(true),
+		},
+		EngineVersion: stringz.Ptr(openSearchCloudVersion),
+		NodeToNodeEncryptionOptions: &goopensearch.Domain_NodeToNodeEncryptionOptions{
+			Enabled: boolz.Ptr(true),
+		},
+		VPCOptions: &goopensearch.Domain_VPCOptions{
+			SecurityGroupIds: &[]string{
+				network.Exports.GetRef(NetworkRefSecurityGroup),
+			},
+		},
+		Tags: CloudGetDefaultTags(OpenSearchRefDomain.Name(p)),
+	}
+
+	if p.cfg.Stage.GetMode().IsProduction() {
+		domain.ClusterConfig.ZoneAwarenessEnabled = boolz.Ptr(true)
+		domain.ClusterConfig.ZoneAwarenessConfig = &goopensearch.Domain_ZoneAwarenessConfig{
+			AvailabilityZoneCount: intz.Ptr(2),
+		}
+		domain.VPCOptions.SubnetIds = &[]string{
+			network.Exports.GetRef(NetworkRefSubnetPrivateA),
+			network.Exports.GetRef(NetworkRefSubnetPrivateB),
+		}
+	} else {
+		domain.VPCOptions.SubnetIds = &[]string{
+			network.Exports.GetRef(NetworkRefSubnetPrivateA),
+		}
+	}
+
+	tpl.Resources[OpenSearchRefDomain.Ref()] = domain
+	CloudAddExpRef(tpl, p, OpenSearchRefDomain)
+	CloudAddExpGetAtt(tpl, p, OpenSearchRefDomain, OpenSearchAttARN)
+	CloudAddExpGetAtt(tpl, p, OpenSearchRefDomain, OpenSearchAttDomainEndpoint)
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *openSearchImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+	endpoint := exports.GetAtt(OpenSearchRefDomain, OpenSearchAttDomainEndpoint)
+
+	p.cloudMetadata = &OpenSearchCloudMetadata{
+		Exports: exports,
+		URL: &url.URL{
+			Scheme: "https",
+			User:   url.UserPassword(openSearchMasterUsername, p.cfg.Cloud.MasterPassword),
+			Host:   endpoint,
+		},
+		DashboardsURL: urlz.MustParse(fmt.Sprintf("https://%v/_dashboards", endpoint)),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *openSearchImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
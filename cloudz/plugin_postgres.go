@@ -1,6 +1,7 @@
 package cloudz
 
 import (
+	"bytes"
 	"fmt"
 	"net/url"
 	"path/filepath"
@@ -22,6 +23,7 @@ import (
 	"github.com/ibrt/golang-validation/vz"
 
 	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+	"github.com/ibrt/golang-cloud/opz"
 )
 
 // Postgres constants.
@@ -38,15 +40,15 @@ const (
 	PostgresAttEndpointAddress  = CloudAtt("Endpoint.Address")
 	PostgresAttEndpointPort     = CloudAtt("Endpoint.Port")
 
-	postgresVersion      = "12.10"
-	postgresPort         = 5432
-	postgresAdminVersion = "6.8"
-	postgresAdminPort    = 80
+	postgresPort             = 5432
+	postgresAdminPort        = 80
+	postgresSnapshotFileName = "postgres.sql"
 )
 
 var (
-	_ Postgres = &postgresImpl{}
-	_ Plugin   = &postgresImpl{}
+	_ Postgres         = &postgresImpl{}
+	_ Plugin           = &postgresImpl{}
+	_ localSnapshotter = &postgresImpl{}
 )
 
 // PostgresConfigFunc returns the postgres config for a given Stage.
@@ -56,11 +58,22 @@ type PostgresConfigFunc func(Stage, *PostgresDependencies) *PostgresConfig
 type PostgresEventHookFunc func(Postgres, Event, string)
 
 // PostgresConfig describes the postgres config.
+//
+// If LogicalReplication is set, the database is configured with "wal_level=logical" (through the "rds.logical_replication"
+// parameter in cloud stages), and the configured publications and replication slots are created after the database is
+// started or deployed, so that CDC consumers (e.g. Debezium) and read-model projections can be attached to it. Note that
+// enabling it on an existing cloud instance only takes effect after the instance is rebooted.
+//
+// Maintenance declares SQL statements to be run periodically (e.g. see NewPostgresVacuumAnalyzeStatement,
+// NewPostgresRefreshMaterializedViewStatement, and PostgresPartmanRunMaintenanceStatement).
 type PostgresConfig struct {
-	Stage     Stage `validate:"required"`
-	Local     *PostgresConfigLocal
-	Cloud     *PostgresConfigCloud
-	EventHook PostgresEventHookFunc
+	Stage              Stage  `validate:"required"`
+	InstanceName       string `validate:"omitempty,resource-name"` // if empty, the plugin is a singleton
+	Local              *PostgresConfigLocal
+	Cloud              *PostgresConfigCloud
+	LogicalReplication *PostgresConfigLogicalReplication
+	Maintenance        []*PostgresConfigMaintenanceJob `validate:"dive,required"`
+	EventHook          PostgresEventHookFunc
 }
 
 // MustValidate validates the postgres config.
@@ -68,13 +81,26 @@ func (c *PostgresConfig) MustValidate(stageTarget StageTarget) {
 	vz.MustValidateStruct(c)
 	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing PostgresConfig.Cloud")
 	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing PostgresConfig.Local")
+
+	if c.Cloud != nil && c.Cloud.Aurora != nil {
+		c.Cloud.Aurora.MustValidate()
+	}
+
+	jobNames := map[string]struct{}{}
+	for _, job := range c.Maintenance {
+		job.MustValidate()
+		_, ok := jobNames[job.Name]
+		errorz.Assertf(!ok, "duplicate PostgresConfigMaintenanceJob.Name: %v", errorz.A(job.Name))
+		jobNames[job.Name] = struct{}{}
+	}
 }
 
 // PostgresConfigCloud describes part of the postgres config.
 type PostgresConfigCloud struct {
 	Password            string `validate:"required,min=16"`
-	AllocatedStorageGBs int    `validate:"required,min=5"`
-	InstanceClass       string `validate:"required"`
+	AllocatedStorageGBs int    `validate:"required_without=Aurora,omitempty,min=5"`
+	InstanceClass       string `validate:"required_without=Aurora"`
+	Aurora              *PostgresConfigCloudAurora
 }
 
 // PostgresConfigLocal describes part of the postgres config.
@@ -83,6 +109,20 @@ type PostgresConfigLocal struct {
 	AdminExternalPort uint16 `validate:"required"`
 }
 
+// PostgresConfigLogicalReplication describes part of the postgres config.
+type PostgresConfigLogicalReplication struct {
+	Publications []*PostgresConfigPublication `validate:"dive,required"`
+}
+
+// PostgresConfigPublication describes part of the postgres config.
+// If Tables is empty, the publication includes all tables. If SlotName is set, a logical replication slot using the
+// "pgoutput" plugin is created for the publication, otherwise consumers are expected to create their own.
+type PostgresConfigPublication struct {
+	Name     string   `validate:"required"`
+	Tables   []string `validate:"dive,required"`
+	SlotName string
+}
+
 // PostgresDependencies describes the postgres dependencies.
 type PostgresDependencies struct {
 	Network           Network `validate:"required"`
@@ -100,12 +140,23 @@ type PostgresLocalMetadata struct {
 	ExternalURL             *url.URL
 	InternalURL             *url.URL
 	AdminConsoleExternalURL *url.URL
+	ReplicationSlots        []*PostgresReplicationSlotMetadata
 }
 
 // PostgresCloudMetadata describes the postgres cloud metadata.
 type PostgresCloudMetadata struct {
-	Exports CloudExports
-	URL     *url.URL
+	Exports          CloudExports
+	URL              *url.URL
+	ReaderURL        *url.URL // only set for Aurora clusters
+	ReplicationSlots []*PostgresReplicationSlotMetadata
+}
+
+// PostgresReplicationSlotMetadata describes a logical replication slot created by the postgres plugin.
+// The fields map to the "slot.name", "publication.name", and "plugin.name" settings of the Debezium connector.
+type PostgresReplicationSlotMetadata struct {
+	SlotName        string
+	PublicationName string
+	PluginName      string
 }
 
 // Postgres describes a postgres.
@@ -115,6 +166,7 @@ type Postgres interface {
 	GetDependencies() *PostgresDependencies
 	GetLocalMetadata() *PostgresLocalMetadata
 	GetCloudMetadata(require bool) *PostgresCloudMetadata
+	GetTopSQL(hours int) []*opz.TopSQLEntry
 }
 
 type postgresImpl struct {
@@ -147,7 +199,10 @@ func (p *postgresImpl) GetName() string {
 
 // GetInstanceName implements the Plugin interface.
 func (p *postgresImpl) GetInstanceName() *string {
-	return nil
+	if p.cfg.InstanceName == "" {
+		return nil
+	}
+	return stringz.Ptr(p.cfg.InstanceName)
 }
 
 // GetDependenciesMap implements the Plugin interface.
@@ -197,6 +252,14 @@ func (p *postgresImpl) GetCloudMetadata(require bool) *PostgresCloudMetadata {
 	return p.cloudMetadata
 }
 
+// GetTopSQL implements the Postgres interface.
+// Note: Performance Insights is only enabled in production stages.
+func (p *postgresImpl) GetTopSQL(hours int) []*opz.TopSQLEntry {
+	return p.cfg.Stage.GetConfig().App.GetOperations().TopSQL(
+		p.GetCloudMetadata(true).Exports.GetRef(PostgresRefDBInstance),
+		hours)
+}
+
 // IsDeployed implements the Plugin interface.
 func (p *postgresImpl) IsDeployed() bool {
 	return p.cloudMetadata != nil
@@ -212,6 +275,7 @@ func (p *postgresImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath str
 		ExternalURL:             urlz.MustParse(fmt.Sprintf("postgres://postgres:%v@localhost:%v/postgres?sslmode=disable", LocalPassword, p.cfg.Local.ExternalPort)),
 		InternalURL:             urlz.MustParse(fmt.Sprintf("postgres://postgres:%v@%v:%v/postgres?sslmode=disable", LocalPassword, containerName, postgresPort)),
 		AdminConsoleExternalURL: urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.AdminExternalPort)),
+		ReplicationSlots:        p.getReplicationSlots(),
 	}
 
 	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
@@ -246,7 +310,7 @@ func (p *postgresImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath str
 			"PGADMIN_CONFIG_SERVER_MODE":              stringz.Ptr("False"),
 			"PGADMIN_CONFIG_MASTER_PASSWORD_REQUIRED": stringz.Ptr("False"),
 		},
-		Image:    "dpage/pgadmin4:" + postgresAdminVersion,
+		Image:    LocalGetImage(p, "dpage/pgadmin4:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().PgAdmin),
 		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
 		Ports: []dctypes.ServicePortConfig{
 			{
@@ -270,19 +334,31 @@ func (p *postgresImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath str
 			},
 		},
 	})
+
+	p.updateLocalMaintenanceTemplate(tpl, buildDirPath)
 }
 
 // GetCloudTemplate implements the Plugin interface.
 func (p *postgresImpl) GetCloudTemplate(_ string) *gocf.Template {
 	tpl := gocf.NewTemplate()
 
+	parameters := map[string]string{
+		"application_name": PostgresRefDBParameterGroup.Name(p),
+	}
+
+	engine := "postgres"
+
+	if p.isCloudAurora() {
+		engine = postgresAuroraEngine // logical replication is configured in the cluster parameter group
+	} else if p.cfg.LogicalReplication != nil {
+		parameters["rds.logical_replication"] = "1"
+	}
+
 	tpl.Resources[PostgresRefDBParameterGroup.Ref()] = &gords.DBParameterGroup{
 		Description: PostgresRefDBParameterGroup.Name(p),
-		Family:      "postgres" + strings.Split(postgresVersion, ".")[0],
-		Parameters: &map[string]string{
-			"application_name": PostgresRefDBParameterGroup.Name(p),
-		},
-		Tags: CloudGetDefaultTags(PostgresRefDBParameterGroup.Name(p)),
+		Family:      engine + strings.Split(p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Postgres, ".")[0],
+		Parameters:  &parameters,
+		Tags:        CloudGetDefaultTags(PostgresRefDBParameterGroup.Name(p)),
 	}
 	CloudAddExpRef(tpl, p, PostgresRefDBParameterGroup)
 
@@ -316,6 +392,18 @@ func (p *postgresImpl) GetCloudTemplate(_ string) *gocf.Template {
 	CloudAddExpGetAtt(tpl, p, PostgresRefRoleMonitoring, PostgresAttARN)
 	CloudAddExpGetAtt(tpl, p, PostgresRefRoleMonitoring, PostgresAttRoleID)
 
+	if p.isCloudAurora() {
+		p.addCloudAuroraResources(tpl)
+	} else {
+		p.addCloudInstanceResources(tpl)
+	}
+
+	p.addCloudMaintenanceResources(tpl)
+
+	return tpl
+}
+
+func (p *postgresImpl) addCloudInstanceResources(tpl *gocf.Template) {
 	rdsDBInstance := &gords.DBInstance{
 		AWSCloudFormationDependsOn: []string{
 			PostgresRefLogGroup.Ref(),
@@ -333,7 +421,7 @@ func (p *postgresImpl) GetCloudTemplate(_ string) *gocf.Template {
 			"upgrade",
 		},
 		Engine:                     stringz.Ptr("postgres"),
-		EngineVersion:              stringz.Ptr(postgresVersion),
+		EngineVersion:              stringz.Ptr(p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Postgres),
 		MasterUserPassword:         stringz.Ptr(p.cfg.Cloud.Password),
 		MasterUsername:             stringz.Ptr(p.cfg.Stage.GetName()),
 		PreferredBackupWindow:      stringz.Ptr("07:00-08:00"),
@@ -362,22 +450,39 @@ func (p *postgresImpl) GetCloudTemplate(_ string) *gocf.Template {
 	CloudAddExpRef(tpl, p, PostgresRefDBInstance)
 	CloudAddExpGetAtt(tpl, p, PostgresRefDBInstance, PostgresAttEndpointAddress)
 	CloudAddExpGetAtt(tpl, p, PostgresRefDBInstance, PostgresAttEndpointPort)
+}
 
-	return tpl
+// getCloudEndpointRef returns the ref of the resource exposing the writer endpoint.
+func (p *postgresImpl) getCloudEndpointRef() CloudRef {
+	if p.isCloudAurora() {
+		return PostgresRefDBCluster
+	}
+	return PostgresRefDBInstance
 }
 
 // UpdateCloudMetadata implements the Plugin interface.
 func (p *postgresImpl) UpdateCloudMetadata(stack *awscft.Stack) {
 	exports := NewCloudExports(stack)
+	endpointRef := p.getCloudEndpointRef()
 
 	p.cloudMetadata = &PostgresCloudMetadata{
 		Exports: exports,
 		URL: urlz.MustParse(fmt.Sprintf("postgres://%v:%v@%v:%v/%v",
 			p.cfg.Stage.GetName(),
 			p.cfg.Cloud.Password,
-			exports.GetAtt(PostgresRefDBInstance, PostgresAttEndpointAddress),
-			exports.GetAtt(PostgresRefDBInstance, PostgresAttEndpointPort),
+			exports.GetAtt(endpointRef, PostgresAttEndpointAddress),
+			exports.GetAtt(endpointRef, PostgresAttEndpointPort),
 			p.cfg.Stage.GetName())),
+		ReplicationSlots: p.getReplicationSlots(),
+	}
+
+	if p.isCloudAurora() {
+		p.cloudMetadata.ReaderURL = urlz.MustParse(fmt.Sprintf("postgres://%v:%v@%v:%v/%v",
+			p.cfg.Stage.GetName(),
+			p.cfg.Cloud.Password,
+			exports.GetAtt(endpointRef, PostgresAttReadEndpointAddress),
+			exports.GetAtt(endpointRef, PostgresAttEndpointPort),
+			p.cfg.Stage.GetName()))
 	}
 }
 
@@ -386,6 +491,10 @@ func (p *postgresImpl) EventHook(event Event, buildDirPath string) {
 	switch event {
 	case LocalBeforeCreateEvent:
 		p.localBeforeCreateEvent(buildDirPath)
+	case LocalAfterCreateEvent:
+		p.localAfterCreateEvent()
+	case CloudAfterDeployEvent:
+		p.cloudAfterDeployEvent()
 	}
 
 	if p.cfg.EventHook != nil {
@@ -401,7 +510,8 @@ func (p *postgresImpl) localBeforeCreateEvent(buildDirPath string) {
 		templatez.MustParseAndExecuteText(
 			assets.PostgresDockerfileTemplateAsset,
 			assets.PostgresDockerfileTemplateData{
-				Version: postgresVersion,
+				BaseImage:          LocalGetImage(p, fmt.Sprintf("postgres:%v-alpine", p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Postgres)),
+				LogicalReplication: p.cfg.LogicalReplication != nil,
 			}))
 
 	filez.MustWriteFile(
@@ -431,4 +541,71 @@ func (p *postgresImpl) localBeforeCreateEvent(buildDirPath string) {
 				Password: LocalPassword,
 				Database: "postgres",
 			}))
+
+	p.writeLocalMaintenanceFiles(buildDirPath)
+}
+
+func (p *postgresImpl) localAfterCreateEvent() {
+	p.ensurePublications(p.localMetadata.ExternalURL)
+}
+
+// snapshotLocal implements the localSnapshotter interface.
+func (p *postgresImpl) snapshotLocal(dirPath string) {
+	dump := p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker").
+		AddParams("exec", p.localMetadata.ContainerName).
+		AddParams("pg_dump", "-U", "postgres", "--clean", "--if-exists", "postgres").
+		SetQuiet().
+		MustOutput()
+
+	filez.MustWriteFile(filepath.Join(dirPath, postgresSnapshotFileName), 0777, 0666, []byte(dump))
+}
+
+// restoreLocal implements the localSnapshotter interface.
+func (p *postgresImpl) restoreLocal(dirPath string) {
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand("docker").
+		AddParams("exec", "-i", p.localMetadata.ContainerName).
+		AddParams("psql", "-U", "postgres", "-d", "postgres", "-q", "-v", "ON_ERROR_STOP=1", "--single-transaction").
+		SetStdin(bytes.NewReader(filez.MustReadFile(filepath.Join(dirPath, postgresSnapshotFileName)))).
+		MustRun()
+
+	p.ensurePublications(p.localMetadata.ExternalURL)
+}
+
+func (p *postgresImpl) cloudAfterDeployEvent() {
+	p.ensurePublications(p.GetCloudMetadata(true).URL)
+}
+
+func (p *postgresImpl) ensurePublications(pgURL *url.URL) {
+	if p.cfg.LogicalReplication == nil || len(p.cfg.LogicalReplication.Publications) == 0 {
+		return
+	}
+
+	publications := make([]*opz.PostgresPublication, 0, len(p.cfg.LogicalReplication.Publications))
+	for _, publication := range p.cfg.LogicalReplication.Publications {
+		publications = append(publications, &opz.PostgresPublication{
+			Name:     publication.Name,
+			Tables:   publication.Tables,
+			SlotName: publication.SlotName,
+		})
+	}
+
+	p.cfg.Stage.GetConfig().App.GetOperations().EnsurePostgresPublications(pgURL.String(), publications)
+}
+
+func (p *postgresImpl) getReplicationSlots() []*PostgresReplicationSlotMetadata {
+	slots := make([]*PostgresReplicationSlotMetadata, 0)
+
+	if p.cfg.LogicalReplication != nil {
+		for _, publication := range p.cfg.LogicalReplication.Publications {
+			if publication.SlotName != "" {
+				slots = append(slots, &PostgresReplicationSlotMetadata{
+					SlotName:        publication.SlotName,
+					PublicationName: publication.Name,
+					PluginName:      opz.PostgresLogicalDecodingPlugin,
+				})
+			}
+		}
+	}
+
+	return slots
 }
@@ -0,0 +1,177 @@
+package cloudz
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	gords "github.com/awslabs/goformation/v6/cloudformation/rds"
+	gotags "github.com/awslabs/goformation/v6/cloudformation/tags"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// Postgres Aurora constants.
+const (
+	PostgresRefDBClusterParameterGroup = CloudRef("cpg")
+	PostgresRefDBCluster               = CloudRef("c")
+	PostgresAttReadEndpointAddress     = CloudAtt("ReadEndpoint.Address")
+
+	postgresAuroraEngine        = "aurora-postgresql"
+	postgresAuroraInstanceClass = "db.serverless"
+)
+
+// PostgresConfigCloudAurora describes part of the postgres config.
+//
+// If set, the cloud database is an Aurora Serverless v2 cluster instead of a single RDS instance, and the AllocatedStorageGBs
+// and InstanceClass settings are ignored. The cluster has a writer instance and the given number of reader instances, all
+// scaling between MinCapacityACUs and MaxCapacityACUs (in increments of 0.5). The readers are reachable through the
+// cluster reader endpoint (see PostgresCloudMetadata.ReaderURL). Local stages are not affected.
+type PostgresConfigCloudAurora struct {
+	MinCapacityACUs float64 `validate:"required,min=0.5,max=128"`
+	MaxCapacityACUs float64 `validate:"required,min=1,max=128,gtefield=MinCapacityACUs"`
+	Readers         int     `validate:"min=0,max=15"`
+}
+
+// MustValidate validates the postgres Aurora config.
+func (c *PostgresConfigCloudAurora) MustValidate() {
+	errorz.Assertf(math.Mod(c.MinCapacityACUs*2, 1) == 0, "invalid PostgresConfigCloudAurora.MinCapacityACUs: %v", errorz.A(c.MinCapacityACUs))
+	errorz.Assertf(math.Mod(c.MaxCapacityACUs*2, 1) == 0, "invalid PostgresConfigCloudAurora.MaxCapacityACUs: %v", errorz.A(c.MaxCapacityACUs))
+}
+
+func (p *postgresImpl) isCloudAurora() bool {
+	return p.cfg.Cloud != nil && p.cfg.Cloud.Aurora != nil
+}
+
+func (p *postgresImpl) addCloudAuroraResources(tpl *gocf.Template) {
+	version := p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Postgres
+	parameters := map[string]string{}
+
+	if p.cfg.LogicalReplication != nil {
+		parameters["rds.logical_replication"] = "1"
+	}
+
+	tpl.Resources[PostgresRefDBClusterParameterGroup.Ref()] = &gords.DBClusterParameterGroup{
+		Description: PostgresRefDBClusterParameterGroup.Name(p),
+		Family:      postgresAuroraEngine + strings.Split(version, ".")[0],
+		Parameters:  &parameters,
+		Tags:        CloudGetDefaultTags(PostgresRefDBClusterParameterGroup.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, PostgresRefDBClusterParameterGroup)
+
+	dbCluster := &postgresAuroraDBCluster{
+		BackupRetentionPeriod:       1,
+		CopyTagsToSnapshot:          true,
+		DBClusterIdentifier:         PostgresRefDBCluster.Name(p),
+		DBClusterParameterGroupName: gocf.Ref(PostgresRefDBClusterParameterGroup.Ref()),
+		DBSubnetGroupName:           gocf.Ref(PostgresRefDBSubnetGroup.Ref()),
+		DatabaseName:                p.cfg.Stage.GetName(),
+		EnableCloudwatchLogsExports: []string{"postgresql"},
+		Engine:                      postgresAuroraEngine,
+		EngineVersion:               version,
+		MasterUserPassword:          p.cfg.Cloud.Password,
+		MasterUsername:              p.cfg.Stage.GetName(),
+		PreferredBackupWindow:       "07:00-08:00",
+		PreferredMaintenanceWindow:  "wed:10:00-wed:12:00",
+		ServerlessV2ScalingConfiguration: &postgresAuroraServerlessV2ScalingConfiguration{
+			MinCapacity: p.cfg.Cloud.Aurora.MinCapacityACUs,
+			MaxCapacity: p.cfg.Cloud.Aurora.MaxCapacityACUs,
+		},
+		StorageEncrypted: true,
+		VpcSecurityGroupIds: []string{
+			p.deps.Network.GetCloudMetadata(true).Exports.GetRef(NetworkRefSecurityGroup),
+		},
+		Tags: CloudGetDefaultTags(PostgresRefDBCluster.Name(p)),
+	}
+
+	if p.cfg.Stage.GetMode().IsProduction() {
+		dbCluster.BackupRetentionPeriod = 30
+	}
+
+	tpl.Resources[PostgresRefDBCluster.Ref()] = dbCluster
+	CloudAddExpRef(tpl, p, PostgresRefDBCluster)
+	CloudAddExpGetAtt(tpl, p, PostgresRefDBCluster, PostgresAttEndpointAddress)
+	CloudAddExpGetAtt(tpl, p, PostgresRefDBCluster, PostgresAttEndpointPort)
+	CloudAddExpGetAtt(tpl, p, PostgresRefDBCluster, PostgresAttReadEndpointAddress)
+
+	tpl.Resources[PostgresRefDBInstance.Ref()] = p.newCloudAuroraDBInstance(PostgresRefDBInstance, 0)
+	CloudAddExpRef(tpl, p, PostgresRefDBInstance)
+
+	for i := 0; i < p.cfg.Cloud.Aurora.Readers; i++ {
+		readerRef := CloudRef(fmt.Sprintf("i-r%v", i))
+		tpl.Resources[readerRef.Ref()] = p.newCloudAuroraDBInstance(readerRef, 1)
+		CloudAddExpRef(tpl, p, readerRef)
+	}
+}
+
+func (p *postgresImpl) newCloudAuroraDBInstance(ref CloudRef, promotionTier int) *gords.DBInstance {
+	dbInstance := &gords.DBInstance{
+		AutoMinorVersionUpgrade: boolz.Ptr(false),
+		DBClusterIdentifier:     stringz.Ptr(gocf.Ref(PostgresRefDBCluster.Ref())),
+		DBInstanceClass:         postgresAuroraInstanceClass,
+		DBInstanceIdentifier:    stringz.Ptr(ref.Name(p)),
+		DBParameterGroupName:    stringz.Ptr(gocf.Ref(PostgresRefDBParameterGroup.Ref())),
+		Engine:                  stringz.Ptr(postgresAuroraEngine),
+		PromotionTier:           intz.Ptr(promotionTier),
+		PubliclyAccessible:      boolz.Ptr(true),
+		Tags:                    CloudGetDefaultTags(ref.Name(p)),
+	}
+
+	if p.cfg.Stage.GetMode().IsProduction() {
+		dbInstance.EnablePerformanceInsights = boolz.Ptr(true)
+		dbInstance.MonitoringInterval = intz.Ptr(60)
+		dbInstance.MonitoringRoleArn = stringz.Ptr(gocf.GetAtt(PostgresRefRoleMonitoring.Ref(), "Arn"))
+	} else if promotionTier == 0 {
+		dbInstance.AvailabilityZone = stringz.Ptr(p.cfg.Stage.GetConfig().App.GetConfig().AWSConfig.Region + "a")
+	}
+
+	return dbInstance
+}
+
+// postgresAuroraDBCluster describes an "AWS::RDS::DBCluster" resource, since the goformation one does not support the
+// "ServerlessV2ScalingConfiguration" property.
+type postgresAuroraDBCluster struct {
+	BackupRetentionPeriod            int
+	CopyTagsToSnapshot               bool
+	DBClusterIdentifier              string
+	DBClusterParameterGroupName      string
+	DBSubnetGroupName                string
+	DatabaseName                     string
+	EnableCloudwatchLogsExports      []string
+	Engine                           string
+	EngineVersion                    string
+	MasterUserPassword               string
+	MasterUsername                   string
+	PreferredBackupWindow            string
+	PreferredMaintenanceWindow       string
+	ServerlessV2ScalingConfiguration *postgresAuroraServerlessV2ScalingConfiguration
+	StorageEncrypted                 bool
+	VpcSecurityGroupIds              []string
+	Tags                             *[]gotags.Tag `json:",omitempty"`
+}
+
+type postgresAuroraServerlessV2ScalingConfiguration struct {
+	MinCapacity float64
+	MaxCapacity float64
+}
+
+// AWSCloudFormationType implements the gocf.Resource interface.
+func (*postgresAuroraDBCluster) AWSCloudFormationType() string {
+	return "AWS::RDS::DBCluster"
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (r postgresAuroraDBCluster) MarshalJSON() ([]byte, error) {
+	type Properties postgresAuroraDBCluster
+	return json.Marshal(&struct {
+		Type       string
+		Properties Properties
+	}{
+		Type:       r.AWSCloudFormationType(),
+		Properties: (Properties)(r),
+	})
+}
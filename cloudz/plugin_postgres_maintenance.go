@@ -0,0 +1,293 @@
+package cloudz
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goecs "github.com/awslabs/goformation/v6/cloudformation/ecs"
+	goevents "github.com/awslabs/goformation/v6/cloudformation/events"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	gologs "github.com/awslabs/goformation/v6/cloudformation/logs"
+	gosm "github.com/awslabs/goformation/v6/cloudformation/secretsmanager"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// Postgres maintenance constants.
+const (
+	PostgresRefMaintenanceLogGroup      = CloudRef("mt-lg")
+	PostgresRefMaintenanceRoleExecution = CloudRef("mt-r-ex")
+	PostgresRefMaintenanceRoleEvents    = CloudRef("mt-r-ev")
+	PostgresRefMaintenanceCluster       = CloudRef("mt-cl")
+	PostgresRefMaintenanceSecret        = CloudRef("mt-s")
+
+	// PostgresPartmanRunMaintenanceStatement runs the pg_partman maintenance, creating and dropping partitions according
+	// to the retention configured in "partman.part_config".
+	PostgresPartmanRunMaintenanceStatement = "CALL partman.run_maintenance_proc()"
+)
+
+// PostgresConfigMaintenanceJob describes part of the postgres config.
+//
+// The Expression uses the EventBridge syntax (see ScheduleConfig). The Statements are executed in order by psql, each in
+// its own transaction (so that e.g. VACUUM is allowed), and the job stops at the first failing one. Locally, jobs are
+// run by a cron container. In cloud stages, each job is an ECS scheduled task running in the private subnets of the
+// Network, which logs to the "mt-lg" log group, and reads the database password from the "mt-s" secret.
+type PostgresConfigMaintenanceJob struct {
+	Name       string   `validate:"required,resource-name"`
+	Expression string   `validate:"required"`
+	Statements []string `validate:"required,min=1,dive,required"`
+}
+
+// MustValidate validates the postgres maintenance job.
+func (j *PostgresConfigMaintenanceJob) MustValidate() {
+	errorz.Assertf(
+		scheduleRateExpressionRegexp.MatchString(j.Expression) || scheduleCronExpressionRegexp.MatchString(j.Expression),
+		"invalid PostgresConfigMaintenanceJob.Expression: %v", errorz.A(j.Expression))
+}
+
+// NewPostgresVacuumAnalyzeStatement returns a statement that vacuums and analyzes the given tables (all tables if none).
+func NewPostgresVacuumAnalyzeStatement(tables ...string) string {
+	if len(tables) == 0 {
+		return "VACUUM (ANALYZE)"
+	}
+
+	quotedTables := make([]string, 0, len(tables))
+	for _, table := range tables {
+		quotedTables = append(quotedTables, quotePostgresMaintenanceIdentifier(table))
+	}
+
+	return "VACUUM (ANALYZE) " + strings.Join(quotedTables, ", ")
+}
+
+// NewPostgresRefreshMaterializedViewStatement returns a statement that refreshes the given materialized view. Refreshing
+// concurrently doesn't block reads, but requires a unique index on the view.
+func NewPostgresRefreshMaterializedViewStatement(view string, concurrently bool) string {
+	if concurrently {
+		return "REFRESH MATERIALIZED VIEW CONCURRENTLY " + quotePostgresMaintenanceIdentifier(view)
+	}
+	return "REFRESH MATERIALIZED VIEW " + quotePostgresMaintenanceIdentifier(view)
+}
+
+func (p *postgresImpl) updateLocalMaintenanceTemplate(tpl *dctypes.Config, buildDirPath string) {
+	if len(p.cfg.Maintenance) == 0 {
+		return
+	}
+
+	containerName := LocalGetContainerName(p, "maintenance")
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          containerName,
+		ContainerName: containerName,
+		Command:       dctypes.ShellCommand{"crond", "-f", "-l", "8"},
+		DependsOn: []string{
+			p.localMetadata.ContainerName,
+		},
+		Environment: map[string]*string{
+			"PGHOST":     stringz.Ptr(p.localMetadata.ContainerName),
+			"PGPORT":     stringz.Ptr(fmt.Sprintf("%v", postgresPort)),
+			"PGUSER":     stringz.Ptr("postgres"),
+			"PGPASSWORD": stringz.Ptr(LocalPassword),
+			"PGDATABASE": stringz.Ptr("postgres"),
+		},
+		Image:    LocalGetImage(p, p.getMaintenanceImage()),
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Restart:  "unless-stopped",
+		Volumes: []dctypes.ServiceVolumeConfig{
+			{
+				Type:     "bind",
+				Source:   filez.MustAbs(filepath.Join(buildDirPath, "maintenance", "crontab")),
+				Target:   "/etc/crontabs/root",
+				ReadOnly: true,
+			},
+		},
+	})
+}
+
+func (p *postgresImpl) writeLocalMaintenanceFiles(buildDirPath string) {
+	if len(p.cfg.Maintenance) == 0 {
+		return
+	}
+
+	crontab := &strings.Builder{}
+	for _, job := range p.cfg.Maintenance {
+		crontab.WriteString(getScheduleCronExpression(job.Expression) + " " + strings.Join(getPostgresMaintenanceCommand(job), " ") + "\n")
+	}
+
+	filez.MustWriteFile(filepath.Join(buildDirPath, "maintenance", "crontab"), 0777, 0666, []byte(crontab.String()))
+}
+
+func (p *postgresImpl) addCloudMaintenanceResources(tpl *gocf.Template) {
+	if len(p.cfg.Maintenance) == 0 {
+		return
+	}
+
+	network := p.deps.Network.GetCloudMetadata(true)
+
+	tpl.Resources[PostgresRefMaintenanceLogGroup.Ref()] = &gologs.LogGroup{
+		LogGroupName:    stringz.Ptr(PostgresRefMaintenanceLogGroup.Name(p)),
+		RetentionInDays: intz.Ptr(90),
+	}
+	CloudAddExpRef(tpl, p, PostgresRefMaintenanceLogGroup)
+
+	tpl.Resources[PostgresRefMaintenanceSecret.Ref()] = &gosm.Secret{
+		Name:         stringz.Ptr(PostgresRefMaintenanceSecret.Name(p)),
+		SecretString: stringz.Ptr(p.cfg.Cloud.Password),
+		Tags:         CloudGetDefaultTags(PostgresRefMaintenanceSecret.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, PostgresRefMaintenanceSecret)
+
+	tpl.Resources[PostgresRefMaintenanceRoleExecution.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("ecs-tasks.amazonaws.com"),
+		ManagedPolicyArns: &[]string{
+			"arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy",
+		},
+		Policies: &[]goiam.Role_Policy{
+			{
+				PolicyName: PostgresPluginName + "-maintenance-secrets",
+				PolicyDocument: NewPolicyDocument(
+					NewPolicyStatement().
+						AddActions("secretsmanager:GetSecretValue").
+						AddResources(gocf.Ref(PostgresRefMaintenanceSecret.Ref()))),
+			},
+		},
+		RoleName: stringz.Ptr(PostgresRefMaintenanceRoleExecution.Name(p)),
+		Tags:     CloudGetDefaultTags(PostgresRefMaintenanceRoleExecution.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, PostgresRefMaintenanceRoleExecution)
+
+	tpl.Resources[PostgresRefMaintenanceCluster.Ref()] = &goecs.Cluster{
+		ClusterName: stringz.Ptr(PostgresRefMaintenanceCluster.Name(p)),
+		Tags:        CloudGetDefaultTags(PostgresRefMaintenanceCluster.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, PostgresRefMaintenanceCluster)
+
+	taskDefinitionARNs := make([]string, 0, len(p.cfg.Maintenance))
+
+	for _, job := range p.cfg.Maintenance {
+		taskDefinitionRef := CloudRef("mt-td-" + job.Name)
+		ruleRef := CloudRef("mt-r-" + job.Name)
+		taskDefinitionARNs = append(taskDefinitionARNs, gocf.Ref(taskDefinitionRef.Ref()))
+
+		tpl.Resources[taskDefinitionRef.Ref()] = &goecs.TaskDefinition{
+			ContainerDefinitions: &[]goecs.TaskDefinition_ContainerDefinition{
+				{
+					Command: &[]string{"sh", "-c", strings.Join(getPostgresMaintenanceCommand(job), " ")},
+					Environment: CloudGetTaskDefinitionKeyValuePairs(map[string]string{
+						"PGHOST":     gocf.GetAtt(p.getCloudEndpointRef().Ref(), PostgresAttEndpointAddress.Ref()),
+						"PGPORT":     gocf.GetAtt(p.getCloudEndpointRef().Ref(), PostgresAttEndpointPort.Ref()),
+						"PGUSER":     p.cfg.Stage.GetName(),
+						"PGDATABASE": p.cfg.Stage.GetName(),
+						"PGSSLMODE":  "require",
+					}),
+					Essential: boolz.Ptr(true),
+					Image:     stringz.Ptr(LocalGetImage(p, p.getMaintenanceImage())),
+					LogConfiguration: &goecs.TaskDefinition_LogConfiguration{
+						LogDriver: "awslogs",
+						Options: &map[string]string{
+							"awslogs-region":        gocf.Ref("AWS::Region"),
+							"awslogs-group":         gocf.Ref(PostgresRefMaintenanceLogGroup.Ref()),
+							"awslogs-stream-prefix": job.Name,
+						},
+					},
+					Name: stringz.Ptr(job.Name),
+					Secrets: &[]goecs.TaskDefinition_Secret{
+						{
+							Name:      "PGPASSWORD",
+							ValueFrom: gocf.Ref(PostgresRefMaintenanceSecret.Ref()),
+						},
+					},
+				},
+			},
+			Cpu:              stringz.Ptr("256"),
+			ExecutionRoleArn: stringz.Ptr(gocf.GetAtt(PostgresRefMaintenanceRoleExecution.Ref(), "Arn")),
+			Family:           stringz.Ptr(taskDefinitionRef.Name(p)),
+			Memory:           stringz.Ptr("512"),
+			NetworkMode:      stringz.Ptr("awsvpc"),
+			RequiresCompatibilities: &[]string{
+				"FARGATE",
+			},
+			Tags: CloudGetDefaultTags(taskDefinitionRef.Name(p)),
+		}
+
+		tpl.Resources[ruleRef.Ref()] = &goevents.Rule{
+			Description:        stringz.Ptr(ruleRef.Name(p)),
+			Name:               stringz.Ptr(ruleRef.Name(p)),
+			ScheduleExpression: stringz.Ptr(job.Expression),
+			State:              stringz.Ptr("ENABLED"),
+			Targets: &[]goevents.Rule_Target{
+				{
+					Arn: gocf.GetAtt(PostgresRefMaintenanceCluster.Ref(), "Arn"),
+					EcsParameters: &goevents.Rule_EcsParameters{
+						LaunchType: stringz.Ptr("FARGATE"),
+						NetworkConfiguration: &goevents.Rule_NetworkConfiguration{
+							AwsVpcConfiguration: &goevents.Rule_AwsVpcConfiguration{
+								AssignPublicIp: stringz.Ptr("DISABLED"),
+								SecurityGroups: &[]string{
+									network.Exports.GetRef(NetworkRefSecurityGroup),
+								},
+								Subnets: []string{
+									network.Exports.GetRef(NetworkRefSubnetPrivateA),
+									network.Exports.GetRef(NetworkRefSubnetPrivateB),
+								},
+							},
+						},
+						TaskCount:         intz.Ptr(1),
+						TaskDefinitionArn: gocf.Ref(taskDefinitionRef.Ref()),
+					},
+					Id:      job.Name,
+					RoleArn: stringz.Ptr(gocf.GetAtt(PostgresRefMaintenanceRoleEvents.Ref(), "Arn")),
+				},
+			},
+		}
+		CloudAddExpRef(tpl, p, ruleRef)
+	}
+
+	tpl.Resources[PostgresRefMaintenanceRoleEvents.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("events.amazonaws.com"),
+		Policies: &[]goiam.Role_Policy{
+			{
+				PolicyName: PostgresPluginName + "-maintenance",
+				PolicyDocument: NewPolicyDocument(
+					NewPolicyStatement().
+						AddActions("ecs:RunTask").
+						AddResources(taskDefinitionARNs...),
+					NewPolicyStatement().
+						AddActions("iam:PassRole").
+						AddResources(gocf.GetAtt(PostgresRefMaintenanceRoleExecution.Ref(), "Arn"))),
+			},
+		},
+		RoleName: stringz.Ptr(PostgresRefMaintenanceRoleEvents.Name(p)),
+		Tags:     CloudGetDefaultTags(PostgresRefMaintenanceRoleEvents.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, PostgresRefMaintenanceRoleEvents)
+}
+
+func (p *postgresImpl) getMaintenanceImage() string {
+	return fmt.Sprintf("postgres:%v-alpine", p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Postgres)
+}
+
+func getPostgresMaintenanceCommand(job *PostgresConfigMaintenanceJob) []string {
+	command := []string{"psql", "-v", "ON_ERROR_STOP=1"}
+	for _, statement := range job.Statements {
+		command = append(command, "-c", quotePostgresMaintenanceShellArg(statement))
+	}
+	return command
+}
+
+func quotePostgresMaintenanceShellArg(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func quotePostgresMaintenanceIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,83 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotePostgresMaintenanceIdentifier(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "table",
+			input:    "users",
+			expected: `"users"`,
+		},
+		{
+			name:     "schema and table",
+			input:    "public.users",
+			expected: `"public"."users"`,
+		},
+		{
+			name:     "mixed case",
+			input:    "Public.Users",
+			expected: `"Public"."Users"`,
+		},
+		{
+			name:     "embedded quote",
+			input:    `us"ers`,
+			expected: `"us""ers"`,
+		},
+		{
+			name:     "injection attempt",
+			input:    `users"; DROP TABLE users; --`,
+			expected: `"users""; DROP TABLE users; --"`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expected, quotePostgresMaintenanceIdentifier(testCase.input))
+		})
+	}
+}
+
+func TestQuotePostgresMaintenanceShellArg(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "plain",
+			input:    "VACUUM (ANALYZE)",
+			expected: `'VACUUM (ANALYZE)'`,
+		},
+		{
+			name:     "single quote",
+			input:    "SELECT 'a'",
+			expected: `'SELECT '\''a'\'''`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expected, quotePostgresMaintenanceShellArg(testCase.input))
+		})
+	}
+}
+
+func TestNewPostgresMaintenanceStatements(t *testing.T) {
+	require.Equal(t, "VACUUM (ANALYZE)", NewPostgresVacuumAnalyzeStatement())
+	require.Equal(t, `VACUUM (ANALYZE) "a", "public"."b"`, NewPostgresVacuumAnalyzeStatement("a", "public.b"))
+	require.Equal(t, `REFRESH MATERIALIZED VIEW "v"`, NewPostgresRefreshMaterializedViewStatement("v", false))
+	require.Equal(t, `REFRESH MATERIALIZED VIEW CONCURRENTLY "v"`, NewPostgresRefreshMaterializedViewStatement("v", true))
+}
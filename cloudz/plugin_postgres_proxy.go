@@ -254,16 +254,25 @@ func (p *postgresProxyImpl) GetCloudTemplate(_ string) *gocf.Template {
 	CloudAddExpGetAtt(tpl, p, PostgresProxyRefDBProxy, PostgresProxyAttDBProxyARN)
 	CloudAddExpGetAtt(tpl, p, PostgresProxyRefDBProxy, PostgresProxyAttEndpoint)
 
-	tpl.Resources[PostgresProxyRefDBProxyTargetGroup.Ref()] = &gords.DBProxyTargetGroup{
+	dbProxyTargetGroup := &gords.DBProxyTargetGroup{
 		ConnectionPoolConfigurationInfo: &gords.DBProxyTargetGroup_ConnectionPoolConfigurationInfoFormat{
 			ConnectionBorrowTimeout: intz.Ptr(10),
 		},
-		DBInstanceIdentifiers: &[]string{
-			p.deps.Postgres.GetCloudMetadata(true).Exports.GetRef(PostgresRefDBInstance),
-		},
 		DBProxyName:     gocf.Ref(PostgresProxyRefDBProxy.Ref()),
 		TargetGroupName: "default",
 	}
+
+	if postgresCfg := p.deps.Postgres.GetConfig(); postgresCfg.Cloud != nil && postgresCfg.Cloud.Aurora != nil {
+		dbProxyTargetGroup.DBClusterIdentifiers = &[]string{
+			p.deps.Postgres.GetCloudMetadata(true).Exports.GetRef(PostgresRefDBCluster),
+		}
+	} else {
+		dbProxyTargetGroup.DBInstanceIdentifiers = &[]string{
+			p.deps.Postgres.GetCloudMetadata(true).Exports.GetRef(PostgresRefDBInstance),
+		}
+	}
+
+	tpl.Resources[PostgresProxyRefDBProxyTargetGroup.Ref()] = dbProxyTargetGroup
 	CloudAddExpRef(tpl, p, PostgresProxyRefDBProxyTargetGroup)
 
 	return tpl
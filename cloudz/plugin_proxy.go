@@ -0,0 +1,319 @@
+package cloudz
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+)
+
+// Proxy constants.
+const (
+	ProxyPluginDisplayName = "Proxy"
+	ProxyPluginName        = "proxy"
+
+	proxyCertificateContainerFilePath = "/etc/caddy/cert.pem"
+	proxyKeyContainerFilePath         = "/etc/caddy/key.pem"
+)
+
+var (
+	_ Proxy  = &proxyImpl{}
+	_ Plugin = &proxyImpl{}
+
+	proxyDataDirParts = []string{
+		"data",
+	}
+)
+
+// ProxyConfigFunc returns the proxy config for a given Stage.
+type ProxyConfigFunc func(Stage, *ProxyDependencies) *ProxyConfig
+
+// ProxyEventHookFunc describes a proxy event hook.
+type ProxyEventHookFunc func(Proxy, Event, string)
+
+// ProxyConfig describes the proxy config.
+//
+// The proxy is only available in local stages: it runs Caddy, which routes each hostname in ProxyDependencies.Routes
+// (e.g. "api.localhost") to the corresponding plugin, mirroring the host-header routing of the cloud load balancer. TLS
+// certificates are issued by a local certificate authority, whose root certificate can be trusted by the host using
+// the RootCertificateFilePath in the local metadata. Use Proxy.GetURL to get the external URL for a hostname.
+//
+// By default the Caddy internal certificate authority is used. If ProxyConfigLocal.CARootDirPath is set, a single
+// certificate covering all hostnames is instead issued by the mkcert-compatible certificate authority in that directory
+// (generated if missing), and exposed in the local metadata so that other services can use it too.
+type ProxyConfig struct {
+	Stage     Stage `validate:"required"`
+	Local     *ProxyConfigLocal
+	EventHook ProxyEventHookFunc
+}
+
+// MustValidate validates the proxy config.
+func (c *ProxyConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing ProxyConfig.Local")
+}
+
+// ProxyConfigLocal describes part of the proxy config.
+type ProxyConfigLocal struct {
+	HTTPExternalPort  uint16 `validate:"required"`
+	HTTPSExternalPort uint16 `validate:"required"`
+	CARootDirPath     string // e.g. the output of "mkcert -CAROOT"
+}
+
+// ProxyDependencies describes the proxy dependencies.
+// Routes maps hostnames to plugins exposing an "InternalURL" local metadata value (e.g. API, Hasura, ContainerService).
+type ProxyDependencies struct {
+	Routes            map[string]Plugin `validate:"required,min=1,dive,keys,hostname,endkeys,required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the proxy dependencies.
+func (d *ProxyDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// ProxyLocalMetadata describes the proxy local metadata.
+// CertificateFilePath and KeyFilePath are only set if ProxyConfigLocal.CARootDirPath is set.
+type ProxyLocalMetadata struct {
+	ContainerName           string
+	RootCertificateFilePath string
+	CertificateFilePath     string
+	KeyFilePath             string
+}
+
+// Proxy describes a proxy.
+type Proxy interface {
+	Plugin
+	GetConfig() *ProxyConfig
+	GetDependencies() *ProxyDependencies
+	GetLocalMetadata() *ProxyLocalMetadata
+	GetURL(hostname string) string
+}
+
+type proxyImpl struct {
+	cfgFunc       ProxyConfigFunc
+	deps          *ProxyDependencies
+	cfg           *ProxyConfig
+	localMetadata *ProxyLocalMetadata
+}
+
+// NewProxy initializes a new Proxy.
+func NewProxy(cfgFunc ProxyConfigFunc, deps *ProxyDependencies) Proxy {
+	deps.MustValidate()
+
+	return &proxyImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*proxyImpl) GetDisplayName() string {
+	return ProxyPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *proxyImpl) GetName() string {
+	return ProxyPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *proxyImpl) GetInstanceName() *string {
+	return nil
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *proxyImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{}
+
+	for _, target := range p.deps.Routes {
+		dependenciesMap[target] = struct{}{}
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *proxyImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *proxyImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(ProxyPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the Proxy interface.
+func (p *proxyImpl) GetConfig() *ProxyConfig {
+	return p.cfg
+}
+
+// GetDependencies implements the Proxy interface.
+func (p *proxyImpl) GetDependencies() *ProxyDependencies {
+	return p.deps
+}
+
+// GetLocalMetadata implements the Proxy interface.
+func (p *proxyImpl) GetLocalMetadata() *ProxyLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(ProxyPluginName))
+	return p.localMetadata
+}
+
+// GetURL implements the Proxy interface.
+func (p *proxyImpl) GetURL(hostname string) string {
+	_, ok := p.deps.Routes[hostname]
+	errorz.Assertf(ok, "unknown hostname: %v", errorz.A(hostname), errorz.Prefix(ProxyPluginName))
+
+	if p.cfg.Local.HTTPSExternalPort == 443 {
+		return fmt.Sprintf("https://%v", hostname)
+	}
+	return fmt.Sprintf("https://%v:%v", hostname, p.cfg.Local.HTTPSExternalPort)
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *proxyImpl) IsDeployed() bool {
+	return false
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *proxyImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath string) {
+	containerName := LocalGetContainerName(p)
+	dataDirPath := p.cfg.Stage.GetConfig().App.GetConfig().GetConfigDirPathForPlugin(p, proxyDataDirParts...)
+
+	p.localMetadata = &ProxyLocalMetadata{
+		ContainerName:           containerName,
+		RootCertificateFilePath: filez.MustAbs(filepath.Join(dataDirPath, "caddy", "pki", "authorities", "local", "root.crt")),
+	}
+
+	volumes := []dctypes.ServiceVolumeConfig{
+		{
+			Type:     "bind",
+			Source:   filez.MustAbs(filepath.Join(buildDirPath, "Caddyfile")),
+			Target:   "/etc/caddy/Caddyfile",
+			ReadOnly: true,
+		},
+		{
+			Type:   "bind",
+			Source: filez.MustAbs(dataDirPath),
+			Target: "/data",
+		},
+	}
+
+	if p.cfg.Local.CARootDirPath != "" {
+		p.localMetadata.RootCertificateFilePath = filez.MustAbs(filepath.Join(p.cfg.Local.CARootDirPath, LocalRootCertificateFileName))
+		p.localMetadata.CertificateFilePath = filez.MustAbs(filepath.Join(buildDirPath, "cert.pem"))
+		p.localMetadata.KeyFilePath = filez.MustAbs(filepath.Join(buildDirPath, "key.pem"))
+
+		volumes = append(volumes,
+			dctypes.ServiceVolumeConfig{
+				Type:     "bind",
+				Source:   p.localMetadata.CertificateFilePath,
+				Target:   proxyCertificateContainerFilePath,
+				ReadOnly: true,
+			},
+			dctypes.ServiceVolumeConfig{
+				Type:     "bind",
+				Source:   p.localMetadata.KeyFilePath,
+				Target:   proxyKeyContainerFilePath,
+				ReadOnly: true,
+			})
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          containerName,
+		ContainerName: containerName,
+		Image:         LocalGetImage(p, "caddy:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Caddy),
+		Networks:      p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    uint32(p.cfg.Local.HTTPExternalPort),
+				Published: uint32(p.cfg.Local.HTTPExternalPort),
+			},
+			{
+				Target:    uint32(p.cfg.Local.HTTPSExternalPort),
+				Published: uint32(p.cfg.Local.HTTPSExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+		Volumes: volumes,
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *proxyImpl) GetCloudTemplate(_ string) *gocf.Template {
+	// nothing to do here
+	return nil
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *proxyImpl) UpdateCloudMetadata(_ *awscft.Stack) {
+	// nothing to do here
+}
+
+// EventHook implements the Plugin interface.
+func (p *proxyImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case LocalBeforeCreateEvent:
+		p.localBeforeCreateEventHook(buildDirPath)
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *proxyImpl) localBeforeCreateEventHook(buildDirPath string) {
+	filez.MustPrepareDir(buildDirPath, 0777)
+
+	// Note: the data dir is persisted across runs so that the local certificate authority only needs to be trusted once.
+	// It contains the private key of the certificate authority, so it is excluded from version control.
+	dataDirPath := p.cfg.Stage.GetConfig().App.GetConfig().GetConfigDirPathForPlugin(p, proxyDataDirParts...)
+	errorz.MaybeMustWrap(os.MkdirAll(dataDirPath, 0777))
+	filez.MustWriteFile(filepath.Join(dataDirPath, ".gitignore"), 0777, 0666, []byte("*\n"))
+
+	hostnames := make([]string, 0, len(p.deps.Routes))
+	for hostname := range p.deps.Routes {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+
+	routes := make([]*assets.ProxyCaddyfileTemplateDataRoute, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		routes = append(routes, &assets.ProxyCaddyfileTemplateDataRoute{
+			Hostname: hostname,
+			Upstream: LocalGetInternalOrigin(p.deps.Routes[hostname]).String(),
+		})
+	}
+
+	templateData := assets.ProxyCaddyfileTemplateData{
+		HTTPPort:  p.cfg.Local.HTTPExternalPort,
+		HTTPSPort: p.cfg.Local.HTTPSExternalPort,
+		Routes:    routes,
+	}
+
+	if p.cfg.Local.CARootDirPath != "" {
+		LocalMustIssueCertificate(p.cfg.Local.CARootDirPath, hostnames, p.localMetadata.CertificateFilePath, p.localMetadata.KeyFilePath)
+		templateData.TLSCertificateFilePath = proxyCertificateContainerFilePath
+		templateData.TLSKeyFilePath = proxyKeyContainerFilePath
+	}
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "Caddyfile"), 0777, 0666,
+		templatez.MustParseAndExecuteText(assets.ProxyCaddyfileTemplateAsset, templateData))
+}
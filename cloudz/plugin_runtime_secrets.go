@@ -0,0 +1,254 @@
+package cloudz
+
+import (
+	"strings"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	gosm "github.com/awslabs/goformation/v6/cloudformation/secretsmanager"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/jsonz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// Runtime secrets constants.
+const (
+	RuntimeSecretsPluginDisplayName = "RuntimeSecrets"
+	RuntimeSecretsPluginName        = "runtime-secrets"
+	RuntimeSecretsRefSecret         = CloudRef("s")
+
+	// RuntimeSecretsKeyPostgresPassword is the key of the Postgres password, set if Postgres is a dependency.
+	RuntimeSecretsKeyPostgresPassword = "POSTGRES_PASSWORD"
+
+	// RuntimeSecretsKeyPostgresURL is the key of the Postgres URL, set if Postgres is a dependency.
+	RuntimeSecretsKeyPostgresURL = "POSTGRES_URL"
+
+	// RuntimeSecretsKeyHasuraAdminSecret is the key of the Hasura admin secret. It is not set automatically: if it is set
+	// in the config values, it must match HasuraConfigCloud.AdminSecret.
+	RuntimeSecretsKeyHasuraAdminSecret = "HASURA_ADMIN_SECRET"
+
+	// RuntimeSecretsKeyKeycloakAdminPassword is the key of the Keycloak admin password. It is not set automatically: it
+	// must be set in the config values if Keycloak is deployed to the cloud.
+	RuntimeSecretsKeyKeycloakAdminPassword = "KEYCLOAK_ADMIN_PASSWORD"
+)
+
+var (
+	_ RuntimeSecrets = &runtimeSecretsImpl{}
+	_ Plugin         = &runtimeSecretsImpl{}
+)
+
+// RuntimeSecretsConfigFunc returns the runtime secrets config for a given Stage.
+type RuntimeSecretsConfigFunc func(Stage, *RuntimeSecretsDependencies) *RuntimeSecretsConfig
+
+// RuntimeSecretsEventHookFunc describes a runtime secrets event hook.
+type RuntimeSecretsEventHookFunc func(RuntimeSecrets, Event, string)
+
+// RuntimeSecretsConfig describes the runtime secrets config.
+type RuntimeSecretsConfig struct {
+	Stage        Stage  `validate:"required"`
+	InstanceName string `validate:"omitempty,resource-name"` // if empty, the plugin is a singleton
+	Values       map[string]string
+	EventHook    RuntimeSecretsEventHookFunc
+}
+
+// MustValidate validates the runtime secrets config.
+func (c *RuntimeSecretsConfig) MustValidate(_ StageTarget) {
+	vz.MustValidateStruct(c)
+
+	for k := range c.Values {
+		errorz.Assertf(k != "" && !strings.Contains(k, ":"), "invalid RuntimeSecretsConfig.Values key: %v", errorz.A(k))
+	}
+}
+
+// RuntimeSecretsDependencies describes the runtime secrets dependencies.
+type RuntimeSecretsDependencies struct {
+	Postgres          Postgres
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the runtime secrets dependencies.
+func (d *RuntimeSecretsDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// RuntimeSecretsCloudMetadata describes the runtime secrets cloud metadata.
+type RuntimeSecretsCloudMetadata struct {
+	Exports   CloudExports
+	SecretARN string
+}
+
+// RuntimeSecrets describes a Secrets Manager secret holding the runtime secrets of a stage as a JSON object. Other
+// plugins reference individual keys in their ECS task definitions (see GetECSSecret), so that the values are injected
+// when tasks start instead of being stored in plaintext in the task definitions. Note that running tasks are not
+// restarted when values change. Local stages don't use Secrets Manager, and values can be read using GetValue.
+type RuntimeSecrets interface {
+	Plugin
+	GetConfig() *RuntimeSecretsConfig
+	GetCloudMetadata(require bool) *RuntimeSecretsCloudMetadata
+	HasValue(key string) bool
+	GetValue(key string) string
+	GetECSSecret(name, key string) *ECSServiceTemplateConfigSecret
+}
+
+type runtimeSecretsImpl struct {
+	cfgFunc       RuntimeSecretsConfigFunc
+	deps          *RuntimeSecretsDependencies
+	cfg           *RuntimeSecretsConfig
+	cloudMetadata *RuntimeSecretsCloudMetadata
+}
+
+// NewRuntimeSecrets initializes a new RuntimeSecrets.
+func NewRuntimeSecrets(cfgFunc RuntimeSecretsConfigFunc, deps *RuntimeSecretsDependencies) RuntimeSecrets {
+	deps.MustValidate()
+
+	return &runtimeSecretsImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*runtimeSecretsImpl) GetDisplayName() string {
+	return RuntimeSecretsPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (*runtimeSecretsImpl) GetName() string {
+	return RuntimeSecretsPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *runtimeSecretsImpl) GetInstanceName() *string {
+	if p.cfg.InstanceName == "" {
+		return nil
+	}
+	return stringz.Ptr(p.cfg.InstanceName)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *runtimeSecretsImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{}
+
+	if p.deps.Postgres != nil {
+		dependenciesMap[p.deps.Postgres] = struct{}{}
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *runtimeSecretsImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *runtimeSecretsImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(RuntimeSecretsPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the RuntimeSecrets interface.
+func (p *runtimeSecretsImpl) GetConfig() *RuntimeSecretsConfig {
+	return p.cfg
+}
+
+// GetCloudMetadata implements the RuntimeSecrets interface.
+func (p *runtimeSecretsImpl) GetCloudMetadata(require bool) *RuntimeSecretsCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(RuntimeSecretsPluginName))
+	return p.cloudMetadata
+}
+
+// HasValue implements the RuntimeSecrets interface.
+func (p *runtimeSecretsImpl) HasValue(key string) bool {
+	_, ok := p.getValues()[key]
+	return ok
+}
+
+// GetValue implements the RuntimeSecrets interface.
+func (p *runtimeSecretsImpl) GetValue(key string) string {
+	value, ok := p.getValues()[key]
+	errorz.Assertf(ok, "unknown key: %v", errorz.A(key), errorz.Prefix(RuntimeSecretsPluginName))
+	return value
+}
+
+// GetECSSecret implements the RuntimeSecrets interface.
+func (p *runtimeSecretsImpl) GetECSSecret(name, key string) *ECSServiceTemplateConfigSecret {
+	errorz.Assertf(p.HasValue(key), "unknown key: %v", errorz.A(key), errorz.Prefix(RuntimeSecretsPluginName))
+
+	return &ECSServiceTemplateConfigSecret{
+		Name:      name,
+		SecretARN: p.GetCloudMetadata(true).SecretARN,
+		JSONKey:   key,
+	}
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *runtimeSecretsImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (*runtimeSecretsImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
+	// nothing to do here
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *runtimeSecretsImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	tpl.Resources[RuntimeSecretsRefSecret.Ref()] = &gosm.Secret{
+		Name:         stringz.Ptr(RuntimeSecretsRefSecret.Name(p)),
+		SecretString: stringz.Ptr(jsonz.MustMarshalIndentDefaultString(p.getValues())),
+		Tags:         CloudGetDefaultTags(RuntimeSecretsRefSecret.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, RuntimeSecretsRefSecret)
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *runtimeSecretsImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+
+	p.cloudMetadata = &RuntimeSecretsCloudMetadata{
+		Exports:   exports,
+		SecretARN: exports.GetRef(RuntimeSecretsRefSecret),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *runtimeSecretsImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *runtimeSecretsImpl) getValues() map[string]string {
+	values := map[string]string{}
+
+	if p.deps.Postgres != nil {
+		switch p.cfg.Stage.GetTarget() {
+		case Local:
+			values[RuntimeSecretsKeyPostgresPassword] = LocalPassword
+			values[RuntimeSecretsKeyPostgresURL] = p.deps.Postgres.GetLocalMetadata().InternalURL.String()
+		case Cloud:
+			values[RuntimeSecretsKeyPostgresPassword] = p.deps.Postgres.GetConfig().Cloud.Password
+			values[RuntimeSecretsKeyPostgresURL] = p.deps.Postgres.GetCloudMetadata(true).URL.String()
+		}
+	}
+
+	for k, v := range p.cfg.Values {
+		_, ok := values[k]
+		errorz.Assertf(!ok, "reserved key: %v", errorz.A(k), errorz.Prefix(RuntimeSecretsPluginName))
+		values[k] = v
+	}
+
+	return values
+}
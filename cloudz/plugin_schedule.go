@@ -0,0 +1,335 @@
+package cloudz
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goevents "github.com/awslabs/goformation/v6/cloudformation/events"
+	golambda "github.com/awslabs/goformation/v6/cloudformation/lambda"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/jsonz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// Schedule constants.
+const (
+	SchedulePluginDisplayName = "Schedule"
+	SchedulePluginName        = "schedule"
+	ScheduleRefRule           = CloudRef("r")
+	ScheduleRefPermission     = CloudRef("p")
+	ScheduleAttARN            = CloudAtt("Arn")
+
+	scheduleTargetID = "function"
+)
+
+var (
+	_ Schedule = &scheduleImpl{}
+	_ Plugin   = &scheduleImpl{}
+
+	scheduleRateExpressionRegexp = regexp.MustCompile(`^rate\((\d+) (minutes?|hours?|days?)\)$`)
+	scheduleCronExpressionRegexp = regexp.MustCompile(`^cron\((\S+) (\S+) (\S+) (\S+) (\S+) (\S+)\)$`)
+	scheduleDayOfWeekRegexp      = regexp.MustCompile(`[1-7]`)
+	scheduleUnsupportedRegexp    = regexp.MustCompile(`#|LW|(^|[0-9,])[LW]($|[^A-Z])`)
+)
+
+// ScheduleConfigFunc returns the schedule config for a given Stage.
+type ScheduleConfigFunc func(Stage, *ScheduleDependencies) *ScheduleConfig
+
+// ScheduleEventHookFunc describes a schedule event hook.
+type ScheduleEventHookFunc func(Schedule, Event, string)
+
+// ScheduleConfig describes the schedule config.
+//
+// The Expression uses the EventBridge syntax, i.e. "rate(5 minutes)" or "cron(0 12 * * ? *)". The Input, if any, is
+// marshaled to JSON and passed to the function as event payload (an empty object otherwise).
+type ScheduleConfig struct {
+	Stage      Stage  `validate:"required"`
+	Name       string `validate:"required,resource-name"`
+	Expression string `validate:"required"`
+	Input      map[string]interface{}
+	Disabled   bool
+	EventHook  ScheduleEventHookFunc
+}
+
+// MustValidate validates the schedule config.
+func (c *ScheduleConfig) MustValidate(_ StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(
+		scheduleRateExpressionRegexp.MatchString(c.Expression) || scheduleCronExpressionRegexp.MatchString(c.Expression),
+		"invalid ScheduleConfig.Expression: %v", errorz.A(c.Expression))
+}
+
+// ScheduleDependencies describes the schedule dependencies.
+type ScheduleDependencies struct {
+	Function          Function `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the schedule dependencies.
+func (d *ScheduleDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// ScheduleLocalMetadata describes the schedule local metadata.
+type ScheduleLocalMetadata struct {
+	ContainerName  string
+	CronExpression string
+}
+
+// ScheduleCloudMetadata describes the schedule cloud metadata.
+type ScheduleCloudMetadata struct {
+	Exports CloudExports
+}
+
+// GetARN returns the rule ARN.
+func (m *ScheduleCloudMetadata) GetARN() string {
+	return m.Exports.GetAtt(ScheduleRefRule, ScheduleAttARN)
+}
+
+// GetName returns the rule name.
+func (m *ScheduleCloudMetadata) GetName() string {
+	return m.Exports.GetRef(ScheduleRefRule)
+}
+
+// Schedule describes an EventBridge rule that periodically invokes a function.
+// Locally, the rule is emulated by a cron container that posts the input to the function runtime interface emulator.
+type Schedule interface {
+	Plugin
+	GetConfig() *ScheduleConfig
+	GetLocalMetadata() *ScheduleLocalMetadata
+	GetCloudMetadata(require bool) *ScheduleCloudMetadata
+}
+
+type scheduleImpl struct {
+	cfgFunc       ScheduleConfigFunc
+	deps          *ScheduleDependencies
+	cfg           *ScheduleConfig
+	localMetadata *ScheduleLocalMetadata
+	cloudMetadata *ScheduleCloudMetadata
+}
+
+// NewSchedule initializes a new Schedule.
+func NewSchedule(cfgFunc ScheduleConfigFunc, deps *ScheduleDependencies) Schedule {
+	deps.MustValidate()
+
+	return &scheduleImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*scheduleImpl) GetDisplayName() string {
+	return SchedulePluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *scheduleImpl) GetName() string {
+	return SchedulePluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *scheduleImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *scheduleImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Function: {},
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *scheduleImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *scheduleImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(SchedulePluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the Schedule interface.
+func (p *scheduleImpl) GetConfig() *ScheduleConfig {
+	return p.cfg
+}
+
+// GetLocalMetadata implements the Schedule interface.
+func (p *scheduleImpl) GetLocalMetadata() *ScheduleLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(SchedulePluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the Schedule interface.
+func (p *scheduleImpl) GetCloudMetadata(require bool) *ScheduleCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(SchedulePluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *scheduleImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *scheduleImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath string) {
+	containerName := LocalGetContainerName(p)
+
+	p.localMetadata = &ScheduleLocalMetadata{
+		ContainerName:  containerName,
+		CronExpression: getScheduleCronExpression(p.cfg.Expression),
+	}
+
+	if p.cfg.Disabled {
+		return
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          containerName,
+		ContainerName: containerName,
+		Image:         LocalGetImage(p, "alpine:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Alpine),
+		Command:       dctypes.ShellCommand{"crond", "-f", "-l", "8"},
+		Networks:      p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Restart:       "unless-stopped",
+		Volumes: []dctypes.ServiceVolumeConfig{
+			{
+				Type:     "bind",
+				Source:   filez.MustAbs(filepath.Join(buildDirPath, "crontab")),
+				Target:   "/etc/crontabs/root",
+				ReadOnly: true,
+			},
+			{
+				Type:     "bind",
+				Source:   filez.MustAbs(filepath.Join(buildDirPath, "input.json")),
+				Target:   "/etc/schedule/input.json",
+				ReadOnly: true,
+			},
+		},
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *scheduleImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	tpl.Resources[ScheduleRefRule.Ref()] = &goevents.Rule{
+		Description:        stringz.Ptr(ScheduleRefRule.Name(p)),
+		Name:               stringz.Ptr(ScheduleRefRule.Name(p)),
+		ScheduleExpression: stringz.Ptr(p.cfg.Expression),
+		State: stringz.Ptr(func() string {
+			if p.cfg.Disabled {
+				return "DISABLED"
+			}
+			return "ENABLED"
+		}()),
+		Targets: &[]goevents.Rule_Target{
+			{
+				Arn:   p.deps.Function.GetCloudMetadata(true).GetARN(),
+				Id:    scheduleTargetID,
+				Input: stringz.Ptr(string(p.getInput())),
+			},
+		},
+	}
+	CloudAddExpRef(tpl, p, ScheduleRefRule)
+	CloudAddExpGetAtt(tpl, p, ScheduleRefRule, ScheduleAttARN)
+
+	tpl.Resources[ScheduleRefPermission.Ref()] = &golambda.Permission{
+		Action:       "lambda:InvokeFunction",
+		FunctionName: p.deps.Function.GetCloudMetadata(true).GetARN(),
+		Principal:    "events.amazonaws.com",
+		SourceArn:    stringz.Ptr(gocf.GetAtt(ScheduleRefRule.Ref(), ScheduleAttARN.Ref())),
+	}
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *scheduleImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &ScheduleCloudMetadata{
+		Exports: NewCloudExports(stack),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *scheduleImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case LocalBeforeCreateEvent:
+		p.localBeforeCreateEventHook(buildDirPath)
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *scheduleImpl) localBeforeCreateEventHook(buildDirPath string) {
+	filez.MustPrepareDir(buildDirPath, 0777)
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "crontab"), 0777, 0666,
+		[]byte(fmt.Sprintf(
+			"%v wget -q -O /dev/null --header 'Content-Type: application/json' --post-file /etc/schedule/input.json '%v'\n",
+			p.localMetadata.CronExpression,
+			p.deps.Function.GetLocalMetadata().InternalURL.String())))
+
+	filez.MustWriteFile(filepath.Join(buildDirPath, "input.json"), 0777, 0666, p.getInput())
+}
+
+func (p *scheduleImpl) getInput() []byte {
+	if p.cfg.Input == nil {
+		return []byte("{}")
+	}
+	return jsonz.MustMarshal(p.cfg.Input)
+}
+
+// getScheduleCronExpression converts an EventBridge schedule expression to a crontab expression. Rates are converted to
+// steps of the corresponding field. For cron expressions, the year field is dropped, "?" is replaced by "*", and numeric
+// days of the week are shifted from 1-7 (SUN-SAT) to 0-6. Other EventBridge extensions (e.g. "L", "W", "#") are not
+// supported.
+func getScheduleCronExpression(expression string) string {
+	if match := scheduleRateExpressionRegexp.FindStringSubmatch(expression); match != nil {
+		value, err := strconv.Atoi(match[1])
+		errorz.MaybeMustWrap(err)
+
+		switch strings.TrimSuffix(match[2], "s") {
+		case "minute":
+			return fmt.Sprintf("*/%v * * * *", value)
+		case "hour":
+			return fmt.Sprintf("0 */%v * * *", value)
+		default:
+			return fmt.Sprintf("0 0 */%v * *", value)
+		}
+	}
+
+	match := scheduleCronExpressionRegexp.FindStringSubmatch(expression)
+	errorz.Assertf(match != nil, "invalid schedule expression: %v", errorz.A(expression), errorz.Prefix(SchedulePluginName))
+
+	fields := match[1:6]
+	for i, field := range fields {
+		errorz.Assertf(!scheduleUnsupportedRegexp.MatchString(field), "unsupported schedule expression: %v", errorz.A(expression), errorz.Prefix(SchedulePluginName))
+		fields[i] = strings.ReplaceAll(field, "?", "*")
+	}
+
+	fields[4] = scheduleDayOfWeekRegexp.ReplaceAllStringFunc(fields[4], func(d string) string {
+		return strconv.Itoa(int(d[0]-'0') - 1)
+	})
+
+	return strings.Join(fields, " ")
+}
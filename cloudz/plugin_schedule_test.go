@@ -0,0 +1,46 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetScheduleCronExpression(t *testing.T) {
+	testCases := []struct {
+		expression string
+		expected   string
+		isValid    bool
+	}{
+		{expression: "rate(1 minute)", expected: "*/1 * * * *", isValid: true},
+		{expression: "rate(5 minutes)", expected: "*/5 * * * *", isValid: true},
+		{expression: "rate(1 hour)", expected: "0 */1 * * *", isValid: true},
+		{expression: "rate(2 days)", expected: "0 0 */2 * *", isValid: true},
+		{expression: "cron(0 12 * * ? *)", expected: "0 12 * * *", isValid: true},
+		{expression: "cron(0/15 * * * ? *)", expected: "0/15 * * * *", isValid: true},
+		{expression: "cron(0 8 1 * ? 2030)", expected: "0 8 1 * *", isValid: true},
+		{expression: "cron(15 10 ? * 2-6 *)", expected: "15 10 * * 1-5", isValid: true},
+		{expression: "cron(0 0 ? * 1,7 *)", expected: "0 0 * * 0,6", isValid: true},
+		{expression: "cron(0 18 ? * MON-FRI *)", expected: "0 18 * * MON-FRI", isValid: true},
+		{expression: "cron(0 0 ? * WED *)", expected: "0 0 * * WED", isValid: true},
+		{expression: "cron(0 0 L * ? *)", isValid: false},
+		{expression: "cron(0 0 15W * ? *)", isValid: false},
+		{expression: "cron(0 0 ? * 6#3 *)", isValid: false},
+		{expression: "cron(0 0 ? * 6L *)", isValid: false},
+		{expression: "cron(0 0 * * *)", isValid: false},
+		{expression: "rate(1 week)", isValid: false},
+		{expression: "*/5 * * * *", isValid: false},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.expression, func(t *testing.T) {
+			if testCase.isValid {
+				require.Equal(t, testCase.expected, getScheduleCronExpression(testCase.expression))
+			} else {
+				require.Panics(t, func() { getScheduleCronExpression(testCase.expression) })
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package cloudz
+
+import (
+	"fmt"
+	"net/url"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	gotransfer "github.com/awslabs/goformation/v6/cloudformation/transfer"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// SFTP constants.
+const (
+	SFTPPluginDisplayName = "SFTP"
+	SFTPPluginName        = "sftp"
+	SFTPRefServer         = CloudRef("s")
+	SFTPRefRole           = CloudRef("r")
+	SFTPRefLoggingRole    = CloudRef("lr")
+	SFTPAttARN            = CloudAtt("Arn")
+	SFTPAttServerID       = CloudAtt("ServerId")
+
+	sftpPort           = 22
+	sftpSecurityPolicy = "TransferSecurityPolicy-2020-06"
+)
+
+var (
+	_ SFTP   = &sftpImpl{}
+	_ Plugin = &sftpImpl{}
+)
+
+// SFTPConfigFunc returns the SFTP config for a given Stage.
+type SFTPConfigFunc func(Stage, *SFTPDependencies) *SFTPConfig
+
+// SFTPEventHookFunc describes a SFTP event hook.
+type SFTPEventHookFunc func(SFTP, Event, string)
+
+// SFTPConfig describes the SFTP config.
+//
+// The SFTP server is an AWS Transfer Family server with a public endpoint and service-managed users, which authenticate
+// using SSH keys. Each user is confined (chroot) to its home directory, i.e. the "<user name>/" prefix of the Bucket.
+// SFTP servers are not run in local stages: local processes should access the Bucket directly.
+type SFTPConfig struct {
+	Stage     Stage             `validate:"required"`
+	Name      string            `validate:"required,resource-name"`
+	Users     []*SFTPConfigUser `validate:"required,min=1,dive,required"`
+	EventHook SFTPEventHookFunc
+}
+
+// MustValidate validates the SFTP config.
+func (c *SFTPConfig) MustValidate(_ StageTarget) {
+	vz.MustValidateStruct(c)
+
+	userNames := map[string]struct{}{}
+	for _, user := range c.Users {
+		_, ok := userNames[user.Name]
+		errorz.Assertf(!ok, "duplicate SFTPConfig.Users name: %v", errorz.A(user.Name), errorz.Prefix(SFTPPluginName))
+		userNames[user.Name] = struct{}{}
+	}
+}
+
+// SFTPConfigUser describes part of the SFTP config.
+// SSH public keys are in OpenSSH format (e.g. "ssh-ed25519 AAAA... comment").
+type SFTPConfigUser struct {
+	Name          string   `validate:"required,min=3,resource-name"`
+	SSHPublicKeys []string `validate:"required,min=1,max=50,dive,required,startswith=ssh-|startswith=ecdsa-"`
+}
+
+func (u *SFTPConfigUser) getRef() CloudRef {
+	return CloudRef("u-" + u.Name)
+}
+
+// SFTPDependencies describes the SFTP dependencies.
+type SFTPDependencies struct {
+	Bucket            Bucket `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the SFTP dependencies.
+func (d *SFTPDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// SFTPCloudMetadata describes the SFTP cloud metadata.
+type SFTPCloudMetadata struct {
+	Exports  CloudExports
+	Endpoint string
+	URL      *url.URL
+}
+
+// SFTP describes a SFTP server.
+type SFTP interface {
+	Plugin
+	GetConfig() *SFTPConfig
+	GetCloudMetadata(require bool) *SFTPCloudMetadata
+}
+
+type sftpImpl struct {
+	cfgFunc       SFTPConfigFunc
+	deps          *SFTPDependencies
+	cfg           *SFTPConfig
+	cloudMetadata *SFTPCloudMetadata
+}
+
+// NewSFTP initializes a new SFTP.
+func NewSFTP(cfgFunc SFTPConfigFunc, deps *SFTPDependencies) SFTP {
+	deps.MustValidate()
+
+	return &sftpImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*sftpImpl) GetDisplayName() string {
+	return SFTPPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *sftpImpl) GetName() string {
+	return SFTPPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *sftpImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *sftpImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Bucket: {},
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *sftpImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *sftpImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(SFTPPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the SFTP interface.
+func (p *sftpImpl) GetConfig() *SFTPConfig {
+	return p.cfg
+}
+
+// GetCloudMetadata implements the SFTP interface.
+func (p *sftpImpl) GetCloudMetadata(require bool) *SFTPCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(SFTPPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *sftpImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *sftpImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
+	// nothing to do here
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *sftpImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+	bucketARN := p.deps.Bucket.GetCloudMetadata(true).Exports.GetAtt(BucketRefBucket, BucketAttARN)
+
+	tpl.Resources[SFTPRefLoggingRole.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("transfer.amazonaws.com"),
+		ManagedPolicyArns: &[]string{
+			"arn:aws:iam::aws:policy/service-role/AWSTransferLoggingAccess",
+		},
+		RoleName: stringz.Ptr(SFTPRefLoggingRole.Name(p)),
+		Tags:     CloudGetDefaultTags(SFTPRefLoggingRole.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, SFTPRefLoggingRole)
+	CloudAddExpGetAtt(tpl, p, SFTPRefLoggingRole, SFTPAttARN)
+
+	tpl.Resources[SFTPRefRole.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("transfer.amazonaws.com"),
+		Policies: &[]goiam.Role_Policy{
+			{
+				PolicyName: SFTPRefRole.Name(p),
+				PolicyDocument: NewPolicyDocument(
+					NewPolicyStatement().
+						AddActions("s3:ListBucket", "s3:GetBucketLocation").
+						AddResources(bucketARN),
+					NewPolicyStatement().
+						AddActions("s3:GetObject", "s3:GetObjectVersion", "s3:PutObject", "s3:DeleteObject").
+						AddResources(bucketARN+"/*")),
+			},
+		},
+		RoleName: stringz.Ptr(SFTPRefRole.Name(p)),
+		Tags:     CloudGetDefaultTags(SFTPRefRole.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, SFTPRefRole)
+	CloudAddExpGetAtt(tpl, p, SFTPRefRole, SFTPAttARN)
+
+	tpl.Resources[SFTPRefServer.Ref()] = &gotransfer.Server{
+		Domain:               stringz.Ptr("S3"),
+		EndpointType:         stringz.Ptr("PUBLIC"),
+		IdentityProviderType: stringz.Ptr("SERVICE_MANAGED"),
+		LoggingRole:          stringz.Ptr(gocf.GetAtt(SFTPRefLoggingRole.Ref(), SFTPAttARN.Ref())),
+		SecurityPolicyName:   stringz.Ptr(sftpSecurityPolicy),
+		Tags:                 CloudGetDefaultTags(SFTPRefServer.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, SFTPRefServer)
+	CloudAddExpGetAtt(tpl, p, SFTPRefServer, SFTPAttARN)
+	CloudAddExpGetAtt(tpl, p, SFTPRefServer, SFTPAttServerID)
+
+	for _, user := range p.cfg.Users {
+		// Note: goformation does not model SshPublicKeys correctly (it should be a list of strings).
+		tpl.Resources[user.getRef().Ref()] = &gocf.CustomResource{
+			Type: "AWS::Transfer::User",
+			Properties: map[string]interface{}{
+				"HomeDirectoryMappings": []gotransfer.User_HomeDirectoryMapEntry{
+					{
+						Entry:  "/",
+						Target: fmt.Sprintf("/%v/%v", p.deps.Bucket.GetCloudMetadata(true).GetName(), user.Name),
+					},
+				},
+				"HomeDirectoryType": "LOGICAL",
+				"Role":              gocf.GetAtt(SFTPRefRole.Ref(), SFTPAttARN.Ref()),
+				"ServerId":          gocf.GetAtt(SFTPRefServer.Ref(), SFTPAttServerID.Ref()),
+				"SshPublicKeys":     user.SSHPublicKeys,
+				"Tags":              CloudGetDefaultTags(user.getRef().Name(p)),
+				"UserName":          user.Name,
+			},
+		}
+	}
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *sftpImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+	serverID := exports.GetAtt(SFTPRefServer, SFTPAttServerID)
+	endpoint := fmt.Sprintf("%v.server.transfer.%v.amazonaws.com", serverID, p.cfg.Stage.GetConfig().App.GetConfig().AWSConfig.Region)
+
+	p.cloudMetadata = &SFTPCloudMetadata{
+		Exports:  exports,
+		Endpoint: endpoint,
+		URL:      urlz.MustParse(fmt.Sprintf("sftp://%v:%v", endpoint, sftpPort)),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *sftpImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
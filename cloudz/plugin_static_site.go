@@ -0,0 +1,484 @@
+package cloudz
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	gocloudfront "github.com/awslabs/goformation/v6/cloudformation/cloudfront"
+	goroute53 "github.com/awslabs/goformation/v6/cloudformation/route53"
+	gos3 "github.com/awslabs/goformation/v6/cloudformation/s3"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+)
+
+// StaticSite constants.
+const (
+	StaticSitePluginDisplayName       = "StaticSite"
+	StaticSitePluginName              = "static-site"
+	StaticSiteRefBucket               = CloudRef("b")
+	StaticSiteRefOriginAccessIdentity = CloudRef("oai")
+	StaticSiteRefBucketPolicy         = CloudRef("bp")
+	StaticSiteRefDistribution         = CloudRef("d")
+	StaticSiteRefRecordSet            = CloudRef("rs")
+	StaticSiteRefHealthCheck          = CloudRef("hc")
+	StaticSiteAttARN                  = CloudAtt("Arn")
+	StaticSiteAttDomainName           = CloudAtt("DomainName")
+	StaticSiteAttRegionalDomainName   = CloudAtt("RegionalDomainName")
+	StaticSiteAttS3CanonicalUserID    = CloudAtt("S3CanonicalUserId")
+
+	staticSiteOriginID = "bucket"
+)
+
+var (
+	_ StaticSite       = &staticSiteImpl{}
+	_ Plugin           = &staticSiteImpl{}
+	_ cloudBucketOwner = &staticSiteImpl{}
+)
+
+// StaticSiteConfigFunc returns the static site config for a given Stage.
+type StaticSiteConfigFunc func(Stage, *StaticSiteDependencies) *StaticSiteConfig
+
+// StaticSiteEventHookFunc describes a static site event hook.
+type StaticSiteEventHookFunc func(StaticSite, Event, string)
+
+// StaticSiteConfig describes the static site config.
+//
+// The site is built by running BuildCommand in DirPath, with the "STAGE_*" values, the public stage environment values
+// listed in StageEnvironment (e.g. "HASURA_URL", also passed with the EnvExport prefixes, see GetStageEnv), plus
+// Environment. The build output is expected in DirPath/BuildOutputDirName (e.g. "build" or "dist").
+type StaticSiteConfig struct {
+	Stage              Stage    `validate:"required"`
+	Name               string   `validate:"required,resource-name"`
+	DirPath            string   `validate:"required"`
+	BuildCommand       []string `validate:"required,min=1"`
+	BuildOutputDirName string   `validate:"required"`
+	StageEnvironment   []string
+	Environment        map[string]string
+	IsSinglePageApp    bool
+	Local              *StaticSiteConfigLocal
+	Cloud              *StaticSiteConfigCloud
+	EventHook          StaticSiteEventHookFunc
+}
+
+// MustValidate validates the static site config.
+func (c *StaticSiteConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing StaticSiteConfig.Local")
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing StaticSiteConfig.Cloud")
+
+	if stageTarget == Cloud && c.Cloud.Routing != nil {
+		c.Cloud.Routing.MustValidate()
+	}
+}
+
+// StaticSiteConfigLocal describes part of the static site config.
+type StaticSiteConfigLocal struct {
+	ExternalPort uint16 `validate:"required"`
+}
+
+// StaticSiteConfigCloud describes part of the static site config.
+type StaticSiteConfigCloud struct {
+	DomainName string `validate:"required,fqdn"`
+	PriceClass string `validate:"omitempty,oneof=PriceClass_100 PriceClass_200 PriceClass_All"`
+	Routing    *RecordSetRoutingConfig
+}
+
+// StaticSiteDependencies describes the static site dependencies.
+// The Certificate must be in the us-east-1 region, as required by CloudFront.
+type StaticSiteDependencies struct {
+	Certificate       Certificate `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the static site dependencies.
+func (d *StaticSiteDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// StaticSiteLocalMetadata describes the static site local metadata.
+type StaticSiteLocalMetadata struct {
+	ContainerName string
+	ExternalURL   *url.URL
+}
+
+// StaticSiteCloudMetadata describes the static site cloud metadata.
+type StaticSiteCloudMetadata struct {
+	Exports CloudExports
+	URL     *url.URL
+}
+
+// GetBucketName returns the bucket name.
+func (m *StaticSiteCloudMetadata) GetBucketName() string {
+	return m.Exports.GetRef(StaticSiteRefBucket)
+}
+
+// GetDistributionID returns the CloudFront distribution ID.
+func (m *StaticSiteCloudMetadata) GetDistributionID() string {
+	return m.Exports.GetRef(StaticSiteRefDistribution)
+}
+
+// StaticSite describes a static site.
+type StaticSite interface {
+	Plugin
+	GetConfig() *StaticSiteConfig
+	GetLocalMetadata() *StaticSiteLocalMetadata
+	GetCloudMetadata(require bool) *StaticSiteCloudMetadata
+	Build()
+}
+
+type staticSiteImpl struct {
+	cfgFunc       StaticSiteConfigFunc
+	deps          *StaticSiteDependencies
+	cfg           *StaticSiteConfig
+	localMetadata *StaticSiteLocalMetadata
+	cloudMetadata *StaticSiteCloudMetadata
+}
+
+// NewStaticSite initializes a new StaticSite.
+func NewStaticSite(cfgFunc StaticSiteConfigFunc, deps *StaticSiteDependencies) StaticSite {
+	deps.MustValidate()
+
+	return &staticSiteImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*staticSiteImpl) GetDisplayName() string {
+	return StaticSitePluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *staticSiteImpl) GetName() string {
+	return StaticSitePluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *staticSiteImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *staticSiteImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Certificate: {},
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *staticSiteImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *staticSiteImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(StaticSitePluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the StaticSite interface.
+func (p *staticSiteImpl) GetConfig() *StaticSiteConfig {
+	return p.cfg
+}
+
+// GetLocalMetadata implements the StaticSite interface.
+func (p *staticSiteImpl) GetLocalMetadata() *StaticSiteLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(StaticSitePluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the StaticSite interface.
+func (p *staticSiteImpl) GetCloudMetadata(require bool) *StaticSiteCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(StaticSitePluginName))
+	return p.cloudMetadata
+}
+
+// Build builds the static site for the stage it is configured with.
+func (p *staticSiteImpl) Build() {
+	env := p.getStageEnv()
+	for k, v := range p.cfg.Environment {
+		env[k] = v
+	}
+
+	p.cfg.Stage.GetConfig().App.GetOperations().NewCommand(p.cfg.BuildCommand[0]).
+		AddParamsString(p.cfg.BuildCommand[1:]...).
+		SetDir(p.cfg.DirPath).
+		SetEnvMap(env).
+		MustRun()
+}
+
+func (p *staticSiteImpl) getStageEnv() map[string]string {
+	allowedKeys := map[string]struct{}{
+		"STAGE_NAME":   {},
+		"STAGE_TARGET": {},
+		"STAGE_MODE":   {},
+	}
+
+	for _, k := range p.cfg.StageEnvironment {
+		allowedKeys[k] = struct{}{}
+	}
+
+	prefixes := []string{""}
+	if exportCfg := p.cfg.Stage.GetConfig().EnvExport; exportCfg != nil {
+		prefixes = append(prefixes, exportCfg.Prefixes...)
+	}
+
+	env := make(map[string]string)
+	for k, v := range getStageEnv(p.cfg.Stage, false) {
+		for _, prefix := range prefixes {
+			if _, ok := allowedKeys[strings.TrimPrefix(k, prefix)]; ok && strings.HasPrefix(k, prefix) {
+				env[k] = v
+			}
+		}
+	}
+
+	return env
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *staticSiteImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *staticSiteImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath string) {
+	containerName := LocalGetContainerName(p)
+
+	p.localMetadata = &StaticSiteLocalMetadata{
+		ContainerName: containerName,
+		ExternalURL:   urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.ExternalPort)),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          containerName,
+		ContainerName: containerName,
+		Image:         LocalGetImage(p, "nginx:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Nginx),
+		Networks:      p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    uint32(p.cfg.Local.ExternalPort),
+				Published: uint32(p.cfg.Local.ExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+		Volumes: []dctypes.ServiceVolumeConfig{
+			{
+				Type:     "bind",
+				Source:   filez.MustAbs(filepath.Join(p.cfg.DirPath, p.cfg.BuildOutputDirName)),
+				Target:   "/usr/share/nginx/html",
+				ReadOnly: true,
+			},
+			{
+				Type:     "bind",
+				Source:   filez.MustAbs(filepath.Join(buildDirPath, "default.conf")),
+				Target:   "/etc/nginx/conf.d/default.conf",
+				ReadOnly: true,
+			},
+		},
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *staticSiteImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	tpl.Resources[StaticSiteRefBucket.Ref()] = &gos3.Bucket{
+		BucketName: stringz.Ptr(StaticSiteRefBucket.Name(p)),
+		PublicAccessBlockConfiguration: &gos3.Bucket_PublicAccessBlockConfiguration{
+			BlockPublicAcls:       boolz.Ptr(true),
+			BlockPublicPolicy:     boolz.Ptr(true),
+			IgnorePublicAcls:      boolz.Ptr(true),
+			RestrictPublicBuckets: boolz.Ptr(true),
+		},
+		Tags: CloudGetDefaultTags(StaticSiteRefBucket.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, StaticSiteRefBucket)
+	CloudAddExpGetAtt(tpl, p, StaticSiteRefBucket, StaticSiteAttARN)
+	CloudAddExpGetAtt(tpl, p, StaticSiteRefBucket, StaticSiteAttRegionalDomainName)
+
+	tpl.Resources[StaticSiteRefOriginAccessIdentity.Ref()] = &gocloudfront.CloudFrontOriginAccessIdentity{
+		CloudFrontOriginAccessIdentityConfig: &gocloudfront.CloudFrontOriginAccessIdentity_CloudFrontOriginAccessIdentityConfig{
+			Comment: StaticSiteRefOriginAccessIdentity.Name(p),
+		},
+	}
+	CloudAddExpRef(tpl, p, StaticSiteRefOriginAccessIdentity)
+
+	tpl.Resources[StaticSiteRefBucketPolicy.Ref()] = &gos3.BucketPolicy{
+		Bucket: gocf.Ref(StaticSiteRefBucket.Ref()),
+		PolicyDocument: NewPolicyDocument(
+			NewPolicyStatement().
+				SetCanonicalUserPrincipal(gocf.GetAtt(StaticSiteRefOriginAccessIdentity.Ref(), StaticSiteAttS3CanonicalUserID.Ref())).
+				AddActions("s3:GetObject").
+				AddResources(gocf.Join("", []string{
+					gocf.GetAtt(StaticSiteRefBucket.Ref(), StaticSiteAttARN.Ref()),
+					"/*",
+				}))),
+	}
+
+	tpl.Resources[StaticSiteRefDistribution.Ref()] = &gocloudfront.Distribution{
+		DistributionConfig: &gocloudfront.Distribution_DistributionConfig{
+			Aliases: &[]string{
+				p.cfg.Cloud.DomainName,
+			},
+			Comment: stringz.Ptr(StaticSiteRefDistribution.Name(p)),
+			CustomErrorResponses: func() *[]gocloudfront.Distribution_CustomErrorResponse {
+				if !p.cfg.IsSinglePageApp {
+					return nil
+				}
+
+				return &[]gocloudfront.Distribution_CustomErrorResponse{
+					{
+						ErrorCode:        403,
+						ResponseCode:     intz.Ptr(200),
+						ResponsePagePath: stringz.Ptr("/index.html"),
+					},
+					{
+						ErrorCode:        404,
+						ResponseCode:     intz.Ptr(200),
+						ResponsePagePath: stringz.Ptr("/index.html"),
+					},
+				}
+			}(),
+			DefaultCacheBehavior: &gocloudfront.Distribution_DefaultCacheBehavior{
+				AllowedMethods:       &[]string{"GET", "HEAD", "OPTIONS"},
+				CachePolicyId:        stringz.Ptr(cdnCachePolicyIDs[CDNCachePolicyOptimized]),
+				Compress:             boolz.Ptr(true),
+				TargetOriginId:       staticSiteOriginID,
+				ViewerProtocolPolicy: "redirect-to-https",
+			},
+			DefaultRootObject: stringz.Ptr("index.html"),
+			Enabled:           true,
+			HttpVersion:       stringz.Ptr("http2"),
+			IPV6Enabled:       boolz.Ptr(true),
+			Origins: &[]gocloudfront.Distribution_Origin{
+				{
+					DomainName: gocf.GetAtt(StaticSiteRefBucket.Ref(), StaticSiteAttRegionalDomainName.Ref()),
+					Id:         staticSiteOriginID,
+					S3OriginConfig: &gocloudfront.Distribution_S3OriginConfig{
+						OriginAccessIdentity: stringz.Ptr(gocf.Join("", []string{
+							"origin-access-identity/cloudfront/",
+							gocf.Ref(StaticSiteRefOriginAccessIdentity.Ref()),
+						})),
+					},
+				},
+			},
+			PriceClass: stringz.PtrZeroToNil(p.cfg.Cloud.PriceClass),
+			ViewerCertificate: &gocloudfront.Distribution_ViewerCertificate{
+				AcmCertificateArn:      stringz.Ptr(p.deps.Certificate.GetCloudMetadata(true).ARN),
+				MinimumProtocolVersion: stringz.Ptr("TLSv1.2_2021"),
+				SslSupportMethod:       stringz.Ptr("sni-only"),
+			},
+		},
+		Tags: CloudGetDefaultTags(StaticSiteRefDistribution.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, StaticSiteRefDistribution)
+	CloudAddExpGetAtt(tpl, p, StaticSiteRefDistribution, StaticSiteAttDomainName)
+
+	if hostedZoneID := p.deps.Certificate.GetConfig().Cloud.GetDNSProvider().GetHostedZoneID(); hostedZoneID != nil {
+		recordSet := &goroute53.RecordSet{
+			AliasTarget: &goroute53.RecordSet_AliasTarget{
+				DNSName:      gocf.GetAtt(StaticSiteRefDistribution.Ref(), StaticSiteAttDomainName.Ref()),
+				HostedZoneId: cdnCloudFrontHostedZoneID,
+			},
+			HostedZoneId: hostedZoneID,
+			Name:         p.cfg.Cloud.DomainName,
+			Type:         "A",
+		}
+		CloudApplyRecordSetRouting(tpl, p, StaticSiteRefHealthCheck, recordSet, p.cfg.Cloud.Routing)
+		tpl.Resources[StaticSiteRefRecordSet.Ref()] = recordSet
+		CloudAddExpRef(tpl, p, StaticSiteRefRecordSet)
+	}
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *staticSiteImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &StaticSiteCloudMetadata{
+		Exports: NewCloudExports(stack),
+		URL:     urlz.MustParse(fmt.Sprintf("https://%v", p.cfg.Cloud.DomainName)),
+	}
+}
+
+// getCloudBucketNames implements the cloudBucketOwner interface.
+func (p *staticSiteImpl) getCloudBucketNames() []string {
+	return []string{p.cloudMetadata.GetBucketName()}
+}
+
+// EventHook implements the Plugin interface.
+func (p *staticSiteImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case LocalBeforeCreateEvent:
+		p.localBeforeCreateEventHook(buildDirPath)
+	case CloudPreflightEvent:
+		p.cloudPreflightEventHook()
+	case CloudAfterDeployEvent:
+		p.cloudAfterDeployEventHook()
+	case CloudBeforeDestroyEvent:
+		p.cloudBeforeDestroyEventHook()
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *staticSiteImpl) localBeforeCreateEventHook(buildDirPath string) {
+	filez.MustPrepareDir(buildDirPath, 0777)
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "default.conf"), 0777, 0666,
+		templatez.MustParseAndExecuteText(
+			assets.StaticSiteNginxConfTemplateAsset,
+			assets.StaticSiteNginxConfTemplateData{
+				Port:            p.cfg.Local.ExternalPort,
+				IsSinglePageApp: p.cfg.IsSinglePageApp,
+			}))
+
+	p.Build()
+}
+
+func (p *staticSiteImpl) cloudPreflightEventHook() {
+	region := p.deps.Certificate.GetStage().GetConfig().App.GetConfig().AWSConfig.Region
+	errorz.Assertf(region == cdnCertificateRequiredRegion, "CloudFront requires a certificate in %v, got %v",
+		errorz.A(cdnCertificateRequiredRegion, region), errorz.Prefix(StaticSitePluginName))
+
+	CloudMustCheckDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName, p.cfg.Cloud.Routing)
+}
+
+func (p *staticSiteImpl) cloudAfterDeployEventHook() {
+	CloudMaybeUpsertDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName,
+		p.cloudMetadata.Exports.GetAtt(StaticSiteRefDistribution, StaticSiteAttDomainName))
+
+	p.Build()
+
+	ops := p.cfg.Stage.GetConfig().App.GetOperations()
+	ops.SyncDirToBucket(filepath.Join(p.cfg.DirPath, p.cfg.BuildOutputDirName), p.cloudMetadata.GetBucketName())
+	ops.InvalidateDistribution(p.cloudMetadata.GetDistributionID())
+}
+
+func (p *staticSiteImpl) cloudBeforeDestroyEventHook() {
+	CloudMaybeDeleteDomainRecord(p, p.deps.Certificate.GetConfig().Cloud.GetDNSProvider(), p.cfg.Cloud.DomainName,
+		p.cloudMetadata.Exports.GetAtt(StaticSiteRefDistribution, StaticSiteAttDomainName))
+}
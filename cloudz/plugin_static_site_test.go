@@ -0,0 +1,75 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticSite_GetStageEnv(t *testing.T) {
+	testCases := []struct {
+		name             string
+		envExportCfg     *EnvExportConfig
+		stageEnvironment []string
+		expected         map[string]string
+	}{
+		{
+			name:             "stage values only",
+			envExportCfg:     nil,
+			stageEnvironment: nil,
+			expected: map[string]string{
+				"STAGE_NAME":   "test",
+				"STAGE_TARGET": "local",
+				"STAGE_MODE":   "staging",
+			},
+		},
+		{
+			name: "allow-listed values with prefixes",
+			envExportCfg: &EnvExportConfig{
+				Prefixes: []string{"REACT_APP_"},
+				Values:   map[string]string{"EXTRA": "extra"},
+			},
+			stageEnvironment: []string{"TEST_URL", "TEST_PASSWORD"},
+			expected: map[string]string{
+				"STAGE_NAME":             "test",
+				"STAGE_TARGET":           "local",
+				"STAGE_MODE":             "staging",
+				"TEST_URL":               "postgres://localhost:5432/db",
+				"REACT_APP_STAGE_NAME":   "test",
+				"REACT_APP_STAGE_TARGET": "local",
+				"REACT_APP_STAGE_MODE":   "staging",
+				"REACT_APP_TEST_URL":     "postgres://localhost:5432/db",
+			},
+		},
+		{
+			name: "secrets never included",
+			envExportCfg: &EnvExportConfig{
+				IncludeSecrets: true,
+			},
+			stageEnvironment: []string{"TEST_URL", "TEST_PASSWORD"},
+			expected: map[string]string{
+				"STAGE_NAME":   "test",
+				"STAGE_TARGET": "local",
+				"STAGE_MODE":   "staging",
+				"TEST_URL":     "postgres://localhost:5432/db",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			stage, _ := newTestMetadataStage(testCase.envExportCfg, newTestMetadata())
+
+			p := &staticSiteImpl{
+				cfg: &StaticSiteConfig{
+					Stage:            stage,
+					StageEnvironment: testCase.stageEnvironment,
+				},
+			}
+
+			require.Equal(t, testCase.expected, p.getStageEnv())
+		})
+	}
+}
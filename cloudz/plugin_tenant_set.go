@@ -0,0 +1,242 @@
+package cloudz
+
+import (
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// Tenant set constants.
+const (
+	TenantSetPluginDisplayName = "TenantSet"
+	TenantSetPluginName        = "tenant-set"
+
+	// TenantSetLocalPortStride is the offset between the local ports of consecutive tenants.
+	TenantSetLocalPortStride = 10
+)
+
+var (
+	_ TenantSet = &tenantSetImpl{}
+	_ Plugin    = &tenantSetImpl{}
+)
+
+// TenantSetConfigFunc returns the tenant set config for a given Stage.
+type TenantSetConfigFunc func(Stage, *TenantSetDependencies) *TenantSetConfig
+
+// TenantSetEventHookFunc describes a tenant set event hook.
+type TenantSetEventHookFunc func(TenantSet, Event, string)
+
+// TenantSetFactoryFunc returns the plugins for a given Tenant. The plugins must use the tenant name as instance name
+// (e.g. HasuraConfig.InstanceName, PostgresConfig.InstanceName, RuntimeSecretsConfig.InstanceName), and may depend on
+// the shared plugins in TenantSetDependencies or on each other.
+type TenantSetFactoryFunc func(*Tenant, *TenantSetDependencies) []Plugin
+
+// TenantSetConfig describes the tenant set config.
+type TenantSetConfig struct {
+	Stage     Stage  `validate:"required"`
+	Name      string `validate:"required,resource-name"`
+	EventHook TenantSetEventHookFunc
+}
+
+// MustValidate validates the tenant set config.
+func (c *TenantSetConfig) MustValidate(_ StageTarget) {
+	vz.MustValidateStruct(c)
+}
+
+// TenantSetDependencies describes the tenant set dependencies.
+type TenantSetDependencies struct {
+	LoadBalancer      LoadBalancer `validate:"required"`
+	Network           Network      `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the tenant set dependencies.
+func (d *TenantSetDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// Tenant describes a tenant. The index is the position of the tenant in the list given to NewTenantSet, therefore it
+// is stable as long as tenants are only appended to the list.
+type Tenant struct {
+	Name  string `validate:"required,resource-name"`
+	Index int
+}
+
+// GetLocalPort returns a local port for the tenant, offsetting the given base port by TenantSetLocalPortStride for
+// each preceding tenant. Base ports used by the same tenant plugins should be within TenantSetLocalPortStride.
+func (t *Tenant) GetLocalPort(basePort uint16) uint16 {
+	return basePort + uint16(t.Index*TenantSetLocalPortStride)
+}
+
+// GetListenerRulePriority returns a load balancer listener rule priority for the tenant, offsetting the given base
+// priority by one for each preceding tenant.
+func (t *Tenant) GetListenerRulePriority(basePriority int) int {
+	return basePriority + t.Index
+}
+
+// TenantSet describes a set of tenants, each served by its own copy of a subset of plugins (e.g. a Hasura and a
+// Postgres), sharing the Network and LoadBalancer. Add the plugins returned by GetPlugins to AppConfig.Plugins.
+type TenantSet interface {
+	Plugin
+	GetConfig() *TenantSetConfig
+	GetTenants() []*Tenant
+	GetPlugins() []Plugin
+	GetTenantPlugins(tenantName string) OtherDependencies
+}
+
+type tenantSetImpl struct {
+	cfgFunc       TenantSetConfigFunc
+	deps          *TenantSetDependencies
+	cfg           *TenantSetConfig
+	tenants       []*Tenant
+	tenantPlugins map[string]OtherDependencies
+}
+
+// NewTenantSet initializes a new TenantSet, calling the factory once for each tenant name.
+func NewTenantSet(tenantNames []string, factory TenantSetFactoryFunc, cfgFunc TenantSetConfigFunc, deps *TenantSetDependencies) TenantSet {
+	deps.MustValidate()
+	errorz.Assertf(len(tenantNames) > 0, "no tenants", errorz.Prefix(TenantSetPluginName))
+
+	p := &tenantSetImpl{
+		cfgFunc:       cfgFunc,
+		deps:          deps,
+		tenants:       make([]*Tenant, 0, len(tenantNames)),
+		tenantPlugins: map[string]OtherDependencies{},
+	}
+
+	for i, tenantName := range tenantNames {
+		tenant := &Tenant{
+			Name:  tenantName,
+			Index: i,
+		}
+
+		vz.MustValidateStruct(tenant)
+		_, ok := p.tenantPlugins[tenantName]
+		errorz.Assertf(!ok, "duplicate tenant: %v", errorz.A(tenantName), errorz.Prefix(TenantSetPluginName))
+
+		p.tenants = append(p.tenants, tenant)
+		p.tenantPlugins[tenantName] = factory(tenant, deps)
+	}
+
+	return p
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*tenantSetImpl) GetDisplayName() string {
+	return TenantSetPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (*tenantSetImpl) GetName() string {
+	return TenantSetPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *tenantSetImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *tenantSetImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.LoadBalancer: {},
+		p.deps.Network:      {},
+	}
+
+	for _, plugin := range p.getTenantPlugins() {
+		dependenciesMap[plugin] = struct{}{}
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *tenantSetImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+
+	// Note: the tenant plugins are configured before the tenant set, since it depends on them.
+	for _, tenant := range p.tenants {
+		for _, plugin := range p.tenantPlugins[tenant.Name] {
+			instanceName := plugin.GetInstanceName()
+			errorz.Assertf(instanceName != nil && *instanceName == tenant.Name,
+				"plugin %v of tenant %v must use the tenant name as instance name",
+				errorz.A(plugin.GetName(), tenant.Name), errorz.Prefix(TenantSetPluginName))
+		}
+	}
+}
+
+// GetStage implements the Plugin interface.
+func (p *tenantSetImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(TenantSetPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the TenantSet interface.
+func (p *tenantSetImpl) GetConfig() *TenantSetConfig {
+	return p.cfg
+}
+
+// GetTenants implements the TenantSet interface.
+func (p *tenantSetImpl) GetTenants() []*Tenant {
+	return p.tenants
+}
+
+// GetPlugins implements the TenantSet interface.
+func (p *tenantSetImpl) GetPlugins() []Plugin {
+	return append(p.getTenantPlugins(), p)
+}
+
+// GetTenantPlugins implements the TenantSet interface.
+func (p *tenantSetImpl) GetTenantPlugins(tenantName string) OtherDependencies {
+	plugins, ok := p.tenantPlugins[tenantName]
+	errorz.Assertf(ok, "unknown tenant: %v", errorz.A(tenantName), errorz.Prefix(TenantSetPluginName))
+	return plugins
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *tenantSetImpl) IsDeployed() bool {
+	for _, plugin := range p.getTenantPlugins() {
+		if !plugin.IsDeployed() {
+			return false
+		}
+	}
+	return true
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (*tenantSetImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
+	// nothing to do here
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (*tenantSetImpl) GetCloudTemplate(_ string) *gocf.Template {
+	return nil
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (*tenantSetImpl) UpdateCloudMetadata(_ *awscft.Stack) {
+	// nothing to do here
+}
+
+// EventHook implements the Plugin interface.
+func (p *tenantSetImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *tenantSetImpl) getTenantPlugins() []Plugin {
+	plugins := make([]Plugin, 0)
+	for _, tenant := range p.tenants {
+		plugins = append(plugins, p.tenantPlugins[tenant.Name]...)
+	}
+	return plugins
+}
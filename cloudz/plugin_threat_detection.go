@@ -0,0 +1,344 @@
+package cloudz
+
+import (
+	"fmt"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goevents "github.com/awslabs/goformation/v6/cloudformation/events"
+	goguardduty "github.com/awslabs/goformation/v6/cloudformation/guardduty"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// ThreatDetection constants.
+const (
+	ThreatDetectionPluginDisplayName          = "ThreatDetection"
+	ThreatDetectionPluginName                 = "threat-detection"
+	ThreatDetectionRefDetector                = CloudRef("d")
+	ThreatDetectionRefHub                     = CloudRef("h")
+	ThreatDetectionRefGuardDutyFindingsRule   = CloudRef("gd-r")
+	ThreatDetectionRefSecurityHubFindingsRule = CloudRef("sh-r")
+	ThreatDetectionAttARN                     = CloudAtt("Arn")
+
+	threatDetectionDefaultMinSeverity = "HIGH"
+	threatDetectionTargetID           = "sns"
+)
+
+// Known Security Hub standards.
+const (
+	ThreatDetectionStandardAWSFoundational ThreatDetectionStandard = "aws-foundational-security-best-practices"
+	ThreatDetectionStandardCIS             ThreatDetectionStandard = "cis-aws-foundations-benchmark"
+	ThreatDetectionStandardPCIDSS          ThreatDetectionStandard = "pci-dss"
+	ThreatDetectionStandardNIST            ThreatDetectionStandard = "nist-800-53"
+)
+
+var (
+	_ ThreatDetection = &threatDetectionImpl{}
+	_ Plugin          = &threatDetectionImpl{}
+)
+
+var (
+	threatDetectionStandardVersions = map[ThreatDetectionStandard]string{
+		ThreatDetectionStandardAWSFoundational: "1.0.0",
+		ThreatDetectionStandardCIS:             "1.4.0",
+		ThreatDetectionStandardPCIDSS:          "3.2.1",
+		ThreatDetectionStandardNIST:            "5.0.0",
+	}
+
+	threatDetectionSeverityLabels = map[string][]string{
+		"MEDIUM":   {"MEDIUM", "HIGH", "CRITICAL"},
+		"HIGH":     {"HIGH", "CRITICAL"},
+		"CRITICAL": {"CRITICAL"},
+	}
+
+	threatDetectionGuardDutySeverities = map[string]float64{
+		"MEDIUM":   4,
+		"HIGH":     7,
+		"CRITICAL": 9,
+	}
+)
+
+// ThreatDetectionStandard describes a Security Hub standard.
+type ThreatDetectionStandard string
+
+// getARN returns the standard ARN, as a CloudFormation expression.
+func (s ThreatDetectionStandard) getARN() string {
+	return gocf.Sub(fmt.Sprintf("arn:${AWS::Partition}:securityhub:${AWS::Region}::standards/%v/v/%v", s, threatDetectionStandardVersions[s]))
+}
+
+// getRef returns the standard ref.
+func (s ThreatDetectionStandard) getRef() CloudRef {
+	return CloudRef("s-" + string(s))
+}
+
+// ThreatDetectionConfigFunc returns the threat detection config for a given Stage.
+type ThreatDetectionConfigFunc func(Stage, *ThreatDetectionDependencies) *ThreatDetectionConfig
+
+// ThreatDetectionEventHookFunc describes a threat detection event hook.
+type ThreatDetectionEventHookFunc func(ThreatDetection, Event, string)
+
+// ThreatDetectionConfig describes the threat detection config.
+type ThreatDetectionConfig struct {
+	Stage     Stage  `validate:"required"`
+	Name      string `validate:"required,resource-name"`
+	Cloud     *ThreatDetectionConfigCloud
+	EventHook ThreatDetectionEventHookFunc
+}
+
+// MustValidate validates the threat detection config.
+func (c *ThreatDetectionConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing ThreatDetectionConfig.Cloud", errorz.Prefix(ThreatDetectionPluginName))
+}
+
+// ThreatDetectionConfigCloud describes part of the threat detection config.
+//
+// GuardDuty and Security Hub can only be enabled once per account and region, so at most one stage per account and
+// region can include this plugin, and deploys fail if they have been enabled outside of it. Only the given Standards are
+// enabled (the Security Hub defaults are not). Findings with at least MinSeverity (defaults to "HIGH") are published to
+// the SNS topic, which must allow "events.amazonaws.com" to publish to it. GuardDuty findings are routed directly, and
+// excluded from the routed Security Hub findings to avoid duplicates.
+type ThreatDetectionConfigCloud struct {
+	Standards              []ThreatDetectionStandard `validate:"dive,oneof=aws-foundational-security-best-practices cis-aws-foundations-benchmark pci-dss nist-800-53"`
+	MinSeverity            string                    `validate:"omitempty,oneof=MEDIUM HIGH CRITICAL"`
+	SNSTopicARN            string                    `validate:"required"`
+	IsS3ProtectionEnabled  bool
+	IsEKSProtectionEnabled bool
+}
+
+// ThreatDetectionDependencies describes the threat detection dependencies.
+type ThreatDetectionDependencies struct {
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the threat detection dependencies.
+func (d *ThreatDetectionDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// ThreatDetectionCloudMetadata describes the threat detection cloud metadata.
+type ThreatDetectionCloudMetadata struct {
+	Exports CloudExports
+}
+
+// ThreatDetection describes a GuardDuty detector and a Security Hub with selected standards, which route high-severity
+// findings to an SNS topic. It has no local equivalent.
+type ThreatDetection interface {
+	Plugin
+	GetConfig() *ThreatDetectionConfig
+	GetCloudMetadata(require bool) *ThreatDetectionCloudMetadata
+}
+
+type threatDetectionImpl struct {
+	cfgFunc       ThreatDetectionConfigFunc
+	deps          *ThreatDetectionDependencies
+	cfg           *ThreatDetectionConfig
+	cloudMetadata *ThreatDetectionCloudMetadata
+}
+
+// NewThreatDetection initializes a new ThreatDetection.
+func NewThreatDetection(cfgFunc ThreatDetectionConfigFunc, deps *ThreatDetectionDependencies) ThreatDetection {
+	deps.MustValidate()
+
+	return &threatDetectionImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*threatDetectionImpl) GetDisplayName() string {
+	return ThreatDetectionPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *threatDetectionImpl) GetName() string {
+	return ThreatDetectionPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *threatDetectionImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *threatDetectionImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{}
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *threatDetectionImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *threatDetectionImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(ThreatDetectionPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the ThreatDetection interface.
+func (p *threatDetectionImpl) GetConfig() *ThreatDetectionConfig {
+	return p.cfg
+}
+
+// GetCloudMetadata implements the ThreatDetection interface.
+func (p *threatDetectionImpl) GetCloudMetadata(require bool) *ThreatDetectionCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(ThreatDetectionPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *threatDetectionImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (*threatDetectionImpl) UpdateLocalTemplate(_ *dctypes.Config, _ string) {
+	// intentionally empty
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *threatDetectionImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	tpl.Resources[ThreatDetectionRefDetector.Ref()] = &goguardduty.Detector{
+		DataSources: &goguardduty.Detector_CFNDataSourceConfigurations{
+			Kubernetes: &goguardduty.Detector_CFNKubernetesConfiguration{
+				AuditLogs: &goguardduty.Detector_CFNKubernetesAuditLogsConfiguration{
+					Enable: boolz.Ptr(p.cfg.Cloud.IsEKSProtectionEnabled),
+				},
+			},
+			S3Logs: &goguardduty.Detector_CFNS3LogsConfiguration{
+				Enable: boolz.Ptr(p.cfg.Cloud.IsS3ProtectionEnabled),
+			},
+		},
+		Enable:                     true,
+		FindingPublishingFrequency: stringz.Ptr("FIFTEEN_MINUTES"),
+	}
+	CloudAddExpRef(tpl, p, ThreatDetectionRefDetector)
+
+	// Note: goformation does not support EnableDefaultStandards yet.
+	tpl.Resources[ThreatDetectionRefHub.Ref()] = &gocf.CustomResource{
+		Type: "AWS::SecurityHub::Hub",
+		Properties: map[string]interface{}{
+			"EnableDefaultStandards": false,
+			"Tags": map[string]string{
+				"Name": ThreatDetectionRefHub.Name(p),
+			},
+		},
+	}
+	CloudAddExpRef(tpl, p, ThreatDetectionRefHub)
+
+	for _, standard := range p.cfg.Cloud.Standards {
+		// Note: the Security Hub standard is not supported by goformation yet.
+		tpl.Resources[standard.getRef().Ref()] = &gocf.CustomResource{
+			Type: "AWS::SecurityHub::Standard",
+			Properties: map[string]interface{}{
+				"StandardsArn": standard.getARN(),
+			},
+			AWSCloudFormationDependsOn: []string{
+				ThreatDetectionRefHub.Ref(),
+			},
+		}
+	}
+
+	tpl.Resources[ThreatDetectionRefGuardDutyFindingsRule.Ref()] = &goevents.Rule{
+		Description:  stringz.Ptr(ThreatDetectionRefGuardDutyFindingsRule.Name(p)),
+		EventPattern: p.getGuardDutyEventPattern(),
+		Name:         stringz.Ptr(ThreatDetectionRefGuardDutyFindingsRule.Name(p)),
+		State:        stringz.Ptr("ENABLED"),
+		Targets:      p.getTargets(),
+	}
+	CloudAddExpRef(tpl, p, ThreatDetectionRefGuardDutyFindingsRule)
+	CloudAddExpGetAtt(tpl, p, ThreatDetectionRefGuardDutyFindingsRule, ThreatDetectionAttARN)
+
+	tpl.Resources[ThreatDetectionRefSecurityHubFindingsRule.Ref()] = &goevents.Rule{
+		Description:  stringz.Ptr(ThreatDetectionRefSecurityHubFindingsRule.Name(p)),
+		EventPattern: p.getSecurityHubEventPattern(),
+		Name:         stringz.Ptr(ThreatDetectionRefSecurityHubFindingsRule.Name(p)),
+		State:        stringz.Ptr("ENABLED"),
+		Targets:      p.getTargets(),
+	}
+	CloudAddExpRef(tpl, p, ThreatDetectionRefSecurityHubFindingsRule)
+	CloudAddExpGetAtt(tpl, p, ThreatDetectionRefSecurityHubFindingsRule, ThreatDetectionAttARN)
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *threatDetectionImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	p.cloudMetadata = &ThreatDetectionCloudMetadata{
+		Exports: NewCloudExports(stack),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *threatDetectionImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *threatDetectionImpl) getMinSeverity() string {
+	if p.cfg.Cloud.MinSeverity != "" {
+		return p.cfg.Cloud.MinSeverity
+	}
+	return threatDetectionDefaultMinSeverity
+}
+
+func (p *threatDetectionImpl) getGuardDutyEventPattern() *interface{} {
+	var eventPattern interface{} = map[string]interface{}{
+		"source":      []string{"aws.guardduty"},
+		"detail-type": []string{"GuardDuty Finding"},
+		"detail": map[string]interface{}{
+			"severity": []interface{}{
+				map[string]interface{}{
+					"numeric": []interface{}{">=", threatDetectionGuardDutySeverities[p.getMinSeverity()]},
+				},
+			},
+		},
+	}
+	return &eventPattern
+}
+
+func (p *threatDetectionImpl) getSecurityHubEventPattern() *interface{} {
+	var eventPattern interface{} = map[string]interface{}{
+		"source":      []string{"aws.securityhub"},
+		"detail-type": []string{"Security Hub Findings - Imported"},
+		"detail": map[string]interface{}{
+			"findings": map[string]interface{}{
+				"ProductName": []interface{}{
+					map[string]interface{}{
+						"anything-but": []string{"GuardDuty"},
+					},
+				},
+				"Severity": map[string]interface{}{
+					"Label": threatDetectionSeverityLabels[p.getMinSeverity()],
+				},
+				"Workflow": map[string]interface{}{
+					"Status": []string{"NEW"},
+				},
+			},
+		},
+	}
+	return &eventPattern
+}
+
+func (p *threatDetectionImpl) getTargets() *[]goevents.Rule_Target {
+	return &[]goevents.Rule_Target{
+		{
+			Arn: p.cfg.Cloud.SNSTopicARN,
+			Id:  threatDetectionTargetID,
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package cloudz
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreatDetection_GetEventPatterns(t *testing.T) {
+	testCases := []struct {
+		name                string
+		minSeverity         string
+		expectedGuardDuty   string
+		expectedSecurityHub string
+	}{
+		{
+			name:                "default",
+			minSeverity:         "",
+			expectedGuardDuty:   `{"detail":{"severity":[{"numeric":[">=",7]}]},"detail-type":["GuardDuty Finding"],"source":["aws.guardduty"]}`,
+			expectedSecurityHub: `{"detail":{"findings":{"ProductName":[{"anything-but":["GuardDuty"]}],"Severity":{"Label":["HIGH","CRITICAL"]},"Workflow":{"Status":["NEW"]}}},"detail-type":["Security Hub Findings - Imported"],"source":["aws.securityhub"]}`,
+		},
+		{
+			name:                "medium",
+			minSeverity:         "MEDIUM",
+			expectedGuardDuty:   `{"detail":{"severity":[{"numeric":[">=",4]}]},"detail-type":["GuardDuty Finding"],"source":["aws.guardduty"]}`,
+			expectedSecurityHub: `{"detail":{"findings":{"ProductName":[{"anything-but":["GuardDuty"]}],"Severity":{"Label":["MEDIUM","HIGH","CRITICAL"]},"Workflow":{"Status":["NEW"]}}},"detail-type":["Security Hub Findings - Imported"],"source":["aws.securityhub"]}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			p := &threatDetectionImpl{
+				cfg: &ThreatDetectionConfig{
+					Name: "test",
+					Cloud: &ThreatDetectionConfigCloud{
+						MinSeverity: testCase.minSeverity,
+						SNSTopicARN: "arn:aws:sns:us-east-1:123456789012:alerts",
+					},
+				},
+			}
+
+			buf, err := json.Marshal(p.getGuardDutyEventPattern())
+			require.NoError(t, err)
+			require.JSONEq(t, testCase.expectedGuardDuty, string(buf))
+
+			buf, err = json.Marshal(p.getSecurityHubEventPattern())
+			require.NoError(t, err)
+			require.JSONEq(t, testCase.expectedSecurityHub, string(buf))
+		})
+	}
+}
+
+func TestThreatDetectionStandard(t *testing.T) {
+	require.Equal(t, CloudRef("s-pci-dss"), ThreatDetectionStandardPCIDSS.getRef())
+
+	for _, standard := range []ThreatDetectionStandard{
+		ThreatDetectionStandardAWSFoundational,
+		ThreatDetectionStandardCIS,
+		ThreatDetectionStandardPCIDSS,
+		ThreatDetectionStandardNIST,
+	} {
+		require.NotEmpty(t, threatDetectionStandardVersions[standard])
+	}
+}
@@ -0,0 +1,278 @@
+package cloudz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/opz"
+)
+
+// Tunnel constants.
+const (
+	TunnelPluginDisplayName = "Tunnel"
+	TunnelPluginName        = "tunnel"
+
+	tunnelPublicURLTimeout      = 60 * time.Second
+	tunnelMetricsRequestTimeout = 5 * time.Second
+)
+
+var (
+	_ Tunnel = &tunnelImpl{}
+	_ Plugin = &tunnelImpl{}
+)
+
+// TunnelConfigFunc returns the tunnel config for a given Stage.
+type TunnelConfigFunc func(Stage, *TunnelDependencies) *TunnelConfig
+
+// TunnelEventHookFunc describes a tunnel event hook.
+type TunnelEventHookFunc func(Tunnel, Event, string)
+
+// TunnelWebhookFunc is called with the public URL of the tunnel once it becomes available, e.g. to update the webhook
+// configuration of a third party service (see NewStripeTunnelWebhookFunc). The given context is the one of the operations.
+type TunnelWebhookFunc func(ctx context.Context, publicURL *url.URL)
+
+// TunnelConfig describes the tunnel config.
+//
+// The tunnel is only available in local stages: it runs a Cloudflare quick tunnel, which maps a random public
+// "trycloudflare.com" URL to the Target, so that third party services can deliver webhooks during local development.
+type TunnelConfig struct {
+	Stage     Stage  `validate:"required"`
+	Name      string `validate:"required,resource-name"`
+	Local     *TunnelConfigLocal
+	Webhooks  []TunnelWebhookFunc
+	EventHook TunnelEventHookFunc
+}
+
+// MustValidate validates the tunnel config.
+func (c *TunnelConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing TunnelConfig.Local")
+}
+
+// TunnelConfigLocal describes part of the tunnel config.
+type TunnelConfigLocal struct {
+	MetricsExternalPort uint16 `validate:"required"`
+}
+
+// TunnelDependencies describes the tunnel dependencies.
+// The Target must expose an "InternalURL" local metadata value (e.g. API, Hasura, ContainerService).
+type TunnelDependencies struct {
+	Target            Plugin `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the tunnel dependencies.
+func (d *TunnelDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// TunnelLocalMetadata describes the tunnel local metadata.
+// The PublicURL is only available after the local stage has been created (e.g. from TunnelConfig.EventHook, on
+// LocalAfterCreateEvent).
+type TunnelLocalMetadata struct {
+	ContainerName string
+	TargetURL     *url.URL
+	PublicURL     *url.URL
+}
+
+// Tunnel describes a tunnel.
+type Tunnel interface {
+	Plugin
+	GetConfig() *TunnelConfig
+	GetDependencies() *TunnelDependencies
+	GetLocalMetadata() *TunnelLocalMetadata
+}
+
+type tunnelImpl struct {
+	cfgFunc       TunnelConfigFunc
+	deps          *TunnelDependencies
+	cfg           *TunnelConfig
+	localMetadata *TunnelLocalMetadata
+	httpClient    *http.Client
+}
+
+// NewTunnel initializes a new Tunnel.
+func NewTunnel(cfgFunc TunnelConfigFunc, deps *TunnelDependencies) Tunnel {
+	deps.MustValidate()
+
+	return &tunnelImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+		httpClient: &http.Client{
+			Timeout: tunnelMetricsRequestTimeout,
+		},
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*tunnelImpl) GetDisplayName() string {
+	return TunnelPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *tunnelImpl) GetName() string {
+	return TunnelPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *tunnelImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *tunnelImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Target: {},
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *tunnelImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *tunnelImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(TunnelPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the Tunnel interface.
+func (p *tunnelImpl) GetConfig() *TunnelConfig {
+	return p.cfg
+}
+
+// GetDependencies implements the Tunnel interface.
+func (p *tunnelImpl) GetDependencies() *TunnelDependencies {
+	return p.deps
+}
+
+// GetLocalMetadata implements the Tunnel interface.
+func (p *tunnelImpl) GetLocalMetadata() *TunnelLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(TunnelPluginName))
+	return p.localMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *tunnelImpl) IsDeployed() bool {
+	return false
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *tunnelImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
+	containerName := LocalGetContainerName(p)
+
+	p.localMetadata = &TunnelLocalMetadata{
+		ContainerName: containerName,
+		TargetURL:     LocalGetInternalOrigin(p.deps.Target),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          containerName,
+		ContainerName: containerName,
+		Command: dctypes.ShellCommand{
+			"tunnel",
+			"--no-autoupdate",
+			"--metrics", fmt.Sprintf("0.0.0.0:%v", p.cfg.Local.MetricsExternalPort),
+			"--url", p.localMetadata.TargetURL.String(),
+		},
+		Image:    LocalGetImage(p, "cloudflare/cloudflared:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Cloudflared),
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    uint32(p.cfg.Local.MetricsExternalPort),
+				Published: uint32(p.cfg.Local.MetricsExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *tunnelImpl) GetCloudTemplate(_ string) *gocf.Template {
+	// nothing to do here
+	return nil
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *tunnelImpl) UpdateCloudMetadata(_ *awscft.Stack) {
+	// nothing to do here
+}
+
+// EventHook implements the Plugin interface.
+func (p *tunnelImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case LocalAfterCreateEvent:
+		p.localAfterCreateEventHook()
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *tunnelImpl) localAfterCreateEventHook() {
+	p.localMetadata.PublicURL = p.waitPublicURL()
+
+	for _, webhook := range p.cfg.Webhooks {
+		webhook(p.cfg.Stage.GetConfig().App.GetOperations().GetContext(), p.localMetadata.PublicURL)
+	}
+}
+
+func (p *tunnelImpl) waitPublicURL() *url.URL {
+	deadline := time.Now().Add(tunnelPublicURLTimeout)
+
+	for {
+		if hostname := p.getQuickTunnelHostname(); hostname != "" {
+			return urlz.MustParse("https://" + hostname)
+		}
+
+		errorz.Assertf(time.Now().Before(deadline), "timed out waiting for tunnel public URL", errorz.Prefix(TunnelPluginName))
+		opz.Sleep(p.cfg.Stage.GetConfig().App.GetOperations().GetContext(), time.Second)
+	}
+}
+
+func (p *tunnelImpl) getQuickTunnelHostname() string {
+	req, err := http.NewRequestWithContext(p.cfg.Stage.GetConfig().App.GetOperations().GetContext(),
+		http.MethodGet, fmt.Sprintf("http://localhost:%v/quicktunnel", p.cfg.Local.MetricsExternalPort), nil)
+	errorz.MaybeMustWrap(err)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "" // not ready yet
+	}
+	defer errorz.IgnoreClose(resp.Body)
+
+	buf, err := io.ReadAll(resp.Body)
+	errorz.MaybeMustWrap(err)
+
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	quickTunnel := &struct {
+		Hostname string `json:"hostname"`
+	}{}
+	errorz.MaybeMustWrap(json.Unmarshal(buf, quickTunnel))
+	return quickTunnel.Hostname
+}
@@ -0,0 +1,40 @@
+package cloudz
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+const (
+	stripeAPIBaseURL = "https://api.stripe.com/v1"
+)
+
+// NewStripeTunnelWebhookFunc returns a TunnelWebhookFunc that points an existing Stripe webhook endpoint to the given
+// path on the tunnel public URL. It should be used with a test mode API key.
+func NewStripeTunnelWebhookFunc(apiKey, webhookEndpointID, path string) TunnelWebhookFunc {
+	return func(ctx context.Context, publicURL *url.URL) {
+		webhookURL := publicURL.ResolveReference(&url.URL{Path: path}).String()
+
+		req, err := http.NewRequestWithContext(
+			ctx,
+			http.MethodPost,
+			stripeAPIBaseURL+"/webhook_endpoints/"+url.PathEscape(webhookEndpointID),
+			strings.NewReader(url.Values{"url": {webhookURL}}.Encode()))
+		errorz.MaybeMustWrap(err)
+		req.SetBasicAuth(apiKey, "")
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		resp, err := http.DefaultClient.Do(req)
+		errorz.MaybeMustWrap(err, errorz.M("webhookEndpointID", webhookEndpointID))
+		defer errorz.IgnoreClose(resp.Body)
+
+		buf, err := io.ReadAll(resp.Body)
+		errorz.MaybeMustWrap(err)
+		errorz.Assertf(resp.StatusCode == http.StatusOK, "unexpected Stripe API status code %v: %v", errorz.A(resp.StatusCode, string(buf)), errorz.Prefix(TunnelPluginName))
+	}
+}
@@ -0,0 +1,484 @@
+package cloudz
+
+import (
+	"fmt"
+	"net/url"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goevents "github.com/awslabs/goformation/v6/cloudformation/events"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	gosm "github.com/awslabs/goformation/v6/cloudformation/secretsmanager"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/jsonz"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// Warehouse constants.
+const (
+	WarehousePluginDisplayName  = "Warehouse"
+	WarehousePluginName         = "warehouse"
+	WarehouseRefSecret          = CloudRef("s")
+	WarehouseRefNamespace       = CloudRef("ns")
+	WarehouseRefWorkgroup       = CloudRef("wg")
+	WarehouseRefBucketsRole     = CloudRef("r-b")
+	WarehouseRefJobsRole        = CloudRef("r-j")
+	WarehouseAttARN             = CloudAtt("Arn")
+	WarehouseAttNamespaceARN    = CloudAtt("Namespace.NamespaceArn")
+	WarehouseAttWorkgroupARN    = CloudAtt("Workgroup.WorkgroupArn")
+	WarehouseAttEndpointAddress = CloudAtt("Workgroup.Endpoint.Address")
+	WarehouseAttEndpointPort    = CloudAtt("Workgroup.Endpoint.Port")
+	WarehouseDatabaseName       = "warehouse"
+	WarehouseAdminUsername      = "admin"
+
+	warehousePort                = 5439
+	warehouseLocalPort           = 5432
+	warehouseSecretPasswordKey   = "password"
+	warehouseBaseCapacityDivisor = 8
+	warehouseJobTargetID         = "warehouse"
+)
+
+var (
+	_ Warehouse = &warehouseImpl{}
+	_ Plugin    = &warehouseImpl{}
+)
+
+// WarehouseConfigFunc returns the warehouse config for a given Stage.
+type WarehouseConfigFunc func(Stage, *WarehouseDependencies) *WarehouseConfig
+
+// WarehouseEventHookFunc describes a warehouse event hook.
+type WarehouseEventHookFunc func(Warehouse, Event, string)
+
+// WarehouseConfig describes the warehouse config.
+//
+// Cloud stages provision a Redshift Serverless namespace and workgroup in the private subnets of the Network. The admin
+// password is generated and stored in Secrets Manager (see WarehouseCloudMetadata.SecretARN and
+// Warehouse.GetClientRolePolicy): it never appears in the template or in the metadata. Local stages run a Postgres
+// container with the same database and username instead: Redshift speaks the Postgres wire protocol, so clients work
+// against both, but Redshift-specific SQL (e.g. DISTKEY, SORTKEY, SUPER) is only supported in cloud stages.
+//
+// The WarehouseDependencies.Buckets are readable and writable by the default IAM role of the namespace, so that COPY and
+// UNLOAD statements can use "IAM_ROLE default". Such statements can be run on a schedule (see WarehouseConfigCloud.Jobs).
+type WarehouseConfig struct {
+	Stage     Stage `validate:"required"`
+	Local     *WarehouseConfigLocal
+	Cloud     *WarehouseConfigCloud
+	EventHook WarehouseEventHookFunc
+}
+
+// MustValidate validates the warehouse config.
+func (c *WarehouseConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing WarehouseConfig.Local")
+	errorz.Assertf(stageTarget == Local || c.Cloud != nil, "missing WarehouseConfig.Cloud")
+
+	if stageTarget == Cloud {
+		errorz.Assertf(c.Cloud.BaseCapacity%warehouseBaseCapacityDivisor == 0,
+			"WarehouseConfig.Cloud.BaseCapacity must be a multiple of %v", errorz.A(warehouseBaseCapacityDivisor))
+
+		names := map[string]struct{}{}
+		for _, job := range c.Cloud.Jobs {
+			_, ok := names[job.Name]
+			errorz.Assertf(!ok, "duplicate job: %v", errorz.A(job.Name))
+			names[job.Name] = struct{}{}
+
+			errorz.Assertf(
+				scheduleRateExpressionRegexp.MatchString(job.Expression) || scheduleCronExpressionRegexp.MatchString(job.Expression),
+				"invalid WarehouseConfigCloudJob.Expression: %v", errorz.A(job.Expression))
+		}
+	}
+}
+
+// WarehouseConfigLocal describes part of the warehouse config.
+type WarehouseConfigLocal struct {
+	ExternalPort uint16 `validate:"required"`
+}
+
+// WarehouseConfigCloud describes part of the warehouse config.
+// The BaseCapacity is expressed in Redshift Processing Units (RPUs), in increments of 8.
+type WarehouseConfigCloud struct {
+	BaseCapacity int                        `validate:"required,min=8,max=512"`
+	Jobs         []*WarehouseConfigCloudJob `validate:"dive,required"`
+}
+
+// WarehouseConfigCloudJob describes part of the warehouse config, i.e. a SQL statement run on a schedule through the
+// Redshift Data API, typically a COPY from or an UNLOAD to one of the WarehouseDependencies.Buckets. The Expression uses
+// the EventBridge syntax (see ScheduleConfig). The SQL is a Go text template, executed with WarehouseJobSQLTemplateData,
+// e.g. "COPY events FROM 's3://{{ index .BucketNames "raw" }}/events/' IAM_ROLE default FORMAT AS PARQUET". Jobs don't
+// run in local stages, as the local Postgres cannot read from S3.
+type WarehouseConfigCloudJob struct {
+	Name       string `validate:"required,resource-name"`
+	Expression string `validate:"required"`
+	SQL        string `validate:"required"`
+	Disabled   bool
+}
+
+func (j *WarehouseConfigCloudJob) getRef() CloudRef {
+	return CloudRef("j-" + j.Name)
+}
+
+// WarehouseJobSQLTemplateData describes the template data for WarehouseConfigCloudJob.SQL.
+type WarehouseJobSQLTemplateData struct {
+	BucketNames map[string]string // keyed by Bucket instance name
+}
+
+// WarehouseDependencies describes the warehouse dependencies.
+type WarehouseDependencies struct {
+	Network           Network  `validate:"required"`
+	Buckets           []Bucket `validate:"dive,required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the warehouse dependencies.
+func (d *WarehouseDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// WarehouseLocalMetadata describes the warehouse local metadata.
+type WarehouseLocalMetadata struct {
+	ContainerName string
+	ExternalURL   *url.URL
+	InternalURL   *url.URL
+}
+
+// WarehouseCloudMetadata describes the warehouse cloud metadata.
+// The URL does not include the password, which must be read from the secret at runtime (key "password").
+type WarehouseCloudMetadata struct {
+	Exports   CloudExports
+	SecretARN string
+	URL       *url.URL
+}
+
+// Warehouse describes a warehouse.
+type Warehouse interface {
+	Plugin
+	GetConfig() *WarehouseConfig
+	GetDependencies() *WarehouseDependencies
+	GetLocalMetadata() *WarehouseLocalMetadata
+	GetCloudMetadata(require bool) *WarehouseCloudMetadata
+	GetClientRolePolicy() goiam.Role_Policy
+}
+
+type warehouseImpl struct {
+	cfgFunc       WarehouseConfigFunc
+	deps          *WarehouseDependencies
+	cfg           *WarehouseConfig
+	localMetadata *WarehouseLocalMetadata
+	cloudMetadata *WarehouseCloudMetadata
+}
+
+// NewWarehouse initializes a new Warehouse.
+func NewWarehouse(cfgFunc WarehouseConfigFunc, deps *WarehouseDependencies) Warehouse {
+	deps.MustValidate()
+
+	return &warehouseImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*warehouseImpl) GetDisplayName() string {
+	return WarehousePluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *warehouseImpl) GetName() string {
+	return WarehousePluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *warehouseImpl) GetInstanceName() *string {
+	return nil
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *warehouseImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Network: {},
+	}
+
+	for _, bucket := range p.deps.Buckets {
+		dependenciesMap[bucket] = struct{}{}
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *warehouseImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *warehouseImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(WarehousePluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the Warehouse interface.
+func (p *warehouseImpl) GetConfig() *WarehouseConfig {
+	return p.cfg
+}
+
+// GetDependencies implements the Warehouse interface.
+func (p *warehouseImpl) GetDependencies() *WarehouseDependencies {
+	return p.deps
+}
+
+// GetLocalMetadata implements the Warehouse interface.
+func (p *warehouseImpl) GetLocalMetadata() *WarehouseLocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(WarehousePluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the Warehouse interface.
+func (p *warehouseImpl) GetCloudMetadata(require bool) *WarehouseCloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(WarehousePluginName))
+	return p.cloudMetadata
+}
+
+// GetClientRolePolicy implements the Warehouse interface.
+// It returns a role policy granting read access to the secret holding the admin credentials.
+func (p *warehouseImpl) GetClientRolePolicy() goiam.Role_Policy {
+	return goiam.Role_Policy{
+		PolicyName: WarehousePluginName + "-client",
+		PolicyDocument: NewPolicyDocument(
+			NewPolicyStatement().
+				AddActions("secretsmanager:GetSecretValue").
+				AddResources(p.GetCloudMetadata(true).SecretARN),
+			NewPolicyStatement().
+				AddActions("kms:Decrypt").
+				AddResources(gocf.Sub("arn:aws:kms:${AWS::Region}:${AWS::AccountId}:key/aws/secretsmanager"))),
+	}
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *warehouseImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *warehouseImpl) UpdateLocalTemplate(tpl *dctypes.Config, _ string) {
+	containerName := LocalGetContainerName(p)
+
+	p.localMetadata = &WarehouseLocalMetadata{
+		ContainerName: containerName,
+		ExternalURL: urlz.MustParse(fmt.Sprintf("postgres://%v:%v@localhost:%v/%v?sslmode=disable",
+			WarehouseAdminUsername, LocalPassword, p.cfg.Local.ExternalPort, WarehouseDatabaseName)),
+		InternalURL: urlz.MustParse(fmt.Sprintf("postgres://%v:%v@%v:%v/%v?sslmode=disable",
+			WarehouseAdminUsername, LocalPassword, containerName, warehouseLocalPort, WarehouseDatabaseName)),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          containerName,
+		ContainerName: containerName,
+		Environment: map[string]*string{
+			"POSTGRES_DB":       stringz.Ptr(WarehouseDatabaseName),
+			"POSTGRES_PASSWORD": stringz.Ptr(LocalPassword),
+			"POSTGRES_USER":     stringz.Ptr(WarehouseAdminUsername),
+		},
+		Image:    LocalGetImage(p, fmt.Sprintf("postgres:%v-alpine", p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().Postgres)),
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    warehouseLocalPort,
+				Published: uint32(p.cfg.Local.ExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *warehouseImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+	network := p.deps.Network.GetCloudMetadata(true)
+
+	tpl.Resources[WarehouseRefSecret.Ref()] = &gosm.Secret{
+		GenerateSecretString: &gosm.Secret_GenerateSecretString{
+			ExcludePunctuation:      boolz.Ptr(true),
+			GenerateStringKey:       stringz.Ptr(warehouseSecretPasswordKey),
+			PasswordLength:          intz.Ptr(32),
+			RequireEachIncludedType: boolz.Ptr(true),
+			SecretStringTemplate: stringz.Ptr(jsonz.MustMarshalString(map[string]string{
+				"username": WarehouseAdminUsername,
+			})),
+		},
+		Name: stringz.Ptr(WarehouseRefSecret.Name(p)),
+		Tags: CloudGetDefaultTags(WarehouseRefSecret.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, WarehouseRefSecret)
+
+	// Note: Redshift Serverless is not supported by goformation yet.
+	namespace := &gocf.CustomResource{
+		Type: "AWS::RedshiftServerless::Namespace",
+		Properties: map[string]interface{}{
+			"AdminUserPassword": gocf.Sub(fmt.Sprintf("{{resolve:secretsmanager:${%v}:SecretString:%v}}",
+				WarehouseRefSecret.Ref(), warehouseSecretPasswordKey)),
+			"AdminUsername": WarehouseAdminUsername,
+			"DbName":        WarehouseDatabaseName,
+			"NamespaceName": WarehouseRefNamespace.Name(p),
+			"Tags":          CloudGetDefaultTags(WarehouseRefNamespace.Name(p)),
+		},
+	}
+
+	if len(p.deps.Buckets) > 0 {
+		p.addBucketsRole(tpl)
+		namespace.Properties["DefaultIamRoleArn"] = gocf.GetAtt(WarehouseRefBucketsRole.Ref(), WarehouseAttARN.Ref())
+		namespace.Properties["IamRoles"] = []string{gocf.GetAtt(WarehouseRefBucketsRole.Ref(), WarehouseAttARN.Ref())}
+	}
+
+	tpl.Resources[WarehouseRefNamespace.Ref()] = namespace
+	CloudAddExpRef(tpl, p, WarehouseRefNamespace)
+	CloudAddExpGetAtt(tpl, p, WarehouseRefNamespace, WarehouseAttNamespaceARN)
+
+	tpl.Resources[WarehouseRefWorkgroup.Ref()] = &gocf.CustomResource{
+		Type: "AWS::RedshiftServerless::Workgroup",
+		Properties: map[string]interface{}{
+			"BaseCapacity":       p.cfg.Cloud.BaseCapacity,
+			"NamespaceName":      gocf.Ref(WarehouseRefNamespace.Ref()),
+			"Port":               warehousePort,
+			"PubliclyAccessible": false,
+			"SecurityGroupIds": []string{
+				network.Exports.GetRef(NetworkRefSecurityGroup),
+			},
+			"SubnetIds": []string{
+				network.Exports.GetRef(NetworkRefSubnetPrivateA),
+				network.Exports.GetRef(NetworkRefSubnetPrivateB),
+			},
+			"Tags":          CloudGetDefaultTags(WarehouseRefWorkgroup.Name(p)),
+			"WorkgroupName": WarehouseRefWorkgroup.Name(p),
+		},
+	}
+	CloudAddExpRef(tpl, p, WarehouseRefWorkgroup)
+	CloudAddExpGetAtt(tpl, p, WarehouseRefWorkgroup, WarehouseAttEndpointAddress)
+	CloudAddExpGetAtt(tpl, p, WarehouseRefWorkgroup, WarehouseAttEndpointPort)
+	CloudAddExpGetAtt(tpl, p, WarehouseRefWorkgroup, WarehouseAttWorkgroupARN)
+
+	if len(p.cfg.Cloud.Jobs) > 0 {
+		p.addJobs(tpl)
+	}
+
+	return tpl
+}
+
+// addBucketsRole adds the default IAM role of the namespace, which grants access to the buckets.
+func (p *warehouseImpl) addBucketsRole(tpl *gocf.Template) {
+	resources := make([]string, 0, 2*len(p.deps.Buckets))
+	for _, bucket := range p.deps.Buckets {
+		resources = append(resources, bucket.GetCloudMetadata(true).BucketARN(), bucket.GetCloudMetadata(true).BucketARN()+"/*")
+	}
+
+	tpl.Resources[WarehouseRefBucketsRole.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("redshift.amazonaws.com"),
+		Policies: &[]goiam.Role_Policy{
+			{
+				PolicyName: WarehouseRefBucketsRole.Name(p),
+				PolicyDocument: NewPolicyDocument(
+					NewPolicyStatement().
+						AddActions(
+							"s3:GetBucketLocation",
+							"s3:GetObject",
+							"s3:ListBucket",
+							"s3:PutObject").
+						AddResources(resources...)),
+			},
+		},
+		RoleName: stringz.Ptr(WarehouseRefBucketsRole.Name(p)),
+		Tags:     CloudGetDefaultTags(WarehouseRefBucketsRole.Name(p)),
+	}
+	CloudAddExpGetAtt(tpl, p, WarehouseRefBucketsRole, WarehouseAttARN)
+}
+
+// addJobs adds a rule per job, which runs its SQL through the Redshift Data API, authenticating with the admin secret.
+func (p *warehouseImpl) addJobs(tpl *gocf.Template) {
+	workgroupARN := gocf.GetAtt(WarehouseRefWorkgroup.Ref(), WarehouseAttWorkgroupARN.Ref())
+
+	tpl.Resources[WarehouseRefJobsRole.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("events.amazonaws.com"),
+		Policies: &[]goiam.Role_Policy{
+			{
+				PolicyName: WarehouseRefJobsRole.Name(p),
+				PolicyDocument: NewPolicyDocument(
+					NewPolicyStatement().
+						AddActions(
+							"redshift-data:ExecuteStatement",
+							"redshift-serverless:GetCredentials").
+						AddResources(workgroupARN),
+					NewPolicyStatement().
+						AddActions("secretsmanager:GetSecretValue").
+						AddResources(gocf.Ref(WarehouseRefSecret.Ref()))),
+			},
+		},
+		RoleName: stringz.Ptr(WarehouseRefJobsRole.Name(p)),
+		Tags:     CloudGetDefaultTags(WarehouseRefJobsRole.Name(p)),
+	}
+
+	data := &WarehouseJobSQLTemplateData{
+		BucketNames: map[string]string{},
+	}
+
+	for _, bucket := range p.deps.Buckets {
+		data.BucketNames[bucket.GetConfig().Name] = bucket.GetCloudMetadata(true).GetName()
+	}
+
+	for _, job := range p.cfg.Cloud.Jobs {
+		tpl.Resources[job.getRef().Ref()] = &goevents.Rule{
+			Name:               stringz.Ptr(job.getRef().Name(p)),
+			ScheduleExpression: stringz.Ptr(job.Expression),
+			State: stringz.Ptr(func() string {
+				if job.Disabled {
+					return "DISABLED"
+				}
+				return "ENABLED"
+			}()),
+			Targets: &[]goevents.Rule_Target{
+				{
+					Arn: workgroupARN,
+					Id:  warehouseJobTargetID,
+					RedshiftDataParameters: &goevents.Rule_RedshiftDataParameters{
+						Database:         WarehouseDatabaseName,
+						SecretManagerArn: stringz.Ptr(gocf.Ref(WarehouseRefSecret.Ref())),
+						Sql:              string(templatez.MustParseAndExecuteText(job.SQL, data)),
+						StatementName:    stringz.Ptr(job.getRef().Name(p)),
+					},
+					RoleArn: stringz.Ptr(gocf.GetAtt(WarehouseRefJobsRole.Ref(), WarehouseAttARN.Ref())),
+				},
+			},
+		}
+	}
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *warehouseImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+
+	p.cloudMetadata = &WarehouseCloudMetadata{
+		Exports:   exports,
+		SecretARN: exports.GetRef(WarehouseRefSecret),
+		URL: urlz.MustParse(fmt.Sprintf("postgres://%v@%v:%v/%v?sslmode=require",
+			WarehouseAdminUsername,
+			exports.GetAtt(WarehouseRefWorkgroup, WarehouseAttEndpointAddress),
+			exports.GetAtt(WarehouseRefWorkgroup, WarehouseAttEndpointPort),
+			WarehouseDatabaseName)),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *warehouseImpl) EventHook(event Event, buildDirPath string) {
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
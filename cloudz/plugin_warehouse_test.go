@@ -0,0 +1,36 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarehouseConfig_MustValidate(t *testing.T) {
+	newConfig := func(jobs ...*WarehouseConfigCloudJob) *WarehouseConfig {
+		return &WarehouseConfig{
+			Stage: &cloudStageImpl{cfg: &CloudStageConfig{Name: "staging"}},
+			Cloud: &WarehouseConfigCloud{
+				BaseCapacity: 8,
+				Jobs:         jobs,
+			},
+		}
+	}
+
+	job := func(name, expression string) *WarehouseConfigCloudJob {
+		return &WarehouseConfigCloudJob{
+			Name:       name,
+			Expression: expression,
+			SQL:        `COPY events FROM 's3://{{ index .BucketNames "raw" }}/events/' IAM_ROLE default FORMAT AS PARQUET`,
+		}
+	}
+
+	require.NotPanics(t, func() { newConfig().MustValidate(Cloud) })
+	require.NotPanics(t, func() { newConfig(job("load", "rate(1 hour)"), job("unload", "cron(0 3 * * ? *)")).MustValidate(Cloud) })
+	require.Panics(t, func() { newConfig(job("load", "0 3 * * *")).MustValidate(Cloud) })
+	require.Panics(t, func() { newConfig(job("load", "rate(1 hour)"), job("load", "rate(2 hours)")).MustValidate(Cloud) })
+
+	cfg := newConfig()
+	cfg.Cloud.BaseCapacity = 12
+	require.Panics(t, func() { cfg.MustValidate(Cloud) })
+}
@@ -0,0 +1,485 @@
+package cloudz
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goapigwv2 "github.com/awslabs/goformation/v6/cloudformation/apigatewayv2"
+	godynamodb "github.com/awslabs/goformation/v6/cloudformation/dynamodb"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	golambda "github.com/awslabs/goformation/v6/cloudformation/lambda"
+	dctypes "github.com/docker/cli/cli/compose/types"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/jsonz"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+)
+
+// WebSocketAPI constants.
+const (
+	WebSocketAPIPluginDisplayName    = "WebSocketAPI"
+	WebSocketAPIPluginName           = "websocket-api"
+	WebSocketAPIRefAPI               = CloudRef("api")
+	WebSocketAPIRefStage             = CloudRef("stg")
+	WebSocketAPIRefIntegration       = CloudRef("intg")
+	WebSocketAPIRefPermission        = CloudRef("perm")
+	WebSocketAPIRefConnectionsTable  = CloudRef("ct")
+	WebSocketAPIRefFunctionPolicy    = CloudRef("fp")
+	WebSocketAPIAttAPIEndpoint       = CloudAtt("ApiEndpoint")
+	WebSocketAPIAttARN               = CloudAtt("Arn")
+	WebSocketAPIConnectRouteKey      = "$connect"
+	WebSocketAPIDisconnectRouteKey   = "$disconnect"
+	WebSocketAPIDefaultRouteKey      = "$default"
+	WebSocketAPIStageName            = "live"
+	WebSocketAPIConnectionsHashKey   = "connectionId"
+	WebSocketAPIConnectionsTTLKey    = "expiresAt"
+	WebSocketAPIDefaultRouteSelector = "$request.body.action"
+
+	webSocketAPISimulatorPort = 8080
+	webSocketAPIDynamoDBPort  = 8000
+)
+
+var (
+	_ WebSocketAPI = &webSocketAPIImpl{}
+	_ Plugin       = &webSocketAPIImpl{}
+)
+
+// WebSocketAPIConfigFunc returns the websocket api config for a given Stage.
+type WebSocketAPIConfigFunc func(Stage, *WebSocketAPIDependencies) *WebSocketAPIConfig
+
+// WebSocketAPIEventHookFunc describes a websocket api event hook.
+type WebSocketAPIEventHookFunc func(WebSocketAPI, Event, string)
+
+// WebSocketAPIConfig describes the websocket api config.
+//
+// Messages are routed to the Function dependency by the value selected by RouteSelectionExpression (which defaults to
+// WebSocketAPIDefaultRouteSelector), among the RouteKeys and the "$connect", "$disconnect", and "$default" routes, which
+// are always added. Responses returned by the function for messages are sent back to the client.
+//
+// Since the Function is deployed before the api, it cannot reference the api metadata in its config: it should read the
+// connections table name and the management URL at runtime (e.g. from SSM parameters, see CloudStageSSMExportConfig).
+// The api grants the function access to both. Connections are stored by the function, keyed by
+// WebSocketAPIConnectionsHashKey, and expire at the (optional) Unix timestamp in WebSocketAPIConnectionsTTLKey.
+type WebSocketAPIConfig struct {
+	Stage                    Stage    `validate:"required"`
+	Name                     string   `validate:"required,resource-name"`
+	RouteKeys                []string `validate:"dive,required,excludesall=$"`
+	RouteSelectionExpression string   `validate:"omitempty,startswith=$request.body."`
+	Local                    *WebSocketAPIConfigLocal
+	Cloud                    *WebSocketAPIConfigCloud
+	EventHook                WebSocketAPIEventHookFunc
+}
+
+// MustValidate validates the websocket api config.
+func (c *WebSocketAPIConfig) MustValidate(stageTarget StageTarget) {
+	vz.MustValidateStruct(c)
+	errorz.Assertf(stageTarget == Cloud || c.Local != nil, "missing WebSocketAPIConfig.Local", errorz.Prefix(WebSocketAPIPluginName))
+}
+
+// WebSocketAPIConfigLocal describes part of the websocket api config.
+// Locally, the api is served by a simulator, and the connections table by DynamoDB Local.
+type WebSocketAPIConfigLocal struct {
+	ExternalPort         uint16 `validate:"required"`
+	DynamoDBExternalPort uint16 `validate:"required"`
+}
+
+// WebSocketAPIConfigCloud describes part of the websocket api config.
+type WebSocketAPIConfigCloud struct {
+	Throttling *APIThrottlingLimits
+}
+
+// WebSocketAPIDependencies describes the websocket api dependencies.
+type WebSocketAPIDependencies struct {
+	Function          Function `validate:"required"`
+	OtherDependencies OtherDependencies
+}
+
+// MustValidate validates the websocket api dependencies.
+func (d *WebSocketAPIDependencies) MustValidate() {
+	vz.MustValidateStruct(d)
+}
+
+// WebSocketAPILocalMetadata describes the websocket api local metadata.
+type WebSocketAPILocalMetadata struct {
+	ExternalURL                         *url.URL
+	InternalURL                         *url.URL
+	ExternalManagementURL               *url.URL
+	InternalManagementURL               *url.URL
+	ConnectionsTableName                string
+	ConnectionsTableExternalEndpointURL *url.URL
+	ConnectionsTableInternalEndpointURL *url.URL
+}
+
+// WebSocketAPICloudMetadata describes the websocket api cloud metadata.
+type WebSocketAPICloudMetadata struct {
+	Exports              CloudExports
+	URL                  *url.URL
+	ManagementURL        *url.URL
+	ConnectionsTableName string
+}
+
+// WebSocketAPI describes a websocket api.
+type WebSocketAPI interface {
+	Plugin
+	GetConfig() *WebSocketAPIConfig
+	GetLocalMetadata() *WebSocketAPILocalMetadata
+	GetCloudMetadata(require bool) *WebSocketAPICloudMetadata
+}
+
+type webSocketAPIImpl struct {
+	cfgFunc       WebSocketAPIConfigFunc
+	deps          *WebSocketAPIDependencies
+	cfg           *WebSocketAPIConfig
+	localMetadata *WebSocketAPILocalMetadata
+	cloudMetadata *WebSocketAPICloudMetadata
+}
+
+// NewWebSocketAPI initializes a new WebSocketAPI.
+func NewWebSocketAPI(cfgFunc WebSocketAPIConfigFunc, deps *WebSocketAPIDependencies) WebSocketAPI {
+	deps.MustValidate()
+
+	return &webSocketAPIImpl{
+		cfgFunc: cfgFunc,
+		deps:    deps,
+	}
+}
+
+// GetDisplayName implements the Plugin interface.
+func (*webSocketAPIImpl) GetDisplayName() string {
+	return WebSocketAPIPluginDisplayName
+}
+
+// GetName implements the Plugin interface.
+func (p *webSocketAPIImpl) GetName() string {
+	return WebSocketAPIPluginName
+}
+
+// GetInstanceName implements the Plugin interface.
+func (p *webSocketAPIImpl) GetInstanceName() *string {
+	return stringz.Ptr(p.cfg.Name)
+}
+
+// GetDependenciesMap implements the Plugin interface.
+func (p *webSocketAPIImpl) GetDependenciesMap() map[Plugin]struct{} {
+	dependenciesMap := map[Plugin]struct{}{
+		p.deps.Function: {},
+	}
+
+	for _, otherDependency := range p.deps.OtherDependencies {
+		dependenciesMap[otherDependency] = struct{}{}
+	}
+
+	return dependenciesMap
+}
+
+// Configure implements the Plugin interface.
+func (p *webSocketAPIImpl) Configure(stage Stage) {
+	p.cfg = p.cfgFunc(stage, p.deps)
+	p.cfg.MustValidate(stage.GetTarget())
+}
+
+// GetStage implements the Plugin interface.
+func (p *webSocketAPIImpl) GetStage() Stage {
+	errorz.Assertf(p.cfg != nil, "plugin not configured", errorz.Prefix(WebSocketAPIPluginName))
+	return p.cfg.Stage
+}
+
+// GetConfig implements the WebSocketAPI interface.
+func (p *webSocketAPIImpl) GetConfig() *WebSocketAPIConfig {
+	return p.cfg
+}
+
+// GetLocalMetadata implements the WebSocketAPI interface.
+func (p *webSocketAPIImpl) GetLocalMetadata() *WebSocketAPILocalMetadata {
+	errorz.Assertf(p.localMetadata != nil, "local not deployed", errorz.Prefix(WebSocketAPIPluginName))
+	return p.localMetadata
+}
+
+// GetCloudMetadata implements the WebSocketAPI interface.
+func (p *webSocketAPIImpl) GetCloudMetadata(require bool) *WebSocketAPICloudMetadata {
+	errorz.Assertf(!require || p.cloudMetadata != nil, "cloud not deployed", errorz.Prefix(WebSocketAPIPluginName))
+	return p.cloudMetadata
+}
+
+// IsDeployed implements the Plugin interface.
+func (p *webSocketAPIImpl) IsDeployed() bool {
+	return p.cloudMetadata != nil
+}
+
+// UpdateLocalTemplate implements the Plugin interface.
+func (p *webSocketAPIImpl) UpdateLocalTemplate(tpl *dctypes.Config, buildDirPath string) {
+	containerName := LocalGetContainerName(p)
+	dynamoDBContainerName := LocalGetContainerName(p, "dynamodb")
+
+	p.localMetadata = &WebSocketAPILocalMetadata{
+		ExternalURL:                         urlz.MustParse(fmt.Sprintf("ws://localhost:%v", p.cfg.Local.ExternalPort)),
+		InternalURL:                         urlz.MustParse(fmt.Sprintf("ws://%v:%v", containerName, webSocketAPISimulatorPort)),
+		ExternalManagementURL:               urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.ExternalPort)),
+		InternalManagementURL:               urlz.MustParse(fmt.Sprintf("http://%v:%v", containerName, webSocketAPISimulatorPort)),
+		ConnectionsTableName:                WebSocketAPIRefConnectionsTable.Name(p),
+		ConnectionsTableExternalEndpointURL: urlz.MustParse(fmt.Sprintf("http://localhost:%v", p.cfg.Local.DynamoDBExternalPort)),
+		ConnectionsTableInternalEndpointURL: urlz.MustParse(fmt.Sprintf("http://%v:%v", dynamoDBContainerName, webSocketAPIDynamoDBPort)),
+	}
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name:          dynamoDBContainerName,
+		ContainerName: dynamoDBContainerName,
+		Command: dctypes.ShellCommand{
+			"-jar", "DynamoDBLocal.jar",
+			"-inMemory",
+			"-sharedDb",
+		},
+		Image:    LocalGetImage(p, "amazon/dynamodb-local:"+p.cfg.Stage.GetConfig().App.GetConfig().GetVersions().DynamoDB),
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    webSocketAPIDynamoDBPort,
+				Published: uint32(p.cfg.Local.DynamoDBExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+	})
+
+	tpl.Services = append(tpl.Services, dctypes.ServiceConfig{
+		Name: containerName,
+		Build: dctypes.BuildConfig{
+			Context: buildDirPath,
+		},
+		ContainerName: containerName,
+		DependsOn: []string{
+			dynamoDBContainerName,
+		},
+		Image:    containerName,
+		Networks: p.cfg.Stage.AsLocalStage().GetServiceNetworkConfig(),
+		Ports: []dctypes.ServicePortConfig{
+			{
+				Target:    webSocketAPISimulatorPort,
+				Published: uint32(p.cfg.Local.ExternalPort),
+			},
+		},
+		Restart: "unless-stopped",
+	})
+}
+
+// GetCloudTemplate implements the Plugin interface.
+func (p *webSocketAPIImpl) GetCloudTemplate(_ string) *gocf.Template {
+	tpl := gocf.NewTemplate()
+	function := p.deps.Function.GetCloudMetadata(true)
+
+	tpl.Resources[WebSocketAPIRefAPI.Ref()] = &goapigwv2.Api{
+		Name:                     stringz.Ptr(WebSocketAPIRefAPI.Name(p)),
+		ProtocolType:             stringz.Ptr("WEBSOCKET"),
+		RouteSelectionExpression: stringz.Ptr(p.getRouteSelectionExpression()),
+	}
+	CloudAddExpRef(tpl, p, WebSocketAPIRefAPI)
+	CloudAddExpGetAtt(tpl, p, WebSocketAPIRefAPI, WebSocketAPIAttAPIEndpoint)
+
+	tpl.Resources[WebSocketAPIRefConnectionsTable.Ref()] = &godynamodb.Table{
+		AttributeDefinitions: &[]godynamodb.Table_AttributeDefinition{
+			{
+				AttributeName: WebSocketAPIConnectionsHashKey,
+				AttributeType: "S",
+			},
+		},
+		BillingMode: stringz.Ptr("PAY_PER_REQUEST"),
+		KeySchema: []godynamodb.Table_KeySchema{
+			{
+				AttributeName: WebSocketAPIConnectionsHashKey,
+				KeyType:       "HASH",
+			},
+		},
+		TableName: stringz.Ptr(WebSocketAPIRefConnectionsTable.Name(p)),
+		Tags:      CloudGetDefaultTags(WebSocketAPIRefConnectionsTable.Name(p)),
+		TimeToLiveSpecification: &godynamodb.Table_TimeToLiveSpecification{
+			AttributeName: WebSocketAPIConnectionsTTLKey,
+			Enabled:       true,
+		},
+	}
+	CloudAddExpRef(tpl, p, WebSocketAPIRefConnectionsTable)
+	CloudAddExpGetAtt(tpl, p, WebSocketAPIRefConnectionsTable, WebSocketAPIAttARN)
+
+	tpl.Resources[WebSocketAPIRefFunctionPolicy.Ref()] = &goiam.Policy{
+		PolicyName: WebSocketAPIRefFunctionPolicy.Name(p),
+		PolicyDocument: NewPolicyDocument(
+			NewPolicyStatement().
+				AddActions(
+					"dynamodb:DeleteItem",
+					"dynamodb:GetItem",
+					"dynamodb:PutItem",
+					"dynamodb:Query",
+					"dynamodb:Scan",
+					"dynamodb:UpdateItem").
+				AddResources(gocf.GetAtt(WebSocketAPIRefConnectionsTable.Ref(), WebSocketAPIAttARN.Ref())),
+			NewPolicyStatement().
+				AddActions("execute-api:ManageConnections").
+				AddResources(p.getExecuteAPIARN("/*"))),
+		Roles: &[]string{
+			function.RoleRef(),
+		},
+	}
+
+	tpl.Resources[WebSocketAPIRefPermission.Ref()] = &golambda.Permission{
+		Action:       "lambda:InvokeFunction",
+		FunctionName: function.GetARN(),
+		Principal:    "apigateway.amazonaws.com",
+		SourceArn:    stringz.Ptr(p.getExecuteAPIARN("/*/*")),
+	}
+
+	tpl.Resources[WebSocketAPIRefIntegration.Ref()] = &goapigwv2.Integration{
+		ApiId:           gocf.Ref(WebSocketAPIRefAPI.Ref()),
+		IntegrationType: "AWS_PROXY",
+		IntegrationUri: stringz.Ptr(gocf.Join("", []string{
+			gocf.Sub("arn:${AWS::Partition}:apigateway:${AWS::Region}:lambda:path/2015-03-31/functions/"),
+			function.GetARN(),
+			"/invocations",
+		})),
+		TimeoutInMillis: intz.Ptr(29000),
+	}
+	CloudAddExpRef(tpl, p, WebSocketAPIRefIntegration)
+
+	routeRefs := make([]string, 0)
+
+	for _, routeKey := range p.getRouteKeys() {
+		routeRef := getWebSocketAPIRouteRef("r", routeKey)
+		routeRefs = append(routeRefs, routeRef.Ref())
+
+		route := &goapigwv2.Route{
+			ApiId:             gocf.Ref(WebSocketAPIRefAPI.Ref()),
+			AuthorizationType: stringz.Ptr("NONE"),
+			RouteKey:          routeKey,
+			Target: stringz.Ptr(gocf.Join("", []string{
+				"integrations/",
+				gocf.Ref(WebSocketAPIRefIntegration.Ref()),
+			})),
+		}
+		tpl.Resources[routeRef.Ref()] = route
+
+		if routeKey == WebSocketAPIConnectRouteKey || routeKey == WebSocketAPIDisconnectRouteKey {
+			continue
+		}
+
+		// Responses returned by the function are only sent back to the client if the route has a route response.
+		route.RouteResponseSelectionExpression = stringz.Ptr("$default")
+
+		tpl.Resources[getWebSocketAPIRouteRef("rr", routeKey).Ref()] = &goapigwv2.RouteResponse{
+			ApiId:            gocf.Ref(WebSocketAPIRefAPI.Ref()),
+			RouteId:          gocf.Ref(routeRef.Ref()),
+			RouteResponseKey: "$default",
+		}
+	}
+
+	tpl.Resources[WebSocketAPIRefStage.Ref()] = &goapigwv2.Stage{
+		ApiId:                      gocf.Ref(WebSocketAPIRefAPI.Ref()),
+		AutoDeploy:                 boolz.Ptr(true),
+		DefaultRouteSettings:       p.getDefaultRouteSettings(),
+		StageName:                  WebSocketAPIStageName,
+		AWSCloudFormationDependsOn: routeRefs,
+	}
+	CloudAddExpRef(tpl, p, WebSocketAPIRefStage)
+
+	return tpl
+}
+
+// UpdateCloudMetadata implements the Plugin interface.
+func (p *webSocketAPIImpl) UpdateCloudMetadata(stack *awscft.Stack) {
+	exports := NewCloudExports(stack)
+	apiEndpoint := exports.GetAtt(WebSocketAPIRefAPI, WebSocketAPIAttAPIEndpoint) // e.g. "wss://{id}.execute-api.{region}.amazonaws.com"
+
+	p.cloudMetadata = &WebSocketAPICloudMetadata{
+		Exports:              exports,
+		URL:                  urlz.MustParse(fmt.Sprintf("%v/%v", apiEndpoint, WebSocketAPIStageName)),
+		ManagementURL:        urlz.MustParse(fmt.Sprintf("https://%v/%v", strings.TrimPrefix(apiEndpoint, "wss://"), WebSocketAPIStageName)),
+		ConnectionsTableName: WebSocketAPIRefConnectionsTable.Name(p),
+	}
+}
+
+// EventHook implements the Plugin interface.
+func (p *webSocketAPIImpl) EventHook(event Event, buildDirPath string) {
+	switch event {
+	case LocalBeforeCreateEvent:
+		p.localBeforeCreateEventHook(buildDirPath)
+	}
+
+	if p.cfg.EventHook != nil {
+		p.cfg.EventHook(p, event, buildDirPath)
+	}
+}
+
+func (p *webSocketAPIImpl) localBeforeCreateEventHook(buildDirPath string) {
+	filez.MustPrepareDir(buildDirPath, 0777)
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "Dockerfile"), 0777, 0666,
+		templatez.MustParseAndExecuteText(
+			assets.WebSocketAPIDockerfileTemplateAsset,
+			assets.WebSocketAPIDockerfileTemplateData{
+				BaseImage:  LocalGetImage(p, fmt.Sprintf("golang:%v-alpine", strings.TrimPrefix(runtime.Version(), "go"))),
+				ListenAddr: fmt.Sprintf(":%v", webSocketAPISimulatorPort),
+			}))
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "main.go"), 0777, 0666,
+		assets.WebSocketAPIMainGoAsset)
+
+	filez.MustWriteFile(
+		filepath.Join(buildDirPath, "config.json"), 0777, 0666,
+		jsonz.MustMarshalIndentDefault(map[string]interface{}{
+			"functionURL":              p.deps.Function.GetLocalMetadata().InternalURL.String(),
+			"routeSelectionExpression": p.getRouteSelectionExpression(),
+			"routeKeys":                p.getRouteKeys(),
+			"stage":                    WebSocketAPIStageName,
+			"domainName":               p.localMetadata.InternalURL.Host,
+			"connectionsTable": map[string]interface{}{
+				"endpointURL": p.localMetadata.ConnectionsTableInternalEndpointURL.String(),
+				"tableName":   p.localMetadata.ConnectionsTableName,
+				"hashKey":     WebSocketAPIConnectionsHashKey,
+			},
+		}))
+}
+
+func (p *webSocketAPIImpl) getRouteSelectionExpression() string {
+	if p.cfg.RouteSelectionExpression != "" {
+		return p.cfg.RouteSelectionExpression
+	}
+	return WebSocketAPIDefaultRouteSelector
+}
+
+func (p *webSocketAPIImpl) getRouteKeys() []string {
+	return append([]string{
+		WebSocketAPIConnectRouteKey,
+		WebSocketAPIDisconnectRouteKey,
+		WebSocketAPIDefaultRouteKey,
+	}, p.cfg.RouteKeys...)
+}
+
+func (p *webSocketAPIImpl) getDefaultRouteSettings() *goapigwv2.Stage_RouteSettings {
+	if p.cfg.Cloud == nil || p.cfg.Cloud.Throttling == nil {
+		return nil
+	}
+	return newAPIRouteSettings(p.cfg.Cloud.Throttling)
+}
+
+func (p *webSocketAPIImpl) getExecuteAPIARN(suffix string) string {
+	return gocf.Join("", []string{
+		gocf.Sub("arn:${AWS::Partition}:execute-api:${AWS::Region}:${AWS::AccountId}:"),
+		gocf.Ref(WebSocketAPIRefAPI.Ref()),
+		suffix,
+	})
+}
+
+func getWebSocketAPIRouteRef(prefix, routeKey string) CloudRef {
+	return CloudRef(fmt.Sprintf("%v-%x", prefix, sha1.Sum([]byte(routeKey))))
+}
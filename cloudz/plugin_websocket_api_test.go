@@ -0,0 +1,34 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSocketAPI_GetRouteKeys(t *testing.T) {
+	p := &webSocketAPIImpl{
+		cfg: &WebSocketAPIConfig{
+			Name:      "test",
+			RouteKeys: []string{"sendMessage"},
+		},
+	}
+
+	require.Equal(t, []string{"$connect", "$disconnect", "$default", "sendMessage"}, p.getRouteKeys())
+	require.Equal(t, WebSocketAPIDefaultRouteSelector, p.getRouteSelectionExpression())
+
+	p.cfg.RouteSelectionExpression = "$request.body.type"
+	require.Equal(t, "$request.body.type", p.getRouteSelectionExpression())
+}
+
+func TestGetWebSocketAPIRouteRef(t *testing.T) {
+	refs := map[string]struct{}{}
+
+	for _, routeKey := range []string{"$connect", "$disconnect", "$default", "sendMessage"} {
+		refs[getWebSocketAPIRouteRef("r", routeKey).Ref()] = struct{}{}
+		refs[getWebSocketAPIRouteRef("rr", routeKey).Ref()] = struct{}{}
+	}
+
+	require.Len(t, refs, 8)
+	require.Equal(t, getWebSocketAPIRouteRef("r", "$default"), getWebSocketAPIRouteRef("r", "$default"))
+}
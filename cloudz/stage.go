@@ -66,6 +66,7 @@ const (
 type StageConfig struct {
 	App          App `validate:"required"`
 	CustomConfig interface{}
+	EnvExport    *EnvExportConfig
 }
 
 // Stage describes a Stage.
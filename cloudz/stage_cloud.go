@@ -1,11 +1,19 @@
 package cloudz
 
 import (
+	"context"
+	"encoding/json"
 	"path"
+	"path/filepath"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/ibrt/golang-bites/filez"
 	"github.com/ibrt/golang-errors/errorz"
 	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/opz"
 )
 
 // CloudStageConfig describes the Stage cloud config.
@@ -14,11 +22,35 @@ type CloudStageConfig struct {
 	Name         string    `validate:"required,resource-name"`
 	Version      string    `validate:"required"`
 	Mode         StageMode `validate:"required,oneof=prod staging"`
+	SSMExport    *CloudStageSSMExportConfig
+
+	SkipDNSDelegationCheck bool                           // if true, Preflight does not resolve the delegation of hosted zones via public DNS
+	CostAllocationTags     map[string]string              // additional tags applied to all stacks (see CloudGetStackTags)
+	DeployMetrics          *CloudStageDeployMetricsConfig // if set, deploy phase durations are published to CloudWatch
+	Retention              *CloudStageRetentionConfig     // if set, overrides the default retention policies of resources
+}
+
+// CloudStageSSMExportConfig describes the metadata values to export to SSM parameters after deploy.
+// Values are specified as "<metadata key>.<value name>", e.g. "postgres-proxy.URL" (see GetMetadataValues).
+// The prefix defaults to "/<app name>/<stage name>". Secret values are stored as SecureString parameters.
+type CloudStageSSMExportConfig struct {
+	Prefix string   `validate:"omitempty,startswith=/"`
+	Values []string `validate:"required,min=1,dive,required"`
 }
 
 // MustValidate validates the cloud stage config.
 func (c *CloudStageConfig) MustValidate() {
 	vz.MustValidateStruct(c)
+
+	for k := range c.CostAllocationTags {
+		_, isReserved := cloudReservedStackTags[k]
+		errorz.Assertf(k != "" && !isReserved && !strings.HasPrefix(k, "aws:"),
+			"invalid CloudStageConfig.CostAllocationTags key: %v", errorz.A(k))
+	}
+
+	if c.Retention != nil {
+		c.Retention.MustValidate()
+	}
 }
 
 // CloudStage describes a cloud Stage.
@@ -26,12 +58,33 @@ type CloudStage interface {
 	Stage
 	GetCloudConfig() *CloudStageConfig
 	GetArtifactsKeyPrefix(p Plugin, additionalParts ...string) string
+	GetSSMParameterName(p Plugin, valueName string) string
 	IsDeployed() bool
+	Preflight()
+	Synth()
+	ExportIaC(outDirPath string, format IaCFormat)
+	Plan() *CloudPlan
+	TryPlan(ctx context.Context) (*CloudPlan, error)
 	Deploy()
+	TryDeploy(ctx context.Context) error
+	Destroy(cfg *CloudDestroyConfig) []*CloudRetainedResource
+	TryDestroy(ctx context.Context, cfg *CloudDestroyConfig) ([]*CloudRetainedResource, error)
+	GetProvenance() *opz.Provenance
+	AddReleaseArtifact(artifact *CloudReleaseArtifact)
+	VerifyRelease(version string) *CloudReleaseVerification
+	ExportEnv(outFilePath string, format EnvFormat)
+}
+
+// cloudStackWatcher is implemented by plugins that watch their stack while it is being deployed. The returned function
+// stops watching, it is always called, even if the deploy fails.
+type cloudStackWatcher interface {
+	watchCloudStack() func()
 }
 
 type cloudStageImpl struct {
-	cfg *CloudStageConfig
+	cfg             *CloudStageConfig
+	provenance      *opz.Provenance
+	releaseManifest *CloudReleaseManifest
 }
 
 // NewCloudStage initializes a new CloudStage.
@@ -46,6 +99,10 @@ func NewCloudStage(cfg *CloudStageConfig) CloudStage {
 		for _, plugin := range pluginGroup {
 			plugin.Configure(stage)
 
+			if cfg.App.GetOperations().IsOffline() {
+				continue
+			}
+
 			if stack := cfg.App.GetOperations().DescribeStack(CloudGetStackName(plugin)); stack != nil {
 				plugin.UpdateCloudMetadata(stack)
 			}
@@ -118,32 +175,257 @@ func (s *cloudStageImpl) GetArtifactsKeyPrefix(p Plugin, additionalParts ...stri
 	return path.Join(append([]string{strings.Join(parts, "-")}, additionalParts...)...)
 }
 
-// Deploy implements the CloudStage interface.
-func (s *cloudStageImpl) Deploy() {
+// GetSSMParameterName returns the name of the SSM parameter for the given plugin metadata value.
+// It doesn't go through the NamingStrategy: the hierarchy can be customized with CloudStageSSMExportConfig.Prefix.
+func (s *cloudStageImpl) GetSSMParameterName(p Plugin, valueName string) string {
+	prefix := "/" + s.cfg.App.GetConfig().Name + "/" + s.cfg.Name
+	if s.cfg.SSMExport != nil && s.cfg.SSMExport.Prefix != "" {
+		prefix = strings.TrimSuffix(s.cfg.SSMExport.Prefix, "/")
+	}
+
+	return prefix + "/" + GetMetadataKey(p) + "/" + valueName
+}
+
+// Preflight implements the CloudStage interface.
+func (s *cloudStageImpl) Preflight() {
+	s.mustCheckSSMExport()
+	cloudMustCheckQuotas(s)
+	cloudMustCheckRegion(s)
+
 	for _, pluginGroup := range s.cfg.App.GetSortedPlugins() {
 		for _, plugin := range pluginGroup {
-			plugin.Configure(s) // reconfigure plugins as fresher cloud metadata becomes available
+			plugin.EventHook(CloudPreflightEvent, s.cfg.App.GetConfig().GetBuildDirPathForPlugin(plugin))
+		}
+	}
+}
+
+// Synth implements the CloudStage interface.
+// It renders the CloudFormation template of each plugin to "template.json" in its build dir, without deploying it. In
+// offline mode, the cloud metadata of each plugin is derived from its template, using placeholders for values only known
+// after deploy, so that the templates of the plugins depending on it can be rendered too. The export names of all the
+// templates are validated, and checked for duplicates across the app and (if online) with the exports of other stacks.
+func (s *cloudStageImpl) Synth() {
+	s.synthTemplates()
+}
+
+// cloudSynthTemplate describes a rendered plugin template.
+type cloudSynthTemplate struct {
+	plugin       Plugin
+	stackName    string
+	templateBody []byte
+}
+
+func (s *cloudStageImpl) synthTemplates() []*cloudSynthTemplate {
+	templates := make([]*cloudSynthTemplate, 0)
+	exportNames := cloudExportNames{}
+
+	for _, pluginGroup := range s.cfg.App.GetSortedPlugins() {
+		for _, plugin := range pluginGroup {
+			plugin.Configure(s)
 			buildDirPath := s.cfg.App.GetConfig().GetBuildDirPathForPlugin(plugin)
 
 			tpl := plugin.GetCloudTemplate(buildDirPath)
 			if tpl == nil {
 				continue
 			}
+			cloudApplyRetentionPolicies(plugin, tpl)
+			exportNames.mustAdd(plugin, tpl)
 
 			buf, err := tpl.JSON()
 			errorz.MaybeMustWrap(err)
+			filez.MustWriteFile(filepath.Join(buildDirPath, "template.json"), 0777, 0666, buf)
+
+			if s.cfg.App.GetOperations().IsOffline() {
+				plugin.UpdateCloudMetadata(newCloudOfflineStack(CloudGetStackName(plugin), buf))
+			}
+
+			templates = append(templates, &cloudSynthTemplate{
+				plugin:       plugin,
+				stackName:    CloudGetStackName(plugin),
+				templateBody: buf,
+			})
+		}
+	}
+
+	exportNames.mustCheckNotClaimed(s)
+	return templates
+}
+
+// Deploy implements the CloudStage interface.
+// The duration of each phase of the deploy is recorded per plugin, and printed at the end (see CloudDeployReport). If the
+// app context (see AppConfig.Context) is canceled or times out, the deploy fails, at the latest before the next stack.
+func (s *cloudStageImpl) Deploy() {
+	report := newCloudDeployReport()
+	completed := false
+
+	defer func() {
+		report.finish(completed)
+		report.store(s)
+	}()
+
+	report.measure(CloudDeployPhasePreflight, s.Preflight)
+	s.beginRelease()
+
+	for _, pluginGroup := range s.cfg.App.GetSortedPlugins() {
+		for _, plugin := range pluginGroup {
+			errorz.MaybeMustWrap(s.cfg.App.GetOperations().GetContext().Err()) // stop between stacks if canceled
+			pluginReport := report.addPlugin(plugin)
+			buildDirPath := s.cfg.App.GetConfig().GetBuildDirPathForPlugin(plugin)
+			var buf []byte
+
+			pluginReport.measure(CloudDeployPhaseTemplate, func() {
+				plugin.Configure(s) // reconfigure plugins as fresher cloud metadata becomes available
+
+				if tpl := plugin.GetCloudTemplate(buildDirPath); tpl != nil {
+					cloudApplyRetentionPolicies(plugin, tpl)
+					var err error
+					buf, err = tpl.JSON()
+					errorz.MaybeMustWrap(err)
+				}
+			})
+
+			if buf == nil {
+				continue
+			}
+
+			tagsMap := CloudGetStackTags(plugin)
 
-			plugin.EventHook(CloudBeforeDeployEvent, buildDirPath)
+			if plugin.IsDeployed() {
+				pluginReport.measure(CloudDeployPhaseApproval, func() {
+					cloudMustApproveStackUpdate(s, plugin, string(buf), tagsMap)
+				})
+			}
+
+			pluginReport.measure(CloudDeployPhaseBeforeDeploy, func() {
+				plugin.EventHook(CloudBeforeDeployEvent, buildDirPath)
+			})
+
+			pluginReport.measure(CloudDeployPhaseStack, func() {
+				plugin.UpdateCloudMetadata(s.upsertPluginStack(plugin, string(buf), tagsMap))
+			})
+
+			pluginReport.measure(CloudDeployPhaseAfterDeploy, func() {
+				plugin.EventHook(CloudAfterDeployEvent, buildDirPath)
+			})
+		}
+	}
+
+	if s.cfg.SSMExport != nil {
+		report.measure(CloudDeployPhaseSSMExport, s.exportSSMParameters)
+	}
+
+	s.storeRelease()
+	completed = true
+	report.finish(completed)
 
-			plugin.UpdateCloudMetadata(
-				s.cfg.App.GetOperations().UpsertStack(
-					CloudGetStackName(plugin),
-					string(buf),
-					map[string]string{
-						"Stage": s.GetName(),
-					}))
+	if s.cfg.DeployMetrics != nil {
+		s.cfg.App.GetOperations().PutMetricData(s.cfg.DeployMetrics.Namespace,
+			report.getMetricData(s.cfg.App.GetConfig().Name, s.cfg.Name))
+	}
+}
 
-			plugin.EventHook(CloudAfterDeployEvent, buildDirPath)
+// TryDeploy implements the CloudStage interface.
+// It is like Deploy, but bounded by the given context instead of the app context, and returns an error instead of
+// panicking (see opz.Try). Note that a stack that failed to be created cannot be updated, and must be destroyed before
+// retrying.
+func (s *cloudStageImpl) TryDeploy(ctx context.Context) error {
+	return tryWithContext(s.cfg.App, ctx, s.Deploy)
+}
+
+// upsertPluginStack upserts the stack of the given plugin. If the plugin implements cloudStackWatcher, it watches the
+// stack until the upsert completes or fails.
+func (s *cloudStageImpl) upsertPluginStack(plugin Plugin, templateBody string, tagsMap map[string]string) *awscft.Stack {
+	if watcher, ok := plugin.(cloudStackWatcher); ok {
+		defer watcher.watchCloudStack()()
+	}
+
+	return s.cfg.App.GetOperations().UpsertStack(CloudGetStackName(plugin), templateBody, tagsMap)
+}
+
+// ExportEnv implements the CloudStage interface.
+func (s *cloudStageImpl) ExportEnv(outFilePath string, format EnvFormat) {
+	exportStageEnv(s, outFilePath, format)
+}
+
+func (s *cloudStageImpl) mustCheckSSMExport() {
+	if s.cfg.SSMExport == nil {
+		return
+	}
+
+	for _, key := range s.cfg.SSMExport.Values {
+		s.mustFindSSMExportPlugin(key)
+	}
+}
+
+func (s *cloudStageImpl) mustFindSSMExportPlugin(key string) (Plugin, string) {
+	for _, pluginGroup := range s.cfg.App.GetSortedPlugins() {
+		for _, plugin := range pluginGroup {
+			if valueName := strings.TrimPrefix(key, GetMetadataKey(plugin)+"."); valueName != key && hasMetadataValue(plugin, valueName) {
+				return plugin, valueName
+			}
 		}
 	}
+
+	panic(errorz.Errorf("no such metadata value: %v", errorz.A(key)))
+}
+
+func (s *cloudStageImpl) exportSSMParameters() {
+	for _, key := range s.cfg.SSMExport.Values {
+		plugin, valueName := s.mustFindSSMExportPlugin(key)
+
+		for _, value := range GetMetadataValues(plugin) {
+			if value.Name == valueName {
+				errorz.Assertf(value.Value != "", "empty metadata value: %v", errorz.A(key))
+				s.cfg.App.GetOperations().PutParameter(s.GetSSMParameterName(plugin, value.Name), value.Value, value.IsSecret)
+			}
+		}
+	}
+}
+
+// newCloudOfflineStack builds a stack from a rendered template, for use as cloud metadata in offline mode. Literal
+// output values are kept as-is, while values computed by CloudFormation are replaced by placeholders.
+func newCloudOfflineStack(stackName string, templateBody []byte) *awscft.Stack {
+	tpl := &struct {
+		Outputs map[string]struct {
+			Value  interface{}
+			Export *struct {
+				Name interface{}
+			}
+		}
+	}{}
+	errorz.MaybeMustWrap(json.Unmarshal(templateBody, tpl))
+
+	stack := &awscft.Stack{
+		StackName: aws.String(stackName),
+	}
+
+	for key, output := range tpl.Outputs {
+		value, ok := output.Value.(string)
+		if !ok {
+			value = getCloudOfflineOutputValue(key)
+		}
+
+		stackOutput := awscft.Output{
+			OutputKey:   aws.String(key),
+			OutputValue: aws.String(value),
+		}
+
+		if output.Export != nil {
+			if exportName, ok := output.Export.Name.(string); ok {
+				stackOutput.ExportName = aws.String(exportName)
+			}
+		}
+
+		stack.Outputs = append(stack.Outputs, stackOutput)
+	}
+
+	return stack
+}
+
+// getCloudOfflineOutputValue returns a placeholder value for a computed output when offline.
+func getCloudOfflineOutputValue(key string) string {
+	if strings.HasSuffix(key, "Port") {
+		return "0"
+	}
+	return "offline-" + strings.ToLower(key)
 }
@@ -0,0 +1,125 @@
+package cloudz
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+var (
+	// cloudStatefulResourceTypes are the resource types whose replacement or removal loses data.
+	cloudStatefulResourceTypes = map[string]struct{}{
+		"AWS::Cognito::UserPool":         {},
+		"AWS::ECR::Repository":           {},
+		"AWS::EFS::FileSystem":           {},
+		"AWS::MSK::Cluster":              {},
+		"AWS::MSK::ServerlessCluster":    {},
+		"AWS::OpenSearchService::Domain": {},
+		"AWS::RDS::DBCluster":            {},
+		"AWS::RDS::DBInstance":           {},
+		"AWS::S3::Bucket":                {},
+		"AWS::SecretsManager::Secret":    {},
+	}
+)
+
+// ApprovalPolicy describes how destructive actions (e.g. a deploy replacing a database) are approved.
+//
+// If AssumeYes is set (e.g. from a "--yes" command line flag), all destructive actions are approved. Otherwise, if stdin
+// is a terminal the user is prompted, and if it is not destructive actions are denied in production stages and approved
+// in other stages. If Hook is set, it replaces this logic.
+type ApprovalPolicy struct {
+	AssumeYes bool
+	Hook      ApprovalHookFunc
+}
+
+// ApprovalHookFunc decides whether a destructive action can be performed.
+type ApprovalHookFunc func(Stage, *DestructiveAction) bool
+
+// DestructiveAction describes a destructive action about to be performed by a plugin.
+type DestructiveAction struct {
+	Plugin      Plugin
+	Description string
+	Details     []string
+}
+
+// String implements the fmt.Stringer interface.
+func (a *DestructiveAction) String() string {
+	return fmt.Sprintf("[%v] %v", GetMetadataKey(a.Plugin), a.Description)
+}
+
+// MustApproveDestructiveAction asks for approval of a destructive action according to the ApprovalPolicy of the app,
+// panics if denied.
+func MustApproveDestructiveAction(stage Stage, action *DestructiveAction) {
+	policy := stage.GetConfig().App.GetConfig().ApprovalPolicy
+	if policy == nil {
+		policy = &ApprovalPolicy{}
+	}
+
+	var approved bool
+
+	switch {
+	case policy.Hook != nil:
+		approved = policy.Hook(stage, action)
+	case policy.AssumeYes:
+		approved = true
+	case isTerminal(os.Stdin):
+		approved = promptDestructiveAction(action)
+	default:
+		approved = !stage.GetMode().IsProduction()
+	}
+
+	errorz.Assertf(approved, "destructive action not approved: %v", errorz.A(action.String()))
+}
+
+// cloudMustApproveStackUpdate previews the update of the stack of the given plugin, and asks for approval if it would
+// replace or remove stateful resources.
+func cloudMustApproveStackUpdate(s CloudStage, p Plugin, templateBody string, tagsMap map[string]string) {
+	details := make([]string, 0)
+
+	for _, change := range s.GetConfig().App.GetOperations().PreviewStackUpdate(CloudGetStackName(p), templateBody, tagsMap) {
+		if _, ok := cloudStatefulResourceTypes[aws.ToString(change.ResourceType)]; !ok {
+			continue
+		}
+
+		switch {
+		case change.Action == awscft.ChangeActionRemove:
+			details = append(details, fmt.Sprintf("remove %v (%v)", aws.ToString(change.LogicalResourceId), aws.ToString(change.ResourceType)))
+		case change.Replacement == awscft.ReplacementTrue || change.Replacement == awscft.ReplacementConditional:
+			details = append(details, fmt.Sprintf("replace %v (%v, replacement: %v)", aws.ToString(change.LogicalResourceId), aws.ToString(change.ResourceType), change.Replacement))
+		}
+	}
+
+	if len(details) > 0 {
+		MustApproveDestructiveAction(s, &DestructiveAction{
+			Plugin:      p,
+			Description: "stack update replaces or removes stateful resources",
+			Details:     details,
+		})
+	}
+}
+
+func promptDestructiveAction(action *DestructiveAction) bool {
+	fmt.Println(action.String())
+	for _, detail := range action.Details {
+		fmt.Println("  -", detail)
+	}
+	fmt.Print("Proceed? [y/N] ")
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,136 @@
+package cloudz
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// CloudDestroyConfig describes how a cloud Stage is destroyed (see CloudStage.Destroy).
+type CloudDestroyConfig struct {
+	EmptyBuckets          bool // if true, the buckets owned by plugins (e.g. Bucket, StaticSite) are emptied before deleting their stacks
+	RetainFailedResources bool // if true, resources that fail to be deleted are retained, so that their stacks can be deleted anyway
+}
+
+// CloudRetainedResource describes a resource left behind by CloudStage.Destroy, e.g. because of its deletion policy.
+type CloudRetainedResource struct {
+	MetadataKey        string
+	ResourceType       string
+	LogicalResourceID  string
+	PhysicalResourceID string
+}
+
+// String implements the fmt.Stringer interface.
+func (r *CloudRetainedResource) String() string {
+	return fmt.Sprintf("%v: %v %v (%v)", r.MetadataKey, r.ResourceType, r.PhysicalResourceID, r.LogicalResourceID)
+}
+
+// cloudBucketOwner is implemented by plugins whose stack includes S3 buckets, which can only be deleted when empty.
+type cloudBucketOwner interface {
+	getCloudBucketNames() []string
+}
+
+// Destroy implements the CloudStage interface.
+// It deletes the stacks of the deployed plugins in reverse dependency order, so that no stack is deleted while another
+// one still imports its exports. The deletion of each stack is approved up-front according to the ApprovalPolicy of the
+// app (i.e. it is denied by default in production stages). The SSM parameters exported by the stage (see
+// CloudStageSSMExportConfig) are deleted with the stack of their plugin. Resources left behind (e.g. because of their
+// deletion policy) are returned.
+func (s *cloudStageImpl) Destroy(cfg *CloudDestroyConfig) []*CloudRetainedResource {
+	plugins := getCloudDestroyPlugins(s.cfg.App.GetSortedPlugins())
+	parameterNames := s.getSSMExportParameterNames()
+
+	for _, plugin := range plugins {
+		details := []string{fmt.Sprintf("delete stack %v", CloudGetStackName(plugin))}
+
+		for _, parameterName := range parameterNames[GetMetadataKey(plugin)] {
+			details = append(details, fmt.Sprintf("delete SSM parameter %v", parameterName))
+		}
+
+		if owner, ok := plugin.(cloudBucketOwner); ok && cfg.EmptyBuckets {
+			for _, bucketName := range owner.getCloudBucketNames() {
+				details = append(details, fmt.Sprintf("empty bucket %v", bucketName))
+			}
+		}
+
+		MustApproveDestructiveAction(s, &DestructiveAction{
+			Plugin:      plugin,
+			Description: "stage destroy deletes all resources",
+			Details:     details,
+		})
+	}
+
+	retained := make([]*CloudRetainedResource, 0)
+
+	for _, plugin := range plugins {
+		errorz.MaybeMustWrap(s.cfg.App.GetOperations().GetContext().Err()) // stop between stacks if canceled
+		buildDirPath := s.cfg.App.GetConfig().GetBuildDirPathForPlugin(plugin)
+		plugin.EventHook(CloudBeforeDestroyEvent, buildDirPath)
+
+		if owner, ok := plugin.(cloudBucketOwner); ok && cfg.EmptyBuckets {
+			for _, bucketName := range owner.getCloudBucketNames() {
+				s.cfg.App.GetOperations().EmptyBucket(bucketName)
+			}
+		}
+
+		for _, event := range s.cfg.App.GetOperations().DeleteStack(CloudGetStackName(plugin), cfg.RetainFailedResources) {
+			retained = append(retained, &CloudRetainedResource{
+				MetadataKey:        GetMetadataKey(plugin),
+				ResourceType:       aws.ToString(event.ResourceType),
+				LogicalResourceID:  aws.ToString(event.LogicalResourceId),
+				PhysicalResourceID: aws.ToString(event.PhysicalResourceId),
+			})
+		}
+
+		for _, parameterName := range parameterNames[GetMetadataKey(plugin)] {
+			s.cfg.App.GetOperations().DeleteParameter(parameterName)
+		}
+
+		plugin.EventHook(CloudAfterDestroyEvent, buildDirPath)
+	}
+
+	return retained
+}
+
+// TryDestroy implements the CloudStage interface.
+// It is like Destroy, but bounded by the given context instead of the app context, and returns an error instead of
+// panicking (see opz.Try).
+func (s *cloudStageImpl) TryDestroy(ctx context.Context, cfg *CloudDestroyConfig) (retained []*CloudRetainedResource, err error) {
+	err = tryWithContext(s.cfg.App, ctx, func() {
+		retained = s.Destroy(cfg)
+	})
+	return retained, err
+}
+
+// getSSMExportParameterNames returns the names of the SSM parameters exported by the stage, by plugin metadata key.
+func (s *cloudStageImpl) getSSMExportParameterNames() map[string][]string {
+	parameterNames := make(map[string][]string)
+
+	if s.cfg.SSMExport == nil {
+		return parameterNames
+	}
+
+	for _, key := range s.cfg.SSMExport.Values {
+		plugin, valueName := s.mustFindSSMExportPlugin(key)
+		parameterNames[GetMetadataKey(plugin)] = append(parameterNames[GetMetadataKey(plugin)], s.GetSSMParameterName(plugin, valueName))
+	}
+
+	return parameterNames
+}
+
+// getCloudDestroyPlugins returns the deployed plugins, in reverse dependency order.
+func getCloudDestroyPlugins(sortedPlugins [][]Plugin) []Plugin {
+	plugins := make([]Plugin, 0)
+
+	for i := len(sortedPlugins) - 1; i >= 0; i-- {
+		for _, plugin := range sortedPlugins[i] {
+			if plugin.IsDeployed() {
+				plugins = append(plugins, plugin)
+			}
+		}
+	}
+
+	return plugins
+}
@@ -0,0 +1,59 @@
+package cloudz
+
+import (
+	"regexp"
+
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+var (
+	cloudExportNameRegexp = regexp.MustCompile(`^[A-Za-z0-9:-]+$`)
+)
+
+// cloudExportNames tracks the export names of the rendered templates of a stage, by stack name.
+type cloudExportNames map[string]string
+
+// mustAdd validates the export names of the given template and adds them, checking that they are not already exported by
+// another stack of the stage (e.g. "a-b" instance of plugin "p" vs. "b" instance of plugin "p-a").
+func (n cloudExportNames) mustAdd(p Plugin, tpl *gocf.Template) {
+	stackName := CloudGetStackName(p)
+
+	for outputKey, output := range tpl.Outputs {
+		if output.Export == nil {
+			continue
+		}
+
+		exportName := output.Export.Name
+		errorz.Assertf(len(exportName) <= cloudExportNameMaxLength && cloudExportNameRegexp.MatchString(exportName),
+			"invalid export name for output %v: %v", errorz.A(outputKey, exportName), errorz.Prefix(p.GetName()))
+
+		otherStackName, ok := n[exportName]
+		errorz.Assertf(!ok, "duplicate export name %v: already exported by stack %v",
+			errorz.A(exportName, otherStackName), errorz.Prefix(p.GetName()))
+		n[exportName] = stackName
+	}
+}
+
+// mustCheckNotClaimed checks that the export names are not already exported by stacks not managed by the given stage
+// (e.g. by another app in the same account and region), which would make the deploy of their stacks fail.
+func (n cloudExportNames) mustCheckNotClaimed(s Stage) {
+	ops := s.GetConfig().App.GetOperations()
+	if ops.IsOffline() {
+		return
+	}
+
+	for _, export := range ops.ListStackExports() {
+		if export.Name == nil || export.ExportingStackId == nil {
+			continue
+		}
+
+		stackName, ok := n[*export.Name]
+		if !ok || getCloudStackNameFromID(*export.ExportingStackId) == stackName {
+			continue
+		}
+
+		panic(errorz.Errorf("export name %v of stack %v is already exported by stack %v",
+			errorz.A(*export.Name, stackName, *export.ExportingStackId)))
+	}
+}
@@ -0,0 +1,171 @@
+package cloudz
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/iancoleman/strcase"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/jsonz"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-errors/errorz"
+
+	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+)
+
+// IaCFormat describes an infrastructure-as-code export format.
+type IaCFormat string
+
+// MustValidate validates the IaCFormat.
+func (f IaCFormat) MustValidate() {
+	errorz.Assertf(f == TerraformIaC || f == CDKIaC, "invalid IaC format")
+}
+
+// String implements the fmt.Stringer interface.
+func (f IaCFormat) String() string {
+	return string(f)
+}
+
+// Known IaC formats.
+const (
+	TerraformIaC IaCFormat = "terraform"
+	CDKIaC       IaCFormat = "cdk"
+)
+
+// ExportIaC implements the CloudStage interface.
+//
+// It renders the CloudFormation template of each plugin (see Synth) to "templates/<stack-name>.json" in outDirPath, and
+// generates a project that deploys each of them as a stack with the same name, in dependency order:
+//
+//   - TerraformIaC generates "main.tf.json", in the Terraform JSON syntax also used by cdktf, with an
+//     "aws_cloudformation_stack" resource per plugin, and import blocks for the stacks already deployed.
+//   - CDKIaC generates "app.ts" and "cdk.json", with a stack per plugin including its template through CfnInclude,
+//     which preserves logical IDs so that the stacks already deployed are updated in place.
+//
+// This allows organizations standardized on those tools to manage the infrastructure described by the plugins.
+func (s *cloudStageImpl) ExportIaC(outDirPath string, format IaCFormat) {
+	format.MustValidate()
+
+	isDeployed := map[Plugin]bool{}
+	for _, pluginGroup := range s.cfg.App.GetSortedPlugins() {
+		for _, plugin := range pluginGroup {
+			isDeployed[plugin] = plugin.IsDeployed()
+		}
+	}
+
+	templates := s.synthTemplates()
+	templatePlugins := map[Plugin]struct{}{}
+
+	for _, t := range templates {
+		templatePlugins[t.plugin] = struct{}{}
+		filez.MustWriteFile(filepath.Join(outDirPath, getIaCTemplateFilePath(t)), 0777, 0666, t.templateBody)
+	}
+
+	getDependencies := func(p Plugin) []Plugin {
+		dependencies := make([]Plugin, 0)
+		for dependency := range p.GetDependenciesMap() {
+			if _, ok := templatePlugins[dependency]; ok {
+				dependencies = append(dependencies, dependency)
+			}
+		}
+		sort.SliceStable(dependencies, func(i, j int) bool {
+			return GetMetadataKey(dependencies[i]) < GetMetadataKey(dependencies[j])
+		})
+		return dependencies
+	}
+
+	switch format {
+	case TerraformIaC:
+		resources := map[string]interface{}{}
+		imports := make([]interface{}, 0)
+
+		for _, t := range templates {
+			key := strcase.ToSnake(GetMetadataKey(t.plugin))
+			dependsOn := make([]string, 0)
+
+			for _, dependency := range getDependencies(t.plugin) {
+				dependsOn = append(dependsOn, "aws_cloudformation_stack."+strcase.ToSnake(GetMetadataKey(dependency)))
+			}
+
+			resources[key] = map[string]interface{}{
+				"name":          t.stackName,
+				"template_body": fmt.Sprintf(`${file("${path.module}/%v")}`, getIaCTemplateFilePath(t)),
+				"capabilities":  []string{"CAPABILITY_IAM", "CAPABILITY_NAMED_IAM"},
+				"tags":          CloudGetStackTags(t.plugin),
+				"depends_on":    dependsOn,
+			}
+
+			if isDeployed[t.plugin] && !s.cfg.App.GetOperations().IsOffline() {
+				imports = append(imports, map[string]string{
+					"to": "aws_cloudformation_stack." + key,
+					"id": t.stackName,
+				})
+			}
+		}
+
+		tf := map[string]interface{}{
+			"//": "Code generated by golang-cloud. DO NOT EDIT.",
+			"terraform": map[string]interface{}{
+				"required_providers": map[string]interface{}{
+					"aws": map[string]string{
+						"source": "hashicorp/aws",
+					},
+				},
+			},
+			"provider": map[string]interface{}{
+				"aws": []interface{}{
+					map[string]string{
+						"region": s.cfg.App.GetOperations().GetRegion(),
+					},
+				},
+			},
+			"resource": map[string]interface{}{
+				"aws_cloudformation_stack": resources,
+			},
+		}
+
+		if len(imports) > 0 {
+			tf["import"] = imports
+		}
+
+		filez.MustWriteFile(filepath.Join(outDirPath, "main.tf.json"), 0777, 0666, jsonz.MustMarshalIndentDefault(tf))
+	case CDKIaC:
+		data := &assets.IaCCDKAppTemplateData{
+			Region: s.cfg.App.GetOperations().GetRegion(),
+			Stacks: make([]*assets.IaCCDKAppTemplateDataStack, 0, len(templates)),
+		}
+
+		for _, t := range templates {
+			stack := &assets.IaCCDKAppTemplateDataStack{
+				ID:               strcase.ToCamel(GetMetadataKey(t.plugin)),
+				VarName:          getIaCCDKVarName(t.plugin),
+				DisplayName:      t.plugin.GetDisplayName(),
+				StackName:        t.stackName,
+				TemplateFilePath: getIaCTemplateFilePath(t),
+				Tags:             jsonz.MustMarshalString(CloudGetStackTags(t.plugin)),
+				Dependencies:     make([]string, 0),
+			}
+
+			for _, dependency := range getDependencies(t.plugin) {
+				stack.Dependencies = append(stack.Dependencies, getIaCCDKVarName(dependency))
+			}
+
+			data.Stacks = append(data.Stacks, stack)
+		}
+
+		filez.MustWriteFile(filepath.Join(outDirPath, "app.ts"), 0777, 0666,
+			templatez.MustParseAndExecuteText(assets.IaCCDKAppTemplateAsset, data))
+		filez.MustWriteFile(filepath.Join(outDirPath, "cdk.json"), 0777, 0666, jsonz.MustMarshalIndentDefault(map[string]string{
+			"app": "npx ts-node --prefer-ts-exts app.ts",
+		}))
+	}
+}
+
+func getIaCTemplateFilePath(t *cloudSynthTemplate) string {
+	return "templates/" + t.stackName + ".json"
+}
+
+func getIaCCDKVarName(p Plugin) string {
+	return strcase.ToLowerCamel(GetMetadataKey(p)) + "Stack"
+}
@@ -0,0 +1,213 @@
+package cloudz
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// Known cloud plan actions.
+const (
+	CloudPlanActionAdd     CloudPlanAction = "Add"
+	CloudPlanActionModify  CloudPlanAction = "Modify"
+	CloudPlanActionRemove  CloudPlanAction = "Remove"
+	CloudPlanActionReplace CloudPlanAction = "Replace"
+)
+
+var (
+	cloudPlanActionSymbols = map[CloudPlanAction]string{
+		CloudPlanActionAdd:     "+",
+		CloudPlanActionModify:  "~",
+		CloudPlanActionRemove:  "-",
+		CloudPlanActionReplace: "!",
+	}
+)
+
+// CloudPlanAction describes the action a deploy would perform on a resource.
+type CloudPlanAction string
+
+// CloudPlan describes the changes a deploy would make, per plugin (see CloudStage.Plan).
+type CloudPlan struct {
+	Plugins []*CloudPlanPlugin
+}
+
+// CloudPlanPlugin describes the changes a deploy would make to the stack of a plugin.
+// If IsBlocked is true, the plugin is not deployed and depends on plugins that are not deployed either, so its template
+// cannot be rendered yet and its changes are unknown.
+type CloudPlanPlugin struct {
+	Plugin    Plugin
+	StackName string
+	IsNew     bool
+	IsBlocked bool
+	Changes   []*CloudPlanChange
+}
+
+// CloudPlanChange describes the change a deploy would make to a resource.
+// For Modify and Replace actions, Replacement is the CloudFormation replacement value ("True", "False", "Conditional").
+type CloudPlanChange struct {
+	Action            CloudPlanAction
+	LogicalResourceID string
+	ResourceType      string
+	Replacement       string
+}
+
+// IsStateful returns true if the resource holds data, i.e. if its replacement or removal loses data.
+func (c *CloudPlanChange) IsStateful() bool {
+	_, ok := cloudStatefulResourceTypes[c.ResourceType]
+	return ok
+}
+
+// String implements the fmt.Stringer interface.
+func (c *CloudPlanChange) String() string {
+	s := fmt.Sprintf("%v %v %v (%v)", cloudPlanActionSymbols[c.Action], c.Action, c.LogicalResourceID, c.ResourceType)
+
+	if c.Action == CloudPlanActionReplace && c.Replacement == string(awscft.ReplacementConditional) {
+		s += " [conditional]"
+	}
+	if c.IsStateful() && (c.Action == CloudPlanActionRemove || c.Action == CloudPlanActionReplace) {
+		s += " [DATA LOSS]"
+	}
+
+	return s
+}
+
+// Print prints the plan, one stack per plugin in deploy order, followed by its changes.
+func (p *CloudPlan) Print(w io.Writer) {
+	for _, plugin := range p.Plugins {
+		switch {
+		case plugin.IsBlocked:
+			_, _ = fmt.Fprintf(w, "[%v] %v: new stack, blocked by dependencies not yet deployed\n", GetMetadataKey(plugin.Plugin), plugin.StackName)
+			continue
+		case plugin.IsNew:
+			_, _ = fmt.Fprintf(w, "[%v] %v: new stack\n", GetMetadataKey(plugin.Plugin), plugin.StackName)
+		case len(plugin.Changes) == 0:
+			_, _ = fmt.Fprintf(w, "[%v] %v: no changes\n", GetMetadataKey(plugin.Plugin), plugin.StackName)
+			continue
+		default:
+			_, _ = fmt.Fprintf(w, "[%v] %v\n", GetMetadataKey(plugin.Plugin), plugin.StackName)
+		}
+
+		for _, change := range plugin.Changes {
+			_, _ = fmt.Fprintf(w, "  %v\n", change.String())
+		}
+	}
+}
+
+// Plan implements the CloudStage interface.
+// It creates (then deletes) a CloudFormation change set for the stack of each deployed plugin, and returns the
+// resources a deploy would add, modify, replace, or remove, without executing anything. The stacks of plugins not yet
+// deployed are listed as new, with all the resources in their template. Unlike Deploy, plugin event hooks are not run.
+func (s *cloudStageImpl) Plan() *CloudPlan {
+	plan := &CloudPlan{
+		Plugins: make([]*CloudPlanPlugin, 0),
+	}
+
+	for _, pluginGroup := range s.cfg.App.GetSortedPlugins() {
+		for _, plugin := range pluginGroup {
+			planPlugin := &CloudPlanPlugin{
+				Plugin:    plugin,
+				StackName: CloudGetStackName(plugin),
+				IsNew:     !plugin.IsDeployed(),
+				Changes:   make([]*CloudPlanChange, 0),
+			}
+
+			if planPlugin.IsNew && !isCloudPlanPluginRenderable(plugin) {
+				planPlugin.IsBlocked = true
+				plan.Plugins = append(plan.Plugins, planPlugin)
+				continue
+			}
+
+			plugin.Configure(s)
+			tpl := plugin.GetCloudTemplate(s.cfg.App.GetConfig().GetBuildDirPathForPlugin(plugin))
+			if tpl == nil {
+				continue
+			}
+			cloudApplyRetentionPolicies(plugin, tpl)
+
+			if planPlugin.IsNew {
+				planPlugin.Changes = newCloudPlanAddChanges(tpl)
+			} else {
+				buf, err := tpl.JSON()
+				errorz.MaybeMustWrap(err)
+
+				changeSet := s.cfg.App.GetOperations().CreateChangeSet(planPlugin.StackName, string(buf), CloudGetStackTags(plugin))
+				s.cfg.App.GetOperations().DeleteChangeSet(planPlugin.StackName, changeSet.Name)
+
+				for _, resourceChange := range changeSet.ResourceChanges {
+					planPlugin.Changes = append(planPlugin.Changes, newCloudPlanChange(resourceChange))
+				}
+			}
+
+			plan.Plugins = append(plan.Plugins, planPlugin)
+		}
+	}
+
+	return plan
+}
+
+// TryPlan implements the CloudStage interface.
+// It is like Plan, but bounded by the given context instead of the app context, and returns an error instead of panicking
+// (see opz.Try).
+func (s *cloudStageImpl) TryPlan(ctx context.Context) (plan *CloudPlan, err error) {
+	err = tryWithContext(s.cfg.App, ctx, func() {
+		plan = s.Plan()
+	})
+	return plan, err
+}
+
+// isCloudPlanPluginRenderable returns true if all the dependencies of the given plugin are deployed, i.e. if its cloud
+// template can be rendered.
+func isCloudPlanPluginRenderable(plugin Plugin) bool {
+	for dependency := range plugin.GetDependenciesMap() {
+		if !dependency.IsDeployed() {
+			return false
+		}
+	}
+	return true
+}
+
+func newCloudPlanAddChanges(tpl *gocf.Template) []*CloudPlanChange {
+	changes := make([]*CloudPlanChange, 0, len(tpl.Resources))
+
+	for logicalResourceID, resource := range tpl.Resources {
+		changes = append(changes, &CloudPlanChange{
+			Action:            CloudPlanActionAdd,
+			LogicalResourceID: logicalResourceID,
+			ResourceType:      resource.AWSCloudFormationType(),
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].LogicalResourceID < changes[j].LogicalResourceID
+	})
+
+	return changes
+}
+
+func newCloudPlanChange(resourceChange awscft.ResourceChange) *CloudPlanChange {
+	change := &CloudPlanChange{
+		LogicalResourceID: aws.ToString(resourceChange.LogicalResourceId),
+		ResourceType:      aws.ToString(resourceChange.ResourceType),
+	}
+
+	switch {
+	case resourceChange.Action == awscft.ChangeActionAdd:
+		change.Action = CloudPlanActionAdd
+	case resourceChange.Action == awscft.ChangeActionRemove:
+		change.Action = CloudPlanActionRemove
+	case resourceChange.Replacement == awscft.ReplacementTrue || resourceChange.Replacement == awscft.ReplacementConditional:
+		change.Action = CloudPlanActionReplace
+		change.Replacement = string(resourceChange.Replacement)
+	default:
+		change.Action = CloudPlanActionModify
+		change.Replacement = string(resourceChange.Replacement)
+	}
+
+	return change
+}
@@ -0,0 +1,137 @@
+package cloudz
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	gos3 "github.com/awslabs/goformation/v6/cloudformation/s3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCloudPlanChange(t *testing.T) {
+	testCases := []struct {
+		name           string
+		resourceChange awscft.ResourceChange
+		expected       *CloudPlanChange
+		expectedString string
+	}{
+		{
+			name: "add",
+			resourceChange: awscft.ResourceChange{
+				Action:            awscft.ChangeActionAdd,
+				LogicalResourceId: aws.String("Queue"),
+				ResourceType:      aws.String("AWS::SQS::Queue"),
+			},
+			expected: &CloudPlanChange{
+				Action:            CloudPlanActionAdd,
+				LogicalResourceID: "Queue",
+				ResourceType:      "AWS::SQS::Queue",
+			},
+			expectedString: "+ Add Queue (AWS::SQS::Queue)",
+		},
+		{
+			name: "modify",
+			resourceChange: awscft.ResourceChange{
+				Action:            awscft.ChangeActionModify,
+				LogicalResourceId: aws.String("Db"),
+				ResourceType:      aws.String("AWS::RDS::DBInstance"),
+				Replacement:       awscft.ReplacementFalse,
+			},
+			expected: &CloudPlanChange{
+				Action:            CloudPlanActionModify,
+				LogicalResourceID: "Db",
+				ResourceType:      "AWS::RDS::DBInstance",
+				Replacement:       "False",
+			},
+			expectedString: "~ Modify Db (AWS::RDS::DBInstance)",
+		},
+		{
+			name: "replace",
+			resourceChange: awscft.ResourceChange{
+				Action:            awscft.ChangeActionModify,
+				LogicalResourceId: aws.String("Db"),
+				ResourceType:      aws.String("AWS::RDS::DBInstance"),
+				Replacement:       awscft.ReplacementConditional,
+			},
+			expected: &CloudPlanChange{
+				Action:            CloudPlanActionReplace,
+				LogicalResourceID: "Db",
+				ResourceType:      "AWS::RDS::DBInstance",
+				Replacement:       "Conditional",
+			},
+			expectedString: "! Replace Db (AWS::RDS::DBInstance) [conditional] [DATA LOSS]",
+		},
+		{
+			name: "remove",
+			resourceChange: awscft.ResourceChange{
+				Action:            awscft.ChangeActionRemove,
+				LogicalResourceId: aws.String("B"),
+				ResourceType:      aws.String("AWS::S3::Bucket"),
+			},
+			expected: &CloudPlanChange{
+				Action:            CloudPlanActionRemove,
+				LogicalResourceID: "B",
+				ResourceType:      "AWS::S3::Bucket",
+			},
+			expectedString: "- Remove B (AWS::S3::Bucket) [DATA LOSS]",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			change := newCloudPlanChange(testCase.resourceChange)
+			require.Equal(t, testCase.expected, change)
+			require.Equal(t, testCase.expectedString, change.String())
+		})
+	}
+}
+
+func TestNewCloudPlanAddChanges(t *testing.T) {
+	tpl := gocf.NewTemplate()
+	tpl.Resources["R"] = &goiam.Role{}
+	tpl.Resources["B"] = &gos3.Bucket{}
+
+	require.Equal(t, []*CloudPlanChange{
+		{Action: CloudPlanActionAdd, LogicalResourceID: "B", ResourceType: "AWS::S3::Bucket"},
+		{Action: CloudPlanActionAdd, LogicalResourceID: "R", ResourceType: "AWS::IAM::Role"},
+	}, newCloudPlanAddChanges(tpl))
+}
+
+func TestIsCloudPlanPluginRenderable(t *testing.T) {
+	network := &networkImpl{}
+	require.True(t, isCloudPlanPluginRenderable(&budgetImpl{deps: &BudgetDependencies{}}))
+	require.False(t, isCloudPlanPluginRenderable(&budgetImpl{deps: &BudgetDependencies{OtherDependencies: OtherDependencies{network}}}))
+
+	network.cloudMetadata = &NetworkCloudMetadata{}
+	require.True(t, isCloudPlanPluginRenderable(&budgetImpl{deps: &BudgetDependencies{OtherDependencies: OtherDependencies{network}}}))
+}
+
+func TestCloudPlan_Print(t *testing.T) {
+	_, plugins := newTestMetadataStage(nil, newTestMetadata(), newTestMetadata(), newTestMetadata())
+
+	plan := &CloudPlan{
+		Plugins: []*CloudPlanPlugin{
+			{Plugin: plugins[0], StackName: "s0", Changes: []*CloudPlanChange{}},
+			{Plugin: plugins[1], StackName: "s1", IsNew: true, Changes: []*CloudPlanChange{
+				{Action: CloudPlanActionAdd, LogicalResourceID: "B", ResourceType: "AWS::S3::Bucket"},
+			}},
+			{Plugin: plugins[2], StackName: "s2", IsNew: true, IsBlocked: true, Changes: []*CloudPlanChange{}},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	plan.Print(buf)
+
+	require.Equal(t, ""+
+		"[test] s0: no changes\n"+
+		"[test-a] s1: new stack\n"+
+		"  "+plan.Plugins[1].Changes[0].String()+"\n"+
+		"[test-b] s2: new stack, blocked by dependencies not yet deployed\n",
+		buf.String())
+}
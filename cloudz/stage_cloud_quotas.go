@@ -0,0 +1,98 @@
+package cloudz
+
+import (
+	"github.com/ibrt/golang-errors/errorz"
+
+	"github.com/ibrt/golang-cloud/opz"
+)
+
+const (
+	// AWS default values, used if the quotas cannot be looked up.
+	cloudQuotaDefaultStacks        = 2000
+	cloudQuotaDefaultVPCs          = 5
+	cloudQuotaDefaultElasticIPs    = 5
+	cloudQuotaDefaultListenerRules = 100
+)
+
+// cloudMustCheckQuotas checks that the resources created by deploying the stage fit within the AWS service quotas of the
+// account and region, so that deployments to fresh accounts fail early with actionable errors instead of mid-deploy.
+// The required amounts are upper bounds, assuming that every plugin not deployed yet will create a stack.
+func cloudMustCheckQuotas(s CloudStage) {
+	ops := s.GetConfig().App.GetOperations()
+	newStacks, newVPCs, newElasticIPs := 0, 0, 0
+	listenerRules := map[LoadBalancer]int{}
+
+	if s.GetCloudConfig().SSMExport != nil {
+		newStacks++
+	}
+
+	for _, pluginGroup := range s.GetConfig().App.GetSortedPlugins() {
+		for _, plugin := range pluginGroup {
+			if !plugin.IsDeployed() {
+				newStacks++
+			}
+
+			if _, ok := plugin.(Network); ok && !plugin.IsDeployed() {
+				newVPCs++
+				newElasticIPs++
+
+				if s.GetMode().IsProduction() {
+					newElasticIPs++ // production stages have a NAT gateway per availability zone
+				}
+			}
+
+			if plugin.IsDeployed() {
+				continue // its listener rules, if any, are counted as used
+			}
+
+			if loadBalancer, ok := plugin.(LoadBalancer); ok {
+				listenerRules[loadBalancer] += 2 // the default rules of the HTTP and HTTPS listeners
+			}
+
+			for dependency := range plugin.GetDependenciesMap() {
+				if loadBalancer, ok := dependency.(LoadBalancer); ok {
+					listenerRules[loadBalancer]++
+				}
+			}
+		}
+	}
+
+	if newStacks > 0 {
+		cloudMustCheckQuota(
+			ops.GetServiceQuota("cloudformation", "Stack count", cloudQuotaDefaultStacks),
+			ops.CountStacks(), newStacks)
+	}
+
+	if newVPCs > 0 {
+		cloudMustCheckQuota(
+			ops.GetServiceQuota("vpc", "VPCs per Region", cloudQuotaDefaultVPCs),
+			ops.CountVPCs(), newVPCs)
+	}
+
+	if newElasticIPs > 0 {
+		cloudMustCheckQuota(
+			ops.GetServiceQuota("ec2", "EC2-VPC Elastic IPs", cloudQuotaDefaultElasticIPs),
+			ops.CountElasticIPs(), newElasticIPs)
+	}
+
+	if len(listenerRules) > 0 {
+		quota := ops.GetServiceQuota("elasticloadbalancing", "Rules per Application Load Balancer", cloudQuotaDefaultListenerRules)
+
+		for loadBalancer, required := range listenerRules {
+			used := 0
+
+			if loadBalancer.IsDeployed() {
+				used = ops.CountLoadBalancerRules(
+					loadBalancer.GetCloudMetadata(true).Exports.GetRef(LoadBalancerRefLoadBalancer))
+			}
+
+			cloudMustCheckQuota(quota, used, required)
+		}
+	}
+}
+
+func cloudMustCheckQuota(quota *opz.ServiceQuota, used, required int) {
+	errorz.Assertf(used+required <= quota.Value,
+		"insufficient quota: %v (%v): %v in use, %v required, quota is %v; request an increase at %v",
+		errorz.A(quota.QuotaName, quota.ServiceCode, used, required, quota.Value, quota.GetIncreaseURL()))
+}
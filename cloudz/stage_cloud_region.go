@@ -0,0 +1,93 @@
+package cloudz
+
+import (
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// Identifiers of the services required by plugins, as published in the AWS global infrastructure public SSM parameters.
+const (
+	cloudRegionServiceEFS           = "efs"
+	cloudRegionServiceFargate       = "fargate"
+	cloudRegionServiceOpenSearch    = "es"
+	cloudRegionServiceOpenSearchAlt = "opensearch"
+)
+
+// cloudMustCheckRegion checks that the services required by the plugins are available in the configured region, and
+// that the availability zones used by the Network plugin (i.e. the region name followed by "a" and "b") exist, so that
+// deployments to uncommon regions fail early with actionable errors instead of opaque CloudFormation failures.
+func cloudMustCheckRegion(s CloudStage) {
+	ops := s.GetConfig().App.GetOperations()
+	region := ops.GetRegion()
+
+	requiredServices := map[string]Plugin{}
+	requiresAvailabilityZones := false
+	var postgresPlugins []Postgres
+	var postgresProxyPlugin Plugin
+
+	for _, pluginGroup := range s.GetConfig().App.GetSortedPlugins() {
+		for _, plugin := range pluginGroup {
+			switch p := plugin.(type) {
+			case Network:
+				requiresAvailabilityZones = true
+			case Postgres:
+				postgresPlugins = append(postgresPlugins, p)
+				if len(p.GetConfig().Maintenance) > 0 {
+					requiredServices[cloudRegionServiceFargate] = p
+				}
+			case PostgresProxy:
+				postgresProxyPlugin = p
+			case EFS:
+				requiredServices[cloudRegionServiceEFS] = p
+			case OpenSearch:
+				requiredServices[cloudRegionServiceOpenSearch] = p
+			case CDC, ContainerService, Hasura, Keycloak:
+				requiredServices[cloudRegionServiceFargate] = p
+			}
+		}
+	}
+
+	if requiresAvailabilityZones {
+		availabilityZoneNames := map[string]struct{}{}
+		for _, availabilityZoneName := range ops.GetAvailabilityZoneNames() {
+			availabilityZoneNames[availabilityZoneName] = struct{}{}
+		}
+
+		for _, suffix := range []string{"a", "b"} {
+			_, ok := availabilityZoneNames[region+suffix]
+			errorz.Assertf(ok, "availability zone not available: %v", errorz.A(region+suffix))
+		}
+	}
+
+	if len(requiredServices) > 0 {
+		regionServices := map[string]struct{}{}
+		for _, regionService := range ops.GetRegionServices() {
+			regionServices[regionService] = struct{}{}
+		}
+
+		if _, ok := regionServices[cloudRegionServiceOpenSearchAlt]; ok {
+			regionServices[cloudRegionServiceOpenSearch] = struct{}{}
+		}
+
+		for requiredService, plugin := range requiredServices {
+			_, ok := regionServices[requiredService]
+			errorz.Assertf(ok, "service not available in region %v: %v (required by %v)",
+				errorz.A(region, requiredService, plugin.GetDisplayName()))
+		}
+	}
+
+	for _, postgresPlugin := range postgresPlugins {
+		engine := "postgres"
+		if postgresCfg := postgresPlugin.GetConfig(); postgresCfg.Cloud != nil && postgresCfg.Cloud.Aurora != nil {
+			engine = postgresAuroraEngine
+		}
+
+		version := s.GetConfig().App.GetConfig().GetVersions().Postgres
+		errorz.Assertf(ops.IsRDSEngineVersionAvailable(engine, version), "RDS engine version not available in region %v: %v %v",
+			errorz.A(region, engine, version), errorz.Prefix(postgresPlugin.GetName()))
+	}
+
+	if postgresProxyPlugin != nil {
+		errorz.Assertf(ops.IsRDSProxyAvailable(), "RDS Proxy not available in region %v",
+			errorz.A(region), errorz.Prefix(postgresProxyPlugin.GetName()))
+	}
+}
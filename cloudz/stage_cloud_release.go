@@ -0,0 +1,200 @@
+package cloudz
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/jsonz"
+	"github.com/ibrt/golang-errors/errorz"
+
+	"github.com/ibrt/golang-cloud/opz"
+)
+
+// CloudReleaseArtifactKind describes a kind of release artifact.
+type CloudReleaseArtifactKind string
+
+// Known release artifact kinds.
+const (
+	CloudReleaseArtifactKindFunctionPackage CloudReleaseArtifactKind = "functionPackage" // reproducible, see VerifyRelease
+	CloudReleaseArtifactKindImage           CloudReleaseArtifactKind = "image"
+)
+
+// Provenance labels, applied to images (as labels) and functions (as tags).
+const (
+	CloudProvenanceLabelRevision   = "org.opencontainers.image.revision"
+	CloudProvenanceLabelVersion    = "org.opencontainers.image.version"
+	CloudProvenanceLabelSourceHash = "golang-cloud.source-hash"
+	CloudProvenanceLabelGoVersion  = "golang-cloud.go-version"
+)
+
+// CloudReleaseManifest describes the artifacts deployed by a release, i.e. a Deploy of a given stage version. It is
+// stored in the build dir (see GetCloudReleaseManifestFilePath).
+type CloudReleaseManifest struct {
+	StageName  string                  `json:"stageName"`
+	Version    string                  `json:"version"`
+	Provenance *opz.Provenance         `json:"provenance"`
+	Artifacts  []*CloudReleaseArtifact `json:"artifacts"`
+}
+
+// CloudReleaseArtifact describes a release artifact.
+type CloudReleaseArtifact struct {
+	MetadataKey string                   `json:"metadataKey"`
+	Kind        CloudReleaseArtifactKind `json:"kind"`
+	Name        string                   `json:"name"`   // e.g. an artifacts bucket key, or an image with tag
+	SHA256      string                   `json:"sha256"` // for images, the image ID
+}
+
+// CloudReleaseVerification describes the result of a successful release verification (see CloudStage.VerifyRelease).
+// If GoVersion differs from the one of the release, verified packages are reproducible across Go versions, but other
+// packages might not be.
+type CloudReleaseVerification struct {
+	Manifest  *CloudReleaseManifest
+	GoVersion string                  // the Go version used for the verification
+	Verified  []*CloudReleaseArtifact // function packages whose hashes match the release manifest
+	Skipped   []*CloudReleaseArtifact // artifacts that cannot be verified, e.g. images
+}
+
+// HasGoVersionMismatch returns true if the Go version used for the verification differs from the one of the release.
+func (v *CloudReleaseVerification) HasGoVersionMismatch() bool {
+	return v.GoVersion != v.Manifest.Provenance.GoVersion
+}
+
+// GetCloudReleaseManifestFilePath returns the path of the release manifest for the given stage version.
+func GetCloudReleaseManifestFilePath(s CloudStage, version string) string {
+	return s.GetConfig().App.GetConfig().GetBuildDirPath("releases", s.GetName(), version+".json")
+}
+
+// CloudGetProvenanceLabels returns the provenance labels for artifacts built for the given stage.
+func CloudGetProvenanceLabels(s CloudStage) map[string]string {
+	provenance := s.GetProvenance()
+
+	revision := provenance.Commit
+	if provenance.IsDirty {
+		revision += "-dirty"
+	}
+
+	return map[string]string{
+		CloudProvenanceLabelRevision:   revision,
+		CloudProvenanceLabelVersion:    s.GetCloudConfig().Version,
+		CloudProvenanceLabelSourceHash: provenance.SourceHash,
+		CloudProvenanceLabelGoVersion:  provenance.GoVersion,
+	}
+}
+
+// CloudBuildImage builds an image from the Dockerfile in the given dir, with provenance labels (see
+// CloudGetProvenanceLabels), and adds it to the release manifest of the current deploy.
+func CloudBuildImage(p Plugin, dirPath, imageWithTag string) {
+	ops := p.GetStage().GetConfig().App.GetOperations()
+	cmd := ops.NewCommand("docker", "build", "-t", imageWithTag)
+
+	for k, v := range CloudGetProvenanceLabels(p.GetStage().AsCloudStage()) {
+		cmd.AddParams("--label", k+"="+v)
+	}
+
+	cmd.AddParams(".").SetDir(dirPath).MustRun()
+
+	p.GetStage().AsCloudStage().AddReleaseArtifact(&CloudReleaseArtifact{
+		MetadataKey: GetMetadataKey(p),
+		Kind:        CloudReleaseArtifactKindImage,
+		Name:        imageWithTag,
+		SHA256:      strings.TrimSpace(ops.NewCommand("docker", "image", "inspect", "--format", "{{.Id}}", imageWithTag).SetQuiet().MustOutput()),
+	})
+}
+
+// GetProvenance implements the CloudStage interface.
+func (s *cloudStageImpl) GetProvenance() *opz.Provenance {
+	if s.provenance == nil {
+		s.provenance = s.cfg.App.GetOperations().GetProvenance(".")
+	}
+	return s.provenance
+}
+
+// AddReleaseArtifact implements the CloudStage interface.
+// Artifacts are only recorded during Deploy.
+func (s *cloudStageImpl) AddReleaseArtifact(artifact *CloudReleaseArtifact) {
+	if s.releaseManifest != nil {
+		s.releaseManifest.Artifacts = append(s.releaseManifest.Artifacts, artifact)
+	}
+}
+
+// VerifyRelease implements the CloudStage interface.
+//
+// It rebuilds the function packages of the given release, checks that their hashes match the release manifest, and returns
+// the verified and skipped artifacts. The stage must be configured as it was for the release (e.g. same Version), and the
+// working tree must match the source hash of the release. Images are not verified, as Docker builds are not reproducible.
+func (s *cloudStageImpl) VerifyRelease(version string) *CloudReleaseVerification {
+	manifest := &CloudReleaseManifest{}
+	errorz.MaybeMustWrap(json.Unmarshal(filez.MustReadFile(GetCloudReleaseManifestFilePath(s, version)), manifest))
+
+	errorz.Assertf(s.cfg.Version == version, "stage version mismatch: %v (release is %v)", errorz.A(s.cfg.Version, version))
+	errorz.Assertf(s.GetProvenance().SourceHash == manifest.Provenance.SourceHash,
+		"source hash mismatch: check out commit %v", errorz.A(manifest.Provenance.Commit))
+
+	verification := &CloudReleaseVerification{
+		Manifest:  manifest,
+		GoVersion: s.GetProvenance().GoVersion,
+		Verified:  make([]*CloudReleaseArtifact, 0),
+		Skipped:   make([]*CloudReleaseArtifact, 0),
+	}
+
+	mismatches := make([]string, 0)
+
+	for _, artifact := range manifest.Artifacts {
+		if artifact.Kind != CloudReleaseArtifactKindFunctionPackage {
+			verification.Skipped = append(verification.Skipped, artifact)
+			continue
+		}
+
+		function := s.mustFindFunction(artifact.MetadataKey)
+		buildDirPath := s.cfg.App.GetConfig().GetBuildDirPathForPlugin(function, "verify")
+		filez.MustPrepareDir(buildDirPath, 0777)
+
+		function.GetConfig().Builder.BuildCloudPackage(function, buildDirPath)
+		actual := getCloudReleaseArtifactHash(filez.MustReadFile(filepath.Join(buildDirPath, FunctionPackageFileName)))
+
+		if actual == artifact.SHA256 {
+			verification.Verified = append(verification.Verified, artifact)
+		} else {
+			mismatches = append(mismatches, fmt.Sprintf("%v: %v (expected %v, got %v)", artifact.MetadataKey, artifact.Name, artifact.SHA256, actual))
+		}
+	}
+
+	errorz.Assertf(len(mismatches) == 0, "release verification failed: %v", errorz.A(strings.Join(mismatches, ", ")))
+	return verification
+}
+
+func (s *cloudStageImpl) mustFindFunction(metadataKey string) Function {
+	for _, pluginGroup := range s.cfg.App.GetSortedPlugins() {
+		for _, plugin := range pluginGroup {
+			if function, ok := plugin.(Function); ok && GetMetadataKey(function) == metadataKey {
+				return function
+			}
+		}
+	}
+
+	panic(errorz.Errorf("no such function: %v", errorz.A(metadataKey)))
+}
+
+func (s *cloudStageImpl) beginRelease() {
+	s.releaseManifest = &CloudReleaseManifest{
+		StageName:  s.cfg.Name,
+		Version:    s.cfg.Version,
+		Provenance: s.GetProvenance(),
+		Artifacts:  make([]*CloudReleaseArtifact, 0),
+	}
+}
+
+func (s *cloudStageImpl) storeRelease() {
+	filez.MustWriteFile(GetCloudReleaseManifestFilePath(s, s.cfg.Version), 0777, 0666, jsonz.MustMarshalIndentDefault(s.releaseManifest))
+	s.releaseManifest = nil
+}
+
+func getCloudReleaseArtifactHash(buf []byte) string {
+	h := sha256.Sum256(buf)
+	return hex.EncodeToString(h[:])
+}
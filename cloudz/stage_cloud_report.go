@@ -0,0 +1,205 @@
+package cloudz
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/jsonz"
+
+	"github.com/ibrt/golang-cloud/opz"
+)
+
+// CloudDeployPhase describes a timed phase of a Deploy.
+type CloudDeployPhase string
+
+// Known deploy phases.
+const (
+	CloudDeployPhasePreflight    CloudDeployPhase = "preflight"    // stage-level: quotas, region, and plugin checks
+	CloudDeployPhaseTemplate     CloudDeployPhase = "template"     // plugin configuration and template generation
+	CloudDeployPhaseApproval     CloudDeployPhase = "approval"     // stack update preview (change set) and approval
+	CloudDeployPhaseBeforeDeploy CloudDeployPhase = "beforeDeploy" // CloudBeforeDeployEvent: artifact builds and uploads
+	CloudDeployPhaseStack        CloudDeployPhase = "stack"        // stack create or update, until complete
+	CloudDeployPhaseAfterDeploy  CloudDeployPhase = "afterDeploy"  // CloudAfterDeployEvent: e.g. invalidations, migrations
+	CloudDeployPhaseSSMExport    CloudDeployPhase = "ssmExport"    // stage-level: export of SSM parameters
+)
+
+const (
+	cloudDeployReportFileName             = "deploy-report.json"
+	cloudDeployMetricPhaseDuration        = "PhaseDuration"
+	cloudDeployMetricDeployDuration       = "DeployDuration"
+	cloudDeployMetricDimensionApp         = "App"
+	cloudDeployMetricDimensionStage       = "Stage"
+	cloudDeployMetricDimensionPlugin      = "Plugin"
+	cloudDeployMetricDimensionPhase       = "Phase"
+	cloudDeployMetricUnitSeconds          = "Seconds"
+	cloudDeployReportDurationRounding     = 100 * time.Millisecond
+	cloudDeployReportStagePseudoPluginKey = "(stage)"
+)
+
+var (
+	cloudDeployPluginPhases = []CloudDeployPhase{
+		CloudDeployPhaseTemplate,
+		CloudDeployPhaseApproval,
+		CloudDeployPhaseBeforeDeploy,
+		CloudDeployPhaseStack,
+		CloudDeployPhaseAfterDeploy,
+	}
+)
+
+// CloudStageDeployMetricsConfig describes the CloudWatch custom metrics published after each successful Deploy.
+//
+// For each plugin and phase, a "PhaseDuration" metric (in seconds) is published with dimensions "App", "Stage",
+// "Plugin" (the metadata key, or "(stage)" for stage-level phases), and "Phase". A "DeployDuration" metric is published
+// with dimensions "App" and "Stage".
+type CloudStageDeployMetricsConfig struct {
+	Namespace string `validate:"required"`
+}
+
+// CloudDeployReport describes the duration of each phase of a Deploy, per plugin. It is printed as a table and stored as
+// "deploy-report.json" in the build dir at the end of each Deploy, including failed ones.
+type CloudDeployReport struct {
+	StartTime time.Time                          `json:"startTime"`
+	Duration  time.Duration                      `json:"duration"`
+	Phases    map[CloudDeployPhase]time.Duration `json:"phases"`
+	Plugins   []*CloudDeployPluginReport         `json:"plugins"`
+	Completed bool                               `json:"completed"`
+}
+
+// CloudDeployPluginReport describes the duration of each phase of the deploy of a plugin.
+type CloudDeployPluginReport struct {
+	MetadataKey string                             `json:"metadataKey"`
+	Duration    time.Duration                      `json:"duration"`
+	Phases      map[CloudDeployPhase]time.Duration `json:"phases"`
+}
+
+func newCloudDeployReport() *CloudDeployReport {
+	return &CloudDeployReport{
+		StartTime: time.Now(),
+		Phases:    map[CloudDeployPhase]time.Duration{},
+		Plugins:   make([]*CloudDeployPluginReport, 0),
+	}
+}
+
+// measure runs the given function, adding its duration to the given stage-level phase.
+func (r *CloudDeployReport) measure(phase CloudDeployPhase, f func()) {
+	start := time.Now()
+	defer func() { r.Phases[phase] += time.Since(start) }()
+	f()
+}
+
+// addPlugin adds a plugin to the report.
+func (r *CloudDeployReport) addPlugin(plugin Plugin) *CloudDeployPluginReport {
+	pluginReport := &CloudDeployPluginReport{
+		MetadataKey: GetMetadataKey(plugin),
+		Phases:      map[CloudDeployPhase]time.Duration{},
+	}
+
+	r.Plugins = append(r.Plugins, pluginReport)
+	return pluginReport
+}
+
+// finish marks the report as finished.
+func (r *CloudDeployReport) finish(completed bool) {
+	r.Duration = time.Since(r.StartTime)
+	r.Completed = completed
+}
+
+// measure runs the given function, adding its duration to the given phase.
+func (r *CloudDeployPluginReport) measure(phase CloudDeployPhase, f func()) {
+	start := time.Now()
+
+	defer func() {
+		d := time.Since(start)
+		r.Phases[phase] += d
+		r.Duration += d
+	}()
+
+	f()
+}
+
+// Print prints the report as a table, one row per plugin in deploy order.
+func (r *CloudDeployReport) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprint(tw, "plugin\t")
+	for _, phase := range cloudDeployPluginPhases {
+		_, _ = fmt.Fprintf(tw, "%v\t", phase)
+	}
+	_, _ = fmt.Fprintln(tw, "total")
+
+	for _, pluginReport := range r.Plugins {
+		_, _ = fmt.Fprintf(tw, "%v\t", pluginReport.MetadataKey)
+		for _, phase := range cloudDeployPluginPhases {
+			_, _ = fmt.Fprintf(tw, "%v\t", formatCloudDeployDuration(pluginReport.Phases[phase]))
+		}
+		_, _ = fmt.Fprintf(tw, "%v\n", formatCloudDeployDuration(pluginReport.Duration))
+	}
+
+	_ = tw.Flush()
+
+	_, _ = fmt.Fprintf(w, "preflight: %v, ssm export: %v, total: %v%v\n",
+		formatCloudDeployDuration(r.Phases[CloudDeployPhasePreflight]),
+		formatCloudDeployDuration(r.Phases[CloudDeployPhaseSSMExport]),
+		formatCloudDeployDuration(r.Duration),
+		map[bool]string{true: "", false: " (failed)"}[r.Completed])
+}
+
+// getMetricData returns the report as CloudWatch metric data points.
+func (r *CloudDeployReport) getMetricData(appName, stageName string) []*opz.MetricDatum {
+	data := make([]*opz.MetricDatum, 0)
+
+	newDatum := func(name string, value time.Duration, dimensions map[string]string) *opz.MetricDatum {
+		dimensions[cloudDeployMetricDimensionApp] = appName
+		dimensions[cloudDeployMetricDimensionStage] = stageName
+
+		return &opz.MetricDatum{
+			Name:       name,
+			Dimensions: dimensions,
+			Value:      value.Seconds(),
+			Unit:       cloudDeployMetricUnitSeconds,
+		}
+	}
+
+	for _, phase := range []CloudDeployPhase{CloudDeployPhasePreflight, CloudDeployPhaseSSMExport} {
+		if d, ok := r.Phases[phase]; ok {
+			data = append(data, newDatum(cloudDeployMetricPhaseDuration, d, map[string]string{
+				cloudDeployMetricDimensionPlugin: cloudDeployReportStagePseudoPluginKey,
+				cloudDeployMetricDimensionPhase:  string(phase),
+			}))
+		}
+	}
+
+	for _, pluginReport := range r.Plugins {
+		for _, phase := range cloudDeployPluginPhases {
+			if d, ok := pluginReport.Phases[phase]; ok {
+				data = append(data, newDatum(cloudDeployMetricPhaseDuration, d, map[string]string{
+					cloudDeployMetricDimensionPlugin: pluginReport.MetadataKey,
+					cloudDeployMetricDimensionPhase:  string(phase),
+				}))
+			}
+		}
+	}
+
+	return append(data, newDatum(cloudDeployMetricDeployDuration, r.Duration, map[string]string{}))
+}
+
+// store prints the report, and stores it in the build dir.
+func (r *CloudDeployReport) store(s CloudStage) {
+	r.Print(os.Stdout)
+
+	filez.MustWriteFile(
+		filepath.Join(s.GetConfig().App.GetConfig().GetBuildDirPath(), cloudDeployReportFileName),
+		0777, 0666, jsonz.MustMarshalIndentDefault(r))
+}
+
+func formatCloudDeployDuration(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(cloudDeployReportDurationRounding).String()
+}
@@ -0,0 +1,86 @@
+package cloudz
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCloudDeployReport() *CloudDeployReport {
+	return &CloudDeployReport{
+		Duration: 95 * time.Second,
+		Phases: map[CloudDeployPhase]time.Duration{
+			CloudDeployPhasePreflight: 2 * time.Second,
+		},
+		Plugins: []*CloudDeployPluginReport{
+			{
+				MetadataKey: "network",
+				Duration:    62 * time.Second,
+				Phases: map[CloudDeployPhase]time.Duration{
+					CloudDeployPhaseTemplate: 20 * time.Millisecond,
+					CloudDeployPhaseStack:    61980 * time.Millisecond,
+				},
+			},
+			{
+				MetadataKey: "function-worker",
+				Duration:    31 * time.Second,
+				Phases: map[CloudDeployPhase]time.Duration{
+					CloudDeployPhaseTemplate:     150 * time.Millisecond,
+					CloudDeployPhaseBeforeDeploy: 12500 * time.Millisecond,
+					CloudDeployPhaseStack:        18 * time.Second,
+				},
+			},
+		},
+		Completed: true,
+	}
+}
+
+func TestCloudDeployReport_Print(t *testing.T) {
+	buf := &bytes.Buffer{}
+	newTestCloudDeployReport().Print(buf)
+
+	require.Equal(t, ""+
+		"plugin           template  approval  beforeDeploy  stack  afterDeploy  total\n"+
+		"network          0s        -         -             1m2s   -            1m2s\n"+
+		"function-worker  200ms     -         12.5s         18s    -            31s\n"+
+		"preflight: 2s, ssm export: -, total: 1m35s\n",
+		buf.String())
+}
+
+func TestCloudDeployReport_GetMetricData(t *testing.T) {
+	data := newTestCloudDeployReport().getMetricData("app", "stage")
+	require.Len(t, data, 7)
+
+	require.Equal(t, "PhaseDuration", data[0].Name)
+	require.Equal(t, map[string]string{"App": "app", "Stage": "stage", "Plugin": "(stage)", "Phase": "preflight"}, data[0].Dimensions)
+	require.Equal(t, 2.0, data[0].Value)
+	require.Equal(t, "Seconds", data[0].Unit)
+
+	require.Equal(t, map[string]string{"App": "app", "Stage": "stage", "Plugin": "function-worker", "Phase": "beforeDeploy"}, data[4].Dimensions)
+	require.Equal(t, 12.5, data[4].Value)
+
+	require.Equal(t, "DeployDuration", data[6].Name)
+	require.Equal(t, map[string]string{"App": "app", "Stage": "stage"}, data[6].Dimensions)
+	require.Equal(t, 95.0, data[6].Value)
+}
+
+func TestCloudDeployPluginReport_Measure(t *testing.T) {
+	r := newCloudDeployReport()
+	pluginReport := &CloudDeployPluginReport{Phases: map[CloudDeployPhase]time.Duration{}}
+
+	require.Panics(t, func() {
+		pluginReport.measure(CloudDeployPhaseStack, func() {
+			time.Sleep(time.Millisecond)
+			panic("failed")
+		})
+	})
+
+	require.GreaterOrEqual(t, pluginReport.Phases[CloudDeployPhaseStack], time.Millisecond)
+	require.Equal(t, pluginReport.Phases[CloudDeployPhaseStack], pluginReport.Duration)
+
+	r.finish(false)
+	require.False(t, r.Completed)
+	require.Greater(t, r.Duration, time.Duration(0))
+}
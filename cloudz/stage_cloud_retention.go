@@ -0,0 +1,148 @@
+package cloudz
+
+import (
+	"reflect"
+
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	gords "github.com/awslabs/goformation/v6/cloudformation/rds"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// Known cloud retention policies, i.e. values of the CloudFormation DeletionPolicy and UpdateReplacePolicy attributes.
+const (
+	CloudRetentionPolicyDelete   CloudRetentionPolicy = "Delete"
+	CloudRetentionPolicyRetain   CloudRetentionPolicy = "Retain"
+	CloudRetentionPolicySnapshot CloudRetentionPolicy = "Snapshot"
+)
+
+var (
+	// cloudProductionRetentionPolicies are the default retention policies of the stateful resources in production stages.
+	cloudProductionRetentionPolicies = map[string]CloudRetentionPolicy{
+		"AWS::Cognito::UserPool":         CloudRetentionPolicyRetain,
+		"AWS::ECR::Repository":           CloudRetentionPolicyRetain,
+		"AWS::EFS::FileSystem":           CloudRetentionPolicyRetain,
+		"AWS::Logs::LogGroup":            CloudRetentionPolicyRetain,
+		"AWS::MSK::Cluster":              CloudRetentionPolicyRetain,
+		"AWS::MSK::ServerlessCluster":    CloudRetentionPolicyRetain,
+		"AWS::OpenSearchService::Domain": CloudRetentionPolicyRetain,
+		"AWS::RDS::DBCluster":            CloudRetentionPolicySnapshot,
+		"AWS::RDS::DBInstance":           CloudRetentionPolicySnapshot,
+		"AWS::S3::Bucket":                CloudRetentionPolicyRetain,
+		"AWS::SecretsManager::Secret":    CloudRetentionPolicyRetain,
+	}
+
+	// cloudSnapshotResourceTypes are the resource types that support the Snapshot retention policy.
+	cloudSnapshotResourceTypes = map[string]struct{}{
+		"AWS::DocDB::DBCluster":              {},
+		"AWS::EC2::Volume":                   {},
+		"AWS::ElastiCache::CacheCluster":     {},
+		"AWS::ElastiCache::ReplicationGroup": {},
+		"AWS::Neptune::DBCluster":            {},
+		"AWS::RDS::DBCluster":                {},
+		"AWS::RDS::DBInstance":               {},
+		"AWS::Redshift::Cluster":             {},
+	}
+)
+
+// CloudRetentionPolicy describes what happens to a resource when it is removed from its stack, replaced, or when its
+// stack is deleted: it is either deleted, retained (i.e. left behind, outside of the stack), or deleted after taking a
+// final snapshot.
+type CloudRetentionPolicy string
+
+// CloudStageRetentionConfig describes the retention policies of the resources of a cloud Stage.
+//
+// By default, in production stages the stateful resources (e.g. databases, buckets, image repositories, log groups) are
+// retained, or snapshotted if supported, so that a mistaken stack deletion or replacement never destroys data. In other
+// stages no policy is set, i.e. resources are deleted. ResourceTypes override the defaults by resource type (e.g.
+// "AWS::S3::Bucket"), and Plugins override both by metadata key (see GetMetadataKey) then by resource type. Overrides
+// apply to all the resources of the given type, including the ones whose policy is set by their plugin.
+type CloudStageRetentionConfig struct {
+	ResourceTypes map[string]CloudRetentionPolicy
+	Plugins       map[string]map[string]CloudRetentionPolicy
+}
+
+// MustValidate validates the cloud stage retention config.
+func (c *CloudStageRetentionConfig) MustValidate() {
+	mustValidateCloudRetentionPolicies(c.ResourceTypes)
+
+	for _, policies := range c.Plugins {
+		mustValidateCloudRetentionPolicies(policies)
+	}
+}
+
+func mustValidateCloudRetentionPolicies(policies map[string]CloudRetentionPolicy) {
+	for resourceType, policy := range policies {
+		errorz.Assertf(resourceType != "", "invalid CloudStageRetentionConfig resource type: %v", errorz.A(resourceType))
+
+		switch policy {
+		case CloudRetentionPolicyDelete, CloudRetentionPolicyRetain:
+			// valid for all resource types
+		case CloudRetentionPolicySnapshot:
+			_, ok := cloudSnapshotResourceTypes[resourceType]
+			errorz.Assertf(ok, "invalid CloudStageRetentionConfig policy: %v not supported by %v", errorz.A(policy, resourceType))
+		default:
+			panic(errorz.Errorf("invalid CloudStageRetentionConfig policy: %v", errorz.A(policy)))
+		}
+	}
+}
+
+// cloudApplyRetentionPolicies sets the DeletionPolicy and UpdateReplacePolicy of the resources in the template of the
+// given plugin, according to the mode and CloudStageConfig.Retention of its stage.
+func cloudApplyRetentionPolicies(p Plugin, tpl *gocf.Template) {
+	cfg := p.GetStage().AsCloudStage().GetCloudConfig()
+
+	for _, resource := range tpl.Resources {
+		if policy, ok := getCloudRetentionPolicyOverride(cfg.Retention, GetMetadataKey(p), resource.AWSCloudFormationType()); ok {
+			setCloudRetentionPolicy(resource, policy, true)
+			continue
+		}
+
+		if cfg.Mode.IsProduction() {
+			if policy, ok := getCloudDefaultRetentionPolicy(resource); ok {
+				setCloudRetentionPolicy(resource, policy, false)
+			}
+		}
+	}
+}
+
+func getCloudRetentionPolicyOverride(cfg *CloudStageRetentionConfig, metadataKey, resourceType string) (CloudRetentionPolicy, bool) {
+	if cfg == nil {
+		return "", false
+	}
+
+	if policy, ok := cfg.Plugins[metadataKey][resourceType]; ok {
+		return policy, true
+	}
+
+	policy, ok := cfg.ResourceTypes[resourceType]
+	return policy, ok
+}
+
+func getCloudDefaultRetentionPolicy(resource gocf.Resource) (CloudRetentionPolicy, bool) {
+	policy, ok := cloudProductionRetentionPolicies[resource.AWSCloudFormationType()]
+
+	// Note: the instances of Aurora clusters do not support the Snapshot policy, the cluster is snapshotted instead.
+	if dbInstance, isDBInstance := resource.(*gords.DBInstance); ok && isDBInstance && dbInstance.DBClusterIdentifier != nil {
+		return CloudRetentionPolicyDelete, true
+	}
+
+	return policy, ok
+}
+
+// setCloudRetentionPolicy sets the DeletionPolicy and UpdateReplacePolicy of a resource. Unless force is true, policies
+// already set (e.g. by the plugin) are left unchanged.
+func setCloudRetentionPolicy(resource gocf.Resource, policy CloudRetentionPolicy, force bool) {
+	v := reflect.ValueOf(resource)
+	errorz.Assertf(v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct, "unexpected resource type: %v", errorz.A(resource.AWSCloudFormationType()))
+
+	for _, fieldName := range []string{"AWSCloudFormationDeletionPolicy", "AWSCloudFormationUpdateReplacePolicy"} {
+		field := v.Elem().FieldByName(fieldName)
+		if !field.IsValid() || field.Kind() != reflect.String {
+			return
+		}
+
+		if force || field.String() == "" {
+			field.SetString(string(policy))
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/awslabs/goformation/v6/cloudformation/policies"
+	gords "github.com/awslabs/goformation/v6/cloudformation/rds"
+	gos3 "github.com/awslabs/goformation/v6/cloudformation/s3"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudStageRetentionConfig_MustValidate(t *testing.T) {
+	require.NotPanics(t, func() {
+		(&CloudStageRetentionConfig{
+			ResourceTypes: map[string]CloudRetentionPolicy{
+				"AWS::S3::Bucket":     CloudRetentionPolicyDelete,
+				"AWS::RDS::DBCluster": CloudRetentionPolicySnapshot,
+			},
+			Plugins: map[string]map[string]CloudRetentionPolicy{
+				"bucket.b": {"AWS::S3::Bucket": CloudRetentionPolicyRetain},
+			},
+		}).MustValidate()
+	})
+
+	require.Panics(t, func() {
+		(&CloudStageRetentionConfig{
+			ResourceTypes: map[string]CloudRetentionPolicy{"AWS::S3::Bucket": CloudRetentionPolicySnapshot},
+		}).MustValidate()
+	})
+
+	require.Panics(t, func() {
+		(&CloudStageRetentionConfig{
+			Plugins: map[string]map[string]CloudRetentionPolicy{
+				"bucket.b": {"AWS::S3::Bucket": "Unknown"},
+			},
+		}).MustValidate()
+	})
+}
+
+func TestGetCloudRetentionPolicyOverride(t *testing.T) {
+	cfg := &CloudStageRetentionConfig{
+		ResourceTypes: map[string]CloudRetentionPolicy{"AWS::S3::Bucket": CloudRetentionPolicyDelete},
+		Plugins: map[string]map[string]CloudRetentionPolicy{
+			"bucket.b": {"AWS::S3::Bucket": CloudRetentionPolicyRetain},
+		},
+	}
+
+	policy, ok := getCloudRetentionPolicyOverride(cfg, "bucket.b", "AWS::S3::Bucket")
+	require.True(t, ok)
+	require.Equal(t, CloudRetentionPolicyRetain, policy)
+
+	policy, ok = getCloudRetentionPolicyOverride(cfg, "bucket.c", "AWS::S3::Bucket")
+	require.True(t, ok)
+	require.Equal(t, CloudRetentionPolicyDelete, policy)
+
+	_, ok = getCloudRetentionPolicyOverride(cfg, "bucket.b", "AWS::Logs::LogGroup")
+	require.False(t, ok)
+
+	_, ok = getCloudRetentionPolicyOverride(nil, "bucket.b", "AWS::S3::Bucket")
+	require.False(t, ok)
+}
+
+func TestGetCloudDefaultRetentionPolicy(t *testing.T) {
+	policy, ok := getCloudDefaultRetentionPolicy(&gos3.Bucket{})
+	require.True(t, ok)
+	require.Equal(t, CloudRetentionPolicyRetain, policy)
+
+	policy, ok = getCloudDefaultRetentionPolicy(&gords.DBInstance{})
+	require.True(t, ok)
+	require.Equal(t, CloudRetentionPolicySnapshot, policy)
+
+	policy, ok = getCloudDefaultRetentionPolicy(&gords.DBInstance{DBClusterIdentifier: stringz.Ptr("c")})
+	require.True(t, ok)
+	require.Equal(t, CloudRetentionPolicyDelete, policy)
+
+	_, ok = getCloudDefaultRetentionPolicy(&gords.DBSubnetGroup{})
+	require.False(t, ok)
+}
+
+func TestSetCloudRetentionPolicy(t *testing.T) {
+	bucket := &gos3.Bucket{}
+	setCloudRetentionPolicy(bucket, CloudRetentionPolicyRetain, false)
+	require.Equal(t, policies.DeletionPolicy("Retain"), bucket.AWSCloudFormationDeletionPolicy)
+	require.Equal(t, policies.UpdateReplacePolicy("Retain"), bucket.AWSCloudFormationUpdateReplacePolicy)
+
+	bucket = &gos3.Bucket{AWSCloudFormationDeletionPolicy: "Delete"}
+	setCloudRetentionPolicy(bucket, CloudRetentionPolicyRetain, false)
+	require.Equal(t, policies.DeletionPolicy("Delete"), bucket.AWSCloudFormationDeletionPolicy)
+	require.Equal(t, policies.UpdateReplacePolicy("Retain"), bucket.AWSCloudFormationUpdateReplacePolicy)
+
+	setCloudRetentionPolicy(bucket, CloudRetentionPolicyRetain, true)
+	require.Equal(t, policies.DeletionPolicy("Retain"), bucket.AWSCloudFormationDeletionPolicy)
+	require.Equal(t, policies.UpdateReplacePolicy("Retain"), bucket.AWSCloudFormationUpdateReplacePolicy)
+}
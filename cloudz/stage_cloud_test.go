@@ -0,0 +1,140 @@
+package cloudz
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCloudOfflineStack(t *testing.T) {
+	testCases := []struct {
+		name            string
+		templateBody    string
+		expectedOutputs map[string]string
+		expectedExports map[string]string
+	}{
+		{
+			name:            "no outputs",
+			templateBody:    `{"Resources": {}}`,
+			expectedOutputs: map[string]string{},
+			expectedExports: map[string]string{},
+		},
+		{
+			name: "literal output",
+			templateBody: `{
+				"Outputs": {
+					"BucketName": {
+						"Value": "my-bucket",
+						"Export": {"Name": "stack-bucket-name"}
+					}
+				}
+			}`,
+			expectedOutputs: map[string]string{
+				"BucketName": "my-bucket",
+			},
+			expectedExports: map[string]string{
+				"BucketName": "stack-bucket-name",
+			},
+		},
+		{
+			name: "computed outputs",
+			templateBody: `{
+				"Outputs": {
+					"DbExpEndpointAddress": {
+						"Value": {"Fn::GetAtt": ["Db", "Endpoint.Address"]}
+					},
+					"DbExpEndpointPort": {
+						"Value": {"Fn::GetAtt": ["Db", "Endpoint.Port"]}
+					},
+					"DbExpRefRef": {
+						"Value": {"Ref": "Db"},
+						"Export": {"Name": {"Fn::Sub": "${AWS::StackName}-db"}}
+					}
+				}
+			}`,
+			expectedOutputs: map[string]string{
+				"DbExpEndpointAddress": "offline-dbexpendpointaddress",
+				"DbExpEndpointPort":    "0",
+				"DbExpRefRef":          "offline-dbexprefref",
+			},
+			expectedExports: map[string]string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			stack := newCloudOfflineStack("stack", []byte(testCase.templateBody))
+			require.Equal(t, "stack", aws.ToString(stack.StackName))
+
+			outputs := map[string]string{}
+			exports := map[string]string{}
+
+			for _, output := range stack.Outputs {
+				outputs[aws.ToString(output.OutputKey)] = aws.ToString(output.OutputValue)
+
+				if output.ExportName != nil {
+					exports[aws.ToString(output.OutputKey)] = aws.ToString(output.ExportName)
+				}
+			}
+
+			require.Equal(t, testCase.expectedOutputs, outputs)
+			require.Equal(t, testCase.expectedExports, exports)
+		})
+	}
+}
+
+func TestNewCloudOfflineStack_Exports(t *testing.T) {
+	ref := CloudRef("lb")
+	att := CloudAtt("DNSName")
+
+	stack := newCloudOfflineStack("stack", []byte(`{
+		"Outputs": {
+			"`+ref.ExpRefRef()+`": {"Value": {"Ref": "`+ref.Ref()+`"}},
+			"`+ref.ExpAttRef(att)+`": {"Value": "example.com"}
+		}
+	}`))
+
+	exports := NewCloudExports(stack)
+	require.Equal(t, "offline-"+strings.ToLower(ref.ExpRefRef()), exports.GetRef(ref))
+	require.Equal(t, "example.com", exports.GetAtt(ref, att))
+	require.Panics(t, func() { exports.GetRef(CloudRef("other")) })
+}
+
+func TestNewCloudOfflineStack_InvalidTemplate(t *testing.T) {
+	require.Panics(t, func() { newCloudOfflineStack("stack", []byte("{")) })
+}
+
+func TestCloudOfflineExports(t *testing.T) {
+	ref := CloudRef("db")
+	addressAtt := CloudAtt("Endpoint.Address")
+	portAtt := CloudAtt("Endpoint.Port")
+
+	expected := NewCloudExports(newCloudOfflineStack("stack", []byte(`{
+		"Outputs": {
+			"`+ref.ExpRefRef()+`": {"Value": {"Ref": "`+ref.Ref()+`"}},
+			"`+ref.ExpAttRef(addressAtt)+`": {"Value": {"Fn::GetAtt": ["`+ref.Ref()+`", "Endpoint.Address"]}},
+			"`+ref.ExpAttRef(portAtt)+`": {"Value": {"Fn::GetAtt": ["`+ref.Ref()+`", "Endpoint.Port"]}}
+		}
+	}`)))
+
+	exports := &cloudOfflineExports{}
+	require.Equal(t, expected.GetRef(ref), exports.GetRef(ref))
+	require.Equal(t, expected.GetAtt(ref, addressAtt), exports.GetAtt(ref, addressAtt))
+	require.Equal(t, "0", exports.GetAtt(ref, portAtt))
+}
+
+func TestGetCloudDestroyPlugins(t *testing.T) {
+	network := &networkImpl{cloudMetadata: &NetworkCloudMetadata{}}
+	bucket := &bucketImpl{cloudMetadata: &BucketCloudMetadata{BucketName: "bucket"}}
+	undeployedBucket := &bucketImpl{}
+	cronJob := &cronJobImpl{cloudMetadata: &CronJobCloudMetadata{}}
+
+	require.Equal(t,
+		[]Plugin{cronJob, bucket, network},
+		getCloudDestroyPlugins([][]Plugin{{network}, {bucket, undeployedBucket}, {cronJob}}))
+	require.Empty(t, getCloudDestroyPlugins([][]Plugin{{undeployedBucket}}))
+}
@@ -0,0 +1,121 @@
+package cloudz
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/jsonz"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// EnvFormat describes an environment file format.
+type EnvFormat string
+
+// MustValidate validates the EnvFormat.
+func (f EnvFormat) MustValidate() {
+	errorz.Assertf(f == DotEnv || f == JSONEnv, "invalid env format")
+}
+
+// String implements the fmt.Stringer interface.
+func (f EnvFormat) String() string {
+	return string(f)
+}
+
+// Known env formats.
+const (
+	DotEnv  EnvFormat = "dotenv"
+	JSONEnv EnvFormat = "json"
+)
+
+// EnvExportConfig describes how to export the environment of a Stage.
+// Each value is also exported once per prefix (e.g. "REACT_APP_" for Create React App builds).
+// Secret metadata values are only exported if IncludeSecrets is set, and never with prefixes.
+type EnvExportConfig struct {
+	Prefixes       []string
+	Values         map[string]string
+	IncludeSecrets bool
+}
+
+// GetStageEnv returns the environment of the given stage, built from the public metadata values of all plugins (e.g.
+// "HASURA_URL" or "POSTGRES_PROXY_URL") plus the values and prefixes in StageConfig.EnvExport, if any.
+// See GetPublicMetadataValues and EnvExportConfig.IncludeSecrets.
+func GetStageEnv(stage Stage) map[string]string {
+	exportCfg := stage.GetConfig().EnvExport
+	return getStageEnv(stage, exportCfg != nil && exportCfg.IncludeSecrets)
+}
+
+func getStageEnv(stage Stage, includeSecrets bool) map[string]string {
+	env := map[string]string{
+		"STAGE_NAME":   stage.GetName(),
+		"STAGE_TARGET": stage.GetTarget().String(),
+		"STAGE_MODE":   stage.GetMode().String(),
+	}
+
+	for _, pluginGroup := range stage.GetConfig().App.GetSortedPlugins() {
+		for _, plugin := range pluginGroup {
+			for _, value := range GetPublicMetadataValues(plugin) {
+				env[getStageEnvKey(plugin, value)] = value.Value
+			}
+		}
+	}
+
+	if exportCfg := stage.GetConfig().EnvExport; exportCfg != nil {
+		for k, v := range exportCfg.Values {
+			env[k] = v
+		}
+
+		prefixedEnv := make(map[string]string, len(env)*(len(exportCfg.Prefixes)+1))
+		for k, v := range env {
+			prefixedEnv[k] = v
+
+			for _, prefix := range exportCfg.Prefixes {
+				prefixedEnv[prefix+k] = v
+			}
+		}
+		env = prefixedEnv
+	}
+
+	if includeSecrets {
+		for _, pluginGroup := range stage.GetConfig().App.GetSortedPlugins() {
+			for _, plugin := range pluginGroup {
+				for _, value := range GetMetadataValues(plugin) {
+					if value.IsSecret {
+						env[getStageEnvKey(plugin, value)] = value.Value
+					}
+				}
+			}
+		}
+	}
+
+	return env
+}
+
+func getStageEnvKey(p Plugin, value *MetadataValue) string {
+	return strcase.ToScreamingSnake(GetMetadataKey(p) + "-" + value.Name)
+}
+
+func exportStageEnv(stage Stage, outFilePath string, format EnvFormat) {
+	format.MustValidate()
+	env := GetStageEnv(stage)
+
+	switch format {
+	case DotEnv:
+		keys := make([]string, 0, len(env))
+		for k := range env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		lines := make([]string, 0, len(keys))
+		for _, k := range keys {
+			lines = append(lines, fmt.Sprintf("%v=%q", k, env[k]))
+		}
+
+		filez.MustWriteFile(outFilePath, 0777, 0666, []byte(strings.Join(lines, "\n")+"\n"))
+	case JSONEnv:
+		filez.MustWriteFile(outFilePath, 0777, 0666, jsonz.MustMarshalIndentDefault(env))
+	}
+}
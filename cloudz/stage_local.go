@@ -2,11 +2,11 @@ package cloudz
 
 import (
 	"bytes"
+	"context"
 	"os"
 
 	dctypes "github.com/docker/cli/cli/compose/types"
 	"github.com/ibrt/golang-errors/errorz"
-	"github.com/ibrt/golang-shell/shellz"
 	"github.com/ibrt/golang-validation/vz"
 	"gopkg.in/yaml.v3"
 )
@@ -27,7 +27,11 @@ type LocalStage interface {
 	GetLocalConfig() *LocalStageConfig
 	GetServiceNetworkConfig() map[string]*dctypes.ServiceNetworkConfig
 	Create()
+	TryCreate(ctx context.Context) error
 	Destroy()
+	Snapshot(outFilePath string)
+	Restore(filePath string)
+	ExportEnv(outFilePath string, format EnvFormat)
 }
 
 type localStageImpl struct {
@@ -120,11 +124,19 @@ func (s *localStageImpl) Create() {
 
 	for _, pluginGroup := range s.cfg.App.GetSortedPlugins() {
 		for _, plugin := range pluginGroup {
+			errorz.MaybeMustWrap(s.cfg.App.GetOperations().GetContext().Err()) // stop between hooks if canceled
 			plugin.EventHook(LocalAfterCreateEvent, s.cfg.App.GetConfig().GetBuildDirPathForPlugin(plugin))
 		}
 	}
 }
 
+// TryCreate implements the LocalStage interface.
+// It is like Create, but bounded by the given context instead of the app context, and returns an error instead of
+// panicking (see opz.Try).
+func (s *localStageImpl) TryCreate(ctx context.Context) error {
+	return tryWithContext(s.cfg.App, ctx, s.Create)
+}
+
 // Destroy implements the LocalStage interface.
 func (s *localStageImpl) Destroy() {
 	for _, svc := range s.localTemplate.Services {
@@ -137,11 +149,16 @@ func (s *localStageImpl) Destroy() {
 	s.runCmd("down", "-v", "--remove-orphans")
 }
 
+// ExportEnv implements the LocalStage interface.
+func (s *localStageImpl) ExportEnv(outFilePath string, format EnvFormat) {
+	exportStageEnv(s, outFilePath, format)
+}
+
 func (s *localStageImpl) runCmd(params ...interface{}) {
 	rawTpl, err := yaml.Marshal(s.localTemplate)
 	errorz.MaybeMustWrap(err)
 
-	shellz.NewCommand("docker-compose").
+	s.cfg.App.GetOperations().NewCommand("docker-compose").
 		AddParams("-p", s.cfg.App.GetConfig().Name).
 		AddParams("-f", "-").
 		AddParams(params...).
@@ -0,0 +1,161 @@
+package cloudz
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// localSnapshotter is implemented by plugins whose local containers hold data that is included in the snapshots of the
+// local stage (see LocalStage.Snapshot). Each plugin reads and writes its data in its own dir.
+type localSnapshotter interface {
+	snapshotLocal(dirPath string)
+	restoreLocal(dirPath string)
+}
+
+// Snapshot implements the LocalStage interface.
+// It dumps the data of the local containers of the plugins that support it (e.g. Postgres data, Bucket objects, Hasura
+// metadata) to a single ".tar.gz" archive, which can be restored with Restore on another machine, e.g. to give new team
+// members a seeded environment. The local stage must be running (see Create).
+func (s *localStageImpl) Snapshot(outFilePath string) {
+	dirPath, err := os.MkdirTemp("", "snapshot-")
+	errorz.MaybeMustWrap(err)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+
+	for _, pluginGroup := range s.cfg.App.GetSortedPlugins() {
+		for _, plugin := range pluginGroup {
+			if snapshotter, ok := plugin.(localSnapshotter); ok {
+				pluginDirPath := filepath.Join(dirPath, GetMetadataKey(plugin))
+				errorz.MaybeMustWrap(os.MkdirAll(pluginDirPath, 0777))
+				snapshotter.snapshotLocal(pluginDirPath)
+			}
+		}
+	}
+
+	mustWriteLocalSnapshotArchive(dirPath, outFilePath)
+}
+
+// Restore implements the LocalStage interface.
+// It restores a snapshot created by Snapshot, in dependency order (e.g. Postgres data before Hasura metadata), replacing
+// the existing data. Plugins not in the snapshot are left unchanged. The local stage must be running (see Create).
+func (s *localStageImpl) Restore(filePath string) {
+	dirPath, err := os.MkdirTemp("", "snapshot-")
+	errorz.MaybeMustWrap(err)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+
+	mustReadLocalSnapshotArchive(filePath, dirPath)
+
+	for _, pluginGroup := range s.cfg.App.GetSortedPlugins() {
+		for _, plugin := range pluginGroup {
+			if snapshotter, ok := plugin.(localSnapshotter); ok {
+				pluginDirPath := filepath.Join(dirPath, GetMetadataKey(plugin))
+				if _, err := os.Stat(pluginDirPath); err == nil {
+					snapshotter.restoreLocal(pluginDirPath)
+				}
+			}
+		}
+	}
+}
+
+func mustWriteLocalSnapshotArchive(dirPath, outFilePath string) {
+	errorz.MaybeMustWrap(os.MkdirAll(filepath.Dir(outFilePath), 0777))
+
+	f, err := os.Create(outFilePath)
+	errorz.MaybeMustWrap(err)
+	defer errorz.IgnoreClose(f)
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	errorz.MaybeMustWrap(filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == dirPath {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return err
+		}
+
+		header.Name = filepath.ToSlash(relPath)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer errorz.IgnoreClose(src)
+
+		_, err = io.Copy(tw, src)
+		return err
+	}))
+
+	errorz.MaybeMustWrap(tw.Close())
+	errorz.MaybeMustWrap(gw.Close())
+	errorz.MaybeMustWrap(f.Close())
+}
+
+func mustReadLocalSnapshotArchive(filePath, dirPath string) {
+	f, err := os.Open(filePath)
+	errorz.MaybeMustWrap(err)
+	defer errorz.IgnoreClose(f)
+
+	gr, err := gzip.NewReader(f)
+	errorz.MaybeMustWrap(err)
+	defer errorz.IgnoreClose(gr)
+
+	tr := tar.NewReader(gr)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return
+		}
+		errorz.MaybeMustWrap(err)
+
+		path := filepath.Join(dirPath, filepath.FromSlash(header.Name))
+		errorz.Assertf(strings.HasPrefix(path, filepath.Clean(dirPath)+string(os.PathSeparator)),
+			"invalid snapshot entry: %v", errorz.A(header.Name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			errorz.MaybeMustWrap(os.MkdirAll(path, 0777))
+		case tar.TypeReg:
+			mustExtractLocalSnapshotFile(tr, path)
+		default:
+			panic(errorz.Errorf("invalid snapshot entry type: %v", errorz.A(header.Name)))
+		}
+	}
+}
+
+func mustExtractLocalSnapshotFile(r io.Reader, path string) {
+	errorz.MaybeMustWrap(os.MkdirAll(filepath.Dir(path), 0777))
+
+	f, err := os.Create(path)
+	errorz.MaybeMustWrap(err)
+	defer errorz.IgnoreClose(f)
+
+	_, err = io.Copy(f, r)
+	errorz.MaybeMustWrap(err)
+	errorz.MaybeMustWrap(f.Close())
+}
@@ -0,0 +1,46 @@
+package cloudz
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalSnapshotArchive(t *testing.T) {
+	srcDirPath := t.TempDir()
+	filez.MustWriteFile(filepath.Join(srcDirPath, "postgres", "postgres.sql"), 0777, 0666, []byte("SELECT 1;"))
+	filez.MustWriteFile(filepath.Join(srcDirPath, "bucket-b", "objects", "a", "b.txt"), 0777, 0666, []byte("b"))
+	require.NoError(t, os.MkdirAll(filepath.Join(srcDirPath, "bucket-c", "objects"), 0777))
+
+	archiveFilePath := filepath.Join(t.TempDir(), "snapshots", "snapshot.tar.gz")
+	mustWriteLocalSnapshotArchive(srcDirPath, archiveFilePath)
+
+	dstDirPath := t.TempDir()
+	mustReadLocalSnapshotArchive(archiveFilePath, dstDirPath)
+
+	require.Equal(t, []byte("SELECT 1;"), filez.MustReadFile(filepath.Join(dstDirPath, "postgres", "postgres.sql")))
+	require.Equal(t, []byte("b"), filez.MustReadFile(filepath.Join(dstDirPath, "bucket-b", "objects", "a", "b.txt")))
+	require.DirExists(t, filepath.Join(dstDirPath, "bucket-c", "objects"))
+}
+
+func TestLocalSnapshotArchive_InvalidEntry(t *testing.T) {
+	archiveFilePath := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+
+	f, err := os.Create(archiveFilePath)
+	require.NoError(t, err)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "../escape.txt", Typeflag: tar.TypeReg, Mode: 0666}))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	require.NoError(t, f.Close())
+
+	require.Panics(t, func() {
+		mustReadLocalSnapshotArchive(archiveFilePath, t.TempDir())
+	})
+}
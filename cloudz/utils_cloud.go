@@ -1,6 +1,9 @@
 package cloudz
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -9,10 +12,31 @@ import (
 	goecs "github.com/awslabs/goformation/v6/cloudformation/ecs"
 	gotags "github.com/awslabs/goformation/v6/cloudformation/tags"
 	"github.com/iancoleman/strcase"
+	"github.com/ibrt/golang-bites/jsonz"
 	"github.com/ibrt/golang-bites/stringz"
 	"github.com/ibrt/golang-errors/errorz"
 )
 
+// Tags set on all plugin stacks (see CloudGetStackTags).
+const (
+	CloudStackTagApp    = "App"
+	CloudStackTagStage  = "Stage"
+	CloudStackTagPlugin = "Plugin"
+)
+
+const (
+	cloudExportNameMaxLength  = 255
+	cloudExportNameHashLength = 8
+)
+
+var (
+	cloudReservedStackTags = map[string]struct{}{
+		CloudStackTagApp:    {},
+		CloudStackTagStage:  {},
+		CloudStackTagPlugin: {},
+	}
+)
+
 // CloudAtt describes a cloud attribute.
 type CloudAtt string
 
@@ -29,9 +53,9 @@ func (a CloudAtt) Ref() string {
 // CloudRef describes a cloud reference.
 type CloudRef string
 
-// Name returns a name.
+// Name returns a name (see NamingStrategy).
 func (r CloudRef) Name(p Plugin) string {
-	return fmt.Sprintf("%v-%v", CloudGetStackName(p), r)
+	return p.GetStage().GetConfig().App.GetConfig().GetNaming().GetResourceName(p, r)
 }
 
 // Ref returns a reference.
@@ -44,9 +68,9 @@ func (r CloudRef) ExpRefRef() string {
 	return (r + "-exp-ref").Ref()
 }
 
-// ExpRefName returns a name for a reference export.
+// ExpRefName returns a name for a reference export (see NamingStrategy).
 func (r CloudRef) ExpRefName(p Plugin) string {
-	return (r + "-exp-ref").Name(p)
+	return p.GetStage().GetConfig().App.GetConfig().GetNaming().GetExportName(p, fmt.Sprintf("%v-exp-ref", r))
 }
 
 // ExpAttRef returns a reference to an attribute export.
@@ -54,12 +78,32 @@ func (r CloudRef) ExpAttRef(att CloudAtt) string {
 	return (r + "-exp").Ref() + strings.ReplaceAll(att.Ref(), ".", "")
 }
 
-// ExpAttName returns a name for an attribute export.
+// ExpAttName returns a name for an attribute export (see NamingStrategy).
 func (r CloudRef) ExpAttName(p Plugin, att CloudAtt) string {
-	return (r + "-exp").Name(p) + "-" + att.Name()
+	return p.GetStage().GetConfig().App.GetConfig().GetNaming().GetExportName(p, fmt.Sprintf("%v-exp-%v", r, att.Name()))
+}
+
+// getCloudExportName returns the export name formed by the given prefix and suffix. If it exceeds the CloudFormation
+// length limit, the prefix is truncated and a stable hash of the full name is appended to it, so that the suffix (e.g.
+// cloudRecordSetExpRefNameSuffix) is preserved and the name is the same across deploys.
+func getCloudExportName(prefix, suffix string) string {
+	name := prefix + suffix
+	if len(name) <= cloudExportNameMaxLength {
+		return name
+	}
+
+	h := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(h[:])[:cloudExportNameHashLength]
+	prefixLength := cloudExportNameMaxLength - len(suffix) - len(hash) - 1
+	errorz.Assertf(prefixLength > 0, "export name suffix too long: %v", errorz.A(suffix))
+
+	return prefix[:prefixLength] + "-" + hash + suffix
 }
 
-// CloudExports describes a set of cloud exports.
+// CloudExports describes a set of cloud exports. Typed accessors for the exports of each plugin are generated on its
+// cloud metadata type (e.g. HasuraCloudMetadata.ServiceName()).
+//
+//go:generate go run ./internal/cmd/gen-metadata-accessors . metadata_accessors.go
 type CloudExports interface {
 	GetRef(ref CloudRef) string
 	GetAtt(ref CloudRef, att CloudAtt) string
@@ -102,6 +146,32 @@ func (e *cloudExports) GetAtt(ref CloudRef, att CloudAtt) string {
 	panic(errorz.Errorf("no such export: att %v for ref %v", errorz.A(att, ref)))
 }
 
+// ExternalStackExports returns the exports of a stack not managed by the given stage, e.g. a shared network stack
+// deployed by another app. The stack must be in the same account and region. If offline, placeholder values are returned.
+func ExternalStackExports(stage Stage, stackName string) CloudExports {
+	ops := stage.GetConfig().App.GetOperations()
+
+	if ops.IsOffline() {
+		return &cloudOfflineExports{}
+	}
+
+	stack := ops.DescribeStack(stackName)
+	errorz.Assertf(stack != nil, "external stack not found: %v", errorz.A(stackName))
+	return NewCloudExports(stack)
+}
+
+type cloudOfflineExports struct{}
+
+// GetRef gets the value of a reference export.
+func (*cloudOfflineExports) GetRef(ref CloudRef) string {
+	return getCloudOfflineOutputValue(ref.ExpRefRef())
+}
+
+// GetAtt gets the value of an attribute export.
+func (*cloudOfflineExports) GetAtt(ref CloudRef, att CloudAtt) string {
+	return getCloudOfflineOutputValue(ref.ExpAttRef(att))
+}
+
 // NewAssumeRolePolicyDocument generates a new assume role policy document.
 func NewAssumeRolePolicyDocument(service string) interface{} {
 	return map[string]interface{}{
@@ -133,9 +203,10 @@ func NewPolicyDocument(statements ...*PolicyStatement) interface{} {
 
 // PolicyStatement describes a policy statement.
 type PolicyStatement struct {
-	Actions   []string
-	Resources []string
-	Principal interface{}
+	Actions    []string
+	Resources  []string
+	Principal  interface{}
+	Conditions map[string]map[string][]string
 }
 
 // NewPolicyStatement initializes a new PolicyStatement.
@@ -158,6 +229,18 @@ func (s *PolicyStatement) AddResources(resources ...string) *PolicyStatement {
 	return s
 }
 
+// AddCondition adds a condition to the policy statement, e.g. AddCondition("ArnEquals", "aws:SourceArn", arn).
+func (s *PolicyStatement) AddCondition(operator, key string, values ...string) *PolicyStatement {
+	if s.Conditions == nil {
+		s.Conditions = map[string]map[string][]string{}
+	}
+	if s.Conditions[operator] == nil {
+		s.Conditions[operator] = map[string][]string{}
+	}
+	s.Conditions[operator][key] = append(s.Conditions[operator][key], values...)
+	return s
+}
+
 // SetCurrentRootAccountPrincipal sets the current root account as principal on the policy statement.
 func (s *PolicyStatement) SetCurrentRootAccountPrincipal() *PolicyStatement {
 	s.Principal = map[string]interface{}{
@@ -180,6 +263,15 @@ func (s *PolicyStatement) SetServicePrincipal(service string) *PolicyStatement {
 	return s
 }
 
+// SetCanonicalUserPrincipal sets a canonical user (e.g. a CloudFront origin access identity) as principal on the policy
+// statement.
+func (s *PolicyStatement) SetCanonicalUserPrincipal(canonicalUserID string) *PolicyStatement {
+	s.Principal = map[string]interface{}{
+		"CanonicalUser": canonicalUserID,
+	}
+	return s
+}
+
 // SetAnyRootAccountPrincipal sets any root account as principal on the policy statement.
 func (s *PolicyStatement) SetAnyRootAccountPrincipal() *PolicyStatement {
 	s.Principal = map[string]interface{}{
@@ -203,6 +295,10 @@ func (s *PolicyStatement) Build() interface{} {
 		m["Principal"] = s.Principal
 	}
 
+	if len(s.Conditions) > 0 {
+		m["Condition"] = s.Conditions
+	}
+
 	return m
 }
 
@@ -228,6 +324,27 @@ func CloudGetTaskDefinitionKeyValuePairs(m map[string]string) *[]goecs.TaskDefin
 	return &kvs
 }
 
+// CloudNewCustomResource converts the given resource to an equivalent CustomResource, so that properties which are not
+// yet modeled by goformation can be set on it. Only the type, properties, and dependencies are preserved.
+func CloudNewCustomResource(r gocf.Resource) *gocf.CustomResource {
+	raw := &struct {
+		Type       string
+		Properties map[string]interface{}
+		DependsOn  []string
+	}{}
+	errorz.MaybeMustWrap(json.Unmarshal(jsonz.MustMarshal(r), raw))
+
+	if raw.Properties == nil {
+		raw.Properties = map[string]interface{}{}
+	}
+
+	return &gocf.CustomResource{
+		Type:                       raw.Type,
+		Properties:                 raw.Properties,
+		AWSCloudFormationDependsOn: raw.DependsOn,
+	}
+}
+
 // CloudAddExpRef adds a reference export to the given template.
 func CloudAddExpRef(tpl *gocf.Template, p Plugin, ref CloudRef) {
 	tpl.Outputs[ref.ExpRefRef()] = gocf.Output{
@@ -248,17 +365,32 @@ func CloudAddExpGetAtt(tpl *gocf.Template, p Plugin, ref CloudRef, att CloudAtt)
 	}
 }
 
-// CloudGetStackName generates a stack name for the given plugin.
-func CloudGetStackName(p Plugin) string {
-	parts := []string{
-		p.GetStage().GetConfig().App.GetConfig().Name,
-		p.GetStage().GetName(),
-		p.GetName(),
-	}
+// CloudGetStackTags returns the tags of the stack of the given plugin, which CloudFormation propagates to the resources
+// that support tagging. Besides the CloudStageConfig.CostAllocationTags, it includes the "App", "Stage", and "Plugin"
+// (see GetMetadataKey) tags, which can be activated as cost allocation tags to break down costs (see Budget).
+func CloudGetStackTags(p Plugin) map[string]string {
+	tags := map[string]string{}
 
-	if instanceName := p.GetInstanceName(); instanceName != nil {
-		parts = append(parts, *instanceName)
+	for k, v := range p.GetStage().AsCloudStage().GetCloudConfig().CostAllocationTags {
+		tags[k] = v
 	}
 
-	return strings.Join(parts, "-")
+	tags[CloudStackTagApp] = p.GetStage().GetConfig().App.GetConfig().Name
+	tags[CloudStackTagStage] = p.GetStage().GetName()
+	tags[CloudStackTagPlugin] = GetMetadataKey(p)
+	return tags
+}
+
+// CloudGetStackName generates a stack name for the given plugin (see NamingStrategy).
+func CloudGetStackName(p Plugin) string {
+	return p.GetStage().GetConfig().App.GetConfig().GetNaming().GetStackName(p)
+}
+
+// getCloudStackNameFromID returns the stack name from a stack ID, i.e.
+// "arn:aws:cloudformation:<region>:<account>:stack/<name>/<uuid>".
+func getCloudStackNameFromID(stackID string) string {
+	if parts := strings.Split(stackID, "/"); len(parts) == 3 {
+		return parts[1]
+	}
+	return stackID
 }
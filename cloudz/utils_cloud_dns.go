@@ -0,0 +1,228 @@
+package cloudz
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goroute53 "github.com/awslabs/goformation/v6/cloudformation/route53"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// Known record set failover values.
+const (
+	RecordSetFailoverPrimary   = "PRIMARY"
+	RecordSetFailoverSecondary = "SECONDARY"
+)
+
+const (
+	cloudRecordSetExpRefNameSuffix = "-rs-exp-ref"
+)
+
+// RecordSetRoutingConfig describes the routing policy for a Route53 record set.
+// Exactly one of Weight, Region, and Failover must be set.
+type RecordSetRoutingConfig struct {
+	SetIdentifier string  `validate:"required"`
+	Weight        *int    `validate:"omitempty,min=0,max=255"`
+	Region        *string `validate:"omitempty,min=1"`
+	Failover      *string `validate:"omitempty,oneof=PRIMARY SECONDARY"`
+	HealthCheck   *RecordSetHealthCheckConfig
+}
+
+// MustValidate validates the record set routing config.
+func (c *RecordSetRoutingConfig) MustValidate() {
+	vz.MustValidateStruct(c)
+
+	numPolicies := 0
+	for _, isSet := range []bool{c.Weight != nil, c.Region != nil, c.Failover != nil} {
+		if isSet {
+			numPolicies++
+		}
+	}
+
+	errorz.Assertf(numPolicies == 1, "exactly one of RecordSetRoutingConfig.Weight, Region, Failover must be set")
+	errorz.Assertf(c.Failover == nil || *c.Failover != RecordSetFailoverPrimary || c.HealthCheck != nil, "primary failover record set requires RecordSetRoutingConfig.HealthCheck")
+}
+
+// RecordSetHealthCheckConfig describes the health check associated with a Route53 record set. Note that the health check
+// requests are sent to the endpoint of the stack with its own DNS name as host (e.g. the load balancer DNS name), so the
+// resource path must be served regardless of the host.
+type RecordSetHealthCheckConfig struct {
+	ResourcePath     string `validate:"required,startswith=/"`
+	IntervalSeconds  int    `validate:"omitempty,oneof=10 30"`
+	FailureThreshold int    `validate:"omitempty,min=1,max=10"`
+}
+
+// CloudApplyRecordSetRouting applies the routing config to the given record set, adding a health check if configured.
+// It does nothing if the routing config is nil, i.e. for simple routing. The health check targets the endpoint of this
+// stack (i.e. the DNS name of the alias target, if any) rather than the record set name, which is resolved according to
+// the routing policy and might point to another stack.
+func CloudApplyRecordSetRouting(tpl *gocf.Template, p Plugin, healthCheckRef CloudRef, recordSet *goroute53.RecordSet, cfg *RecordSetRoutingConfig) {
+	if cfg == nil {
+		return
+	}
+
+	recordSet.SetIdentifier = stringz.Ptr(cfg.SetIdentifier)
+	recordSet.Weight = cfg.Weight
+	recordSet.Region = cfg.Region
+	recordSet.Failover = cfg.Failover
+
+	if cfg.HealthCheck == nil {
+		return
+	}
+
+	fqdn := recordSet.Name
+	if recordSet.AliasTarget != nil {
+		fqdn = recordSet.AliasTarget.DNSName
+	}
+
+	tpl.Resources[healthCheckRef.Ref()] = &goroute53.HealthCheck{
+		HealthCheckConfig: map[string]interface{}{
+			"Type":                     "HTTPS",
+			"FullyQualifiedDomainName": fqdn,
+			"Port":                     443,
+			"ResourcePath":             cfg.HealthCheck.ResourcePath,
+			"RequestInterval": func() int {
+				if cfg.HealthCheck.IntervalSeconds != 0 {
+					return cfg.HealthCheck.IntervalSeconds
+				}
+				return 30
+			}(),
+			"FailureThreshold": func() int {
+				if cfg.HealthCheck.FailureThreshold != 0 {
+					return cfg.HealthCheck.FailureThreshold
+				}
+				return 3
+			}(),
+		},
+		HealthCheckTags: &[]goroute53.HealthCheck_HealthCheckTag{
+			{
+				Key:   "Name",
+				Value: healthCheckRef.Name(p),
+			},
+		},
+	}
+	CloudAddExpRef(tpl, p, healthCheckRef)
+
+	recordSet.HealthCheckId = stringz.Ptr(gocf.Ref(healthCheckRef.Ref()))
+
+	if recordSet.AliasTarget != nil {
+		recordSet.AliasTarget.EvaluateTargetHealth = boolz.Ptr(true)
+	}
+}
+
+// CloudMustCheckHostedZone checks that the given hosted zone exists, contains the given domain name, and that its
+// delegation resolves to the name servers assigned by Route53. The delegation check resolves the zone name servers via
+// public DNS, it can be disabled with CloudStageConfig.SkipDNSDelegationCheck.
+func CloudMustCheckHostedZone(p Plugin, hostedZoneID, domainName string) {
+	errorz.Assertf(hostedZoneID != "", "domain %v requires a Route53 hosted zone: set the certificate hosted zone ID",
+		errorz.A(domainName), errorz.Prefix(p.GetName()))
+
+	hostedZone := p.GetStage().GetConfig().App.GetOperations().GetHostedZone(hostedZoneID)
+	errorz.Assertf(hostedZone != nil, "hosted zone %v does not exist: create it or fix the hosted zone ID",
+		errorz.A(hostedZoneID), errorz.Prefix(p.GetName()))
+
+	zoneName := strings.TrimSuffix(*hostedZone.HostedZone.Name, ".")
+	errorz.Assertf(domainName == zoneName || strings.HasSuffix(domainName, "."+zoneName), "domain %v does not belong to hosted zone %v (%v)",
+		errorz.A(domainName, hostedZoneID, zoneName), errorz.Prefix(p.GetName()))
+
+	if hostedZone.DelegationSet == nil {
+		return // private hosted zone
+	}
+
+	if p.GetStage().AsCloudStage().GetCloudConfig().SkipDNSDelegationCheck {
+		return
+	}
+
+	expectedNameServers := normalizeNameServers(hostedZone.DelegationSet.NameServers)
+	records, err := net.LookupNS(zoneName)
+	errorz.Assertf(err == nil && len(records) > 0, "delegation for %v does not resolve: add NS records for %v at the registrar or parent zone",
+		errorz.A(zoneName, expectedNameServers), errorz.Prefix(p.GetName()))
+
+	actualNameServers := make([]string, 0, len(records))
+	for _, record := range records {
+		actualNameServers = append(actualNameServers, record.Host)
+	}
+	actualNameServers = normalizeNameServers(actualNameServers)
+
+	errorz.Assertf(strings.Join(actualNameServers, ",") == strings.Join(expectedNameServers, ","), "%v is delegated to %v, but hosted zone %v uses %v: update the NS records at the registrar or parent zone",
+		errorz.A(zoneName, actualNameServers, hostedZoneID, expectedNameServers), errorz.Prefix(p.GetName()))
+}
+
+// CloudMustCheckDomainRecord runs the preflight checks for a domain name the plugin creates a DNS record for, using the
+// given DNS provider and routing config (nil for simple routing).
+func CloudMustCheckDomainRecord(p Plugin, dnsProvider DNSProvider, domainName string, routing *RecordSetRoutingConfig) {
+	dnsProvider.MustCheckDomain(p, domainName)
+
+	errorz.Assertf(routing == nil || dnsProvider.IsRoute53(), "routing for domain %v requires a Route53 DNS provider",
+		errorz.A(domainName), errorz.Prefix(p.GetName()))
+
+	if routing == nil {
+		// Note: record sets with a routing policy are allowed to share their domain with other stacks.
+		CloudMustCheckDomainNotClaimed(p, domainName)
+	}
+}
+
+// CloudMaybeUpsertDomainRecord creates or updates a CNAME record pointing the domain name to the given target, unless the
+// DNS provider is Route53, in which case the record set is managed in the plugin stack.
+func CloudMaybeUpsertDomainRecord(p Plugin, dnsProvider DNSProvider, domainName, target string) {
+	if dnsProvider.IsRoute53() {
+		return
+	}
+
+	dnsProvider.UpsertRecord(p, newCloudDomainRecord(domainName, target))
+}
+
+// CloudMaybeDeleteDomainRecord deletes the record created by CloudMaybeUpsertDomainRecord, unless the DNS provider is
+// Route53, in which case the record set is deleted with the plugin stack.
+func CloudMaybeDeleteDomainRecord(p Plugin, dnsProvider DNSProvider, domainName, target string) {
+	if dnsProvider.IsRoute53() {
+		return
+	}
+
+	dnsProvider.DeleteRecord(p, newCloudDomainRecord(domainName, target))
+}
+
+func newCloudDomainRecord(domainName, target string) *DNSRecord {
+	return &DNSRecord{
+		Name:  domainName,
+		Type:  DNSRecordTypeCNAME,
+		Value: target,
+		TTL:   300,
+	}
+}
+
+// CloudMustCheckDomainNotClaimed checks that the given domain name is not exported by a stack other than the plugin's.
+// Only the record set exports are considered, i.e. the reference exports of the "rs" record sets created by plugins for
+// their domain names (e.g. APIRefRecordSet), whose value is the domain name.
+func CloudMustCheckDomainNotClaimed(p Plugin, domainName string) {
+	ops := p.GetStage().GetConfig().App.GetOperations()
+
+	stackID := ""
+	if stack := ops.DescribeStack(CloudGetStackName(p)); stack != nil {
+		stackID = *stack.StackId
+	}
+
+	for _, export := range ops.ListStackExports() {
+		if export.Name == nil || !strings.HasSuffix(*export.Name, cloudRecordSetExpRefNameSuffix) ||
+			export.Value == nil || strings.TrimSuffix(*export.Value, ".") != domainName || *export.ExportingStackId == stackID {
+			continue
+		}
+
+		panic(errorz.Errorf("domain %v is already claimed by export %v of stack %v: remove it from the other stack or use a different domain",
+			errorz.A(domainName, *export.Name, *export.ExportingStackId), errorz.Prefix(p.GetName())))
+	}
+}
+
+func normalizeNameServers(nameServers []string) []string {
+	normalized := make([]string, 0, len(nameServers))
+	for _, nameServer := range nameServers {
+		normalized = append(normalized, strings.ToLower(strings.TrimSuffix(nameServer, ".")))
+	}
+	sort.Strings(normalized)
+	return normalized
+}
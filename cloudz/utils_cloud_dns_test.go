@@ -0,0 +1,154 @@
+package cloudz
+
+import (
+	"testing"
+
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goroute53 "github.com/awslabs/goformation/v6/cloudformation/route53"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordSetRoutingConfig_MustValidate(t *testing.T) {
+	weight := 10
+	invalidWeight := 256
+
+	testCases := []struct {
+		name    string
+		cfg     *RecordSetRoutingConfig
+		isValid bool
+	}{
+		{
+			name: "weighted",
+			cfg: &RecordSetRoutingConfig{
+				SetIdentifier: "blue",
+				Weight:        &weight,
+			},
+			isValid: true,
+		},
+		{
+			name: "latency",
+			cfg: &RecordSetRoutingConfig{
+				SetIdentifier: "us-east-1",
+				Region:        stringz.Ptr("us-east-1"),
+			},
+			isValid: true,
+		},
+		{
+			name: "failover primary with health check",
+			cfg: &RecordSetRoutingConfig{
+				SetIdentifier: "primary",
+				Failover:      stringz.Ptr(RecordSetFailoverPrimary),
+				HealthCheck: &RecordSetHealthCheckConfig{
+					ResourcePath: "/health",
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "failover secondary without health check",
+			cfg: &RecordSetRoutingConfig{
+				SetIdentifier: "secondary",
+				Failover:      stringz.Ptr(RecordSetFailoverSecondary),
+			},
+			isValid: true,
+		},
+		{
+			name: "failover primary without health check",
+			cfg: &RecordSetRoutingConfig{
+				SetIdentifier: "primary",
+				Failover:      stringz.Ptr(RecordSetFailoverPrimary),
+			},
+			isValid: false,
+		},
+		{
+			name: "no policy",
+			cfg: &RecordSetRoutingConfig{
+				SetIdentifier: "blue",
+			},
+			isValid: false,
+		},
+		{
+			name: "multiple policies",
+			cfg: &RecordSetRoutingConfig{
+				SetIdentifier: "blue",
+				Weight:        &weight,
+				Region:        stringz.Ptr("us-east-1"),
+			},
+			isValid: false,
+		},
+		{
+			name: "missing set identifier",
+			cfg: &RecordSetRoutingConfig{
+				Weight: &weight,
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid weight",
+			cfg: &RecordSetRoutingConfig{
+				SetIdentifier: "blue",
+				Weight:        &invalidWeight,
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid failover",
+			cfg: &RecordSetRoutingConfig{
+				SetIdentifier: "blue",
+				Failover:      stringz.Ptr("TERTIARY"),
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid health check path",
+			cfg: &RecordSetRoutingConfig{
+				SetIdentifier: "primary",
+				Failover:      stringz.Ptr(RecordSetFailoverPrimary),
+				HealthCheck: &RecordSetHealthCheckConfig{
+					ResourcePath: "health",
+				},
+			},
+			isValid: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			if testCase.isValid {
+				require.NotPanics(t, testCase.cfg.MustValidate)
+			} else {
+				require.Panics(t, testCase.cfg.MustValidate)
+			}
+		})
+	}
+}
+
+func TestCloudApplyRecordSetRouting_HealthCheck(t *testing.T) {
+	stage, plugins := newTestMetadataStage(nil, newTestMetadata())
+	stage.cfg.App.(*testApp).cfg = &AppConfig{Name: "app"}
+
+	tpl := gocf.NewTemplate()
+	recordSet := &goroute53.RecordSet{
+		AliasTarget: &goroute53.RecordSet_AliasTarget{
+			DNSName:      "lb-123.us-east-1.elb.amazonaws.com",
+			HostedZoneId: "Z35SXDOTRQ7X7K",
+		},
+		Name: "api.example.com",
+		Type: "A",
+	}
+
+	CloudApplyRecordSetRouting(tpl, plugins[0], CloudRef("health-check"), recordSet, &RecordSetRoutingConfig{
+		SetIdentifier: "primary",
+		Failover:      stringz.Ptr(RecordSetFailoverPrimary),
+		HealthCheck: &RecordSetHealthCheckConfig{
+			ResourcePath: "/health",
+		},
+	})
+
+	healthCheck := tpl.Resources["HealthCheck"].(*goroute53.HealthCheck)
+	require.Equal(t, "lb-123.us-east-1.elb.amazonaws.com", healthCheck.HealthCheckConfig.(map[string]interface{})["FullyQualifiedDomainName"])
+	require.Equal(t, true, *recordSet.AliasTarget.EvaluateTargetHealth)
+}
@@ -0,0 +1,545 @@
+package cloudz
+
+import (
+	"fmt"
+
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goecs "github.com/awslabs/goformation/v6/cloudformation/ecs"
+	elbv2 "github.com/awslabs/goformation/v6/cloudformation/elasticloadbalancingv2"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	gologs "github.com/awslabs/goformation/v6/cloudformation/logs"
+	goroute53 "github.com/awslabs/goformation/v6/cloudformation/route53"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+)
+
+// ECS service constants.
+const (
+	ECSServiceRefLogGroup            = CloudRef("lg")
+	ECSServiceRefRoleExecution       = CloudRef("r-ex")
+	ECSServiceRefRoleTask            = CloudRef("r-tk")
+	ECSServiceRefTaskDefinition      = CloudRef("td")
+	ECSServiceRefTargetGroup         = CloudRef("tg")
+	ECSServiceRefListenerRule        = CloudRef("lr")
+	ECSServiceRefCluster             = CloudRef("cl")
+	ECSServiceRefService             = CloudRef("svc")
+	ECSServiceRefRecordSet           = CloudRef("rs")
+	ECSServiceRefHealthCheck         = CloudRef("hc")
+	ECSServiceAttARN                 = CloudAtt("Arn")
+	ECSServiceAttName                = CloudAtt("Name")
+	ECSServiceAttRoleID              = CloudAtt("RoleId")
+	ECSServiceAttRuleARN             = CloudAtt("RuleArn")
+	ECSServiceAttTargetGroupFullName = CloudAtt("TargetGroupFullName")
+	ECSServiceAttTargetGroupName     = CloudAtt("TargetGroupName")
+
+	ecsServiceConnectPortName = "http"
+)
+
+// ECSServiceTemplateConfig describes a Fargate service running a single container behind the HTTPS listener of a
+// LoadBalancer, reachable at DomainName. If LoadBalancer is nil the service runs as a worker without ingress, and Port
+// (unless it is a Service Connect server), HealthCheckPath, DomainName, ListenerRulePriority, Routing, and Certificate
+// are ignored. If EFSMounts is not empty, the given access points of EFS are mounted in the container, and the task role
+// is allowed to mount them. ExecutionRolePolicies are added to the role that pulls the image (e.g. the ImageCache pull
+// policy).
+type ECSServiceTemplateConfig struct {
+	Image                  string
+	Port                   int
+	Environment            map[string]string
+	Secrets                []*ECSServiceTemplateConfigSecret
+	HealthCheckPath        string
+	Replicas               int
+	CPU                    int
+	Memory                 int
+	TaskRolePolicies       []goiam.Role_Policy
+	ExecutionRolePolicies  []goiam.Role_Policy
+	ScratchVolumes         []*ECSServiceTemplateConfigScratchVolume
+	EFS                    EFS
+	EFSMounts              []*EFSMount
+	ReadonlyRootFilesystem bool
+	DomainName             string
+	ListenerRulePriority   int
+	TargetGroup            *ECSServiceTemplateConfigTargetGroup
+	ServiceConnect         *ECSServiceTemplateConfigServiceConnect
+	Routing                *RecordSetRoutingConfig
+	Certificate            Certificate
+	LoadBalancer           LoadBalancer
+	Network                Network
+}
+
+// ECSServiceTemplateConfigScratchVolume describes part of the ECS service template config.
+type ECSServiceTemplateConfigScratchVolume struct {
+	Name          string
+	ContainerPath string
+}
+
+// ECSServiceTemplateConfigTargetGroup describes part of the ECS service template config: optional target group
+// attributes. StickinessDurationSeconds enables load balancer generated cookie stickiness for the given duration.
+// SlowStartSeconds linearly ramps up the share of traffic sent to newly registered targets for the given duration.
+type ECSServiceTemplateConfigTargetGroup struct {
+	StickinessDurationSeconds int `validate:"omitempty,min=1,max=604800"`
+	SlowStartSeconds          int `validate:"omitempty,min=30,max=900"`
+}
+
+// GetAttributes returns the target group attributes.
+func (c *ECSServiceTemplateConfigTargetGroup) GetAttributes() *[]elbv2.TargetGroup_TargetGroupAttribute {
+	attrs := []elbv2.TargetGroup_TargetGroupAttribute{
+		{
+			Key:   stringz.Ptr("deregistration_delay.timeout_seconds"),
+			Value: stringz.Ptr("30"),
+		},
+	}
+
+	if c == nil {
+		return &attrs
+	}
+
+	if c.StickinessDurationSeconds > 0 {
+		attrs = append(attrs,
+			elbv2.TargetGroup_TargetGroupAttribute{
+				Key:   stringz.Ptr("stickiness.enabled"),
+				Value: stringz.Ptr("true"),
+			},
+			elbv2.TargetGroup_TargetGroupAttribute{
+				Key:   stringz.Ptr("stickiness.type"),
+				Value: stringz.Ptr("lb_cookie"),
+			},
+			elbv2.TargetGroup_TargetGroupAttribute{
+				Key:   stringz.Ptr("stickiness.lb_cookie.duration_seconds"),
+				Value: stringz.Ptr(fmt.Sprintf("%v", c.StickinessDurationSeconds)),
+			})
+	}
+
+	if c.SlowStartSeconds > 0 {
+		attrs = append(attrs, elbv2.TargetGroup_TargetGroupAttribute{
+			Key:   stringz.Ptr("slow_start.duration_seconds"),
+			Value: stringz.Ptr(fmt.Sprintf("%v", c.SlowStartSeconds)),
+		})
+	}
+
+	return &attrs
+}
+
+// ECSServiceTemplateConfigServiceConnect describes part of the ECS service template config: ECS Service Connect, in the
+// namespace created by the Network (see NetworkConfigCloud.IsServiceConnectEnabled). The Service Connect proxy retries
+// failed connections, enforces the timeouts, and publishes per-service request metrics to CloudWatch. If DiscoveryName
+// is set, the other services in the namespace can reach this one at "http://<DiscoveryName>:<Port>", otherwise it is
+// only a client. The timeouts only apply if DiscoveryName is set.
+type ECSServiceTemplateConfigServiceConnect struct {
+	DiscoveryName            string `validate:"omitempty,resource-name"`
+	IdleTimeoutSeconds       int    `validate:"omitempty,min=1"`
+	PerRequestTimeoutSeconds int    `validate:"omitempty,min=1"`
+}
+
+// IsServer returns true if the service is reachable by other services in the namespace.
+func (c *ECSServiceTemplateConfigServiceConnect) IsServer() bool {
+	return c != nil && c.DiscoveryName != ""
+}
+
+// ECSServiceTemplateConfigSecret describes part of the ECS service template config: an environment variable populated
+// from a Secrets Manager secret when the task starts. If JSONKey is set the secret value must be a JSON object, and the
+// environment variable is populated with the value of the given key.
+type ECSServiceTemplateConfigSecret struct {
+	Name      string
+	SecretARN string
+	JSONKey   string
+}
+
+// GetValueFrom returns the value for the "ValueFrom" field of the task definition secret.
+func (s *ECSServiceTemplateConfigSecret) GetValueFrom() string {
+	if s.JSONKey == "" {
+		return s.SecretARN
+	}
+	return fmt.Sprintf("%v:%v::", s.SecretARN, s.JSONKey)
+}
+
+// CloudAddECSTaskResources adds the resources for running a single container on Fargate to the given template: log
+// group, roles, task definition, and cluster. Replicas and the load balancer related fields of the config are ignored.
+func CloudAddECSTaskResources(tpl *gocf.Template, p Plugin, cfg *ECSServiceTemplateConfig) {
+	stage := p.GetStage()
+
+	tpl.Resources[ECSServiceRefLogGroup.Ref()] = &gologs.LogGroup{
+		LogGroupName:    stringz.Ptr(ECSServiceRefLogGroup.Name(p)),
+		RetentionInDays: intz.Ptr(90),
+	}
+	CloudAddExpRef(tpl, p, ECSServiceRefLogGroup)
+	CloudAddExpGetAtt(tpl, p, ECSServiceRefLogGroup, ECSServiceAttARN)
+
+	tpl.Resources[ECSServiceRefRoleExecution.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("ecs-tasks.amazonaws.com"),
+		ManagedPolicyArns: &[]string{
+			"arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy",
+		},
+		Policies: func() *[]goiam.Role_Policy {
+			policies := append([]goiam.Role_Policy{}, cfg.ExecutionRolePolicies...)
+
+			if len(cfg.Secrets) > 0 {
+				secretARNs := make([]string, 0, len(cfg.Secrets))
+				secretARNsSet := map[string]struct{}{}
+
+				for _, secret := range cfg.Secrets {
+					if _, ok := secretARNsSet[secret.SecretARN]; !ok {
+						secretARNsSet[secret.SecretARN] = struct{}{}
+						secretARNs = append(secretARNs, secret.SecretARN)
+					}
+				}
+
+				policies = append(policies, goiam.Role_Policy{
+					PolicyName: p.GetName() + "-secrets",
+					PolicyDocument: NewPolicyDocument(
+						NewPolicyStatement().
+							AddActions("secretsmanager:GetSecretValue").
+							AddResources(secretARNs...)),
+				})
+			}
+
+			if len(policies) == 0 {
+				return nil
+			}
+			return &policies
+		}(),
+		RoleName: stringz.Ptr(ECSServiceRefRoleExecution.Name(p)),
+		Tags:     CloudGetDefaultTags(ECSServiceRefRoleExecution.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, ECSServiceRefRoleExecution)
+	CloudAddExpGetAtt(tpl, p, ECSServiceRefRoleExecution, ECSServiceAttARN)
+	CloudAddExpGetAtt(tpl, p, ECSServiceRefRoleExecution, ECSServiceAttRoleID)
+
+	tpl.Resources[ECSServiceRefRoleTask.Ref()] = &goiam.Role{
+		AssumeRolePolicyDocument: NewAssumeRolePolicyDocument("ecs-tasks.amazonaws.com"),
+		Policies: func() *[]goiam.Role_Policy {
+			policies := append([]goiam.Role_Policy{}, cfg.TaskRolePolicies...)
+			if len(cfg.EFSMounts) > 0 {
+				policies = append(policies, CloudGetEFSClientRolePolicy(p, cfg.EFS, cfg.EFSMounts))
+			}
+
+			if len(policies) == 0 {
+				return nil
+			}
+			return &policies
+		}(),
+		RoleName: stringz.Ptr(ECSServiceRefRoleTask.Name(p)),
+		Tags:     CloudGetDefaultTags(ECSServiceRefRoleTask.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, ECSServiceRefRoleTask)
+	CloudAddExpGetAtt(tpl, p, ECSServiceRefRoleTask, ECSServiceAttARN)
+	CloudAddExpGetAtt(tpl, p, ECSServiceRefRoleTask, ECSServiceAttRoleID)
+
+	var mountPoints *[]goecs.TaskDefinition_MountPoint
+	var volumes *[]goecs.TaskDefinition_Volume
+
+	if len(cfg.ScratchVolumes) > 0 || len(cfg.EFSMounts) > 0 {
+		mountPoints = &[]goecs.TaskDefinition_MountPoint{}
+		volumes = &[]goecs.TaskDefinition_Volume{}
+
+		for _, scratchVolume := range cfg.ScratchVolumes {
+			*mountPoints = append(*mountPoints, goecs.TaskDefinition_MountPoint{
+				ContainerPath: stringz.Ptr(scratchVolume.ContainerPath),
+				SourceVolume:  stringz.Ptr(scratchVolume.Name),
+			})
+			*volumes = append(*volumes, goecs.TaskDefinition_Volume{
+				Name: stringz.Ptr(scratchVolume.Name),
+			})
+		}
+
+		for i, efsMount := range cfg.EFSMounts {
+			volumeName := fmt.Sprintf("efs-%v", i)
+
+			*mountPoints = append(*mountPoints, goecs.TaskDefinition_MountPoint{
+				ContainerPath: stringz.Ptr(efsMount.Path),
+				ReadOnly:      boolz.Ptr(efsMount.IsReadOnly),
+				SourceVolume:  stringz.Ptr(volumeName),
+			})
+			*volumes = append(*volumes, goecs.TaskDefinition_Volume{
+				EFSVolumeConfiguration: &goecs.TaskDefinition_EFSVolumeConfiguration{
+					AuthorizationConfig: &goecs.TaskDefinition_AuthorizationConfig{
+						AccessPointId: stringz.Ptr(cfg.EFS.GetCloudMetadata(true).GetAccessPointID(efsMount.AccessPoint)),
+						IAM:           stringz.Ptr("ENABLED"),
+					},
+					FilesystemId:      cfg.EFS.GetCloudMetadata(true).GetFileSystemID(),
+					TransitEncryption: stringz.Ptr("ENABLED"),
+				},
+				Name: stringz.Ptr(volumeName),
+			})
+		}
+	}
+
+	tpl.Resources[ECSServiceRefTaskDefinition.Ref()] = &goecs.TaskDefinition{
+		ContainerDefinitions: &[]goecs.TaskDefinition_ContainerDefinition{
+			{
+				Environment: CloudGetTaskDefinitionKeyValuePairs(cfg.Environment),
+				Image:       stringz.Ptr(cfg.Image),
+				LogConfiguration: &goecs.TaskDefinition_LogConfiguration{
+					LogDriver: "awslogs",
+					Options: &map[string]string{
+						"awslogs-region":        gocf.Ref("AWS::Region"),
+						"awslogs-group":         gocf.Ref(ECSServiceRefLogGroup.Ref()),
+						"awslogs-stream-prefix": ECSServiceRefTaskDefinition.Name(p),
+					},
+				},
+				MountPoints: mountPoints,
+				Name:        stringz.Ptr(ECSServiceRefTaskDefinition.Name(p)),
+				PortMappings: func() *[]goecs.TaskDefinition_PortMapping {
+					if cfg.LoadBalancer == nil {
+						return nil
+					}
+					return &[]goecs.TaskDefinition_PortMapping{
+						{
+							ContainerPort: intz.Ptr(cfg.Port),
+							HostPort:      intz.Ptr(cfg.Port),
+							Protocol:      stringz.Ptr("tcp"),
+						},
+					}
+				}(),
+				ReadonlyRootFilesystem: boolz.Ptr(cfg.ReadonlyRootFilesystem),
+				Secrets: func() *[]goecs.TaskDefinition_Secret {
+					if len(cfg.Secrets) == 0 {
+						return nil
+					}
+
+					secrets := make([]goecs.TaskDefinition_Secret, 0, len(cfg.Secrets))
+					for _, secret := range cfg.Secrets {
+						secrets = append(secrets, goecs.TaskDefinition_Secret{
+							Name:      secret.Name,
+							ValueFrom: secret.GetValueFrom(),
+						})
+					}
+					return &secrets
+				}(),
+				StopTimeout: intz.Ptr(30),
+			},
+		},
+		Cpu:              stringz.Ptr(fmt.Sprintf("%v", cfg.CPU)),
+		ExecutionRoleArn: stringz.Ptr(gocf.Ref(ECSServiceRefRoleExecution.Ref())),
+		Family:           stringz.Ptr(ECSServiceRefTaskDefinition.Name(p)),
+		Memory:           stringz.Ptr(fmt.Sprintf("%v", cfg.Memory)),
+		NetworkMode:      stringz.Ptr("awsvpc"),
+		RequiresCompatibilities: &[]string{
+			"FARGATE",
+		},
+		TaskRoleArn: stringz.Ptr(gocf.Ref(ECSServiceRefRoleTask.Ref())),
+		Volumes:     volumes,
+		Tags:        CloudGetDefaultTags(ECSServiceRefTaskDefinition.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, ECSServiceRefTaskDefinition)
+
+	tpl.Resources[ECSServiceRefCluster.Ref()] = &goecs.Cluster{
+		ClusterName: stringz.Ptr(ECSServiceRefCluster.Name(p)),
+		ClusterSettings: &[]goecs.Cluster_ClusterSettings{
+			{
+				Name: stringz.Ptr("containerInsights"),
+				Value: func() *string {
+					if stage.GetMode().IsProduction() {
+						return stringz.Ptr("enabled")
+					}
+					return stringz.Ptr("disabled")
+				}(),
+			},
+		},
+		Tags: CloudGetDefaultTags(ECSServiceRefCluster.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, ECSServiceRefCluster)
+	CloudAddExpGetAtt(tpl, p, ECSServiceRefCluster, ECSServiceAttARN)
+}
+
+// CloudAddECSServiceResources adds the resources for an ECS service to the given template: task resources (see
+// CloudAddECSTaskResources), service, and (if LoadBalancer is set) target group, listener rule, and (if the DNS provider
+// is Route53) record set. In non production stages the service always runs a single replica.
+func CloudAddECSServiceResources(tpl *gocf.Template, p Plugin, cfg *ECSServiceTemplateConfig) {
+	stage := p.GetStage()
+	network := cfg.Network.GetCloudMetadata(true)
+
+	CloudAddECSTaskResources(tpl, p, cfg)
+
+	if cfg.LoadBalancer != nil {
+		cloudAddECSServiceLoadBalancerResources(tpl, p, cfg)
+	}
+
+	service := &goecs.Service{
+		AWSCloudFormationDependsOn: func() []string {
+			if cfg.LoadBalancer == nil {
+				return nil
+			}
+			return []string{
+				ECSServiceRefTargetGroup.Ref(),
+			}
+		}(),
+		Cluster: stringz.Ptr(gocf.Ref(ECSServiceRefCluster.Ref())),
+		DeploymentController: &goecs.Service_DeploymentController{
+			Type: stringz.Ptr("ECS"),
+		},
+		DeploymentConfiguration: &goecs.Service_DeploymentConfiguration{
+			DeploymentCircuitBreaker: &goecs.Service_DeploymentCircuitBreaker{
+				Enable:   true,
+				Rollback: true,
+			},
+		},
+		DesiredCount: func() *int {
+			if stage.GetMode().IsProduction() {
+				return intz.Ptr(cfg.Replicas)
+			}
+			return intz.Ptr(1)
+		}(),
+		EnableECSManagedTags: boolz.Ptr(true),
+		LaunchType:           stringz.Ptr("FARGATE"),
+		LoadBalancers: func() *[]goecs.Service_LoadBalancer {
+			if cfg.LoadBalancer == nil {
+				return nil
+			}
+			return &[]goecs.Service_LoadBalancer{
+				{
+					ContainerName:  stringz.Ptr(ECSServiceRefTaskDefinition.Name(p)),
+					ContainerPort:  intz.Ptr(cfg.Port),
+					TargetGroupArn: stringz.Ptr(gocf.Ref(ECSServiceRefTargetGroup.Ref())),
+				},
+			}
+		}(),
+		NetworkConfiguration: &goecs.Service_NetworkConfiguration{
+			AwsvpcConfiguration: &goecs.Service_AwsVpcConfiguration{
+				AssignPublicIp: stringz.Ptr("DISABLED"),
+				SecurityGroups: &[]string{
+					network.Exports.GetRef(NetworkRefSecurityGroup),
+				},
+				Subnets: &[]string{
+					network.Exports.GetRef(NetworkRefSubnetPrivateA),
+					network.Exports.GetRef(NetworkRefSubnetPrivateB),
+				},
+			},
+		},
+		PropagateTags:      stringz.Ptr("TASK_DEFINITION"),
+		SchedulingStrategy: stringz.Ptr("REPLICA"),
+		TaskDefinition:     stringz.Ptr(gocf.Ref(ECSServiceRefTaskDefinition.Ref())),
+		Tags:               CloudGetDefaultTags(ECSServiceRefService.Name(p)),
+	}
+
+	if cfg.ServiceConnect != nil {
+		cloudAddECSServiceConnect(tpl, p, cfg, service)
+	} else {
+		tpl.Resources[ECSServiceRefService.Ref()] = service
+	}
+	CloudAddExpRef(tpl, p, ECSServiceRefService)
+	CloudAddExpGetAtt(tpl, p, ECSServiceRefService, ECSServiceAttName)
+}
+
+// cloudAddECSServiceConnect adds the given service to the template, with its Service Connect configuration. Since the
+// goformation version in use doesn't model Service Connect yet, the service (and, for servers, the task definition, to
+// name its port mapping) are converted to custom resources.
+func cloudAddECSServiceConnect(tpl *gocf.Template, p Plugin, cfg *ECSServiceTemplateConfig, service *goecs.Service) {
+	serviceConnectConfiguration := map[string]interface{}{
+		"Enabled":   true,
+		"Namespace": cfg.Network.GetCloudMetadata(true).Exports.GetAtt(NetworkRefServiceConnectNamespace, NetworkAttARN),
+		"LogConfiguration": map[string]interface{}{
+			"LogDriver": "awslogs",
+			"Options": map[string]interface{}{
+				"awslogs-region":        gocf.Ref("AWS::Region"),
+				"awslogs-group":         gocf.Ref(ECSServiceRefLogGroup.Ref()),
+				"awslogs-stream-prefix": ECSServiceRefTaskDefinition.Name(p) + "-sc",
+			},
+		},
+	}
+
+	if cfg.ServiceConnect.IsServer() {
+		taskDefinition := CloudNewCustomResource(tpl.Resources[ECSServiceRefTaskDefinition.Ref()])
+		containerDefinition := taskDefinition.Properties["ContainerDefinitions"].([]interface{})[0].(map[string]interface{})
+		containerDefinition["PortMappings"] = []interface{}{
+			map[string]interface{}{
+				"AppProtocol":   "http",
+				"ContainerPort": cfg.Port,
+				"HostPort":      cfg.Port,
+				"Name":          ecsServiceConnectPortName,
+				"Protocol":      "tcp",
+			},
+		}
+		tpl.Resources[ECSServiceRefTaskDefinition.Ref()] = taskDefinition
+
+		serviceConnectService := map[string]interface{}{
+			"ClientAliases": []interface{}{
+				map[string]interface{}{
+					"DnsName": cfg.ServiceConnect.DiscoveryName,
+					"Port":    cfg.Port,
+				},
+			},
+			"DiscoveryName": cfg.ServiceConnect.DiscoveryName,
+			"PortName":      ecsServiceConnectPortName,
+		}
+
+		timeout := map[string]interface{}{}
+		if cfg.ServiceConnect.IdleTimeoutSeconds > 0 {
+			timeout["IdleTimeoutSeconds"] = cfg.ServiceConnect.IdleTimeoutSeconds
+		}
+		if cfg.ServiceConnect.PerRequestTimeoutSeconds > 0 {
+			timeout["PerRequestTimeoutSeconds"] = cfg.ServiceConnect.PerRequestTimeoutSeconds
+		}
+		if len(timeout) > 0 {
+			serviceConnectService["Timeout"] = timeout
+		}
+
+		serviceConnectConfiguration["Services"] = []interface{}{
+			serviceConnectService,
+		}
+	}
+
+	r := CloudNewCustomResource(service)
+	r.Properties["ServiceConnectConfiguration"] = serviceConnectConfiguration
+	tpl.Resources[ECSServiceRefService.Ref()] = r
+}
+
+func cloudAddECSServiceLoadBalancerResources(tpl *gocf.Template, p Plugin, cfg *ECSServiceTemplateConfig) {
+	network := cfg.Network.GetCloudMetadata(true)
+	loadBalancer := cfg.LoadBalancer.GetCloudMetadata(true)
+
+	tpl.Resources[ECSServiceRefTargetGroup.Ref()] = &elbv2.TargetGroup{
+		HealthCheckPath:            stringz.Ptr(cfg.HealthCheckPath),
+		HealthCheckIntervalSeconds: intz.Ptr(15),
+		HealthyThresholdCount:      intz.Ptr(2),
+		UnhealthyThresholdCount:    intz.Ptr(8),
+		Port:                       intz.Ptr(cfg.Port),
+		Protocol:                   stringz.Ptr("HTTP"),
+		ProtocolVersion:            stringz.Ptr("HTTP1"), // TODO(ibrt): Try HTTP2?
+		TargetGroupAttributes:      cfg.TargetGroup.GetAttributes(),
+		TargetType:                 stringz.Ptr("ip"),
+		VpcId:                      stringz.Ptr(network.Exports.GetRef(NetworkRefVPC)),
+		Tags:                       CloudGetDefaultTags(ECSServiceRefTargetGroup.Name(p)),
+	}
+	CloudAddExpRef(tpl, p, ECSServiceRefTargetGroup)
+	CloudAddExpGetAtt(tpl, p, ECSServiceRefTargetGroup, ECSServiceAttTargetGroupFullName)
+	CloudAddExpGetAtt(tpl, p, ECSServiceRefTargetGroup, ECSServiceAttTargetGroupName)
+
+	tpl.Resources[ECSServiceRefListenerRule.Ref()] = &elbv2.ListenerRule{
+		Actions: []elbv2.ListenerRule_Action{
+			{
+				TargetGroupArn: stringz.Ptr(gocf.Ref(ECSServiceRefTargetGroup.Ref())),
+				Type:           "forward",
+			},
+		},
+		Conditions: []elbv2.ListenerRule_RuleCondition{
+			{
+				Field: stringz.Ptr("host-header"),
+				HostHeaderConfig: &elbv2.ListenerRule_HostHeaderConfig{
+					Values: &[]string{
+						cfg.DomainName,
+					},
+				},
+			},
+		},
+		ListenerArn: loadBalancer.Exports.GetAtt(LoadBalancerRefListenerHTTPS, LoadBalancerAttListenerArn),
+		Priority:    cfg.ListenerRulePriority,
+	}
+	CloudAddExpRef(tpl, p, ECSServiceRefListenerRule)
+	CloudAddExpGetAtt(tpl, p, ECSServiceRefListenerRule, ECSServiceAttRuleARN)
+
+	if hostedZoneID := cfg.Certificate.GetConfig().Cloud.GetDNSProvider().GetHostedZoneID(); hostedZoneID != nil {
+		recordSet := &goroute53.RecordSet{
+			AliasTarget: &goroute53.RecordSet_AliasTarget{
+				DNSName:      loadBalancer.Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttDNSName),
+				HostedZoneId: loadBalancer.Exports.GetAtt(LoadBalancerRefLoadBalancer, LoadBalancerAttCanonicalHostedZoneID),
+			},
+			HostedZoneId: hostedZoneID,
+			Name:         cfg.DomainName,
+			Type:         "A",
+		}
+		CloudApplyRecordSetRouting(tpl, p, ECSServiceRefHealthCheck, recordSet, cfg.Routing)
+		tpl.Resources[ECSServiceRefRecordSet.Ref()] = recordSet
+		CloudAddExpRef(tpl, p, ECSServiceRefRecordSet)
+	}
+}
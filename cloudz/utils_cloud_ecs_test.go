@@ -0,0 +1,32 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestECSServiceTemplateConfigTargetGroup_GetAttributes(t *testing.T) {
+	getAttributes := func(c *ECSServiceTemplateConfigTargetGroup) map[string]string {
+		m := map[string]string{}
+		for _, attr := range *c.GetAttributes() {
+			m[*attr.Key] = *attr.Value
+		}
+		return m
+	}
+
+	require.Equal(t, map[string]string{
+		"deregistration_delay.timeout_seconds": "30",
+	}, getAttributes(nil))
+
+	require.Equal(t, map[string]string{
+		"deregistration_delay.timeout_seconds":  "30",
+		"stickiness.enabled":                    "true",
+		"stickiness.type":                       "lb_cookie",
+		"stickiness.lb_cookie.duration_seconds": "3600",
+		"slow_start.duration_seconds":           "60",
+	}, getAttributes(&ECSServiceTemplateConfigTargetGroup{
+		StickinessDurationSeconds: 3600,
+		SlowStartSeconds:          60,
+	}))
+}
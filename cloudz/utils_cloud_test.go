@@ -0,0 +1,47 @@
+package cloudz
+
+import (
+	"strings"
+	"testing"
+
+	goecs "github.com/awslabs/goformation/v6/cloudformation/ecs"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudNewCustomResource(t *testing.T) {
+	r := CloudNewCustomResource(&goecs.Service{
+		AWSCloudFormationDependsOn: []string{"tg"},
+		Cluster:                    stringz.Ptr("cluster"),
+		DesiredCount:               intz.Ptr(2),
+	})
+
+	require.Equal(t, "AWS::ECS::Service", r.Type)
+	require.Equal(t, []string{"tg"}, r.AWSCloudFormationDependsOn)
+	require.Equal(t, map[string]interface{}{
+		"Cluster":      "cluster",
+		"DesiredCount": float64(2),
+	}, r.Properties)
+}
+
+func TestGetCloudExportName(t *testing.T) {
+	require.Equal(t, "app-stage-bucket-b-exp-ref", getCloudExportName("app-stage-bucket", "-b-exp-ref"))
+
+	prefix := strings.Repeat("a", 300)
+	name := getCloudExportName(prefix, cloudRecordSetExpRefNameSuffix)
+	require.Len(t, name, cloudExportNameMaxLength)
+	require.True(t, strings.HasPrefix(name, "aaa"))
+	require.True(t, strings.HasSuffix(name, cloudRecordSetExpRefNameSuffix))
+	require.Equal(t, name, getCloudExportName(prefix, cloudRecordSetExpRefNameSuffix))
+	require.NotEqual(t, name, getCloudExportName(prefix+"b", cloudRecordSetExpRefNameSuffix))
+
+	require.Panics(t, func() {
+		getCloudExportName("app", strings.Repeat("a", 300))
+	})
+}
+
+func TestGetCloudStackNameFromID(t *testing.T) {
+	require.Equal(t, "app-stage-bucket", getCloudStackNameFromID("arn:aws:cloudformation:us-east-1:123456789012:stack/app-stage-bucket/0a1b2c3d"))
+	require.Equal(t, "app-stage-bucket", getCloudStackNameFromID("app-stage-bucket"))
+}
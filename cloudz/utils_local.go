@@ -1,7 +1,10 @@
 package cloudz
 
 import (
-	"strings"
+	"net/url"
+
+	"github.com/ibrt/golang-bites/urlz"
+	"github.com/ibrt/golang-errors/errorz"
 )
 
 // Pseudo-secret values used for local services.
@@ -12,16 +15,29 @@ const (
 	LocalSecret             = "secret"
 )
 
-// LocalGetContainerName generates a container name for the given plugin.
+// LocalGetContainerName generates a container name for the given plugin (see NamingStrategy).
 func LocalGetContainerName(p Plugin, additionalParts ...string) string {
-	parts := []string{
-		p.GetStage().GetConfig().App.GetConfig().Name,
-		p.GetName(),
-	}
+	return p.GetStage().GetConfig().App.GetConfig().GetNaming().GetContainerName(p, additionalParts...)
+}
+
+// LocalGetImage returns the reference for the given public image, after applying the app image config (if any).
+func LocalGetImage(p Plugin, image string) string {
+	return p.GetStage().GetConfig().App.GetConfig().GetImage(image)
+}
+
+// LocalGetInternalOrigin returns the origin (scheme and host) of the "InternalURL" local metadata value of the given
+// plugin, e.g. "http://app-hasura:8080". It panics if the plugin doesn't expose one.
+func LocalGetInternalOrigin(p Plugin) *url.URL {
+	for _, value := range GetMetadataValues(p) {
+		if value.Name == "InternalURL" {
+			internalURL := urlz.MustParse(value.Value)
 
-	if instanceName := p.GetInstanceName(); instanceName != nil {
-		parts = append(parts, *instanceName)
+			return &url.URL{
+				Scheme: internalURL.Scheme,
+				Host:   internalURL.Host,
+			}
+		}
 	}
 
-	return strings.Join(append(parts, additionalParts...), "-")
+	panic(errorz.Errorf("%v has no InternalURL local metadata", errorz.A(GetMetadataKey(p))))
 }
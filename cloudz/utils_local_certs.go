@@ -0,0 +1,128 @@
+package cloudz
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// Local certificate authority constants.
+// The file names match the ones used by mkcert, so that an existing mkcert CAROOT directory can be used, and a
+// generated one can be installed in the system trust stores using "CAROOT=<dir> mkcert -install".
+const (
+	LocalRootCertificateFileName = "rootCA.pem"
+	LocalRootKeyFileName         = "rootCA-key.pem"
+
+	localRootCertificateValidity = 10 * 365 * 24 * time.Hour
+	localCertificateValidity     = 825 * 24 * time.Hour // maximum validity accepted by macOS and iOS
+)
+
+// LocalMustEnsureCertificateAuthority loads the local certificate authority from the given directory, generating it if
+// it doesn't exist yet.
+func LocalMustEnsureCertificateAuthority(caRootDirPath string) (*x509.Certificate, *rsa.PrivateKey) {
+	certFilePath := filepath.Join(caRootDirPath, LocalRootCertificateFileName)
+	keyFilePath := filepath.Join(caRootDirPath, LocalRootKeyFileName)
+
+	if !filez.MustCheckExists(certFilePath) || !filez.MustCheckExists(keyFilePath) {
+		key, err := rsa.GenerateKey(rand.Reader, 3072)
+		errorz.MaybeMustWrap(err)
+
+		tpl := &x509.Certificate{
+			SerialNumber: localMustGenerateSerialNumber(),
+			Subject: pkix.Name{
+				Organization:       []string{"golang-cloud development CA"},
+				OrganizationalUnit: []string{filez.MustUserHomeDir()},
+				CommonName:         "golang-cloud development CA",
+			},
+			SubjectKeyId:          localGetSubjectKeyID(&key.PublicKey),
+			NotBefore:             time.Now(),
+			NotAfter:              time.Now().Add(localRootCertificateValidity),
+			KeyUsage:              x509.KeyUsageCertSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			MaxPathLenZero:        true,
+		}
+
+		certDER, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+		errorz.MaybeMustWrap(err)
+
+		filez.MustWriteFile(keyFilePath, 0777, 0400, localMustEncodePrivateKey(key))
+		filez.MustWriteFile(certFilePath, 0777, 0644, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}))
+	}
+
+	certBlock, _ := pem.Decode(filez.MustReadFile(certFilePath))
+	errorz.Assertf(certBlock != nil && certBlock.Type == "CERTIFICATE", "malformed root certificate: %v", errorz.A(certFilePath))
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	errorz.MaybeMustWrap(err)
+
+	keyBlock, _ := pem.Decode(filez.MustReadFile(keyFilePath))
+	errorz.Assertf(keyBlock != nil && keyBlock.Type == "PRIVATE KEY", "malformed root key: %v", errorz.A(keyFilePath))
+	rawKey, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	errorz.MaybeMustWrap(err)
+	key, ok := rawKey.(*rsa.PrivateKey)
+	errorz.Assertf(ok, "unsupported root key type: %v", errorz.A(keyFilePath))
+
+	return cert, key
+}
+
+// LocalMustIssueCertificate issues a certificate for the given hostnames (or IP addresses), signed by the local
+// certificate authority in the given directory, and writes it and its private key to the given files in PEM format.
+func LocalMustIssueCertificate(caRootDirPath string, hostnames []string, certFilePath, keyFilePath string) {
+	caCert, caKey := LocalMustEnsureCertificateAuthority(caRootDirPath)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	errorz.MaybeMustWrap(err)
+
+	tpl := &x509.Certificate{
+		SerialNumber: localMustGenerateSerialNumber(),
+		Subject: pkix.Name{
+			Organization: []string{"golang-cloud development certificate"},
+		},
+		NotBefore:   time.Now(),
+		NotAfter:    time.Now().Add(localCertificateValidity),
+		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	for _, hostname := range hostnames {
+		if ip := net.ParseIP(hostname); ip != nil {
+			tpl.IPAddresses = append(tpl.IPAddresses, ip)
+		} else {
+			tpl.DNSNames = append(tpl.DNSNames, hostname)
+		}
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, tpl, caCert, &key.PublicKey, caKey)
+	errorz.MaybeMustWrap(err)
+
+	// Note: the key is made world-readable so that it can be bind-mounted into containers running as any user.
+	filez.MustWriteFile(keyFilePath, 0777, 0644, localMustEncodePrivateKey(key))
+	filez.MustWriteFile(certFilePath, 0777, 0644, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}))
+}
+
+func localMustGenerateSerialNumber() *big.Int {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	errorz.MaybeMustWrap(err)
+	return serialNumber
+}
+
+func localGetSubjectKeyID(publicKey *rsa.PublicKey) []byte {
+	sum := sha1.Sum(x509.MarshalPKCS1PublicKey(publicKey))
+	return sum[:]
+}
+
+func localMustEncodePrivateKey(key *rsa.PrivateKey) []byte {
+	buf, err := x509.MarshalPKCS8PrivateKey(key)
+	errorz.MaybeMustWrap(err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: buf})
+}
@@ -0,0 +1,200 @@
+package cloudz
+
+import (
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/templatez"
+
+	"github.com/ibrt/golang-cloud/cloudz/internal/assets"
+)
+
+var (
+	urlType = reflect.TypeOf(&url.URL{})
+)
+
+// MetadataValue describes a metadata value exposed by a plugin.
+// Secret values (i.e. fields tagged with `metadata:"secret"` and URLs embedding credentials) have IsSecret set.
+type MetadataValue struct {
+	Name     string
+	Value    string
+	IsSecret bool
+}
+
+// GetMetadataKey returns a key identifying the given plugin in exported metadata, e.g. "hasura" or "bucket-uploads".
+func GetMetadataKey(p Plugin) string {
+	if instanceName := p.GetInstanceName(); instanceName != nil && *instanceName != "" {
+		return p.GetName() + "-" + *instanceName
+	}
+	return p.GetName()
+}
+
+// GetMetadataValues returns the metadata values exposed by the given plugin for the stage it is configured with,
+// sorted by name. It returns an empty slice if the plugin has no metadata for the stage target (e.g. not deployed).
+//
+// The values are collected from the string and URL fields of the local or cloud metadata struct, and from its
+// methods of the form "GetX() string" (exposed as "X").
+func GetMetadataValues(p Plugin) []*MetadataValue {
+	return getMetadataValues(p, false)
+}
+
+// GetPublicMetadataValues is like GetMetadataValues, but it omits secret values and strips credentials from URLs.
+// Use it whenever the values might end up outside of the stage (e.g. env files or front-end builds).
+func GetPublicMetadataValues(p Plugin) []*MetadataValue {
+	return getMetadataValues(p, true)
+}
+
+func getMetadataValues(p Plugin, isPublic bool) []*MetadataValue {
+	values := make([]*MetadataValue, 0)
+
+	m := getMetadata(p)
+	if !m.IsValid() || m.IsNil() {
+		return values
+	}
+
+	for i := 0; i < m.Elem().NumField(); i++ {
+		field := m.Elem().Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldValue := m.Elem().Field(i)
+		isSecret := field.Tag.Get("metadata") == "secret"
+
+		if isSecret && isPublic {
+			continue
+		}
+
+		switch {
+		case field.Type.Kind() == reflect.String:
+			values = append(values, &MetadataValue{Name: field.Name, Value: fieldValue.String(), IsSecret: isSecret})
+		case field.Type == urlType && !fieldValue.IsNil():
+			u := *fieldValue.Interface().(*url.URL)
+
+			if isPublic {
+				u.User = nil
+			}
+
+			values = append(values, &MetadataValue{Name: field.Name, Value: u.String(), IsSecret: isSecret || u.User != nil})
+		}
+	}
+
+	for i := 0; i < m.NumMethod(); i++ {
+		method := m.Type().Method(i)
+		if !strings.HasPrefix(method.Name, "Get") || method.Type.NumIn() != 1 || method.Type.NumOut() != 1 || method.Type.Out(0).Kind() != reflect.String {
+			continue
+		}
+
+		values = append(values, &MetadataValue{
+			Name:  strings.TrimPrefix(method.Name, "Get"),
+			Value: m.Method(i).Call(nil)[0].String(),
+		})
+	}
+
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].Name < values[j].Name
+	})
+
+	return values
+}
+
+// hasMetadataValue returns true if the metadata of the given plugin exposes a value with the given name (see
+// GetMetadataValues). It only inspects the metadata type, so it also works for plugins that are not deployed yet.
+func hasMetadataValue(p Plugin, name string) bool {
+	m := getMetadata(p)
+	if !m.IsValid() || m.Kind() != reflect.Ptr || m.Type().Elem().Kind() != reflect.Struct {
+		return false
+	}
+
+	if field, ok := m.Type().Elem().FieldByName(name); ok && field.IsExported() && len(field.Index) == 1 {
+		return field.Type.Kind() == reflect.String || field.Type == urlType
+	}
+
+	if method, ok := m.Type().MethodByName("Get" + name); ok {
+		return method.Type.NumIn() == 1 && method.Type.NumOut() == 1 && method.Type.Out(0).Kind() == reflect.String
+	}
+
+	return false
+}
+
+// GenerateMetadataGoBinding generates a Go package containing the metadata values of all plugins for the given stage.
+// The package name is taken from the last element of the output directory path.
+func GenerateMetadataGoBinding(stage Stage, outDirPath string) {
+	filez.MustWriteFile(
+		filepath.Join(outDirPath, "metadata.go"), 0777, 0666,
+		templatez.MustParseAndExecuteGo(assets.MetadataGoBindingTemplateAsset, getMetadataBindingTemplateData(stage, filepath.Base(outDirPath), false)))
+}
+
+// GenerateMetadataTypeScriptBinding generates a TypeScript module containing the public metadata values of all plugins
+// for the given stage, for use in front-end builds (see GetPublicMetadataValues).
+func GenerateMetadataTypeScriptBinding(stage Stage, outFilePath string) {
+	filez.MustWriteFile(
+		outFilePath, 0777, 0666,
+		templatez.MustParseAndExecuteText(assets.MetadataTypeScriptBindingTemplateAsset, getMetadataBindingTemplateData(stage, "", true)))
+}
+
+func getMetadataBindingTemplateData(stage Stage, packageName string, isPublic bool) *assets.MetadataBindingTemplateData {
+	data := &assets.MetadataBindingTemplateData{
+		PackageName: packageName,
+		StageName:   stage.GetName(),
+		StageTarget: stage.GetTarget().String(),
+		StageMode:   stage.GetMode().String(),
+		Plugins:     make([]*assets.MetadataBindingTemplateDataPlugin, 0),
+	}
+
+	for _, pluginGroup := range stage.GetConfig().App.GetSortedPlugins() {
+		for _, plugin := range pluginGroup {
+			values := getMetadataValues(plugin, isPublic)
+			if len(values) == 0 {
+				continue
+			}
+
+			tplPlugin := &assets.MetadataBindingTemplateDataPlugin{
+				TypeName:    strcase.ToCamel(GetMetadataKey(plugin)),
+				VarName:     strcase.ToLowerCamel(GetMetadataKey(plugin)),
+				DisplayName: plugin.GetDisplayName(),
+				Values:      make([]*assets.MetadataBindingTemplateDataValue, 0, len(values)),
+			}
+
+			for _, value := range values {
+				tplPlugin.Values = append(tplPlugin.Values, &assets.MetadataBindingTemplateDataValue{
+					Name:         value.Name,
+					PropertyName: strcase.ToLowerCamel(value.Name),
+					Value:        value.Value,
+				})
+			}
+
+			data.Plugins = append(data.Plugins, tplPlugin)
+		}
+	}
+
+	sort.SliceStable(data.Plugins, func(i, j int) bool {
+		return data.Plugins[i].TypeName < data.Plugins[j].TypeName
+	})
+
+	return data
+}
+
+func getMetadata(p Plugin) reflect.Value {
+	var method reflect.Value
+	var args []reflect.Value
+
+	switch p.GetStage().GetTarget() {
+	case Local:
+		method = reflect.ValueOf(p).MethodByName("GetLocalMetadata")
+	case Cloud:
+		method = reflect.ValueOf(p).MethodByName("GetCloudMetadata")
+		args = []reflect.Value{reflect.ValueOf(false)}
+	}
+
+	if !method.IsValid() {
+		return reflect.Value{}
+	}
+
+	return method.Call(args)[0]
+}
@@ -0,0 +1,114 @@
+package cloudz
+
+import (
+	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/opz"
+)
+
+// VersionCatalog describes the pinned versions of the third-party components used by plugins and operations.
+// To override some of them, start from NewDefaultVersionCatalog and set AppConfig.Versions to the result.
+type VersionCatalog struct {
+	ActiveMQ    string            `validate:"required"` // i.e. ActiveMQ Classic, both local and cloud
+	Alpine      string            `validate:"required"` // used by Schedule
+	Caddy       string            `validate:"required"` // used by Proxy
+	Cloudflared string            `validate:"required"` // used by Tunnel
+	Debezium    string            `validate:"required"` // used by CDC
+	Debian      string            `validate:"required"` // used by the Hasura console and DataExport
+	Docker      string            `validate:"required"` // i.e. the Docker CLI, used by CronJob and DataExport
+	DuckDB      string            `validate:"required"` // i.e. the DuckDB CLI, used by DataExport
+	DynamoDB    string            `validate:"required"` // i.e. DynamoDB Local, used by WebSocketAPI
+	ElasticMQ   string            `validate:"required"` // i.e. a local SQS, used by EventBus
+	GlitchTip   string            `validate:"required"` // used by ErrorTracking
+	Grafana     string            `validate:"required"` // used by Observability
+	Hasura      string            `validate:"required"`
+	Keycloak    string            `validate:"required"`
+	MailHog     string            `validate:"required"`
+	MinIO       string            `validate:"required"` // used by Bucket
+	MSKIAMAuth  string            `validate:"required"` // used by CDC
+	Nginx       string            `validate:"required"` // used by StaticSite
+	OpenSearch  string            `validate:"required"`
+	PgAdmin     string            `validate:"required"` // used by Postgres
+	Postgres    string            `validate:"required"` // both local and cloud
+	Prism       string            `validate:"required"` // used by API
+	Prometheus  string            `validate:"required"` // used by Observability
+	Redis       string            `validate:"required"` // used by ErrorTracking
+	Redpanda    string            `validate:"required"` // used by Kafka
+	Tools       *opz.ToolVersions `validate:"required"`
+}
+
+// NewDefaultVersionCatalog returns the default version catalog.
+func NewDefaultVersionCatalog() *VersionCatalog {
+	return &VersionCatalog{
+		ActiveMQ:    "5.18.3",
+		Alpine:      "3.15.4",
+		Caddy:       "2.5.1",
+		Cloudflared: "2022.5.1",
+		Debezium:    "1.9.6.Final",
+		Debian:      "bullseye-slim",
+		Docker:      "20.10.14",
+		DuckDB:      "0.10.0",
+		DynamoDB:    "1.18.0",
+		ElasticMQ:   "1.3.9",
+		GlitchTip:   "3.3.1",
+		Grafana:     "9.1.7",
+		Hasura:      "2.5.1",
+		Keycloak:    "19.0.3",
+		MailHog:     "1.0.1",
+		MinIO:       "2022.4.16",
+		MSKIAMAuth:  "1.1.4",
+		Nginx:       "1.21.6",
+		OpenSearch:  "1.3.2",
+		PgAdmin:     "6.8",
+		Postgres:    "12.10",
+		Prism:       "4.10.5",
+		Prometheus:  "2.38.0",
+		Redis:       "7.0.5",
+		Redpanda:    "22.1.3",
+		Tools:       opz.NewDefaultToolVersions(),
+	}
+}
+
+// MustValidate validates the version catalog.
+func (v *VersionCatalog) MustValidate() {
+	vz.MustValidateStruct(v)
+}
+
+// GetUpgradeComponents returns the version catalog as components to be checked for upgrades (see
+// opz.Operations.CheckUpgrades). Debian is excluded, as it is pinned by release name. Debezium is excluded, as its tags
+// are not semantic versions, Keycloak is excluded, as it is only published on Quay, and DuckDB and MSKIAMAuth are
+// excluded, as they are only published as GitHub releases.
+func (v *VersionCatalog) GetUpgradeComponents() []*opz.UpgradeComponent {
+	return append([]*opz.UpgradeComponent{
+		newDockerHubUpgradeComponent("ActiveMQ", "apache/activemq-classic", v.ActiveMQ, "https://activemq.apache.org/components/classic/download/"),
+		newDockerHubUpgradeComponent("Alpine", "library/alpine", v.Alpine, "https://alpinelinux.org/releases/"),
+		newDockerHubUpgradeComponent("Caddy", "library/caddy", v.Caddy, "https://github.com/caddyserver/caddy/releases"),
+		newDockerHubUpgradeComponent("Cloudflared", "cloudflare/cloudflared", v.Cloudflared, "https://github.com/cloudflare/cloudflared/releases"),
+		newDockerHubUpgradeComponent("Docker", "library/docker", v.Docker, "https://docs.docker.com/engine/release-notes/"),
+		newDockerHubUpgradeComponent("DynamoDB", "amazon/dynamodb-local", v.DynamoDB, "https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/DynamoDBLocalHistory.html"),
+		newDockerHubUpgradeComponent("ElasticMQ", "softwaremill/elasticmq-native", v.ElasticMQ, "https://github.com/softwaremill/elasticmq/releases"),
+		newDockerHubUpgradeComponent("GlitchTip", "glitchtip/glitchtip", v.GlitchTip, "https://gitlab.com/glitchtip/glitchtip/-/releases"),
+		newDockerHubUpgradeComponent("Grafana", "grafana/grafana", v.Grafana, "https://github.com/grafana/grafana/releases"),
+		newDockerHubUpgradeComponent("Hasura", "hasura/graphql-engine", v.Hasura, "https://github.com/hasura/graphql-engine/releases"),
+		newDockerHubUpgradeComponent("MailHog", "mailhog/mailhog", v.MailHog, "https://github.com/mailhog/MailHog/releases"),
+		newDockerHubUpgradeComponent("MinIO", "bitnami/minio", v.MinIO, "https://github.com/minio/minio/releases"),
+		newDockerHubUpgradeComponent("Nginx", "library/nginx", v.Nginx, "https://nginx.org/en/CHANGES"),
+		newDockerHubUpgradeComponent("OpenSearch", "opensearchproject/opensearch", v.OpenSearch, "https://github.com/opensearch-project/OpenSearch/releases"),
+		newDockerHubUpgradeComponent("PgAdmin", "dpage/pgadmin4", v.PgAdmin, "https://www.pgadmin.org/docs/pgadmin4/latest/release_notes.html"),
+		newDockerHubUpgradeComponent("Postgres", "library/postgres", v.Postgres, "https://www.postgresql.org/docs/release/"),
+		newDockerHubUpgradeComponent("Prism", "stoplight/prism", v.Prism, "https://github.com/stoplightio/prism/releases"),
+		newDockerHubUpgradeComponent("Prometheus", "prom/prometheus", v.Prometheus, "https://github.com/prometheus/prometheus/releases"),
+		newDockerHubUpgradeComponent("Redis", "library/redis", v.Redis, "https://github.com/redis/redis/releases"),
+		newDockerHubUpgradeComponent("Redpanda", "vectorized/redpanda", v.Redpanda, "https://github.com/redpanda-data/redpanda/releases"),
+	}, v.Tools.GetUpgradeComponents()...)
+}
+
+func newDockerHubUpgradeComponent(name, repository, currentVersion, changelogURL string) *opz.UpgradeComponent {
+	return &opz.UpgradeComponent{
+		Name:           name,
+		Source:         opz.DockerHubUpgradeSource,
+		Package:        repository,
+		CurrentVersion: currentVersion,
+		ChangelogURL:   changelogURL,
+	}
+}
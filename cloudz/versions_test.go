@@ -0,0 +1,69 @@
+package cloudz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionCatalog_MustValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		update  func(v *VersionCatalog)
+		isValid bool
+	}{
+		{
+			name:    "default",
+			update:  func(_ *VersionCatalog) {},
+			isValid: true,
+		},
+		{
+			name:    "override",
+			update:  func(v *VersionCatalog) { v.Postgres = "14.3" },
+			isValid: true,
+		},
+		{
+			name:    "missing version",
+			update:  func(v *VersionCatalog) { v.Hasura = "" },
+			isValid: false,
+		},
+		{
+			name:    "missing tools",
+			update:  func(v *VersionCatalog) { v.Tools = nil },
+			isValid: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			v := NewDefaultVersionCatalog()
+			testCase.update(v)
+
+			if testCase.isValid {
+				require.NotPanics(t, v.MustValidate)
+			} else {
+				require.Panics(t, v.MustValidate)
+			}
+		})
+	}
+}
+
+func TestVersionCatalog_GetUpgradeComponents(t *testing.T) {
+	v := NewDefaultVersionCatalog()
+	v.Postgres = "14.3"
+
+	components := v.GetUpgradeComponents()
+	require.GreaterOrEqual(t, len(components), 11)
+
+	names := map[string]string{}
+	for _, component := range components {
+		names[component.Name] = component.CurrentVersion
+	}
+
+	require.Equal(t, "14.3", names["Postgres"])
+	require.Equal(t, v.Hasura, names["Hasura"])
+	require.NotContains(t, names, "Debian")
+	require.NotContains(t, names, "Keycloak")
+}
@@ -2,24 +2,46 @@ package opz
 
 import (
 	"bytes"
-	"context"
 	"encoding/base64"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awsacm "github.com/aws/aws-sdk-go-v2/service/acm"
+	awsacmt "github.com/aws/aws-sdk-go-v2/service/acm/types"
 	awscf "github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	awscfr "github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	awscfrt "github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+	awscw "github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	awscwt "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
 	awsecr "github.com/aws/aws-sdk-go-v2/service/ecr"
+	awskafka "github.com/aws/aws-sdk-go-v2/service/kafka"
 	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	awsroute53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	awsroute53t "github.com/aws/aws-sdk-go-v2/service/route53/types"
 	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	awss3t "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	awssesv2 "github.com/aws/aws-sdk-go-v2/service/sesv2"
+	awssesv2t "github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	awsssm "github.com/aws/aws-sdk-go-v2/service/ssm"
+	awsssmt "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/ibrt/golang-bites/filez"
 	"github.com/ibrt/golang-errors/errorz"
-	"github.com/ibrt/golang-shell/shellz"
+)
+
+const (
+	metricDataBatchSize = 20
 )
 
 // UploadFile uploads a file to awss3.
 func (o *operationsImpl) UploadFile(bucketName, key, contentType string, body []byte) {
-	_, err := o.awsS3.PutObject(context.Background(), &awss3.PutObjectInput{
+	_, err := o.getAWSClients().s3.PutObject(o.ctx, &awss3.PutObjectInput{
 		Bucket:      aws.String(bucketName),
 		Key:         aws.String(key),
 		Body:        bytes.NewReader(body),
@@ -28,9 +50,145 @@ func (o *operationsImpl) UploadFile(bucketName, key, contentType string, body []
 	errorz.MaybeMustWrap(err)
 }
 
+// SyncDirToBucket uploads the contents of a directory to a S3 bucket, deleting objects that no longer exist locally.
+// HTML files are uploaded with "Cache-Control: no-cache" so that new deployments are picked up immediately.
+func (o *operationsImpl) SyncDirToBucket(dirPath, bucketName string) {
+	keys := map[string]struct{}{}
+
+	errorz.MaybeMustWrap(filepath.Walk(dirPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relPath, err := filepath.Rel(dirPath, filePath)
+		errorz.MaybeMustWrap(err)
+		key := filepath.ToSlash(relPath)
+		keys[key] = struct{}{}
+
+		contentType := mime.TypeByExtension(filepath.Ext(filePath))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		cacheControl := "public, max-age=31536000"
+		if strings.HasPrefix(contentType, "text/html") {
+			cacheControl = "no-cache"
+		}
+
+		_, err = o.getAWSClients().s3.PutObject(o.ctx, &awss3.PutObjectInput{
+			Bucket:       aws.String(bucketName),
+			Key:          aws.String(key),
+			Body:         bytes.NewReader(filez.MustReadFile(filePath)),
+			ContentType:  aws.String(contentType),
+			CacheControl: aws.String(cacheControl),
+		})
+		errorz.MaybeMustWrap(err, errorz.M("bucketName", bucketName), errorz.M("key", key))
+		return nil
+	}))
+
+	staleObjects := make([]awss3t.ObjectIdentifier, 0)
+	paginator := awss3.NewListObjectsV2Paginator(o.getAWSClients().s3, &awss3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+	})
+
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(o.ctx)
+		errorz.MaybeMustWrap(err, errorz.M("bucketName", bucketName))
+
+		for _, object := range out.Contents {
+			if _, ok := keys[*object.Key]; !ok {
+				staleObjects = append(staleObjects, awss3t.ObjectIdentifier{Key: object.Key})
+			}
+		}
+	}
+
+	for i := 0; i < len(staleObjects); i += 1000 {
+		end := i + 1000
+		if end > len(staleObjects) {
+			end = len(staleObjects)
+		}
+
+		_, err := o.getAWSClients().s3.DeleteObjects(o.ctx, &awss3.DeleteObjectsInput{
+			Bucket: aws.String(bucketName),
+			Delete: &awss3t.Delete{
+				Objects: staleObjects[i:end],
+				Quiet:   true,
+			},
+		})
+		errorz.MaybeMustWrap(err, errorz.M("bucketName", bucketName))
+	}
+}
+
+// EmptyBucket deletes all the objects in a S3 bucket, including all the versions and delete markers if versioning is
+// enabled, so that the bucket itself can be deleted. Does nothing if the bucket does not exist.
+func (o *operationsImpl) EmptyBucket(bucketName string) {
+	in := &awss3.ListObjectVersionsInput{
+		Bucket: aws.String(bucketName),
+	}
+
+	for {
+		out, err := o.getAWSClients().s3.ListObjectVersions(o.ctx, in)
+		if err != nil {
+			// TODO(ibrt): Better error handling.
+			if strings.Contains(err.Error(), "NoSuchBucket") {
+				return
+			}
+			errorz.MaybeMustWrap(err, errorz.M("bucketName", bucketName))
+		}
+
+		objects := make([]awss3t.ObjectIdentifier, 0, len(out.Versions)+len(out.DeleteMarkers))
+		for _, version := range out.Versions {
+			objects = append(objects, awss3t.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+		}
+		for _, deleteMarker := range out.DeleteMarkers {
+			objects = append(objects, awss3t.ObjectIdentifier{Key: deleteMarker.Key, VersionId: deleteMarker.VersionId})
+		}
+
+		// Note: a page contains at most 1000 versions and delete markers, i.e. the DeleteObjects limit.
+		if len(objects) > 0 {
+			delOut, err := o.getAWSClients().s3.DeleteObjects(o.ctx, &awss3.DeleteObjectsInput{
+				Bucket: aws.String(bucketName),
+				Delete: &awss3t.Delete{
+					Objects: objects,
+					Quiet:   true,
+				},
+			})
+			errorz.MaybeMustWrap(err, errorz.M("bucketName", bucketName))
+			errorz.Assertf(len(delOut.Errors) == 0, "failed to delete objects: %v",
+				errorz.A(aws.ToString(delOut.Errors[0].Message)), errorz.M("bucketName", bucketName))
+		}
+
+		if !out.IsTruncated {
+			return
+		}
+
+		in.KeyMarker = out.NextKeyMarker
+		in.VersionIdMarker = out.NextVersionIdMarker
+	}
+}
+
+// InvalidateDistribution creates a CloudFront invalidation for the given paths (default: all paths).
+func (o *operationsImpl) InvalidateDistribution(distributionID string, paths ...string) {
+	if len(paths) == 0 {
+		paths = []string{"/*"}
+	}
+
+	_, err := o.getAWSClients().cfr.CreateInvalidation(o.ctx, &awscfr.CreateInvalidationInput{
+		DistributionId: aws.String(distributionID),
+		InvalidationBatch: &awscfrt.InvalidationBatch{
+			CallerReference: aws.String(fmt.Sprintf("%v", time.Now().UnixNano())),
+			Paths: &awscfrt.Paths{
+				Items:    paths,
+				Quantity: aws.Int32(int32(len(paths))),
+			},
+		},
+	})
+	errorz.MaybeMustWrap(err, errorz.M("distributionID", distributionID))
+}
+
 // Decrypt decrypts some data using a KMS key.
 func (o *operationsImpl) Decrypt(keyAlias string, ciphertext []byte) []byte {
-	resp, err := o.awsKMS.Decrypt(context.Background(), &awskms.DecryptInput{
+	resp, err := o.getAWSClients().kms.Decrypt(o.ctx, &awskms.DecryptInput{
 		KeyId:          aws.String("alias/" + keyAlias),
 		CiphertextBlob: ciphertext,
 	})
@@ -40,7 +198,7 @@ func (o *operationsImpl) Decrypt(keyAlias string, ciphertext []byte) []byte {
 
 // Encrypt encrypts some data using a KMS key.
 func (o *operationsImpl) Encrypt(keyAlias string, plaintext []byte) []byte {
-	resp, err := o.awsKMS.Encrypt(context.Background(), &awskms.EncryptInput{
+	resp, err := o.getAWSClients().kms.Encrypt(o.ctx, &awskms.EncryptInput{
 		KeyId:     aws.String("alias/" + keyAlias),
 		Plaintext: plaintext,
 	})
@@ -50,7 +208,7 @@ func (o *operationsImpl) Encrypt(keyAlias string, plaintext []byte) []byte {
 
 // CreateStack creates a CloudFormation stack.
 func (o *operationsImpl) CreateStack(name string, templateBody string, tagsMap map[string]string) *awscft.Stack {
-	_, err := o.awsCF.CreateStack(context.Background(), &awscf.CreateStackInput{
+	_, err := o.getAWSClients().cf.CreateStack(o.ctx, &awscf.CreateStackInput{
 		Capabilities: []awscft.Capability{
 			awscft.CapabilityCapabilityIam,
 			awscft.CapabilityCapabilityNamedIam,
@@ -58,27 +216,18 @@ func (o *operationsImpl) CreateStack(name string, templateBody string, tagsMap m
 		EnableTerminationProtection: aws.Bool(false),
 		OnFailure:                   awscft.OnFailureRollback,
 		StackName:                   aws.String(name),
-		Tags: func() []awscft.Tag {
-			tags := make([]awscft.Tag, 0)
-			for k, v := range tagsMap {
-				tags = append(tags, awscft.Tag{
-					Key:   aws.String(k),
-					Value: aws.String(v),
-				})
-			}
-			return tags
-		}(),
-		TemplateBody:     aws.String(templateBody),
-		TimeoutInMinutes: aws.Int32(30),
+		Tags:                        newStackTags(tagsMap),
+		TemplateBody:                aws.String(templateBody),
+		TimeoutInMinutes:            aws.Int32(30),
 	})
 	errorz.MaybeMustWrap(err, errorz.M("stackName", name))
 
-	errorz.MaybeMustWrap(awscf.NewStackCreateCompleteWaiter(o.awsCF).Wait(
-		context.Background(),
+	errorz.MaybeMustWrap(awscf.NewStackCreateCompleteWaiter(o.getAWSClients().cf).Wait(
+		o.ctx,
 		&awscf.DescribeStacksInput{
 			StackName: aws.String(name),
 		},
-		30*time.Minute),
+		o.getStackWaitTimeout()),
 		errorz.M("stackName", name))
 
 	return o.DescribeStack(name)
@@ -86,7 +235,7 @@ func (o *operationsImpl) CreateStack(name string, templateBody string, tagsMap m
 
 // DescribeStack describes a CloudFormation stack.
 func (o *operationsImpl) DescribeStack(name string) *awscft.Stack {
-	out, err := o.awsCF.DescribeStacks(context.Background(), &awscf.DescribeStacksInput{
+	out, err := o.getAWSClients().cf.DescribeStacks(o.ctx, &awscf.DescribeStacksInput{
 		StackName: aws.String(name),
 	})
 	if err != nil {
@@ -103,22 +252,13 @@ func (o *operationsImpl) DescribeStack(name string) *awscft.Stack {
 
 // UpdateStack updates a CloudFormation stack.
 func (o *operationsImpl) UpdateStack(name string, templateBody string, tagsMap map[string]string) *awscft.Stack {
-	_, err := o.awsCF.UpdateStack(context.Background(), &awscf.UpdateStackInput{
+	_, err := o.getAWSClients().cf.UpdateStack(o.ctx, &awscf.UpdateStackInput{
 		Capabilities: []awscft.Capability{
 			awscft.CapabilityCapabilityIam,
 			awscft.CapabilityCapabilityNamedIam,
 		},
-		StackName: aws.String(name),
-		Tags: func() []awscft.Tag {
-			tags := make([]awscft.Tag, 0)
-			for k, v := range tagsMap {
-				tags = append(tags, awscft.Tag{
-					Key:   aws.String(k),
-					Value: aws.String(v),
-				})
-			}
-			return tags
-		}(),
+		StackName:    aws.String(name),
+		Tags:         newStackTags(tagsMap),
 		TemplateBody: aws.String(templateBody),
 	})
 	if err != nil {
@@ -128,12 +268,12 @@ func (o *operationsImpl) UpdateStack(name string, templateBody string, tagsMap m
 		errorz.MaybeMustWrap(err, errorz.M("stackName", name))
 	}
 
-	errorz.MaybeMustWrap(awscf.NewStackUpdateCompleteWaiter(o.awsCF).Wait(
-		context.Background(),
+	errorz.MaybeMustWrap(awscf.NewStackUpdateCompleteWaiter(o.getAWSClients().cf).Wait(
+		o.ctx,
 		&awscf.DescribeStacksInput{
 			StackName: aws.String(name),
 		},
-		30*time.Minute),
+		o.getStackWaitTimeout()),
 		errorz.M("stackName", name))
 
 	return o.DescribeStack(name)
@@ -147,9 +287,488 @@ func (o *operationsImpl) UpsertStack(name string, templateBody string, tagsMap m
 	return o.UpdateStack(name, templateBody, tagsMap)
 }
 
+// DeleteStack deletes a CloudFormation stack, and waits for the deletion to complete. If some resources fail to be deleted
+// and retainFailedResources is true, it deletes the stack again retaining them. Returns the events of the resources left
+// behind, i.e. retained by their deletion policy or after failing to be deleted. Does nothing if the stack does not exist.
+func (o *operationsImpl) DeleteStack(name string, retainFailedResources bool) []awscft.StackEvent {
+	stack := o.DescribeStack(name)
+	if stack == nil {
+		return nil
+	}
+
+	// Note: deleted stacks can only be described by ID.
+	stackID := aws.ToString(stack.StackId)
+	startTime := time.Now()
+
+	err := o.deleteStack(stackID, nil)
+	if err != nil {
+		failedResources := o.getStackDeleteFailedResources(stackID)
+		if !retainFailedResources || len(failedResources) == 0 {
+			errorz.MaybeMustWrap(err, errorz.M("stackName", name))
+		}
+
+		errorz.MaybeMustWrap(o.deleteStack(stackID, failedResources), errorz.M("stackName", name))
+	}
+
+	return o.getStackDeleteSkippedEvents(stackID, startTime)
+}
+
+// TryCreateStack is like CreateStack, but returns an error instead of panicking (see Try).
+func (o *operationsImpl) TryCreateStack(name string, templateBody string, tagsMap map[string]string) (stack *awscft.Stack, err error) {
+	err = Try(func() {
+		stack = o.CreateStack(name, templateBody, tagsMap)
+	})
+	return stack, err
+}
+
+// TryDescribeStack is like DescribeStack, but returns an error instead of panicking (see Try).
+func (o *operationsImpl) TryDescribeStack(name string) (stack *awscft.Stack, err error) {
+	err = Try(func() {
+		stack = o.DescribeStack(name)
+	})
+	return stack, err
+}
+
+// TryUpdateStack is like UpdateStack, but returns an error instead of panicking (see Try).
+func (o *operationsImpl) TryUpdateStack(name string, templateBody string, tagsMap map[string]string) (stack *awscft.Stack, err error) {
+	err = Try(func() {
+		stack = o.UpdateStack(name, templateBody, tagsMap)
+	})
+	return stack, err
+}
+
+// TryUpsertStack is like UpsertStack, but returns an error instead of panicking (see Try). Note that a stack that failed
+// to be created (i.e. in ROLLBACK_COMPLETE status) cannot be updated, and must be deleted before retrying.
+func (o *operationsImpl) TryUpsertStack(name string, templateBody string, tagsMap map[string]string) (stack *awscft.Stack, err error) {
+	err = Try(func() {
+		stack = o.UpsertStack(name, templateBody, tagsMap)
+	})
+	return stack, err
+}
+
+// TryDeleteStack is like DeleteStack, but returns an error instead of panicking (see Try).
+func (o *operationsImpl) TryDeleteStack(name string, retainFailedResources bool) (events []awscft.StackEvent, err error) {
+	err = Try(func() {
+		events = o.DeleteStack(name, retainFailedResources)
+	})
+	return events, err
+}
+
+func (o *operationsImpl) deleteStack(stackID string, retainResources []string) error {
+	_, err := o.getAWSClients().cf.DeleteStack(o.ctx, &awscf.DeleteStackInput{
+		RetainResources: retainResources,
+		StackName:       aws.String(stackID),
+	})
+	errorz.MaybeMustWrap(err, errorz.M("stackID", stackID))
+
+	return awscf.NewStackDeleteCompleteWaiter(o.getAWSClients().cf).Wait(
+		o.ctx,
+		&awscf.DescribeStacksInput{
+			StackName: aws.String(stackID),
+		},
+		o.getStackWaitTimeout())
+}
+
+func (o *operationsImpl) getStackDeleteFailedResources(stackID string) []string {
+	logicalResourceIDs := make([]string, 0)
+	paginator := awscf.NewListStackResourcesPaginator(o.getAWSClients().cf, &awscf.ListStackResourcesInput{
+		StackName: aws.String(stackID),
+	})
+
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(o.ctx)
+		errorz.MaybeMustWrap(err, errorz.M("stackID", stackID))
+
+		for _, resource := range out.StackResourceSummaries {
+			if resource.ResourceStatus == awscft.ResourceStatusDeleteFailed {
+				logicalResourceIDs = append(logicalResourceIDs, aws.ToString(resource.LogicalResourceId))
+			}
+		}
+	}
+
+	return logicalResourceIDs
+}
+
+func (o *operationsImpl) getStackDeleteSkippedEvents(stackID string, startTime time.Time) []awscft.StackEvent {
+	events := make([]awscft.StackEvent, 0)
+	paginator := awscf.NewDescribeStackEventsPaginator(o.getAWSClients().cf, &awscf.DescribeStackEventsInput{
+		StackName: aws.String(stackID),
+	})
+
+	// Note: events are sorted newest first.
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(o.ctx)
+		errorz.MaybeMustWrap(err, errorz.M("stackID", stackID))
+
+		for _, event := range out.StackEvents {
+			if aws.ToTime(event.Timestamp).Before(startTime) {
+				return events
+			}
+			if event.ResourceStatus == awscft.ResourceStatusDeleteSkipped {
+				events = append(events, event)
+			}
+		}
+	}
+
+	return events
+}
+
+// PreviewStackUpdate returns the resource changes that updating a CloudFormation stack would cause, without applying
+// them. It creates a change set, describes it, then deletes it.
+func (o *operationsImpl) PreviewStackUpdate(name string, templateBody string, tagsMap map[string]string) []awscft.ResourceChange {
+	changeSet := o.CreateChangeSet(name, templateBody, tagsMap)
+	defer o.DeleteChangeSet(name, changeSet.Name)
+	return changeSet.ResourceChanges
+}
+
+// ChangeSet describes a CloudFormation change set.
+type ChangeSet struct {
+	Name            string
+	Status          awscft.ChangeSetStatus
+	StatusReason    string
+	ResourceChanges []awscft.ResourceChange
+}
+
+// IsEmpty returns true if the change set contains no changes. Such change sets fail, and cannot be executed.
+func (c *ChangeSet) IsEmpty() bool {
+	return c.Status == awscft.ChangeSetStatusFailed && strings.Contains(c.StatusReason, "didn't contain changes")
+}
+
+// CreateChangeSet creates a change set to update an existing CloudFormation stack, waits for it to be ready, then
+// describes it. The change set is not executed. Change sets without changes are returned as well (see ChangeSet.IsEmpty).
+func (o *operationsImpl) CreateChangeSet(stackName string, templateBody string, tagsMap map[string]string) *ChangeSet {
+	changeSetName := fmt.Sprintf("preview-%v", time.Now().UnixNano())
+
+	_, err := o.getAWSClients().cf.CreateChangeSet(o.ctx, &awscf.CreateChangeSetInput{
+		Capabilities: []awscft.Capability{
+			awscft.CapabilityCapabilityIam,
+			awscft.CapabilityCapabilityNamedIam,
+		},
+		ChangeSetName: aws.String(changeSetName),
+		ChangeSetType: awscft.ChangeSetTypeUpdate,
+		StackName:     aws.String(stackName),
+		Tags:          newStackTags(tagsMap),
+		TemplateBody:  aws.String(templateBody),
+	})
+	errorz.MaybeMustWrap(err, errorz.M("stackName", stackName))
+
+	// Note: the waiter also fails if the change set is empty, which is detected below.
+	waitErr := awscf.NewChangeSetCreateCompleteWaiter(o.getAWSClients().cf).Wait(
+		o.ctx,
+		&awscf.DescribeChangeSetInput{
+			ChangeSetName: aws.String(changeSetName),
+			StackName:     aws.String(stackName),
+		},
+		o.getStackWaitTimeout())
+
+	changeSet := o.DescribeChangeSet(stackName, changeSetName)
+	if changeSet.IsEmpty() {
+		return changeSet
+	}
+
+	if changeSet.Status == awscft.ChangeSetStatusFailed {
+		o.DeleteChangeSet(stackName, changeSetName)
+		panic(errorz.Errorf("change set failed: %v", errorz.A(changeSet.StatusReason), errorz.M("stackName", stackName)))
+	}
+	errorz.MaybeMustWrap(waitErr, errorz.M("stackName", stackName))
+
+	return changeSet
+}
+
+// DescribeChangeSet describes a CloudFormation change set, including all its resource changes.
+func (o *operationsImpl) DescribeChangeSet(stackName string, changeSetName string) *ChangeSet {
+	changeSet := &ChangeSet{
+		Name:            changeSetName,
+		ResourceChanges: make([]awscft.ResourceChange, 0),
+	}
+
+	in := &awscf.DescribeChangeSetInput{
+		ChangeSetName: aws.String(changeSetName),
+		StackName:     aws.String(stackName),
+	}
+
+	for {
+		out, err := o.getAWSClients().cf.DescribeChangeSet(o.ctx, in)
+		errorz.MaybeMustWrap(err, errorz.M("stackName", stackName), errorz.M("changeSetName", changeSetName))
+
+		changeSet.Status = out.Status
+		changeSet.StatusReason = aws.ToString(out.StatusReason)
+
+		for _, change := range out.Changes {
+			if change.ResourceChange != nil {
+				changeSet.ResourceChanges = append(changeSet.ResourceChanges, *change.ResourceChange)
+			}
+		}
+
+		if out.NextToken == nil {
+			return changeSet
+		}
+		in.NextToken = out.NextToken
+	}
+}
+
+// DeleteChangeSet deletes a CloudFormation change set. This is best effort: errors are ignored, as a leftover change set
+// is harmless, and panicking here would mask the original error, if any, when deferred.
+func (o *operationsImpl) DeleteChangeSet(stackName string, changeSetName string) {
+	_, _ = o.getAWSClients().cf.DeleteChangeSet(o.ctx, &awscf.DeleteChangeSetInput{
+		ChangeSetName: aws.String(changeSetName),
+		StackName:     aws.String(stackName),
+	})
+}
+
+// DescribeStackEvents returns the most recent events of a CloudFormation stack, newest first. Returns nil if not found.
+func (o *operationsImpl) DescribeStackEvents(name string) []awscft.StackEvent {
+	out, err := o.getAWSClients().cf.DescribeStackEvents(o.ctx, &awscf.DescribeStackEventsInput{
+		StackName: aws.String(name),
+	})
+	if err != nil {
+		// TODO(ibrt): Better error handling.
+		if strings.Contains(err.Error(), "does not exist") {
+			return nil
+		}
+		errorz.MaybeMustWrap(err, errorz.M("stackName", name))
+	}
+
+	return out.StackEvents
+}
+
+// DescribeCertificate describes an ACM certificate. Returns nil if not found.
+func (o *operationsImpl) DescribeCertificate(arn string) *awsacmt.CertificateDetail {
+	out, err := o.getAWSClients().acm.DescribeCertificate(o.ctx, &awsacm.DescribeCertificateInput{
+		CertificateArn: aws.String(arn),
+	})
+	if err != nil {
+		// TODO(ibrt): Better error handling.
+		if strings.Contains(err.Error(), "ResourceNotFoundException") {
+			return nil
+		}
+		errorz.MaybeMustWrap(err, errorz.M("certificateARN", arn))
+	}
+
+	return out.Certificate
+}
+
+// ListStackExports lists all the CloudFormation exports in the current account and region.
+func (o *operationsImpl) ListStackExports() []awscft.Export {
+	exports := make([]awscft.Export, 0)
+	paginator := awscf.NewListExportsPaginator(o.getAWSClients().cf, &awscf.ListExportsInput{})
+
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(o.ctx)
+		errorz.MaybeMustWrap(err)
+		exports = append(exports, out.Exports...)
+	}
+
+	return exports
+}
+
+func newStackTags(tagsMap map[string]string) []awscft.Tag {
+	tags := make([]awscft.Tag, 0)
+	for k, v := range tagsMap {
+		tags = append(tags, awscft.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(v),
+		})
+	}
+	return tags
+}
+
+// GetHostedZone gets a Route53 hosted zone. Returns nil if not found.
+func (o *operationsImpl) GetHostedZone(id string) *awsroute53.GetHostedZoneOutput {
+	out, err := o.getAWSClients().route53.GetHostedZone(o.ctx, &awsroute53.GetHostedZoneInput{
+		Id: aws.String(id),
+	})
+	if err != nil {
+		// TODO(ibrt): Better error handling.
+		if strings.Contains(err.Error(), "NoSuchHostedZone") {
+			return nil
+		}
+		errorz.MaybeMustWrap(err, errorz.M("hostedZoneID", id))
+	}
+
+	return out
+}
+
+// UpsertRecordSet creates or updates a simple Route53 record set.
+func (o *operationsImpl) UpsertRecordSet(hostedZoneID, name, recordType, value string, ttl int64) {
+	_, err := o.getAWSClients().route53.ChangeResourceRecordSets(o.ctx, &awsroute53.ChangeResourceRecordSetsInput{
+		ChangeBatch: &awsroute53t.ChangeBatch{
+			Changes: []awsroute53t.Change{
+				{
+					Action: awsroute53t.ChangeActionUpsert,
+					ResourceRecordSet: &awsroute53t.ResourceRecordSet{
+						Name: aws.String(name),
+						Type: awsroute53t.RRType(recordType),
+						TTL:  aws.Int64(ttl),
+						ResourceRecords: []awsroute53t.ResourceRecord{
+							{
+								Value: aws.String(value),
+							},
+						},
+					},
+				},
+			},
+		},
+		HostedZoneId: aws.String(hostedZoneID),
+	})
+	errorz.MaybeMustWrap(err, errorz.M("hostedZoneID", hostedZoneID), errorz.M("name", name))
+}
+
+// DeleteRecordSet deletes a simple Route53 record set, as created by UpsertRecordSet. Does nothing if it does not exist.
+func (o *operationsImpl) DeleteRecordSet(hostedZoneID, name, recordType, value string, ttl int64) {
+	_, err := o.getAWSClients().route53.ChangeResourceRecordSets(o.ctx, &awsroute53.ChangeResourceRecordSetsInput{
+		ChangeBatch: &awsroute53t.ChangeBatch{
+			Changes: []awsroute53t.Change{
+				{
+					Action: awsroute53t.ChangeActionDelete,
+					ResourceRecordSet: &awsroute53t.ResourceRecordSet{
+						Name: aws.String(name),
+						Type: awsroute53t.RRType(recordType),
+						TTL:  aws.Int64(ttl),
+						ResourceRecords: []awsroute53t.ResourceRecord{
+							{
+								Value: aws.String(value),
+							},
+						},
+					},
+				},
+			},
+		},
+		HostedZoneId: aws.String(hostedZoneID),
+	})
+	if err != nil {
+		// TODO(ibrt): Better error handling.
+		if strings.Contains(err.Error(), "not found") {
+			return
+		}
+		errorz.MaybeMustWrap(err, errorz.M("hostedZoneID", hostedZoneID), errorz.M("name", name))
+	}
+}
+
+// PutParameter creates or updates an SSM parameter. If isSecure is true, it is stored as a SecureString encrypted with
+// the default SSM key.
+func (o *operationsImpl) PutParameter(name, value string, isSecure bool) {
+	parameterType := awsssmt.ParameterTypeString
+	if isSecure {
+		parameterType = awsssmt.ParameterTypeSecureString
+	}
+
+	_, err := o.getAWSClients().ssm.PutParameter(o.ctx, &awsssm.PutParameterInput{
+		Name:      aws.String(name),
+		Value:     aws.String(value),
+		Type:      parameterType,
+		Overwrite: true,
+	})
+	errorz.MaybeMustWrap(err, errorz.M("name", name))
+}
+
+// DeleteParameter deletes an SSM parameter. Does nothing if it does not exist.
+func (o *operationsImpl) DeleteParameter(name string) {
+	_, err := o.getAWSClients().ssm.DeleteParameter(o.ctx, &awsssm.DeleteParameterInput{
+		Name: aws.String(name),
+	})
+	if err != nil {
+		// TODO(ibrt): Better error handling.
+		if strings.Contains(err.Error(), "ParameterNotFound") {
+			return
+		}
+		errorz.MaybeMustWrap(err, errorz.M("name", name))
+	}
+}
+
+// MetricDatum describes a CloudWatch custom metric data point.
+// The unit is a CloudWatch standard unit (e.g. "Seconds", "Count"), it defaults to "None".
+type MetricDatum struct {
+	Name       string
+	Dimensions map[string]string
+	Value      float64
+	Unit       string
+}
+
+// PutMetricData publishes custom metric data points to CloudWatch, in batches.
+func (o *operationsImpl) PutMetricData(namespace string, data []*MetricDatum) {
+	timestamp := time.Now()
+
+	for start := 0; start < len(data); start += metricDataBatchSize {
+		end := start + metricDataBatchSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		metricData := make([]awscwt.MetricDatum, 0, end-start)
+
+		for _, datum := range data[start:end] {
+			unit := awscwt.StandardUnitNone
+			if datum.Unit != "" {
+				unit = awscwt.StandardUnit(datum.Unit)
+			}
+
+			dimensionNames := make([]string, 0, len(datum.Dimensions))
+			for k := range datum.Dimensions {
+				dimensionNames = append(dimensionNames, k)
+			}
+			sort.Strings(dimensionNames)
+
+			dimensions := make([]awscwt.Dimension, 0, len(dimensionNames))
+			for _, k := range dimensionNames {
+				dimensions = append(dimensions, awscwt.Dimension{
+					Name:  aws.String(k),
+					Value: aws.String(datum.Dimensions[k]),
+				})
+			}
+
+			metricData = append(metricData, awscwt.MetricDatum{
+				MetricName: aws.String(datum.Name),
+				Dimensions: dimensions,
+				Value:      aws.Float64(datum.Value),
+				Unit:       unit,
+				Timestamp:  aws.Time(timestamp),
+			})
+		}
+
+		_, err := o.getAWSClients().cw.PutMetricData(o.ctx, &awscw.PutMetricDataInput{
+			Namespace:  aws.String(namespace),
+			MetricData: metricData,
+		})
+		errorz.MaybeMustWrap(err, errorz.M("namespace", namespace))
+	}
+}
+
+// GetKafkaBootstrapBrokers returns the IAM-authenticated bootstrap brokers string for an MSK cluster.
+func (o *operationsImpl) GetKafkaBootstrapBrokers(clusterARN string) string {
+	out, err := o.getAWSClients().kafka.GetBootstrapBrokers(o.ctx, &awskafka.GetBootstrapBrokersInput{
+		ClusterArn: aws.String(clusterARN),
+	})
+	errorz.MaybeMustWrap(err, errorz.M("clusterARN", clusterARN))
+	errorz.Assertf(out.BootstrapBrokerStringSaslIam != nil, "missing IAM bootstrap brokers", errorz.M("clusterARN", clusterARN))
+	return *out.BootstrapBrokerStringSaslIam
+}
+
+// SendRawEmail sends a raw (RFC 5322) email using SES. The configuration set name is optional.
+func (o *operationsImpl) SendRawEmail(configurationSetName, from string, to []string, msg []byte) {
+	input := &awssesv2.SendEmailInput{
+		Content: &awssesv2t.EmailContent{
+			Raw: &awssesv2t.RawMessage{
+				Data: msg,
+			},
+		},
+		Destination: &awssesv2t.Destination{
+			ToAddresses: to,
+		},
+		FromEmailAddress: aws.String(from),
+	}
+
+	if configurationSetName != "" {
+		input.ConfigurationSetName = aws.String(configurationSetName)
+	}
+
+	_, err := o.getAWSClients().sesv2.SendEmail(o.ctx, input)
+	errorz.MaybeMustWrap(err, errorz.M("from", from))
+}
+
 // DockerLoginToECR runs "docker login" with credentials that allow access to ECR image repositories.
 func (o *operationsImpl) DockerLoginToECR() {
-	out, err := o.awsECR.GetAuthorizationToken(context.Background(), &awsecr.GetAuthorizationTokenInput{})
+	out, err := o.getAWSClients().ecr.GetAuthorizationToken(o.ctx, &awsecr.GetAuthorizationTokenInput{})
 	errorz.MaybeMustWrap(err)
 
 	buf, err := base64.StdEncoding.DecodeString(*out.AuthorizationData[0].AuthorizationToken)
@@ -158,7 +777,7 @@ func (o *operationsImpl) DockerLoginToECR() {
 	userPass := strings.SplitN(string(buf), ":", 2)
 	errorz.Assertf(len(userPass) == 2, "malformed authorization data")
 
-	shellz.NewCommand("docker", "login",
+	o.NewCommand("docker", "login",
 		"--username", userPass[0],
 		"--password-stdin",
 		strings.TrimPrefix(*out.AuthorizationData[0].ProxyEndpoint, "https://")).
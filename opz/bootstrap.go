@@ -0,0 +1,173 @@
+package opz
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	awsiam "github.com/aws/aws-sdk-go-v2/service/iam"
+	gocf "github.com/awslabs/goformation/v6/cloudformation"
+	goiam "github.com/awslabs/goformation/v6/cloudformation/iam"
+	gos3 "github.com/awslabs/goformation/v6/cloudformation/s3"
+	gossm "github.com/awslabs/goformation/v6/cloudformation/ssm"
+	"github.com/ibrt/golang-bites/boolz"
+	"github.com/ibrt/golang-bites/numeric/intz"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// Account bootstrap constants.
+const (
+	// BootstrapVersion is the version of the account bootstrap, increased when BootstrapAccount changes.
+	BootstrapVersion = "1"
+
+	// BootstrapStackName is the name of the CloudFormation stack created by BootstrapAccount.
+	BootstrapStackName = "golang-cloud-bootstrap"
+
+	// BootstrapVersionParameterName is the name of the SSM parameter holding the bootstrap version.
+	BootstrapVersionParameterName = "/golang-cloud/bootstrap-version"
+
+	bootstrapOutputArtifactsBucketName = "ArtifactsBucketName"
+	bootstrapOutputDeployRoleARN       = "DeployRoleArn"
+)
+
+var (
+	bootstrapServiceLinkedRoles = map[string]string{
+		"ecs.amazonaws.com": "AWSServiceRoleForECS",
+		"rds.amazonaws.com": "AWSServiceRoleForRDS", // also used by RDS Proxy
+	}
+)
+
+// AccountBootstrap describes the resources created by BootstrapAccount.
+type AccountBootstrap struct {
+	Version             string
+	ArtifactsBucketName string
+	DeployRoleARN       string
+}
+
+// BootstrapAccount prepares the current AWS account and region for deploying apps, and can be re-run safely (e.g. after
+// BootstrapVersion changes). It enables EBS encryption by default, creates the service-linked roles needed by ECS and
+// RDS (which otherwise fail the first deployment in a fresh account), and deploys a stack containing:
+// - An encrypted, versioned, private artifacts bucket, e.g. for use as FunctionDependencies.ArtifactsBucket.
+// - A deploy role with administrator access, assumable by the principals of the account allowed to do so.
+// - An SSM parameter (BootstrapVersionParameterName) marking the account as bootstrapped.
+func (o *operationsImpl) BootstrapAccount() *AccountBootstrap {
+	_, err := o.getAWSClients().ec2.EnableEbsEncryptionByDefault(o.ctx, &awsec2.EnableEbsEncryptionByDefaultInput{})
+	errorz.MaybeMustWrap(err)
+
+	for serviceName, roleName := range bootstrapServiceLinkedRoles {
+		o.ensureServiceLinkedRole(serviceName, roleName)
+	}
+
+	buf, err := newBootstrapTemplate().JSON()
+	errorz.MaybeMustWrap(err)
+
+	stack := o.UpsertStack(BootstrapStackName, string(buf), map[string]string{
+		"BootstrapVersion": BootstrapVersion,
+	})
+
+	bootstrap := &AccountBootstrap{
+		Version: BootstrapVersion,
+	}
+
+	for _, output := range stack.Outputs {
+		switch aws.ToString(output.OutputKey) {
+		case bootstrapOutputArtifactsBucketName:
+			bootstrap.ArtifactsBucketName = aws.ToString(output.OutputValue)
+		case bootstrapOutputDeployRoleARN:
+			bootstrap.DeployRoleARN = aws.ToString(output.OutputValue)
+		}
+	}
+
+	return bootstrap
+}
+
+func (o *operationsImpl) ensureServiceLinkedRole(serviceName, roleName string) {
+	_, err := o.getAWSClients().iam.GetRole(o.ctx, &awsiam.GetRoleInput{
+		RoleName: aws.String(roleName),
+	})
+	if err == nil {
+		return
+	}
+
+	// TODO(ibrt): Better error handling.
+	errorz.Assertf(strings.Contains(err.Error(), "NoSuchEntity"), "unexpected error: %v", errorz.A(err.Error()))
+
+	_, err = o.getAWSClients().iam.CreateServiceLinkedRole(o.ctx, &awsiam.CreateServiceLinkedRoleInput{
+		AWSServiceName: aws.String(serviceName),
+	})
+	errorz.MaybeMustWrap(err, errorz.M("serviceName", serviceName))
+}
+
+func newBootstrapTemplate() *gocf.Template {
+	tpl := gocf.NewTemplate()
+
+	tpl.Resources["ArtifactsBucket"] = &gos3.Bucket{
+		BucketName: stringz.Ptr(gocf.Sub("golang-cloud-artifacts-${AWS::AccountId}-${AWS::Region}")),
+		BucketEncryption: &gos3.Bucket_BucketEncryption{
+			ServerSideEncryptionConfiguration: []gos3.Bucket_ServerSideEncryptionRule{
+				{
+					ServerSideEncryptionByDefault: &gos3.Bucket_ServerSideEncryptionByDefault{
+						SSEAlgorithm: "AES256",
+					},
+				},
+			},
+		},
+		LifecycleConfiguration: &gos3.Bucket_LifecycleConfiguration{
+			Rules: []gos3.Bucket_Rule{
+				{
+					Id:     stringz.Ptr("expire-noncurrent-versions"),
+					Status: "Enabled",
+					NoncurrentVersionExpiration: &gos3.Bucket_NoncurrentVersionExpiration{
+						NoncurrentDays: 30,
+					},
+				},
+			},
+		},
+		PublicAccessBlockConfiguration: &gos3.Bucket_PublicAccessBlockConfiguration{
+			BlockPublicAcls:       boolz.Ptr(true),
+			BlockPublicPolicy:     boolz.Ptr(true),
+			IgnorePublicAcls:      boolz.Ptr(true),
+			RestrictPublicBuckets: boolz.Ptr(true),
+		},
+		VersioningConfiguration: &gos3.Bucket_VersioningConfiguration{
+			Status: "Enabled",
+		},
+	}
+
+	tpl.Resources["DeployRole"] = &goiam.Role{
+		AssumeRolePolicyDocument: map[string]interface{}{
+			"Version": "2012-10-17",
+			"Statement": []interface{}{
+				map[string]interface{}{
+					"Effect": "Allow",
+					"Principal": map[string]interface{}{
+						"AWS": gocf.Sub("arn:aws:iam::${AWS::AccountId}:root"),
+					},
+					"Action": "sts:AssumeRole",
+				},
+			},
+		},
+		ManagedPolicyArns: &[]string{
+			"arn:aws:iam::aws:policy/AdministratorAccess",
+		},
+		MaxSessionDuration: intz.Ptr(3600 * 4),
+		RoleName:           stringz.Ptr(gocf.Sub("golang-cloud-deploy-${AWS::Region}")),
+	}
+
+	tpl.Resources["BootstrapVersionParameter"] = &gossm.Parameter{
+		Name:  stringz.Ptr(BootstrapVersionParameterName),
+		Type:  "String",
+		Value: BootstrapVersion,
+	}
+
+	tpl.Outputs[bootstrapOutputArtifactsBucketName] = gocf.Output{
+		Value: gocf.Ref("ArtifactsBucket"),
+	}
+
+	tpl.Outputs[bootstrapOutputDeployRoleARN] = gocf.Output{
+		Value: gocf.GetAtt("DeployRole", "Arn"),
+	}
+
+	return tpl
+}
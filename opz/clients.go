@@ -0,0 +1,207 @@
+package opz
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsacm "github.com/aws/aws-sdk-go-v2/service/acm"
+	awscf "github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	awscfr "github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	awscw "github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	awsec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	awsecr "github.com/aws/aws-sdk-go-v2/service/ecr"
+	awselbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	awsiam "github.com/aws/aws-sdk-go-v2/service/iam"
+	awskafka "github.com/aws/aws-sdk-go-v2/service/kafka"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	awspi "github.com/aws/aws-sdk-go-v2/service/pi"
+	awsrds "github.com/aws/aws-sdk-go-v2/service/rds"
+	awsroute53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	awssq "github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	awssesv2 "github.com/aws/aws-sdk-go-v2/service/sesv2"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	awsssm "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// AWSClientEndpointsAllServices can be used as key in AWSClientOptionEndpoints to match all services.
+const AWSClientEndpointsAllServices = "*"
+
+// AWSClientOption describes an option for the AWS SDK clients used by Operations.
+type AWSClientOption func(options *awsClientOptions)
+
+type awsClientOptions struct {
+	endpoints      map[string]string
+	useFIPS        bool
+	httpProxyURL   *url.URL
+	caBundlePEM    []byte
+	s3UsePathStyle bool
+}
+
+// AWSClientOptionEndpoints is an AWS client option that overrides the endpoints of some or all services, e.g. for use
+// with LocalStack. The keys are AWS SDK service IDs (e.g. "CloudFormation", "S3"), or AWSClientEndpointsAllServices.
+// Overriding the S3 endpoint also enables path-style addressing, as required by most S3-compatible endpoints.
+func AWSClientOptionEndpoints(endpoints map[string]string) AWSClientOption {
+	for serviceID, endpointURL := range endpoints {
+		_, err := url.Parse(endpointURL)
+		errorz.MaybeMustWrap(err, errorz.M("serviceID", serviceID))
+	}
+
+	return func(o *awsClientOptions) {
+		o.endpoints = endpoints
+		_, hasAll := endpoints[AWSClientEndpointsAllServices]
+		_, hasS3 := endpoints["S3"]
+		o.s3UsePathStyle = hasAll || hasS3
+	}
+}
+
+// AWSClientOptionFIPS is an AWS client option that enables FIPS endpoints (e.g. for GovCloud regions).
+func AWSClientOptionFIPS() AWSClientOption {
+	return func(o *awsClientOptions) {
+		o.useFIPS = true
+	}
+}
+
+// AWSClientOptionHTTPProxy is an AWS client option that routes all requests through the given HTTP proxy.
+func AWSClientOptionHTTPProxy(proxyURL string) AWSClientOption {
+	u, err := url.Parse(proxyURL)
+	errorz.MaybeMustWrap(err)
+
+	return func(o *awsClientOptions) {
+		o.httpProxyURL = u
+	}
+}
+
+// AWSClientOptionCABundle is an AWS client option that adds the certificates from the given PEM file to the trusted
+// root certificates, e.g. for use behind a TLS-intercepting corporate proxy.
+func AWSClientOptionCABundle(pemFilePath string) AWSClientOption {
+	caBundlePEM := filez.MustReadFile(pemFilePath)
+
+	return func(o *awsClientOptions) {
+		o.caBundlePEM = caBundlePEM
+	}
+}
+
+type awsClients struct {
+	acm     *awsacm.Client
+	cf      *awscf.Client
+	cfr     *awscfr.Client
+	cw      *awscw.Client
+	ec2     *awsec2.Client
+	ecr     *awsecr.Client
+	elbv2   *awselbv2.Client
+	iam     *awsiam.Client
+	kafka   *awskafka.Client
+	kms     *awskms.Client
+	pi      *awspi.Client
+	rds     *awsrds.Client
+	route53 *awsroute53.Client
+	s3      *awss3.Client
+	sesv2   *awssesv2.Client
+	sq      *awssq.Client
+	sqs     *awssqs.Client
+	ssm     *awsssm.Client
+}
+
+func newAWSClients(awsCfg *aws.Config, options ...AWSClientOption) *awsClients {
+	clientCfg, resolvedOptions := newAWSClientConfig(awsCfg, options...)
+
+	return &awsClients{
+		acm:     awsacm.NewFromConfig(clientCfg),
+		cf:      awscf.NewFromConfig(clientCfg),
+		cfr:     awscfr.NewFromConfig(clientCfg),
+		cw:      awscw.NewFromConfig(clientCfg),
+		ec2:     awsec2.NewFromConfig(clientCfg),
+		ecr:     awsecr.NewFromConfig(clientCfg),
+		elbv2:   awselbv2.NewFromConfig(clientCfg),
+		iam:     awsiam.NewFromConfig(clientCfg),
+		kafka:   awskafka.NewFromConfig(clientCfg),
+		kms:     awskms.NewFromConfig(clientCfg),
+		pi:      awspi.NewFromConfig(clientCfg),
+		rds:     awsrds.NewFromConfig(clientCfg),
+		route53: awsroute53.NewFromConfig(clientCfg),
+		s3:      awss3.NewFromConfig(clientCfg, resolvedOptions.applyS3Options),
+		sesv2:   awssesv2.NewFromConfig(clientCfg),
+		sq:      awssq.NewFromConfig(clientCfg),
+		sqs:     awssqs.NewFromConfig(clientCfg),
+		ssm:     awsssm.NewFromConfig(clientCfg),
+	}
+}
+
+// newAWSClientConfig returns a copy of the given AWS config with the given options applied.
+func newAWSClientConfig(awsCfg *aws.Config, options ...AWSClientOption) (aws.Config, *awsClientOptions) {
+	resolvedOptions := &awsClientOptions{}
+	for _, option := range options {
+		option(resolvedOptions)
+	}
+
+	cfg := awsCfg.Copy()
+
+	if len(resolvedOptions.endpoints) > 0 {
+		cfg.EndpointResolverWithOptions = aws.EndpointResolverWithOptionsFunc(
+			func(serviceID, region string, _ ...interface{}) (aws.Endpoint, error) {
+				endpointURL, ok := resolvedOptions.endpoints[serviceID]
+				if !ok {
+					endpointURL, ok = resolvedOptions.endpoints[AWSClientEndpointsAllServices]
+				}
+				if !ok {
+					return aws.Endpoint{}, &aws.EndpointNotFoundError{} // fall back to the default resolver
+				}
+
+				return aws.Endpoint{
+					URL:               endpointURL,
+					SigningRegion:     region,
+					HostnameImmutable: true,
+				}, nil
+			})
+	}
+
+	if resolvedOptions.useFIPS {
+		cfg.ConfigSources = append(cfg.ConfigSources, fipsEndpointConfigSource{})
+	}
+
+	if resolvedOptions.httpProxyURL != nil || resolvedOptions.caBundlePEM != nil {
+		var rootCAs *x509.CertPool
+
+		if resolvedOptions.caBundlePEM != nil {
+			var err error
+			rootCAs, err = x509.SystemCertPool()
+			errorz.MaybeMustWrap(err)
+			errorz.Assertf(rootCAs.AppendCertsFromPEM(resolvedOptions.caBundlePEM), "no certificates found in CA bundle")
+		}
+
+		cfg.HTTPClient = awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+			if resolvedOptions.httpProxyURL != nil {
+				tr.Proxy = http.ProxyURL(resolvedOptions.httpProxyURL)
+			}
+
+			if rootCAs != nil {
+				if tr.TLSClientConfig == nil {
+					tr.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+				}
+				tr.TLSClientConfig.RootCAs = rootCAs
+			}
+		})
+	}
+
+	return cfg, resolvedOptions
+}
+
+func (o *awsClientOptions) applyS3Options(s3Options *awss3.Options) {
+	s3Options.UsePathStyle = o.s3UsePathStyle
+}
+
+// fipsEndpointConfigSource is picked up from aws.Config.ConfigSources by the service clients.
+type fipsEndpointConfigSource struct{}
+
+// GetUseFIPSEndpoint implements the interface used by the service clients to resolve FIPS endpoint usage.
+func (fipsEndpointConfigSource) GetUseFIPSEndpoint(_ context.Context) (aws.FIPSEndpointState, bool, error) {
+	return aws.FIPSEndpointStateEnabled, true, nil
+}
@@ -0,0 +1,288 @@
+package opz
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-shell/shellz"
+)
+
+const (
+	commandRedactedParam = "<redacted>"
+)
+
+// CommandPolicy describes the policy applied to the shell commands run by Operations and plugins.
+//
+// If DryRun is set, commands are logged and recorded but not run, and their output is empty. If Allowlist is not empty,
+// only the listed executables (matched by base name, e.g. "docker") can be run, and attempts to run others fail. If
+// Timeout is set, commands running longer than it are killed. If Sink is set, it receives a CommandRecord for each
+// command, including its combined captured output, e.g. to keep an audit log of Deploy and Create runs.
+type CommandPolicy struct {
+	DryRun    bool
+	Allowlist []string
+	Timeout   time.Duration
+	Sink      CommandSinkFunc
+}
+
+// CommandSinkFunc receives the records of the commands run.
+type CommandSinkFunc func(record *CommandRecord)
+
+// CommandRecord describes a command run (or skipped, in dry-run mode). Secret params are redacted.
+type CommandRecord struct {
+	Cmd       string
+	Params    []string
+	Dir       string
+	StartedAt time.Time
+	Duration  time.Duration
+	DryRun    bool
+	Output    string
+	Err       error
+}
+
+// IsAllowed returns true if the policy allows running the given executable.
+func (p *CommandPolicy) IsAllowed(cmd string) bool {
+	if p == nil || len(p.Allowlist) == 0 {
+		return true
+	}
+
+	for _, allowed := range p.Allowlist {
+		if strings.TrimSuffix(filepath.Base(cmd), ".exe") == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Command describes a shell command, run according to a CommandPolicy. It mirrors the shellz.Command API.
+type Command struct {
+	ctx          context.Context
+	policy       *CommandPolicy
+	cmd          string
+	params       []string
+	secretParams map[int]struct{}
+	quiet        bool
+	env          map[string]string
+	dir          string
+	stdin        io.Reader
+	stdout       io.Writer
+	stderr       io.Writer
+}
+
+// NewCommand initializes a new Command, run according to the command policy of the Operations.
+func (o *operationsImpl) NewCommand(cmd string, initialParams ...interface{}) *Command {
+	return (&Command{
+		ctx:          o.ctx,
+		policy:       o.commandPolicy,
+		cmd:          cmd,
+		secretParams: map[int]struct{}{},
+		env:          map[string]string{},
+	}).AddParams(initialParams...)
+}
+
+// AddParams adds params to the command.
+func (c *Command) AddParams(params ...interface{}) *Command {
+	for _, param := range params {
+		c.params = append(c.params, fmt.Sprintf("%v", param))
+	}
+	return c
+}
+
+// AddParamsString adds params to the command.
+func (c *Command) AddParamsString(params ...string) *Command {
+	c.params = append(c.params, params...)
+	return c
+}
+
+// AddSecretParams adds params to the command, which are redacted when logged or recorded.
+func (c *Command) AddSecretParams(params ...string) *Command {
+	for _, param := range params {
+		c.secretParams[len(c.params)] = struct{}{}
+		c.params = append(c.params, param)
+	}
+	return c
+}
+
+// SetQuiet disables logging the command (it is still recorded).
+func (c *Command) SetQuiet() *Command {
+	c.quiet = true
+	return c
+}
+
+// SetDir sets the working dir of the command.
+func (c *Command) SetDir(dir string) *Command {
+	c.dir = dir
+	return c
+}
+
+// SetStdin sets the stdin of the command.
+func (c *Command) SetStdin(stdin io.Reader) *Command {
+	c.stdin = stdin
+	return c
+}
+
+// SetStdout sets the stdout of the command.
+func (c *Command) SetStdout(stdout io.Writer) *Command {
+	c.stdout = stdout
+	return c
+}
+
+// SetStderr sets the stderr of the command.
+func (c *Command) SetStderr(stderr io.Writer) *Command {
+	c.stderr = stderr
+	return c
+}
+
+// SetEnv sets an environment variable for the command.
+func (c *Command) SetEnv(key, value string) *Command {
+	c.env[key] = value
+	return c
+}
+
+// SetEnvMap sets environment variables for the command.
+func (c *Command) SetEnvMap(m map[string]string) *Command {
+	for k, v := range m {
+		c.env[k] = v
+	}
+	return c
+}
+
+// Run runs the command.
+func (c *Command) Run() error {
+	stdout := c.stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+
+	_, err := c.run(stdout)
+	return errorz.MaybeWrap(err, errorz.Skip())
+}
+
+// MustRun runs the command, panics on error.
+func (c *Command) MustRun() {
+	errorz.MaybeMustWrap(c.Run(), errorz.Skip())
+}
+
+// Output runs the command and returns its trimmed stdout.
+func (c *Command) Output() (string, error) {
+	out, err := c.run(nil)
+	if err != nil {
+		return "", errorz.Wrap(err, errorz.Skip())
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// MustOutput runs the command and returns its trimmed stdout, panics on error.
+func (c *Command) MustOutput() string {
+	out, err := c.Output()
+	errorz.MaybeMustWrap(err, errorz.Skip())
+	return out
+}
+
+func (c *Command) run(stdout io.Writer) (string, error) {
+	redactedParams := c.getRedactedParams()
+
+	if !c.policy.IsAllowed(c.cmd) {
+		return "", errorz.Errorf("command not allowed: %v", errorz.A(c.cmd))
+	}
+
+	if !c.quiet {
+		shellz.DefaultLogf(c.cmd, stringsToInterfaces(redactedParams)...)
+	}
+
+	record := &CommandRecord{
+		Cmd:       c.cmd,
+		Params:    redactedParams,
+		Dir:       c.dir,
+		StartedAt: time.Now().UTC(),
+		DryRun:    c.policy != nil && c.policy.DryRun,
+	}
+
+	if record.DryRun {
+		c.maybeSink(record)
+		return "", nil
+	}
+
+	ctx := c.ctx
+
+	if c.policy != nil && c.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.policy.Timeout)
+		defer cancel()
+	}
+
+	stdoutBuf := &bytes.Buffer{}
+	combinedBuf := &bytes.Buffer{}
+
+	stderr := c.stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	cmd := exec.CommandContext(ctx, c.cmd, c.params...)
+	cmd.Dir = c.dir
+	cmd.Stdin = c.stdin
+	cmd.Stderr = io.MultiWriter(stderr, combinedBuf)
+	cmd.Env = os.Environ()
+
+	if stdout != nil {
+		cmd.Stdout = io.MultiWriter(stdout, combinedBuf)
+	} else {
+		cmd.Stdout = io.MultiWriter(stdoutBuf, combinedBuf)
+	}
+
+	for k, v := range c.env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	err := cmd.Run()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if c.ctx.Err() == nil {
+			err = errorz.Errorf("command timed out after %v: %v", errorz.A(c.policy.Timeout, c.cmd))
+		} else {
+			err = errorz.Errorf("command canceled: %v: %v", errorz.A(c.cmd, ctxErr))
+		}
+	}
+
+	record.Duration = time.Since(record.StartedAt)
+	record.Output = combinedBuf.String()
+	record.Err = err
+	c.maybeSink(record)
+
+	return stdoutBuf.String(), errorz.MaybeWrap(err)
+}
+
+func (c *Command) getRedactedParams() []string {
+	redactedParams := make([]string, 0, len(c.params))
+
+	for i, param := range c.params {
+		if _, ok := c.secretParams[i]; ok {
+			param = commandRedactedParam
+		}
+		redactedParams = append(redactedParams, param)
+	}
+
+	return redactedParams
+}
+
+func (c *Command) maybeSink(record *CommandRecord) {
+	if c.policy != nil && c.policy.Sink != nil {
+		c.policy.Sink(record)
+	}
+}
+
+func stringsToInterfaces(values []string) []interface{} {
+	interfaces := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		interfaces = append(interfaces, value)
+	}
+	return interfaces
+}
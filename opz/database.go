@@ -10,14 +10,18 @@ import (
 	"strings"
 
 	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/templatez"
 	"github.com/ibrt/golang-errors/errorz"
 	"github.com/ibrt/golang-inject-pg/pgz/testpgz"
 	"github.com/volatiletech/sqlboiler/v4/boilingcore"
 	"github.com/volatiletech/sqlboiler/v4/drivers"
 	"github.com/volatiletech/sqlboiler/v4/importers"
+	"github.com/volatiletech/strmangle"
 
 	_ "github.com/volatiletech/sqlboiler/v4/drivers/sqlboiler-psql/driver"    // SQLBoiler Postgres driver
 	_ "github.com/volatiletech/sqlboiler/v4/drivers/sqlboiler-sqlite3/driver" // SQLBoiler SQLite driver
+
+	"github.com/ibrt/golang-cloud/opz/internal/assets"
 )
 
 type sqlBoilerORMOptions struct {
@@ -89,11 +93,6 @@ func NewSQLBoilerORMTypeReplace(table, column string, nullable bool, fullType st
 func (o *operationsImpl) GeneratePostgresSQLBoilerORM(pgURL string, outDirPath string, options ...SQLBoilerORMOption) {
 	filez.MustPrepareDir(outDirPath, 0777)
 
-	parsedPGURL, err := url.Parse(pgURL)
-	errorz.MaybeMustWrap(err)
-	pass, ok := parsedPGURL.User.Password()
-	errorz.Assertf(ok, "no password specified in pgURL")
-
 	resolvedOptions := &sqlBoilerORMOptions{}
 	for _, option := range options {
 		option(resolvedOptions)
@@ -103,16 +102,8 @@ func (o *operationsImpl) GeneratePostgresSQLBoilerORM(pgURL string, outDirPath s
 		Aliases: boilingcore.Aliases{
 			Tables: resolvedOptions.tableAliases,
 		},
-		DriverName: "psql",
-		DriverConfig: map[string]interface{}{
-			"dbname":    path.Base(parsedPGURL.Path),
-			"host":      parsedPGURL.Hostname(),
-			"port":      parsedPGURL.Port(),
-			"user":      parsedPGURL.User.Username(),
-			"pass":      pass,
-			"sslmode":   parsedPGURL.Query().Get("sslmode"),
-			"blacklist": resolvedOptions.blacklist,
-		},
+		DriverName:      "psql",
+		DriverConfig:    getSQLBoilerPostgresDriverConfig(pgURL, resolvedOptions),
 		PkgName:         filepath.Base(outDirPath),
 		Imports:         importers.NewDefaultImports(),
 		OutFolder:       outDirPath,
@@ -127,6 +118,74 @@ func (o *operationsImpl) GeneratePostgresSQLBoilerORM(pgURL string, outDirPath s
 	errorz.MaybeMustWrap(state.Cleanup())
 }
 
+// GeneratePostgresSQLBoilerFactories generates test data factories for the SQLBoiler ORM of a Postgres database, as
+// generated by GeneratePostgresSQLBoilerORM with the same options. A "NewX(overrides ...func(m *models.X)) *models.X"
+// function is generated for each model, populating the required columns with fake values based on their type. Nullable
+// columns, columns with defaults, foreign keys, and columns with replaced types are left empty, and can be set using
+// the overrides. The ORM package is imported from modelsImportPath.
+func (o *operationsImpl) GeneratePostgresSQLBoilerFactories(pgURL string, modelsImportPath string, outDirPath string, options ...SQLBoilerORMOption) {
+	filez.MustPrepareDir(outDirPath, 0777)
+
+	resolvedOptions := &sqlBoilerORMOptions{}
+	for _, option := range options {
+		option(resolvedOptions)
+	}
+
+	dbInfo, err := drivers.GetDriver("psql").Assemble(getSQLBoilerPostgresDriverConfig(pgURL, resolvedOptions))
+	errorz.MaybeMustWrap(err)
+
+	data := &assets.SQLBoilerFactoriesTemplateData{
+		PackageName:      filepath.Base(outDirPath),
+		ModelsImportPath: modelsImportPath,
+		Models:           make([]*assets.SQLBoilerFactoriesTemplateDataModel, 0),
+	}
+
+	for _, table := range dbInfo.Tables {
+		if table.IsView || table.IsJoinTable {
+			continue
+		}
+
+		tableAlias := resolvedOptions.tableAliases[table.Name]
+		model := &assets.SQLBoilerFactoriesTemplateDataModel{
+			Name:   strmangle.TitleCase(strmangle.Singular(table.Name)),
+			Fields: make([]*assets.SQLBoilerFactoriesTemplateDataField, 0),
+		}
+		if tableAlias.UpSingular != "" {
+			model.Name = tableAlias.UpSingular
+		}
+
+		for _, column := range table.Columns {
+			if column.Nullable || column.Default != "" || column.AutoGenerated ||
+				isSQLBoilerForeignKey(table, column) || isSQLBoilerTypeReplaced(resolvedOptions, table, column) {
+				continue
+			}
+
+			value := getSQLBoilerFactoryFakeValue(column)
+			if value == "" {
+				continue
+			}
+
+			field := &assets.SQLBoilerFactoriesTemplateDataField{
+				Name:  strmangle.TitleCase(column.Name),
+				Value: value,
+			}
+			if columnAlias := tableAlias.Columns[column.Name]; columnAlias != "" {
+				field.Name = columnAlias
+			}
+
+			data.ImportTime = data.ImportTime || column.Type == "time.Time"
+			data.ImportTypes = data.ImportTypes || strings.HasPrefix(column.Type, "types.")
+			model.Fields = append(model.Fields, field)
+		}
+
+		data.Models = append(data.Models, model)
+	}
+
+	filez.MustWriteFile(
+		filepath.Join(outDirPath, "factories.go"), 0777, 0666,
+		templatez.MustParseAndExecuteGo(assets.SQLBoilerFactoriesTemplateAsset, data))
+}
+
 // GenerateSQLiteSQLBoilerORM generates a SQLBoiler ORM for a SQLite database.
 func (o *operationsImpl) GenerateSQLiteSQLBoilerORM(dbSpec string, outDirPath string, options ...SQLBoilerORMOption) {
 	filez.MustPrepareDir(outDirPath, 0777)
@@ -246,3 +305,227 @@ func RevertPostgresHasuraMigrations(pgURL string, embedFS embed.FS, embedMigrati
 
 	errorz.MaybeMustWrap(tx.Commit())
 }
+
+func getSQLBoilerPostgresDriverConfig(pgURL string, resolvedOptions *sqlBoilerORMOptions) map[string]interface{} {
+	parsedPGURL, err := url.Parse(pgURL)
+	errorz.MaybeMustWrap(err)
+	pass, ok := parsedPGURL.User.Password()
+	errorz.Assertf(ok, "no password specified in pgURL")
+
+	return map[string]interface{}{
+		"dbname":    path.Base(parsedPGURL.Path),
+		"host":      parsedPGURL.Hostname(),
+		"port":      parsedPGURL.Port(),
+		"user":      parsedPGURL.User.Username(),
+		"pass":      pass,
+		"sslmode":   parsedPGURL.Query().Get("sslmode"),
+		"blacklist": resolvedOptions.blacklist,
+	}
+}
+
+func isSQLBoilerForeignKey(table drivers.Table, column drivers.Column) bool {
+	for _, fKey := range table.FKeys {
+		if fKey.Column == column.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func isSQLBoilerTypeReplaced(resolvedOptions *sqlBoilerORMOptions, table drivers.Table, column drivers.Column) bool {
+	for _, typeReplace := range resolvedOptions.typeReplaces {
+		if typeReplace.Match.Name != column.Name {
+			continue
+		}
+
+		for _, tableName := range typeReplace.Tables {
+			if tableName == table.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func getSQLBoilerFactoryFakeValue(column drivers.Column) string {
+	switch column.Type {
+	case "string":
+		if column.DBType == "uuid" {
+			return "fakeUUID()"
+		}
+		return fmt.Sprintf(`fmt.Sprintf("%v-%%v", s)`, column.Name)
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64":
+		return fmt.Sprintf("%v(s)", column.Type)
+	case "time.Time":
+		return "time.Now().UTC().Truncate(time.Microsecond)"
+	case "[]byte":
+		return fmt.Sprintf(`[]byte(fmt.Sprintf("%v-%%v", s))`, column.Name)
+	case "types.JSON":
+		return `types.JSON("{}")`
+	case "types.StringArray":
+		return fmt.Sprintf(`types.StringArray{fmt.Sprintf("%v-%%v", s)}`, column.Name)
+	default:
+		return "" // e.g. bool, enums, and other types for which the zero value is fine or a fake can't be generated
+	}
+}
+
+// GeneratePostgresERD generates an entity-relationship diagram of the "public" schema of the given Postgres database,
+// using the information schema. The output is a PlantUML diagram if outFilePath has a ".puml" or ".plantuml" extension,
+// and a Mermaid diagram otherwise. It is meant to be run as part of code generation, after applying the migrations.
+func (o *operationsImpl) GeneratePostgresERD(pgURL string, outFilePath string) {
+	tables := loadPostgresERDTables(pgURL)
+
+	switch filepath.Ext(outFilePath) {
+	case ".puml", ".plantuml":
+		filez.MustWriteFile(outFilePath, 0777, 0666, []byte(renderPostgresERDPlantUML(tables)))
+	default:
+		filez.MustWriteFile(outFilePath, 0777, 0666, []byte(renderPostgresERDMermaid(tables)))
+	}
+}
+
+type postgresERDTable struct {
+	Name    string
+	Columns []*postgresERDColumn
+}
+
+type postgresERDColumn struct {
+	Name         string
+	Type         string
+	Nullable     bool
+	PrimaryKey   bool
+	ForeignTable string
+}
+
+func loadPostgresERDTables(pgURL string) []*postgresERDTable {
+	db := testpgz.MustOpen(pgURL)
+	defer errorz.IgnoreClose(db)
+
+	tables := make([]*postgresERDTable, 0)
+	columns := map[string]*postgresERDColumn{}
+
+	rows, err := db.Query(`
+		SELECT c.table_name, c.column_name, c.data_type, c.is_nullable = 'YES'
+		FROM information_schema.columns c
+		JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = 'public' AND t.table_type = 'BASE TABLE'
+		ORDER BY c.table_name, c.ordinal_position`)
+	errorz.MaybeMustWrap(err)
+	defer errorz.IgnoreClose(rows)
+
+	for rows.Next() {
+		var tableName string
+		column := &postgresERDColumn{}
+		errorz.MaybeMustWrap(rows.Scan(&tableName, &column.Name, &column.Type, &column.Nullable))
+
+		if len(tables) == 0 || tables[len(tables)-1].Name != tableName {
+			tables = append(tables, &postgresERDTable{Name: tableName})
+		}
+
+		tables[len(tables)-1].Columns = append(tables[len(tables)-1].Columns, column)
+		columns[tableName+"."+column.Name] = column
+	}
+	errorz.MaybeMustWrap(rows.Err())
+
+	constraintRows, err := db.Query(`
+		SELECT tc.table_name, kcu.column_name, tc.constraint_type, ccu.table_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+		JOIN information_schema.constraint_column_usage ccu ON ccu.constraint_schema = tc.constraint_schema AND ccu.constraint_name = tc.constraint_name
+		WHERE tc.table_schema = 'public' AND tc.constraint_type IN ('PRIMARY KEY', 'FOREIGN KEY')`)
+	errorz.MaybeMustWrap(err)
+	defer errorz.IgnoreClose(constraintRows)
+
+	for constraintRows.Next() {
+		var tableName, columnName, constraintType, foreignTableName string
+		errorz.MaybeMustWrap(constraintRows.Scan(&tableName, &columnName, &constraintType, &foreignTableName))
+
+		if column, ok := columns[tableName+"."+columnName]; ok {
+			switch constraintType {
+			case "PRIMARY KEY":
+				column.PrimaryKey = true
+			case "FOREIGN KEY":
+				column.ForeignTable = foreignTableName
+			}
+		}
+	}
+	errorz.MaybeMustWrap(constraintRows.Err())
+
+	return tables
+}
+
+func renderPostgresERDMermaid(tables []*postgresERDTable) string {
+	b := &strings.Builder{}
+	b.WriteString("erDiagram\n")
+
+	for _, table := range tables {
+		fmt.Fprintf(b, "    %v {\n", table.Name)
+		for _, column := range table.Columns {
+			keys := make([]string, 0, 2)
+			if column.PrimaryKey {
+				keys = append(keys, "PK")
+			}
+			if column.ForeignTable != "" {
+				keys = append(keys, "FK")
+			}
+			fmt.Fprintf(b, "        %v %v %v\n", strings.ReplaceAll(column.Type, " ", "_"), column.Name, strings.Join(keys, ","))
+		}
+		b.WriteString("    }\n")
+	}
+
+	for _, table := range tables {
+		for _, column := range table.Columns {
+			if column.ForeignTable != "" {
+				fmt.Fprintf(b, "    %v %v--o{ %v : %q\n", column.ForeignTable, getPostgresERDCardinality(column), table.Name, column.Name)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func renderPostgresERDPlantUML(tables []*postgresERDTable) string {
+	b := &strings.Builder{}
+	b.WriteString("@startuml\nhide circle\nskinparam linetype ortho\n\n")
+
+	for _, table := range tables {
+		fmt.Fprintf(b, "entity %v {\n", table.Name)
+		for _, column := range table.Columns {
+			if column.PrimaryKey {
+				fmt.Fprintf(b, "    * %v : %v <<PK>>\n", column.Name, column.Type)
+			}
+		}
+		b.WriteString("    --\n")
+		for _, column := range table.Columns {
+			if !column.PrimaryKey {
+				marker := "*"
+				if column.Nullable {
+					marker = " "
+				}
+				suffix := ""
+				if column.ForeignTable != "" {
+					suffix = " <<FK>>"
+				}
+				fmt.Fprintf(b, "    %v %v : %v%v\n", marker, column.Name, column.Type, suffix)
+			}
+		}
+		b.WriteString("}\n\n")
+	}
+
+	for _, table := range tables {
+		for _, column := range table.Columns {
+			if column.ForeignTable != "" {
+				fmt.Fprintf(b, "%v %v--o{ %v : %v\n", column.ForeignTable, getPostgresERDCardinality(column), table.Name, column.Name)
+			}
+		}
+	}
+
+	b.WriteString("@enduml\n")
+	return b.String()
+}
+
+func getPostgresERDCardinality(column *postgresERDColumn) string {
+	if column.Nullable {
+		return "|o"
+	}
+	return "||"
+}
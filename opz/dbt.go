@@ -0,0 +1,252 @@
+package opz
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	dbtDefaultSchema  = "public"
+	dbtDefaultThreads = 4
+	dbtPasswordEnvVar = "DBT_ENV_SECRET_PASSWORD" // the "DBT_ENV_SECRET_" prefix makes dbt scrub it from logs
+)
+
+// DBTAdapter describes a dbt adapter, i.e. the kind of database a dbt project runs against.
+type DBTAdapter string
+
+// Known dbt adapters.
+const (
+	DBTAdapterPostgres DBTAdapter = "postgres" // e.g. Postgres plugin URLs, Warehouse plugin local URLs
+	DBTAdapterRedshift DBTAdapter = "redshift" // e.g. Warehouse plugin cloud URL
+)
+
+// DBTTarget describes the database a dbt project runs against.
+//
+// URL is a "postgres://" URL, as found in the metadata of the Postgres and Warehouse plugins. If Password is set, it
+// overrides the one in the URL (e.g. because the Warehouse plugin cloud URL doesn't include it). The password is passed
+// to dbt through the environment, and it is never written to the generated profile or the artifacts.
+type DBTTarget struct {
+	Name     string     `validate:"required"`
+	Adapter  DBTAdapter `validate:"required,oneof=postgres redshift"`
+	URL      string     `validate:"required,url"`
+	Password string
+	Schema   string // defaults to "public"
+	Threads  int    `validate:"omitempty,min=1,max=64"`
+}
+
+// MustValidate validates the dbt target.
+func (t *DBTTarget) MustValidate() {
+	vz.MustValidateStruct(t)
+}
+
+// DBTRunReport describes the results of a dbt run.
+type DBTRunReport struct {
+	ArtifactsDirPath string
+	ElapsedTime      time.Duration
+	Results          []*DBTRunResult
+}
+
+// CountByStatus returns the number of results for each status.
+func (r *DBTRunReport) CountByStatus() map[string]int {
+	counts := map[string]int{}
+	for _, result := range r.Results {
+		counts[result.Status]++
+	}
+	return counts
+}
+
+// Print prints a summary of the report.
+func (r *DBTRunReport) Print(w io.Writer) {
+	statuses := make([]string, 0)
+	for status, count := range r.CountByStatus() {
+		statuses = append(statuses, fmt.Sprintf("%v=%v", status, count))
+	}
+	sort.Strings(statuses)
+
+	_, _ = fmt.Fprintf(w, "elapsed time: %v\n", r.ElapsedTime)
+	_, _ = fmt.Fprintf(w, "results: %v\n", strings.Join(statuses, " "))
+	_, _ = fmt.Fprintf(w, "artifacts: %v\n", r.ArtifactsDirPath)
+}
+
+// DBTRunResult describes the result of a dbt node (e.g. model, test, seed, snapshot) in a dbt run.
+type DBTRunResult struct {
+	UniqueID      string
+	Status        string
+	ExecutionTime time.Duration
+	Message       string
+}
+
+type dbtRunResults struct {
+	ElapsedTime float64 `json:"elapsed_time"`
+	Results     []struct {
+		UniqueID      string  `json:"unique_id"`
+		Status        string  `json:"status"`
+		ExecutionTime float64 `json:"execution_time"`
+		Message       string  `json:"message"`
+	} `json:"results"`
+}
+
+// RunDBT runs "dbt build" (in Docker) on the given dbt project against the given target, restricted to the given
+// selectors if any (e.g. "tag:daily", "+orders"). Packages are installed first if the project declares any. The profile,
+// compiled SQL, logs, and artifacts (e.g. "manifest.json", "run_results.json") are stored under the build dir, and the
+// results are returned (see also DBTRunReport.Print).
+func (o *operationsImpl) RunDBT(projectDirPath string, target *DBTTarget, selectors []string) *DBTRunReport {
+	target.MustValidate()
+
+	workDirPath := filepath.Join(o.buildDirPath, "dbt", target.Name, time.Now().UTC().Format("20060102T150405"))
+	filez.MustPrepareDir(workDirPath, 0777)
+
+	profile, password := newDBTProfile(getDBTProjectProfileName(projectDirPath), target)
+	filez.MustWriteFile(filepath.Join(workDirPath, "profiles.yml"), 0777, 0666, profile)
+
+	if filez.MustCheckExists(filepath.Join(projectDirPath, "packages.yml")) {
+		o.getDBTCommand(projectDirPath, workDirPath, target, password).
+			AddParams("deps").
+			AddParams("--profiles-dir", "/work").
+			AddParams("--log-path", "/work/logs").
+			MustRun()
+	}
+
+	err := o.getDBTCommand(projectDirPath, workDirPath, target, password).
+		AddParams("build").
+		AddParams("--profiles-dir", "/work").
+		AddParams("--target", target.Name).
+		AddParams("--target-path", "/work/target").
+		AddParams("--log-path", "/work/logs").
+		AddParamsString(getDBTSelectorParams(selectors)...).
+		Run()
+
+	// Note: dbt exits with an error if any node fails, but still writes the artifacts, which are referenced by the error.
+	if err != nil && !filez.MustCheckExists(filepath.Join(workDirPath, "target", "run_results.json")) {
+		errorz.MaybeMustWrap(err)
+	}
+
+	report := loadDBTRunReport(workDirPath)
+	errorz.MaybeMustWrap(err, errorz.M("artifactsDirPath", report.ArtifactsDirPath), errorz.M("counts", report.CountByStatus()))
+	return report
+}
+
+func (o *operationsImpl) getDBTCommand(projectDirPath, workDirPath string, target *DBTTarget, password string) *Command {
+	image := fmt.Sprintf("ghcr.io/dbt-labs/dbt-postgres:%v", o.toolVersions.DBTPostgres)
+	if target.Adapter == DBTAdapterRedshift {
+		image = fmt.Sprintf("ghcr.io/dbt-labs/dbt-redshift:%v", o.toolVersions.DBTRedshift)
+	}
+
+	return o.NewCommand("docker", "run", "--rm", "--network", "host").
+		AddParams("-v", fmt.Sprintf("%v:/usr/app", filez.MustAbs(projectDirPath))).
+		AddParams("-v", fmt.Sprintf("%v:/work", filez.MustAbs(workDirPath))).
+		AddParams("-w", "/usr/app").
+		AddParams("-u", fmt.Sprintf("%v:%v", os.Getuid(), os.Getgid())).
+		AddParams("-e", "HOME=/tmp").
+		AddParams("-e", dbtPasswordEnvVar). // inherited from the environment, so it doesn't appear in the params
+		SetEnv(dbtPasswordEnvVar, password).
+		AddParams(image)
+}
+
+func getDBTProjectProfileName(projectDirPath string) string {
+	project := &struct {
+		Profile string `yaml:"profile"`
+	}{}
+
+	projectFilePath := filepath.Join(projectDirPath, "dbt_project.yml")
+	errorz.MaybeMustWrap(yaml.Unmarshal(filez.MustReadFile(projectFilePath), project), errorz.M("filePath", projectFilePath))
+	errorz.Assertf(project.Profile != "", "missing profile in dbt project: %v", errorz.A(projectFilePath))
+	return project.Profile
+}
+
+func newDBTProfile(profileName string, target *DBTTarget) ([]byte, string) {
+	u, err := url.Parse(target.URL)
+	errorz.MaybeMustWrap(err)
+	errorz.Assertf(u.Scheme == "postgres" || u.Scheme == "postgresql", "unsupported dbt target URL scheme: %v", errorz.A(u.Scheme))
+
+	password, _ := u.User.Password()
+	if target.Password != "" {
+		password = target.Password
+	}
+
+	port := 5432
+	if u.Port() != "" {
+		port, err = strconv.Atoi(u.Port())
+		errorz.MaybeMustWrap(err)
+	}
+
+	schema := target.Schema
+	if schema == "" {
+		schema = dbtDefaultSchema
+	}
+
+	threads := target.Threads
+	if threads == 0 {
+		threads = dbtDefaultThreads
+	}
+
+	output := map[string]interface{}{
+		"type":     string(target.Adapter),
+		"host":     u.Hostname(),
+		"port":     port,
+		"user":     u.User.Username(),
+		"password": fmt.Sprintf("{{ env_var('%v') }}", dbtPasswordEnvVar),
+		"dbname":   strings.TrimPrefix(u.Path, "/"),
+		"schema":   schema,
+		"threads":  threads,
+	}
+
+	if sslMode := u.Query().Get("sslmode"); sslMode != "" {
+		output["sslmode"] = sslMode
+	}
+
+	buf, err := yaml.Marshal(map[string]interface{}{
+		profileName: map[string]interface{}{
+			"target": target.Name,
+			"outputs": map[string]interface{}{
+				target.Name: output,
+			},
+		},
+	})
+	errorz.MaybeMustWrap(err)
+
+	return buf, password
+}
+
+func getDBTSelectorParams(selectors []string) []string {
+	if len(selectors) == 0 {
+		return nil
+	}
+	return append([]string{"--select"}, selectors...)
+}
+
+func loadDBTRunReport(workDirPath string) *DBTRunReport {
+	runResultsFilePath := filepath.Join(workDirPath, "target", "run_results.json")
+	runResults := &dbtRunResults{}
+	errorz.MaybeMustWrap(json.Unmarshal(filez.MustReadFile(runResultsFilePath), runResults), errorz.M("filePath", runResultsFilePath))
+
+	report := &DBTRunReport{
+		ArtifactsDirPath: workDirPath,
+		ElapsedTime:      time.Duration(runResults.ElapsedTime * float64(time.Second)),
+		Results:          make([]*DBTRunResult, 0, len(runResults.Results)),
+	}
+
+	for _, result := range runResults.Results {
+		report.Results = append(report.Results, &DBTRunResult{
+			UniqueID:      result.UniqueID,
+			Status:        result.Status,
+			ExecutionTime: time.Duration(result.ExecutionTime * float64(time.Second)),
+			Message:       result.Message,
+		})
+	}
+
+	return report
+}
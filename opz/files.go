@@ -0,0 +1,59 @@
+package opz
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// MustCopyDir recursively copies the contents of srcDirPath to dstDirPath, which is created if it does not exist. It
+// preserves file permissions where supported, and is a portable replacement for "cp -R".
+func MustCopyDir(srcDirPath, dstDirPath string) {
+	errorz.MaybeMustWrap(filepath.WalkDir(srcDirPath, func(srcPath string, entry fs.DirEntry, err error) error {
+		errorz.MaybeMustWrap(err)
+
+		relPath, err := filepath.Rel(srcDirPath, srcPath)
+		errorz.MaybeMustWrap(err)
+
+		info, err := entry.Info()
+		errorz.MaybeMustWrap(err)
+
+		if entry.IsDir() {
+			errorz.MaybeMustWrap(os.MkdirAll(filepath.Join(dstDirPath, relPath), info.Mode().Perm()|0700))
+			return nil
+		}
+
+		mustCopyFile(srcPath, filepath.Join(dstDirPath, relPath), info.Mode().Perm())
+		return nil
+	}), errorz.M("srcDirPath", srcDirPath), errorz.M("dstDirPath", dstDirPath))
+}
+
+func mustCopyFile(srcFilePath, dstFilePath string, mode os.FileMode) {
+	src, err := os.Open(srcFilePath)
+	errorz.MaybeMustWrap(err)
+	defer errorz.IgnoreClose(src)
+
+	dst, err := os.OpenFile(dstFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	errorz.MaybeMustWrap(err)
+	defer errorz.IgnoreClose(dst)
+
+	_, err = io.Copy(dst, src)
+	errorz.MaybeMustWrap(err)
+	errorz.MaybeMustWrap(dst.Close())
+}
+
+// openInBrowser opens the given file or URL with the default application, depending on the OS.
+func (o *operationsImpl) openInBrowser(fileOrURL string) {
+	switch runtime.GOOS {
+	case "darwin":
+		o.NewCommand("open", fileOrURL).MustRun()
+	case "windows":
+		o.NewCommand("rundll32", "url.dll,FileProtocolHandler", fileOrURL).MustRun()
+	default:
+		o.NewCommand("xdg-open", fileOrURL).MustRun()
+	}
+}
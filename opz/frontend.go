@@ -0,0 +1,38 @@
+package opz
+
+import (
+	"path/filepath"
+
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+var (
+	frontendBuildDirNames = []string{
+		"build", // create-react-app
+		"dist",  // vite, vue-cli
+	}
+)
+
+// BuildAndDeployFrontend runs "yarn build" in the given directory with the given environment, uploads the build
+// output to the given S3 bucket, and invalidates the given CloudFront distribution (if not empty).
+func (o *operationsImpl) BuildAndDeployFrontend(dirPath string, envMap map[string]string, bucketName, distributionID string) {
+	o.NewCommand("yarn", "install", "--frozen-lockfile").SetDir(dirPath).MustRun()
+	o.NewCommand("yarn", "build").SetDir(dirPath).SetEnvMap(envMap).MustRun()
+
+	o.SyncDirToBucket(getFrontendBuildDirPath(dirPath), bucketName)
+
+	if distributionID != "" {
+		o.InvalidateDistribution(distributionID)
+	}
+}
+
+func getFrontendBuildDirPath(dirPath string) string {
+	for _, buildDirName := range frontendBuildDirNames {
+		if buildDirPath := filepath.Join(dirPath, buildDirName); filez.MustCheckExists(buildDirPath) {
+			return buildDirPath
+		}
+	}
+
+	panic(errorz.Errorf("frontend build output not found in %v (tried %v)", errorz.A(dirPath, frontendBuildDirNames)))
+}
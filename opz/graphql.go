@@ -1,8 +1,14 @@
 package opz
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/gobuffalo/flect"
@@ -11,8 +17,10 @@ import (
 	"github.com/ibrt/golang-bites/filez"
 	"github.com/ibrt/golang-bites/jsonz"
 	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-errors/errorz"
 	"github.com/vektah/gqlparser"
 	"github.com/vektah/gqlparser/ast"
+	"github.com/vektah/gqlparser/parser"
 
 	"github.com/ibrt/golang-cloud/opz/internal/assets"
 )
@@ -23,7 +31,8 @@ func (o *operationsImpl) GenerateHasuraGraphQLSchema(hsURL, adminSecret, role, o
 		AddParams(hsURL).
 		AddParams("--introspect").
 		AddParams("--format", "graphql").
-		AddParams("-H", fmt.Sprintf("X-Hasura-Admin-Secret: %v", adminSecret)).
+		AddParams("-H").
+		AddSecretParams(fmt.Sprintf("X-Hasura-Admin-Secret: %v", adminSecret)).
 		AddParams("-H", fmt.Sprintf("X-Hasura-Role: %v", role)).
 		MustOutput()
 
@@ -103,3 +112,216 @@ func (o *operationsImpl) GenerateHasuraGraphQLTypescriptBinding(schemaFilePath,
 
 	o.GetNodeToolCommand(GraphQLCodeGen).AddParams("-c", configFilePath).MustRun()
 }
+
+// HasuraQueryAnalysisEntry describes the analysis of a single GraphQL operation.
+type HasuraQueryAnalysisEntry struct {
+	FilePath      string
+	OperationName string
+	SeqScans      []string
+	Error         string
+	InAllowList   bool
+}
+
+// GetIssues returns a description of the issues found for the operation, if any.
+func (e *HasuraQueryAnalysisEntry) GetIssues() []string {
+	issues := make([]string, 0)
+	if e.Error != "" {
+		issues = append(issues, "error: "+e.Error)
+	}
+	if len(e.SeqScans) > 0 {
+		issues = append(issues, fmt.Sprintf("sequential scans on %v", strings.Join(e.SeqScans, ", ")))
+	}
+	if !e.InAllowList {
+		issues = append(issues, "not in allow-list")
+	}
+	return issues
+}
+
+// String implements the fmt.Stringer interface.
+func (e *HasuraQueryAnalysisEntry) String() string {
+	issues := e.GetIssues()
+	if len(issues) == 0 {
+		issues = append(issues, "ok")
+	}
+	return fmt.Sprintf("%v (%v): %v", e.OperationName, e.FilePath, strings.Join(issues, "; "))
+}
+
+// AnalyzeHasuraQueries analyzes the GraphQL operations matching the given glob path against a running Hasura endpoint
+// (typically the local one), using the given role. For each operation it reports:
+// - sequential scans in the query plans returned by Hasura's explain API (often a sign of missing indexes)
+// - errors returned by the explain API (e.g. fields not covered by permissions for the role)
+// - whether the operation is part of a query collection in the allow-list
+// Mutations are not supported by the explain API, so they are only checked against the allow-list. The entries are
+// returned, see HasuraQueryAnalysisEntry.GetIssues.
+func (o *operationsImpl) AnalyzeHasuraQueries(hsURL, adminSecret, role, queriesGlobPath string) []*HasuraQueryAnalysisEntry {
+	baseURL := getHasuraBaseURL(hsURL)
+	allowList := getHasuraAllowList(o.ctx, baseURL, adminSecret)
+	entries := make([]*HasuraQueryAnalysisEntry, 0)
+
+	for _, op := range loadGraphQLOperations(queriesGlobPath) {
+		entry := &HasuraQueryAnalysisEntry{
+			FilePath:      op.FilePath,
+			OperationName: op.OperationName,
+			SeqScans:      make([]string, 0),
+		}
+		_, entry.InAllowList = allowList[op.OperationName]
+
+		if !strings.HasPrefix(strings.TrimSpace(op.Query), "mutation") {
+			explanations := make([]*hasuraExplanation, 0)
+			if err := postHasuraJSON(o.ctx, baseURL+"/v1/graphql/explain", adminSecret, map[string]interface{}{
+				"query": map[string]interface{}{
+					"query":         op.Query,
+					"operationName": op.OperationName,
+				},
+				"user": map[string]interface{}{
+					"x-hasura-role": role,
+				},
+			}, &explanations); err != nil {
+				entry.Error = err.Error()
+			}
+
+			for _, explanation := range explanations {
+				for _, line := range explanation.Plan {
+					if match := hasuraSeqScanRegexp.FindStringSubmatch(line); match != nil {
+						entry.SeqScans = append(entry.SeqScans, match[1])
+					}
+				}
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+type hasuraExplanation struct {
+	Field string   `json:"field"`
+	SQL   string   `json:"sql"`
+	Plan  []string `json:"plan"`
+}
+
+var (
+	hasuraSeqScanRegexp = regexp.MustCompile(`Seq Scan on (\S+)`)
+)
+
+// ExportHasuraMetadata exports the metadata of the Hasura instance at the given GraphQL URL, as JSON.
+func (o *operationsImpl) ExportHasuraMetadata(hsURL, adminSecret string) []byte {
+	metadata := json.RawMessage{}
+
+	errorz.MaybeMustWrap(postHasuraJSON(o.ctx, getHasuraBaseURL(hsURL)+"/v1/metadata", adminSecret, map[string]interface{}{
+		"type": "export_metadata",
+		"args": map[string]interface{}{},
+	}, &metadata))
+
+	return metadata
+}
+
+// ReplaceHasuraMetadata replaces the metadata of the Hasura instance at the given GraphQL URL with the given one, as
+// returned by ExportHasuraMetadata.
+func (o *operationsImpl) ReplaceHasuraMetadata(hsURL, adminSecret string, metadata []byte) {
+	errorz.MaybeMustWrap(postHasuraJSON(o.ctx, getHasuraBaseURL(hsURL)+"/v1/metadata", adminSecret, map[string]interface{}{
+		"type": "replace_metadata",
+		"args": json.RawMessage(metadata),
+	}, &json.RawMessage{}))
+}
+
+func getHasuraBaseURL(hsURL string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(hsURL, "/"), "/v1/graphql")
+}
+
+func getHasuraAllowList(ctx context.Context, baseURL, adminSecret string) map[string]struct{} {
+	metadata := &struct {
+		QueryCollections []struct {
+			Name       string `json:"name"`
+			Definition struct {
+				Queries []struct {
+					Name string `json:"name"`
+				} `json:"queries"`
+			} `json:"definition"`
+		} `json:"query_collections"`
+		AllowList []struct {
+			Collection string `json:"collection"`
+		} `json:"allowlist"`
+	}{}
+
+	errorz.MaybeMustWrap(postHasuraJSON(ctx, baseURL+"/v1/metadata", adminSecret, map[string]interface{}{
+		"type": "export_metadata",
+		"args": map[string]interface{}{},
+	}, metadata))
+
+	allowedCollections := map[string]struct{}{}
+	for _, entry := range metadata.AllowList {
+		allowedCollections[entry.Collection] = struct{}{}
+	}
+
+	allowList := map[string]struct{}{}
+	for _, collection := range metadata.QueryCollections {
+		if _, ok := allowedCollections[collection.Name]; ok {
+			for _, query := range collection.Definition.Queries {
+				allowList[query.Name] = struct{}{}
+			}
+		}
+	}
+
+	return allowList
+}
+
+func postHasuraJSON(ctx context.Context, url, adminSecret string, reqBody interface{}, respBody interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonz.MustMarshal(reqBody)))
+	errorz.MaybeMustWrap(err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Hasura-Admin-Secret", adminSecret)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errorz.Wrap(err)
+	}
+	defer errorz.IgnoreClose(resp.Body)
+
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errorz.Wrap(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errorz.Errorf("%v", errorz.A(strings.TrimSpace(string(buf))), errorz.M("status", resp.StatusCode))
+	}
+
+	return errorz.MaybeWrap(json.Unmarshal(buf, respBody))
+}
+
+type graphQLOperation struct {
+	FilePath      string `json:"-"`
+	Query         string `json:"query"`
+	OperationName string `json:"operationName,omitempty"`
+	Subscription  bool   `json:"subscription"`
+}
+
+func loadGraphQLOperations(queriesGlobPath string) []*graphQLOperation {
+	operations := make([]*graphQLOperation, 0)
+
+	filePaths, err := filepath.Glob(queriesGlobPath)
+	errorz.MaybeMustWrap(err)
+
+	for _, filePath := range filePaths {
+		query := string(filez.MustReadFile(filePath))
+
+		doc, gErr := parser.ParseQuery(&ast.Source{Name: filePath, Input: query})
+		if gErr != nil {
+			panic(errorz.Wrap(gErr, errorz.M("filePath", filePath)))
+		}
+
+		for _, op := range doc.Operations {
+			operations = append(operations, &graphQLOperation{
+				FilePath:      filePath,
+				Query:         query,
+				OperationName: op.Name,
+				Subscription:  op.Operation == ast.Subscription,
+			})
+		}
+	}
+
+	errorz.Assertf(len(operations) > 0, "no GraphQL operations found in %v", errorz.A(queriesGlobPath))
+	return operations
+}
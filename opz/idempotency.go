@@ -0,0 +1,40 @@
+package opz
+
+import (
+	"path/filepath"
+
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-errors/errorz"
+
+	"github.com/ibrt/golang-cloud/opz/internal/assets"
+)
+
+// GeneratePostgresIdempotencyMigration adds a Hasura migration creating an idempotency table in the "public" schema to
+// the given migrations directory, unless a migration creating it already exists. The table is used by the middleware
+// generated by GeneratePostgresIdempotencyGoMiddleware to lock keys and store responses. Rows can be pruned
+// periodically by "created_at", once retries are no longer expected.
+func (*operationsImpl) GeneratePostgresIdempotencyMigration(migrationsDirPath, tableName string) {
+	mustAddPostgresTableMigration(migrationsDirPath, tableName,
+		assets.IdempotencyUpSQLTemplateAsset, assets.IdempotencyDownSQLTemplateAsset,
+		assets.IdempotencyMigrationTemplateData{
+			TableName: tableName,
+		})
+}
+
+// GeneratePostgresIdempotencyGoMiddleware generates a Go file ("idempotency.go") in the given package, with a
+// middleware for Lambda handlers that makes them idempotent using the table created by
+// GeneratePostgresIdempotencyMigration (see the generated NewIdempotentHandler). The generated code only depends on
+// "database/sql": the handler must open the database with a Postgres driver, and its Function must depend on the
+// Network of the Postgres plugin to reach it.
+func (*operationsImpl) GeneratePostgresIdempotencyGoMiddleware(outDirPath, packageName, tableName string) {
+	errorz.Assertf(postgresTableNameRegexp.MatchString(tableName), "invalid table name: %v", errorz.A(tableName))
+
+	filez.MustWriteFile(
+		filepath.Join(outDirPath, "idempotency.go"), 0777, 0666,
+		templatez.MustParseAndExecuteGo(assets.IdempotencyMiddlewareGoTemplateAsset,
+			assets.IdempotencyMiddlewareGoTemplateData{
+				PackageName: packageName,
+				TableName:   tableName,
+			}))
+}
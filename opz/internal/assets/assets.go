@@ -6,16 +6,93 @@ import (
 
 // Embedded assets.
 var (
+	//go:embed idempotency/down.sql.gotpl
+	IdempotencyDownSQLTemplateAsset string
+
+	//go:embed idempotency/middleware.go.gotpl
+	IdempotencyMiddlewareGoTemplateAsset string
+
+	//go:embed idempotency/up.sql.gotpl
+	IdempotencyUpSQLTemplateAsset string
+
+	//go:embed k6/graphql-load-test.js.asset
+	K6GraphQLLoadTestJSAsset []byte
+
 	//go:embed node-tools/package.json.asset
 	NodeToolsPackageJSONAsset []byte
 
 	//go:embed node-tools/graphql-codegen.yml.gotpl
 	NodeToolsGraphQLCodeGenYMLTemplateAsset string
+
+	//go:embed outbox/down.sql.gotpl
+	OutboxDownSQLTemplateAsset string
+
+	//go:embed outbox/up.sql.gotpl
+	OutboxUpSQLTemplateAsset string
+
+	//go:embed queues/bindings.go.gotpl
+	QueueGoBindingsTemplateAsset string
+
+	//go:embed sqlboiler/factories.go.gotpl
+	SQLBoilerFactoriesTemplateAsset string
 )
 
+// IdempotencyMigrationTemplateData describes the template data for IdempotencyUpSQLTemplateAsset and IdempotencyDownSQLTemplateAsset.
+type IdempotencyMigrationTemplateData struct {
+	TableName string
+}
+
+// IdempotencyMiddlewareGoTemplateData describes the template data for IdempotencyMiddlewareGoTemplateAsset.
+type IdempotencyMiddlewareGoTemplateData struct {
+	PackageName string
+	TableName   string
+}
+
 // NodeToolsGraphQLCodeGenYMLTemplateData describes the template data for NodeToolsGraphQLCodeGenYMLTemplateAsset.
 type NodeToolsGraphQLCodeGenYMLTemplateData struct {
 	SchemaFilePath  string
 	QueriesGlobPath string
 	OutFilePath     string
 }
+
+// OutboxMigrationTemplateData describes the template data for OutboxUpSQLTemplateAsset and OutboxDownSQLTemplateAsset.
+type OutboxMigrationTemplateData struct {
+	TableName string
+}
+
+// QueueGoBindingsTemplateData describes the template data for QueueGoBindingsTemplateAsset.
+type QueueGoBindingsTemplateData struct {
+	PackageName string
+	Queues      []*QueueGoBindingsTemplateDataQueue
+}
+
+// QueueGoBindingsTemplateDataQueue describes part of QueueGoBindingsTemplateData.
+type QueueGoBindingsTemplateDataQueue struct {
+	Name                     string
+	Identifier               string
+	MessageType              string
+	FIFO                     bool
+	SNSEnvelope              bool
+	VisibilityTimeoutSeconds int
+}
+
+// SQLBoilerFactoriesTemplateData describes the template data for SQLBoilerFactoriesTemplateAsset.
+type SQLBoilerFactoriesTemplateData struct {
+	PackageName      string
+	ModelsImportPath string
+	ImportTime       bool
+	ImportTypes      bool
+	Models           []*SQLBoilerFactoriesTemplateDataModel
+}
+
+// SQLBoilerFactoriesTemplateDataModel describes part of the template data for SQLBoilerFactoriesTemplateAsset.
+type SQLBoilerFactoriesTemplateDataModel struct {
+	Name   string
+	Fields []*SQLBoilerFactoriesTemplateDataField
+}
+
+// SQLBoilerFactoriesTemplateDataField describes part of the template data for SQLBoilerFactoriesTemplateAsset.
+type SQLBoilerFactoriesTemplateDataField struct {
+	Name  string
+	Value string
+}
@@ -0,0 +1,189 @@
+package opz
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+var (
+	hasuraMigrationDirNameRegexp = regexp.MustCompile(`^([0-9]+)_`)
+	postgresTableNameRegexp      = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+	sqlCommentRegexp             = regexp.MustCompile(`(?s)--[^\n]*|/\*.*?\*/`)
+	createIndexRegexp            = regexp.MustCompile(`(?i)\bCREATE\s+(UNIQUE\s+)?INDEX\b`)
+	concurrentlyRegexp           = regexp.MustCompile(`(?i)\bCONCURRENTLY\b`)
+
+	migrationLintRules = []*migrationLintRule{
+		{
+			regexp: regexp.MustCompile(`(?i)\bDROP\s+(TABLE|SCHEMA|VIEW|MATERIALIZED\s+VIEW|TYPE|FUNCTION)\b`),
+			reason: "drops a database object",
+		},
+		{
+			regexp: regexp.MustCompile(`(?i)\bDROP\s+COLUMN\b`),
+			reason: "drops a column",
+		},
+		{
+			regexp: regexp.MustCompile(`(?i)\bTRUNCATE\b`),
+			reason: "truncates a table",
+		},
+		{
+			regexp: regexp.MustCompile(`(?i)\bALTER\s+COLUMN\s+\S+\s+(SET\s+DATA\s+)?TYPE\b`),
+			reason: "changes a column type (possibly narrowing it, and rewriting the table)",
+		},
+		{
+			regexp: regexp.MustCompile(`(?i)\bSET\s+NOT\s+NULL\b`),
+			reason: "adds a NOT NULL constraint (fails on existing NULL values, and locks the table)",
+		},
+		{
+			regexp: regexp.MustCompile(`(?i)\bRENAME\b`),
+			reason: "renames a database object (breaks existing clients)",
+		},
+	}
+)
+
+type migrationLintRule struct {
+	regexp *regexp.Regexp
+	reason string
+}
+
+// MigrationLintReport describes the result of linting a set of migrations.
+type MigrationLintReport struct {
+	LatestVersion int64
+	Issues        []*MigrationLintIssue
+}
+
+// MigrationLintIssue describes a potentially destructive statement in a migration.
+type MigrationLintIssue struct {
+	Version   int64
+	Name      string
+	Statement string
+	Reason    string
+}
+
+// String implements the fmt.Stringer interface.
+func (i *MigrationLintIssue) String() string {
+	return fmt.Sprintf("%v: %v: %v", i.Name, i.Reason, i.Statement)
+}
+
+// LintPostgresHasuraMigrations parses the "up.sql" files of the Hasura migrations in the given directory that are newer
+// than sinceVersion (i.e. pending), and flags potentially destructive statements: dropped tables and columns, column
+// type changes, new NOT NULL constraints, renames, and non-concurrent index creation. The migrations directory can
+// either contain the migrations directly, or have one sub-directory per database (e.g. "default").
+func (o *operationsImpl) LintPostgresHasuraMigrations(fsys fs.FS, migrationsDirPath string, sinceVersion int64) *MigrationLintReport {
+	return LintPostgresHasuraMigrations(fsys, migrationsDirPath, sinceVersion)
+}
+
+// LintPostgresHasuraMigrations parses the "up.sql" files of the Hasura migrations in the given directory that are newer
+// than sinceVersion (i.e. pending), and flags potentially destructive statements: dropped tables and columns, column
+// type changes, new NOT NULL constraints, renames, and non-concurrent index creation. The migrations directory can
+// either contain the migrations directly, or have one sub-directory per database (e.g. "default").
+func LintPostgresHasuraMigrations(fsys fs.FS, migrationsDirPath string, sinceVersion int64) *MigrationLintReport {
+	report := &MigrationLintReport{
+		LatestVersion: sinceVersion,
+		Issues:        make([]*MigrationLintIssue, 0),
+	}
+
+	errorz.MaybeMustWrap(fs.WalkDir(fsys, migrationsDirPath, func(dirPath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return errorz.Wrap(err)
+		}
+
+		match := hasuraMigrationDirNameRegexp.FindStringSubmatch(dirEntry.Name())
+		if !dirEntry.IsDir() || match == nil {
+			return nil
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		errorz.MaybeMustWrap(err)
+
+		if version <= sinceVersion {
+			return fs.SkipDir
+		}
+
+		if version > report.LatestVersion {
+			report.LatestVersion = version
+		}
+
+		migration, err := fs.ReadFile(fsys, path.Join(dirPath, "up.sql"))
+		errorz.MaybeMustWrap(err, errorz.M("migration", dirEntry.Name()))
+
+		for _, statement := range strings.Split(sqlCommentRegexp.ReplaceAllString(string(migration), ""), ";") {
+			statement = strings.Join(strings.Fields(statement), " ")
+			if statement == "" {
+				continue
+			}
+
+			for _, reason := range getMigrationLintReasons(statement) {
+				report.Issues = append(report.Issues, &MigrationLintIssue{
+					Version:   version,
+					Name:      dirEntry.Name(),
+					Statement: statement,
+					Reason:    reason,
+				})
+			}
+		}
+
+		return fs.SkipDir
+	}))
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].Version < report.Issues[j].Version
+	})
+
+	return report
+}
+
+func getMigrationLintReasons(statement string) []string {
+	reasons := make([]string, 0)
+
+	for _, rule := range migrationLintRules {
+		if rule.regexp.MatchString(statement) {
+			reasons = append(reasons, rule.reason)
+		}
+	}
+
+	if createIndexRegexp.MatchString(statement) && !concurrentlyRegexp.MatchString(statement) {
+		reasons = append(reasons, "creates an index without CONCURRENTLY (locks the table for writes)")
+	}
+
+	return reasons
+}
+
+// mustAddPostgresTableMigration adds a Hasura migration creating the given table to the given migrations directory,
+// rendering the given up/down templates, unless a migration creating it already exists.
+func mustAddPostgresTableMigration(migrationsDirPath, tableName, upTemplate, downTemplate string, data interface{}) {
+	errorz.Assertf(postgresTableNameRegexp.MatchString(tableName), "invalid table name: %v", errorz.A(tableName))
+	suffix := "_create_" + tableName
+
+	if filez.MustCheckExists(migrationsDirPath) {
+		dirEntries, err := os.ReadDir(migrationsDirPath)
+		errorz.MaybeMustWrap(err)
+
+		for _, dirEntry := range dirEntries {
+			if dirEntry.IsDir() && hasuraMigrationDirNameRegexp.MatchString(dirEntry.Name()) && strings.HasSuffix(dirEntry.Name(), suffix) {
+				return
+			}
+		}
+	}
+
+	migrationDirPath := filepath.Join(migrationsDirPath, fmt.Sprintf("%v%v", time.Now().UnixMilli(), suffix))
+
+	filez.MustWriteFile(
+		filepath.Join(migrationDirPath, "up.sql"), 0777, 0666,
+		templatez.MustParseAndExecuteText(upTemplate, data))
+
+	filez.MustWriteFile(
+		filepath.Join(migrationDirPath, "down.sql"), 0777, 0666,
+		templatez.MustParseAndExecuteText(downTemplate, data))
+}
@@ -0,0 +1,110 @@
+package opz
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintPostgresHasuraMigrations(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		fsys                  fstest.MapFS
+		sinceVersion          int64
+		expectedLatestVersion int64
+		expectedIssues        []*MigrationLintIssue
+	}{
+		{
+			name: "no migrations",
+			fsys: fstest.MapFS{
+				"migrations/default/.keep": &fstest.MapFile{},
+			},
+			sinceVersion:          0,
+			expectedLatestVersion: 0,
+			expectedIssues:        []*MigrationLintIssue{},
+		},
+		{
+			name: "safe migrations",
+			fsys: fstest.MapFS{
+				"migrations/default/1_init/up.sql":       &fstest.MapFile{Data: []byte("CREATE TABLE users (id INT);")},
+				"migrations/default/2_add_name/up.sql":   &fstest.MapFile{Data: []byte("ALTER TABLE users ADD COLUMN name TEXT;")},
+				"migrations/default/2_add_name/down.sql": &fstest.MapFile{Data: []byte("ALTER TABLE users DROP COLUMN name;")},
+			},
+			sinceVersion:          0,
+			expectedLatestVersion: 2,
+			expectedIssues:        []*MigrationLintIssue{},
+		},
+		{
+			name: "destructive migrations",
+			fsys: fstest.MapFS{
+				"migrations/1_init/up.sql":  &fstest.MapFile{Data: []byte("CREATE TABLE users (id INT);")},
+				"migrations/2_drop/up.sql":  &fstest.MapFile{Data: []byte("-- DROP TABLE ignored;\nALTER TABLE users\n  DROP COLUMN name;\nTRUNCATE events;")},
+				"migrations/3_index/up.sql": &fstest.MapFile{Data: []byte("CREATE INDEX users_name ON users (name);\nCREATE UNIQUE INDEX CONCURRENTLY users_email ON users (email);")},
+			},
+			sinceVersion:          0,
+			expectedLatestVersion: 3,
+			expectedIssues: []*MigrationLintIssue{
+				{Version: 2, Name: "2_drop", Statement: "ALTER TABLE users DROP COLUMN name", Reason: "drops a column"},
+				{Version: 2, Name: "2_drop", Statement: "TRUNCATE events", Reason: "truncates a table"},
+				{Version: 3, Name: "3_index", Statement: "CREATE INDEX users_name ON users (name)", Reason: "creates an index without CONCURRENTLY (locks the table for writes)"},
+			},
+		},
+		{
+			name: "applied migrations",
+			fsys: fstest.MapFS{
+				"migrations/1_drop/up.sql":   &fstest.MapFile{Data: []byte("DROP TABLE users;")},
+				"migrations/2_rename/up.sql": &fstest.MapFile{Data: []byte("ALTER TABLE events RENAME TO logs;")},
+			},
+			sinceVersion:          1,
+			expectedLatestVersion: 2,
+			expectedIssues: []*MigrationLintIssue{
+				{Version: 2, Name: "2_rename", Statement: "ALTER TABLE events RENAME TO logs", Reason: "renames a database object (breaks existing clients)"},
+			},
+		},
+		{
+			name: "all applied",
+			fsys: fstest.MapFS{
+				"migrations/1_drop/up.sql": &fstest.MapFile{Data: []byte("DROP TABLE users;")},
+			},
+			sinceVersion:          5,
+			expectedLatestVersion: 5,
+			expectedIssues:        []*MigrationLintIssue{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			report := LintPostgresHasuraMigrations(testCase.fsys, "migrations", testCase.sinceVersion)
+			require.Equal(t, testCase.expectedLatestVersion, report.LatestVersion)
+			require.Equal(t, testCase.expectedIssues, report.Issues)
+		})
+	}
+}
+
+func TestGetMigrationLintReasons(t *testing.T) {
+	testCases := []struct {
+		statement       string
+		expectedReasons []string
+	}{
+		{statement: "CREATE TABLE users (id INT)", expectedReasons: []string{}},
+		{statement: "DROP TABLE users", expectedReasons: []string{"drops a database object"}},
+		{statement: "drop materialized view stats", expectedReasons: []string{"drops a database object"}},
+		{statement: "ALTER TABLE users ALTER COLUMN age TYPE SMALLINT", expectedReasons: []string{"changes a column type (possibly narrowing it, and rewriting the table)"}},
+		{statement: "ALTER TABLE users ALTER COLUMN age SET DATA TYPE SMALLINT", expectedReasons: []string{"changes a column type (possibly narrowing it, and rewriting the table)"}},
+		{statement: "ALTER TABLE users ALTER COLUMN name SET NOT NULL", expectedReasons: []string{"adds a NOT NULL constraint (fails on existing NULL values, and locks the table)"}},
+		{statement: "ALTER TABLE users RENAME COLUMN name TO full_name", expectedReasons: []string{"renames a database object (breaks existing clients)"}},
+		{statement: "CREATE INDEX CONCURRENTLY users_name ON users (name)", expectedReasons: []string{}},
+		{statement: "CREATE UNIQUE INDEX users_name ON users (name)", expectedReasons: []string{"creates an index without CONCURRENTLY (locks the table for writes)"}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.statement, func(t *testing.T) {
+			require.Equal(t, testCase.expectedReasons, getMigrationLintReasons(testCase.statement))
+		})
+	}
+}
@@ -1,15 +1,21 @@
 package opz
 
 import (
+	"context"
 	"embed"
+	"io/fs"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	awscf "github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	awsacmt "github.com/aws/aws-sdk-go-v2/service/acm/types"
 	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
-	awsecr "github.com/aws/aws-sdk-go-v2/service/ecr"
-	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
-	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/ibrt/golang-shell/shellz"
+	awsroute53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+const (
+	defaultStackWaitTimeout = 30 * time.Minute
 )
 
 var (
@@ -20,47 +26,216 @@ var (
 type Operations interface {
 	GenerateCommitVersion() string
 	GenerateTimestampAndCommitVersion() string
-	GetGoToolCommand(goTool GoTool) *shellz.Command
-	GetNodeToolCommand(nodeTool *NodeTool) *shellz.Command
+	GetProvenance(dirPath string) *Provenance
+	NewCommand(cmd string, initialParams ...interface{}) *Command
+	GetGoToolCommand(goTool GoTool) *Command
+	GetNodeToolCommand(nodeTool *NodeTool) *Command
+	CheckUpgrades(components []*UpgradeComponent) *UpgradeReport
 	GoTest(rootDirPath string, packages []string, filter string, force, cover bool)
-	GoCrossBuildForLinuxAMD64(workDirPath, packageName, binFilePath string, injectValues map[string]string)
+	GoCrossBuildForLinuxAMD64(workDirPath, packageName, binFilePath string, injectValues map[string]string, options ...GoBuildOption)
 	PackageLambdaFunctionHandler(handlerFilePath, functionHandlerFileName, packageFilePath string)
+	BuildAndDeployFrontend(dirPath string, envMap map[string]string, bucketName, distributionID string)
 
+	IsOffline() bool
+	GetContext() context.Context
+	WithContext(ctx context.Context) Operations
+	BootstrapAccount() *AccountBootstrap
 	UploadFile(bucketName, key, contentType string, body []byte)
+	SyncDirToBucket(dirPath, bucketName string)
+	EmptyBucket(bucketName string)
+	InvalidateDistribution(distributionID string, paths ...string)
 	Decrypt(keyAlias string, ciphertext []byte) []byte
 	Encrypt(keyAlias string, plaintext []byte) []byte
 	CreateStack(name string, templateBody string, tagsMap map[string]string) *awscft.Stack
 	DescribeStack(name string) *awscft.Stack
 	UpdateStack(name string, templateBody string, tagsMap map[string]string) *awscft.Stack
 	UpsertStack(name string, templateBody string, tagsMap map[string]string) *awscft.Stack
+	DeleteStack(name string, retainFailedResources bool) []awscft.StackEvent
+	TryCreateStack(name string, templateBody string, tagsMap map[string]string) (*awscft.Stack, error)
+	TryDescribeStack(name string) (*awscft.Stack, error)
+	TryUpdateStack(name string, templateBody string, tagsMap map[string]string) (*awscft.Stack, error)
+	TryUpsertStack(name string, templateBody string, tagsMap map[string]string) (*awscft.Stack, error)
+	TryDeleteStack(name string, retainFailedResources bool) ([]awscft.StackEvent, error)
+	PreviewStackUpdate(name string, templateBody string, tagsMap map[string]string) []awscft.ResourceChange
+	CreateChangeSet(stackName string, templateBody string, tagsMap map[string]string) *ChangeSet
+	DescribeChangeSet(stackName string, changeSetName string) *ChangeSet
+	DeleteChangeSet(stackName string, changeSetName string)
+	DescribeStackEvents(name string) []awscft.StackEvent
+	DescribeCertificate(arn string) *awsacmt.CertificateDetail
+	ListStackExports() []awscft.Export
+	CountStacks() int
+	CountVPCs() int
+	CountElasticIPs() int
+	CountLoadBalancerRules(loadBalancerARN string) int
+	GetServiceQuota(serviceCode, quotaName string, defaultValue int) *ServiceQuota
+	GetRegion() string
+	GetAvailabilityZoneNames() []string
+	GetRegionServices() []string
+	IsRDSEngineVersionAvailable(engine, version string) bool
+	IsRDSProxyAvailable() bool
+	GetHostedZone(id string) *awsroute53.GetHostedZoneOutput
+	UpsertRecordSet(hostedZoneID, name, recordType, value string, ttl int64)
+	DeleteRecordSet(hostedZoneID, name, recordType, value string, ttl int64)
+	PutParameter(name, value string, isSecure bool)
+	DeleteParameter(name string)
+	PutMetricData(namespace string, data []*MetricDatum)
+	GetKafkaBootstrapBrokers(clusterARN string) string
+	SendRawEmail(configurationSetName, from string, to []string, msg []byte)
 	DockerLoginToECR()
+	PeekQueueMessages(queueURL string, maxMessages int) []*QueueMessage
+	RedriveQueueMessages(deadLetterQueueURL, sourceQueueURL string, maxMessages int) int
+	DumpQueueMessages(queueURL, bucketName, key string, purge bool) int
+	RestoreQueueMessages(bucketName, key, queueURL string) int
+	GenerateQueueGoBindings(outDirPath, packageName string, queues []*QueueGoBinding)
 
 	GenerateHasuraGraphQLSchema(hsURL, adminSecret, role, outFilePath string)
 	GenerateHasuraGraphQLEnumsGoBinding(schemaFilePath, outDirPath string)
 	GenerateHasuraGraphQLEnumsJSONBinding(schemaFilePath, outFilePath string)
 	GenerateHasuraGraphQLTypescriptBinding(schemaFilePath, queriesGlobPath, outFilePath string)
+	AnalyzeHasuraQueries(hsURL, adminSecret, role, queriesGlobPath string) []*HasuraQueryAnalysisEntry
+	ExportHasuraMetadata(hsURL, adminSecret string) []byte
+	ReplaceHasuraMetadata(hsURL, adminSecret string, metadata []byte)
+	LoadTestGraphQL(url, queriesDirPath string, headers map[string]string, concurrency int, duration time.Duration) *GraphQLLoadTestReport
 
 	GeneratePostgresSQLBoilerORM(pgURL string, outDirPath string, options ...SQLBoilerORMOption)
+	GeneratePostgresSQLBoilerFactories(pgURL string, modelsImportPath string, outDirPath string, options ...SQLBoilerORMOption)
 	GenerateSQLiteSQLBoilerORM(dbSpec string, outDirPath string, options ...SQLBoilerORMOption)
 	ApplyPostgresHasuraMigrations(pgURL string, embedFS embed.FS, embedMigrationsDirPath string)
 	RevertPostgresHasuraMigrations(pgURL string, embedFS embed.FS, embedMigrationsDirPath string)
+	LintPostgresHasuraMigrations(fsys fs.FS, migrationsDirPath string, sinceVersion int64) *MigrationLintReport
+	GeneratePostgresERD(pgURL string, outFilePath string)
+	GeneratePostgresOutboxMigration(migrationsDirPath, tableName string)
+	GeneratePostgresIdempotencyMigration(migrationsDirPath, tableName string)
+	GeneratePostgresIdempotencyGoMiddleware(outDirPath, packageName, tableName string)
+	EnsurePostgresPublications(pgURL string, publications []*PostgresPublication)
+	EnsurePostgresSchema(pgURL string, schema string)
+	BenchmarkPostgres(pgURL string, scale int, duration time.Duration) *PostgresBenchmarkReport
+	TopSQL(dbInstanceIdentifier string, hours int) []*TopSQLEntry
+	RunDBT(projectDirPath string, target *DBTTarget, selectors []string) *DBTRunReport
 }
 
 type operationsImpl struct {
-	buildDirPath string
-	awsCF        *awscf.Client
-	awsECR       *awsecr.Client
-	awsKMS       *awskms.Client
-	awsS3        *awss3.Client
+	ctx              context.Context
+	buildDirPath     string
+	toolVersions     *ToolVersions
+	commandPolicy    *CommandPolicy
+	awsRegion        string
+	awsCfg           *aws.Config // nil in offline mode
+	awsClientOptions []AWSClientOption
+	awsClients       *lazyAWSClients // shared with the copies returned by WithContext
+}
+
+type lazyAWSClients struct {
+	once    sync.Once
+	clients *awsClients
 }
 
 // NewOperations initializes a new Operations.
-func NewOperations(buildDirPath string, awsCfg *aws.Config) Operations {
+// AWS clients are only initialized when first needed, so methods that don't call AWS work without credentials. The
+// given context bounds all the AWS calls, stack waits, and commands: if it has a deadline, stack waits last up to it
+// instead of the default 30 minutes, and if it is canceled (e.g. when a CI job is canceled) they fail.
+func NewOperations(ctx context.Context, buildDirPath string, awsCfg *aws.Config, toolVersions *ToolVersions, commandPolicy *CommandPolicy, awsClientOptions ...AWSClientOption) Operations {
+	toolVersions.MustValidate()
+
+	return &operationsImpl{
+		ctx:              ctx,
+		buildDirPath:     buildDirPath,
+		toolVersions:     toolVersions,
+		commandPolicy:    commandPolicy,
+		awsRegion:        awsCfg.Region,
+		awsCfg:           awsCfg,
+		awsClientOptions: awsClientOptions,
+		awsClients:       &lazyAWSClients{},
+	}
+}
+
+// NewOfflineOperations initializes a new Operations in offline mode, i.e. without access to AWS. Methods that call AWS
+// panic, while methods that only render or synthesize (e.g. CloudFormation templates, code bindings) work as usual.
+func NewOfflineOperations(ctx context.Context, buildDirPath string, awsRegion string, toolVersions *ToolVersions, commandPolicy *CommandPolicy) Operations {
+	toolVersions.MustValidate()
+
+	return &operationsImpl{
+		ctx:           ctx,
+		buildDirPath:  buildDirPath,
+		toolVersions:  toolVersions,
+		commandPolicy: commandPolicy,
+		awsRegion:     awsRegion,
+		awsClients:    &lazyAWSClients{},
+	}
+}
+
+// Try calls f and returns the error it panics with (if any), so that the panicking (Must-style) methods of Operations,
+// and of the stages and plugins built on it, can be used where errors must be handled gracefully, e.g. in long-running
+// services and CLIs. Panics with non-error values (e.g. runtime errors) are converted to errors as well. The stack methods
+// of Operations also have error-returning variants (e.g. TryUpsertStack).
+//
+//	err := opz.Try(func() { ops.UploadFile(bucketName, key, contentType, body) })
+func Try(f func()) (err error) {
+	defer func() {
+		err = errorz.MaybeWrapRecover(recover())
+	}()
+
+	f()
+	return nil
+}
+
+// Sleep pauses for the given duration, e.g. between retries, or panics with the error of the given context if it is done
+// first (e.g. when a CI job is canceled).
+func Sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		errorz.MaybeMustWrap(ctx.Err())
+	case <-timer.C:
+	}
+}
+
+// IsOffline returns true if the operations are in offline mode.
+func (o *operationsImpl) IsOffline() bool {
+	return o.awsCfg == nil
+}
+
+// GetContext returns the context that bounds the operations, e.g. for use in plugin event hooks.
+func (o *operationsImpl) GetContext() context.Context {
+	return o.ctx
+}
+
+// WithContext returns a copy of the operations bound to the given context instead, e.g. to set a timeout on a single call.
+// The copy shares the AWS clients of the original.
+//
+//	stack := ops.WithContext(ctx).UpsertStack(name, templateBody, tagsMap)
+func (o *operationsImpl) WithContext(ctx context.Context) Operations {
 	return &operationsImpl{
-		buildDirPath: buildDirPath,
-		awsCF:        awscf.NewFromConfig(*awsCfg),
-		awsECR:       awsecr.NewFromConfig(*awsCfg),
-		awsKMS:       awskms.NewFromConfig(*awsCfg),
-		awsS3:        awss3.NewFromConfig(*awsCfg),
+		ctx:              ctx,
+		buildDirPath:     o.buildDirPath,
+		toolVersions:     o.toolVersions,
+		commandPolicy:    o.commandPolicy,
+		awsRegion:        o.awsRegion,
+		awsCfg:           o.awsCfg,
+		awsClientOptions: o.awsClientOptions,
+		awsClients:       o.awsClients,
 	}
 }
+
+// getStackWaitTimeout returns the maximum duration of stack waits, i.e. until the context deadline if set.
+func (o *operationsImpl) getStackWaitTimeout() time.Duration {
+	errorz.MaybeMustWrap(o.ctx.Err())
+
+	if deadline, ok := o.ctx.Deadline(); ok {
+		return time.Until(deadline)
+	}
+
+	return defaultStackWaitTimeout
+}
+
+func (o *operationsImpl) getAWSClients() *awsClients {
+	errorz.Assertf(!o.IsOffline(), "AWS access not available in offline mode")
+
+	o.awsClients.once.Do(func() {
+		o.awsClients.clients = newAWSClients(o.awsCfg, o.awsClientOptions...)
+	})
+
+	return o.awsClients.clients
+}
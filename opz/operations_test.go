@@ -0,0 +1,35 @@
+package opz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTry(t *testing.T) {
+	require.NoError(t, Try(func() {}))
+	require.EqualError(t, Try(func() { errorz.MustErrorf("test error") }), "test error")
+	require.EqualError(t, Try(func() { panic("test panic") }), "test panic")
+}
+
+func TestOperationsImpl_WithContext(t *testing.T) {
+	type key struct{}
+	ops := &operationsImpl{ctx: context.Background(), awsClients: &lazyAWSClients{}}
+	ctx := context.WithValue(context.Background(), key{}, "v")
+
+	boundOps := ops.WithContext(ctx).(*operationsImpl)
+	require.Equal(t, ctx, boundOps.GetContext())
+	require.Equal(t, context.Background(), ops.GetContext())
+	require.Same(t, ops.awsClients, boundOps.awsClients)
+}
+
+func TestSleep(t *testing.T) {
+	require.NotPanics(t, func() { Sleep(context.Background(), time.Millisecond) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.EqualError(t, Try(func() { Sleep(ctx, time.Hour) }), context.Canceled.Error())
+}
@@ -0,0 +1,19 @@
+package opz
+
+import (
+	"github.com/ibrt/golang-cloud/opz/internal/assets"
+)
+
+// GeneratePostgresOutboxMigration adds a Hasura migration creating an outbox table in the "public" schema to the given
+// migrations directory, unless a migration creating it already exists. The table follows the layout expected by the
+// Debezium outbox event router (see CDCConfig.Outbox in cloudz), i.e. events are published by inserting rows with an
+// "aggregatetype" (which determines the topic), an "aggregateid" (the message key), a "type", and a JSON "payload", in
+// the same transaction as the corresponding state changes. Once the migration is applied, the SQLBoiler ORM generated
+// by GeneratePostgresSQLBoilerORM includes a model for it. Published rows can be pruned periodically by "created_at".
+func (*operationsImpl) GeneratePostgresOutboxMigration(migrationsDirPath, tableName string) {
+	mustAddPostgresTableMigration(migrationsDirPath, tableName,
+		assets.OutboxUpSQLTemplateAsset, assets.OutboxDownSQLTemplateAsset,
+		assets.OutboxMigrationTemplateData{
+			TableName: tableName,
+		})
+}
@@ -0,0 +1,285 @@
+package opz
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awspi "github.com/aws/aws-sdk-go-v2/service/pi"
+	awspit "github.com/aws/aws-sdk-go-v2/service/pi/types"
+	awsrds "github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/jsonz"
+	"github.com/ibrt/golang-errors/errorz"
+
+	"github.com/ibrt/golang-cloud/opz/internal/assets"
+)
+
+const (
+	pgbenchClients = 10
+	pgbenchJobs    = 2
+	topSQLLimit    = 10
+)
+
+var (
+	pgbenchTransactionsRegexp = regexp.MustCompile(`number of transactions actually processed: (\d+)`)
+	pgbenchTPSRegexp          = regexp.MustCompile(`tps = ([0-9.]+) \((?:excluding connections establishing|without initial connection time)\)`)
+	pgbenchLatencyRegexp      = regexp.MustCompile(`latency average = ([0-9.]+) ms`)
+)
+
+// LatencyStats describes latency statistics, in milliseconds.
+type LatencyStats struct {
+	Avg    float64
+	Median float64
+	P95    float64
+	P99    float64
+	Max    float64
+}
+
+// String implements the fmt.Stringer interface.
+func (s *LatencyStats) String() string {
+	if s == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("avg=%.1fms med=%.1fms p95=%.1fms p99=%.1fms max=%.1fms", s.Avg, s.Median, s.P95, s.P99, s.Max)
+}
+
+// GraphQLLoadTestReport describes the results of a GraphQL load test.
+type GraphQLLoadTestReport struct {
+	Iterations                   int64
+	IterationsPerSecond          float64
+	ErrorRate                    float64
+	QueryLatency                 *LatencyStats
+	SubscriptionFirstDataLatency *LatencyStats
+}
+
+// Print prints the report.
+func (r *GraphQLLoadTestReport) Print(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "iterations: %v (%.1f/s)\n", r.Iterations, r.IterationsPerSecond)
+	_, _ = fmt.Fprintf(w, "error rate: %.2f%%\n", r.ErrorRate*100)
+	_, _ = fmt.Fprintf(w, "query latency: %v\n", r.QueryLatency)
+	_, _ = fmt.Fprintf(w, "subscription first data latency: %v\n", r.SubscriptionFirstDataLatency)
+}
+
+type k6Summary struct {
+	Metrics map[string]map[string]float64 `json:"metrics"`
+}
+
+// LoadTestGraphQL runs a load test against a GraphQL endpoint using k6 (in Docker), picking operations at random from
+// the ".graphql" files in the given directory. Subscriptions are run over WebSocket ("graphql-ws" protocol), measuring
+// the time to the first data message. Results are stored under the build dir, and returned (see also
+// GraphQLLoadTestReport.Print).
+func (o *operationsImpl) LoadTestGraphQL(url, queriesDirPath string, headers map[string]string, concurrency int, duration time.Duration) *GraphQLLoadTestReport {
+	workDirPath := filepath.Join(o.buildDirPath, "load-test", "graphql", time.Now().UTC().Format("20060102T150405"))
+	filez.MustPrepareDir(workDirPath, 0777)
+
+	if headers == nil {
+		headers = map[string]string{}
+	}
+
+	filez.MustWriteFile(filepath.Join(workDirPath, "script.js"), 0777, 0666, assets.K6GraphQLLoadTestJSAsset)
+	filez.MustWriteFile(filepath.Join(workDirPath, "operations.json"), 0777, 0666, jsonz.MustMarshalIndentDefault(loadGraphQLOperations(filepath.Join(queriesDirPath, "*.graphql"))))
+	filez.MustWriteFile(filepath.Join(workDirPath, "headers.json"), 0777, 0666, jsonz.MustMarshalIndentDefault(headers))
+
+	o.NewCommand("docker", "run", "--rm", "--network", "host").
+		AddParams("-v", fmt.Sprintf("%v:/work", filez.MustAbs(workDirPath))).
+		AddParams("-w", "/work").
+		AddParams("-u", fmt.Sprintf("%v:%v", os.Getuid(), os.Getgid())).
+		AddParams("grafana/k6:"+o.toolVersions.K6, "run").
+		AddParams("--vus", concurrency).
+		AddParams("--duration", duration.String()).
+		AddParams("--summary-trend-stats", "avg,med,max,p(95),p(99)").
+		AddParams("--summary-export", "/work/summary.json").
+		AddParams("-e", "GRAPHQL_URL="+url).
+		AddParams("script.js").
+		MustRun()
+
+	summary := &k6Summary{}
+	errorz.MaybeMustWrap(json.Unmarshal(filez.MustReadFile(filepath.Join(workDirPath, "summary.json")), summary))
+
+	report := &GraphQLLoadTestReport{
+		Iterations:                   int64(summary.Metrics["iterations"]["count"]),
+		IterationsPerSecond:          summary.Metrics["iterations"]["rate"],
+		ErrorRate:                    summary.Metrics["graphql_errors"]["value"],
+		QueryLatency:                 newLatencyStats(summary.Metrics["http_req_duration"]),
+		SubscriptionFirstDataLatency: newLatencyStats(summary.Metrics["subscription_first_data"]),
+	}
+
+	return report
+}
+
+func newLatencyStats(m map[string]float64) *LatencyStats {
+	if m == nil {
+		return nil
+	}
+
+	return &LatencyStats{
+		Avg:    m["avg"],
+		Median: m["med"],
+		P95:    m["p(95)"],
+		P99:    m["p(99)"],
+		Max:    m["max"],
+	}
+}
+
+// PostgresBenchmarkResult describes the results of a pgbench run.
+type PostgresBenchmarkResult struct {
+	Timestamp    time.Time
+	Scale        int
+	Duration     time.Duration
+	Transactions int64
+	TPS          float64
+	LatencyAvgMS float64
+}
+
+// PostgresBenchmarkReport describes the results of a pgbench run, compared to the previous one with the same scale.
+type PostgresBenchmarkReport struct {
+	Result   *PostgresBenchmarkResult
+	Baseline *PostgresBenchmarkResult
+}
+
+// Print prints the report, including the deltas from the baseline (if any).
+func (r *PostgresBenchmarkReport) Print(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "tps: %.1f%v\n", r.Result.TPS, formatBaselineDelta(r.Result.TPS, r.Baseline, func(r *PostgresBenchmarkResult) float64 { return r.TPS }))
+	_, _ = fmt.Fprintf(w, "latency avg: %.3fms%v\n", r.Result.LatencyAvgMS, formatBaselineDelta(r.Result.LatencyAvgMS, r.Baseline, func(r *PostgresBenchmarkResult) float64 { return r.LatencyAvgMS }))
+}
+
+// BenchmarkPostgres runs pgbench (in Docker) against the given database, after initializing the pgbench tables with the
+// given scale factor. Note that the initialization drops and re-creates the "pgbench_*" tables in the target database.
+// Results are stored under the build dir, and returned along with the most recent prior result with the same scale, as a
+// baseline (see also PostgresBenchmarkReport.Print).
+func (o *operationsImpl) BenchmarkPostgres(pgURL string, scale int, duration time.Duration) *PostgresBenchmarkReport {
+	resultsDirPath := filepath.Join(o.buildDirPath, "benchmark", "postgres")
+	baseline := loadLatestPostgresBenchmarkResult(resultsDirPath, scale)
+
+	o.runPGBench("-i", "-q", "-s", scale, pgURL)
+
+	out := o.runPGBench(
+		"-c", pgbenchClients,
+		"-j", pgbenchJobs,
+		"-T", int(duration.Seconds()),
+		pgURL)
+
+	result := &PostgresBenchmarkResult{
+		Timestamp:    time.Now().UTC(),
+		Scale:        scale,
+		Duration:     duration,
+		Transactions: int64(mustParsePGBenchValue(out, pgbenchTransactionsRegexp)),
+		TPS:          mustParsePGBenchValue(out, pgbenchTPSRegexp),
+		LatencyAvgMS: mustParsePGBenchValue(out, pgbenchLatencyRegexp),
+	}
+
+	filez.MustWriteFile(
+		filepath.Join(resultsDirPath, result.Timestamp.Format("20060102T150405")+".json"), 0777, 0666,
+		jsonz.MustMarshalIndentDefault(result))
+
+	return &PostgresBenchmarkReport{
+		Result:   result,
+		Baseline: baseline,
+	}
+}
+
+func (o *operationsImpl) runPGBench(params ...interface{}) string {
+	return o.NewCommand("docker", "run", "--rm", "--network", "host").
+		AddParams("postgres:"+o.toolVersions.PGBench, "pgbench").
+		AddParams(params...).
+		MustOutput()
+}
+
+func loadLatestPostgresBenchmarkResult(resultsDirPath string, scale int) *PostgresBenchmarkResult {
+	filePaths, err := filepath.Glob(filepath.Join(resultsDirPath, "*.json"))
+	errorz.MaybeMustWrap(err)
+	sort.Sort(sort.Reverse(sort.StringSlice(filePaths))) // file names are timestamps
+
+	for _, filePath := range filePaths {
+		result := &PostgresBenchmarkResult{}
+		errorz.MaybeMustWrap(json.Unmarshal(filez.MustReadFile(filePath), result), errorz.M("filePath", filePath))
+
+		if result.Scale == scale {
+			return result
+		}
+	}
+
+	return nil
+}
+
+func mustParsePGBenchValue(out string, re *regexp.Regexp) float64 {
+	match := re.FindStringSubmatch(out)
+	errorz.Assertf(match != nil, "unable to parse pgbench output", errorz.M("out", out))
+
+	v, err := strconv.ParseFloat(match[1], 64)
+	errorz.MaybeMustWrap(err)
+	return v
+}
+
+func formatBaselineDelta(v float64, baseline *PostgresBenchmarkResult, getBaselineValue func(*PostgresBenchmarkResult) float64) string {
+	if baseline == nil || getBaselineValue(baseline) == 0 {
+		return ""
+	}
+
+	baselineValue := getBaselineValue(baseline)
+	return fmt.Sprintf(" (baseline %.3f from %v, %+.1f%%)", baselineValue, baseline.Timestamp.Format(time.RFC3339), (v-baselineValue)/baselineValue*100)
+}
+
+// TopSQLEntry describes a SQL statement and its contribution to database load.
+type TopSQLEntry struct {
+	Statement string
+	DBLoad    float64
+}
+
+// String implements the fmt.Stringer interface.
+func (e *TopSQLEntry) String() string {
+	return fmt.Sprintf("%8.3f  %v", e.DBLoad, strings.Join(strings.Fields(e.Statement), " "))
+}
+
+// TopSQL returns the SQL statements that contributed the most to the load of the given RDS instance over the last
+// given number of hours, as reported by Performance Insights, by decreasing load. The load is expressed in average active
+// sessions.
+func (o *operationsImpl) TopSQL(dbInstanceIdentifier string, hours int) []*TopSQLEntry {
+	out, err := o.getAWSClients().rds.DescribeDBInstances(o.ctx, &awsrds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+	})
+	errorz.MaybeMustWrap(err, errorz.M("dbInstanceIdentifier", dbInstanceIdentifier))
+	errorz.Assertf(len(out.DBInstances) == 1, "unexpected number of DB instances")
+
+	dbInstance := out.DBInstances[0]
+	errorz.Assertf(dbInstance.PerformanceInsightsEnabled != nil && *dbInstance.PerformanceInsightsEnabled,
+		"Performance Insights is not enabled on DB instance %v", errorz.A(dbInstanceIdentifier))
+
+	endTime := time.Now()
+	keysOut, err := o.getAWSClients().pi.DescribeDimensionKeys(o.ctx, &awspi.DescribeDimensionKeysInput{
+		ServiceType: awspit.ServiceTypeRds,
+		Identifier:  dbInstance.DbiResourceId,
+		Metric:      aws.String("db.load.avg"),
+		StartTime:   aws.Time(endTime.Add(-time.Duration(hours) * time.Hour)),
+		EndTime:     aws.Time(endTime),
+		GroupBy: &awspit.DimensionGroup{
+			Group: aws.String("db.sql_tokenized"),
+			Limit: aws.Int32(topSQLLimit),
+		},
+	})
+	errorz.MaybeMustWrap(err, errorz.M("dbInstanceIdentifier", dbInstanceIdentifier))
+
+	entries := make([]*TopSQLEntry, 0, len(keysOut.Keys))
+	for _, key := range keysOut.Keys {
+		entry := &TopSQLEntry{
+			Statement: key.Dimensions["db.sql_tokenized.statement"],
+		}
+
+		if key.Total != nil {
+			entry.DBLoad = *key.Total
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
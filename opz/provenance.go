@@ -0,0 +1,78 @@
+package opz
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// Provenance describes the source and toolchain used to build artifacts, for supply-chain audits.
+type Provenance struct {
+	Commit       string        `json:"commit"`
+	IsDirty      bool          `json:"isDirty"`
+	SourceHash   string        `json:"sourceHash"`
+	GoVersion    string        `json:"goVersion"`
+	ToolVersions *ToolVersions `json:"toolVersions"`
+}
+
+// GetShortCommit returns the abbreviated commit.
+func (p *Provenance) GetShortCommit() string {
+	if len(p.Commit) > 12 {
+		return p.Commit[:12]
+	}
+	return p.Commit
+}
+
+// GetProvenance returns the provenance of artifacts built from the git working tree containing the given dir.
+//
+// The source hash is a SHA-256 over the paths and contents of all files in the working tree which are tracked or not
+// ignored, so it changes with uncommitted changes too. The Go version is the one of the "go" command used for builds.
+func (o *operationsImpl) GetProvenance(dirPath string) *Provenance {
+	rootDirPath := strings.TrimSpace(o.NewCommand("git", "rev-parse", "--show-toplevel").SetDir(dirPath).SetQuiet().MustOutput())
+
+	return &Provenance{
+		Commit:       strings.TrimSpace(o.NewCommand("git", "rev-parse", "HEAD").SetDir(rootDirPath).SetQuiet().MustOutput()),
+		IsDirty:      strings.TrimSpace(o.NewCommand("git", "status", "--porcelain").SetDir(rootDirPath).SetQuiet().MustOutput()) != "",
+		SourceHash:   o.getSourceHash(rootDirPath),
+		GoVersion:    strings.TrimSpace(o.NewCommand("go", "env", "GOVERSION").SetQuiet().MustOutput()),
+		ToolVersions: o.toolVersions,
+	}
+}
+
+func (o *operationsImpl) getSourceHash(rootDirPath string) string {
+	out := o.NewCommand("git", "ls-files", "-z", "--cached", "--others", "--exclude-standard").SetDir(rootDirPath).SetQuiet().MustOutput()
+
+	filePaths := make([]string, 0)
+	for _, filePath := range strings.Split(out, "\x00") {
+		if filePath != "" {
+			filePaths = append(filePaths, filePath)
+		}
+	}
+	sort.Strings(filePaths)
+
+	h := sha256.New()
+
+	for i, filePath := range filePaths {
+		if i > 0 && filePaths[i-1] == filePath {
+			continue // listed twice, e.g. if both tracked and modified
+		}
+
+		buf, err := os.ReadFile(filepath.Join(rootDirPath, filePath))
+		if os.IsNotExist(err) {
+			continue // tracked, but deleted in the working tree
+		}
+		errorz.MaybeMustWrap(err, errorz.M("filePath", filePath))
+
+		contentHash := sha256.Sum256(buf)
+		_, _ = h.Write([]byte(filePath))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write(contentHash[:])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,315 @@
+package opz
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	awssqst "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/iancoleman/strcase"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/templatez"
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-validation/vz"
+
+	"github.com/ibrt/golang-cloud/opz/internal/assets"
+)
+
+const (
+	queueReceiveBatchSize         = 10
+	queueReceiveWaitTimeSeconds   = 2
+	queuePeekVisibilityTimeout    = 60
+	queueDumpVisibilityTimeout    = 15 * 60
+	queueRedriveVisibilityTimeout = 60
+	queueDumpMaxLineSize          = 4 * 1024 * 1024
+	queueDefaultVisibilityTimeout = 30
+
+	queueMessageAttributeGroupID         = "MessageGroupId"
+	queueMessageAttributeDeduplicationID = "MessageDeduplicationId"
+)
+
+// QueueMessage describes a message received from a SQS queue, as returned by PeekQueueMessages and as serialized (one
+// JSON object per line) by DumpQueueMessages.
+type QueueMessage struct {
+	MessageID         string                                   `json:"messageId"`
+	Body              string                                   `json:"body"`
+	Attributes        map[string]string                        `json:"attributes,omitempty"`
+	MessageAttributes map[string]awssqst.MessageAttributeValue `json:"messageAttributes,omitempty"`
+}
+
+// QueueGoBinding describes a queue for GenerateQueueGoBindings. The Name is used to derive the Go identifiers (e.g.
+// "order-events" generates OrderEventsQueuePublisher), and MessageType is the name of a JSON-serializable type declared
+// in the package of the generated file. SNSEnvelope must be set for queues receiving messages from an EventBus topic
+// without raw message delivery. VisibilityTimeoutSeconds should match the queue config, and defaults to 30.
+type QueueGoBinding struct {
+	Name                     string `validate:"required"`
+	MessageType              string `validate:"required"`
+	FIFO                     bool
+	SNSEnvelope              bool
+	VisibilityTimeoutSeconds int `validate:"omitempty,min=1,max=43200"`
+}
+
+// MustValidate validates the queue Go binding.
+func (b *QueueGoBinding) MustValidate() {
+	vz.MustValidateStruct(b)
+	errorz.Assertf(token.IsIdentifier(strcase.ToCamel(b.Name)), "invalid queue name: %v", errorz.A(b.Name))
+	errorz.Assertf(token.IsIdentifier(b.MessageType), "invalid message type: %v", errorz.A(b.MessageType))
+}
+
+// GenerateQueueGoBindings generates a Go file ("queues.go") in the given package, with a typed publisher (see the
+// generated New<Name>QueuePublisher) and a typed Lambda consumer handler (see the generated New<Name>QueueHandler) for
+// each of the given queues. FIFO publishers take a message group ID and an optional deduplication ID. The generated
+// code depends on the AWS SDK SQS client, and works the same against AWS and a local ElasticMQ endpoint.
+func (*operationsImpl) GenerateQueueGoBindings(outDirPath, packageName string, queues []*QueueGoBinding) {
+	data := assets.QueueGoBindingsTemplateData{
+		PackageName: packageName,
+		Queues:      make([]*assets.QueueGoBindingsTemplateDataQueue, 0, len(queues)),
+	}
+
+	identifiers := map[string]struct{}{}
+
+	for _, queue := range queues {
+		queue.MustValidate()
+
+		identifier := strcase.ToCamel(queue.Name)
+		_, ok := identifiers[identifier]
+		errorz.Assertf(!ok, "duplicate queue name: %v", errorz.A(queue.Name))
+		identifiers[identifier] = struct{}{}
+
+		visibilityTimeoutSeconds := queue.VisibilityTimeoutSeconds
+		if visibilityTimeoutSeconds == 0 {
+			visibilityTimeoutSeconds = queueDefaultVisibilityTimeout
+		}
+
+		data.Queues = append(data.Queues, &assets.QueueGoBindingsTemplateDataQueue{
+			Name:                     queue.Name,
+			Identifier:               identifier,
+			MessageType:              queue.MessageType,
+			FIFO:                     queue.FIFO,
+			SNSEnvelope:              queue.SNSEnvelope,
+			VisibilityTimeoutSeconds: visibilityTimeoutSeconds,
+		})
+	}
+
+	filez.MustWriteFile(
+		filepath.Join(outDirPath, "queues.go"), 0777, 0666,
+		templatez.MustParseAndExecuteGo(assets.QueueGoBindingsTemplateAsset, data))
+}
+
+// PeekQueueMessages returns up to maxMessages messages from the given queue without deleting them, e.g. to inspect the
+// contents of a dead-letter queue. The messages become visible again as soon as they have been collected.
+//
+// Note that peeking increments the receive count of the messages: if the queue itself has a redrive policy, peeking
+// might cause messages to be moved to its dead-letter queue.
+func (o *operationsImpl) PeekQueueMessages(queueURL string, maxMessages int) []*QueueMessage {
+	messages, receiptHandles := o.receiveQueueMessages(queueURL, maxMessages, queuePeekVisibilityTimeout)
+	o.releaseQueueMessages(queueURL, receiptHandles)
+	return messages
+}
+
+// RedriveQueueMessages moves up to maxMessages messages (or all messages if maxMessages is 0) from the given
+// dead-letter queue back to the given source queue, returning the number of messages moved. Message attributes and,
+// for FIFO queues, message group and deduplication IDs are preserved.
+//
+// Messages are moved client-side (received, sent, deleted): a message that fails to be deleted after having been sent
+// might be delivered twice, so consumers are expected to be idempotent.
+func (o *operationsImpl) RedriveQueueMessages(deadLetterQueueURL, sourceQueueURL string, maxMessages int) int {
+	count := 0
+
+	for maxMessages <= 0 || count < maxMessages {
+		batchSize := queueReceiveBatchSize
+		if maxMessages > 0 && maxMessages-count < batchSize {
+			batchSize = maxMessages - count
+		}
+
+		messages, receiptHandles := o.receiveQueueMessages(deadLetterQueueURL, batchSize, queueRedriveVisibilityTimeout)
+		if len(messages) == 0 {
+			break
+		}
+
+		for _, message := range messages {
+			o.sendQueueMessage(sourceQueueURL, message)
+		}
+
+		o.deleteQueueMessages(deadLetterQueueURL, receiptHandles)
+		count += len(messages)
+	}
+
+	return count
+}
+
+// DumpQueueMessages saves all the messages in the given queue to a S3 object, as one JSON-serialized QueueMessage per
+// line, returning the number of messages saved. If purge is true the messages are deleted from the queue once the
+// object has been uploaded, otherwise they become visible again.
+//
+// Messages are hidden while the dump is in progress: consumers of the queue should be stopped beforehand.
+func (o *operationsImpl) DumpQueueMessages(queueURL, bucketName, key string, purge bool) int {
+	messages, receiptHandles := o.receiveQueueMessages(queueURL, 0, queueDumpVisibilityTimeout)
+	buf := &bytes.Buffer{}
+
+	for _, message := range messages {
+		errorz.MaybeMustWrap(json.NewEncoder(buf).Encode(message))
+	}
+
+	o.UploadFile(bucketName, key, "application/x-ndjson", buf.Bytes())
+
+	if purge {
+		o.deleteQueueMessages(queueURL, receiptHandles)
+	} else {
+		o.releaseQueueMessages(queueURL, receiptHandles)
+	}
+
+	return len(messages)
+}
+
+// RestoreQueueMessages sends the messages saved by DumpQueueMessages in the given S3 object to the given queue,
+// returning the number of messages sent.
+func (o *operationsImpl) RestoreQueueMessages(bucketName, key, queueURL string) int {
+	out, err := o.getAWSClients().s3.GetObject(o.ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	errorz.MaybeMustWrap(err, errorz.M("bucketName", bucketName), errorz.M("key", key))
+	defer errorz.IgnoreClose(out.Body)
+
+	scanner := bufio.NewScanner(out.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), queueDumpMaxLineSize)
+	count := 0
+
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		message := &QueueMessage{}
+		errorz.MaybeMustWrap(json.Unmarshal(scanner.Bytes(), message), errorz.M("line", count+1))
+		o.sendQueueMessage(queueURL, message)
+		count++
+	}
+
+	errorz.MaybeMustWrap(scanner.Err())
+	return count
+}
+
+// receiveQueueMessages receives up to maxMessages messages (or all visible messages if maxMessages is 0), hiding them
+// for the given visibility timeout. Messages received more than once (e.g. if the timeout expires) are deduplicated,
+// keeping the latest receipt handle.
+func (o *operationsImpl) receiveQueueMessages(queueURL string, maxMessages int, visibilityTimeout int32) ([]*QueueMessage, []string) {
+	messages := make([]*QueueMessage, 0)
+	receiptHandles := make([]string, 0)
+	indexes := map[string]int{}
+
+	for maxMessages <= 0 || len(messages) < maxMessages {
+		batchSize := queueReceiveBatchSize
+		if maxMessages > 0 && maxMessages-len(messages) < batchSize {
+			batchSize = maxMessages - len(messages)
+		}
+
+		out, err := o.getAWSClients().sqs.ReceiveMessage(o.ctx, &awssqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(queueURL),
+			AttributeNames:        []awssqst.QueueAttributeName{awssqst.QueueAttributeNameAll},
+			MessageAttributeNames: []string{"All"},
+			MaxNumberOfMessages:   int32(batchSize),
+			VisibilityTimeout:     visibilityTimeout,
+			WaitTimeSeconds:       queueReceiveWaitTimeSeconds,
+		})
+		errorz.MaybeMustWrap(err, errorz.M("queueURL", queueURL))
+
+		if len(out.Messages) == 0 {
+			break
+		}
+
+		for _, message := range out.Messages {
+			if i, ok := indexes[aws.ToString(message.MessageId)]; ok {
+				receiptHandles[i] = aws.ToString(message.ReceiptHandle)
+				continue
+			}
+
+			indexes[aws.ToString(message.MessageId)] = len(messages)
+			receiptHandles = append(receiptHandles, aws.ToString(message.ReceiptHandle))
+			messages = append(messages, &QueueMessage{
+				MessageID:         aws.ToString(message.MessageId),
+				Body:              aws.ToString(message.Body),
+				Attributes:        message.Attributes,
+				MessageAttributes: message.MessageAttributes,
+			})
+		}
+	}
+
+	return messages, receiptHandles
+}
+
+func (o *operationsImpl) sendQueueMessage(queueURL string, message *QueueMessage) {
+	input := &awssqs.SendMessageInput{
+		QueueUrl:          aws.String(queueURL),
+		MessageBody:       aws.String(message.Body),
+		MessageAttributes: message.MessageAttributes,
+	}
+
+	if groupID := message.Attributes[queueMessageAttributeGroupID]; groupID != "" {
+		input.MessageGroupId = aws.String(groupID)
+	}
+
+	if deduplicationID := message.Attributes[queueMessageAttributeDeduplicationID]; deduplicationID != "" {
+		input.MessageDeduplicationId = aws.String(deduplicationID)
+	}
+
+	_, err := o.getAWSClients().sqs.SendMessage(o.ctx, input)
+	errorz.MaybeMustWrap(err, errorz.M("queueURL", queueURL), errorz.M("messageID", message.MessageID))
+}
+
+func (o *operationsImpl) deleteQueueMessages(queueURL string, receiptHandles []string) {
+	for i := 0; i < len(receiptHandles); i += queueReceiveBatchSize {
+		entries := make([]awssqst.DeleteMessageBatchRequestEntry, 0, queueReceiveBatchSize)
+
+		for j := i; j < len(receiptHandles) && j < i+queueReceiveBatchSize; j++ {
+			entries = append(entries, awssqst.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(fmt.Sprintf("%v", j)),
+				ReceiptHandle: aws.String(receiptHandles[j]),
+			})
+		}
+
+		out, err := o.getAWSClients().sqs.DeleteMessageBatch(o.ctx, &awssqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(queueURL),
+			Entries:  entries,
+		})
+		errorz.MaybeMustWrap(err, errorz.M("queueURL", queueURL))
+		mustCheckQueueBatchFailures(queueURL, out.Failed)
+	}
+}
+
+// releaseQueueMessages makes the given messages visible again, by resetting their visibility timeout.
+func (o *operationsImpl) releaseQueueMessages(queueURL string, receiptHandles []string) {
+	for i := 0; i < len(receiptHandles); i += queueReceiveBatchSize {
+		entries := make([]awssqst.ChangeMessageVisibilityBatchRequestEntry, 0, queueReceiveBatchSize)
+
+		for j := i; j < len(receiptHandles) && j < i+queueReceiveBatchSize; j++ {
+			entries = append(entries, awssqst.ChangeMessageVisibilityBatchRequestEntry{
+				Id:                aws.String(fmt.Sprintf("%v", j)),
+				ReceiptHandle:     aws.String(receiptHandles[j]),
+				VisibilityTimeout: 0,
+			})
+		}
+
+		out, err := o.getAWSClients().sqs.ChangeMessageVisibilityBatch(o.ctx, &awssqs.ChangeMessageVisibilityBatchInput{
+			QueueUrl: aws.String(queueURL),
+			Entries:  entries,
+		})
+		errorz.MaybeMustWrap(err, errorz.M("queueURL", queueURL))
+		mustCheckQueueBatchFailures(queueURL, out.Failed)
+	}
+}
+
+func mustCheckQueueBatchFailures(queueURL string, failed []awssqst.BatchResultErrorEntry) {
+	if len(failed) > 0 {
+		errorz.MustErrorf("%v batch entries failed, first: %v: %v",
+			errorz.A(len(failed), aws.ToString(failed[0].Code), aws.ToString(failed[0].Message)),
+			errorz.M("queueURL", queueURL))
+	}
+}
@@ -0,0 +1,42 @@
+package opz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateQueueGoBindings(t *testing.T) {
+	outDirPath := t.TempDir()
+
+	(&operationsImpl{}).GenerateQueueGoBindings(outDirPath, "events", []*QueueGoBinding{
+		{
+			Name:        "order-events",
+			MessageType: "OrderEvent",
+			FIFO:        true,
+			SNSEnvelope: true,
+		},
+		{
+			Name:                     "emails",
+			MessageType:              "Email",
+			VisibilityTimeoutSeconds: 120,
+		},
+	})
+
+	buf, err := os.ReadFile(filepath.Join(outDirPath, "queues.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(buf), "package events")
+	require.Contains(t, string(buf), "const OrderEventsQueueVisibilityTimeout = 30 * time.Second")
+	require.Contains(t, string(buf), "func (p *OrderEventsQueuePublisher) Publish(ctx context.Context, groupID, deduplicationID string, message *OrderEvent) (string, error)")
+	require.Contains(t, string(buf), "const EmailsQueueVisibilityTimeout = 120 * time.Second")
+	require.Contains(t, string(buf), "func (p *EmailsQueuePublisher) Publish(ctx context.Context, message *Email) (string, error)")
+}
+
+func TestQueueGoBinding_MustValidate(t *testing.T) {
+	require.NotPanics(t, func() { (&QueueGoBinding{Name: "order-events", MessageType: "OrderEvent"}).MustValidate() })
+	require.Panics(t, func() { (&QueueGoBinding{Name: "order-events", MessageType: "*OrderEvent"}).MustValidate() })
+	require.Panics(t, func() { (&QueueGoBinding{Name: "1-orders", MessageType: "OrderEvent"}).MustValidate() })
+	require.Panics(t, func() { (&QueueGoBinding{Name: "orders"}).MustValidate() })
+}
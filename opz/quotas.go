@@ -0,0 +1,170 @@
+package opz
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscf "github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	awscft "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	awsec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	awsec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	awselbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	awssq "github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	awssqt "github.com/aws/aws-sdk-go-v2/service/servicequotas/types"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// ServiceQuota describes an AWS service quota.
+type ServiceQuota struct {
+	ServiceCode string
+	QuotaCode   string // empty if the quota could not be found
+	QuotaName   string
+	Value       int
+}
+
+// GetIncreaseURL returns the URL of the Service Quotas console page where an increase can be requested.
+func (q *ServiceQuota) GetIncreaseURL() string {
+	if q.QuotaCode == "" {
+		return "https://console.aws.amazon.com/servicequotas/home/services/" + q.ServiceCode + "/quotas"
+	}
+	return "https://console.aws.amazon.com/servicequotas/home/services/" + q.ServiceCode + "/quotas/" + q.QuotaCode
+}
+
+// GetServiceQuota returns the value of the quota with the given service code and name (e.g. "vpc", "VPCs per Region").
+// It returns the applied value if the quota has been adjusted for the account, the AWS default value otherwise, and
+// the given default value if the quota cannot be found.
+func (o *operationsImpl) GetServiceQuota(serviceCode, quotaName string, defaultValue int) *ServiceQuota {
+	quota := &ServiceQuota{
+		ServiceCode: serviceCode,
+		QuotaName:   quotaName,
+		Value:       defaultValue,
+	}
+
+	appliedPaginator := awssq.NewListServiceQuotasPaginator(o.getAWSClients().sq, &awssq.ListServiceQuotasInput{
+		ServiceCode: aws.String(serviceCode),
+	})
+
+	for appliedPaginator.HasMorePages() {
+		out, err := appliedPaginator.NextPage(o.ctx)
+		errorz.MaybeMustWrap(err, errorz.M("serviceCode", serviceCode))
+
+		if serviceQuota := findServiceQuota(out.Quotas, quotaName); serviceQuota != nil {
+			quota.QuotaCode = aws.ToString(serviceQuota.QuotaCode)
+			quota.Value = int(aws.ToFloat64(serviceQuota.Value))
+			return quota
+		}
+	}
+
+	defaultPaginator := awssq.NewListAWSDefaultServiceQuotasPaginator(o.getAWSClients().sq, &awssq.ListAWSDefaultServiceQuotasInput{
+		ServiceCode: aws.String(serviceCode),
+	})
+
+	for defaultPaginator.HasMorePages() {
+		out, err := defaultPaginator.NextPage(o.ctx)
+		errorz.MaybeMustWrap(err, errorz.M("serviceCode", serviceCode))
+
+		if serviceQuota := findServiceQuota(out.Quotas, quotaName); serviceQuota != nil {
+			quota.QuotaCode = aws.ToString(serviceQuota.QuotaCode)
+			quota.Value = int(aws.ToFloat64(serviceQuota.Value))
+			return quota
+		}
+	}
+
+	return quota
+}
+
+// CountVPCs returns the number of VPCs in the region.
+func (o *operationsImpl) CountVPCs() int {
+	count := 0
+	paginator := awsec2.NewDescribeVpcsPaginator(o.getAWSClients().ec2, &awsec2.DescribeVpcsInput{})
+
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(o.ctx)
+		errorz.MaybeMustWrap(err)
+		count += len(out.Vpcs)
+	}
+
+	return count
+}
+
+// CountElasticIPs returns the number of VPC Elastic IPs allocated in the region.
+func (o *operationsImpl) CountElasticIPs() int {
+	out, err := o.getAWSClients().ec2.DescribeAddresses(o.ctx, &awsec2.DescribeAddressesInput{
+		Filters: []awsec2t.Filter{
+			{
+				Name:   aws.String("domain"),
+				Values: []string{"vpc"},
+			},
+		},
+	})
+	errorz.MaybeMustWrap(err)
+	return len(out.Addresses)
+}
+
+// CountStacks returns the number of CloudFormation stacks in the region, excluding deleted ones.
+func (o *operationsImpl) CountStacks() int {
+	count := 0
+	paginator := awscf.NewListStacksPaginator(o.getAWSClients().cf, &awscf.ListStacksInput{})
+
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(o.ctx)
+		errorz.MaybeMustWrap(err)
+
+		for _, stackSummary := range out.StackSummaries {
+			if stackSummary.StackStatus != awscft.StackStatusDeleteComplete {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+// CountLoadBalancerRules returns the number of listener rules of the given load balancer, including default rules.
+func (o *operationsImpl) CountLoadBalancerRules(loadBalancerARN string) int {
+	count := 0
+	listenerARNs := make([]string, 0)
+	listenersIn := &awselbv2.DescribeListenersInput{
+		LoadBalancerArn: aws.String(loadBalancerARN),
+	}
+
+	for {
+		out, err := o.getAWSClients().elbv2.DescribeListeners(o.ctx, listenersIn)
+		errorz.MaybeMustWrap(err, errorz.M("loadBalancerARN", loadBalancerARN))
+
+		for _, listener := range out.Listeners {
+			listenerARNs = append(listenerARNs, aws.ToString(listener.ListenerArn))
+		}
+
+		if aws.ToString(out.NextMarker) == "" {
+			break
+		}
+		listenersIn.Marker = out.NextMarker
+	}
+
+	for _, listenerARN := range listenerARNs {
+		rulesIn := &awselbv2.DescribeRulesInput{
+			ListenerArn: aws.String(listenerARN),
+		}
+
+		for {
+			out, err := o.getAWSClients().elbv2.DescribeRules(o.ctx, rulesIn)
+			errorz.MaybeMustWrap(err, errorz.M("listenerARN", listenerARN))
+			count += len(out.Rules)
+
+			if aws.ToString(out.NextMarker) == "" {
+				break
+			}
+			rulesIn.Marker = out.NextMarker
+		}
+	}
+
+	return count
+}
+
+func findServiceQuota(serviceQuotas []awssqt.ServiceQuota, quotaName string) *awssqt.ServiceQuota {
+	for _, serviceQuota := range serviceQuotas {
+		if aws.ToString(serviceQuota.QuotaName) == quotaName {
+			return &serviceQuota
+		}
+	}
+	return nil
+}
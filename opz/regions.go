@@ -0,0 +1,82 @@
+package opz
+
+import (
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	awsec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	awsrds "github.com/aws/aws-sdk-go-v2/service/rds"
+	awsssm "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// GetRegion returns the configured AWS region.
+func (o *operationsImpl) GetRegion() string {
+	return o.awsRegion
+}
+
+// GetAvailabilityZoneNames returns the names of the availability zones available in the region, e.g. "us-east-1a".
+func (o *operationsImpl) GetAvailabilityZoneNames() []string {
+	out, err := o.getAWSClients().ec2.DescribeAvailabilityZones(o.ctx, &awsec2.DescribeAvailabilityZonesInput{
+		Filters: []awsec2t.Filter{
+			{
+				Name:   aws.String("state"),
+				Values: []string{"available"},
+			},
+		},
+	})
+	errorz.MaybeMustWrap(err)
+
+	names := make([]string, 0, len(out.AvailabilityZones))
+	for _, availabilityZone := range out.AvailabilityZones {
+		names = append(names, aws.ToString(availabilityZone.ZoneName))
+	}
+	return names
+}
+
+// GetRegionServices returns the identifiers of the services available in the region (e.g. "fargate", "es"), as
+// published in the AWS global infrastructure public SSM parameters.
+func (o *operationsImpl) GetRegionServices() []string {
+	services := make([]string, 0)
+	paginator := awsssm.NewGetParametersByPathPaginator(o.getAWSClients().ssm, &awsssm.GetParametersByPathInput{
+		Path: aws.String("/aws/service/global-infrastructure/regions/" + o.awsRegion + "/services"),
+	})
+
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(o.ctx)
+		errorz.MaybeMustWrap(err)
+
+		for _, parameter := range out.Parameters {
+			services = append(services, path.Base(aws.ToString(parameter.Name)))
+		}
+	}
+
+	return services
+}
+
+// IsRDSEngineVersionAvailable returns true if the given RDS engine (e.g. "postgres", "aurora-postgresql") and version are
+// available in the region.
+func (o *operationsImpl) IsRDSEngineVersionAvailable(engine, version string) bool {
+	out, err := o.getAWSClients().rds.DescribeDBEngineVersions(o.ctx, &awsrds.DescribeDBEngineVersionsInput{
+		Engine:        aws.String(engine),
+		EngineVersion: aws.String(version),
+	})
+	errorz.MaybeMustWrap(err, errorz.M("engine", engine), errorz.M("version", version))
+	return len(out.DBEngineVersions) > 0
+}
+
+// IsRDSProxyAvailable returns true if RDS Proxy is available in the region.
+func (o *operationsImpl) IsRDSProxyAvailable() bool {
+	_, err := o.getAWSClients().rds.DescribeDBProxies(o.ctx, &awsrds.DescribeDBProxiesInput{
+		MaxRecords: aws.Int32(20),
+	})
+	if err == nil {
+		return true
+	}
+
+	// TODO(ibrt): Better error handling.
+	errorz.Assertf(!strings.Contains(err.Error(), "AccessDenied"), "unexpected error: %v", errorz.A(err.Error()))
+	return false
+}
@@ -0,0 +1,108 @@
+package opz
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ibrt/golang-errors/errorz"
+	"github.com/ibrt/golang-inject-pg/pgz/testpgz"
+)
+
+const (
+	postgresReadyTimeout = 60 * time.Second
+
+	// PostgresLogicalDecodingPlugin is the logical decoding output plugin used for replication slots.
+	PostgresLogicalDecodingPlugin = "pgoutput"
+)
+
+// PostgresPublication describes a logical replication publication, and optionally a replication slot decoding it.
+type PostgresPublication struct {
+	Name     string
+	Tables   []string // e.g. "public.orders"; if empty, the publication includes all tables, including future ones
+	SlotName string   // if set, a logical replication slot using PostgresLogicalDecodingPlugin is created
+}
+
+// EnsurePostgresPublications creates the given publications and replication slots in the given Postgres database if
+// they don't exist yet, and updates the tables of existing publications. It waits for the database to accept
+// connections, so that it can be run right after starting it. The database must be configured with "wal_level=logical".
+//
+// Note that slots are never dropped: an unconsumed slot retains WAL indefinitely, so obsolete slots must be dropped
+// manually using "pg_drop_replication_slot". Existing publications cannot be switched from all tables to a table list.
+func (o *operationsImpl) EnsurePostgresPublications(pgURL string, publications []*PostgresPublication) {
+	db := mustOpenPostgresWhenReady(o.ctx, pgURL)
+	defer errorz.IgnoreClose(db)
+
+	for _, publication := range publications {
+		target := "ALL TABLES"
+		if len(publication.Tables) > 0 {
+			tables := make([]string, 0, len(publication.Tables))
+			for _, table := range publication.Tables {
+				tables = append(tables, quotePostgresIdentifier(table))
+			}
+			target = "TABLE " + strings.Join(tables, ", ")
+		}
+
+		if !mustCheckPostgresExists(db, `SELECT EXISTS (SELECT 1 FROM pg_publication WHERE pubname = $1)`, publication.Name) {
+			_, err := db.Exec(fmt.Sprintf("CREATE PUBLICATION %v FOR %v", quotePostgresIdentifier(publication.Name), target))
+			errorz.MaybeMustWrap(err, errorz.M("publication", publication.Name))
+		} else if len(publication.Tables) > 0 {
+			_, err := db.Exec(fmt.Sprintf("ALTER PUBLICATION %v SET %v", quotePostgresIdentifier(publication.Name), target))
+			errorz.MaybeMustWrap(err, errorz.M("publication", publication.Name))
+		}
+
+		if publication.SlotName != "" &&
+			!mustCheckPostgresExists(db, `SELECT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)`, publication.SlotName) {
+			_, err := db.Exec(`SELECT pg_create_logical_replication_slot($1, $2)`, publication.SlotName, PostgresLogicalDecodingPlugin)
+			errorz.MaybeMustWrap(err, errorz.M("slot", publication.SlotName))
+		}
+	}
+}
+
+// EnsurePostgresSchema creates the given schema in the given Postgres database if it doesn't exist yet. It waits for the
+// database to accept connections, so that it can be run right after starting it.
+func (o *operationsImpl) EnsurePostgresSchema(pgURL string, schema string) {
+	db := mustOpenPostgresWhenReady(o.ctx, pgURL)
+	defer errorz.IgnoreClose(db)
+
+	_, err := db.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %v", quotePostgresIdentifier(schema)))
+	errorz.MaybeMustWrap(err, errorz.M("schema", schema))
+}
+
+func mustOpenPostgresWhenReady(ctx context.Context, pgURL string) *sql.DB {
+	deadline := time.Now().Add(postgresReadyTimeout)
+
+	for {
+		db, err := tryOpenPostgres(pgURL)
+		if err == nil {
+			return db
+		}
+
+		errorz.Assertf(time.Now().Before(deadline), "timed out waiting for database: %v", errorz.A(err))
+		Sleep(ctx, time.Second)
+	}
+}
+
+func tryOpenPostgres(pgURL string) (db *sql.DB, err error) {
+	defer func() {
+		err = errorz.MaybeWrapRecover(recover())
+	}()
+
+	return testpgz.MustOpen(pgURL), nil
+}
+
+func mustCheckPostgresExists(db *sql.DB, query string, args ...interface{}) bool {
+	var exists bool
+	errorz.MaybeMustWrap(db.QueryRow(query, args...).Scan(&exists))
+	return exists
+}
+
+func quotePostgresIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
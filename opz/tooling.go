@@ -15,46 +15,45 @@ import (
 	"github.com/ibrt/golang-bites/filez"
 	"github.com/ibrt/golang-bites/jsonz"
 	"github.com/ibrt/golang-errors/errorz"
-	"github.com/ibrt/golang-shell/shellz"
 
 	"github.com/ibrt/golang-cloud/opz/internal/assets"
 )
 
-// GoTool describes a Go tool.
+// GoTool describes a Go tool, as a package path. Its version is looked up in the ToolVersions.
 type GoTool string
 
 // Known Go tools.
 const (
-	GoCov       GoTool = "github.com/axw/gocov/gocov@v1.0.0"
-	GoCovHTML   GoTool = "github.com/matm/gocov-html@v0.0.0-20200509184451-71874e2e203b"
-	GoLint      GoTool = "golang.org/x/lint/golint@v0.0.0-20210508222113-6edffad5e616"
-	GoTest      GoTool = "github.com/rakyll/gotest@v0.0.6"
-	StaticCheck GoTool = "honnef.co/go/tools/cmd/staticcheck@2022.1"
+	GoCov       GoTool = "github.com/axw/gocov/gocov"
+	GoCovHTML   GoTool = "github.com/matm/gocov-html"
+	GoLint      GoTool = "golang.org/x/lint/golint"
+	GoTest      GoTool = "github.com/rakyll/gotest"
+	StaticCheck GoTool = "honnef.co/go/tools/cmd/staticcheck"
 )
 
-// NodeTool describes a Node tool.
+// NodeTool describes a Node tool. The versions of its packages are looked up in the ToolVersions.
 type NodeTool struct {
-	Packages map[string]string
+	Packages []string
 	Command  string
 }
 
 // Known node tools.
 var (
 	GraphQURL = &NodeTool{
-		Packages: map[string]string{
-			"graphqurl": "1.0.1",
+		Packages: []string{
+			"graphqurl",
 		},
 		Command: "graphqurl",
 	}
 
 	GraphQLCodeGen = &NodeTool{
-		Packages: map[string]string{
-			"@graphql-codegen/cli":                     "2.6.2",
-			"@graphql-codegen/typescript":              "2.4.8",
-			"@graphql-codegen/typescript-operations":   "2.3.5",
-			"@graphql-codegen/typescript-react-apollo": "3.2.11",
-			"graphql":     "15.8.0",
-			"graphql-tag": "2.12.6",
+		Packages: []string{
+			"@graphql-codegen/cli",
+			"@graphql-codegen/typescript",
+			"@graphql-codegen/typescript-operations",
+			"@graphql-codegen/typescript-react-apollo",
+			"graphql",
+			"graphql-tag",
 		},
 		Command: "graphql-codegen",
 	}
@@ -62,9 +61,9 @@ var (
 
 // GenerateCommitVersion generates a version using the current  git commit.
 func (o *operationsImpl) GenerateCommitVersion() string {
-	return strings.TrimSpace(shellz.
+	return strings.TrimSpace(o.
 		NewCommand("git", "rev-parse", "--short", "HEAD").
-		SetLogf(nil).
+		SetQuiet().
 		MustOutput())
 }
 
@@ -73,13 +72,15 @@ func (o *operationsImpl) GenerateTimestampAndCommitVersion() string {
 	return fmt.Sprintf("%v-%v", time.Now().UTC().Format("20060102T150405"), o.GenerateCommitVersion())
 }
 
-// GetGoToolCommand returns a *shellz.Command ready to run a command provided as Go package.
-func (o *operationsImpl) GetGoToolCommand(goTool GoTool) *shellz.Command {
-	return shellz.NewCommand("go", "run", string(goTool))
+// GetGoToolCommand returns a *Command ready to run a command provided as Go package.
+func (o *operationsImpl) GetGoToolCommand(goTool GoTool) *Command {
+	version, ok := o.toolVersions.GoTools[goTool]
+	errorz.Assertf(ok, "missing version for Go tool: %v", errorz.A(goTool))
+	return o.NewCommand("go", "run", fmt.Sprintf("%v@%v", goTool, version))
 }
 
-// GetNodeToolCommand returns a *shellz.Command ready to run a command provided as node package.
-func (o *operationsImpl) GetNodeToolCommand(nodeTool *NodeTool) *shellz.Command {
+// GetNodeToolCommand returns a *Command ready to run a command provided as node package.
+func (o *operationsImpl) GetNodeToolCommand(nodeTool *NodeTool) *Command {
 	nodeDirPath := filepath.Join(o.buildDirPath, "node-tools")
 	packageJSONFilePath := filepath.Join(nodeDirPath, "package.json")
 	errorz.MaybeMustWrap(os.MkdirAll(nodeDirPath, 0777))
@@ -95,13 +96,15 @@ func (o *operationsImpl) GetNodeToolCommand(nodeTool *NodeTool) *shellz.Command
 	}{}
 
 	errorz.MaybeMustWrap(json.Unmarshal(filez.MustReadFile(packageJSONFilePath), pkgJSON))
-	for k, v := range nodeTool.Packages {
-		pkgJSON.DevDependencies[k] = v
+	for _, pkg := range nodeTool.Packages {
+		version, ok := o.toolVersions.NodePackages[pkg]
+		errorz.Assertf(ok, "missing version for node package: %v", errorz.A(pkg))
+		pkgJSON.DevDependencies[pkg] = version
 	}
 	filez.MustWriteFile(packageJSONFilePath, 0777, 0666, jsonz.MustMarshalIndentDefault(pkgJSON))
 
-	shellz.NewCommand("yarn", "install").SetDir(nodeDirPath).MustRun()
-	return shellz.NewCommand("yarn", "--silent", nodeTool.Command).SetDir(nodeDirPath)
+	o.NewCommand("yarn", "install").SetDir(nodeDirPath).MustRun()
+	return o.NewCommand("yarn", "--silent", nodeTool.Command).SetDir(nodeDirPath)
 }
 
 // GoTest runs Go tests.
@@ -111,11 +114,11 @@ func (o *operationsImpl) GoTest(dirPath string, packages []string, filter string
 	rawCoverageFilePath := filepath.Join(outDirPath, "coverage.out")
 	htmlCoverageFilePath := filepath.Join(outDirPath, "coverage.html")
 
-	shellz.NewCommand("go", "mod", "tidy").SetDir(dirPath).MustRun()
-	shellz.NewCommand("go", "generate", "./...").SetDir(dirPath).MustRun()
-	shellz.NewCommand("go", "build", "-v", "./...").SetDir(dirPath).MustRun()
+	o.NewCommand("go", "mod", "tidy").SetDir(dirPath).MustRun()
+	o.NewCommand("go", "generate", "./...").SetDir(dirPath).MustRun()
+	o.NewCommand("go", "build", "-v", "./...").SetDir(dirPath).MustRun()
 	o.GetGoToolCommand(GoLint).AddParams("-set_exit_status", "./...").SetDir(dirPath).MustRun()
-	shellz.NewCommand("go", "vet", "./...").SetDir(dirPath).MustRun()
+	o.NewCommand("go", "vet", "./...").SetDir(dirPath).MustRun()
 	o.GetGoToolCommand(StaticCheck).AddParams("./...").SetDir(dirPath).MustRun()
 
 	cmd := o.GetGoToolCommand(GoTest).
@@ -143,12 +146,43 @@ func (o *operationsImpl) GoTest(dirPath string, packages []string, filter string
 		coverageJSON := o.GetGoToolCommand(GoCov).AddParams("convert", rawCoverageFilePath).SetDir(dirPath).MustOutput()
 		coverageHTML := o.GetGoToolCommand(GoCovHTML).SetStdin(strings.NewReader(coverageJSON)).SetDir(dirPath).MustOutput()
 		filez.MustWriteFile(htmlCoverageFilePath, 0777, 0666, []byte(coverageHTML))
-		shellz.NewCommand("open", htmlCoverageFilePath).SetDir(dirPath).MustRun()
+		o.openInBrowser(filez.MustAbs(htmlCoverageFilePath))
+	}
+}
+
+// GoBuildOption describes an option for GoCrossBuildForLinuxAMD64.
+type GoBuildOption func(options *goBuildOptions)
+
+type goBuildOptions struct {
+	tags     []string
+	upxLevel int
+}
+
+// GoBuildOptionTags is a Go build option that adds build tags, e.g. to compile out optional code paths (the binary is
+// always stripped of symbols and debug information).
+func GoBuildOptionTags(tags ...string) GoBuildOption {
+	return func(o *goBuildOptions) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// GoBuildOptionUPX is a Go build option that compresses the binary using UPX (in Docker), with a level between 1
+// (fastest) and 9 (best). Compressed binaries are decompressed in memory when started, trading size for startup latency.
+func GoBuildOptionUPX(level int) GoBuildOption {
+	errorz.Assertf(level >= 1 && level <= 9, "invalid UPX level: %v", errorz.A(level))
+
+	return func(o *goBuildOptions) {
+		o.upxLevel = level
 	}
 }
 
 // GoCrossBuildForLinuxAMD64 builds a Go binary for linux/amd64.
-func (o *operationsImpl) GoCrossBuildForLinuxAMD64(workDirPath, packageName, binFilePath string, injectValues map[string]string) {
+func (o *operationsImpl) GoCrossBuildForLinuxAMD64(workDirPath, packageName, binFilePath string, injectValues map[string]string, options ...GoBuildOption) {
+	resolvedOptions := &goBuildOptions{}
+	for _, option := range options {
+		option(resolvedOptions)
+	}
+
 	ldFlags := []string{
 		"-ldflags=-s", "-w", "-extldflags", "-static",
 	}
@@ -157,16 +191,26 @@ func (o *operationsImpl) GoCrossBuildForLinuxAMD64(workDirPath, packageName, bin
 		ldFlags = append(ldFlags, fmt.Sprintf("-X' %v=%v'", k, v))
 	}
 
-	shellz.NewCommand("go", "build", "-v",
+	o.NewCommand("go", "build", "-v",
 		"-trimpath",
 		strings.Join(ldFlags, " "),
-		"-tags=netgo osusergo",
+		"-tags="+strings.Join(append([]string{"netgo", "osusergo"}, resolvedOptions.tags...), " "),
 		"-o", binFilePath, packageName).
 		SetEnv("CGO_ENABLED", "0").
 		SetEnv("GOOS", "linux").
 		SetEnv("GOARCH", "amd64").
 		SetDir(workDirPath).
 		MustRun()
+
+	if resolvedOptions.upxLevel > 0 {
+		o.NewCommand("docker", "run", "--rm").
+			AddParams("-v", fmt.Sprintf("%v:/work", filez.MustAbs(filepath.Dir(binFilePath)))).
+			AddParams("-w", "/work").
+			AddParams("-u", fmt.Sprintf("%v:%v", os.Getuid(), os.Getgid())).
+			AddParams("gruebel/upx:"+o.toolVersions.UPX).
+			AddParams(fmt.Sprintf("-%v", resolvedOptions.upxLevel), "-q", filepath.Base(binFilePath)).
+			MustRun()
+	}
 }
 
 // PackageLambdaFunctionHandler packages a self-contained, executable Lambda function handler.
@@ -0,0 +1,276 @@
+package opz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	semver "github.com/Masterminds/semver/v3"
+	"github.com/ibrt/golang-bites/filez"
+	"github.com/ibrt/golang-bites/jsonz"
+	"github.com/ibrt/golang-errors/errorz"
+	"golang.org/x/mod/module"
+)
+
+const (
+	dockerHubAPIBaseURL = "https://hub.docker.com/v2"
+	dockerHubMaxPages   = 10
+	ecrPublicBaseURL    = "https://public.ecr.aws"
+	ghcrBaseURL         = "https://ghcr.io"
+	goProxyBaseURL      = "https://proxy.golang.org"
+	npmRegistryBaseURL  = "https://registry.npmjs.org"
+)
+
+// UpgradeSource describes where to look up the versions of a component.
+type UpgradeSource string
+
+// Known upgrade sources.
+const (
+	DockerHubUpgradeSource UpgradeSource = "docker-hub" // Package is a repository, e.g. "library/caddy"
+	ECRPublicUpgradeSource UpgradeSource = "ecr-public" // Package is a repository, e.g. "docker/library/caddy"
+	GHCRUpgradeSource      UpgradeSource = "ghcr"       // Package is a repository, e.g. "dbt-labs/dbt-postgres"
+	GoProxyUpgradeSource   UpgradeSource = "go-proxy"   // Package is a Go package or module path
+	NPMUpgradeSource       UpgradeSource = "npm"        // Package is a package name, e.g. "@graphql-codegen/cli"
+)
+
+// UpgradeComponent describes a pinned component to check for upgrades.
+// ChangelogURL is optional, and reported as-is to help reviewing the upgrade.
+type UpgradeComponent struct {
+	Name           string
+	Source         UpgradeSource
+	Package        string
+	CurrentVersion string
+	ChangelogURL   string
+}
+
+// UpgradeReport describes the result of checking a set of components for upgrades.
+type UpgradeReport struct {
+	Entries []*UpgradeReportEntry
+}
+
+// GetOutdated returns the entries for which a newer version is available.
+func (r *UpgradeReport) GetOutdated() []*UpgradeReportEntry {
+	outdated := make([]*UpgradeReportEntry, 0)
+	for _, entry := range r.Entries {
+		if entry.IsOutdated {
+			outdated = append(outdated, entry)
+		}
+	}
+	return outdated
+}
+
+// UpgradeReportEntry describes the result of checking a component for upgrades.
+// Error is set if the latest version could not be determined (e.g. because the current version is not semantic).
+type UpgradeReportEntry struct {
+	Component     *UpgradeComponent
+	LatestVersion string
+	IsOutdated    bool
+	Error         string
+}
+
+// String implements the fmt.Stringer interface.
+func (e *UpgradeReportEntry) String() string {
+	switch {
+	case e.Error != "":
+		return fmt.Sprintf("%v: %v (error: %v)", e.Component.Name, e.Component.CurrentVersion, e.Error)
+	case e.IsOutdated && e.Component.ChangelogURL != "":
+		return fmt.Sprintf("%v: %v -> %v (%v)", e.Component.Name, e.Component.CurrentVersion, e.LatestVersion, e.Component.ChangelogURL)
+	case e.IsOutdated:
+		return fmt.Sprintf("%v: %v -> %v", e.Component.Name, e.Component.CurrentVersion, e.LatestVersion)
+	default:
+		return fmt.Sprintf("%v: %v (up to date)", e.Component.Name, e.Component.CurrentVersion)
+	}
+}
+
+// CheckUpgrades looks up the latest stable version of each component in its source, and reports the components for
+// which a newer version is available. Only tags with the same number of version segments as the current version are
+// considered (e.g. "14.3" for "12.10", but neither "14" nor "14.3-alpine"). The report is stored under the build dir,
+// so that it can be archived when run periodically (e.g. by a scheduled CI job).
+func (o *operationsImpl) CheckUpgrades(components []*UpgradeComponent) *UpgradeReport {
+	report := &UpgradeReport{
+		Entries: make([]*UpgradeReportEntry, 0, len(components)),
+	}
+
+	for _, component := range components {
+		report.Entries = append(report.Entries, checkUpgrade(o.ctx, component))
+	}
+
+	filez.MustWriteFile(
+		filepath.Join(o.buildDirPath, "upgrades", time.Now().UTC().Format("20060102T150405")+".json"), 0777, 0666,
+		jsonz.MustMarshalIndentDefault(report))
+
+	return report
+}
+
+func checkUpgrade(ctx context.Context, component *UpgradeComponent) (entry *UpgradeReportEntry) {
+	entry = &UpgradeReportEntry{
+		Component: component,
+	}
+
+	defer func() {
+		if err := errorz.MaybeWrapRecover(recover()); err != nil {
+			entry.Error = err.Error()
+		}
+	}()
+
+	currentVersion, err := semver.NewVersion(component.CurrentVersion)
+	errorz.MaybeMustWrap(err, errorz.M("version", component.CurrentVersion))
+	latestVersion, latestTag := currentVersion, component.CurrentVersion
+
+	for _, tag := range getUpgradeCandidateTags(ctx, component) {
+		if getVersionSegmentsCount(tag) != getVersionSegmentsCount(component.CurrentVersion) {
+			continue
+		}
+
+		// Note: pre-releases (including Go pseudo-versions) are only considered if the current version is one.
+		if version, err := semver.NewVersion(tag); err == nil &&
+			(version.Prerelease() == "" || currentVersion.Prerelease() != "") &&
+			version.GreaterThan(latestVersion) {
+			latestVersion, latestTag = version, tag
+		}
+	}
+
+	entry.LatestVersion = latestTag
+	entry.IsOutdated = latestVersion.GreaterThan(currentVersion)
+	return entry
+}
+
+func getVersionSegmentsCount(version string) int {
+	return strings.Count(strings.SplitN(version, "-", 2)[0], ".") + 1
+}
+
+func getUpgradeCandidateTags(ctx context.Context, component *UpgradeComponent) []string {
+	switch component.Source {
+	case DockerHubUpgradeSource:
+		return getDockerHubTags(ctx, component.Package)
+	case ECRPublicUpgradeSource:
+		return getECRPublicTags(ctx, component.Package)
+	case GHCRUpgradeSource:
+		return getGHCRTags(ctx, component.Package)
+	case GoProxyUpgradeSource:
+		return []string{getGoProxyLatestVersion(ctx, component.Package)}
+	case NPMUpgradeSource:
+		return []string{getNPMLatestVersion(ctx, component.Package)}
+	default:
+		panic(errorz.Errorf("unknown upgrade source: %v", errorz.A(component.Source)))
+	}
+}
+
+func getDockerHubTags(ctx context.Context, repository string) []string {
+	tags := make([]string, 0)
+	nextURL := fmt.Sprintf("%v/repositories/%v/tags?page_size=100&ordering=last_updated", dockerHubAPIBaseURL, repository)
+
+	for i := 0; i < dockerHubMaxPages && nextURL != ""; i++ {
+		page := &struct {
+			Next    string `json:"next"`
+			Results []struct {
+				Name string `json:"name"`
+			} `json:"results"`
+		}{}
+
+		mustGetUpgradeJSON(ctx, nextURL, nil, page)
+
+		for _, result := range page.Results {
+			tags = append(tags, result.Name)
+		}
+
+		nextURL = page.Next
+	}
+
+	return tags
+}
+
+func getECRPublicTags(ctx context.Context, repository string) []string {
+	token := &struct {
+		Token string `json:"token"`
+	}{}
+	mustGetUpgradeJSON(ctx, ecrPublicBaseURL+"/token", nil, token)
+
+	tags := &struct {
+		Tags []string `json:"tags"`
+	}{}
+	mustGetUpgradeJSON(ctx, fmt.Sprintf("%v/v2/%v/tags/list", ecrPublicBaseURL, repository), map[string]string{
+		"Authorization": "Bearer " + token.Token,
+	}, tags)
+
+	return tags.Tags
+}
+
+func getGHCRTags(ctx context.Context, repository string) []string {
+	token := &struct {
+		Token string `json:"token"`
+	}{}
+	mustGetUpgradeJSON(ctx, fmt.Sprintf("%v/token?scope=repository:%v:pull", ghcrBaseURL, repository), nil, token)
+
+	tags := &struct {
+		Tags []string `json:"tags"`
+	}{}
+	mustGetUpgradeJSON(ctx, fmt.Sprintf("%v/v2/%v/tags/list?n=10000", ghcrBaseURL, repository), map[string]string{
+		"Authorization": "Bearer " + token.Token,
+	}, tags)
+
+	return tags.Tags
+}
+
+func getGoProxyLatestVersion(ctx context.Context, packagePath string) string {
+	// Note: the module path is not known in advance, so it's found by trimming the package path one segment at a time.
+	for modulePath := packagePath; modulePath != "." && modulePath != ""; modulePath = path.Dir(modulePath) {
+		escapedModulePath, err := module.EscapePath(modulePath)
+		errorz.MaybeMustWrap(err)
+
+		latest := &struct {
+			Version string `json:"Version"`
+		}{}
+
+		if getUpgradeJSON(ctx, fmt.Sprintf("%v/%v/@latest", goProxyBaseURL, escapedModulePath), nil, latest) {
+			return latest.Version
+		}
+	}
+
+	panic(errorz.Errorf("module not found for package: %v", errorz.A(packagePath)))
+}
+
+func getNPMLatestVersion(ctx context.Context, packageName string) string {
+	distTags := map[string]string{}
+	mustGetUpgradeJSON(ctx, fmt.Sprintf("%v/-/package/%v/dist-tags", npmRegistryBaseURL, url.PathEscape(packageName)), nil, &distTags)
+
+	latest, ok := distTags["latest"]
+	errorz.Assertf(ok, "missing latest dist-tag for package: %v", errorz.A(packageName))
+	return latest
+}
+
+func mustGetUpgradeJSON(ctx context.Context, reqURL string, headers map[string]string, respBody interface{}) {
+	errorz.Assertf(getUpgradeJSON(ctx, reqURL, headers, respBody), "not found: %v", errorz.A(reqURL))
+}
+
+func getUpgradeJSON(ctx context.Context, reqURL string, headers map[string]string, respBody interface{}) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	errorz.MaybeMustWrap(err)
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	errorz.MaybeMustWrap(err, errorz.M("url", reqURL))
+	defer errorz.IgnoreClose(resp.Body)
+
+	buf, err := io.ReadAll(resp.Body)
+	errorz.MaybeMustWrap(err)
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return false
+	}
+
+	errorz.Assertf(resp.StatusCode == http.StatusOK, "unexpected status code %v: %v",
+		errorz.A(resp.StatusCode, strings.TrimSpace(string(buf))), errorz.M("url", reqURL))
+	errorz.MaybeMustWrap(json.Unmarshal(buf, respBody), errorz.M("url", reqURL))
+	return true
+}
@@ -0,0 +1,125 @@
+package opz
+
+import (
+	"sort"
+
+	"github.com/ibrt/golang-validation/vz"
+)
+
+// ToolVersions describes the pinned versions of the third-party tools used by Operations.
+type ToolVersions struct {
+	GoTools      map[GoTool]string `validate:"required,dive,keys,required,endkeys,required"`
+	NodePackages map[string]string `validate:"required,dive,keys,required,endkeys,required"`
+	DBTPostgres  string            `validate:"required"`
+	DBTRedshift  string            `validate:"required"`
+	K6           string            `validate:"required"`
+	PGBench      string            `validate:"required"` // i.e. the version of the "postgres" image
+	UPX          string            `validate:"required"`
+}
+
+// NewDefaultToolVersions returns the default tool versions.
+func NewDefaultToolVersions() *ToolVersions {
+	return &ToolVersions{
+		GoTools: map[GoTool]string{
+			GoCov:       "v1.0.0",
+			GoCovHTML:   "v0.0.0-20200509184451-71874e2e203b",
+			GoLint:      "v0.0.0-20210508222113-6edffad5e616",
+			GoTest:      "v0.0.6",
+			StaticCheck: "2022.1",
+		},
+		NodePackages: map[string]string{
+			"@graphql-codegen/cli":                     "2.6.2",
+			"@graphql-codegen/typescript":              "2.4.8",
+			"@graphql-codegen/typescript-operations":   "2.3.5",
+			"@graphql-codegen/typescript-react-apollo": "3.2.11",
+			"graphql":     "15.8.0",
+			"graphql-tag": "2.12.6",
+			"graphqurl":   "1.0.1",
+		},
+		DBTPostgres: "1.7.4",
+		DBTRedshift: "1.7.1",
+		K6:          "0.38.3",
+		PGBench:     "12.10",
+		UPX:         "3.96",
+	}
+}
+
+// MustValidate validates the tool versions.
+func (v *ToolVersions) MustValidate() {
+	vz.MustValidateStruct(v)
+}
+
+// GetUpgradeComponents returns the tool versions as components to be checked for upgrades (see CheckUpgrades).
+func (v *ToolVersions) GetUpgradeComponents() []*UpgradeComponent {
+	components := []*UpgradeComponent{
+		{
+			Name:           "dbt-postgres",
+			Source:         GHCRUpgradeSource,
+			Package:        "dbt-labs/dbt-postgres",
+			CurrentVersion: v.DBTPostgres,
+			ChangelogURL:   "https://github.com/dbt-labs/dbt-postgres/releases",
+		},
+		{
+			Name:           "dbt-redshift",
+			Source:         GHCRUpgradeSource,
+			Package:        "dbt-labs/dbt-redshift",
+			CurrentVersion: v.DBTRedshift,
+			ChangelogURL:   "https://github.com/dbt-labs/dbt-redshift/releases",
+		},
+		{
+			Name:           "k6",
+			Source:         DockerHubUpgradeSource,
+			Package:        "grafana/k6",
+			CurrentVersion: v.K6,
+			ChangelogURL:   "https://github.com/grafana/k6/releases",
+		},
+		{
+			Name:           "pgbench",
+			Source:         DockerHubUpgradeSource,
+			Package:        "library/postgres",
+			CurrentVersion: v.PGBench,
+			ChangelogURL:   "https://www.postgresql.org/docs/release/",
+		},
+		{
+			Name:           "upx",
+			Source:         DockerHubUpgradeSource,
+			Package:        "gruebel/upx",
+			CurrentVersion: v.UPX,
+			ChangelogURL:   "https://github.com/upx/upx/releases",
+		},
+	}
+
+	goTools := make([]string, 0, len(v.GoTools))
+	for goTool := range v.GoTools {
+		goTools = append(goTools, string(goTool))
+	}
+	sort.Strings(goTools)
+
+	for _, goTool := range goTools {
+		components = append(components, &UpgradeComponent{
+			Name:           goTool,
+			Source:         GoProxyUpgradeSource,
+			Package:        goTool,
+			CurrentVersion: v.GoTools[GoTool(goTool)],
+			ChangelogURL:   "https://pkg.go.dev/" + goTool + "?tab=versions",
+		})
+	}
+
+	nodePackages := make([]string, 0, len(v.NodePackages))
+	for nodePackage := range v.NodePackages {
+		nodePackages = append(nodePackages, nodePackage)
+	}
+	sort.Strings(nodePackages)
+
+	for _, nodePackage := range nodePackages {
+		components = append(components, &UpgradeComponent{
+			Name:           nodePackage,
+			Source:         NPMUpgradeSource,
+			Package:        nodePackage,
+			CurrentVersion: v.NodePackages[nodePackage],
+			ChangelogURL:   "https://www.npmjs.com/package/" + nodePackage + "?activeTab=versions",
+		})
+	}
+
+	return components
+}
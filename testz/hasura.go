@@ -0,0 +1,184 @@
+package testz
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ibrt/golang-bites/jsonz"
+	"github.com/ibrt/golang-errors/errorz"
+)
+
+// HasuraPermissionsOutcome describes the expected outcome of a query in a HasuraPermissionsTestCase.
+type HasuraPermissionsOutcome string
+
+// Known Hasura permissions outcomes.
+const (
+	HasuraPermissionsAllowed HasuraPermissionsOutcome = "allowed"
+	HasuraPermissionsDenied  HasuraPermissionsOutcome = "denied"
+)
+
+// HasuraPermissionsTestCase describes a Hasura permissions test case.
+//
+// Requests are made using the admin secret, impersonating the given role and session variables (e.g.
+// "x-hasura-user-id"), so that the test cases don't depend on how JWTs are issued. If Query is set, it is executed
+// and checked against Outcome and, if allowed, against RowCounts (the number of rows returned for each root field,
+// useful to test row-level filters). VisibleFields and HiddenFields map GraphQL type names to fields that must (or must
+// not) be visible to the role, and are checked using introspection.
+type HasuraPermissionsTestCase struct {
+	Name             string
+	Role             string
+	SessionVariables map[string]string
+	Query            string
+	Variables        map[string]interface{}
+	Outcome          HasuraPermissionsOutcome
+	RowCounts        map[string]int
+	VisibleFields    map[string][]string
+	HiddenFields     map[string][]string
+}
+
+// RunHasuraPermissionsTests runs the given permissions test cases against a running Hasura endpoint (typically the
+// local one), as sub-tests of the given test.
+func RunHasuraPermissionsTests(t *testing.T, hsURL, adminSecret string, testCases []*HasuraPermissionsTestCase) {
+	t.Helper()
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.Name, func(t *testing.T) {
+			if testCase.Query != "" {
+				checkHasuraQuery(t, hsURL, adminSecret, testCase)
+			}
+
+			if len(testCase.VisibleFields) > 0 || len(testCase.HiddenFields) > 0 {
+				checkHasuraFieldVisibility(t, hsURL, adminSecret, testCase)
+			}
+		})
+	}
+}
+
+type hasuraResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (r *hasuraResponse) getErrorMessages() string {
+	messages := make([]string, 0, len(r.Errors))
+	for _, err := range r.Errors {
+		messages = append(messages, err.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func checkHasuraQuery(t *testing.T, hsURL, adminSecret string, testCase *HasuraPermissionsTestCase) {
+	resp := mustExecuteHasuraQuery(hsURL, adminSecret, testCase, testCase.Query, testCase.Variables)
+
+	switch testCase.Outcome {
+	case HasuraPermissionsAllowed:
+		if len(resp.Errors) > 0 {
+			t.Errorf("expected query to be allowed for role %v, got errors: %v", testCase.Role, resp.getErrorMessages())
+			return
+		}
+	case HasuraPermissionsDenied:
+		if len(resp.Errors) == 0 {
+			t.Errorf("expected query to be denied for role %v, got data: %v", testCase.Role, jsonz.MustMarshalString(resp.Data))
+		}
+		return
+	default:
+		t.Fatalf("invalid outcome: %v", testCase.Outcome)
+	}
+
+	for fieldName, expectedRowCount := range testCase.RowCounts {
+		rows := make([]json.RawMessage, 0)
+		if err := json.Unmarshal(resp.Data[fieldName], &rows); err != nil {
+			t.Errorf("expected a list of rows for field %v, got: %v", fieldName, string(resp.Data[fieldName]))
+			continue
+		}
+
+		if len(rows) != expectedRowCount {
+			t.Errorf("expected %v rows for field %v and role %v, got %v", expectedRowCount, fieldName, testCase.Role, len(rows))
+		}
+	}
+}
+
+func checkHasuraFieldVisibility(t *testing.T, hsURL, adminSecret string, testCase *HasuraPermissionsTestCase) {
+	typeNames := make([]string, 0)
+	for typeName := range testCase.VisibleFields {
+		typeNames = append(typeNames, typeName)
+	}
+	for typeName := range testCase.HiddenFields {
+		if _, ok := testCase.VisibleFields[typeName]; !ok {
+			typeNames = append(typeNames, typeName)
+		}
+	}
+	sort.Strings(typeNames)
+
+	for _, typeName := range typeNames {
+		resp := mustExecuteHasuraQuery(hsURL, adminSecret, testCase,
+			`query ($name: String!) { __type(name: $name) { fields { name } } }`,
+			map[string]interface{}{"name": typeName})
+
+		if len(resp.Errors) > 0 {
+			t.Errorf("unable to introspect type %v for role %v: %v", typeName, testCase.Role, resp.getErrorMessages())
+			continue
+		}
+
+		introspection := &struct {
+			Fields []struct {
+				Name string `json:"name"`
+			} `json:"fields"`
+		}{}
+		errorz.MaybeMustWrap(json.Unmarshal(resp.Data["__type"], introspection))
+
+		fieldNames := map[string]struct{}{}
+		for _, field := range introspection.Fields {
+			fieldNames[field.Name] = struct{}{}
+		}
+
+		for _, fieldName := range testCase.VisibleFields[typeName] {
+			if _, ok := fieldNames[fieldName]; !ok {
+				t.Errorf("expected field %v.%v to be visible to role %v", typeName, fieldName, testCase.Role)
+			}
+		}
+
+		for _, fieldName := range testCase.HiddenFields[typeName] {
+			if _, ok := fieldNames[fieldName]; ok {
+				t.Errorf("expected field %v.%v to be hidden from role %v", typeName, fieldName, testCase.Role)
+			}
+		}
+	}
+}
+
+func mustExecuteHasuraQuery(hsURL, adminSecret string, testCase *HasuraPermissionsTestCase, query string, variables map[string]interface{}) *hasuraResponse {
+	req, err := http.NewRequest(http.MethodPost, hsURL, bytes.NewReader(jsonz.MustMarshal(map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})))
+	errorz.MaybeMustWrap(err)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Hasura-Admin-Secret", adminSecret)
+	req.Header.Set("X-Hasura-Role", testCase.Role)
+
+	for k, v := range testCase.SessionVariables {
+		req.Header.Set(k, v)
+	}
+
+	httpResp, err := http.DefaultClient.Do(req)
+	errorz.MaybeMustWrap(err)
+	defer errorz.IgnoreClose(httpResp.Body)
+
+	buf, err := io.ReadAll(httpResp.Body)
+	errorz.MaybeMustWrap(err)
+	errorz.Assertf(httpResp.StatusCode == http.StatusOK, "unexpected status code %v: %v", errorz.A(httpResp.StatusCode, string(buf)))
+
+	resp := &hasuraResponse{}
+	errorz.MaybeMustWrap(json.Unmarshal(buf, resp))
+	return resp
+}
@@ -0,0 +1,131 @@
+package testz
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/ibrt/golang-bites/stringz"
+	"github.com/ibrt/golang-errors/errorz"
+
+	"github.com/ibrt/golang-cloud/cloudz"
+)
+
+// Token minter constants.
+const (
+	HasuraDefaultClaimsNamespace = "https://hasura.io/jwt/claims"
+
+	tokenMinterRSAKeyBits = 2048
+	tokenMinterDefaultTTL = time.Hour
+)
+
+// HasuraClaims describes the Hasura claims of a test token.
+// SessionVariables can be used to set additional session variables (e.g. "x-hasura-org-id").
+type HasuraClaims struct {
+	Subject          string
+	UserID           string
+	DefaultRole      string
+	AllowedRoles     []string
+	SessionVariables map[string]string
+	TTL              time.Duration
+}
+
+// TokenMinter mints RS256 tokens accepted by a Hasura (or API) configured with the corresponding HasuraConfigJWT.
+type TokenMinter struct {
+	privateKey      *rsa.PrivateKey
+	issuer          string
+	audience        string
+	claimsNamespace string
+}
+
+// MustGenerateRSAPrivateKey generates a new RSA private key suitable for signing RS256 tokens.
+func MustGenerateRSAPrivateKey() *rsa.PrivateKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, tokenMinterRSAKeyBits)
+	errorz.MaybeMustWrap(err)
+	return privateKey
+}
+
+// NewTokenMinter initializes a new TokenMinter. Note that the local Hasura is configured when the stage is created, so
+// the private key should typically be stable (e.g. loaded from a PEM file using rsaz.MustPEMToRSAPrivateKey).
+func NewTokenMinter(privateKey *rsa.PrivateKey, issuer, audience string) *TokenMinter {
+	return &TokenMinter{
+		privateKey:      privateKey,
+		issuer:          issuer,
+		audience:        audience,
+		claimsNamespace: HasuraDefaultClaimsNamespace,
+	}
+}
+
+// SetClaimsNamespace sets a custom claims namespace.
+func (m *TokenMinter) SetClaimsNamespace(claimsNamespace string) *TokenMinter {
+	m.claimsNamespace = claimsNamespace
+	return m
+}
+
+// GetPublicKey returns the public key used to verify the minted tokens.
+func (m *TokenMinter) GetPublicKey() *rsa.PublicKey {
+	return &m.privateKey.PublicKey
+}
+
+// GetHasuraConfigJWT returns a HasuraConfigJWT that accepts the minted tokens.
+func (m *TokenMinter) GetHasuraConfigJWT() *cloudz.HasuraConfigJWT {
+	cfg := &cloudz.HasuraConfigJWT{
+		PublicKey: m.GetPublicKey(),
+		Issuer:    m.issuer,
+		Audience:  m.audience,
+	}
+
+	if m.claimsNamespace != HasuraDefaultClaimsNamespace {
+		cfg.ClaimsNamespace = stringz.Ptr(m.claimsNamespace)
+	}
+
+	return cfg
+}
+
+// MustMint mints a new signed token with the given claims.
+// The allowed roles default to the default role, and the subject defaults to the user ID.
+func (m *TokenMinter) MustMint(claims *HasuraClaims) string {
+	errorz.Assertf(claims.DefaultRole != "", "missing HasuraClaims.DefaultRole")
+
+	now := time.Now()
+	ttl := claims.TTL
+	if ttl == 0 {
+		ttl = tokenMinterDefaultTTL
+	}
+
+	allowedRoles := claims.AllowedRoles
+	if len(allowedRoles) == 0 {
+		allowedRoles = []string{claims.DefaultRole}
+	}
+
+	hasuraClaims := map[string]interface{}{
+		"x-hasura-default-role":  claims.DefaultRole,
+		"x-hasura-allowed-roles": allowedRoles,
+	}
+
+	if claims.UserID != "" {
+		hasuraClaims["x-hasura-user-id"] = claims.UserID
+	}
+
+	for k, v := range claims.SessionVariables {
+		hasuraClaims[k] = v
+	}
+
+	subject := claims.Subject
+	if subject == "" {
+		subject = claims.UserID
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":             m.issuer,
+		"aud":             m.audience,
+		"sub":             subject,
+		"iat":             now.Unix(),
+		"exp":             now.Add(ttl).Unix(),
+		m.claimsNamespace: hasuraClaims,
+	}).SignedString(m.privateKey)
+	errorz.MaybeMustWrap(err)
+
+	return token
+}
@@ -0,0 +1,43 @@
+package testz
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibrt/golang-cloud/cloudz"
+)
+
+// Mail assertion constants.
+const (
+	mailWaitTimeout      = 10 * time.Second
+	mailWaitPollInterval = 250 * time.Millisecond
+)
+
+// AssertMailSent waits for a message for the given recipient with a subject containing the given string (if not empty)
+// to be received by MailHog, and returns it. It fails the test if no such message is received within a few seconds.
+func AssertMailSent(t *testing.T, client *cloudz.MailHogClient, recipient, subjectContains string) *cloudz.MailHogMessage {
+	t.Helper()
+
+	for deadline := time.Now().Add(mailWaitTimeout); ; time.Sleep(mailWaitPollInterval) {
+		for _, message := range client.FindByRecipient(recipient) {
+			if strings.Contains(message.Subject, subjectContains) {
+				return message
+			}
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a message for %v with subject containing %q, none received within %v", recipient, subjectContains, mailWaitTimeout)
+			return nil
+		}
+	}
+}
+
+// AssertNoMailSent fails the test if any message for the given recipient has been received by MailHog.
+func AssertNoMailSent(t *testing.T, client *cloudz.MailHogClient, recipient string) {
+	t.Helper()
+
+	if messages := client.FindByRecipient(recipient); len(messages) > 0 {
+		t.Errorf("expected no messages for %v, got %v (first subject: %q)", recipient, len(messages), messages[0].Subject)
+	}
+}